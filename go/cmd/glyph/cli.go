@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+// ============================================================
+// Exit Codes and Output
+// ============================================================
+//
+// Every command returns a result and an error instead of printing and
+// exiting, so all of them fail the same way. The exit code says what went
+// wrong: a bad invocation, input that does not parse, input that parses
+// but fails a check, or a file that cannot be read or written.
+//
+// Two flags apply to every command, anywhere on the line:
+//
+//	--json      write one JSON envelope to stdout and nothing to stderr:
+//	            {"ok":true,"command":"fmt-loose","result":...} or
+//	            {"ok":false,"command":"eval","error":{"kind":"parse","exit":3,"message":...}}
+//	--quiet/-q  no banners or progress notes on stderr; errors still show
+
+// Exit codes.
+const (
+	exitOK         = 0
+	exitError      = 1 // Anything not covered below
+	exitUsage      = 2 // Unknown command or flag, missing or bad argument
+	exitParse      = 3 // Input that does not parse: JSON, GLYPH, schemas, frames
+	exitValidation = 4 // Input that parses but fails a check: bundle hash, frame CRC, coercion
+	exitIO         = 5 // A file or stream that cannot be opened, read or written
+)
+
+// exitKinds names the exit codes in the --json envelope.
+var exitKinds = map[int]string{
+	exitError:      "error",
+	exitUsage:      "usage",
+	exitParse:      "parse",
+	exitValidation: "validation",
+	exitIO:         "io",
+}
+
+// cliError is an error with the exit code it ends the run with.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string { return e.err.Error() }
+func (e *cliError) Unwrap() error { return e.err }
+
+// failf returns an error, formatted as by fmt.Errorf, that exits with code.
+func failf(code int, format string, args ...any) error {
+	return &cliError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// exitCode returns the exit code for err. Errors not made by failf are
+// classified by what they wrap.
+func exitCode(err error) int {
+	var ce *cliError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.Is(err, glyph.ErrBundleHash),
+		errors.As(err, new(*stream.CRCMismatchError)),
+		errors.As(err, new(*stream.BaseMismatchError)),
+		errors.As(err, new(*stream.StreamClosedError)):
+		return exitValidation
+	case errors.As(err, new(*glyph.ParseError)),
+		errors.As(err, new(*stream.ParseError)),
+		errors.As(err, new(*json.SyntaxError)),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return exitParse
+	case errors.As(err, new(*fs.PathError)):
+		return exitIO
+	}
+	return exitError
+}
+
+// cli is one run of the tool: where it reads and writes, and how.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	json           bool // Write one JSON envelope to stdout
+	quiet          bool // No banners or progress notes
+}
+
+// parseGlobalFlags sets the flags that apply to every command (see
+// globalFlags) and returns args without them.
+func (c *cli) parseGlobalFlags(args []string) []string {
+	rest := args[:0:0]
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			c.json = true
+		case "--quiet", "-q":
+			c.quiet = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// note writes a banner or progress note to stderr, unless quiet or --json.
+func (c *cli) note(format string, args ...any) {
+	if !c.quiet && !c.json {
+		fmt.Fprintf(c.stderr, format+"\n", args...)
+	}
+}
+
+// textResult is the --json result of a command whose output is text.
+type textResult struct {
+	Output string `json:"output"`
+}
+
+// text writes s to stdout, or under --json returns it as the result.
+func (c *cli) text(s string) (any, error) {
+	if c.json {
+		return textResult{Output: s}, nil
+	}
+	if _, err := io.WriteString(c.stdout, s); err != nil {
+		return nil, failf(exitIO, "write output: %w", err)
+	}
+	return nil, nil
+}
+
+// envelope is the --json form of a run.
+type envelope struct {
+	OK      bool       `json:"ok"`
+	Command string     `json:"command"`
+	Result  any        `json:"result,omitempty"` // Also set on some failures, such as partly decoded streams
+	Error   *errorInfo `json:"error,omitempty"`
+}
+
+type errorInfo struct {
+	Kind    string `json:"kind"` // usage, parse, validation, io or error
+	Exit    int    `json:"exit"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"` // Where a GLYPH parse error is, when known
+	Column  int    `json:"column,omitempty"`
+}
+
+// finish reports how command ended and returns the exit code.
+func (c *cli) finish(command string, result any, err error) int {
+	code := exitCode(err)
+	if !c.json {
+		if err != nil {
+			fmt.Fprintf(c.stderr, "glyph: %v\n", err)
+		}
+		return code
+	}
+
+	env := envelope{OK: err == nil, Command: command, Result: result}
+	if err != nil {
+		env.Error = &errorInfo{Kind: exitKinds[code], Exit: code, Message: err.Error()}
+		var pe *glyph.ParseError
+		if errors.As(err, &pe) {
+			env.Error.Line, env.Error.Column = pe.Pos.Line, pe.Pos.Column
+		}
+	}
+	if err := json.NewEncoder(c.stdout).Encode(env); err != nil {
+		return exitIO
+	}
+	return code
+}
+
+// commandName returns the command args name, with its subcommand if it
+// has any: "fmt-loose", "bundle pack".
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	for _, cmd := range commands {
+		if cmd.Name == args[0] && len(cmd.Subcommands) > 0 && len(args) > 1 {
+			return strings.Join(args[:2], " ")
+		}
+	}
+	return args[0]
+}
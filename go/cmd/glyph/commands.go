@@ -0,0 +1,438 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Command Table
+// ============================================================
+//
+// commands describes every command and flag the CLI takes. It is what
+// `glyph help --json` prints and what `glyph completion` builds shell
+// completions from, so wrappers and editors can introspect the tool
+// without scraping the usage text. A command or flag added to main must
+// be added here too; TestCommandTable checks the two agree with the
+// usage text.
+
+type cliFlag struct {
+	Name   string `json:"name"`             // --docs, -o
+	Value  string `json:"value,omitempty"`  // Placeholder of its value (N, FILE); "" for a switch
+	Equals bool   `json:"equals,omitempty"` // The value is written --flag=VALUE only
+	Help   string `json:"help"`
+}
+
+type cliCommand struct {
+	Name        string       `json:"name"`
+	Aliases     []string     `json:"aliases,omitempty"`
+	Args        string       `json:"args,omitempty"`  // Positional arguments, as in the usage line
+	Files       bool         `json:"files,omitempty"` // The positional argument is a file path
+	Help        string       `json:"help"`
+	Flags       []cliFlag    `json:"flags,omitempty"`
+	Subcommands []cliCommand `json:"subcommands,omitempty"`
+}
+
+var commands = []cliCommand{
+	{
+		Name: "fmt-loose", Aliases: []string{"fmt"}, Args: "[file]", Files: true,
+		Help: "Format JSON as canonical GLYPH-Loose",
+		Flags: []cliFlag{
+			{Name: "--no-tabular", Help: "Disable auto-tabular"},
+			{Name: "--llm", Help: "Use LLM-friendly mode (ASCII _ for null)"},
+			{Name: "--compact", Help: "Use schema header + compact keys (#0, #1, etc.)"},
+			{Name: "--auto-compact", Help: "Use compact keys only when smaller"},
+			{Name: "--docs", Value: "N", Equals: true, Help: "Number of documents sharing the header, for --auto-compact"},
+			{Name: "--bundle", Value: "FILE", Equals: true, Help: "Take the canonical options from a .glyphpkg bundle"},
+			{Name: "--profile", Value: "NAME", Equals: true, Help: "Bundle profile to use with --bundle"},
+		},
+	},
+	{Name: "to-json", Args: "[file]", Files: true, Help: "Convert GLYPH canonical to JSON"},
+	{Name: "from-json", Args: "[file]", Files: true, Help: "Parse JSON to GLYPH-Loose canonical"},
+	{
+		Name: "stream", Help: "Read and write GS1-T frame streams",
+		Subcommands: []cliCommand{
+			{Name: "decode", Args: "[file]", Files: true, Help: "Decode GS1-T frames and print"},
+			{
+				Name: "run", Args: "scenario.glyph", Files: true, Help: "Write the stream a scenario file describes",
+				Flags: []cliFlag{
+					{Name: "--seed", Value: "N", Equals: true, Help: "Reproducible output (fixed clock, ids seeded with N, no delays)"},
+				},
+			},
+			{
+				Name: "demo", Help: "Run the Agent Cockpit streaming demo",
+				Flags: []cliFlag{
+					{Name: "--seed", Value: "N", Equals: true, Help: "Reproducible output (fixed clock, ids seeded with N, no delays)"},
+				},
+			},
+		},
+	},
+	{
+		Name: "gen", Help: "Generate text from schemas",
+		Subcommands: []cliCommand{{
+			Name: "prompt", Help: "Write a system-prompt section for a type",
+			Flags: []cliFlag{
+				{Name: "--schema", Value: "FILE", Help: "Schema file"},
+				{Name: "--type", Value: "T", Help: "Type to describe"},
+				{Name: "--examples", Value: "DIR", Help: "Examples: DIR/*.glyph and DIR/*.json"},
+			},
+		}},
+	},
+	{
+		Name: "eval", Args: "[file]", Files: true,
+		Help: "Parse and validate model outputs, one per NDJSON line",
+		Flags: []cliFlag{
+			{Name: "--schema", Value: "FILE", Help: "Schema file"},
+			{Name: "--type", Value: "T", Help: "Type the outputs must have"},
+		},
+	},
+	{
+		Name: "bundle", Help: "Build and check .glyphpkg bundles",
+		Subcommands: []cliCommand{
+			{
+				Name: "pack", Help: "Write a .glyphpkg bundle of schemas, dicts, pools and profiles",
+				Flags: []cliFlag{
+					{Name: "--name", Value: "N", Help: "Bundle name"},
+					{Name: "--version", Value: "V", Help: "Bundle version"},
+					{Name: "-o", Value: "FILE", Help: "Output file (default NAME.glyphpkg)"},
+					{Name: "--out", Value: "FILE", Help: "Same as -o"},
+					{Name: "--schema", Value: "NAME=FILE", Help: "Add a schema"},
+					{Name: "--dict", Value: "NAME=FILE", Help: "Add a key dictionary, one entry per line"},
+					{Name: "--pool", Value: "NAME=FILE", Help: "Add a string pool, one entry per line"},
+					{Name: "--profile", Value: "NAME=SPEC", Help: "Add a profile, SPEC like {tabular=t dict=NAME}"},
+				},
+			},
+			{Name: "inspect", Args: "FILE", Files: true, Help: "Check a bundle's hash and list its manifest"},
+		},
+	},
+	{
+		Name: "completion", Help: "Print a shell completion script",
+		Subcommands: []cliCommand{
+			{Name: "bash", Help: "Bash completion (source it, or install in bash-completion's directory)"},
+			{Name: "zsh", Help: "Zsh completion (save as _glyph on $fpath)"},
+			{Name: "fish", Help: "Fish completion (save in ~/.config/fish/completions)"},
+		},
+	},
+	{Name: "help", Help: "Print usage; with --json, this table"},
+	{Name: "version", Help: "Print version info"},
+}
+
+// globalFlags apply to every command.
+var globalFlags = []cliFlag{
+	{Name: "--json", Help: "Write one JSON envelope with the result or error to stdout"},
+	{Name: "--quiet", Help: "No banners or progress notes on stderr"},
+	{Name: "-q", Help: "Same as --quiet"},
+}
+
+// manifestResult is the --json result of glyph help.
+type manifestResult struct {
+	Name      string         `json:"name"`
+	Version   string         `json:"version"`
+	Spec      string         `json:"spec"`
+	Flags     []cliFlag      `json:"flags"` // Global flags
+	ExitCodes map[string]int `json:"exit_codes"`
+	Commands  []cliCommand   `json:"commands"`
+}
+
+// manifest returns the command table with the global flags and exit codes.
+func manifest() manifestResult {
+	codes := make(map[string]int, len(exitKinds))
+	for code, kind := range exitKinds {
+		codes[kind] = code
+	}
+	return manifestResult{"glyph", libVersion, specVersion, globalFlags, codes, commands}
+}
+
+// ============================================================
+// Shell Completion
+// ============================================================
+//
+// The scripts complete commands, subcommands and flags, file names where a
+// command takes a file, and the values of FILE and DIR flags. Each shell
+// gets the same cases: one per command path ("fmt-loose", "stream decode").
+
+// writeCompletion writes the completion script for shell.
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w)
+	case "zsh":
+		writeZshCompletion(w)
+	case "fish":
+		writeFishCompletion(w)
+	default:
+		return fmt.Errorf("unknown shell %q (want bash, zsh or fish)", shell)
+	}
+	return nil
+}
+
+// leafCommand is a command that runs, with the words that reach it.
+type leafCommand struct {
+	patterns []string // e.g. "fmt-loose" and "fmt", or "stream decode"
+	cmd      cliCommand
+}
+
+// leafCommands returns the runnable commands of the table in order, each
+// with the global flags after its own.
+func leafCommands() []leafCommand {
+	var leaves []leafCommand
+	add := func(patterns []string, c cliCommand) {
+		c.Flags = append(c.Flags[:len(c.Flags):len(c.Flags)], globalFlags...)
+		leaves = append(leaves, leafCommand{patterns, c})
+	}
+	for _, c := range commands {
+		if len(c.Subcommands) == 0 {
+			add(append([]string{c.Name}, c.Aliases...), c)
+			continue
+		}
+		for _, sub := range c.Subcommands {
+			add([]string{c.Name + " " + sub.Name}, sub)
+		}
+	}
+	return leaves
+}
+
+// commandNames returns the names and aliases of cmds.
+func commandNames(cmds []cliCommand) []string {
+	var names []string
+	for _, c := range cmds {
+		names = append(names, c.Name)
+		names = append(names, c.Aliases...)
+	}
+	return names
+}
+
+// flagWords returns the words that complete the flags of c: --flag= for a
+// flag written with =, else the flag itself.
+func flagWords(c cliCommand) []string {
+	var words []string
+	for _, f := range c.Flags {
+		if f.Equals {
+			words = append(words, f.Name+"=")
+		} else {
+			words = append(words, f.Name)
+		}
+	}
+	return words
+}
+
+// valueFlags returns the flags of c that take their value as the next
+// word, by what completes the value: "file", "dir" or "" (nothing).
+func valueFlags(c cliCommand) map[string][]string {
+	byKind := make(map[string][]string)
+	for _, f := range c.Flags {
+		if f.Value == "" || f.Equals {
+			continue
+		}
+		kind := ""
+		switch {
+		case f.Value == "DIR":
+			kind = "dir"
+		case f.Value == "FILE":
+			kind = "file"
+		}
+		byKind[kind] = append(byKind[kind], f.Name)
+	}
+	return byKind
+}
+
+// sortedKinds returns the keys of byKind in order.
+func sortedKinds(byKind map[string][]string) []string {
+	kinds := make([]string, 0, len(byKind))
+	for k := range byKind {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprint(w, `# bash completion for glyph (glyph completion bash)
+_glyph() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	local cmd=${COMP_WORDS[1]} words="" files=""
+	if [[ $COMP_CWORD -eq 1 ]]; then
+`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n", strings.Join(commandNames(commands), " "))
+	fmt.Fprint(w, "\tcase $cmd in\n")
+	for _, c := range commands {
+		if len(c.Subcommands) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\t%s)\n\t\tif [[ $COMP_CWORD -eq 2 ]]; then\n", c.Name)
+		fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\t\treturn\n\t\tfi\n", strings.Join(commandNames(c.Subcommands), " "))
+		fmt.Fprint(w, "\t\tcmd=\"$cmd ${COMP_WORDS[2]}\" ;;\n")
+	}
+	fmt.Fprint(w, "\tesac\n\tcase $cmd in\n")
+	for _, leaf := range leafCommands() {
+		fmt.Fprintf(w, "\t%s)\n", bashPatterns(leaf.patterns))
+		if byKind := valueFlags(leaf.cmd); len(byKind) > 0 {
+			fmt.Fprint(w, "\t\tcase $prev in\n")
+			for _, kind := range sortedKinds(byKind) {
+				fmt.Fprintf(w, "\t\t%s)", strings.Join(byKind[kind], "|"))
+				switch kind {
+				case "file":
+					fmt.Fprint(w, " COMPREPLY=($(compgen -f -- \"$cur\"));")
+				case "dir":
+					fmt.Fprint(w, " COMPREPLY=($(compgen -d -- \"$cur\"));")
+				}
+				fmt.Fprint(w, " return ;;\n")
+			}
+			fmt.Fprint(w, "\t\tesac\n")
+		}
+		fmt.Fprintf(w, "\t\twords=%q", strings.Join(flagWords(leaf.cmd), " "))
+		if leaf.cmd.Files {
+			fmt.Fprint(w, " files=1")
+		}
+		fmt.Fprint(w, " ;;\n")
+	}
+	fmt.Fprint(w, `	esac
+	COMPREPLY=($(compgen -W "$words" -- "$cur"))
+	if [[ -n $files && $cur != -* ]]; then
+		COMPREPLY+=($(compgen -f -- "$cur"))
+	fi
+}
+complete -o filenames -F _glyph glyph
+`)
+}
+
+// bashPatterns joins the patterns of a case, quoting those with spaces.
+func bashPatterns(patterns []string) string {
+	quoted := make([]string, len(patterns))
+	for i, p := range patterns {
+		if strings.Contains(p, " ") {
+			p = `"` + p + `"`
+		}
+		quoted[i] = p
+	}
+	return strings.Join(quoted, "|")
+}
+
+func writeZshCompletion(w io.Writer) {
+	// $path is special in zsh (it mirrors $PATH), so the command path is
+	// kept in $cmd.
+	fmt.Fprint(w, `#compdef glyph
+# zsh completion for glyph (glyph completion zsh)
+_glyph() {
+	local cmd=${words[2]} prev=${words[CURRENT-1]}
+	if (( CURRENT == 2 )); then
+`)
+	fmt.Fprintf(w, "\t\tcompadd -- %s\n\t\treturn\n\tfi\n", strings.Join(commandNames(commands), " "))
+	fmt.Fprint(w, "\tcase $cmd in\n")
+	for _, c := range commands {
+		if len(c.Subcommands) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\t%s)\n\t\tif (( CURRENT == 3 )); then\n", c.Name)
+		fmt.Fprintf(w, "\t\t\tcompadd -- %s\n\t\t\treturn\n\t\tfi\n", strings.Join(commandNames(c.Subcommands), " "))
+		fmt.Fprint(w, "\t\tcmd=\"$cmd ${words[3]}\" ;;\n")
+	}
+	fmt.Fprint(w, "\tesac\n\tcase $cmd in\n")
+	for _, leaf := range leafCommands() {
+		fmt.Fprintf(w, "\t%s)\n", bashPatterns(leaf.patterns))
+		if byKind := valueFlags(leaf.cmd); len(byKind) > 0 {
+			fmt.Fprint(w, "\t\tcase $prev in\n")
+			for _, kind := range sortedKinds(byKind) {
+				fmt.Fprintf(w, "\t\t%s)", strings.Join(byKind[kind], "|"))
+				switch kind {
+				case "file":
+					fmt.Fprint(w, " _files;")
+				case "dir":
+					fmt.Fprint(w, " _files -/;")
+				}
+				fmt.Fprint(w, " return ;;\n")
+			}
+			fmt.Fprint(w, "\t\tesac\n")
+		}
+		words := flagWords(leaf.cmd)
+		if eq := equalsWords(words); len(eq) > 0 {
+			fmt.Fprintf(w, "\t\tcompadd -S '' -- %s\n", strings.Join(eq, " "))
+		}
+		if plain := plainWords(words); len(plain) > 0 {
+			fmt.Fprintf(w, "\t\tcompadd -- %s\n", strings.Join(plain, " "))
+		}
+		if leaf.cmd.Files {
+			fmt.Fprint(w, "\t\t_files\n")
+		}
+		fmt.Fprint(w, "\t\t;;\n")
+	}
+	fmt.Fprint(w, "\tesac\n}\ncompdef _glyph glyph\n")
+}
+
+// equalsWords returns the words ending in =, which take no trailing space.
+func equalsWords(words []string) []string {
+	var out []string
+	for _, word := range words {
+		if strings.HasSuffix(word, "=") {
+			out = append(out, word)
+		}
+	}
+	return out
+}
+
+// plainWords returns the words not ending in =.
+func plainWords(words []string) []string {
+	var out []string
+	for _, word := range words {
+		if !strings.HasSuffix(word, "=") {
+			out = append(out, word)
+		}
+	}
+	return out
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprint(w, "# fish completion for glyph (glyph completion fish)\ncomplete -c glyph -f\n")
+	for _, c := range commands {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			fmt.Fprintf(w, "complete -c glyph -n __fish_use_subcommand -a %s -d %s\n", name, fishQuote(c.Help))
+		}
+		if len(c.Subcommands) == 0 {
+			continue
+		}
+		subs := strings.Join(commandNames(c.Subcommands), " ")
+		for _, sub := range c.Subcommands {
+			fmt.Fprintf(w, "complete -c glyph -n %s -a %s -d %s\n",
+				fishQuote("__fish_seen_subcommand_from "+c.Name+"; and not __fish_seen_subcommand_from "+subs),
+				sub.Name, fishQuote(sub.Help))
+		}
+	}
+	for _, leaf := range leafCommands() {
+		cond := "__fish_seen_subcommand_from " + leaf.patterns[0]
+		if parent, sub, ok := strings.Cut(leaf.patterns[0], " "); ok {
+			cond = "__fish_seen_subcommand_from " + parent + "; and __fish_seen_subcommand_from " + sub
+		} else if len(leaf.patterns) > 1 {
+			cond = "__fish_seen_subcommand_from " + strings.Join(leaf.patterns, " ")
+		}
+		for _, f := range leaf.cmd.Flags {
+			fmt.Fprintf(w, "complete -c glyph -n %s %s", fishQuote(cond), fishFlag(f.Name))
+			switch {
+			case f.Value == "DIR":
+				fmt.Fprint(w, " -r -a '(__fish_complete_directories)'")
+			case f.Value == "FILE":
+				fmt.Fprint(w, " -r -F")
+			case f.Value != "":
+				fmt.Fprint(w, " -x")
+			}
+			fmt.Fprintf(w, " -d %s\n", fishQuote(f.Help))
+		}
+		if leaf.cmd.Files {
+			fmt.Fprintf(w, "complete -c glyph -n %s -F\n", fishQuote(cond))
+		}
+	}
+}
+
+// fishFlag returns the complete option naming flag: -l for --long, -s for -x.
+func fishFlag(flag string) string {
+	if strings.HasPrefix(flag, "--") {
+		return "-l " + flag[2:]
+	}
+	return "-s " + flag[1:]
+}
+
+// fishQuote quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -6,9 +6,23 @@
 //	glyph to-json [file]                   Convert GLYPH-Loose canonical to JSON
 //	glyph from-json [file]                 Parse JSON to GLYPH-Loose canonical
 //	glyph stream decode [file]             Decode GS1-T frames and print
-//	glyph stream demo                      Run the Agent Cockpit streaming demo
+//	glyph stream run [--seed=N] FILE       Write the stream a scenario file describes
+//	glyph stream demo [--seed=N]           Run the Agent Cockpit streaming demo
+//	glyph gen prompt --schema F --type T [--examples DIR]
+//	                                       Write a system-prompt section for type T
+//	glyph eval --schema F [--type T] outputs.ndjson
+//	                                       Measure how well model outputs parse and validate
+//	glyph bundle pack --name N [--version V] [--schema|--dict|--pool|--profile NAME=SRC]... [-o FILE]
+//	                                       Write a .glyphpkg bundle of schemas, dicts, pools and profiles
+//	glyph bundle inspect FILE              Check a bundle and list its manifest
+//	glyph completion bash|zsh|fish         Print a shell completion script
+//	glyph help                             Print usage, or with --json the commands and flags
 //	glyph version                          Print version info
 //
+// Every command takes --json, to write one JSON envelope with its result or
+// error to stdout, and --quiet. The exit code tells usage (2), parse (3),
+// validation (4) and IO (5) errors apart.
+//
 // Smart auto-tabular is ON by default: lists of 3+ objects become @tab blocks.
 // Use --no-tabular to disable.
 //
@@ -16,11 +30,14 @@
 package main
 
 import (
-	"bufio"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,47 +51,100 @@ const (
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	cmd := os.Args[1]
-	var input io.Reader = os.Stdin
+// run runs the command in args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	args = c.parseGlobalFlags(args)
+	result, err := c.dispatch(args)
+	return c.finish(commandName(args), result, err)
+}
 
-	// Handle stream subcommands
-	if cmd == "stream" {
-		if len(os.Args) < 3 {
-			fmt.Fprintln(os.Stderr, "glyph stream: missing subcommand (decode, demo)")
-			os.Exit(1)
+// dispatch runs the command in args and returns its --json result.
+func (c *cli) dispatch(args []string) (any, error) {
+	if len(args) == 0 {
+		if !c.quiet && !c.json {
+			printUsage(c.stderr)
 		}
-		subcmd := os.Args[2]
-		switch subcmd {
+		return nil, failf(exitUsage, "missing command")
+	}
+	cmd, args := args[0], args[1:]
+
+	// Commands with subcommands
+	sub, subArgs := "", []string(nil)
+	if len(args) > 0 {
+		sub, subArgs = args[0], args[1:]
+	}
+	switch cmd {
+	case "stream":
+		switch sub {
 		case "decode":
-			if len(os.Args) > 3 && os.Args[3] != "-" {
-				f, err := os.Open(os.Args[3])
-				if err != nil {
-					fatal("open file: %v", err)
-				}
-				defer f.Close()
-				input = f
+			input, closeInput, err := c.open(fileArg(subArgs))
+			if err != nil {
+				return nil, err
 			}
-			cmdStreamDecode(input)
+			defer closeInput()
+			return c.cmdStreamDecode(input)
 		case "demo":
-			cmdStreamDemo()
-		default:
-			fmt.Fprintf(os.Stderr, "glyph stream: unknown subcommand: %s\n", subcmd)
-			os.Exit(1)
+			return c.cmdStreamRun(subArgs, true)
+		case "run":
+			return c.cmdStreamRun(subArgs, false)
+		}
+		return nil, failf(exitUsage, "stream: missing or unknown subcommand %q (decode, run, demo)", sub)
+	case "gen":
+		if sub != "prompt" {
+			return nil, failf(exitUsage, "gen: missing or unknown subcommand %q (prompt)", sub)
+		}
+		return c.cmdGenPrompt(subArgs)
+	case "bundle":
+		switch sub {
+		case "pack":
+			return c.cmdBundlePack(subArgs)
+		case "inspect":
+			return c.cmdBundleInspect(subArgs)
+		}
+		return nil, failf(exitUsage, "bundle: missing or unknown subcommand %q (pack, inspect)", sub)
+	case "completion":
+		if len(args) != 1 {
+			return nil, failf(exitUsage, "completion: want a shell (bash, zsh, fish)")
+		}
+		var buf strings.Builder
+		if err := writeCompletion(&buf, sub); err != nil {
+			return nil, failf(exitUsage, "completion: %w", err)
+		}
+		return c.text(buf.String())
+	case "eval":
+		return c.cmdEval(args)
+	case "version", "-v", "--version":
+		if c.json {
+			return versionResult{Version: libVersion, Spec: specVersion}, nil
+		}
+		return c.text(fmt.Sprintf("glyph %s (spec %s)\n", libVersion, specVersion))
+	case "help", "-h", "--help":
+		if c.json {
+			return manifest(), nil
 		}
-		return
+		printUsage(c.stderr)
+		return nil, nil
+	case "fmt-loose", "fmt", "to-json", "from-json":
+	default:
+		if !c.quiet && !c.json {
+			printUsage(c.stderr)
+		}
+		return nil, failf(exitUsage, "unknown command: %s", cmd)
 	}
 
-	// Parse flags and file argument for non-stream commands
+	// Parse flags and file argument for the conversion commands
 	noTabular := false
 	llmMode := false
 	compactMode := false
-	fileArg := ""
-	for _, arg := range os.Args[2:] {
+	autoCompact := false
+	docCount := 1
+	bundleFile, profile := "", ""
+	file := ""
+	for _, arg := range args {
 		switch {
 		case arg == "--no-tabular":
 			noTabular = true
@@ -82,64 +152,132 @@ func main() {
 			llmMode = true
 		case arg == "--compact":
 			compactMode = true
+		case arg == "--auto-compact":
+			autoCompact = true
+		case strings.HasPrefix(arg, "--docs="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--docs="))
+			if err != nil || n < 1 {
+				return nil, failf(exitUsage, "invalid --docs value: %s", arg)
+			}
+			docCount = n
+		case strings.HasPrefix(arg, "--bundle="):
+			bundleFile = strings.TrimPrefix(arg, "--bundle=")
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
 		case arg == "--auto-tabular":
 			// For backward compat (tabular is already default)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, failf(exitUsage, "%s: unknown flag: %s", cmd, arg)
 		default:
-			if !strings.HasPrefix(arg, "-") && arg != "-" {
-				fileArg = arg
-			}
+			file = arg
 		}
 	}
 
-	// If a file argument is provided, use it
-	if fileArg != "" {
-		f, err := os.Open(fileArg)
-		if err != nil {
-			fatal("open file: %v", err)
-		}
-		defer f.Close()
-		input = f
+	input, closeInput, err := c.open(file)
+	if err != nil {
+		return nil, err
 	}
+	defer closeInput()
 
 	switch cmd {
-	case "fmt-loose", "fmt":
-		cmdFmtLoose(input, noTabular, llmMode, compactMode)
 	case "to-json":
-		cmdToJSON(input)
+		return c.cmdToJSON(input)
 	case "from-json":
-		cmdFromJSON(input)
-	case "version", "-v", "--version":
-		fmt.Printf("glyph %s (spec %s)\n", libVersion, specVersion)
-	case "help", "-h", "--help":
-		printUsage()
-	default:
-		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
-		printUsage()
-		os.Exit(1)
+		return c.cmdFromJSON(input)
 	}
+	return c.cmdFmtLoose(input, noTabular, llmMode, compactMode, autoCompact, docCount, bundleFile, profile)
 }
 
-func printUsage() {
-	fmt.Fprint(os.Stderr, `glyph - GLYPH codec CLI tool (v2.4.0)
+// versionResult is the --json result of glyph version.
+type versionResult struct {
+	Version string `json:"version"`
+	Spec    string `json:"spec"`
+}
+
+// fileArg returns the first argument that is not a flag, or "".
+func fileArg(args []string) string {
+	for _, arg := range args {
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// open returns the input named by file: stdin for "" or "-".
+func (c *cli) open(file string) (io.Reader, func(), error) {
+	if file == "" || file == "-" {
+		return c.stdin, func() {}, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, failf(exitIO, "open file: %w", err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, usage)
+}
+
+// usage is the help text. Every command and flag in the commands table
+// appears in it.
+const usage = `glyph - GLYPH codec CLI tool (v2.4.0)
 
 Usage:
   glyph fmt-loose [options] [file]       Format JSON as canonical GLYPH-Loose
   glyph to-json [file]                   Convert GLYPH canonical to JSON  
   glyph from-json [file]                 Parse JSON to GLYPH-Loose canonical
   glyph stream decode [file]             Decode GS1-T frames and print
-  glyph stream demo                      Run the Agent Cockpit streaming demo
+  glyph stream run [--seed=N] FILE       Write the stream a scenario file describes: documents,
+                                         patches, UI events and pauses, as GLYPH
+  glyph stream demo [--seed=N]           Run the Agent Cockpit streaming demo (a built-in scenario)
+  glyph gen prompt --schema F --type T [--examples DIR]
+                                         Write a system-prompt section for type T
+                                         (examples: DIR/*.glyph and DIR/*.json)
+  glyph eval --schema F [--type T] [file]
+                                         Parse and validate model outputs, one per NDJSON
+                                         line, and report parse rate, repairs, validation
+                                         failures and tokens
+  glyph bundle pack --name N [--version V] [-o|--out FILE]
+                    [--schema NAME=FILE] [--dict NAME=FILE] [--pool NAME=FILE] [--profile NAME=SPEC]
+                                         Write a .glyphpkg bundle (dict and pool files: one
+                                         entry per line; SPEC: {tabular=t dict=NAME ...})
+  glyph bundle inspect FILE              Check a bundle's hash and list its manifest
+  glyph completion bash|zsh|fish         Print a shell completion script
+  glyph help                             Print usage; with --json, the commands, flags and
+                                         exit codes, for wrappers and editors
   glyph version                          Print version info
 
+Global options (any command):
+  --json              Write one JSON envelope to stdout, nothing to stderr:
+                      {"ok":true,"command":"fmt-loose","result":{"output":"..."}}
+                      {"ok":false,"command":"eval","error":{"kind":"parse","exit":3,"message":"..."}}
+  --quiet, -q         No banners or progress notes on stderr (errors still show)
+
 Options:
   --no-tabular        Disable auto-tabular (it's ON by default for 35-65% token savings)
   --llm               Use LLM-friendly mode (ASCII _ for null)
   --compact           Use schema header + compact keys (#0, #1, etc.) for max compression
+  --auto-compact      Use compact keys only when smaller (header counted once per --docs)
+  --docs=N            Number of documents sharing the header, for --auto-compact (default 1)
+  --bundle=FILE       fmt-loose: take the canonical options from a .glyphpkg bundle ...
+  --profile=NAME      ... using its profile NAME
+  --seed=N            stream run/demo: reproducible output (fixed clock, ids seeded with N, no delays)
 
 Smart auto-tabular: lists of 3+ homogeneous objects become compact @tab blocks.
 Non-eligible data (primitives, mixed lists, <3 items) uses standard format.
 
 If no file is given, reads from stdin.
 
+Exit codes:
+  0  success
+  1  other error
+  2  usage: unknown command or flag, missing or bad argument
+  3  parse: input, schema or frame that does not parse
+  4  validation: input that parses but fails a check (bundle hash, frame CRC, coercion)
+  5  io: a file that cannot be opened, read or written
+
 Examples:
   echo '{"b":1,"a":2}' | glyph fmt-loose
   # Output: {a=2 b=1}
@@ -169,25 +307,36 @@ Examples:
 
   cat data.json | glyph fmt-loose > data.glyph
   glyph to-json data.glyph > data.json
-`)
-}
+`
 
 // cmdFmtLoose: JSON -> canonical GLYPH-Loose
-func cmdFmtLoose(r io.Reader, noTabular, llmMode, compactMode bool) {
+func (c *cli) cmdFmtLoose(r io.Reader, noTabular, llmMode, compactMode, autoCompact bool, docCount int, bundleFile, profile string) (any, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
-		fatal("read input: %v", err)
+		return nil, failf(exitIO, "read input: %w", err)
 	}
 
 	gv, err := glyph.FromJSONLoose(data)
 	if err != nil {
-		fatal("parse JSON: %v", err)
+		return nil, failf(exitParse, "parse JSON: %w", err)
 	}
 
 	var opts glyph.LooseCanonOpts
-	if llmMode {
+	switch {
+	case bundleFile != "":
+		if profile == "" {
+			return nil, failf(exitUsage, "fmt-loose: --bundle needs --profile")
+		}
+		b, err := glyph.LoadBundle(bundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("load bundle: %w", err)
+		}
+		if opts, err = b.CanonOpts(profile); err != nil {
+			return nil, failf(exitUsage, "%w", err)
+		}
+	case llmMode:
 		opts = glyph.LLMLooseCanonOpts()
-	} else {
+	default:
 		opts = glyph.DefaultLooseCanonOpts()
 	}
 
@@ -196,7 +345,9 @@ func cmdFmtLoose(r io.Reader, noTabular, llmMode, compactMode bool) {
 	}
 
 	var canonical string
-	if compactMode {
+	if autoCompact && !compactMode {
+		canonical = glyph.CanonicalizeLooseAuto(gv, opts, docCount)
+	} else if compactMode {
 		// Build key dictionary and emit with schema header + compact keys
 		keyDict := glyph.BuildKeyDictFromValue(gv)
 		hash := stream.StateHashLoose(gv)
@@ -208,15 +359,16 @@ func cmdFmtLoose(r io.Reader, noTabular, llmMode, compactMode bool) {
 	} else {
 		canonical = glyph.CanonicalizeLooseWithOpts(gv, opts)
 	}
-	fmt.Println(canonical)
+	return c.text(canonical + "\n")
 }
 
 // cmdToJSON: GLYPH-Loose canonical -> JSON
 // Parses GLYPH canonical form (with optional @schema and @tab directives) and outputs JSON.
-func cmdToJSON(r io.Reader) {
+// Under --json the result is the JSON value itself.
+func (c *cli) cmdToJSON(r io.Reader) (any, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
-		fatal("read input: %v", err)
+		return nil, failf(exitIO, "read input: %w", err)
 	}
 
 	input := strings.TrimSpace(string(data))
@@ -227,30 +379,38 @@ func cmdToJSON(r io.Reader) {
 		// Fallback: try as JSON
 		gv, err = glyph.FromJSONLoose(data)
 		if err != nil {
-			fatal("parse input (neither GLYPH nor JSON): %v", err)
+			return nil, failf(exitParse, "parse input (neither GLYPH nor JSON): %w", err)
 		}
 	}
 
 	jsonData, err := glyph.ToJSONLoose(gv)
 	if err != nil {
-		fatal("convert to JSON: %v", err)
+		return nil, fmt.Errorf("convert to JSON: %w", err)
+	}
+	if c.json {
+		return json.RawMessage(jsonData), nil
 	}
 
 	// Pretty-print JSON
 	var pretty interface{}
 	json.Unmarshal(jsonData, &pretty)
 	out, _ := json.MarshalIndent(pretty, "", "  ")
-	fmt.Println(string(out))
+	return c.text(string(out) + "\n")
 }
 
 // cmdFromJSON: JSON -> GLYPH-Loose canonical (same as fmt-loose)
-func cmdFromJSON(r io.Reader) {
-	cmdFmtLoose(r, false, false, false)
+func (c *cli) cmdFromJSON(r io.Reader) (any, error) {
+	return c.cmdFmtLoose(r, false, false, false, false, 1, "", "")
 }
 
-// cmdStreamDecode: Decode GS1-T frames and print them
-func cmdStreamDecode(r io.Reader) {
+// cmdStreamDecode: Decode GS1-T frames and print them. A frame that
+// fails to parse or check is reported and skipped; the run then fails with
+// the exit code of the first.
+func (c *cli) cmdStreamDecode(r io.Reader) (any, error) {
 	reader := stream.NewReader(r)
+	var result decodeResult
+	var first error
+	firstCode := exitOK
 	frameNum := 0
 
 	for {
@@ -259,29 +419,87 @@ func cmdStreamDecode(r io.Reader) {
 			break
 		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "frame %d: error: %v\n", frameNum, err)
+			code := exitCode(err)
+			if code != exitParse && code != exitValidation {
+				return result, failf(exitIO, "frame %d: %w", frameNum+1, err)
+			}
+			if first == nil {
+				first, firstCode = fmt.Errorf("frame %d: %w", frameNum+1, err), code
+			}
+			result.Errors = append(result.Errors, fmt.Sprintf("frame %d: %v", frameNum+1, err))
+			if !c.json {
+				fmt.Fprintf(c.stderr, "frame %d: error: %v\n", frameNum+1, err)
+			}
 			continue
 		}
 
 		frameNum++
-		printFrame(frameNum, frame)
+		if c.json {
+			result.Frames = append(result.Frames, newFrameResult(frame))
+		} else {
+			printFrame(c.stdout, frameNum, frame)
+		}
+	}
+
+	c.note("\n--- %d frames decoded ---", frameNum)
+	var err error
+	if first != nil {
+		err = failf(firstCode, "%d frames failed to decode, first %w", len(result.Errors), first)
+	}
+	if c.json {
+		return result, err
 	}
+	return nil, err
+}
+
+// decodeResult is the --json result of glyph stream decode.
+type decodeResult struct {
+	Frames []frameResult `json:"frames"`
+	Errors []string      `json:"errors,omitempty"`
+}
+
+// frameResult is a decoded frame, with its whole payload.
+type frameResult struct {
+	SID     uint64 `json:"sid"`
+	Seq     uint64 `json:"seq"`
+	Kind    string `json:"kind"`
+	Len     int    `json:"len"`
+	CRC     string `json:"crc,omitempty"`
+	Base    string `json:"base,omitempty"`
+	Final   bool   `json:"final,omitempty"`
+	Payload string `json:"payload"`
+}
 
-	fmt.Fprintf(os.Stderr, "\n--- %d frames decoded ---\n", frameNum)
+func newFrameResult(f *stream.Frame) frameResult {
+	fr := frameResult{
+		SID:     f.SID,
+		Seq:     f.Seq,
+		Kind:    f.Kind.String(),
+		Len:     len(f.Payload),
+		Final:   f.Final,
+		Payload: string(f.Payload),
+	}
+	if f.CRC != nil {
+		fr.CRC = fmt.Sprintf("%08x", *f.CRC)
+	}
+	if f.Base != nil {
+		fr.Base = stream.HashToHex(*f.Base)
+	}
+	return fr
 }
 
-func printFrame(n int, f *stream.Frame) {
-	fmt.Printf("--- Frame %d ---\n", n)
-	fmt.Printf("  sid=%d seq=%d kind=%s len=%d\n", f.SID, f.Seq, f.Kind, len(f.Payload))
+func printFrame(w io.Writer, n int, f *stream.Frame) {
+	fmt.Fprintf(w, "--- Frame %d ---\n", n)
+	fmt.Fprintf(w, "  sid=%d seq=%d kind=%s len=%d\n", f.SID, f.Seq, f.Kind, len(f.Payload))
 
 	if f.CRC != nil {
-		fmt.Printf("  crc=%08x\n", *f.CRC)
+		fmt.Fprintf(w, "  crc=%08x\n", *f.CRC)
 	}
 	if f.Base != nil {
-		fmt.Printf("  base=%s\n", stream.HashToHex(*f.Base))
+		fmt.Fprintf(w, "  base=%s\n", stream.HashToHex(*f.Base))
 	}
 	if f.Final {
-		fmt.Printf("  final=true\n")
+		fmt.Fprintf(w, "  final=true\n")
 	}
 
 	// Print payload (truncated if long)
@@ -290,160 +508,550 @@ func printFrame(n int, f *stream.Frame) {
 		payload = payload[:200] + "..."
 	}
 	if len(payload) > 0 {
-		fmt.Printf("  payload: %s\n", payload)
+		fmt.Fprintf(w, "  payload: %s\n", payload)
 	}
 }
 
-// cmdStreamDemo: Run the Agent Cockpit streaming demo
-func cmdStreamDemo() {
-	w := stream.NewWriterWithCRC(os.Stdout)
-	bw := bufio.NewWriter(os.Stdout)
+// cmdGenPrompt writes the prompt section for a schema type, with the
+// examples found in a directory.
+func (c *cli) cmdGenPrompt(args []string) (any, error) {
+	var schemaFile, typeName, examplesDir string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--schema":
+			schemaFile = value
+		case "--type":
+			typeName = value
+		case "--examples":
+			examplesDir = value
+		default:
+			return nil, failf(exitUsage, "gen prompt: unknown argument: %s", args[i])
+		}
+	}
+	if schemaFile == "" || typeName == "" {
+		return nil, failf(exitUsage, "gen prompt: --schema and --type are required")
+	}
 
-	sid := uint64(1)
-	seq := uint64(0)
+	schema, err := readSchema(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	if schema.GetType(typeName) == nil {
+		return nil, failf(exitUsage, "gen prompt: unknown type: %s", typeName)
+	}
+	var examples []*glyph.GValue
+	if examplesDir != "" {
+		if examples, err = loadExamples(examplesDir, schema, typeName); err != nil {
+			return nil, err
+		}
+	}
+	out, err := schema.PromptSection(glyph.PromptOptions{Type: typeName, Examples: examples})
+	if err != nil {
+		return nil, err
+	}
+	return c.text(out)
+}
 
-	// Helper to write and flush
-	writeFrame := func(f *stream.Frame) {
-		if err := w.WriteFrame(f); err != nil {
-			fatal("write frame: %v", err)
+// readSchema reads and parses the schema in file.
+func readSchema(file string) (*glyph.Schema, error) {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, failf(exitIO, "read schema: %w", err)
+	}
+	schema, err := glyph.ParseSchema(string(text))
+	if err != nil {
+		return nil, failf(exitParse, "parse schema %s: %w", file, err)
+	}
+	return schema, nil
+}
+
+// loadExamples reads the examples in dir, in name order: .glyph files are
+// parsed under schema, .json files converted and coerced to typeName.
+func loadExamples(dir string, schema *glyph.Schema, typeName string) ([]*glyph.GValue, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, failf(exitIO, "read examples: %w", err)
+	}
+	var examples []*glyph.GValue
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		var v *glyph.GValue
+		switch filepath.Ext(e.Name()) {
+		case ".glyph":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			result, err := glyph.ParseWithSchema(string(data), schema)
+			if err == nil && result.HasErrors() {
+				err = &result.Errors[0]
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			v = result.Value
+		case ".json":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = glyph.FromJSONLoose(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if v, _, err = glyph.Coerce(v, schema, typeName); err != nil {
+				return nil, failf(exitValidation, "%s: %w", path, err)
+			}
+		default:
+			continue
 		}
-		bw.Flush()
-		os.Stdout.Sync()
+		examples = append(examples, v)
 	}
+	return examples, nil
+}
 
-	// 1. Initial doc snapshot
-	initialState := glyph.Struct("AgentState",
-		glyph.MapEntry{Key: "task", Value: glyph.Str("process_data")},
-		glyph.MapEntry{Key: "step", Value: glyph.Int(0)},
-		glyph.MapEntry{Key: "total_steps", Value: glyph.Int(10)},
-		glyph.MapEntry{Key: "items", Value: glyph.List()},
-	)
+// cmdBundlePack writes a bundle from files named on the command line.
+func (c *cli) cmdBundlePack(args []string) (any, error) {
+	b, out, err := packBundle(args)
+	if err != nil {
+		return nil, fmt.Errorf("bundle pack: %w", err)
+	}
+	if out == "" {
+		out = b.Name + glyph.BundleSuffix
+	}
+	text, err := b.Encode()
+	if err != nil {
+		return nil, failf(exitValidation, "bundle pack: %w", err)
+	}
+	if err := os.WriteFile(out, []byte(text), 0o644); err != nil {
+		return nil, failf(exitIO, "bundle pack: %w", err)
+	}
+	if c.json {
+		return packResult{Path: out, Hash: b.Hash}, nil
+	}
+	return c.text(fmt.Sprintf("%s %s\n", out, b.Hash))
+}
 
-	currentState := initialState
-	stateHash := stream.StateHashLoose(currentState)
+// packResult is the --json result of glyph bundle pack.
+type packResult struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
 
-	seq++
-	writeFrame(&stream.Frame{
-		Version: stream.Version,
-		SID:     sid,
-		Seq:     seq,
-		Kind:    stream.KindDoc,
-		Payload: []byte(glyph.Emit(currentState)),
-	})
+// packBundle builds the bundle args describe and returns it with the
+// output path (-o, "" if not given).
+func packBundle(args []string) (*glyph.Bundle, string, error) {
+	b := glyph.NewBundle("", "")
+	var out string
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch flag {
+		case "--name":
+			b.Name = value
+			continue
+		case "--version":
+			b.Version = value
+			continue
+		case "-o", "--out":
+			out = value
+			continue
+		case "--schema", "--dict", "--pool", "--profile":
+		default:
+			return nil, "", failf(exitUsage, "unknown argument: %s", args[i])
+		}
 
-	fmt.Fprintln(os.Stderr, "[demo] Sent initial state")
-	time.Sleep(500 * time.Millisecond)
-
-	// 2. Progress through steps with UI events and patches
-	for step := 1; step <= 10; step++ {
-		// UI: Progress event
-		seq++
-		writeFrame(&stream.Frame{
-			Version: stream.Version,
-			SID:     sid,
-			Seq:     seq,
-			Kind:    stream.KindUI,
-			Payload: stream.EmitProgress(float64(step)/10.0, fmt.Sprintf("Processing step %d of 10", step)),
-		})
-
-		time.Sleep(200 * time.Millisecond)
-
-		// UI: Log event
-		seq++
-		writeFrame(&stream.Frame{
-			Version: stream.Version,
-			SID:     sid,
-			Seq:     seq,
-			Kind:    stream.KindUI,
-			Payload: stream.EmitLog("info", fmt.Sprintf("Step %d: generated item_%d", step, step)),
-		})
-
-		// Patch: Update state
-		patchPayload := fmt.Sprintf(`@patch
-= .step %d
-+ .items {id=%d name="item_%d"}
-@end`, step, step, step)
-
-		seq++
-		writeFrame(&stream.Frame{
-			Version: stream.Version,
-			SID:     sid,
-			Seq:     seq,
-			Kind:    stream.KindPatch,
-			Payload: []byte(patchPayload),
-			Base:    &stateHash,
-		})
-
-		// Apply the patch to update state (real parse + apply, not simulated)
-		parsed, err := glyph.ParsePatch(patchPayload, nil)
-		if err != nil {
-			fatal("parse patch: %v", err)
+		name, src, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, "", failf(exitUsage, "%s wants NAME=SOURCE, got %q", flag, value)
+		}
+		if flag == "--profile" {
+			spec, err := glyph.ParseLoose(src, nil)
+			if err != nil {
+				return nil, "", failf(exitParse, "profile %s: %w", name, err)
+			}
+			if b.Profiles[name], err = glyph.DecodeBundleProfile(spec); err != nil {
+				return nil, "", failf(exitValidation, "profile %s: %w", name, err)
+			}
+			continue
 		}
-		currentState, err = glyph.ApplyPatch(currentState, parsed)
+		data, err := os.ReadFile(src)
 		if err != nil {
-			fatal("apply patch: %v", err)
+			return nil, "", failf(exitIO, "%w", err)
+		}
+		switch flag {
+		case "--schema":
+			if b.Schemas[name], err = glyph.ParseSchema(string(data)); err != nil {
+				return nil, "", failf(exitParse, "%s: %w", src, err)
+			}
+		case "--dict":
+			b.Dicts[name] = nonBlankLines(string(data))
+		case "--pool":
+			b.Pools[name] = nonBlankLines(string(data))
 		}
-		stateHash = stream.StateHashLoose(currentState)
+	}
+	if b.Name == "" {
+		return nil, "", failf(exitUsage, "--name is required")
+	}
+	return b, out, nil
+}
 
-		// UI: Metric every 3 steps
-		if step%3 == 0 {
-			seq++
-			writeFrame(&stream.Frame{
-				Version: stream.Version,
-				SID:     sid,
-				Seq:     seq,
-				Kind:    stream.KindUI,
-				Payload: stream.EmitMetric("items_processed", float64(step), "count"),
-			})
+// nonBlankLines returns the trimmed lines of s that are not empty.
+func nonBlankLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
 		}
+	}
+	return lines
+}
 
-		time.Sleep(300 * time.Millisecond)
+// cmdBundleInspect checks a bundle and prints its manifest.
+func (c *cli) cmdBundleInspect(args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, failf(exitUsage, "bundle inspect: want one bundle file")
+	}
+	b, err := glyph.LoadBundle(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if c.json {
+		return newInspectResult(b), nil
 	}
+	var buf strings.Builder
+	writeBundleManifest(&buf, b)
+	return c.text(buf.String())
+}
 
-	// 3. Final: Artifact reference
-	seq++
-	writeFrame(&stream.Frame{
-		Version: stream.Version,
-		SID:     sid,
-		Seq:     seq,
-		Kind:    stream.KindUI,
-		Payload: stream.EmitArtifact("application/json", "blob:sha256:abc123...", "results.json"),
-	})
+// inspectResult is the --json result of glyph bundle inspect.
+type inspectResult struct {
+	Name    string        `json:"name"`
+	Version string        `json:"version"`
+	Hash    string        `json:"hash"`
+	Entries []bundleEntry `json:"entries"`
+}
 
-	// 4. Completion log
-	seq++
-	writeFrame(&stream.Frame{
-		Version: stream.Version,
-		SID:     sid,
-		Seq:     seq,
-		Kind:    stream.KindUI,
-		Payload: stream.EmitLog("info", "Task completed successfully"),
-	})
+type bundleEntry struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
 
-	// 5. Final doc snapshot with final flag
-	finalState := glyph.Struct("AgentState",
-		glyph.MapEntry{Key: "task", Value: glyph.Str("process_data")},
-		glyph.MapEntry{Key: "step", Value: glyph.Int(10)},
-		glyph.MapEntry{Key: "total_steps", Value: glyph.Int(10)},
-		glyph.MapEntry{Key: "status", Value: glyph.Str("completed")},
-	)
-
-	seq++
-	writeFrame(&stream.Frame{
-		Version: stream.Version,
-		SID:     sid,
-		Seq:     seq,
-		Kind:    stream.KindDoc,
-		Payload: []byte(glyph.Emit(finalState)),
-		Final:   true,
-	})
+func newInspectResult(b *glyph.Bundle) inspectResult {
+	r := inspectResult{Name: b.Name, Version: b.Version, Hash: b.Hash, Entries: []bundleEntry{}}
+	for _, e := range b.Manifest() {
+		r.Entries = append(r.Entries, bundleEntry{Kind: e.Kind, Name: e.Name, Hash: e.Hash})
+	}
+	return r
+}
+
+// writeBundleManifest prints the name, version and hash of b, then one line
+// per entry.
+func writeBundleManifest(w io.Writer, b *glyph.Bundle) {
+	fmt.Fprintf(w, "%s %s %s\n", b.Name, b.Version, b.Hash)
+	for _, e := range b.Manifest() {
+		fmt.Fprintf(w, "  %-8s %-20s %s\n", e.Kind, e.Name, e.Hash)
+	}
+}
+
+// cmdEval measures model outputs against a schema. Samples that fail to
+// parse or validate are part of the report, not errors of the run.
+func (c *cli) cmdEval(args []string) (any, error) {
+	var schemaFile, typeName, file string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !strings.HasPrefix(name, "--") {
+			file = args[i]
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--schema":
+			schemaFile = value
+		case "--type":
+			typeName = value
+		default:
+			return nil, failf(exitUsage, "eval: unknown argument: %s", name)
+		}
+	}
+	if schemaFile == "" {
+		return nil, failf(exitUsage, "eval: --schema is required")
+	}
+
+	schema, err := readSchema(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	if typeName != "" && schema.GetType(typeName) == nil {
+		return nil, failf(exitUsage, "eval: unknown type: %s", typeName)
+	}
+
+	input, closeInput, err := c.open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	report, err := evalOutputs(input, glyph.EvalOptions{Schema: schema, Type: typeName})
+	if err != nil {
+		return nil, err
+	}
+	if c.json {
+		return newEvalResult(report), nil
+	}
+	var buf strings.Builder
+	writeEvalReport(&buf, report)
+	return c.text(buf.String())
+}
+
+// evalOutputs evaluates the model outputs in r, one per line: a JSON
+// string, or an object with an "output" string and an optional "id".
+// Samples without an id are named by line number.
+func evalOutputs(r io.Reader, opts glyph.EvalOptions) (*glyph.EvalReport, error) {
+	report := glyph.NewEvalReport()
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var line json.RawMessage
+		if err := dec.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, failf(exitParse, "sample %d: %w", n, err)
+		}
+		sample := struct {
+			ID     string  `json:"id"`
+			Output *string `json:"output"`
+		}{ID: strconv.Itoa(n)}
+		target := any(&sample)
+		if len(line) > 0 && line[0] == '"' {
+			target = &sample.Output
+		}
+		if err := json.Unmarshal(line, target); err != nil || sample.Output == nil {
+			return nil, failf(exitParse, "sample %d: want a string or an object with an \"output\" string", n)
+		}
+		report.Add(glyph.EvalOutput(sample.ID, *sample.Output, opts))
+	}
+	return report, nil
+}
 
-	fmt.Fprintln(os.Stderr, "[demo] Stream complete")
-	fmt.Fprintf(os.Stderr, "[demo] Sent %d frames\n", seq)
+// writeEvalReport writes one line per sample, then the totals.
+func writeEvalReport(w io.Writer, report *glyph.EvalReport) {
+	for _, s := range report.Samples {
+		status := "ok"
+		switch {
+		case !s.Parsed:
+			status = "unparsed"
+		case !s.Valid:
+			status = "invalid"
+		case len(s.Repairs) > 0:
+			status = "repaired"
+		}
+		fmt.Fprintf(w, "%-8s %-8s tokens=%d canonical=%d", s.ID, status, s.Tokens, s.CanonicalTokens)
+		if len(s.Repairs) > 0 {
+			fmt.Fprintf(w, " repairs=%s", strings.Join(s.Repairs, ","))
+		}
+		switch {
+		case s.ParseError != "":
+			fmt.Fprintf(w, "  %s", s.ParseError)
+		case len(s.Validation) > 0:
+			fmt.Fprintf(w, "  %s", s.Validation[0].Error())
+		}
+		fmt.Fprintln(w)
+	}
+
+	n := len(report.Samples)
+	percent := func(k int) float64 {
+		if n == 0 {
+			return 0
+		}
+		return 100 * float64(k) / float64(n)
+	}
+	fmt.Fprintf(w, "\nsamples: %d\n", n)
+	fmt.Fprintf(w, "parsed:  %d (%.1f%%)\n", report.Parsed, percent(report.Parsed))
+	fmt.Fprintf(w, "valid:   %d (%.1f%%)\n", report.Valid, percent(report.Valid))
+	fmt.Fprintf(w, "clean:   %d (%.1f%%) valid with no repairs\n", report.Clean, percent(report.Clean))
+	fmt.Fprintf(w, "tokens:  min %d, mean %.1f, max %d, total %d\n",
+		report.Tokens.Min, report.MeanTokens(), report.Tokens.Max, report.Tokens.Total)
+	for _, section := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"repairs", report.Repairs},
+		{"validation failures", report.Failures},
+	} {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", section.title)
+		for _, code := range glyph.RankCounts(section.counts) {
+			fmt.Fprintf(w, "  %-24s %d\n", code, section.counts[code])
+		}
+	}
+}
+
+// evalResult is the --json result of glyph eval.
+type evalResult struct {
+	Samples  []evalSampleResult `json:"samples"`
+	Parsed   int                `json:"parsed"`
+	Valid    int                `json:"valid"`
+	Clean    int                `json:"clean"`
+	Tokens   tokenResult        `json:"tokens"`
+	Repairs  map[string]int     `json:"repairs"`
+	Failures map[string]int     `json:"failures"`
+}
+
+type evalSampleResult struct {
+	ID              string             `json:"id"`
+	Parsed          bool               `json:"parsed"`
+	Valid           bool               `json:"valid"`
+	Tokens          int                `json:"tokens"`
+	CanonicalTokens int                `json:"canonical_tokens"`
+	Repairs         []string           `json:"repairs,omitempty"`
+	ParseError      string             `json:"parse_error,omitempty"`
+	Validation      []validationResult `json:"validation,omitempty"`
+}
+
+type validationResult struct {
+	Path    string `json:"path,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type tokenResult struct {
+	Min   int     `json:"min"`
+	Mean  float64 `json:"mean"`
+	Max   int     `json:"max"`
+	Total int     `json:"total"`
 }
 
-func fatal(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "glyph: "+format+"\n", args...)
-	os.Exit(1)
+func newEvalResult(report *glyph.EvalReport) evalResult {
+	r := evalResult{
+		Samples:  []evalSampleResult{},
+		Parsed:   report.Parsed,
+		Valid:    report.Valid,
+		Clean:    report.Clean,
+		Tokens:   tokenResult{report.Tokens.Min, report.MeanTokens(), report.Tokens.Max, report.Tokens.Total},
+		Repairs:  report.Repairs,
+		Failures: report.Failures,
+	}
+	for _, s := range report.Samples {
+		sr := evalSampleResult{
+			ID:              s.ID,
+			Parsed:          s.Parsed,
+			Valid:           s.Valid,
+			Tokens:          s.Tokens,
+			CanonicalTokens: s.CanonicalTokens,
+			Repairs:         s.Repairs,
+			ParseError:      s.ParseError,
+		}
+		for _, v := range s.Validation {
+			sr.Validation = append(sr.Validation, validationResult{Path: v.Path, Code: v.Code, Message: v.Message})
+		}
+		r.Samples = append(r.Samples, sr)
+	}
+	return r
 }
 
+// demoEnv is where a scenario run gets its time and randomness.
+type demoEnv struct {
+	now   func() time.Time    // Stamps frames and log events
+	sleep func(time.Duration) // Paces the demo
+	ids   *glyph.IDGen        // Generates the run id
+}
+
+// liveDemoEnv runs the demo on the real clock with random ids.
+func liveDemoEnv() demoEnv {
+	return demoEnv{now: time.Now, sleep: time.Sleep, ids: &glyph.IDGen{}}
+}
+
+// replayDemoEnv makes a run reproducible byte for byte: a clock that
+// starts at 2025-01-01 and moves only when read or slept on, and ids drawn
+// from a source seeded with seed.
+func replayDemoEnv(seed uint64) demoEnv {
+	clock := stream.NewStepClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+	var key [32]byte
+	binary.BigEndian.PutUint64(key[:], seed)
+	return demoEnv{
+		now:   clock.Now,
+		sleep: clock.Sleep,
+		ids:   &glyph.IDGen{Now: clock.Now, Rand: rand.NewChaCha8(key)},
+	}
+}
+
+// cmdStreamRun plays the scenario file in args (see scenario.go), or for
+// glyph stream demo the built-in Agent Cockpit scenario. Under --json the
+// stream is the output of the result, so it arrives all at once.
+func (c *cli) cmdStreamRun(args []string, demo bool) (any, error) {
+	env := liveDemoEnv()
+	cmd, file := "stream run", ""
+	if demo {
+		cmd = "stream demo"
+	}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--seed="):
+			seed, err := strconv.ParseUint(strings.TrimPrefix(arg, "--seed="), 10, 64)
+			if err != nil {
+				return nil, failf(exitUsage, "invalid --seed value: %s", arg)
+			}
+			env = replayDemoEnv(seed)
+		case !demo && file == "" && !strings.HasPrefix(arg, "-"):
+			file = arg
+		default:
+			return nil, failf(exitUsage, "%s: unknown argument: %s", cmd, arg)
+		}
+	}
+
+	text := cockpitScenario
+	if !demo {
+		if file == "" {
+			return nil, failf(exitUsage, "stream run: want a scenario file")
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, failf(exitIO, "read scenario: %w", err)
+		}
+		text = string(data)
+	}
+	s, err := parseScenario(text)
+	if err != nil {
+		return nil, err
+	}
+
+	out := c.stdout
+	var buf strings.Builder
+	if c.json {
+		out = &buf
+	}
+	prefix := "[run]"
+	if demo {
+		prefix = "[demo]"
+	}
+	frames, err := runScenario(out, s, env, func(msg string) {
+		c.note("%s %s", prefix, msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.note("%s Stream complete", prefix)
+	c.note("%s Sent %d frames", prefix, frames)
+	if c.json {
+		return streamRunResult{Frames: frames, Output: buf.String()}, nil
+	}
+	return nil, nil
+}
+
+// streamRunResult is the --json result of glyph stream run and demo.
+type streamRunResult struct {
+	Frames uint64 `json:"frames"`
+	Output string `json:"output"`
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update golden files with current output")
+
+// TestStreamDemoGolden checks the replayed demo stream byte for byte, so a
+// change anywhere in the streaming stack that alters the wire shows up here.
+func TestStreamDemoGolden(t *testing.T) {
+	golden := filepath.Join("testdata", "stream_demo_seed1.gs1")
+
+	s, err := parseScenario(cockpitScenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	frames, err := runScenario(&buf, s, replayDemoEnv(1), func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update-golden to create it): %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("demo stream differs from %s; run with -update-golden if the change is intended\ngot:\n%s", golden, buf.String())
+	}
+
+	// The recorded stream is also a valid one: every seq, crc and base
+	// checks out for a consumer.
+	store := stream.NewDocStore(nil)
+	r := stream.NewReader(bytes.NewReader(want))
+	read, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range read {
+		if err := store.Apply(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+	}
+	if uint64(len(read)) != frames {
+		t.Errorf("read %d frames, wrote %d", len(read), frames)
+	}
+}
+
+func TestLoadExamples(t *testing.T) {
+	schema, err := glyph.ParseSchema(`@schema{ Call struct{ name: str  n: int } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.glyph"), []byte(`Call{name=x n=1}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"name":"y","n":"2"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0o644)
+
+	examples, err := loadExamples(dir, schema, "Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := schema.PromptSection(glyph.PromptOptions{Type: "Call", Examples: examples})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Call{n=1 name=x}\nCall{n=2 name=y}\n") {
+		t.Errorf("examples not in prompt:\n%s", out)
+	}
+}
+
+func TestEvalOutputs(t *testing.T) {
+	schema, err := glyph.ParseSchema(`@schema{ Call struct{ name: str  n: int } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `"Call{name=a n=1}"
+{"id": "b", "output": "Call{name=b n=1,000"}
+{"output": "Call{name=c}"}
+`
+	report, err := evalOutputs(strings.NewReader(input), glyph.EvalOptions{Schema: schema, Type: "Call"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range report.Samples {
+		ids = append(ids, s.ID)
+	}
+	if got := strings.Join(ids, " "); got != "1 b 3" {
+		t.Errorf("ids = %s", got)
+	}
+	if report.Valid != 2 || report.Clean != 1 || report.Repairs["repaired_number"] != 1 {
+		t.Errorf("valid=%d clean=%d repairs=%v", report.Valid, report.Clean, report.Repairs)
+	}
+
+	var out bytes.Buffer
+	writeEvalReport(&out, report)
+	for _, want := range []string{"b        repaired", "valid:   2 (66.7%)", "required_field"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	if _, err := evalOutputs(strings.NewReader(`{"text": "x"}`), glyph.EvalOptions{}); err == nil {
+		t.Error("object without output accepted")
+	}
+}
+
+func TestPackBundle(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	schemaFile := write("calls.schema", `@schema{ Call struct{ id: str  tool: str } }`)
+	dictFile := write("tools.txt", "id\ntool\n\nargs\n")
+
+	b, out, err := packBundle([]string{
+		"--name", "agent", "--version=1.0", "-o", filepath.Join(dir, "agent.glyphpkg"),
+		"--schema", "calls=" + schemaFile,
+		"--dict=tools=" + dictFile,
+		"--profile", "wire={dict=tools tabular=t}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteFile(out); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := glyph.LoadBundle(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(loaded.Dicts["tools"], " ") != "id tool args" || loaded.Profiles["wire"].Dict != "tools" {
+		t.Errorf("loaded %+v", loaded)
+	}
+
+	var buf bytes.Buffer
+	writeBundleManifest(&buf, loaded)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "agent 1.0 "+b.Hash || !strings.HasPrefix(strings.TrimSpace(lines[3]), "schema   calls") {
+		t.Errorf("manifest:\n%s", buf.String())
+	}
+
+	b, _, err = packBundle([]string{"--name", "x", "--profile", "p={dict=missing}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Encode(); err == nil {
+		t.Error("expected an error for a profile naming a missing dict")
+	}
+}
+
+// TestCommandTable keeps the completion and help --json table in step with
+// the usage text.
+func TestCommandTable(t *testing.T) {
+	// A usage line names the command, or lists it: completion bash|zsh|fish.
+	listed := func(prefix, name string) bool {
+		for _, line := range strings.Split(usage, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "glyph "+prefix) && strings.Contains(line, name) {
+				return true
+			}
+		}
+		return false
+	}
+	var check func(prefix string, cmds []cliCommand)
+	check = func(prefix string, cmds []cliCommand) {
+		for _, c := range cmds {
+			if !listed(prefix, c.Name) {
+				t.Errorf("usage has no command %q", prefix+c.Name)
+			}
+			for _, f := range c.Flags {
+				if !strings.Contains(usage, f.Name) {
+					t.Errorf("usage has no flag %s of %q", f.Name, prefix+c.Name)
+				}
+			}
+			check(prefix+c.Name+" ", c.Subcommands)
+		}
+	}
+	check("", commands)
+
+	for _, f := range globalFlags {
+		if !strings.Contains(usage, f.Name) {
+			t.Errorf("usage has no global flag %s", f.Name)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		buf.Reset()
+		if err := writeCompletion(&buf, shell); err != nil {
+			t.Fatal(err)
+		}
+		for _, word := range []string{"fmt-loose", "inspect", "--auto-compact"} {
+			if !strings.Contains(buf.String(), word) {
+				t.Errorf("%s completion has no %s", shell, word)
+			}
+		}
+	}
+	if err := writeCompletion(&buf, "tcsh"); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	badSchema := write("bad.schema", "@schema{\n  Call struct{ id: }\n}")
+	b := glyph.NewBundle("agent", "1")
+	b.Pools["status"] = []string{"ok", "error"}
+	text, err := b.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := write("tampered.glyphpkg", strings.Replace(text, "error", "eror", 1))
+	scenarioFile := write("ping.glyph", `Scenario{steps=[Snapshot{} Note{msg=hi} UI{event=Ping{}}]}`)
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string // Substring of stdout
+		stderr string // Substring of stderr; "" means it must be empty
+	}{
+		{"ok", []string{"fmt-loose"}, `{"b":1,"a":2}`, exitOK, "{a=2 b=1}\n", ""},
+		{"json", []string{"fmt-loose", "--json"}, `{"b":1,"a":2}`, exitOK,
+			`{"ok":true,"command":"fmt-loose","result":{"output":"{a=2 b=1}\n"}}`, ""},
+		{"json to-json", []string{"--json", "to-json"}, `{a=1}`, exitOK, `"result":{"a":1}`, ""},
+		{"parse", []string{"fmt-loose"}, `{"a":`, exitParse, "", "glyph: parse JSON"},
+		{"unknown command", []string{"-q", "bogus"}, "", exitUsage, "", "glyph: unknown command: bogus\n"},
+		{"unknown flag", []string{"fmt", "--nope"}, "", exitUsage, "", "unknown flag: --nope"},
+		{"missing file", []string{"to-json", filepath.Join(dir, "missing.glyph")}, "", exitIO, "", "open file"},
+		{"bundle hash", []string{"--json", "bundle", "inspect", tampered}, "", exitValidation,
+			`"command":"bundle inspect","error":{"kind":"validation","exit":4`, ""},
+		{"schema position", []string{"--json", "gen", "prompt", "--schema", badSchema, "--type", "Call"}, "", exitParse,
+			`"kind":"parse","exit":3,`, ""},
+		{"usage json", []string{"--json", "eval"}, "", exitUsage, `"error":{"kind":"usage","exit":2,"message":"eval: --schema is required"}`, ""},
+		{"quiet decode", []string{"stream", "decode", "-q"}, "@frame{v=1 sid=1 seq=1 kind=doc len=5}\n{a=1}\ngarbage\n", exitParse,
+			"--- Frame 1 ---", "frame 2: error"},
+		{"stream run", []string{"stream", "run", "--seed=1", scenarioFile}, "", exitOK, "kind=ui len=6", "[run] hi\n"},
+		{"stream run missing", []string{"stream", "run", filepath.Join(dir, "missing.glyph")}, "", exitIO, "", "read scenario"},
+		{"help json", []string{"help", "--json"}, "", exitOK, `"exit_codes":{`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("exit %d, want %d\nstdout: %s\nstderr: %s", code, tt.code, stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("stdout lacks %q:\n%s", tt.stdout, stdout.String())
+			}
+			if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr %q, want %q", stderr.String(), tt.stderr)
+			}
+			if strings.Contains(stderr.String(), "frames decoded") {
+				t.Error("banner under -q")
+			}
+		})
+	}
+
+	// Under --json a parse error carries its position.
+	var stdout bytes.Buffer
+	run([]string{"--json", "gen", "prompt", "--schema", badSchema, "--type", "Call"}, nil, &stdout, io.Discard)
+	var env struct {
+		OK    bool
+		Error struct{ Line, Column int }
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.OK || env.Error.Line != 2 || env.Error.Column == 0 {
+		t.Errorf("envelope %s", stdout.String())
+	}
+}
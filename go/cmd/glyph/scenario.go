@@ -0,0 +1,558 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+// ============================================================
+// Stream Scenarios
+// ============================================================
+//
+// glyph stream run plays a scenario: a GLYPH file that says which frames
+// to write and how long to wait between them. Cockpit demos and load tests
+// can then be written without recompiling. The scenario is one struct:
+//
+//	Scenario{
+//	  sid=1
+//	  doc=AgentState{run="$run" step=0 items=[]}
+//	  steps=[
+//	    Snapshot{}
+//	    Repeat{times=10 steps=[
+//	      Progress{pct="$frac" msg="step $i of $n"}
+//	      Batch{steps=[Set{path=step value="$i"} Append{path=items value={id="$i"}}]}
+//	      Every{n=3 steps=[Metric{name=items value="$i" unit=count}]}
+//	      Sleep{ms=300}
+//	    ]}
+//	    Doc{final=t body=AgentState{run="$run" status=done}}
+//	  ]
+//	}
+//
+// doc is the document the patch steps change; it defaults to an empty map.
+// The steps:
+//
+//	Snapshot{}                       doc frame with the document
+//	Doc{body=V [final=t]}            doc frame with V, which becomes the document
+//	Set{path value}  Append{path value}  Delete{path}  Delta{path by}
+//	                                 a patch frame changing the document
+//	Batch{steps=[...]}               one patch frame for the changes inside
+//	Progress{pct msg}  Log{level msg [ts]}  Metric{name value [unit]}
+//	Artifact{mime ref name}          ui frames (Log is stamped if ts is absent)
+//	UI{event=V}                      ui frame with any other event
+//	Sleep{ms}                        pause
+//	Note{msg}                        progress note on stderr
+//	Repeat{times steps=[...]}        the steps, times times
+//	Every{n steps=[...]}             in a Repeat, the steps on every nth pass
+//
+// A string value "$name" is replaced by a variable; "$name" inside a longer
+// string by its text, and "$$" by "$". $run is an id made when the run
+// starts. In a Repeat, $i is the pass from 1, $n the number of passes and
+// $frac is $i/$n.
+
+// cockpitScenario is what glyph stream demo plays.
+//
+//go:embed scenarios/cockpit.glyph
+var cockpitScenario string
+
+// scenario is a parsed scenario file.
+type scenario struct {
+	sid   uint64
+	doc   *glyph.GValue
+	steps []scenarioStep
+}
+
+// scenarioStep is a step of a scenario, with the steps it contains.
+type scenarioStep struct {
+	kind  string // Its type name: Set, Repeat, ...
+	where string // Where it is in the file, for errors: steps[2].steps[0]
+	v     *glyph.GValue
+	steps []scenarioStep
+}
+
+// stepFields lists the fields each step takes; required ones are marked *.
+var stepFields = map[string][]string{
+	"Snapshot": {},
+	"Doc":      {"*body", "final"},
+	"Set":      {"*path", "*value"},
+	"Append":   {"*path", "*value"},
+	"Delete":   {"*path"},
+	"Delta":    {"*path", "*by"},
+	"Batch":    {"*steps"},
+	"Progress": {"*pct", "*msg"},
+	"Log":      {"*level", "*msg", "ts"},
+	"Metric":   {"*name", "*value", "unit"},
+	"Artifact": {"*mime", "*ref", "*name"},
+	"UI":       {"*event"},
+	"Sleep":    {"*ms"},
+	"Note":     {"*msg"},
+	"Repeat":   {"*times", "*steps"},
+	"Every":    {"*n", "*steps"},
+}
+
+// parseScenario parses and checks a scenario file.
+func parseScenario(text string) (*scenario, error) {
+	// Strict: a scenario is written by hand, so repairs would hide mistakes.
+	result, err := glyph.ParseWithOptions(text, glyph.ParseOptions{})
+	if err == nil && result.HasErrors() {
+		err = &result.Errors[0]
+	}
+	if err != nil {
+		return nil, failf(exitParse, "scenario: %w", err)
+	}
+	v := result.Value
+	sv, err := v.AsStruct()
+	if err != nil || sv.TypeName != "Scenario" {
+		return nil, failf(exitValidation, "scenario: want a Scenario{...} struct")
+	}
+
+	s := &scenario{sid: 1, doc: glyph.Map()}
+	for key, val := range v.Fields() {
+		switch key {
+		case "sid":
+			sid, err := val.AsInt()
+			if err != nil || sid < 1 {
+				return nil, failf(exitValidation, "scenario: sid must be a positive int")
+			}
+			s.sid = uint64(sid)
+		case "doc":
+			s.doc = val
+		case "steps":
+			if s.steps, err = parseSteps(val, "steps", 0); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, failf(exitValidation, "scenario: unknown field %s", key)
+		}
+	}
+	return s, nil
+}
+
+// parseSteps parses the list of steps v, found at where. depth counts the
+// Repeats around it.
+func parseSteps(v *glyph.GValue, where string, depth int) ([]scenarioStep, error) {
+	items, err := v.AsList()
+	if err != nil {
+		return nil, failf(exitValidation, "scenario: %s must be a list of steps", where)
+	}
+	steps := make([]scenarioStep, 0, len(items))
+	for i, item := range items {
+		at := fmt.Sprintf("%s[%d]", where, i)
+		sv, err := item.AsStruct()
+		if err != nil {
+			return nil, failf(exitValidation, "scenario: %s: want a step such as Set{...}", at)
+		}
+		fields, ok := stepFields[sv.TypeName]
+		if !ok {
+			return nil, failf(exitValidation, "scenario: %s: unknown step %s", at, sv.TypeName)
+		}
+		for _, f := range fields {
+			if name, required := strings.CutPrefix(f, "*"); required && item.Get(name) == nil {
+				return nil, failf(exitValidation, "scenario: %s: %s needs %s", at, sv.TypeName, name)
+			}
+		}
+		for key := range item.Fields() {
+			known := false
+			for _, f := range fields {
+				known = known || strings.TrimPrefix(f, "*") == key
+			}
+			if !known {
+				return nil, failf(exitValidation, "scenario: %s: %s has no field %s", at, sv.TypeName, key)
+			}
+		}
+		if sv.TypeName == "Every" && depth == 0 {
+			return nil, failf(exitValidation, "scenario: %s: Every outside a Repeat", at)
+		}
+
+		step := scenarioStep{kind: sv.TypeName, where: at, v: item}
+		if inner := item.Get("steps"); inner != nil {
+			d := depth
+			if sv.TypeName == "Repeat" {
+				d++
+			}
+			if step.steps, err = parseSteps(inner, at+".steps", d); err != nil {
+				return nil, err
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// scenarioVar is the value of a variable, and its text inside a string.
+type scenarioVar struct {
+	value *glyph.GValue
+	text  string
+}
+
+// scenarioRun is one play of a scenario.
+type scenarioRun struct {
+	s      *scenario
+	env    demoEnv
+	status func(string)
+	w      *stream.Writer
+	doc    *glyph.Document
+	ds     *glyph.DocumentStream
+	vars   map[string]scenarioVar
+}
+
+// runScenario writes the stream s describes to out and returns the number
+// of frames written. status receives the Note steps.
+func runScenario(out io.Writer, s *scenario, env demoEnv, status func(string)) (uint64, error) {
+	w := stream.NewWriterWithCRC(out)
+	w.SetClock(env.now)
+
+	run := env.ids.ULID()
+	id, _ := run.AsID()
+	r := &scenarioRun{
+		s:      s,
+		env:    env,
+		status: status,
+		w:      w,
+		vars:   map[string]scenarioVar{"run": {run, id.Value}},
+	}
+	body, err := r.expand(s.doc)
+	if err != nil {
+		return 0, failf(exitValidation, "scenario: doc: %w", err)
+	}
+	r.doc = &glyph.Document{Body: body}
+	r.ds = r.doc.AttachStream(w, s.sid)
+	defer r.ds.Detach()
+
+	if err := r.steps(s.steps); err != nil {
+		return 0, err
+	}
+	return w.NextSeq(s.sid) - 1, nil
+}
+
+func (r *scenarioRun) steps(steps []scenarioStep) error {
+	for _, step := range steps {
+		if err := r.step(step); err != nil {
+			if errors.As(err, new(*stepError)) {
+				return err
+			}
+			return &stepError{step: step, err: err}
+		}
+	}
+	return nil
+}
+
+// stepError is an error of the innermost step it happened in.
+type stepError struct {
+	step scenarioStep
+	err  error
+}
+
+func (e *stepError) Error() string {
+	return fmt.Sprintf("scenario: %s: %s: %v", e.step.where, e.step.kind, e.err)
+}
+
+func (e *stepError) Unwrap() error { return e.err }
+
+func (r *scenarioRun) step(step scenarioStep) error {
+	// Fields are expanded as they are read, so $i is the current pass.
+	field := func(name string) (*glyph.GValue, error) {
+		v := step.v.Get(name)
+		if v == nil {
+			return nil, nil
+		}
+		return r.expand(v)
+	}
+	str := func(name string) (string, error) {
+		v, err := field(name)
+		if err != nil || v == nil {
+			return "", err
+		}
+		s, err := v.AsStr()
+		if err != nil {
+			return "", failf(exitValidation, "%s must be a string", name)
+		}
+		return s, nil
+	}
+	num := func(name string) (float64, error) {
+		v, err := field(name)
+		if err != nil {
+			return 0, err
+		}
+		n, ok := v.Number()
+		if !ok {
+			return 0, failf(exitValidation, "%s must be a number", name)
+		}
+		return n, nil
+	}
+	ui := func(event *glyph.GValue) error {
+		return r.write(&stream.Frame{Kind: stream.KindUI, Payload: stream.EmitUI(event)})
+	}
+
+	switch step.kind {
+	case "Snapshot":
+		return r.ds.Snapshot()
+
+	case "Doc":
+		body, err := field("body")
+		if err != nil {
+			return err
+		}
+		final := false
+		if v := step.v.Get("final"); v != nil {
+			if final, err = v.AsBool(); err != nil {
+				return failf(exitValidation, "final must be a bool")
+			}
+		}
+		r.doc.Body = body
+		return r.write(&stream.Frame{Kind: stream.KindDoc, Payload: []byte(glyph.Emit(body)), Final: final})
+
+	case "Set", "Append", "Delete", "Delta":
+		path, err := str("path")
+		if err != nil {
+			return err
+		}
+		switch step.kind {
+		case "Set", "Append":
+			value, err := field("value")
+			if err != nil {
+				return err
+			}
+			if step.kind == "Set" {
+				return r.doc.Set(path, value)
+			}
+			return r.doc.Append(path, value)
+		case "Delete":
+			return r.doc.Delete(path)
+		}
+		by, err := num("by")
+		if err != nil {
+			return err
+		}
+		return r.doc.Delta(path, by)
+
+	case "Batch":
+		return r.ds.Batch(func() error { return r.steps(step.steps) })
+
+	case "Progress":
+		pct, err := num("pct")
+		if err != nil {
+			return err
+		}
+		msg, err := str("msg")
+		if err != nil {
+			return err
+		}
+		return ui(stream.Progress(pct, msg))
+
+	case "Log":
+		level, err := str("level")
+		if err != nil {
+			return err
+		}
+		msg, err := str("msg")
+		if err != nil {
+			return err
+		}
+		ts, err := field("ts")
+		if err != nil {
+			return err
+		}
+		if ts == nil {
+			return ui(stream.LogAt(level, msg, r.env.now()))
+		}
+		t, err := ts.AsTime()
+		if err != nil {
+			return failf(exitValidation, "ts must be a time")
+		}
+		return ui(stream.LogAt(level, msg, t))
+
+	case "Metric":
+		name, err := str("name")
+		if err != nil {
+			return err
+		}
+		value, err := num("value")
+		if err != nil {
+			return err
+		}
+		unit, err := str("unit")
+		if err != nil {
+			return err
+		}
+		return ui(stream.Metric(name, value, unit))
+
+	case "Artifact":
+		var parts [3]string
+		for i, name := range []string{"mime", "ref", "name"} {
+			var err error
+			if parts[i], err = str(name); err != nil {
+				return err
+			}
+		}
+		return ui(stream.Artifact(parts[0], parts[1], parts[2]))
+
+	case "UI":
+		event, err := field("event")
+		if err != nil {
+			return err
+		}
+		return ui(event)
+
+	case "Sleep":
+		ms, err := num("ms")
+		if err != nil {
+			return err
+		}
+		r.env.sleep(time.Duration(ms * float64(time.Millisecond)))
+		return nil
+
+	case "Note":
+		msg, err := str("msg")
+		if err != nil {
+			return err
+		}
+		r.status(msg)
+		return nil
+
+	case "Repeat":
+		times, err := num("times")
+		if err != nil {
+			return err
+		}
+		saved := r.vars
+		defer func() { r.vars = saved }()
+		n := int(times)
+		for i := 1; i <= n; i++ {
+			r.vars = make(map[string]scenarioVar, len(saved)+3)
+			for k, v := range saved {
+				r.vars[k] = v
+			}
+			frac := float64(i) / float64(n)
+			r.vars["i"] = scenarioVar{glyph.Int(int64(i)), strconv.Itoa(i)}
+			r.vars["n"] = scenarioVar{glyph.Int(int64(n)), strconv.Itoa(n)}
+			r.vars["frac"] = scenarioVar{glyph.Float(frac), strconv.FormatFloat(frac, 'f', -1, 64)}
+			if err := r.steps(step.steps); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "Every":
+		n, err := num("n")
+		if err != nil {
+			return err
+		}
+		i, _ := r.vars["i"].value.AsInt()
+		if n >= 1 && i%int64(n) == 0 {
+			return r.steps(step.steps)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown step")
+}
+
+// write writes f on the scenario's sid, after the frames already there.
+func (r *scenarioRun) write(f *stream.Frame) error {
+	f.Version, f.SID, f.Seq = stream.Version, r.s.sid, r.w.NextSeq(r.s.sid)
+	if err := r.w.WriteFrame(f); err != nil {
+		return fmt.Errorf("write frame: %w", err)
+	}
+	return nil
+}
+
+// expand returns v with its variables replaced.
+func (r *scenarioRun) expand(v *glyph.GValue) (*glyph.GValue, error) {
+	switch v.Type() {
+	case glyph.TypeStr:
+		s, _ := v.AsStr()
+		if !strings.Contains(s, "$") {
+			return v, nil
+		}
+		if name, ok := strings.CutPrefix(s, "$"); ok && isVarName(name) {
+			x, ok := r.vars[name]
+			if !ok {
+				return nil, failf(exitValidation, "unknown variable $%s", name)
+			}
+			return x.value, nil
+		}
+		text, err := r.expandText(s)
+		if err != nil {
+			return nil, err
+		}
+		return glyph.Str(text), nil
+	case glyph.TypeList:
+		items, _ := v.AsList()
+		out := make([]*glyph.GValue, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = r.expand(item); err != nil {
+				return nil, err
+			}
+		}
+		return glyph.List(out...), nil
+	case glyph.TypeMap, glyph.TypeStruct:
+		var entries []glyph.MapEntry
+		for key, val := range v.Fields() {
+			x, err := r.expand(val)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, glyph.MapEntry{Key: key, Value: x})
+		}
+		if v.Type() == glyph.TypeMap {
+			return glyph.Map(entries...), nil
+		}
+		sv, _ := v.AsStruct()
+		return glyph.Struct(sv.TypeName, entries...), nil
+	}
+	return v, nil
+}
+
+// expandText replaces the variables inside s by their text.
+func (r *scenarioRun) expandText(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+		if strings.HasPrefix(s, "$") {
+			b.WriteByte('$')
+			s = s[1:]
+			continue
+		}
+		n := 0
+		for n < len(s) && (s[n] >= 'a' && s[n] <= 'z' || s[n] >= 'A' && s[n] <= 'Z' || s[n] == '_') {
+			n++
+		}
+		if n == 0 {
+			b.WriteByte('$') // A lone $, as in "$5"
+			continue
+		}
+		x, ok := r.vars[s[:n]]
+		if !ok {
+			return "", failf(exitValidation, "unknown variable $%s", s[:n])
+		}
+		b.WriteString(x.text)
+		s = s[n:]
+	}
+}
+
+// isVarName reports whether s is a variable name: letters and underscores.
+func isVarName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/Neumenon/glyph/stream"
+)
+
+func TestRunScenario(t *testing.T) {
+	s, err := parseScenario(`Scenario{
+  sid=7
+  doc={run="$run" n=0 tags=[] old=1}
+  steps=[
+    Snapshot{}
+    Repeat{times=2 steps=[
+      Repeat{times=3 steps=[Delta{path=n by=1}]}
+      Append{path=tags value="tag $i of $n, $$5"}
+      Every{n=2 steps=[UI{event=Custom{at="$i" share="$frac"}}]}
+    ]}
+    Delete{path=old}
+    Note{msg="done with $run"}
+    Doc{final=t body={n=-1}}
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	var notes []string
+	frames, err := runScenario(&buf, s, replayDemoEnv(1), func(msg string) { notes = append(notes, msg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Snapshot, 2 x (3 deltas + append), one UI event, delete, doc.
+	if frames != 12 {
+		t.Errorf("wrote %d frames, want 12", frames)
+	}
+	out := buf.String()
+	for _, want := range []string{`"tag 1 of 2, $5"`, "Custom{at=2 share=1.0}", "sid=7 seq=12 kind=doc"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stream lacks %s:\n%s", want, out)
+		}
+	}
+	if len(notes) != 1 || !strings.HasPrefix(notes[0], "done with 01") {
+		t.Errorf("notes %q", notes)
+	}
+
+	// Every patch applies to the state before it.
+	store := stream.NewDocStore(nil)
+	read, err := stream.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range read[:len(read)-1] {
+		if err := store.Apply(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+	}
+}
+
+func TestScenarioErrors(t *testing.T) {
+	tests := []struct {
+		scenario string
+		code     int
+		want     string
+	}{
+		{`Scenario{steps=[Snapshot{}`, exitParse, "scenario:"},
+		{`{steps=[]}`, exitValidation, "want a Scenario"},
+		{`Scenario{steps=[Jump{}]}`, exitValidation, "steps[0]: unknown step Jump"},
+		{`Scenario{steps=[Set{path=a}]}`, exitValidation, "steps[0]: Set needs value"},
+		{`Scenario{steps=[Sleep{ms=1 s=2}]}`, exitValidation, "Sleep has no field s"},
+		{`Scenario{steps=[Every{n=2 steps=[]}]}`, exitValidation, "Every outside a Repeat"},
+		{`Scenario{steps=[Repeat{times=2 steps=[Sleep{ms="$i$x"}]}]}`, exitValidation,
+			"steps[0].steps[0]: Sleep: unknown variable $x"},
+		{`Scenario{steps=[Progress{pct=half msg=x}]}`, exitValidation, "steps[0]: Progress: pct must be a number"},
+		{`Scenario{doc={s=x} steps=[Delta{path=s by=1}]}`, exitError, "steps[0]: Delta:"},
+	}
+	for _, tt := range tests {
+		s, err := parseScenario(tt.scenario)
+		if err == nil {
+			_, err = runScenario(&bytes.Buffer{}, s, replayDemoEnv(1), func(string) {})
+		}
+		if err == nil || exitCode(err) != tt.code || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v (exit %d), want %q (exit %d)", tt.scenario, err, exitCode(err), tt.want, tt.code)
+		}
+	}
+
+	// A failing write to stdout is an I/O error.
+	s, _ := parseScenario(`Scenario{steps=[Snapshot{}]}`)
+	if _, err := runScenario(failingWriter{}, s, replayDemoEnv(1), func(string) {}); exitCode(err) != exitIO {
+		t.Errorf("write error %v, exit %d", err, exitCode(err))
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: "/dev/stdout", Err: errors.New("broken pipe")}
+}
@@ -0,0 +1,357 @@
+// Package agentserver serves GLYPH documents to agents and the UIs watching
+// them: one document per session, changed by patches, with every change
+// broadcast as GS1 frames. It is a reference integration, small enough to
+// read and copy, and usable as is:
+//
+//	srv := agentserver.New(agentserver.Options{Schema: schema, RootType: "AgentState"})
+//	http.Handle("/sessions/", http.StripPrefix("/sessions", srv))
+//
+// Routes, relative to where the Server is mounted:
+//
+//	GET  /{session}         the document, as GLYPH-T
+//	POST /{session}/patch   apply the @patch in the body
+//	GET  /{session}/stream  GS1-T frames: a doc snapshot, then one patch
+//	                        frame per change
+//
+// The stream is served over WebSocket, one GS1-T frame per text message,
+// when the request asks to upgrade, and as a streamed response otherwise.
+// Each connection numbers its frames from seq 1 on sid 1, and every patch
+// frame carries the base hash of the state it applies to
+// (stream.StateHashLoose), so clients can check they missed nothing.
+// WebSocket clients may send patch frames of their own; a frame that is
+// rejected is answered with an err frame.
+//
+// A patch may name the state it was made against, in the Glyph-Base header
+// of a POST or the base of a patch frame; it is rejected if the document
+// has moved on. With a Schema, a patch whose result does not validate is
+// rejected and the document left as it was. GET and POST answers carry the
+// hash of the current state in Glyph-Base.
+//
+// Only a POST creates a session, and only if Options.Open allows it; GET and
+// stream requests for a session that does not exist are answered 404.
+// WebSocket upgrades from another origin are refused (see
+// Options.CheckOrigin).
+package agentserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+// BaseHeader carries the hash of a document state, as sha256:<hex>.
+const BaseHeader = "Glyph-Base"
+
+// streamSID is the sid of every frame the server writes.
+const streamSID = 1
+
+// Options configures a Server.
+type Options struct {
+	// Schema, if set, parses patches and validates every new document.
+	Schema *glyph.Schema
+	// RootType is the type documents are validated as. Empty validates
+	// them by their own type name.
+	RootType string
+	// NewDocument returns the document a new session starts with. The
+	// default is an empty map.
+	NewDocument func(session string) *glyph.GValue
+	// Buffer is how many frames a subscriber may fall behind before it is
+	// disconnected (default 64).
+	Buffer int
+	// MaxPatchSize limits the size of a patch body or message (default
+	// 1 MiB).
+	MaxPatchSize int
+	// MaxSessions limits how many sessions are kept (default 1024). Opening
+	// one more drops the session used least recently that nobody is
+	// watching; if every session is watched, the new one is refused.
+	MaxSessions int
+	// Open, if set, reports whether a POST may create the session id. The
+	// default lets any POST create one.
+	Open func(id string) bool
+	// CheckOrigin reports whether a WebSocket upgrade may proceed. The
+	// default accepts requests without an Origin header and those whose
+	// Origin host is the request's Host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Errors for a session that cannot be handed out.
+var (
+	errNoSession       = errors.New("agentserver: no such session")
+	errTooManySessions = errors.New("agentserver: too many sessions")
+)
+
+// Server holds the sessions and serves them over HTTP. Safe for concurrent
+// use.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	clock    uint64 // Counts session lookups, to order them by last use
+}
+
+// New creates a server with no sessions.
+func New(opts Options) *Server {
+	if opts.NewDocument == nil {
+		opts.NewDocument = func(string) *glyph.GValue { return glyph.Map() }
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	if opts.MaxPatchSize <= 0 {
+		opts.MaxPatchSize = 1 << 20
+	}
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = 1024
+	}
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+	s := &Server{opts: opts, sessions: make(map[string]*session)}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /{session}", s.handleGet)
+	s.mux.HandleFunc("POST /{session}/patch", s.handlePatch)
+	s.mux.HandleFunc("GET /{session}/stream", s.handleStream)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Get returns the document of a session, creating the session if needed. It
+// returns nil if the session cannot be created (see Options.MaxSessions).
+func (s *Server) Get(id string) *glyph.GValue {
+	sess, err := s.session(id, true)
+	if err != nil {
+		return nil
+	}
+	body, _ := sess.snapshot()
+	return body
+}
+
+// Apply applies p to the document of a session, as a POST would; base, if
+// not nil, is the state hash p was made against. Agents running in the same
+// process use it to publish their own changes.
+func (s *Server) Apply(id string, p *glyph.Patch, base *[32]byte) error {
+	sess, err := s.session(id, true)
+	if err != nil {
+		return err
+	}
+	return sess.apply(p, base)
+}
+
+// session returns the session id, creating it if create is set.
+func (s *Server) session(id string, create bool) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if sess == nil {
+		if !create {
+			return nil, errNoSession
+		}
+		if len(s.sessions) >= s.opts.MaxSessions && !s.evict() {
+			return nil, errTooManySessions
+		}
+		sess = newSession(s, s.opts.NewDocument(id))
+		s.sessions[id] = sess
+	}
+	s.clock++
+	sess.used = s.clock
+	return sess, nil
+}
+
+// evict drops the session used least recently among those nobody watches,
+// and reports whether there was one. The caller holds mu.
+func (s *Server) evict() bool {
+	var oldest *session
+	var oldestID string
+	for id, sess := range s.sessions {
+		if (oldest == nil || sess.used < oldest.used) && !sess.watched() {
+			oldest, oldestID = sess, id
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(s.sessions, oldestID)
+	oldest.close()
+	return true
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.session(r.PathValue("session"), false)
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	body, hash := sess.snapshot()
+	w.Header().Set(BaseHeader, "sha256:"+stream.HashToHex(hash))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, glyph.Emit(body)+"\n")
+}
+
+func (s *Server) handlePatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("session")
+	sess, err := s.session(id, s.opts.Open == nil || s.opts.Open(id))
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	text, err := io.ReadAll(io.LimitReader(r.Body, int64(s.opts.MaxPatchSize)+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(text) > s.opts.MaxPatchSize {
+		http.Error(w, "patch too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var base *[32]byte
+	if h := r.Header.Get(BaseHeader); h != "" {
+		hash, ok := stream.HexToHash(strings.TrimPrefix(h, "sha256:"))
+		if !ok {
+			http.Error(w, "invalid "+BaseHeader+" header", http.StatusBadRequest)
+			return
+		}
+		base = &hash
+	}
+
+	p, err := glyph.ParsePatch(string(text), s.opts.Schema)
+	if err == nil {
+		err = sess.apply(p, base)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	_, hash := sess.snapshot()
+	w.Header().Set(BaseHeader, "sha256:"+stream.HashToHex(hash))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// statusFor maps a rejected patch to an HTTP status.
+func statusFor(err error) int {
+	var baseErr *stream.BaseMismatchError
+	var payloadErr *stream.PayloadError
+	switch {
+	case errors.As(err, &baseErr):
+		return http.StatusConflict
+	case errors.As(err, &payloadErr), errors.Is(err, errApply):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errNoSession):
+		return http.StatusNotFound
+	case errors.Is(err, errTooManySessions):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.session(r.PathValue("session"), false)
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	if isWebSocketRequest(r) {
+		if !s.opts.CheckOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		s.serveWebSocket(w, r, sess)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	sub := sess.subscribe()
+	defer sess.unsubscribe(sub)
+	sw := stream.NewWriter(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case f, ok := <-sub.frames:
+			if !ok {
+				return
+			}
+			f.SID, f.Seq = streamSID, sw.NextSeq(streamSID)
+			if err := sw.WriteFrame(f); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request, sess *session) {
+	conn, err := upgradeWebSocket(w, r, s.opts.MaxPatchSize+stream.MaxHeaderSize)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	sub := sess.subscribe()
+
+	// Writer: each frame is one text message.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var buf bytes.Buffer
+		sw := stream.NewWriter(&buf)
+		for f := range sub.frames {
+			buf.Reset()
+			f.SID, f.Seq = streamSID, sw.NextSeq(streamSID)
+			if sw.WriteFrame(f) != nil || conn.writeMessage(wsText, buf.Bytes()) != nil {
+				break
+			}
+		}
+		conn.conn.Close() // unblocks the reader if the writer stopped first
+	}()
+
+	// Reader: patch frames from the client.
+	for {
+		_, msg, err := conn.readMessage()
+		if err != nil {
+			break
+		}
+		frames, err := stream.NewReader(bytes.NewReader(msg)).ReadAll()
+		if err != nil {
+			sess.reply(sub, stream.ErrorEventFor(err, 0, 0))
+			continue
+		}
+		for _, f := range frames {
+			if f.Kind != stream.KindPatch {
+				continue
+			}
+			if err := s.applyFrame(sess, f); err != nil {
+				sess.reply(sub, stream.ErrorEventFor(err, f.SID, f.Seq))
+			}
+		}
+	}
+	sess.unsubscribe(sub)
+	<-done
+}
+
+// applyFrame applies the patch carried by a client's patch frame.
+func (s *Server) applyFrame(sess *session, f *stream.Frame) error {
+	if len(f.Payload) > s.opts.MaxPatchSize {
+		return fmt.Errorf("agentserver: patch exceeds %d bytes", s.opts.MaxPatchSize)
+	}
+	p, err := glyph.ParsePatch(string(f.Payload), s.opts.Schema)
+	if err != nil {
+		return &stream.PayloadError{SID: f.SID, Seq: f.Seq, Schema: f.Schema, Errors: []string{err.Error()}}
+	}
+	return sess.apply(p, f.Base)
+}
@@ -0,0 +1,354 @@
+package agentserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+func testSchema(t *testing.T) *glyph.Schema {
+	t.Helper()
+	schema, err := glyph.ParseSchema(`@schema{
+		Task struct{
+			step: int [min=0] @k(st)
+			status: str [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	srv := New(Options{
+		Schema:   testSchema(t),
+		RootType: "Task",
+		NewDocument: func(string) *glyph.GValue {
+			return glyph.Struct("Task", glyph.MapEntry{Key: "step", Value: glyph.Int(0)})
+		},
+	})
+	srv.Get("s1") // Requests other than POST do not create sessions
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func postPatch(t *testing.T, url, patch, base string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(patch))
+	if base != "" {
+		req.Header.Set(BaseHeader, base)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestServer_HTTP(t *testing.T) {
+	srv, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := strings.TrimSpace(string(body)); got != "Task{step=0}" {
+		t.Errorf("GET = %q", got)
+	}
+	base := resp.Header.Get(BaseHeader)
+
+	if resp := postPatch(t, ts.URL+"/s1/patch", "@patch\n= step 1\n@end", base); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("patch: %s", resp.Status)
+	}
+	if got := glyph.Emit(srv.Get("s1")); got != "Task{step=1}" {
+		t.Errorf("after patch: %s", got)
+	}
+
+	for _, tc := range []struct {
+		name, patch, base string
+		status            int
+	}{
+		{"stale base", "@patch\n= step 2\n@end", base, http.StatusConflict},
+		{"invalid result", "@patch\n= step -1\n@end", "", http.StatusUnprocessableEntity},
+		{"unparsable", "= step", "", http.StatusBadRequest},
+		{"bad base header", "@patch\n= step 2\n@end", "sha256:zz", http.StatusBadRequest},
+	} {
+		if resp := postPatch(t, ts.URL+"/s1/patch", tc.patch, tc.base); resp.StatusCode != tc.status {
+			t.Errorf("%s: %s, want %d", tc.name, resp.Status, tc.status)
+		}
+	}
+	if got := glyph.Emit(srv.Get("s1")); got != "Task{step=1}" {
+		t.Errorf("after rejected patches: %s", got)
+	}
+}
+
+func TestServer_StreamHTTP(t *testing.T) {
+	srv, ts := newTestServer(t)
+	resp, err := http.Get(ts.URL + "/s1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := stream.NewReader(resp.Body)
+
+	store := stream.NewDocStore(nil)
+	next := func() *stream.Frame {
+		t.Helper()
+		f, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Apply(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+		return f
+	}
+	if f := next(); f.Kind != stream.KindDoc || f.Seq != 1 {
+		t.Fatalf("first frame: %+v", f)
+	}
+
+	p := glyph.NewPatch(glyph.RefID{}, "")
+	p.Set("status", glyph.Str("running"))
+	if err := srv.Apply("s1", p, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f := next(); f.Kind != stream.KindPatch || f.Base == nil {
+		t.Fatalf("patch frame: %+v", f)
+	}
+	if got, _ := store.Get(1); !glyph.EqualLoose(got, srv.Get("s1")) {
+		t.Errorf("client state %s, server %s", glyph.Emit(got), glyph.Emit(srv.Get("s1")))
+	}
+}
+
+// wsClient is a bare WebSocket client for the tests.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, url string) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	io.WriteString(conn, "GET /s1/stream HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake: %s %v", resp.Status, resp.Header)
+	}
+	return &wsClient{conn: conn, br: br}
+}
+
+// send writes one masked text message.
+func (c *wsClient) send(msg []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | wsText}
+	if len(msg) < 126 {
+		frame = append(frame, 0x80|byte(len(msg)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(msg)))
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range msg {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+// frame reads one message and decodes the GS1-T frame in it.
+func (c *wsClient) frame(t *testing.T) *stream.Frame {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(head[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(c.br, msg); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.NewReader(bytes.NewReader(msg)).Next()
+	if err != nil {
+		t.Fatalf("message %q: %v", msg, err)
+	}
+	return f
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	srv, ts := newTestServer(t)
+	a, b := dialWS(t, ts.URL), dialWS(t, ts.URL)
+	snap := a.frame(t)
+	if snap.Kind != stream.KindDoc {
+		t.Fatalf("first frame: %+v", snap)
+	}
+	b.frame(t)
+
+	// A patch from one client reaches both.
+	base := stream.StateHashLoose(srv.Get("s1"))
+	var msg bytes.Buffer
+	stream.NewWriter(&msg).WritePatch(1, 1, []byte("@patch\n= step 3\n@end"), &base)
+	a.send(msg.Bytes())
+	for _, c := range []*wsClient{a, b} {
+		if f := c.frame(t); f.Kind != stream.KindPatch || f.Seq != 2 || *f.Base != base {
+			t.Errorf("broadcast: %+v", f)
+		}
+	}
+
+	// A stale base is answered with an err frame to the sender alone.
+	a.send(msg.Bytes())
+	f := a.frame(t)
+	ev, err := stream.ParseErrorEvent(f.Payload)
+	if f.Kind != stream.KindErr || err != nil || ev.Code != stream.ErrCodeBaseMismatch {
+		t.Errorf("reply: %+v %v %v", f, ev, err)
+	}
+	if got := glyph.Emit(srv.Get("s1")); got != "Task{step=3}" {
+		t.Errorf("document: %s", got)
+	}
+}
+
+func TestServer_WireKeyPatch(t *testing.T) {
+	srv, ts := newTestServer(t)
+
+	// EmitPatch writes the wire key and names the root type.
+	p := glyph.NewPatch(glyph.RefID{}, "")
+	p.TargetType = "Task"
+	p.Set("step", glyph.Int(5))
+	emitted, err := glyph.EmitPatch(p, testSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(emitted, "= st 5") {
+		t.Fatalf("expected a wire key in:\n%s", emitted)
+	}
+
+	for i, text := range []string{emitted, "@patch @keys=wire\n= st 6\n@end"} {
+		if resp := postPatch(t, ts.URL+"/s1/patch", text, ""); resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("patch %d: %s", i, resp.Status)
+		}
+	}
+	if got := glyph.Emit(srv.Get("s1")); got != "Task{step=6}" {
+		t.Errorf("after patches: %s", got)
+	}
+}
+
+func TestServer_MaxSessions(t *testing.T) {
+	srv := New(Options{MaxSessions: 2})
+	srv.Get("a")
+	srv.Get("b")
+	a, _ := srv.session("a", false)
+	sub := a.subscribe()
+	srv.Get("b") // b is now the most recent, but a is watched
+	srv.Get("c") // drops b
+
+	has := func(id string) bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return srv.sessions[id] != nil
+	}
+	if !has("a") || has("b") || !has("c") {
+		t.Fatalf("sessions after eviction: a=%v b=%v c=%v", has("a"), has("b"), has("c"))
+	}
+
+	c, _ := srv.session("c", false)
+	c.subscribe()
+	if srv.Get("d") != nil || has("d") {
+		t.Error("session created with every session watched")
+	}
+	if err := srv.Apply("d", glyph.NewPatch(glyph.RefID{}, ""), nil); !errors.Is(err, errTooManySessions) {
+		t.Errorf("Apply = %v", err)
+	}
+	<-sub.frames // snapshot
+	select {
+	case _, ok := <-sub.frames:
+		t.Errorf("watcher of a kept session got a frame (open=%v)", ok)
+	default:
+	}
+}
+
+func TestServer_SessionCreation(t *testing.T) {
+	srv := New(Options{Open: func(id string) bool { return strings.HasPrefix(id, "ok") }})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for _, path := range []string{"/nope", "/nope/stream"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: %s", path, resp.Status)
+		}
+	}
+	patch := "@patch\n= step 1\n@end"
+	if resp := postPatch(t, ts.URL+"/nope/patch", patch, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST refused by Open: %s", resp.Status)
+	}
+	if resp := postPatch(t, ts.URL+"/ok1/patch", patch, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST allowed by Open: %s", resp.Status)
+	}
+	srv.mu.Lock()
+	n := len(srv.sessions)
+	srv.mu.Unlock()
+	if n != 1 {
+		t.Errorf("%d sessions, want 1", n)
+	}
+}
+
+func TestServer_WebSocketOrigin(t *testing.T) {
+	_, ts := newTestServer(t)
+	host := strings.TrimPrefix(ts.URL, "http://")
+	for origin, want := range map[string]int{
+		"":                     http.StatusSwitchingProtocols,
+		"http://" + host:       http.StatusSwitchingProtocols,
+		"https://evil.example": http.StatusForbidden,
+	} {
+		conn, err := net.Dial("tcp", host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := "GET /s1/stream HTTP/1.1\r\nHost: " + host + "\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+			"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+		if origin != "" {
+			req += "Origin: " + origin + "\r\n"
+		}
+		io.WriteString(conn, req+"\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("origin %q: %s, want %d", origin, resp.Status, want)
+		}
+	}
+}
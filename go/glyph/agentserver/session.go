@@ -0,0 +1,181 @@
+package agentserver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+// errApply is wrapped by the error for a patch that does not apply to the
+// document, such as a set below a missing field.
+var errApply = errors.New("agentserver: patch does not apply")
+
+// session is one document and the connections watching it.
+type session struct {
+	opts *Options
+
+	used uint64 // When the server last handed the session out; guarded by Server.mu
+
+	mu     sync.Mutex
+	doc    *glyph.Document
+	subs   map[*subscriber]bool
+	closed bool // Evicted: new subscribers get the snapshot and nothing more
+}
+
+// subscriber is one connection's queue of frames to send. The connection
+// numbers them.
+type subscriber struct {
+	frames chan *stream.Frame
+}
+
+func newSession(srv *Server, body *glyph.GValue) *session {
+	if body == nil {
+		body = glyph.Map()
+	}
+	doc := &glyph.Document{Body: body, Schema: srv.opts.Schema}
+	sess := &session{opts: &srv.opts, doc: doc, subs: make(map[*subscriber]bool)}
+	if doc.Schema != nil {
+		doc.Check = sess.validate
+	}
+	sess.doc.Watch(sess.broadcast)
+	return sess
+}
+
+// watched reports whether anybody is subscribed to the session.
+func (s *session) watched() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs) > 0
+}
+
+// snapshot returns the document and its state hash.
+func (s *session) snapshot() (*glyph.GValue, [32]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc.Body, stream.StateHashLoose(s.doc.Body)
+}
+
+// apply checks p against base, then applies it; the document checks the
+// result against the schema (see validate) before keeping it.
+func (s *session) apply(p *glyph.Patch, base *[32]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if base != nil {
+		if got := stream.StateHashLoose(s.doc.Body); got != *base {
+			return &stream.BaseMismatchError{Expected: *base, Got: got}
+		}
+	}
+	if p.TargetType == "" {
+		p.TargetType = s.opts.RootType // Wire keys and FIDs resolve from it
+	}
+	if err := s.doc.ApplyPatch(p); err != nil {
+		var payloadErr *stream.PayloadError
+		if errors.As(err, &payloadErr) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errApply, err)
+	}
+	return nil
+}
+
+// validate is the document's Check: it rejects a new body that does not
+// validate against the schema.
+func (s *session) validate(body *glyph.GValue) error {
+	schema := s.opts.Schema
+	var check *glyph.ValidationResult
+	if s.opts.RootType != "" {
+		check = glyph.ValidateAs(body, schema, s.opts.RootType)
+	} else {
+		check = glyph.ValidateWithSchema(body, schema)
+	}
+	if check.Valid {
+		return nil
+	}
+	msgs := make([]string, len(check.Errors))
+	for i := range check.Errors {
+		msgs[i] = check.Errors[i].Error()
+	}
+	hash := schema.Hash
+	if hash == "" {
+		hash = schema.ComputeHash()
+	}
+	return &stream.PayloadError{Schema: hash, Errors: msgs}
+}
+
+// broadcast queues a change as a patch frame for every subscriber. It runs
+// inside apply, with mu held.
+func (s *session) broadcast(change *glyph.DocumentChange) error {
+	opts := glyph.DefaultPatchOptions(nil)
+	opts.SortOps = false
+	payload, err := glyph.EmitPatchWithOptions(change.Patch, opts)
+	if err != nil {
+		return err
+	}
+	base := stream.StateHashLoose(change.Base)
+	for sub := range s.subs {
+		s.send(sub, &stream.Frame{Kind: stream.KindPatch, Payload: []byte(payload), Base: &base})
+	}
+	return nil
+}
+
+// subscribe registers a new subscriber, whose first frame is a snapshot of
+// the document.
+func (s *session) subscribe() *subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := &subscriber{frames: make(chan *stream.Frame, s.opts.Buffer)}
+	sub.frames <- &stream.Frame{Kind: stream.KindDoc, Payload: []byte(glyph.Emit(s.doc.Body))}
+	if s.closed {
+		close(sub.frames)
+		return sub
+	}
+	s.subs[sub] = true
+	return sub
+}
+
+// close disconnects every subscriber.
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subs {
+		s.drop(sub)
+	}
+}
+
+// unsubscribe removes sub and closes its queue, if it is still registered.
+func (s *session) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(sub)
+}
+
+// reply queues an err frame for sub alone.
+func (s *session) reply(sub *subscriber, ev *stream.ErrorEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs[sub] {
+		s.send(sub, &stream.Frame{Kind: stream.KindErr, Payload: stream.EmitErrorEvent(ev)})
+	}
+}
+
+// send queues f for sub, dropping a subscriber that has fallen too far
+// behind. The caller holds mu.
+func (s *session) send(sub *subscriber, f *stream.Frame) {
+	select {
+	case sub.frames <- f:
+	default:
+		s.drop(sub)
+	}
+}
+
+// drop unregisters sub and closes its queue. The caller holds mu.
+func (s *session) drop(sub *subscriber) {
+	if s.subs[sub] {
+		delete(s.subs, sub)
+		close(sub.frames)
+	}
+}
@@ -0,0 +1,247 @@
+package agentserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// WebSocket (RFC 6455), server side
+// ============================================================
+//
+// Just enough of the protocol to carry GS1-T frames: text and binary
+// messages, fragmentation, ping/pong and the closing handshake. There are
+// no extensions or subprotocols.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// errWSClosed is returned by readMessage once the peer has closed.
+var errWSClosed = errors.New("agentserver: websocket closed")
+
+// isWebSocketRequest reports whether r asks to upgrade to a WebSocket.
+func isWebSocketRequest(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") &&
+		headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerHasToken reports whether the comma-separated header name holds
+// token, compared case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin is the default Options.CheckOrigin. Browsers send Origin with
+// every WebSocket request, so one naming another host comes from a page on
+// another site; clients that are not browsers send none.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsConn is an upgraded connection. Writes are serialized; reads must come
+// from one goroutine.
+type wsConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	maxSize int
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// upgradeWebSocket completes the opening handshake and takes over the
+// connection. On failure it has already answered the request.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, maxSize int) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "bad websocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("agentserver: bad websocket handshake")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("agentserver: response writer cannot be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("agentserver: hijack: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("agentserver: handshake: %w", err)
+	}
+	return &wsConn{conn: conn, br: rw.Reader, maxSize: maxSize}, nil
+}
+
+// writeMessage sends data as one unfragmented message.
+func (c *wsConn) writeMessage(opcode byte, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return errWSClosed
+	}
+	return c.writeFrame(opcode, data)
+}
+
+// writeFrame writes a final, unmasked frame. The caller holds wmu.
+func (c *wsConn) writeFrame(opcode byte, data []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(data); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := c.conn.Write(append(header, data...)); err != nil {
+		return fmt.Errorf("agentserver: websocket write: %w", err)
+	}
+	return nil
+}
+
+// readMessage returns the next text or binary message, answering pings on
+// the way. It returns errWSClosed after a close frame.
+func (c *wsConn) readMessage() (opcode byte, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeMessage(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.closeWith(payload)
+			return 0, nil, errWSClosed
+		case wsText, wsBinary:
+			if opcode != 0 {
+				return 0, nil, fmt.Errorf("agentserver: websocket: new message inside a fragmented one")
+			}
+			opcode = op
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, fmt.Errorf("agentserver: websocket: continuation without a message")
+			}
+		default:
+			return 0, nil, fmt.Errorf("agentserver: websocket: unknown opcode %#x", op)
+		}
+		if len(data)+len(payload) > c.maxSize {
+			return 0, nil, fmt.Errorf("agentserver: websocket message exceeds %d bytes", c.maxSize)
+		}
+		data = append(data, payload...)
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// readFrame reads one frame. Client frames must be masked.
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = head[0]&0x80 != 0, head[0]&0x0F
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, fmt.Errorf("agentserver: websocket: unmasked client frame")
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > uint64(c.maxSize) {
+		return false, 0, nil, fmt.Errorf("agentserver: websocket frame exceeds %d bytes", c.maxSize)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// closeWith sends a close frame echoing the peer's status, if it has not
+// been sent yet.
+func (c *wsConn) closeWith(payload []byte) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return
+	}
+	if len(payload) > 2 {
+		payload = payload[:2]
+	}
+	c.writeFrame(wsClose, payload)
+	c.closed = true
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *wsConn) Close() error {
+	c.closeWith([]byte{0x03, 0xE8}) // 1000: normal closure
+	return c.conn.Close()
+}
@@ -0,0 +1,181 @@
+package glyph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // Decoders for StoreArtifact dimensions
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// Artifacts
+// ============================================================
+//
+// Multimodal tool outputs (screenshots, recordings, generated images) are
+// referenced rather than inlined. Artifact is the standard shape for such a
+// reference, declared in StdSchema so every project writes the same fields:
+//
+//	Artifact{mime=image/png blob="sha256:9f86d081884c7d65" sha256=9f86…
+//	         size=48213 width=640 height=480}
+//
+// The content is located by uri (a URL or path) or by blob (a BlobStore id),
+// exactly one of them. width and height apply to images and video, duration
+// (seconds) to audio and video. StoreArtifact puts content in a BlobStore and
+// fills in the digest, size and, for PNG, JPEG and GIF, the dimensions.
+
+// ArtifactTypeName is the struct type of an artifact reference.
+const ArtifactTypeName = "Artifact"
+
+// stdSchemaText declares the standard library types.
+const stdSchemaText = `@schema{
+	Artifact:v1 struct{
+		mime: str [regex="^[a-z]+/[0-9A-Za-z.+-]+$"]
+		uri: str [optional] [nonempty]
+		blob: str [optional] [nonempty]
+		sha256: str [optional] [regex="^[0-9a-f]{64}$"]
+		size: int [optional] [min=0]
+		width: int [optional] [min=1]
+		height: int [optional] [min=1]
+		duration: float [optional] [min=0]
+		alt: str [optional]
+		@oneof(uri | blob)
+	}
+}`
+
+// StdSchema returns a new schema holding the standard library types
+// (currently Artifact). Merge it into an application schema to use them in
+// field types:
+//
+//	schema.Merge(glyph.StdSchema())
+func StdSchema() *Schema {
+	schema, err := ParseSchema(stdSchemaText)
+	if err != nil {
+		panic(fmt.Sprintf("glyph: standard schema: %v", err))
+	}
+	return schema
+}
+
+// stdSchema is the shared StdSchema used for validation; callers get their
+// own copy from StdSchema.
+var stdSchema = sync.OnceValue(StdSchema)
+
+// Artifact is a reference to an image, audio, video or other binary output.
+type Artifact struct {
+	MIME     string        // Media type, e.g. "image/png"
+	URI      string        // URL or path of the content
+	Blob     string        // BlobStore id of the content (instead of URI)
+	SHA256   string        // Hex SHA-256 of the content (optional)
+	Size     int64         // Content length in bytes (optional)
+	Width    int           // Pixels, for images and video (optional)
+	Height   int           // Pixels, for images and video (optional)
+	Duration time.Duration // Length, for audio and video (optional)
+	Alt      string        // Text description (optional)
+}
+
+// StoreArtifact puts data in store and returns an artifact referencing it,
+// with its digest and size. For PNG, JPEG and GIF images the dimensions are
+// filled in too.
+func StoreArtifact(store BlobStore, mime string, data []byte) (Artifact, error) {
+	id, err := store.Put(data)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("glyph: storing artifact: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	a := Artifact{
+		MIME:   mime,
+		Blob:   id,
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   int64(len(data)),
+	}
+	if strings.HasPrefix(mime, "image/") {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			a.Width, a.Height = cfg.Width, cfg.Height
+		}
+	}
+	return a, a.Validate()
+}
+
+// Value returns the artifact as an Artifact struct value. Unset optional
+// fields are omitted.
+func (a Artifact) Value() *GValue {
+	fields := []MapEntry{{Key: "mime", Value: Str(a.MIME)}}
+	add := func(key string, set bool, v *GValue) {
+		if set {
+			fields = append(fields, MapEntry{Key: key, Value: v})
+		}
+	}
+	add("uri", a.URI != "", Str(a.URI))
+	add("blob", a.Blob != "", Str(a.Blob))
+	add("sha256", a.SHA256 != "", Str(a.SHA256))
+	add("size", a.Size != 0, Int(a.Size))
+	add("width", a.Width != 0, Int(int64(a.Width)))
+	add("height", a.Height != 0, Int(int64(a.Height)))
+	add("duration", a.Duration != 0, Float(a.Duration.Seconds()))
+	add("alt", a.Alt != "", Str(a.Alt))
+	return Struct(ArtifactTypeName, fields...)
+}
+
+// Validate checks the artifact against the Artifact schema and the
+// conventions it cannot express: width and height are set together and only
+// for images and video, and duration only for audio and video.
+func (a Artifact) Validate() error {
+	return validateArtifact(a, a.Value())
+}
+
+// ArtifactFromValue reads an artifact from an Artifact struct or a map with
+// the same fields, and validates it.
+func ArtifactFromValue(v *GValue) (Artifact, error) {
+	v.force()
+	if v == nil || (v.typ != TypeStruct && v.typ != TypeMap) {
+		return Artifact{}, fmt.Errorf("glyph: artifact: expected struct, got %s", v.Type())
+	}
+	if v.typ == TypeStruct && v.structVal.TypeName != ArtifactTypeName {
+		return Artifact{}, fmt.Errorf("glyph: artifact: expected %s, got %s", ArtifactTypeName, v.structVal.TypeName)
+	}
+
+	var a Artifact
+	str := func(key string) string {
+		s, _ := v.Get(key).AsStr()
+		return s
+	}
+	a.MIME, a.URI, a.Blob, a.SHA256, a.Alt = str("mime"), str("uri"), str("blob"), str("sha256"), str("alt")
+	a.Size, _ = v.Get("size").AsInt()
+	if w, err := v.Get("width").AsInt(); err == nil {
+		a.Width = int(w)
+	}
+	if h, err := v.Get("height").AsInt(); err == nil {
+		a.Height = int(h)
+	}
+	if dv := v.Get("duration"); dv != nil {
+		if d, ok := dv.Number(); ok && !math.IsNaN(d) && !math.IsInf(d, 0) {
+			a.Duration = time.Duration(d * float64(time.Second))
+		}
+	}
+	return a, validateArtifact(a, v)
+}
+
+// validateArtifact validates v, the value form of a, against StdSchema and
+// the artifact conventions.
+func validateArtifact(a Artifact, v *GValue) error {
+	if r := ValidateAs(v, stdSchema(), ArtifactTypeName); !r.Valid {
+		return fmt.Errorf("glyph: artifact: %w", &r.Errors[0])
+	}
+	kind, _, _ := strings.Cut(a.MIME, "/")
+	switch {
+	case (a.Width == 0) != (a.Height == 0):
+		return fmt.Errorf("glyph: artifact: width and height must be set together")
+	case a.Width != 0 && kind != "image" && kind != "video":
+		return fmt.Errorf("glyph: artifact: dimensions on %s content", a.MIME)
+	case a.Duration != 0 && kind != "audio" && kind != "video":
+		return fmt.Errorf("glyph: artifact: duration on %s content", a.MIME)
+	}
+	return nil
+}
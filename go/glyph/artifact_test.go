@@ -0,0 +1,105 @@
+package glyph
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArtifact_Store(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryBlobStore()
+	a, err := StoreArtifact(store, "image/png", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Width != 4 || a.Height != 3 || a.Size != int64(buf.Len()) || len(a.SHA256) != 64 {
+		t.Errorf("artifact = %+v", a)
+	}
+	if data, err := store.Get(a.Blob); err != nil || !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("blob %q: %v", a.Blob, err)
+	}
+
+	// The struct value round-trips through text.
+	text := Emit(a.Value())
+	r, err := Parse(text)
+	if err != nil || r.HasErrors() {
+		t.Fatalf("parse %s: %v %v", text, err, r.Errors)
+	}
+	back, err := ArtifactFromValue(r.Value)
+	if err != nil || back != a {
+		t.Errorf("round trip = %+v, %v", back, err)
+	}
+}
+
+func TestArtifact_Validate(t *testing.T) {
+	valid := []Artifact{
+		{MIME: "audio/mpeg", URI: "https://example.com/a.mp3", Duration: 90 * time.Second},
+		{MIME: "video/mp4", Blob: "sha256:9f86d081884c7d65", Width: 1920, Height: 1080, Duration: time.Minute},
+		{MIME: "application/vnd.api+json", URI: "out.json", Alt: "results"},
+	}
+	for _, a := range valid {
+		if err := a.Validate(); err != nil {
+			t.Errorf("%+v: %v", a, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		a    Artifact
+		want string
+	}{
+		{Artifact{URI: "x"}, "mime"},
+		{Artifact{MIME: "png", URI: "x"}, "mime"},
+		{Artifact{MIME: "image/png"}, "exactly one of uri, blob"},
+		{Artifact{MIME: "image/png", URI: "x", Blob: "y"}, "exactly one of uri, blob"},
+		{Artifact{MIME: "image/png", URI: "x", SHA256: "abc"}, "sha256"},
+		{Artifact{MIME: "image/png", URI: "x", Width: 10}, "together"},
+		{Artifact{MIME: "image/png", URI: "x", Width: -1, Height: -1}, "width"},
+		{Artifact{MIME: "audio/wav", URI: "x", Width: 1, Height: 1}, "dimensions"},
+		{Artifact{MIME: "image/png", URI: "x", Duration: time.Second}, "duration"},
+	} {
+		if err := tc.a.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: err = %v, want %q", tc.a, err, tc.want)
+		}
+	}
+
+	if _, err := ArtifactFromValue(Struct("Image", MapEntry{Key: "mime", Value: Str("image/png")})); err == nil {
+		t.Error("expected error for another struct type")
+	}
+	m := Map(MapEntry{Key: "mime", Value: Str("audio/ogg")}, MapEntry{Key: "uri", Value: Str("a.ogg")}, MapEntry{Key: "duration", Value: Float(1.5)})
+	if a, err := ArtifactFromValue(m); err != nil || a.Duration != 1500*time.Millisecond {
+		t.Errorf("from map = %+v, %v", a, err)
+	}
+}
+
+func TestStdSchema_Merge(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		Reply:v1 struct{
+			text: str
+			attachments: list<Artifact> [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Merge(StdSchema()); err != nil {
+		t.Fatal(err)
+	}
+	reply := Struct("Reply",
+		MapEntry{Key: "text", Value: Str("done")},
+		MapEntry{Key: "attachments", Value: List(
+			Artifact{MIME: "image/png", URI: "a.png"}.Value(),
+			Struct(ArtifactTypeName, MapEntry{Key: "mime", Value: Str("image/png")}),
+		)},
+	)
+	r := ValidateAs(reply, schema, "Reply")
+	if r.Valid || len(r.Errors) != 1 || !strings.HasPrefix(r.Errors[0].Path, "attachments[1]") {
+		t.Errorf("errors = %v", r.Errors)
+	}
+}
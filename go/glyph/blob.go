@@ -0,0 +1,146 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// ============================================================
+// Blob Attachments and Base85 Bytes
+// ============================================================
+//
+// Large bytes values bloat the token stream: base64 costs 4 characters per 3
+// bytes and is opaque to a model anyway. Two alternatives are provided:
+//
+//   - Out-of-band attachment. With EmitOptions.Blobs set, bytes values longer
+//     than EmitOptions.BlobThreshold are written to the BlobStore and emitted
+//     as a declaration
+//
+//         @blob "sha256:9f86d081884c7d65" 4096
+//
+//     naming the blob id and its length. ParseOptions.Blobs resolves the
+//     declaration back to the bytes, checking the length.
+//
+//   - Base85 inline. With EmitOptions.Base85 set, bytes are emitted as
+//     b85"..." (5 characters per 4 bytes) using the RFC 1924 alphabet, which
+//     contains no quote or backslash so the body never needs escaping.
+
+// BlobStore holds binary attachments referenced from GLYPH text by id.
+type BlobStore interface {
+	// Put stores data and returns the id to reference it by.
+	Put(data []byte) (string, error)
+	// Get returns the data stored under id.
+	Get(id string) ([]byte, error)
+}
+
+// MemoryBlobStore is an in-memory, content-addressed BlobStore. Ids are
+// "sha256:" followed by the first 16 hex digits of the data's SHA-256, so
+// storing the same bytes twice yields the same id. Safe for concurrent use.
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryBlobStore creates an empty in-memory blob store.
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Put stores a copy of data under its content id.
+func (s *MemoryBlobStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	id := "sha256:" + hex.EncodeToString(sum[:8])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[id]; !ok {
+		s.blobs[id] = append([]byte(nil), data...)
+	}
+	return id, nil
+}
+
+// Get returns the data stored under id.
+func (s *MemoryBlobStore) Get(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[id]
+	if !ok {
+		return nil, fmt.Errorf("blob not found: %s", id)
+	}
+	return data, nil
+}
+
+// Len returns the number of stored blobs.
+func (s *MemoryBlobStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blobs)
+}
+
+// ============================================================
+// Base85 (RFC 1924 alphabet)
+// ============================================================
+
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+var base85Decode = func() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = 0xFF
+	}
+	for i := 0; i < len(base85Alphabet); i++ {
+		t[base85Alphabet[i]] = byte(i)
+	}
+	return t
+}()
+
+// encodeBase85 encodes data as base85. Each 4-byte group becomes 5 characters;
+// a final group of n < 4 bytes becomes n+1 characters.
+func encodeBase85(data []byte) string {
+	out := make([]byte, 0, (len(data)+3)/4*5)
+	for len(data) > 0 {
+		n := min(len(data), 4)
+		var group [4]byte
+		copy(group[:], data[:n])
+		v := uint32(group[0])<<24 | uint32(group[1])<<16 | uint32(group[2])<<8 | uint32(group[3])
+
+		var chars [5]byte
+		for i := 4; i >= 0; i-- {
+			chars[i] = base85Alphabet[v%85]
+			v /= 85
+		}
+		out = append(out, chars[:n+1]...)
+		data = data[n:]
+	}
+	return string(out)
+}
+
+// decodeBase85 decodes text produced by encodeBase85.
+func decodeBase85(s string) ([]byte, error) {
+	if len(s)%5 == 1 {
+		return nil, fmt.Errorf("invalid base85 length %d", len(s))
+	}
+	out := make([]byte, 0, len(s)/5*4+3)
+	for pos := 0; pos < len(s); pos += 5 {
+		chunk := s[pos:min(pos+5, len(s))]
+		var v uint64
+		for i := 0; i < 5; i++ {
+			d := byte(84) // pad a short final group with the highest digit
+			if i < len(chunk) {
+				d = base85Decode[chunk[i]]
+				if d == 0xFF {
+					return nil, fmt.Errorf("invalid base85 character %q at %d", chunk[i], pos+i)
+				}
+			}
+			v = v*85 + uint64(d)
+		}
+		if v > 0xFFFFFFFF {
+			return nil, fmt.Errorf("base85 group overflow at %d", pos)
+		}
+		group := [4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, group[:len(chunk)-1]...)
+	}
+	return out, nil
+}
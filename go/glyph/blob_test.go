@@ -0,0 +1,105 @@
+package glyph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBase85_RoundTrip(t *testing.T) {
+	for n := 0; n <= 37; n++ {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i*97 + n)
+		}
+		if n%5 == 4 {
+			data = bytes.Repeat([]byte{0xFF}, n) // largest group values
+		}
+		enc := encodeBase85(data)
+		if strings.ContainsAny(enc, `"\`) {
+			t.Fatalf("n=%d: encoding needs escaping: %s", n, enc)
+		}
+		dec, err := decodeBase85(enc)
+		if err != nil || !bytes.Equal(dec, data) {
+			t.Errorf("n=%d: round trip failed (%v): %x vs %x", n, err, dec, data)
+		}
+	}
+
+	for _, bad := range []string{"0", "00000~", `"0000`, "|NsC1"} {
+		if _, err := decodeBase85(bad); err == nil {
+			t.Errorf("%q: expected decode error", bad)
+		}
+	}
+}
+
+func TestEmit_Base85Bytes(t *testing.T) {
+	opts := DefaultEmitOptions()
+	opts.Base85 = true
+	v := Map(MapEntry{Key: "data", Value: Bytes([]byte("hello, world"))})
+
+	out := EmitWithOptions(v, opts)
+	if !strings.Contains(out, `b85"`) {
+		t.Fatalf("expected b85 literal in %s", out)
+	}
+	result, err := ParseWithOptions(out, ParseOptions{})
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse error: %v %v", err, result)
+	}
+	if !EqualLoose(result.Value, v) {
+		t.Errorf("round-trip mismatch: %s", Emit(result.Value))
+	}
+
+	result, _ = ParseWithOptions(`b85"0"`, ParseOptions{})
+	if !result.HasErrors() {
+		t.Error("invalid base85 should be a parse error")
+	}
+}
+
+func TestEmit_BlobAttachments(t *testing.T) {
+	store := NewMemoryBlobStore()
+	big := bytes.Repeat([]byte{0xAB}, 4096)
+	v := Map(
+		MapEntry{Key: "big", Value: Bytes(big)},
+		MapEntry{Key: "copy", Value: Bytes(big)},
+		MapEntry{Key: "small", Value: Bytes([]byte{1, 2})},
+	)
+
+	opts := DefaultEmitOptions()
+	opts.Blobs = store
+	opts.BlobThreshold = 64
+	out := EmitWithOptions(v, opts)
+
+	if !strings.Contains(out, `big:@blob "sha256:`) || !strings.Contains(out, `" 4096`) {
+		t.Errorf("expected @blob declaration in %s", out)
+	}
+	if !strings.Contains(out, `small:b64"AQI="`) {
+		t.Errorf("small bytes should stay inline: %s", out)
+	}
+	if store.Len() != 1 {
+		t.Errorf("identical blobs should be stored once, got %d", store.Len())
+	}
+
+	result, err := ParseWithOptions(out, ParseOptions{Blobs: store})
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse error: %v %v", err, result.Errors)
+	}
+	if !EqualLoose(result.Value, v) {
+		t.Error("blob round-trip mismatch")
+	}
+
+	// Without a store the declaration cannot be resolved.
+	result, _ = ParseWithOptions(out, ParseOptions{})
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "no blob store") {
+		t.Errorf("expected missing-store error, got %v", result.Errors)
+	}
+	result, _ = Parse(out)
+	if result.HasErrors() || len(result.Warnings) == 0 || !result.Value.Get("big").IsNull() {
+		t.Errorf("tolerant parse should warn and null the blob, got %v", result.Warnings)
+	}
+
+	id, _ := store.Put(big)
+	result, _ = ParseWithOptions(`@blob "`+id+`" 10`, ParseOptions{Blobs: store})
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "declared 10") {
+		t.Errorf("expected length mismatch error, got %v", result.Errors)
+	}
+}
@@ -22,6 +22,7 @@ import (
 //     - TypeID  → cowrie.String("^prefix:val")   — decoded back as plain Str
 //     - TypeStruct → cowrie.Object (fields only, TypeName dropped)
 //     - TypeSum    → cowrie.Object{tag: value}    (plain map on decode)
+//     - TypeCustom → cowrie.String(text)           — decoded back as plain Str
 //     - "_type", "_tag", "^"-prefix strings are NOT reinterpreted on decode.
 //     - cowrie.TypeDatetime64 and cowrie.TypeBytes are native, already lossless.
 //
@@ -30,6 +31,7 @@ import (
 //     - TypeID  → {$glyph:"id",  value:"^prefix:val"}
 //     - TypeStruct → {$glyph:"struct", type:"T", fields:{...}}
 //     - TypeSum    → {$glyph:"sum",    tag:"T",  value:...}
+//     - TypeCustom → {$glyph:"custom", tag:"money", value:"12.34 USD"}
 //     - TypeBytes  and TypeTime use native cowrie types — no marker needed.
 //     - "$glyph" key in user maps/struct-fields/sum-tags → hard error on emit.
 //     - On decode, only exactly-shaped marker objects are interpreted; any extra
@@ -70,6 +72,7 @@ func ToSJSONWithOpts(v *GValue, opts BridgeOpts) (*cowrie.Value, error) {
 }
 
 func toSJSONValue(v *GValue, opts BridgeOpts) (*cowrie.Value, error) {
+	v.force()
 	if v == nil {
 		return cowrie.Null(), nil
 	}
@@ -92,11 +95,11 @@ func toSJSONValue(v *GValue, opts BridgeOpts) (*cowrie.Value, error) {
 
 	case TypeBytes:
 		// cowrie.TypeBytes is a native wire type — lossless in both modes.
-		return cowrie.Bytes(v.bytesVal), nil
+		return cowrie.Bytes(v.bytesVal()), nil
 
 	case TypeTime:
 		// cowrie.TypeDatetime64 is a native nanosecond wire type — lossless.
-		return cowrie.Datetime64(v.timeVal.UnixNano()), nil
+		return cowrie.Datetime64(v.timeVal().UnixNano()), nil
 
 	case TypeID:
 		if opts.Extended {
@@ -104,14 +107,26 @@ func toSJSONValue(v *GValue, opts BridgeOpts) (*cowrie.Value, error) {
 			// quoted, unlike canonRef which may add GLYPH text quoting). The
 			// cowrie wire layer is binary, so text quoting is not needed and
 			// would prevent a clean colon-split on decode.
-			rawIDStr := v.idVal.String() // e.g. "^ns:path/value" — unquoted
+			rawIDStr := v.idVal().String() // e.g. "^ns:path/value" — unquoted
 			return cowrie.Object(
 				cowrie.Member{Key: cowrieMarkerKey, Value: cowrie.String("id")},
 				cowrie.Member{Key: "value", Value: cowrie.String(rawIDStr)},
 			), nil
 		}
 		// Strict: emit as a plain canonRef string. Lossy — decoded back as Str.
-		return cowrie.String(canonRef(v.idVal)), nil
+		return cowrie.String(canonRef(v.idVal())), nil
+
+	case TypeCustom:
+		cv := v.customVal()
+		if opts.Extended {
+			return cowrie.Object(
+				cowrie.Member{Key: cowrieMarkerKey, Value: cowrie.String("custom")},
+				cowrie.Member{Key: "tag", Value: cowrie.String(cv.Tag)},
+				cowrie.Member{Key: "value", Value: cowrie.String(cv.Text)},
+			), nil
+		}
+		// Strict: emit the literal text. Lossy — decoded back as Str.
+		return cowrie.String(cv.Text), nil
 
 	case TypeList:
 		items := make([]*cowrie.Value, len(v.listVal))
@@ -375,6 +390,17 @@ func fromCowrieMarker(markerType string, v *cowrie.Value, opts BridgeOpts) (*GVa
 		}
 		return Sum(tagField.String(), gv), nil
 
+	case "custom":
+		// Shape: {$glyph:"custom", tag:"money", value:"12.34 USD"}
+		if err := exactKeys(cowrieMarkerKey, "tag", "value"); err != nil {
+			return nil, err
+		}
+		tagField, valueField := v.Get("tag"), v.Get("value")
+		if tagField.Type() != cowrie.TypeString || valueField.Type() != cowrie.TypeString {
+			return nil, fmt.Errorf("$glyph custom marker: tag and value must be strings")
+		}
+		return DefaultScalarRegistry.Decode(tagField.String(), valueField.String())
+
 	default:
 		return nil, fmt.Errorf("$glyph cowrie marker: unknown type %q", markerType)
 	}
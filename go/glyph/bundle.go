@@ -0,0 +1,469 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Bundles (.glyphpkg)
+// ============================================================
+//
+// A deployment's encoding contract is spread over several artifacts: the
+// schemas its payloads are typed by, the key dictionaries behind compact
+// keys, the pools of common values, and the canonical options producers
+// use. A Bundle carries all of them in one file, so both ends of a stream
+// load the same contract and can tell whether they have.
+//
+// The file is itself a GLYPH-Loose map:
+//
+//	{dicts={tools=[args id name]}
+//	 hash="sha256:..."
+//	 manifest={entries=@tab _ rows=5 cols=3 [hash kind name] ... @end format="glyphpkg/1" name=agent version="1.2"}
+//	 pools={...} profiles={llm={dict=tools tabular=t ...}} schemas={agent="@schema{...}"}}
+//
+// The manifest lists every entry with its hash: a schema's Schema.Hash, the
+// one GS1 frames carry, or the FingerprintLoose of anything else. The
+// bundle hash is the SHA-256 of the no-tabular canonical form of the map
+// without its hash key, so reformatting the file keeps it valid and any
+// change to its content does not.
+
+// BundleSuffix is the file extension of bundles.
+const BundleSuffix = ".glyphpkg"
+
+// bundleFormat names the layout written by Encode.
+const bundleFormat = "glyphpkg/1"
+
+// ErrBundleHash is wrapped by the error for a bundle whose content does not
+// match its hash or manifest.
+var ErrBundleHash = errors.New("glyph: bundle does not match its hash")
+
+// Bundle is a named, versioned set of schemas, key dictionaries, value
+// pools and canonical profiles. Entries are keyed by name.
+type Bundle struct {
+	Name    string
+	Version string
+
+	Schemas  map[string]*Schema
+	Dicts    map[string][]string // Key dictionaries, in #N order
+	Pools    map[string][]string // Common string values, for interning
+	Profiles map[string]BundleProfile
+
+	Hash string // "sha256:<hex>", set by Encode and DecodeBundle
+}
+
+// BundleProfile is a named set of canonical options. Dict, Types and Order
+// name entries of the same bundle.
+type BundleProfile struct {
+	AutoTabular bool
+	MinRows     int // 0 means the default
+	MaxCols     int // 0 means the default
+	NullStyle   NullStyle
+	Dict        string // Key dictionary for compact keys (#N)
+	Types       string // Schema for typed tables (LooseCanonOpts.Types)
+	Order       string // Schema for the schema-order profile (LooseCanonOpts.FieldOrder)
+	OrderRoot   string // Root type under Order
+}
+
+// NewBundle creates an empty bundle.
+func NewBundle(name, version string) *Bundle {
+	return &Bundle{
+		Name:     name,
+		Version:  version,
+		Schemas:  make(map[string]*Schema),
+		Dicts:    make(map[string][]string),
+		Pools:    make(map[string][]string),
+		Profiles: make(map[string]BundleProfile),
+	}
+}
+
+// CanonOpts returns the canonical options of the named profile, with its
+// dictionary and schemas resolved.
+func (b *Bundle) CanonOpts(profile string) (LooseCanonOpts, error) {
+	p, ok := b.Profiles[profile]
+	if !ok {
+		return LooseCanonOpts{}, fmt.Errorf("glyph: bundle %s: no profile %q", b.Name, profile)
+	}
+	opts := LooseCanonOpts{
+		AutoTabular:  p.AutoTabular,
+		MinRows:      p.MinRows,
+		MaxCols:      p.MaxCols,
+		AllowMissing: true,
+		NullStyle:    p.NullStyle,
+	}
+	if p.Dict != "" {
+		dict, ok := b.Dicts[p.Dict]
+		if !ok {
+			return LooseCanonOpts{}, fmt.Errorf("glyph: bundle %s: profile %q: no dict %q", b.Name, profile, p.Dict)
+		}
+		opts.KeyDict, opts.UseCompactKeys = dict, true
+	}
+	var err error
+	if opts.Types, err = b.profileSchema(profile, p.Types); err != nil {
+		return LooseCanonOpts{}, err
+	}
+	if opts.FieldOrder, err = b.profileSchema(profile, p.Order); err != nil {
+		return LooseCanonOpts{}, err
+	}
+	opts.FieldOrderRoot = p.OrderRoot
+	return opts, nil
+}
+
+// profileSchema returns the schema a profile names, or nil for "".
+func (b *Bundle) profileSchema(profile, name string) (*Schema, error) {
+	if name == "" {
+		return nil, nil
+	}
+	schema, ok := b.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("glyph: bundle %s: profile %q: no schema %q", b.Name, profile, name)
+	}
+	return schema, nil
+}
+
+// Interner returns a new Interner holding the values of the named pool.
+func (b *Bundle) Interner(pool string) (*Interner, error) {
+	values, ok := b.Pools[pool]
+	if !ok {
+		return nil, fmt.Errorf("glyph: bundle %s: no pool %q", b.Name, pool)
+	}
+	in := NewInterner()
+	if in.MaxEntries < len(values) {
+		in.MaxEntries = len(values)
+	}
+	for _, s := range values {
+		in.Intern(s)
+	}
+	in.stats = InternStats{Entries: len(in.table)}
+	return in, nil
+}
+
+// BundleEntry is one line of a bundle's manifest.
+type BundleEntry struct {
+	Kind string // "schema", "dict", "pool" or "profile"
+	Name string
+	Hash string
+}
+
+// Manifest lists the entries of b by kind, then name.
+func (b *Bundle) Manifest() []BundleEntry {
+	var entries []BundleEntry
+	add := func(kind string, names []string, hash func(string) string) {
+		for _, name := range names {
+			entries = append(entries, BundleEntry{Kind: kind, Name: name, Hash: hash(name)})
+		}
+	}
+	add("dict", mapKeys(b.Dicts), func(name string) string { return FingerprintLoose(strList(b.Dicts[name])) })
+	add("pool", mapKeys(b.Pools), func(name string) string { return FingerprintLoose(strList(b.Pools[name])) })
+	add("profile", mapKeys(b.Profiles), func(name string) string { return FingerprintLoose(b.Profiles[name].value()) })
+	add("schema", mapKeys(b.Schemas), func(name string) string {
+		if s := b.Schemas[name]; s.Hash != "" {
+			return s.Hash
+		}
+		return b.Schemas[name].ComputeHash()
+	})
+	return entries
+}
+
+// check reports a profile that names an entry b lacks.
+func (b *Bundle) check() error {
+	for name := range b.Profiles {
+		if _, err := b.CanonOpts(name); err != nil {
+			return err
+		}
+	}
+	for name, s := range b.Schemas {
+		if s == nil {
+			return fmt.Errorf("glyph: bundle %s: schema %q is nil", b.Name, name)
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// Bundle Encoding
+// ============================================================
+
+// Encode returns the file form of b and sets b.Hash.
+func (b *Bundle) Encode() (string, error) {
+	if err := b.check(); err != nil {
+		return "", err
+	}
+	v := b.value()
+	b.Hash = bundleHash(v)
+	v.mapVal = append(v.mapVal, MapEntry{Key: "hash", Value: Str(b.Hash)})
+	return CanonicalizeLoose(v) + "\n", nil
+}
+
+// WriteFile writes the file form of b to path.
+func (b *Bundle) WriteFile(path string) error {
+	text, err := b.Encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0o644)
+}
+
+// value returns b as a map, without its hash.
+func (b *Bundle) value() *GValue {
+	entries := b.Manifest()
+	rows := make([]*GValue, len(entries))
+	for i, e := range entries {
+		rows[i] = Map(
+			MapEntry{Key: "hash", Value: Str(e.Hash)},
+			MapEntry{Key: "kind", Value: Str(e.Kind)},
+			MapEntry{Key: "name", Value: Str(e.Name)},
+		)
+	}
+	section := func(names []string, value func(string) *GValue) *GValue {
+		m := Map()
+		for _, name := range names {
+			m.mapVal = append(m.mapVal, MapEntry{Key: name, Value: value(name)})
+		}
+		return m
+	}
+	return Map(
+		MapEntry{Key: "dicts", Value: section(mapKeys(b.Dicts), func(name string) *GValue { return strList(b.Dicts[name]) })},
+		MapEntry{Key: "manifest", Value: Map(
+			MapEntry{Key: "entries", Value: List(rows...)},
+			MapEntry{Key: "format", Value: Str(bundleFormat)},
+			MapEntry{Key: "name", Value: Str(b.Name)},
+			MapEntry{Key: "version", Value: Str(b.Version)},
+		)},
+		MapEntry{Key: "pools", Value: section(mapKeys(b.Pools), func(name string) *GValue { return strList(b.Pools[name]) })},
+		MapEntry{Key: "profiles", Value: section(mapKeys(b.Profiles), func(name string) *GValue { return b.Profiles[name].value() })},
+		MapEntry{Key: "schemas", Value: section(mapKeys(b.Schemas), func(name string) *GValue { return Str(b.Schemas[name].Canonical()) })},
+	)
+}
+
+// value returns p as a map, leaving out unset names.
+func (p BundleProfile) value() *GValue {
+	null := "_"
+	if p.NullStyle == NullStyleSymbol {
+		null = "∅"
+	}
+	m := Map(
+		MapEntry{Key: "max_cols", Value: Int(int64(p.MaxCols))},
+		MapEntry{Key: "min_rows", Value: Int(int64(p.MinRows))},
+		MapEntry{Key: "null", Value: Str(null)},
+		MapEntry{Key: "tabular", Value: Bool(p.AutoTabular)},
+	)
+	for _, e := range []MapEntry{
+		{Key: "dict", Value: Str(p.Dict)},
+		{Key: "order", Value: Str(p.Order)},
+		{Key: "root", Value: Str(p.OrderRoot)},
+		{Key: "types", Value: Str(p.Types)},
+	} {
+		if e.Value.strVal != "" {
+			m.mapVal = append(m.mapVal, e)
+		}
+	}
+	return m
+}
+
+// DecodeBundleProfile reads a profile from its map form, as written in a
+// bundle: {tabular=t min_rows=3 max_cols=20 null=_ dict=D types=S order=S
+// root=T}. Missing keys take their zero values, except null, which is _
+// as in DefaultLooseCanonOpts.
+func DecodeBundleProfile(v *GValue) (BundleProfile, error) {
+	p := BundleProfile{NullStyle: NullStyleUnderscore}
+	if v == nil || v.Type() != TypeMap {
+		return p, fmt.Errorf("profile is not a map")
+	}
+	for _, e := range v.mapVal {
+		var err error
+		switch e.Key {
+		case "tabular":
+			p.AutoTabular, err = e.Value.AsBool()
+		case "min_rows", "max_cols":
+			var n int64
+			n, err = e.Value.AsInt()
+			if e.Key == "min_rows" {
+				p.MinRows = int(n)
+			} else {
+				p.MaxCols = int(n)
+			}
+		case "null":
+			var s string
+			if s, err = e.Value.AsStr(); err == nil {
+				switch s {
+				case "_":
+					p.NullStyle = NullStyleUnderscore
+				case "∅":
+					p.NullStyle = NullStyleSymbol
+				default:
+					err = fmt.Errorf("null style %q is not _ or ∅", s)
+				}
+			}
+		case "dict":
+			p.Dict, err = e.Value.AsStr()
+		case "types":
+			p.Types, err = e.Value.AsStr()
+		case "order":
+			p.Order, err = e.Value.AsStr()
+		case "root":
+			p.OrderRoot, err = e.Value.AsStr()
+		default:
+			err = fmt.Errorf("unknown key %q", e.Key)
+		}
+		if err != nil {
+			return p, fmt.Errorf("%s: %w", e.Key, err)
+		}
+	}
+	return p, nil
+}
+
+// DecodeBundle parses the file form of a bundle. The content must match the
+// bundle hash and the manifest (see ErrBundleHash).
+func DecodeBundle(s string) (*Bundle, error) {
+	v, err := ParseLoose(strings.TrimSpace(s), nil)
+	if err != nil {
+		return nil, fmt.Errorf("glyph: bundle: %w", err)
+	}
+	if v.Type() != TypeMap {
+		return nil, fmt.Errorf("glyph: bundle: not a map")
+	}
+	hash, err := v.Get("hash").AsStr()
+	if err != nil {
+		return nil, fmt.Errorf("glyph: bundle: hash: %w", err)
+	}
+	body := Map()
+	for _, e := range v.mapVal {
+		if e.Key != "hash" {
+			body.mapVal = append(body.mapVal, e)
+		}
+	}
+	if got := bundleHash(body); got != hash {
+		return nil, fmt.Errorf("%w: hash is %s, content hashes to %s", ErrBundleHash, hash, got)
+	}
+
+	manifest := v.Get("manifest")
+	format, err := manifest.Get("format").AsStr()
+	if err != nil || format != bundleFormat {
+		return nil, fmt.Errorf("glyph: bundle: format %q is not %s", format, bundleFormat)
+	}
+	name, err1 := manifest.Get("name").AsStr()
+	version, err2 := manifest.Get("version").AsStr()
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, fmt.Errorf("glyph: bundle: manifest: %w", err)
+	}
+	b := NewBundle(name, version)
+	b.Hash = hash
+
+	err = errors.Join(
+		eachBundleEntry(v, "dicts", func(name string, e *GValue) (err error) {
+			b.Dicts[name], err = strsOf(e)
+			return err
+		}),
+		eachBundleEntry(v, "pools", func(name string, e *GValue) (err error) {
+			b.Pools[name], err = strsOf(e)
+			return err
+		}),
+		eachBundleEntry(v, "profiles", func(name string, e *GValue) (err error) {
+			b.Profiles[name], err = DecodeBundleProfile(e)
+			return err
+		}),
+		eachBundleEntry(v, "schemas", func(name string, e *GValue) error {
+			text, err := e.AsStr()
+			if err == nil {
+				b.Schemas[name], err = ParseSchema(text)
+			}
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	listed, err := manifest.Get("entries").AsList()
+	if err != nil {
+		return nil, fmt.Errorf("glyph: bundle: manifest: entries: %w", err)
+	}
+	entries := b.Manifest()
+	if len(listed) != len(entries) {
+		return nil, fmt.Errorf("%w: manifest lists %d entries, bundle has %d", ErrBundleHash, len(listed), len(entries))
+	}
+	for i, e := range entries {
+		row := listed[i]
+		kind, _ := row.Get("kind").AsStr()
+		name, _ := row.Get("name").AsStr()
+		hash, _ := row.Get("hash").AsStr()
+		if kind != e.Kind || name != e.Name || hash != e.Hash {
+			return nil, fmt.Errorf("%w: manifest entry %s %q does not match the %s %q in the bundle", ErrBundleHash, kind, name, e.Kind, e.Name)
+		}
+	}
+	return b, nil
+}
+
+// LoadBundle reads and checks the bundle file at path.
+func LoadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := DecodeBundle(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// bundleHash returns the hash of a bundle's map form, less its hash key.
+func bundleHash(v *GValue) string {
+	return "sha256:" + FingerprintLoose(v)
+}
+
+// eachBundleEntry calls fn with each entry of the named section of v.
+func eachBundleEntry(v *GValue, section string, fn func(name string, e *GValue) error) error {
+	sec := v.Get(section)
+	if sec == nil {
+		return nil
+	}
+	entries, err := sec.AsMap()
+	if err != nil {
+		return fmt.Errorf("glyph: bundle: %s: %w", section, err)
+	}
+	for _, e := range entries {
+		if err := fn(e.Key, e.Value); err != nil {
+			return fmt.Errorf("glyph: bundle: %s %q: %w", section, e.Key, err)
+		}
+	}
+	return nil
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func strList(ss []string) *GValue {
+	items := make([]*GValue, len(ss))
+	for i, s := range ss {
+		items[i] = Str(s)
+	}
+	return List(items...)
+}
+
+func strsOf(v *GValue) ([]string, error) {
+	items, err := v.AsList()
+	if err != nil {
+		return nil, err
+	}
+	ss := make([]string, len(items))
+	for i, item := range items {
+		if ss[i], err = item.AsStr(); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return ss, nil
+}
@@ -0,0 +1,129 @@
+package glyph
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	schema, err := ParseSchema(`@schema{ Call struct{ id: str @fid(1)  tool: str @fid(2)  args: map<str,str> @fid(3) [optional] } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBundle("agent", "1.2")
+	b.Schemas["calls"] = schema
+	b.Dicts["tools"] = []string{"id", "tool", "args"}
+	b.Pools["status"] = []string{"ok", "error", "pending"}
+	b.Profiles["wire"] = BundleProfile{AutoTabular: true, NullStyle: NullStyleUnderscore, Dict: "tools"}
+	b.Profiles["ordered"] = BundleProfile{Order: "calls", OrderRoot: "Call", Types: "calls"}
+	return b
+}
+
+func TestBundle_RoundTrip(t *testing.T) {
+	b := testBundle(t)
+	path := filepath.Join(t.TempDir(), "agent"+BundleSuffix)
+	if err := b.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.Hash, "sha256:") {
+		t.Fatalf("Hash = %q", b.Hash)
+	}
+
+	got, err := LoadBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "agent" || got.Version != "1.2" || got.Hash != b.Hash {
+		t.Errorf("loaded %s %s %s", got.Name, got.Version, got.Hash)
+	}
+	if got.Schemas["calls"].Hash != b.Schemas["calls"].Hash {
+		t.Errorf("schema hash %s, want %s", got.Schemas["calls"].Hash, b.Schemas["calls"].Hash)
+	}
+	if strings.Join(got.Dicts["tools"], " ") != "id tool args" || len(got.Pools["status"]) != 3 {
+		t.Errorf("dicts %v, pools %v", got.Dicts, got.Pools)
+	}
+	if got.Profiles["wire"] != b.Profiles["wire"] || got.Profiles["ordered"] != b.Profiles["ordered"] {
+		t.Errorf("profiles %+v", got.Profiles)
+	}
+
+	want := []string{"dict tools", "pool status", "profile ordered", "profile wire", "schema calls"}
+	m := got.Manifest()
+	if len(m) != len(want) {
+		t.Fatalf("manifest %+v", m)
+	}
+	for i, e := range m {
+		if e.Kind+" "+e.Name != want[i] || e.Hash == "" {
+			t.Errorf("manifest[%d] = %+v, want %s", i, e, want[i])
+		}
+	}
+	if m[4].Hash != b.Schemas["calls"].Hash {
+		t.Errorf("schema entry hash %s", m[4].Hash)
+	}
+}
+
+func TestBundle_CanonOpts(t *testing.T) {
+	b := testBundle(t)
+	v := Map(
+		MapEntry{Key: "tool", Value: Str("search")},
+		MapEntry{Key: "id", Value: Str("c1")},
+		MapEntry{Key: "args", Value: Map(MapEntry{Key: "q", Value: Str("x")})},
+	)
+	opts, err := b.CanonOpts("wire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLooseWithOpts(v, opts); got != "{#2={q=x} #0=c1 #1=search}" {
+		t.Errorf("wire profile: %s", got)
+	}
+	opts, err = b.CanonOpts("ordered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLooseWithOpts(v, opts); got != "{id=c1 tool=search args={q=x}}" {
+		t.Errorf("ordered profile: %s", got)
+	}
+	if _, err := b.CanonOpts("missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+
+	b.Profiles["broken"] = BundleProfile{Dict: "nope"}
+	if _, err := b.Encode(); err == nil {
+		t.Error("Encode accepted a profile naming a missing dict")
+	}
+}
+
+func TestBundle_Interner(t *testing.T) {
+	in, err := testBundle(t).Interner("status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Intern(strings.Clone("ok"))
+	if s := in.Stats(); s.Entries != 3 || s.Hits != 1 || s.Lookups != 1 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestDecodeBundle_Tampered(t *testing.T) {
+	text, err := testBundle(t).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reformatting keeps the bundle valid.
+	v, err := ParseLoose(strings.TrimSpace(text), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeBundle(CanonicalizeLooseWithOpts(v, PrettyLooseCanonOpts())); err != nil {
+		t.Errorf("reformatted bundle: %v", err)
+	}
+
+	// Changing content does not.
+	edited := strings.Replace(text, "pending", "pendng", 1)
+	if _, err := DecodeBundle(edited); !errors.Is(err, ErrBundleHash) {
+		t.Errorf("edited bundle: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package glyph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLooseBytesEncoding_Emit(t *testing.T) {
+	data := []byte{0xfb, 0xff, 0x00, 0x10, 0x3e}
+	v := Map(MapEntry{Key: "d", Value: Bytes(data)})
+
+	tests := []struct {
+		enc  BytesEncoding
+		want string
+	}{
+		{BytesBase64, `{d=b64"+/8AED4="}`},
+		{BytesBase64URL, `{d=b64u"-_8AED4"}`},
+		{BytesHex, `{d=hex"fbff00103e"}`},
+	}
+	for _, tt := range tests {
+		opts := NoTabularLooseCanonOpts()
+		opts.BytesEncoding = tt.enc
+		got := CanonicalizeLooseWithOpts(v, opts)
+		if got != tt.want {
+			t.Errorf("encoding %d: got %s, want %s", tt.enc, got, tt.want)
+		}
+
+		result, err := ParseWithOptions(got, ParseOptions{})
+		if err != nil || result.HasErrors() {
+			t.Fatalf("parse %s: %v %v", got, err, result)
+		}
+		if b, _ := result.Value.Get("d").AsBytes(); !bytes.Equal(b, data) {
+			t.Errorf("parse %s: got %x", got, b)
+		}
+	}
+
+	if FingerprintLoose(v) != FingerprintLoose(Map(MapEntry{Key: "d", Value: Bytes(data)})) {
+		t.Error("fingerprint must not depend on emission options")
+	}
+}
+
+func TestBytesLiteralAliases_AllParsers(t *testing.T) {
+	data := []byte("\x00glyph?>")
+	literals := []string{
+		`b64"AGdseXBoPz4="`,
+		`b64u"AGdseXBoPz4"`,
+		`b64u"AGdseXBoPz4="`,
+		`hex"00676C7970683F3E"`,
+		`b85"` + encodeBase85(data) + `"`,
+	}
+
+	schema := NewSchemaBuilder().
+		AddPackedStruct("Blob", "v1", Field("data", PrimitiveType("bytes"))).
+		Build()
+
+	for _, lit := range literals {
+		v, err := parseLooseValue(lit)
+		if b, _ := v.AsBytes(); err != nil || !bytes.Equal(b, data) {
+			t.Errorf("loose %s: %x %v", lit, b, err)
+		}
+
+		packed, err := ParsePacked("Blob@("+lit+")", schema)
+		if err != nil {
+			t.Errorf("packed %s: %v", lit, err)
+		} else if b, _ := packed.Get("data").AsBytes(); !bytes.Equal(b, data) {
+			t.Errorf("packed %s: got %x", lit, b)
+		}
+
+		rows := "@tab _ [id data]\n|1|" + lit + "|\n|2|" + lit + "|\n|3|" + lit + "|\n@end"
+		tab, err := ParseTabularLoose(rows)
+		if err != nil {
+			t.Errorf("tabular %s: %v", lit, err)
+		} else if b, _ := tab.listVal[2].Get("data").AsBytes(); !bytes.Equal(b, data) {
+			t.Errorf("tabular %s: got %x", lit, b)
+		}
+	}
+
+	for _, bad := range []string{`hex"abc"`, `b64u"a+b"`} {
+		result, _ := ParseWithOptions(bad, ParseOptions{})
+		if result == nil || !result.HasErrors() {
+			t.Errorf("%s: expected parse error", bad)
+		} else if !strings.Contains(result.Errors[0].Message, "in bytes literal") {
+			t.Errorf("%s: unexpected error %s", bad, result.Errors[0].Message)
+		}
+	}
+
+	// A bare hex string next to a quoted string is still two values.
+	result, err := Parse(`[hex "ab"]`)
+	if err != nil || len(result.Value.listVal) != 2 {
+		t.Errorf("spaced prefix should not form a literal: %v", result.Value)
+	}
+}
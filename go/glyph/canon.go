@@ -116,7 +116,11 @@ func isRefSafe(s string) bool {
 	}
 	colon := strings.IndexByte(s, ':')
 	if colon < 0 {
-		// No prefix: whole string is the value.
+		// No prefix: whole string is the value. A leading '#' marks a
+		// content-hash ref (^#hash).
+		if s[0] == '#' && len(s) > 1 {
+			s = s[1:]
+		}
 		for i := 0; i < len(s); i++ {
 			if !isRefChar(s[i]) {
 				return false
@@ -188,6 +192,7 @@ func quoteString(s string) string {
 // canonValue returns the canonical string representation of any GValue.
 // This is used for scalar values; containers use specialized encoders.
 func canonValue(v *GValue) string {
+	v.force()
 	if v == nil {
 		return canonNull()
 	}
@@ -204,12 +209,14 @@ func canonValue(v *GValue) string {
 	case TypeStr:
 		return canonString(v.strVal)
 	case TypeID:
-		return canonRef(v.idVal)
+		return canonRef(v.idVal())
 	case TypeTime:
-		return canonTime(v.timeVal)
+		return canonTime(v.timeVal())
 	case TypeBytes:
 		// Base64 encoded per D6
-		return "b64" + quoteString(base64.StdEncoding.EncodeToString(v.bytesVal))
+		return "b64" + quoteString(base64.StdEncoding.EncodeToString(v.bytesVal()))
+	case TypeCustom:
+		return canonCustom(v.customVal())
 	default:
 		// Container types handled by specialized encoders
 		return ""
@@ -0,0 +1,118 @@
+package glyph
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// Batch Canonicalization
+// ============================================================
+//
+// An agent turn can carry thousands of small tool results, each
+// canonicalized on its own. Per call, CanonicalizeLooseWithOpts fills in
+// the option defaults, takes a builder that starts empty, copies the result
+// out of it, and, with a KeyDict, indexes the dictionary once for every map
+// it writes. CanonicalizeBatch does that setup once for all documents: it
+// writes them into one builder and hands out slices of the single string
+// that results, so the outputs share one allocation.
+//
+// The outputs are exactly those of CanonicalizeLooseWithOpts, document by
+// document. Since they share memory, keeping any one of them keeps the
+// text of its whole batch (or, in parallel, its chunk) alive.
+
+// minBatchChunk is the fewest documents a parallel batch gives a worker.
+const minBatchChunk = 64
+
+// CanonicalizeBatch returns the canonical form of each value, as
+// CanonicalizeLooseWithOpts(v, opts) would.
+func CanonicalizeBatch(vs []*GValue, opts LooseCanonOpts) []string {
+	out := make([]string, len(vs))
+	canonBatch(vs, out, batchCanonOpts(opts))
+	return out
+}
+
+// CanonicalizeBatchParallel is CanonicalizeBatch split over up to workers
+// goroutines (GOMAXPROCS if workers < 1). Small batches run on the caller's
+// goroutine.
+func CanonicalizeBatchParallel(vs []*GValue, opts LooseCanonOpts, workers int) []string {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	opts = batchCanonOpts(opts)
+	out := make([]string, len(vs))
+	chunk := (len(vs) + workers - 1) / workers
+	if chunk < minBatchChunk {
+		chunk = minBatchChunk
+	}
+	if chunk >= len(vs) {
+		canonBatch(vs, out, opts)
+		return out
+	}
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(vs); lo += chunk {
+		hi := lo + chunk
+		if hi > len(vs) {
+			hi = len(vs)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			canonBatch(vs[lo:hi], out[lo:hi], opts)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// batchCanonOpts fills in the defaults CanonicalizeLooseWithOpts applies
+// and indexes the key dictionary.
+func batchCanonOpts(opts LooseCanonOpts) LooseCanonOpts {
+	if opts.MinRows == 0 {
+		opts.MinRows = 3
+	}
+	if opts.MaxCols == 0 {
+		opts.MaxCols = 20
+	}
+	if opts.UseCompactKeys && opts.Schema == nil && len(opts.KeyDict) > 0 {
+		opts.keyIndex = make(map[string]int, len(opts.KeyDict))
+		for i, k := range opts.KeyDict {
+			opts.keyIndex[k] = i
+		}
+	}
+	return rootFieldOrder(opts)
+}
+
+// canonBatch writes the canonical forms of vs into out. Verify checks each
+// document once all are written, panicking like CanonicalizeLooseWithOpts.
+func canonBatch(vs []*GValue, out []string, opts LooseCanonOpts) {
+	var header string
+	if opts.VersionHeader {
+		header = EmitVersionHeader() + "\n"
+	}
+	var b strings.Builder
+	ends := make([]int, len(vs))
+	for i, v := range vs {
+		if v == nil {
+			b.WriteString(canonNull())
+		} else {
+			b.WriteString(header)
+			writeCanonLoose(&b, v, opts)
+		}
+		ends[i] = b.Len()
+	}
+
+	text := b.String()
+	start := 0
+	for i, end := range ends {
+		out[i] = text[start:end]
+		start = end
+		if opts.Verify && vs[i] != nil {
+			if err := verifyLoose(vs[i], out[i], opts); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package glyph
+
+import (
+	"strconv"
+	"testing"
+)
+
+func batchDocs(n int) []*GValue {
+	vs := make([]*GValue, n)
+	for i := range vs {
+		rows := make([]*GValue, i%5)
+		for j := range rows {
+			rows[j] = Map(MapEntry{Key: "id", Value: Int(int64(j))}, MapEntry{Key: "ok", Value: Bool(j%2 == 0)})
+		}
+		vs[i] = Map(
+			MapEntry{Key: "name", Value: Str("doc " + strconv.Itoa(i))},
+			MapEntry{Key: "id", Value: Int(int64(i))},
+			MapEntry{Key: "rows", Value: List(rows...)},
+		)
+	}
+	vs[3] = nil
+	vs[4] = Str("plain")
+	return vs
+}
+
+func TestCanonicalizeBatch(t *testing.T) {
+	compact := DefaultLooseCanonOpts()
+	compact.KeyDict = []string{"id", "name", "ok"}
+	compact.UseCompactKeys = true
+	header := NoTabularLooseCanonOpts()
+	header.VersionHeader = true
+	header.Verify = true
+
+	vs := batchDocs(300)
+	for name, opts := range map[string]LooseCanonOpts{
+		"zero":    {},
+		"default": DefaultLooseCanonOpts(),
+		"compact": compact,
+		"header":  header,
+	} {
+		for _, got := range [][]string{CanonicalizeBatch(vs, opts), CanonicalizeBatchParallel(vs, opts, 4)} {
+			if len(got) != len(vs) {
+				t.Fatalf("%s: %d outputs for %d values", name, len(got), len(vs))
+			}
+			for i, v := range vs {
+				if want := CanonicalizeLooseWithOpts(v, opts); got[i] != want {
+					t.Fatalf("%s: doc %d = %q, want %q", name, i, got[i], want)
+				}
+			}
+		}
+	}
+
+	if got := CanonicalizeBatch(nil, DefaultLooseCanonOpts()); len(got) != 0 {
+		t.Errorf("empty batch = %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// ============================================================
+// Schema-Order Canonical Profile
+// ============================================================
+//
+// Loose canonical form sorts keys bytewise, so "id" can land anywhere in a
+// record. Models read and write records more consistently when the order is
+// the semantic one (id first, then name, ...), so the schema-order profile
+// writes keys in the order the schema declares them instead: the fields of
+// a struct, or of a map the schema types as a struct, go in FID order, then
+// the fields without an @fid as declared, then any keys the type lacks in
+// byte order. Everything else is as in the default profile: maps the schema
+// does not type stay sorted, and so do the columns of untyped tables.
+//
+// The type of a value comes from its position under the root type
+// (LooseCanonOpts.FieldOrderRoot), through fields, list items, map values
+// and sum variants, or else from its own struct type name.
+//
+// The two profiles write different bytes for the same value, so their
+// fingerprints must not be compared: FingerprintLooseProfile hashes the
+// profile id, and the schema it orders by, along with the canonical form.
+
+// Canonical profile ids.
+const (
+	CanonProfileSorted      = "sorted"       // Keys in byte order (the default)
+	CanonProfileSchemaOrder = "schema-order" // Keys in schema declaration order
+)
+
+// CanonProfile returns the id of the canonical profile opts select.
+func (opts LooseCanonOpts) CanonProfile() string {
+	if opts.FieldOrder != nil {
+		return CanonProfileSchemaOrder
+	}
+	return CanonProfileSorted
+}
+
+// SchemaOrderLooseCanonOpts returns the default options with the
+// schema-order profile, for values of rootType ("" to order structs by
+// their type names only).
+func SchemaOrderLooseCanonOpts(schema *Schema, rootType string) LooseCanonOpts {
+	opts := DefaultLooseCanonOpts()
+	opts.FieldOrder = schema
+	opts.FieldOrderRoot = rootType
+	return opts
+}
+
+// FingerprintLooseProfile returns the SHA-256 hex digest of the no-tabular
+// canonical form of v under the profile opts select. For the default profile
+// it equals FingerprintLoose. For the schema-order profile the digest also
+// covers the profile id and the schema hash, so values ordered differently,
+// or by different schemas, never share a fingerprint.
+func FingerprintLooseProfile(v *GValue, opts LooseCanonOpts) string {
+	if opts.FieldOrder == nil {
+		return FingerprintLoose(v)
+	}
+	canonOpts := NoTabularLooseCanonOpts()
+	canonOpts.FieldOrder = opts.FieldOrder
+	canonOpts.FieldOrderRoot = opts.FieldOrderRoot
+	hash := opts.FieldOrder.Hash
+	if hash == "" {
+		hash = opts.FieldOrder.ComputeHash()
+	}
+	h := sha256.New()
+	h.Write([]byte("profile=" + CanonProfileSchemaOrder + " schema=" + hash + "\n"))
+	h.Write([]byte(CanonicalizeLooseWithOpts(v, canonOpts)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// rootFieldOrder returns opts with the type of the root value set, if the
+// schema-order profile names one.
+func rootFieldOrder(opts LooseCanonOpts) LooseCanonOpts {
+	if opts.FieldOrder != nil && opts.FieldOrderRoot != "" && opts.fieldOrder == nil {
+		ts := RefType(opts.FieldOrderRoot)
+		opts.fieldOrder = &ts
+	}
+	return opts
+}
+
+// orderStruct returns the struct type whose field order applies to a value
+// at ts, or nil if there is none.
+func (opts *LooseCanonOpts) orderStruct(ts *TypeSpec) *TypeDef {
+	if ts == nil {
+		return nil
+	}
+	switch ts.Kind {
+	case TypeSpecRef:
+		if td := opts.FieldOrder.GetType(ts.Name); td != nil && td.Struct != nil {
+			return td
+		}
+	case TypeSpecInlineStruct:
+		return &TypeDef{Kind: TypeDefStruct, Struct: ts.Struct}
+	}
+	return nil
+}
+
+// sortByFields moves the entries that are fields of td to the front, in
+// declaration order, keeping the rest in the order they had.
+func sortByFields(entries []sortableMapEntry, td *TypeDef) {
+	rank := make(map[*FieldDef]int)
+	for i, fd := range declaredFields(td.Struct) {
+		rank[fd] = i
+	}
+	pos := func(e sortableMapEntry) int {
+		if fd := td.FieldByKey(e.entry.Key); fd != nil {
+			return rank[fd]
+		}
+		return len(rank)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return pos(entries[i]) < pos(entries[j])
+	})
+}
+
+// declaredFields returns the fields of sd in FID order, then those without
+// an FID in the order declared. Unlike FieldsByFID, which sorts the latter
+// by name, it keeps the order the schema author chose.
+func declaredFields(sd *StructDef) []*FieldDef {
+	fields := make([]*FieldDef, len(sd.Fields))
+	copy(fields, sd.Fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		fi, fj := fields[i].FID, fields[j].FID
+		if fi == 0 || fj == 0 {
+			return fi != 0 && fj == 0
+		}
+		return fi < fj
+	})
+	return fields
+}
+
+// entryOrder returns the type of the value under key in a map at ts, whose
+// struct type, if any, is td.
+func entryOrder(ts *TypeSpec, td *TypeDef, key string) *TypeSpec {
+	if td != nil {
+		if fd := td.FieldByKey(key); fd != nil {
+			return &fd.Type
+		}
+		return nil
+	}
+	if ts != nil && ts.Kind == TypeSpecMap {
+		return ts.ValType
+	}
+	return nil
+}
+
+// variantOrder returns the type of the payload of variant tag in a sum at ts.
+func (opts *LooseCanonOpts) variantOrder(ts *TypeSpec, tag string) *TypeSpec {
+	if ts == nil || ts.Kind != TypeSpecRef {
+		return nil
+	}
+	td := opts.FieldOrder.GetType(ts.Name)
+	if td == nil || td.Sum == nil {
+		return nil
+	}
+	for _, v := range td.Sum.Variants {
+		if v.Tag == tag {
+			return &v.Type
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package glyph
+
+import "testing"
+
+func TestSchemaOrderProfile(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		User struct{
+			id: str
+			name: str
+			tags: list<Tag>
+			meta: map<str, Tag> [optional]
+		}
+		Tag struct{
+			slug: str
+			count: int
+		}
+		Result sum{ Ok: User  Err: str }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := func(slug string) *GValue {
+		return Map(MapEntry{Key: "slug", Value: Str(slug)}, MapEntry{Key: "count", Value: Int(1)})
+	}
+	user := Map(
+		MapEntry{Key: "zeta", Value: Int(0)},
+		MapEntry{Key: "name", Value: Str("Ada")},
+		MapEntry{Key: "tags", Value: List(tag("a"))},
+		MapEntry{Key: "meta", Value: Map(MapEntry{Key: "y", Value: tag("y")}, MapEntry{Key: "x", Value: tag("x")})},
+		MapEntry{Key: "id", Value: Str("u1")},
+	)
+
+	opts := SchemaOrderLooseCanonOpts(schema, "User")
+	want := "{id=u1 name=Ada tags=[{slug=a count=1}] meta={x={slug=x count=1} y={slug=y count=1}} zeta=0}"
+	if got := CanonicalizeLooseWithOpts(user, opts); got != want {
+		t.Errorf("map at root:\n got %s\nwant %s", got, want)
+	}
+	if got := CanonicalizeLoose(user); got == want {
+		t.Errorf("default profile ordered by schema: %s", got)
+	}
+
+	// Without a root type, structs are ordered by their type names, and a
+	// sum's payload by its variant.
+	opts.FieldOrderRoot = ""
+	s := Struct("Tag", MapEntry{Key: "count", Value: Int(2)}, MapEntry{Key: "slug", Value: Str("b")})
+	if got := CanonicalizeLooseWithOpts(s, opts); got != "{slug=b count=2}" {
+		t.Errorf("struct: %s", got)
+	}
+	opts.FieldOrderRoot = "Result"
+	ok := Sum("Ok", Map(MapEntry{Key: "name", Value: Str("Ada")}, MapEntry{Key: "id", Value: Str("u1")}, MapEntry{Key: "tags", Value: List()}))
+	if got := CanonicalizeLooseWithOpts(ok, opts); got != "{Ok={id=u1 name=Ada tags=[]}}" {
+		t.Errorf("sum: %s", got)
+	}
+
+	// Tables of typed rows keep working; untyped ones keep sorted columns.
+	rows := List(tag("a"), tag("b"), tag("c"))
+	if got := CanonicalizeLooseWithOpts(rows, SchemaOrderLooseCanonOpts(schema, "")); got != CanonicalizeLoose(rows) {
+		t.Errorf("untyped table changed: %s", got)
+	}
+}
+
+func TestFingerprintLooseProfile(t *testing.T) {
+	schema, err := ParseSchema(`@schema{ P struct{ b: int  a: int } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := Map(MapEntry{Key: "a", Value: Int(1)}, MapEntry{Key: "b", Value: Int(2)})
+	if FingerprintLooseProfile(v, DefaultLooseCanonOpts()) != FingerprintLoose(v) {
+		t.Error("default profile fingerprint differs from FingerprintLoose")
+	}
+	ordered := SchemaOrderLooseCanonOpts(schema, "P")
+	if ordered.CanonProfile() != CanonProfileSchemaOrder || DefaultLooseCanonOpts().CanonProfile() != CanonProfileSorted {
+		t.Error("CanonProfile")
+	}
+	fp := FingerprintLooseProfile(v, ordered)
+	if fp == FingerprintLoose(v) || len(fp) != 64 {
+		t.Errorf("schema-order fingerprint %s", fp)
+	}
+	if FingerprintLooseProfile(Map(MapEntry{Key: "b", Value: Int(2)}, MapEntry{Key: "a", Value: Int(1)}), ordered) != fp {
+		t.Error("fingerprint depends on input order")
+	}
+
+	// Same order, different schema: a different fingerprint.
+	other, _ := ParseSchema(`@schema{ P struct{ b: int  a: int  c: int [optional] } }`)
+	if FingerprintLooseProfile(v, SchemaOrderLooseCanonOpts(other, "P")) == fp {
+		t.Error("fingerprint ignores the schema")
+	}
+}
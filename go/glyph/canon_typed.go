@@ -0,0 +1,209 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Schema-Typed Canonicalization
+// ============================================================
+//
+// CanonicalizeTyped normalizes a value through its schema before writing the
+// canonical bytes, so two producers that agree on the schema-visible content
+// produce identical output even if they differ in:
+//   - field order, or name vs wire key spelling
+//   - optional fields that are absent vs explicitly null
+//   - fields left out vs set to their declared default
+//   - fields the schema does not know about
+//
+// Structs are written as Type{k=v ...} with fields in FID order (see
+// FieldsByFID) keyed by wire key, falling back to the field name. Values the
+// schema does not describe are written in no-tabular loose canonical form.
+
+// CanonicalizeTyped returns the schema-normalized canonical form of v, which
+// must be an instance of rootType.
+func CanonicalizeTyped(v *GValue, schema *Schema, rootType string) (string, error) {
+	if schema == nil {
+		return "", fmt.Errorf("typed canonicalization requires a schema")
+	}
+	if schema.GetType(rootType) == nil {
+		return "", fmt.Errorf("unknown type: %s", rootType)
+	}
+	if v != nil && hasNonFiniteFloat(v) {
+		return "", fmt.Errorf("non-finite float in typed canonicalization")
+	}
+	var b strings.Builder
+	ts := RefType(rootType)
+	if err := writeCanonTyped(&b, v, &ts, schema, "$"); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeCanonTyped(b *strings.Builder, v *GValue, ts *TypeSpec, schema *Schema, path string) error {
+	v.force()
+	opts := NoTabularLooseCanonOpts()
+	if v == nil || v.typ == TypeNull || ts == nil {
+		writeCanonLoose(b, v, opts)
+		return nil
+	}
+
+	switch ts.Kind {
+	case TypeSpecRef:
+		td := schema.GetType(ts.Name)
+		if td == nil {
+			if _, ok := DefaultScalarRegistry.Lookup(ts.Name); ok {
+				break
+			}
+			return fmt.Errorf("%s: unknown type: %s", path, ts.Name)
+		}
+		switch td.Kind {
+		case TypeDefStruct:
+			return writeStructTyped(b, v, td.Name, td.Struct, schema, path)
+		case TypeDefSum:
+			return writeSumTyped(b, v, td, schema, path)
+		}
+
+	case TypeSpecInlineStruct:
+		return writeStructTyped(b, v, "", ts.Struct, schema, path)
+
+	case TypeSpecList:
+		if v.typ != TypeList {
+			break
+		}
+		b.WriteByte('[')
+		for i, item := range v.listVal {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if err := writeCanonTyped(b, item, ts.Elem, schema, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+
+	case TypeSpecMap:
+		if v.typ != TypeMap {
+			break
+		}
+		return writeMapTyped(b, v.mapVal, ts.ValType, schema, path)
+	}
+
+	writeCanonLoose(b, v, opts)
+	return nil
+}
+
+// writeStructTyped writes a struct (or a map standing in for one) with fields
+// in FID order. Defaults fill missing fields, null optionals are dropped
+// unless the field keeps nulls, and unknown fields are ignored.
+func writeStructTyped(b *strings.Builder, v *GValue, typeName string, sd *StructDef, schema *Schema, path string) error {
+	v.force()
+	var fields []MapEntry
+	switch v.typ {
+	case TypeStruct:
+		fields = v.structVal.Fields
+	case TypeMap:
+		fields = v.mapVal
+	default:
+		return fmt.Errorf("%s: expected struct %s, got %s", path, typeName, v.typ)
+	}
+
+	td := &TypeDef{Name: typeName, Kind: TypeDefStruct, Struct: sd}
+	present := make(map[*FieldDef]*GValue, len(fields))
+	for _, e := range fields {
+		if fd := td.FieldByKey(e.Key); fd != nil {
+			present[fd] = e.Value
+		}
+	}
+
+	b.WriteString(typeName)
+	b.WriteByte('{')
+	first := true
+	for _, fd := range td.FieldsByFID() {
+		fv, ok := present[fd]
+		explicitNull := ok && (fv == nil || fv.typ == TypeNull)
+		if !ok || explicitNull {
+			switch {
+			case fd.Default != nil && !(explicitNull && fd.KeepNull):
+				fv = fd.Default
+			case explicitNull && fd.KeepNull:
+				// keep the null
+			case fd.Optional:
+				continue
+			default:
+				return fmt.Errorf("%s: missing required field %s", path, fd.Name)
+			}
+		}
+
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		key := fd.WireKey
+		if key == "" {
+			key = fd.Name
+		}
+		writeCanonString(b, key)
+		b.WriteByte('=')
+		if err := writeCanonTyped(b, fv, &fd.Type, schema, path+"."+fd.Name); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func writeSumTyped(b *strings.Builder, v *GValue, td *TypeDef, schema *Schema, path string) error {
+	if v.typ != TypeSum || v.sumVal == nil {
+		return fmt.Errorf("%s: expected sum %s, got %s", path, td.Name, v.typ)
+	}
+	for _, variant := range td.Sum.Variants {
+		if variant.Tag != v.sumVal.Tag {
+			continue
+		}
+		b.WriteString(td.Name)
+		b.WriteByte('{')
+		writeCanonString(b, variant.Tag)
+		b.WriteByte('=')
+		if err := writeCanonTyped(b, v.sumVal.Value, &variant.Type, schema, path+"."+variant.Tag); err != nil {
+			return err
+		}
+		b.WriteByte('}')
+		return nil
+	}
+	return fmt.Errorf("%s: unknown variant %s for %s", path, v.sumVal.Tag, td.Name)
+}
+
+// writeMapTyped writes map entries sorted by canonical key, matching the
+// ordering of writeMapLoose, with values normalized against valType.
+func writeMapTyped(b *strings.Builder, entries []MapEntry, valType *TypeSpec, schema *Schema, path string) error {
+	if len(entries) == 0 {
+		b.WriteString("{}")
+		return nil
+	}
+	type rendered struct{ key, val string }
+	out := make([]rendered, len(entries))
+	for i, e := range entries {
+		var vb strings.Builder
+		if err := writeCanonTyped(&vb, e.Value, valType, schema, path+"["+quoteString(e.Key)+"]"); err != nil {
+			return err
+		}
+		out[i] = rendered{key: canonString(e.Key), val: vb.String()}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key < out[j].key })
+
+	b.WriteByte('{')
+	for i, r := range out {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(r.key)
+		b.WriteByte('=')
+		b.WriteString(r.val)
+	}
+	b.WriteByte('}')
+	return nil
+}
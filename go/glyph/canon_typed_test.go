@@ -0,0 +1,99 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// canon_typed_test.go covers CanonicalizeTyped: FID ordering, wire keys,
+// default/null/unknown-field normalization, and nested type resolution.
+
+func makeTypedCanonSchema() *Schema {
+	return NewSchemaBuilder().
+		AddStruct("Team", "v1",
+			Field("name", PrimitiveType("str"), WithWireKey("n"), WithFID(2)),
+			Field("score", PrimitiveType("int"), WithWireKey("s"), WithFID(1), WithDefault(Int(0))),
+			Field("coach", PrimitiveType("str"), WithOptional(), WithFID(3)),
+		).
+		AddStruct("Match", "v1",
+			Field("home", RefType("Team"), WithFID(1)),
+			Field("tags", MapType(PrimitiveType("str"), RefType("Team")), WithOptional(), WithFID(2)),
+			Field("note", PrimitiveType("str"), WithOptional(), WithKeepNull(), WithFID(3)),
+		).
+		Build()
+}
+
+func TestCanonicalizeTyped_Normalizes(t *testing.T) {
+	schema := makeTypedCanonSchema()
+
+	a := Struct("Match",
+		FieldVal("home", Struct("Team", FieldVal("name", Str("ARS")), FieldVal("score", Int(0)))),
+	)
+	b := Map(
+		MapEntry{Key: "extra", Value: Str("ignored")},
+		MapEntry{Key: "home", Value: Map(
+			MapEntry{Key: "coach", Value: Null()},
+			MapEntry{Key: "n", Value: Str("ARS")},
+			MapEntry{Key: "debug", Value: Int(7)},
+		)},
+	)
+
+	ca, err := CanonicalizeTyped(a, schema, "Match")
+	if err != nil {
+		t.Fatalf("CanonicalizeTyped(a) error: %v", err)
+	}
+	cb, err := CanonicalizeTyped(b, schema, "Match")
+	if err != nil {
+		t.Fatalf("CanonicalizeTyped(b) error: %v", err)
+	}
+	if want := "Match{home=Team{s=0 n=ARS}}"; ca != want {
+		t.Errorf("got %q, want %q", ca, want)
+	}
+	if ca != cb {
+		t.Errorf("noise changed typed form:\n%s\n%s", ca, cb)
+	}
+	if CanonicalizeLoose(a) == CanonicalizeLoose(b) {
+		t.Error("loose forms should differ for this fixture")
+	}
+}
+
+func TestCanonicalizeTyped_NestedAndKeepNull(t *testing.T) {
+	schema := makeTypedCanonSchema()
+	v := Struct("Match",
+		FieldVal("home", Struct("Team", FieldVal("n", Str("A")))),
+		FieldVal("tags", Map(
+			MapEntry{Key: "z", Value: Struct("Team", FieldVal("name", Str("Z")), FieldVal("x", Int(1)))},
+			MapEntry{Key: "a", Value: Struct("Team", FieldVal("name", Str("Y")), FieldVal("coach", Str("c")))},
+		)),
+		FieldVal("note", Null()),
+	)
+	got, err := CanonicalizeTyped(v, schema, "Match")
+	if err != nil {
+		t.Fatalf("CanonicalizeTyped error: %v", err)
+	}
+	want := "Match{home=Team{s=0 n=A} tags={a=Team{s=0 n=Y coach=c} z=Team{s=0 n=Z}} note=∅}"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestCanonicalizeTyped_Errors(t *testing.T) {
+	schema := makeTypedCanonSchema()
+	cases := map[string]struct {
+		v        *GValue
+		schema   *Schema
+		rootType string
+		want     string
+	}{
+		"nil-schema":       {Struct("Match"), nil, "Match", "requires a schema"},
+		"unknown-root":     {Struct("Match"), schema, "Nope", "unknown type"},
+		"missing-required": {Struct("Match"), schema, "Match", "missing required field home"},
+		"wrong-shape":      {Struct("Match", FieldVal("home", Int(1))), schema, "Match", "expected struct Team"},
+	}
+	for name, tc := range cases {
+		_, err := CanonicalizeTyped(tc.v, tc.schema, tc.rootType)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want error containing %q", name, err, tc.want)
+		}
+	}
+}
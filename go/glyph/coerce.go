@@ -0,0 +1,282 @@
+package glyph
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Schema-Driven Value Coercion
+// ============================================================
+//
+// LLMs often emit stringly-typed values: "42" for an int field, "true" for a
+// bool, an ISO date string for a time. Coerce walks a value alongside its
+// schema and converts such values to the type the schema declares, so the
+// result validates and canonicalizes like hand-written input.
+//
+// Conversions performed (anything else is left unchanged for Validate to
+// report):
+//   - int:   numeric string ("42", " -7 "), or a float with no fraction
+//   - float: numeric string ("3.5", "1e3"); non-finite values are rejected
+//   - int/float strings written with locale habits ("1,234", "+5", "12%",
+//     "3,5") are repaired as described in number_repair.go
+//   - bool:  "true"/"false"/"t"/"f"/"1"/"0" in any case
+//   - time:  ISO-8601 string in any format the parsers accept
+//   - id:    "^prefix:value" string, digit-only string, or int
+//   - custom scalar: string its registered Parse hook accepts
+
+// Coercion records a single value converted by Coerce.
+type Coercion struct {
+	Path string // Location of the value, in Validator path form ("" is the root)
+	From GType  // Type of the original value
+	To   GType  // Type of the converted value
+	Raw  string // Original value in loose canonical form
+
+	// Repairs names the number repairs applied (RepairThousandsSeparator,
+	// etc.), if any.
+	Repairs []string
+}
+
+func (c Coercion) String() string {
+	path := c.Path
+	if path == "" {
+		path = "$"
+	}
+	if len(c.Repairs) > 0 {
+		return fmt.Sprintf("%s: %s %s -> %s (%s)", path, c.From, c.Raw, c.To, strings.Join(c.Repairs, ", "))
+	}
+	return fmt.Sprintf("%s: %s %s -> %s", path, c.From, c.Raw, c.To)
+}
+
+// CoerceReport lists the coercions performed by Coerce, in document order.
+type CoerceReport struct {
+	Coercions []Coercion
+}
+
+// Changed reports whether any value was converted.
+func (r *CoerceReport) Changed() bool {
+	return len(r.Coercions) > 0
+}
+
+// Coerce returns a copy of v, an instance of rootType, with stringly-typed
+// scalars converted to the types schema declares for them. The input is not
+// modified; containers are copied only along paths that changed. The report
+// lists every conversion. Values that cannot be converted are kept as-is.
+func Coerce(v *GValue, schema *Schema, rootType string) (*GValue, *CoerceReport, error) {
+	if schema == nil {
+		return nil, nil, fmt.Errorf("coercion requires a schema")
+	}
+	if schema.GetType(rootType) == nil {
+		return nil, nil, fmt.Errorf("unknown type: %s", rootType)
+	}
+	c := &coercer{schema: schema, report: &CoerceReport{}}
+	out := c.coerce(v, RefType(rootType), "")
+	return out, c.report, nil
+}
+
+type coercer struct {
+	schema *Schema
+	report *CoerceReport
+}
+
+func (c *coercer) coerce(v *GValue, ts TypeSpec, path string) *GValue {
+	v.force()
+	if v == nil || v.typ == TypeNull {
+		return v
+	}
+
+	switch ts.Kind {
+	case TypeSpecInt, TypeSpecFloat, TypeSpecBool, TypeSpecTime, TypeSpecID:
+		if out, repairs := coerceScalar(v, ts.Kind); out != nil {
+			c.report.Coercions = append(c.report.Coercions, Coercion{
+				Path:    path,
+				From:    v.typ,
+				To:      out.typ,
+				Raw:     CanonicalizeLoose(v),
+				Repairs: repairs,
+			})
+			return out
+		}
+
+	case TypeSpecList:
+		if v.typ != TypeList || ts.Elem == nil {
+			break
+		}
+		var items []*GValue
+		for i, item := range v.listVal {
+			if out := c.coerce(item, *ts.Elem, fmt.Sprintf("%s[%d]", path, i)); out != item {
+				if items == nil {
+					items = append([]*GValue(nil), v.listVal...)
+				}
+				items[i] = out
+			}
+		}
+		if items != nil {
+			return List(items...)
+		}
+
+	case TypeSpecMap:
+		if v.typ != TypeMap || ts.ValType == nil {
+			break
+		}
+		if entries := c.coerceEntries(v.mapVal, path, func(string) *TypeSpec { return ts.ValType }); entries != nil {
+			return Map(entries...)
+		}
+
+	case TypeSpecRef:
+		td := c.schema.GetType(ts.Name)
+		if td == nil {
+			// A registered custom scalar: convert its text.
+			if _, ok := DefaultScalarRegistry.Lookup(ts.Name); ok && v.typ == TypeStr {
+				if out, err := decodeScalarLiteral(ts.Name, v.strVal); err == nil {
+					c.report.Coercions = append(c.report.Coercions, Coercion{
+						Path: path,
+						From: v.typ,
+						To:   out.typ,
+						Raw:  CanonicalizeLoose(v),
+					})
+					return out
+				}
+			}
+			break
+		}
+		switch td.Kind {
+		case TypeDefStruct:
+			return c.coerceStruct(v, td.Struct, path)
+		case TypeDefSum:
+			return c.coerceSum(v, td.Sum, path)
+		}
+
+	case TypeSpecInlineStruct:
+		return c.coerceStruct(v, ts.Struct, path)
+	}
+
+	return v
+}
+
+// coerceStruct coerces the declared fields of a struct (or a map standing in
+// for one). Unknown fields pass through untouched.
+func (c *coercer) coerceStruct(v *GValue, sd *StructDef, path string) *GValue {
+	v.force()
+	if sd == nil {
+		return v
+	}
+	td := &TypeDef{Kind: TypeDefStruct, Struct: sd}
+	fieldType := func(key string) *TypeSpec {
+		if fd := td.FieldByKey(key); fd != nil {
+			return &fd.Type
+		}
+		return nil
+	}
+
+	switch v.typ {
+	case TypeStruct:
+		if fields := c.coerceEntries(v.structVal.Fields, path, fieldType); fields != nil {
+			return Struct(v.structVal.TypeName, fields...)
+		}
+	case TypeMap:
+		if entries := c.coerceEntries(v.mapVal, path, fieldType); entries != nil {
+			return Map(entries...)
+		}
+	}
+	return v
+}
+
+func (c *coercer) coerceSum(v *GValue, sd *SumDef, path string) *GValue {
+	if v.typ != TypeSum || v.sumVal == nil || sd == nil {
+		return v
+	}
+	for _, variant := range sd.Variants {
+		if variant.Tag != v.sumVal.Tag {
+			continue
+		}
+		if out := c.coerce(v.sumVal.Value, variant.Type, joinPath(path, variant.Tag)); out != v.sumVal.Value {
+			return Sum(v.sumVal.Tag, out)
+		}
+		break
+	}
+	return v
+}
+
+// coerceEntries coerces each entry against the type returned by typeOf (nil
+// means leave the entry alone). It returns nil when nothing changed, so
+// callers can keep the original container.
+func (c *coercer) coerceEntries(entries []MapEntry, path string, typeOf func(key string) *TypeSpec) []MapEntry {
+	var out []MapEntry
+	for i, e := range entries {
+		ts := typeOf(e.Key)
+		if ts == nil {
+			continue
+		}
+		if nv := c.coerce(e.Value, *ts, joinPath(path, e.Key)); nv != e.Value {
+			if out == nil {
+				out = append([]MapEntry(nil), entries...)
+			}
+			out[i].Value = nv
+		}
+	}
+	return out
+}
+
+// coerceScalar converts v to the scalar kind, returning nil when v already has
+// an acceptable type or cannot be converted. Number repairs applied on the way
+// are returned alongside.
+func coerceScalar(v *GValue, kind TypeSpecKind) (*GValue, []string) {
+	switch kind {
+	case TypeSpecInt:
+		switch v.typ {
+		case TypeStr:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v.strVal), 10, 64); err == nil {
+				return Int(n), nil
+			}
+			return repairNumber(v.strVal, kind)
+		case TypeFloat:
+			if isInteger(v.floatVal) && math.Abs(v.floatVal) < 1<<63 {
+				return Int(int64(v.floatVal)), nil
+			}
+		}
+
+	case TypeSpecFloat:
+		if v.typ == TypeStr {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.strVal), 64)
+			if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return Float(f), nil
+			}
+			return repairNumber(v.strVal, kind)
+		}
+
+	case TypeSpecBool:
+		if v.typ == TypeStr {
+			if b, err := strconv.ParseBool(strings.TrimSpace(v.strVal)); err == nil {
+				return Bool(b), nil
+			}
+		}
+
+	case TypeSpecTime:
+		if v.typ == TypeStr {
+			if t, err := parseTimeLiteralStr(strings.TrimSpace(v.strVal)); err == nil {
+				return t, nil
+			}
+		}
+
+	case TypeSpecID:
+		switch v.typ {
+		case TypeStr:
+			s := strings.TrimSpace(v.strVal)
+			if ref, ok := strings.CutPrefix(s, "^"); ok && ref != "" {
+				if prefix, value, found := strings.Cut(ref, ":"); found {
+					return ID(prefix, value), nil
+				}
+				return ID("", ref), nil
+			}
+			if s != "" && strings.Trim(s, "0123456789") == "" {
+				return ID("", s), nil
+			}
+		case TypeInt:
+			return ID("", strconv.FormatInt(v.intVal, 10)), nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,106 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func makeCoerceSchema() *Schema {
+	return NewSchemaBuilder().
+		AddStruct("Item", "v1",
+			Field("qty", PrimitiveType("int")),
+			Field("price", PrimitiveType("float"), WithWireKey("p")),
+		).
+		AddOpenStruct("Order", "v1",
+			Field("id", PrimitiveType("id")),
+			Field("paid", PrimitiveType("bool")),
+			Field("at", PrimitiveType("time")),
+			Field("note", PrimitiveType("str"), WithOptional()),
+			Field("items", ListType(RefType("Item"))),
+			Field("tags", MapType(PrimitiveType("str"), PrimitiveType("int")), WithOptional()),
+		).
+		Build()
+}
+
+func TestCoerce_StringlyTypedOrder(t *testing.T) {
+	schema := makeCoerceSchema()
+	v := Struct("Order",
+		FieldVal("id", Str("^o:A1")),
+		FieldVal("paid", Str("TRUE")),
+		FieldVal("at", Str("2025-01-02T03:04:05Z")),
+		FieldVal("note", Str("42")),
+		FieldVal("items", List(
+			Struct("Item", FieldVal("qty", Str(" 3 ")), FieldVal("p", Str("9.5"))),
+			Struct("Item", FieldVal("qty", Float(2)), FieldVal("p", Float(1.25))),
+		)),
+		FieldVal("tags", Map(MapEntry{Key: "rank", Value: Str("7")})),
+		FieldVal("extra", Str("1")),
+	)
+	before := CanonicalizeLoose(v)
+
+	out, report, err := Coerce(v, schema, "Order")
+	if err != nil {
+		t.Fatalf("Coerce error: %v", err)
+	}
+	if CanonicalizeLoose(v) != before {
+		t.Error("Coerce modified its input")
+	}
+	if res := ValidateAs(out, schema, "Order"); !res.Valid {
+		t.Errorf("coerced value should validate: %v", res.Errors)
+	}
+
+	var paths []string
+	for _, c := range report.Coercions {
+		paths = append(paths, c.Path)
+	}
+	want := "id paid at items[0].qty items[0].p items[1].qty tags.rank"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("coerced paths:\n got %s\nwant %s", got, want)
+	}
+
+	id := out.Get("id")
+	if id.typ != TypeID || id.idVal() != (RefID{Prefix: "o", Value: "A1"}) {
+		t.Errorf("id: got %s", CanonicalizeLoose(id))
+	}
+	if note := out.Get("note"); note.typ != TypeStr {
+		t.Errorf("str field should stay a string, got %s", note.typ)
+	}
+	if got := report.Coercions[1].String(); got != "paid: str TRUE -> bool" {
+		t.Errorf("Coercion.String() = %q", got)
+	}
+}
+
+func TestCoerce_LeavesUnconvertibleAndShares(t *testing.T) {
+	schema := makeCoerceSchema()
+	items := List(Struct("Item", FieldVal("qty", Int(1)), FieldVal("price", Float(2))))
+	v := Struct("Order",
+		FieldVal("id", Str("abc")),
+		FieldVal("paid", Str("maybe")),
+		FieldVal("at", Str("yesterday")),
+		FieldVal("items", items),
+	)
+
+	out, report, err := Coerce(v, schema, "Order")
+	if err != nil {
+		t.Fatalf("Coerce error: %v", err)
+	}
+	if report.Changed() {
+		t.Errorf("nothing should be coerced, got %v", report.Coercions)
+	}
+	if out != v {
+		t.Error("unchanged value should be returned as-is")
+	}
+
+	for _, s := range []string{"NaN", "Inf", "1.5x"} {
+		if got, _ := coerceScalar(Str(s), TypeSpecFloat); got != nil {
+			t.Errorf("%s: expected no float coercion, got %s", s, CanonicalizeLoose(got))
+		}
+	}
+	if got, _ := coerceScalar(Int(12), TypeSpecID); got == nil || got.idVal().Value != "12" {
+		t.Error("int should coerce to id")
+	}
+
+	if _, _, err := Coerce(v, schema, "Nope"); err == nil {
+		t.Error("expected error for unknown root type")
+	}
+}
@@ -0,0 +1,194 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// Legacy Document Compatibility
+// ============================================================
+//
+// Documents stored by earlier versions of the library must stay readable.
+// Three forms have changed since v2.2 and v2.3:
+//
+//   - @tab headers without rows= and cols= (added in v2.4);
+//   - inline schema definitions spelled keys=[...] rather than @keys=[...];
+//   - null written only as ∅. Before v2.4, _ was not a null alias, so a
+//     bare _ was the string "_".
+//
+// The first two are unambiguous, so parsers read them by default. The third
+// changes what a document means, so reading _ as a string takes an explicit
+// CompatLevel. ParseSession.SetCompat selects it:
+//
+//	s := glyph.NewParseSession(nil)
+//	s.SetCompat(glyph.CompatV23)
+//	v, err := s.Parse(stored) // _ reads as "_"
+//
+// CompatStrict goes the other way and rejects the legacy forms, for checking
+// that a producer writes the current format.
+
+// CompatLevel selects which earlier document formats a ParseSession reads.
+type CompatLevel uint8
+
+const (
+	// CompatDefault reads current documents and the unambiguous legacy
+	// forms: @tab headers without rows= and cols=, and keys=[...] schema
+	// definitions. Both _ and ∅ are null.
+	CompatDefault CompatLevel = iota
+
+	// CompatStrict reads only the current format: @tab headers must declare
+	// rows= and cols=, and schema definitions must use @keys=.
+	CompatStrict
+
+	// CompatV23 reads documents written by v2.2 and v2.3, which wrote null
+	// only as ∅: a bare _ is the string "_". The other legacy forms are read
+	// as under CompatDefault.
+	CompatV23
+)
+
+// String returns the level's name.
+func (c CompatLevel) String() string {
+	switch c {
+	case CompatDefault:
+		return "default"
+	case CompatStrict:
+		return "strict"
+	case CompatV23:
+		return "v2.3"
+	}
+	return fmt.Sprintf("CompatLevel(%d)", uint8(c))
+}
+
+// SetCompat sets which earlier document formats the session reads.
+// CompatDefault is the default.
+func (s *ParseSession) SetCompat(level CompatLevel) {
+	s.compat = level
+}
+
+// applyCompat checks or rewrites input, without its @glyph and @doc headers,
+// into the current format according to level.
+func applyCompat(input string, level CompatLevel) (string, error) {
+	switch level {
+	case CompatStrict:
+		return input, checkCurrentFormat(input)
+	case CompatV23:
+		return quoteLegacyUnderscores(input), nil
+	}
+	return input, nil
+}
+
+// checkCurrentFormat returns an error for the first legacy form in input.
+func checkCurrentFormat(input string) error {
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@schema") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "keys=") {
+				return fmt.Errorf("legacy schema definition %q: use @keys=[...]", line)
+			}
+		}
+	}
+
+	for i := 0; i < len(input); i++ {
+		switch {
+		case input[i] == '"':
+			i = skipQuoted(input, i)
+		case tabHeaderAt(input, i):
+			header := input[i:]
+			if end := strings.IndexByte(header, '['); end >= 0 {
+				header = header[:end]
+			}
+			var rows, cols bool
+			for _, field := range strings.Fields(header) {
+				rows = rows || strings.HasPrefix(field, "rows=")
+				cols = cols || strings.HasPrefix(field, "cols=")
+			}
+			if !rows || !cols {
+				return fmt.Errorf("legacy @tab header %q: missing rows= or cols=", strings.TrimSpace(header))
+			}
+		}
+	}
+	return nil
+}
+
+// quoteLegacyUnderscores rewrites each bare _ value in input as "_", so the
+// current parser reads it as a string rather than null. @tab headers, where _
+// marks an untyped table, and directive lines are left alone.
+func quoteLegacyUnderscores(input string) string {
+	var b strings.Builder
+	b.Grow(len(input) + 8)
+	lineStart := true
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\n':
+			lineStart = true
+			b.WriteByte(c)
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			b.WriteByte(c)
+			continue
+		case c == '"':
+			end := skipQuoted(input, i)
+			b.WriteString(input[i : end+1])
+			i = end
+		case tabHeaderAt(input, i):
+			// Copy the header through its column list.
+			end := strings.IndexByte(input[i:], ']')
+			if end < 0 {
+				end = len(input) - i - 1
+			}
+			b.WriteString(input[i : i+end+1])
+			i += end
+		case lineStart && c == '@':
+			// Copy the directive line.
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input) - i
+			}
+			b.WriteString(input[i : i+end])
+			i += end - 1
+		case c == '_' && bareBoundary(input, i-1) && bareBoundary(input, i+1):
+			b.WriteString(`"_"`)
+		default:
+			b.WriteByte(c)
+		}
+		lineStart = false
+	}
+	return b.String()
+}
+
+// tabHeaderAt reports whether an @tab or @tabc header starts at input[i].
+func tabHeaderAt(input string, i int) bool {
+	if i > 0 && !bareBoundary(input, i-1) {
+		return false
+	}
+	rest := input[i:]
+	return strings.HasPrefix(rest, "@tab ") || strings.HasPrefix(rest, "@tabc ")
+}
+
+// bareBoundary reports whether input[i] ends a bare token on that side:
+// out of range, whitespace, or a delimiter.
+func bareBoundary(input string, i int) bool {
+	if i < 0 || i >= len(input) {
+		return true
+	}
+	return strings.IndexByte(" \t\r\n=[]{}()|", input[i]) >= 0
+}
+
+// skipQuoted returns the index of the quote closing the string that opens
+// at input[i], or the last index if it is unterminated.
+func skipQuoted(input string, i int) int {
+	for j := i + 1; j < len(input); j++ {
+		switch input[j] {
+		case '\\':
+			j++
+		case '"':
+			return j
+		}
+	}
+	return len(input) - 1
+}
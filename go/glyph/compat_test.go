@@ -0,0 +1,97 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// Documents as v2.2 and v2.3 wrote them: no rows=/cols=, keys=[...], ∅ for
+// null and _ for the string "_".
+const (
+	legacyTable  = "@tab _ [id sep]\n|1|_|\n|2|∅|\n|3|\"_\"|\n@end"
+	legacySchema = "@schema#s1 keys=[id sep]\n{#0=1 #1=_}"
+	legacyNested = "{a=_ b=[_ ∅ x_y] c=\"_\" d=@tab _ [k]\n|_|\n|v|\n|w|\n@end}"
+)
+
+func TestCompat_Default(t *testing.T) {
+	v, err := NewParseSession(nil).Parse(legacyTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := v.AsList()
+	if len(rows) != 3 || !rows[0].Get("sep").IsNull() || !rows[1].Get("sep").IsNull() {
+		t.Errorf("table = %s", CanonicalizeLoose(v))
+	}
+
+	v, err = NewParseSession(nil).Parse(legacySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Get("sep").IsNull() {
+		t.Errorf("schema payload = %s", CanonicalizeLoose(v))
+	}
+}
+
+func TestCompat_V23(t *testing.T) {
+	s := NewParseSession(nil)
+	s.SetCompat(CompatV23)
+
+	v, err := s.Parse(legacyTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := v.AsList()
+	want := []string{"_", "", "_"}
+	for i, row := range rows {
+		got, _ := row.Get("sep").AsStr()
+		if got != want[i] || (want[i] == "") != row.Get("sep").IsNull() {
+			t.Errorf("row %d sep = %s", i, CanonicalizeLoose(row.Get("sep")))
+		}
+	}
+
+	v, _, err = s.ParsePayload(legacySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := v.Get("sep").AsStr(); got != "_" {
+		t.Errorf("schema payload = %s", CanonicalizeLoose(v))
+	}
+
+	v, err = s.Parse(legacyNested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantNested = `{a="_" b=["_" ∅ x_y] c="_" d=[{k="_"} {k=v} {k=w}]}`
+	if got := CanonicalizeLooseNoTabular(v); got != wantNested {
+		t.Errorf("nested = %s\nwant     %s", got, wantNested)
+	}
+}
+
+func TestCompat_Strict(t *testing.T) {
+	s := NewParseSession(nil)
+	s.SetCompat(CompatStrict)
+
+	for _, legacy := range []string{legacyTable, legacySchema, "{rows=@tab _ [k]\n|v|\n@end}"} {
+		if _, err := s.Parse(legacy); err == nil || !strings.Contains(err.Error(), "legacy") {
+			t.Errorf("Parse(%q) = %v, want legacy error", legacy, err)
+		}
+	}
+
+	// Current output, and legacy text inside strings, are accepted.
+	rows := List(
+		Map(MapEntry{Key: "id", Value: Int(1)}, MapEntry{Key: "note", Value: Str("@tab _ [x]")}),
+		Map(MapEntry{Key: "id", Value: Int(2)}, MapEntry{Key: "note", Value: Null()}),
+		Map(MapEntry{Key: "id", Value: Int(3)}, MapEntry{Key: "note", Value: Str("keys=[a]")}),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.Schema = NewSchemaContext([]string{"id", "note"})
+	for _, doc := range []string{CanonicalizeLoose(rows), CanonicalizeLooseWithSchema(rows, opts)} {
+		if _, err := s.Parse(doc); err != nil {
+			t.Errorf("Parse(%q): %v", doc, err)
+		}
+	}
+
+	if CompatV23.String() != "v2.3" || CompatLevel(9).String() != "CompatLevel(9)" {
+		t.Error("CompatLevel.String")
+	}
+}
@@ -1134,7 +1134,7 @@ func TestSchemaBuilder_WithPack_ByName(t *testing.T) {
 }
 
 // ============================================================
-// Additional document.go — parseMapWithEmbeddedTab edge cases
+// Additional document.go — embedded @tab edge cases
 // ============================================================
 
 func TestParseDocument_EmbeddedTabWithEnd(t *testing.T) {
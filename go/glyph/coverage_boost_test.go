@@ -713,21 +713,6 @@ other=42}`
 	}
 }
 
-func TestIsDocKeyStart(t *testing.T) {
-	if !isDocKeyStart("key=value") {
-		t.Error("should recognize key=")
-	}
-	if isDocKeyStart("=value") {
-		t.Error("should reject starting with =")
-	}
-	if isDocKeyStart("no equals") {
-		t.Error("should reject no equals")
-	}
-	if isDocKeyStart("") {
-		t.Error("should reject empty")
-	}
-}
-
 // ============================================================
 // parse_header.go — uncovered paths
 // ============================================================
@@ -0,0 +1,219 @@
+//go:build determinism
+
+package glyph
+
+// Canonical output determinism suite.
+//
+// Fingerprints and state hashes are SHA-256 over canonical strings, so those
+// strings must be byte-identical on every platform and Go release. This
+// suite canonicalizes a fixed corpus of awkward values and compares the
+// output with testdata/determinism/canonical.golden. CI runs it under a
+// matrix of GOOS, GOARCH and Go versions:
+//
+//	go test -tags determinism -run Determinism ./glyph/
+//
+// After an intentional format change, regenerate the golden file with
+// -update-golden and review the diff.
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // Zone rules from the binary, not the host
+)
+
+const determinismGolden = "testdata/determinism/canonical.golden"
+
+type determinismCase struct {
+	name string
+	v    *GValue
+}
+
+func determinismCorpus(t *testing.T) []determinismCase {
+	t.Helper()
+	var cases []determinismCase
+	add := func(name string, v *GValue) {
+		cases = append(cases, determinismCase{name, v})
+	}
+	fromJSON := func(name, json string) {
+		v, err := FromJSONLoose([]byte(json))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		add(name, v)
+	}
+
+	// Maps with unicode keys: byte order, not collation; NFC and NFD spellings
+	// of é stay distinct.
+	add("map/unicode-keys", Map(
+		MapEntry{Key: "zebra", Value: Int(1)},
+		MapEntry{Key: "Zebra", Value: Int(2)},
+		MapEntry{Key: "é", Value: Int(3)},
+		MapEntry{Key: "e\u0301", Value: Int(4)},
+		MapEntry{Key: "日本", Value: Int(5)},
+		MapEntry{Key: "🙂", Value: Int(6)},
+		MapEntry{Key: "ß", Value: Int(7)},
+		MapEntry{Key: "", Value: Int(8)},
+		MapEntry{Key: "with space", Value: Int(9)},
+		MapEntry{Key: "a=b", Value: Int(10)},
+		MapEntry{Key: "\u00a0nbsp", Value: Int(11)},
+		MapEntry{Key: "\U0010FFFF", Value: Int(12)},
+	))
+	fromJSON("map/json-unicode-keys", `{"ü":1,"u":2,"Ω":3,"\u2028":4,"k\"q":5,"\t":6}`)
+	fromJSON("map/nested", `{"b":{"y":[1,{"d":1,"c":2}],"x":null},"a":[{"k":"v"},{"k":"w"}]}`)
+
+	// Float corner cases, built from exact bits so nothing is computed on the
+	// platform under test.
+	floats := []struct {
+		name string
+		bits uint64
+	}{
+		{"zero", 0},
+		{"neg-zero", 0x8000000000000000},
+		{"one", math.Float64bits(1)},
+		{"tenth", math.Float64bits(0.1)},
+		{"point-three-sum", 0x3FD3333333333334}, // 0.1 + 0.2
+		{"third", math.Float64bits(1.0 / 3)},
+		{"max", math.Float64bits(math.MaxFloat64)},
+		{"min-normal", 0x0010000000000000},
+		{"max-subnormal", 0x000FFFFFFFFFFFFF},
+		{"min-subnormal", 1},
+		{"2^53", math.Float64bits(1 << 53)},
+		{"2^53+2", math.Float64bits(1<<53 + 2)},
+		{"1e20", math.Float64bits(1e20)},
+		{"1e21", math.Float64bits(1e21)},
+		{"1e-6", math.Float64bits(1e-6)},
+		{"1e-7", math.Float64bits(1e-7)},
+		{"neg-large", math.Float64bits(-123456789.125)},
+		{"integral", math.Float64bits(42)},
+	}
+	for _, f := range floats {
+		add("float/"+f.name, Float(math.Float64frombits(f.bits)))
+	}
+	fromJSON("float/json", `[0.1,-0.0,1E-7,123456789012345678901234567890,2.5e-324,0.30000000000000004]`)
+	add("int/extremes", List(Int(math.MinInt64), Int(math.MaxInt64), Int(0), Int(-1)))
+
+	// One instant in several zones canonicalizes the same; zone rules come
+	// from time/tzdata, so the host's zoneinfo does not matter.
+	instant := time.Date(2024, 3, 10, 7, 30, 0, 123456789, time.UTC) // US DST change
+	for _, zone := range []string{"UTC", "America/New_York", "Asia/Kolkata", "Australia/Lord_Howe", "Pacific/Chatham"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add("time/"+zone, Time(instant.In(loc)))
+	}
+	add("time/fixed-offset", Time(instant.In(time.FixedZone("", -(9*3600+30*60)))))
+	add("time/whole-second", Time(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)))
+	add("time/millis", Time(time.Date(2000, 2, 29, 0, 0, 0, 1e6, time.UTC)))
+	add("time/pre-epoch", Time(time.Date(1901, 12, 13, 20, 45, 52, 1, time.UTC)))
+	add("time/year-1", Time(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)))
+	add("time/year-9999", Time(time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)))
+
+	// Strings and the rest of the scalar types.
+	add("str/escapes", List(Str(""), Str("a\"b\\c"), Str("\n\t\r\x00\x1f"), Str("t"), Str("null"), Str("12"), Str("  pad  "), Str("\u2028\u2029"), Str("\xff\xfe")))
+	add("str/unicode", List(Str("naïve"), Str("nai\u0308ve"), Str("👩‍👩‍👧"), Str("עברית"), Str("\ufeffbom")))
+	add("scalar/misc", List(Null(), Bool(true), Bool(false), Bytes([]byte{0, 1, 2, 0xff}), Bytes(nil), ID("user", "42"), ID("", "x y")))
+	add("struct/sum", Struct("Point", MapEntry{Key: "y", Value: Float(-0.5)}, MapEntry{Key: "x", Value: Int(1)}))
+	add("sum/tagged", Sum("Ok", Map(MapEntry{Key: "b", Value: Int(1)}, MapEntry{Key: "a", Value: Int(2)})))
+
+	// Tabular lists, including a ragged one and unicode column names.
+	fromJSON("table/rows", `[{"id":1,"名前":"a"},{"id":2,"名前":"b"},{"id":3,"名前":"c"}]`)
+	fromJSON("table/ragged", `[{"a":1},{"b":2.5},{"a":null,"b":"x"}]`)
+	return cases
+}
+
+// determinismOutputs returns the canonical forms recorded for v.
+func determinismOutputs(v *GValue) [][2]string {
+	return [][2]string{
+		{"loose", CanonicalizeLoose(v)},
+		{"notab", CanonicalizeLooseNoTabular(v)},
+		{"emit", Emit(v)},
+		{"sha256", FingerprintLoose(v)},
+	}
+}
+
+func TestDeterminism_Golden(t *testing.T) {
+	var b strings.Builder
+	for _, c := range determinismCorpus(t) {
+		for _, out := range determinismOutputs(c.v) {
+			fmt.Fprintf(&b, "%s\t%s\t%s\n", c.name, out[0], strconv.QuoteToASCII(out[1]))
+		}
+	}
+	got := b.String()
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(determinismGolden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(determinismGolden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(determinismGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.ReplaceAll(string(data), "\r\n", "\n") // Windows checkouts
+	if got == want {
+		return
+	}
+	wantLines := bufio.NewScanner(strings.NewReader(want))
+	gotLines := bufio.NewScanner(strings.NewReader(got))
+	for line := 1; ; line++ {
+		w, g := wantLines.Scan(), gotLines.Scan()
+		if !w && !g {
+			break
+		}
+		if wantLines.Text() != gotLines.Text() || w != g {
+			t.Errorf("line %d differs on %s/%s %s:\n got %s\nwant %s",
+				line, runtime.GOOS, runtime.GOARCH, runtime.Version(), gotLines.Text(), wantLines.Text())
+		}
+	}
+}
+
+// TestDeterminism_Invariance checks properties the golden file cannot:
+// output does not depend on map insertion order, zone, or repetition.
+func TestDeterminism_Invariance(t *testing.T) {
+	for _, c := range determinismCorpus(t) {
+		first := fmt.Sprint(determinismOutputs(c.v))
+		for i := 0; i < 50; i++ {
+			if again := fmt.Sprint(determinismOutputs(c.v)); again != first {
+				t.Fatalf("%s: output changed on repetition %d", c.name, i)
+			}
+		}
+	}
+
+	entries := []MapEntry{
+		{Key: "é", Value: Int(1)}, {Key: "e\u0301", Value: Int(2)},
+		{Key: "🙂", Value: Int(3)}, {Key: "a", Value: Int(4)}, {Key: "Z", Value: Int(5)},
+	}
+	want := CanonicalizeLoose(Map(entries...))
+	for shift := 1; shift < len(entries); shift++ {
+		rotated := append(append([]MapEntry{}, entries[shift:]...), entries[:shift]...)
+		if got := CanonicalizeLoose(Map(rotated...)); got != want {
+			t.Errorf("insertion order %d: %s, want %s", shift, got, want)
+		}
+	}
+
+	instant := time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC) // Ambiguous hour in New York
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := CanonicalizeLoose(Time(instant)), CanonicalizeLoose(Time(instant.In(ny))); a != b {
+		t.Errorf("zone changed canonical time: %s vs %s", a, b)
+	}
+	if a, b := CanonicalizeLoose(Float(0)), CanonicalizeLoose(Float(math.Copysign(0, -1))); a != b {
+		t.Errorf("-0 canonicalizes as %s, 0 as %s", b, a)
+	}
+}
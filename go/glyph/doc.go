@@ -78,5 +78,7 @@
 //   - Accepts both = and : for field assignment
 //   - Accepts optional commas between elements
 //   - Auto-corrects common LLM mistakes
+//   - Repairs locale-style numbers ("1,234", "+5", "12%", "3,5") in
+//     schema-declared int/float fields, recording each repair as a warning
 //   - Uses schema for field name fuzzy matching
 package glyph
@@ -0,0 +1,208 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// @doc Metadata Header
+// ============================================================
+//
+// A document stored in a file or object store has nothing around it to say
+// what it is. The optional @doc header line makes it self-describing:
+//
+//	@doc id=^doc:q3-report schema=9f2c41d0 created=2026-10-16T12:00:00Z producer="etl/2.1" profile=llm
+//	{...}
+//
+// Attributes are key=value pairs separated by spaces, in any order; values
+// are bare or quoted strings (ids are refs, times are RFC 3339). Unknown
+// attributes are kept in DocMeta.Extra so newer producers don't break older
+// readers. ParseDocument skips the header; ParseDocumentWithMeta returns it.
+
+// DocMeta is the metadata carried by an @doc header.
+type DocMeta struct {
+	ID         RefID             // Document id (id=^prefix:value)
+	SchemaHash string            // Schema.ComputeHash of the body's schema (schema=)
+	Created    time.Time         // Creation time (created=)
+	Producer   string            // Producing tool or agent (producer=)
+	Profile    string            // Encoding profile, e.g. "llm" (profile=)
+	Extra      map[string]string // Unrecognized attributes, preserved
+
+	// Version and Warnings come from the @glyph version header line, which
+	// precedes the @doc line (see version.go).
+	Version  string   // Declared format version; "" if there is no header
+	Warnings []string // E.g. Version is newer than FormatVersion
+}
+
+// EmitDocHeader returns the @doc header line for m (without a newline).
+// Empty attributes are omitted; Extra attributes follow in key order.
+func EmitDocHeader(m *DocMeta) string {
+	var b strings.Builder
+	b.WriteString("@doc")
+	attr := func(key, value string) {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+	if m.ID.Value != "" {
+		attr("id", canonRef(m.ID))
+	}
+	if m.SchemaHash != "" {
+		attr("schema", canonString(m.SchemaHash))
+	}
+	if !m.Created.IsZero() {
+		attr("created", canonTime(m.Created))
+	}
+	if m.Producer != "" {
+		attr("producer", canonString(m.Producer))
+	}
+	if m.Profile != "" {
+		attr("profile", canonString(m.Profile))
+	}
+	keys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attr(k, canonString(m.Extra[k]))
+	}
+	return b.String()
+}
+
+// ParseDocHeader parses an @doc header line. Returns nil, nil if line is not
+// an @doc header.
+func ParseDocHeader(line string) (*DocMeta, error) {
+	line = strings.TrimSpace(line)
+	rest, ok := strings.CutPrefix(line, "@doc")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, nil
+	}
+
+	m := &DocMeta{}
+	for pos := 0; ; {
+		for pos < len(rest) && (rest[pos] == ' ' || rest[pos] == '\t') {
+			pos++
+		}
+		if pos >= len(rest) {
+			break
+		}
+		eq := strings.IndexByte(rest[pos:], '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("@doc: expected key=value at %q", rest[pos:])
+		}
+		key := rest[pos : pos+eq]
+		pos += eq + 1
+
+		ref := key == "id" && pos < len(rest) && rest[pos] == '^'
+		if ref {
+			pos++
+		}
+		var value string
+		if pos < len(rest) && rest[pos] == '"' {
+			s, end, err := parseQuotedStringShared(rest, pos)
+			if err != nil {
+				return nil, fmt.Errorf("@doc %s: %v", key, err)
+			}
+			value, pos = s, end
+		} else {
+			end := strings.IndexAny(rest[pos:], " \t")
+			if end < 0 {
+				end = len(rest) - pos
+			}
+			value, pos = rest[pos:pos+end], pos+end
+		}
+
+		switch key {
+		case "id":
+			if ref {
+				m.ID = parseRefIDFromTarget(value)
+			} else {
+				m.ID = RefID{Value: value}
+			}
+		case "schema":
+			m.SchemaHash = value
+		case "created":
+			t, err := parseTimeLiteralStr(value)
+			if err != nil {
+				return nil, fmt.Errorf("@doc created: %v", err)
+			}
+			m.Created = t.timeVal()
+		case "producer":
+			m.Producer = value
+		case "profile":
+			m.Profile = value
+		default:
+			if m.Extra == nil {
+				m.Extra = make(map[string]string)
+			}
+			m.Extra[key] = value
+		}
+	}
+	return m, nil
+}
+
+// SplitDocHeader separates a leading @doc header line from the document
+// body. meta is nil if there is no header.
+func SplitDocHeader(input string) (meta *DocMeta, body string, err error) {
+	trimmed := strings.TrimLeft(input, " \t\r\n")
+	if !strings.HasPrefix(trimmed, "@doc") {
+		return nil, input, nil
+	}
+	line, body, _ := strings.Cut(trimmed, "\n")
+	meta, err = ParseDocHeader(line)
+	if err != nil || meta == nil {
+		return nil, input, err
+	}
+	return meta, body, nil
+}
+
+// EmitDocument writes v in loose canonical form preceded by an @doc header,
+// and by a version header if m.Version is set.
+func EmitDocument(m *DocMeta, v *GValue) string {
+	out := EmitDocHeader(m) + "\n" + CanonicalizeLoose(v)
+	if m.Version != "" {
+		out = "@glyph " + m.Version + "\n" + out
+	}
+	return out
+}
+
+// ParseDocumentWithMeta parses a document like ParseDocument and also returns
+// its @doc and version headers (nil if both are absent).
+func ParseDocumentWithMeta(input string) (*GValue, *DocMeta, error) {
+	version, meta, _, err := splitDocHeaders(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != "" {
+		if meta == nil {
+			meta = &DocMeta{}
+		}
+		meta.Version = version
+		if w := versionWarning(version); w != "" {
+			meta.Warnings = append(meta.Warnings, w)
+		}
+	}
+	v, err := ParseDocument(input)
+	return v, meta, err
+}
+
+// CheckSchema reports an error if the header names a schema hash that differs
+// from s. A header without a schema hash matches any schema.
+func (m *DocMeta) CheckSchema(s *Schema) error {
+	if m.SchemaHash == "" {
+		return nil
+	}
+	hash := s.Hash
+	if hash == "" {
+		hash = s.ComputeHash()
+	}
+	if hash != m.SchemaHash {
+		return fmt.Errorf("@doc schema %s does not match schema %s", m.SchemaHash, hash)
+	}
+	return nil
+}
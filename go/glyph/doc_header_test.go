@@ -0,0 +1,87 @@
+package glyph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocHeader_RoundTrip(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Report", "v1", Field("title", PrimitiveType("str"))).
+		Build()
+
+	meta := &DocMeta{
+		ID:         RefID{Prefix: "doc", Value: "q3-report"},
+		SchemaHash: schema.ComputeHash(),
+		Created:    time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		Producer:   "etl/2.1 nightly",
+		Profile:    "llm",
+		Extra:      map[string]string{"lang": "en"},
+	}
+	header := EmitDocHeader(meta)
+	want := `@doc id=^doc:q3-report schema="` + meta.SchemaHash + `" created=2026-10-16T12:00:00Z producer="etl/2.1 nightly" profile=llm lang=en`
+	if header != want {
+		t.Fatalf("got  %s\nwant %s", header, want)
+	}
+
+	got, err := ParseDocHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, meta) {
+		t.Errorf("round trip: got %+v, want %+v", got, meta)
+	}
+	if err := got.CheckSchema(schema); err != nil {
+		t.Errorf("CheckSchema: %v", err)
+	}
+	other := NewSchemaBuilder().AddStruct("Other", "v1").Build()
+	if err := got.CheckSchema(other); err == nil {
+		t.Error("CheckSchema should reject a different schema")
+	}
+}
+
+func TestDocHeader_Document(t *testing.T) {
+	body := Map(MapEntry{Key: "title", Value: Str("Q3")}, MapEntry{Key: "pages", Value: Int(12)})
+	text := EmitDocument(&DocMeta{ID: RefID{Value: "r1"}, Profile: "llm"}, body)
+	if !strings.HasPrefix(text, "@doc id=^r1 profile=llm\n{") {
+		t.Fatalf("unexpected document: %s", text)
+	}
+
+	v, meta, err := ParseDocumentWithMeta(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.ID.Value != "r1" || meta.Profile != "llm" {
+		t.Errorf("meta: got %+v", meta)
+	}
+	if !EqualLoose(v, body) {
+		t.Errorf("body: got %s", CanonicalizeLoose(v))
+	}
+
+	// ParseDocument skips the header; documents without one are unchanged.
+	if v, err := ParseDocument(text); err != nil || !EqualLoose(v, body) {
+		t.Errorf("ParseDocument: %v %v", v, err)
+	}
+	if _, meta, err := ParseDocumentWithMeta(CanonicalizeLoose(body)); err != nil || meta != nil {
+		t.Errorf("headerless: %v %v", meta, err)
+	}
+}
+
+func TestDocHeader_Errors(t *testing.T) {
+	if m, err := ParseDocHeader("@document x=1"); m != nil || err != nil {
+		t.Errorf("@document is not an @doc header: %v %v", m, err)
+	}
+	if m, err := ParseDocHeader("@doc"); err != nil || m == nil {
+		t.Errorf("bare @doc: %v %v", m, err)
+	}
+	for _, bad := range []string{`@doc created=yesterday`, `@doc producer="open`, `@doc =x`} {
+		if _, err := ParseDocHeader(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+	if _, err := ParseDocument("@doc created=never\n{a=1}"); err == nil {
+		t.Error("ParseDocument should report a malformed header")
+	}
+}
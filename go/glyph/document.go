@@ -18,13 +18,74 @@ import (
 //	{messages=@tab _ [content role]\n|hello|user|\n system="prompt"}
 //
 //	@schema#abc @keys=[k1 k2]\n{#0=v1 #1=v2}
+//
+// A leading @glyph version header and @doc metadata header are skipped; use
+// ParseDocumentWithMeta to read them.
 func ParseDocument(input string) (*GValue, error) {
 	return ParseDocumentWithRegistries(input, NewSchemaRegistry())
 }
 
 // ParseDocumentWithRegistries parses a GLYPH document using the given
-// schema registry.
+// schema registry. It is equivalent to ParseLoose.
 func ParseDocumentWithRegistries(input string, schemaReg *SchemaRegistry) (*GValue, error) {
+	return ParseLoose(input, schemaReg)
+}
+
+// ParseLoose is the single entry point for GLYPH-Loose payloads. It accepts,
+// in order: an optional @glyph version header and @doc header (skipped), an optional @schema directive
+// (a definition is added to registry, a reference is resolved from it,
+// @schema.clear clears the active schema), and a value. @tab blocks may
+// appear at the top level or nested at any depth in maps and lists, and #N
+// compact keys are resolved everywhere, including inside table cells. With no
+// directive, the registry's active schema (if any) resolves compact keys. A
+// nil registry accepts only inline schema definitions. The columns of an
+// @tab Type table keep the names they were written with; ResolveTypedColumns
+// maps wire keys and #FIDs back to field names.
+//
+// The active schema is shared by every caller using registry, so a payload
+// without a directive depends on what was parsed before it. To read several
+// streams through one registry, give each a ParseSession.
+func ParseLoose(input string, registry *SchemaRegistry) (*GValue, error) {
+	return registrySession(registry, 0).parseDocument(input)
+}
+
+// ParseLooseLazy is ParseLoose, except that a map, list, or @tab block whose
+// source is at least threshold bytes long is kept as a raw span and parsed
+// the first time it is read (see GValue.Materialize). Reading one field of a
+// large document then parses only the containers on the way to it.
+// ParseLooseLazy still checks the structure of the whole input, so a map
+// entry without a key or value fails here as it does in ParseLoose. Errors
+// in the values of a deferred span, such as a bad bytes literal, surface
+// from Materialize, MaterializeAll, AsList, AsMap, Index, CanonicalizeLooseErr,
+// and ToJSONLoose; to the other accessors the span reads as empty, and
+// Emit and CanonicalizeLoose write it as it was. threshold <= 0 parses
+// eagerly.
+//
+// Reading a deferred container parses it in place, so a lazily parsed value
+// is not safe for concurrent use until MaterializeAll has returned.
+func ParseLooseLazy(input string, registry *SchemaRegistry, threshold int) (*GValue, error) {
+	return registrySession(registry, threshold).parseDocument(input)
+}
+
+// parseDocument implements ParseLoose and ParseSession.Parse.
+func (s *ParseSession) parseDocument(input string) (*GValue, error) {
+	version, _, input, err := splitDocHeaders(input)
+	if err != nil {
+		return nil, err
+	}
+	if input, err = applyCompat(input, s.compat); err != nil {
+		return nil, err
+	}
+	gv, err := s.parseBody(input)
+	if err != nil && versionWarning(version) != "" {
+		return nil, &VersionError{Version: version, Supported: FormatVersion, Err: err}
+	}
+	return gv, err
+}
+
+// parseBody parses a document without its headers.
+func (s *ParseSession) parseBody(input string) (*GValue, error) {
+	lazy := s.lazy
 	lines := strings.Split(input, "\n")
 
 	var valueLines []string
@@ -42,144 +103,20 @@ func ParseDocumentWithRegistries(input string, schemaReg *SchemaRegistry) (*GVal
 	valueStr := strings.Join(valueLines, "\n")
 	trimmedValue := strings.TrimSpace(valueStr)
 
-	switch {
-	case strings.HasPrefix(trimmedValue, "@tab _"):
+	if isTabHeader(trimmedValue) {
+		if lazy > 0 && len(trimmedValue) >= lazy {
+			return deferLoose(trimmedValue, nil, lazy), nil
+		}
 		gv, err := ParseTabularLoose(valueStr)
 		if err != nil {
 			return nil, fmt.Errorf("parse tabular: %w", err)
 		}
 		return gv, nil
-
-	case strings.Contains(valueStr, "=@tab _"):
-		gv, err := parseMapWithEmbeddedTab(valueStr)
-		if err != nil {
-			return nil, fmt.Errorf("parse embedded tab: %w", err)
-		}
-		return gv, nil
-
-	default:
-		gv, _, err := ParseLoosePayload(valueStr, schemaReg)
-		if err != nil {
-			return nil, fmt.Errorf("parse value: %w", err)
-		}
-		return gv, nil
 	}
-}
-
-// parseMapWithEmbeddedTab parses a map that contains embedded @tab blocks.
-// Example: {messages=@tab _ [content role]\n|hello|user|\n system="value"}
-func parseMapWithEmbeddedTab(input string) (*GValue, error) {
-	input = strings.TrimSpace(input)
 
-	if !strings.HasPrefix(input, "{") {
-		return nil, fmt.Errorf("expected { at start of map")
-	}
-
-	input = input[1:]
-
-	if !strings.HasSuffix(strings.TrimSpace(input), "}") {
-		return nil, fmt.Errorf("expected } at end of map")
-	}
-	input = strings.TrimSpace(input)
-	input = input[:len(input)-1]
-
-	var entries []MapEntry
-	remaining := input
-
-	for len(remaining) > 0 {
-		remaining = strings.TrimSpace(remaining)
-		if remaining == "" {
-			break
-		}
-
-		eqIdx := strings.Index(remaining, "=")
-		if eqIdx < 0 {
-			break
-		}
-
-		key := strings.TrimSpace(remaining[:eqIdx])
-		remaining = remaining[eqIdx+1:]
-
-		if strings.HasPrefix(strings.TrimSpace(remaining), "@tab _") {
-			tabStart := strings.Index(remaining, "@tab _")
-			tabContent := remaining[tabStart:]
-
-			var tabEnd int
-			if endIdx := strings.Index(tabContent, "@end"); endIdx >= 0 {
-				tabEnd = endIdx + 4
-			} else {
-				lines := strings.Split(tabContent, "\n")
-				foundEnd := false
-				for i, line := range lines {
-					trimmed := strings.TrimLeft(line, " \t")
-					if i > 0 && !strings.HasPrefix(trimmed, "|") && strings.Contains(trimmed, "=") {
-						tabEnd = strings.Index(tabContent, "\n"+line)
-						if tabEnd < 0 {
-							tabEnd = len(tabContent)
-						}
-						foundEnd = true
-						break
-					}
-				}
-				if !foundEnd {
-					tabEnd = len(tabContent)
-				}
-			}
-
-			tabBlock := strings.TrimSpace(tabContent[:tabEnd])
-			remaining = strings.TrimSpace(tabContent[tabEnd:])
-
-			tabValue, err := ParseTabularLoose(tabBlock)
-			if err != nil {
-				return nil, fmt.Errorf("parse @tab for key %s: %w", key, err)
-			}
-
-			entries = append(entries, MapEntry{Key: key, Value: tabValue})
-		} else {
-			var valueEnd int
-			found := false
-
-			for i := 0; i < len(remaining); i++ {
-				if remaining[i] == ' ' || remaining[i] == '\n' {
-					rest := strings.TrimLeft(remaining[i:], " \t\n")
-					if len(rest) > 0 && isDocKeyStart(rest) {
-						valueEnd = i
-						found = true
-						break
-					}
-				}
-			}
-			if !found {
-				valueEnd = len(remaining)
-			}
-
-			valueStr := strings.TrimSpace(remaining[:valueEnd])
-			remaining = strings.TrimSpace(remaining[valueEnd:])
-
-			val, _, err := ParseLoosePayload(valueStr, nil)
-			if err != nil {
-				val = Str(valueStr)
-			}
-
-			entries = append(entries, MapEntry{Key: key, Value: val})
-		}
-	}
-
-	return Map(entries...), nil
-}
-
-// isDocKeyStart checks if a string looks like it starts with "key=".
-func isDocKeyStart(s string) bool {
-	for i, c := range s {
-		if c == '=' {
-			return i > 0
-		}
-		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_') {
-			if c == '"' && i == 0 {
-				continue
-			}
-			return false
-		}
+	gv, _, err := s.parsePayload(valueStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse value: %w", err)
 	}
-	return false
+	return gv, nil
 }
@@ -1,6 +1,7 @@
 package glyph
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -51,3 +52,77 @@ func TestParseDocument_NoValue(t *testing.T) {
 		t.Fatal("expected error for empty input")
 	}
 }
+
+func parseLooseNested() *GValue {
+	var rows []*GValue
+	for i := 0; i < 3; i++ {
+		rows = append(rows, Map(
+			MapEntry{Key: "sku", Value: Int(int64(i))},
+			MapEntry{Key: "meta", Value: Map(MapEntry{Key: "qty", Value: Int(2)})},
+		))
+	}
+	return Map(
+		MapEntry{Key: "items", Value: List(rows...)},
+		MapEntry{Key: "batches", Value: List(List(rows...), Int(1))},
+		MapEntry{Key: "nest", Value: Map(MapEntry{Key: "rows", Value: List(rows...)})},
+		MapEntry{Key: "name", Value: Str("x")},
+	)
+}
+
+func TestParseLoose_NestedTabs(t *testing.T) {
+	v := parseLooseNested()
+	plain := CanonicalizeLoose(v)
+	if strings.Count(plain, "@tab _") != 3 {
+		t.Fatalf("expected three embedded tables:\n%s", plain)
+	}
+	got, err := ParseLoose(plain, nil)
+	if err != nil || !EqualLoose(got, v) {
+		t.Fatalf("plain: %v\n%s", err, CanonicalizeLoose(got))
+	}
+
+	// Compact keys with embedded tables, including #N keys inside cells.
+	opts := SchemaLooseCanonOpts(NewSchemaContext(BuildKeyDictFromValue(v)))
+	opts.AutoTabular = true
+	compact := CanonicalizeLooseWithSchema(v, opts)
+	if !strings.Contains(compact, "|{#") {
+		t.Fatalf("expected compact keys inside table cells:\n%s", compact)
+	}
+	registry := NewSchemaRegistry()
+	got, err = ParseLoose(compact, registry)
+	if err != nil || !EqualLoose(got, v) {
+		t.Fatalf("compact: %v", err)
+	}
+
+	// The same schema by reference, plus a @doc header.
+	ref := "@doc id=^d1\n" + opts.Schema.EmitHeader(false) + "\n" + canonLooseWithOpts(v, opts)
+	got, err = ParseLoose(ref, registry)
+	if err != nil || !EqualLoose(got, v) {
+		t.Fatalf("reference: %v", err)
+	}
+	if _, err := ParseLoose(ref, NewSchemaRegistry()); err == nil {
+		t.Error("unknown schema reference should fail")
+	}
+}
+
+func TestParseLoose_TabTermination(t *testing.T) {
+	cases := []struct {
+		name, input, want string
+	}{
+		{"end then entry", "{a=@tab _ [x]\n|1|\n|2|\n@end b=3}", "{a=[{x=1} {x=2}] b=3}"},
+		{"end then close", "{b=3 a=@tab _ [x]\n|1|\n@end}", "{a=[{x=1}] b=3}"},
+		{"implicit end", "{a=@tab _ [x y]\n|1|2|\nb=hello}", "{a=[{x=1 y=2}] b=hello}"},
+		{"indented rows", "{a=@tab _ [x]\n  |1|\n  |2|\n  @end b=4}", "{a=[{x=1} {x=2}] b=4}"},
+		{"in list", "[@tab _ [x]\n|1|\n@end [@tab _ [x]\n|2|\n@end] 3]", "[[{x=1}] [[{x=2}]] 3]"},
+		{"cell lookalikes", "{a=@tab _ [x]\n|\"@end\"|\n|\"k=v\\|w\"|\n@end}", `{a=[{x="@end"} {x="k=v|w"}]}`},
+	}
+	for _, tc := range cases {
+		got, err := ParseLoose(tc.input, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if s := CanonicalizeLooseNoTabular(got); s != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, s, tc.want)
+		}
+	}
+}
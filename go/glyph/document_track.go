@@ -0,0 +1,287 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ============================================================
+// Change-Tracked Documents
+// ============================================================
+//
+// A producer that streams its state should not have to write each change
+// twice: once to its state and once as patch text. Changing a Document's
+// Body through Update (or the Set, Append, Delete and Delta shorthands)
+// applies a patch and hands that patch to every watcher, together with the
+// state it was made against.
+//
+// AttachStream is the watcher most producers want. It writes every change
+// as a GS1 patch frame whose base is the hash of the state before it, so a
+// consumer's stream cursor checks that frames apply in order. Changes made
+// inside Batch are coalesced into one frame:
+//
+//	doc := &glyph.Document{Body: state}
+//	ds := doc.AttachStream(w, sid) // w is a *stream.Writer
+//	ds.Snapshot()                  // doc frame with the current Body
+//	doc.Set("step", glyph.Int(1))  // one patch frame
+//	ds.Batch(func() error {        // one patch frame for both
+//	    doc.Set("step", glyph.Int(2))
+//	    return doc.Append("items", item)
+//	})
+//
+// A Document is not safe for concurrent use.
+
+// DocumentChange describes one change to a Document's Body.
+type DocumentChange struct {
+	Base  *GValue // Body before the change
+	Body  *GValue // Body after the change
+	Patch *Patch  // The patch that turned Base into Body
+}
+
+// DocumentWatcher is called with each change made to a Document. An error
+// is returned by the call that made the change, which is kept regardless.
+type DocumentWatcher func(change *DocumentChange) error
+
+// Watch calls fn with every later change to the Body. It returns a function
+// that stops the calls.
+func (d *Document) Watch(fn DocumentWatcher) (stop func()) {
+	w := &fn
+	d.watchers = append(d.watchers, w)
+	return func() {
+		for i, x := range d.watchers {
+			if x == w {
+				d.watchers = append(d.watchers[:i:i], d.watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Update applies the operations fn adds to a patch to the Body, then tells
+// the watchers. If the patch fails to apply, the Body is left as it was. A
+// patch without operations changes nothing and is not reported.
+func (d *Document) Update(fn func(p *Patch)) error {
+	p := NewPatch(RefID{}, "")
+	fn(p)
+	return d.ApplyPatch(p)
+}
+
+// ApplyPatch applies p to the Body, checks the result (see Document.Check),
+// then tells the watchers.
+func (d *Document) ApplyPatch(p *Patch) error {
+	if len(p.Ops) == 0 {
+		return nil
+	}
+	base := d.Body
+	if base == nil {
+		base = Map()
+	}
+	body, err := ApplyPatchWithSchema(base, p, d.Schema)
+	if err != nil {
+		return err
+	}
+	if d.Check != nil {
+		if err := d.Check(body); err != nil {
+			return err
+		}
+	}
+	d.Body = body
+
+	change := &DocumentChange{Base: base, Body: body, Patch: p}
+	var errs []error
+	for _, w := range append([]*DocumentWatcher(nil), d.watchers...) {
+		if err := (*w)(change); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Set sets the value at path. In this and the other shorthands a dotted
+// name addresses a map key where the Body holds a map, and a field where it
+// holds a struct.
+func (d *Document) Set(path string, value *GValue) error {
+	return d.update(func(p *Patch) { p.Set(path, value) })
+}
+
+// Append appends value to the list at path.
+func (d *Document) Append(path string, value *GValue) error {
+	return d.update(func(p *Patch) { p.Append(path, value) })
+}
+
+// Delete removes the value at path.
+func (d *Document) Delete(path string) error {
+	return d.update(func(p *Patch) { p.Delete(path) })
+}
+
+// Delta adds amount to the number at path.
+func (d *Document) Delta(path string, amount float64) error {
+	return d.update(func(p *Patch) { p.Delta(path, amount) })
+}
+
+// update is Update for the shorthands: it resolves the paths of the ops fn
+// adds against the Body (see resolveLoosePath).
+func (d *Document) update(fn func(p *Patch)) error {
+	return d.Update(func(p *Patch) {
+		fn(p)
+		for _, op := range p.Ops {
+			op.Path = resolveLoosePath(d.Body, op.Path)
+		}
+	})
+}
+
+// resolveLoosePath turns each named field segment of path that addresses
+// into a map in v into a map key segment, as far as path exists in v.
+func resolveLoosePath(v *GValue, path []PathSeg) []PathSeg {
+	out := append([]PathSeg(nil), path...)
+	cur := v
+	for i, seg := range out {
+		cur.force()
+		if cur == nil {
+			break
+		}
+		if seg.Kind == PathSegField && seg.Field != "" && cur.typ == TypeMap {
+			out[i] = MapKeySeg(seg.Field)
+		}
+		next, err := lookupPathSegs(cur, out[i:i+1])
+		if err != nil {
+			break
+		}
+		cur = next
+	}
+	return out
+}
+
+// PatchFrameWriter writes GS1 frames for a DocumentStream. *stream.Writer
+// implements it.
+type PatchFrameWriter interface {
+	// NextSeq returns the seq the next frame on sid must carry.
+	NextSeq(sid uint64) uint64
+	WriteDoc(sid, seq uint64, payload []byte) error
+	WritePatch(sid, seq uint64, payload []byte, base *[32]byte) error
+}
+
+// DocumentStream writes the changes of a Document to a GS1 stream. See
+// Document.AttachStream.
+type DocumentStream struct {
+	doc  *Document
+	w    PatchFrameWriter
+	sid  uint64
+	stop func()
+
+	batching int
+	base     *GValue // State before the first pending change
+	pending  *Patch  // Changes not yet written, while batching
+}
+
+// AttachStream writes every later change to the Body as a patch frame on
+// sid, numbered after the frames w has already written there. The frame's
+// base is the hash of the Body before the change: sha256 of its loose
+// canonical form, as stream.StateHashLoose computes it.
+func (d *Document) AttachStream(w PatchFrameWriter, sid uint64) *DocumentStream {
+	ds := &DocumentStream{doc: d, w: w, sid: sid}
+	ds.stop = d.Watch(ds.change)
+	return ds
+}
+
+// Snapshot writes the current Body as a doc frame, the state later patch
+// frames build on.
+func (ds *DocumentStream) Snapshot() error {
+	body := ds.doc.Body
+	if body == nil {
+		body = Map()
+	}
+	return ds.w.WriteDoc(ds.sid, ds.w.NextSeq(ds.sid), []byte(Emit(body)))
+}
+
+// Batch calls fn and writes the changes made during it as one patch frame,
+// based on the state before the first. Operations overwritten by a later
+// set or delete are dropped. Batches nest; the outermost writes the frame.
+// The frame is written even if fn fails, since its changes are kept.
+func (ds *DocumentStream) Batch(fn func() error) error {
+	ds.batching++
+	err := fn()
+	ds.batching--
+	if ds.batching > 0 {
+		return err
+	}
+	return errors.Join(err, ds.flush())
+}
+
+// Detach stops writing the Document's changes. Pending batched changes are
+// dropped.
+func (ds *DocumentStream) Detach() {
+	ds.stop()
+	ds.base, ds.pending = nil, nil
+}
+
+func (ds *DocumentStream) change(c *DocumentChange) error {
+	if ds.pending == nil {
+		ds.base, ds.pending = c.Base, NewPatch(RefID{}, "")
+	}
+	ds.pending.Ops = append(ds.pending.Ops, c.Patch.Ops...)
+	if ds.batching > 0 {
+		return nil
+	}
+	return ds.flush()
+}
+
+// flush writes the pending changes as one patch frame.
+func (ds *DocumentStream) flush() error {
+	if ds.pending == nil {
+		return nil
+	}
+	base, p := ds.base, ds.pending
+	ds.base, ds.pending = nil, nil
+
+	// Keep the order the ops were applied in: sorting could move a set
+	// across a list edit that shifts the index it addresses.
+	p.Ops = coalesceOps(p.Ops)
+	opts := DefaultPatchOptions(nil)
+	opts.SortOps = false
+	payload, err := EmitPatchWithOptions(p, opts)
+	if err != nil {
+		return fmt.Errorf("glyph: document stream: %w", err)
+	}
+	hash := sha256.Sum256([]byte(CanonicalizeLoose(base)))
+	return ds.w.WritePatch(ds.sid, ds.w.NextSeq(ds.sid), []byte(payload), &hash)
+}
+
+// coalesceOps drops every op overwritten by a later set or delete of the
+// same path or a parent of it. Only paths without list indexes overwrite,
+// since earlier list edits may shift what an index addresses, and no op is
+// dropped across one that reads other document state (see pinsOrder).
+func coalesceOps(ops []*PatchOp) []*PatchOp {
+	dropped := make([]bool, len(ops))
+	for j, later := range ops {
+		if later.Op != OpSet && later.Op != OpDelete || hasListIndex(later.Path) {
+			continue
+		}
+		for i := j - 1; i >= 0; i-- {
+			if ops[i].Op.pinsOrder() {
+				break
+			}
+			if len(ops[i].Path) >= len(later.Path) && pathSegsEqual(ops[i].Path[:len(later.Path)], later.Path) {
+				dropped[i] = true
+			}
+		}
+	}
+	out := ops[:0:0]
+	for i, op := range ops {
+		if !dropped[i] {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// hasListIndex reports whether path addresses into a list.
+func hasListIndex(path []PathSeg) bool {
+	for _, seg := range path {
+		if seg.Kind == PathSegListIdx {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// frameLog is a PatchFrameWriter recording what it is asked to write.
+type frameLog struct {
+	seq    uint64
+	frames []string
+	bases  []*[32]byte
+	err    error
+}
+
+func (f *frameLog) NextSeq(sid uint64) uint64 { return f.seq + 1 }
+
+func (f *frameLog) WriteDoc(sid, seq uint64, payload []byte) error {
+	f.seq = seq
+	f.frames = append(f.frames, string(payload))
+	f.bases = append(f.bases, nil)
+	return f.err
+}
+
+func (f *frameLog) WritePatch(sid, seq uint64, payload []byte, base *[32]byte) error {
+	f.seq = seq
+	f.frames = append(f.frames, string(payload))
+	f.bases = append(f.bases, base)
+	return f.err
+}
+
+func trackedDoc() *Document {
+	return &Document{Body: Struct("AgentState",
+		MapEntry{Key: "step", Value: Int(0)},
+		MapEntry{Key: "items", Value: List()},
+	)}
+}
+
+func TestDocument_UpdateAndWatch(t *testing.T) {
+	doc := trackedDoc()
+	var changes []*DocumentChange
+	stop := doc.Watch(func(c *DocumentChange) error {
+		changes = append(changes, c)
+		return nil
+	})
+
+	if err := doc.Set("step", Int(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Append("items", Str("a")); err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLoose(doc.Body); got != "{items=[a] step=1}" {
+		t.Errorf("body = %s", got)
+	}
+	if len(changes) != 2 || CanonicalizeLoose(changes[1].Base) != "{items=[] step=1}" {
+		t.Fatalf("changes = %d", len(changes))
+	}
+
+	// A failing patch leaves the body alone and is not reported.
+	if err := doc.Delta("items", 1); err == nil {
+		t.Error("delta on a list should fail")
+	}
+
+	// So does one Check rejects.
+	doc.Check = func(body *GValue) error {
+		if n, _ := body.Get("step").AsInt(); n > 9 {
+			return fmt.Errorf("step %d too large", n)
+		}
+		return nil
+	}
+	if err := doc.Set("step", Int(10)); err == nil || len(changes) != 2 || CanonicalizeLoose(doc.Body) != "{items=[a] step=1}" {
+		t.Errorf("rejected change: err %v, %d changes, body %s", err, len(changes), CanonicalizeLoose(doc.Body))
+	}
+	doc.Check = nil
+	stop()
+	doc.Set("step", Int(2))
+	if len(changes) != 2 || CanonicalizeLoose(doc.Body) != "{items=[a] step=2}" {
+		t.Errorf("after stop: %d changes, body %s", len(changes), CanonicalizeLoose(doc.Body))
+	}
+}
+
+func TestDocument_NestedMapBody(t *testing.T) {
+	doc := &Document{Body: Map(MapEntry{Key: "state", Value: Map(
+		MapEntry{Key: "step", Value: Int(0)},
+		MapEntry{Key: "items", Value: List()},
+		MapEntry{Key: "task", Value: Struct("Task", FieldVal("count", Int(1)))},
+	)})}
+	for _, err := range []error{
+		doc.Set("state.step", Int(1)),
+		doc.Append("state.items", Str("a")),
+		doc.Delta("state.task.count", 2),
+		doc.Set("state.note", Str("new")),
+		doc.Delete("state.items[0]"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := CanonicalizeLoose(doc.Body); got != "{state={items=[] note=new step=1 task={count=3}}}" {
+		t.Errorf("body = %s", got)
+	}
+}
+
+func TestDocument_AttachStream(t *testing.T) {
+	doc := trackedDoc()
+	log := &frameLog{seq: 4}
+	ds := doc.AttachStream(log, 1)
+
+	if err := ds.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	before := CanonicalizeLoose(doc.Body)
+	doc.Set("step", Int(1))
+	err := ds.Batch(func() error {
+		doc.Set("step", Int(2))
+		doc.Append("items", Str("a"))
+		return doc.Set("step", Int(3))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.seq != 7 || len(log.frames) != 3 {
+		t.Fatalf("seq %d, frames %q", log.seq, log.frames)
+	}
+	if log.frames[0] != Emit(trackedDoc().Body) {
+		t.Errorf("snapshot = %s", log.frames[0])
+	}
+	if want := sha256.Sum256([]byte(before)); *log.bases[1] != want {
+		t.Error("first patch should be based on the snapshot state")
+	}
+	// The batch is one frame, with the overwritten set of step dropped.
+	if got := log.frames[2]; !strings.Contains(got, "+ items a\n= step 3\n") || strings.Contains(got, "step 2") {
+		t.Errorf("batch frame:\n%s", got)
+	}
+
+	// Frames replay to the document's state.
+	state := trackedDoc().Body
+	for _, frame := range log.frames[1:] {
+		p, err := ParsePatch(frame, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state, err = ApplyPatch(state, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !EqualLoose(state, doc.Body) {
+		t.Errorf("replayed %s, want %s", CanonicalizeLoose(state), CanonicalizeLoose(doc.Body))
+	}
+
+	log.err = errors.New("disk full")
+	if err := doc.Set("step", Int(4)); err == nil || doc.Body.Get("step").intVal != 4 {
+		t.Errorf("write error = %v; the change should be kept", err)
+	}
+	ds.Detach()
+	doc.Set("step", Int(5))
+	if len(log.frames) != 4 {
+		t.Errorf("detached stream wrote %d frames", len(log.frames))
+	}
+}
+
+func TestCoalesceOps(t *testing.T) {
+	p := NewPatch(RefID{}, "")
+	p.Set("a.b", Int(1))
+	p.Delete("items[0]")
+	p.Set("items[0].x", Int(1))
+	p.Set("a", Map())
+	p.Copy("c", "d")
+	p.Set("c", Int(2))
+	p.Set("items[0].x", Int(2))
+
+	var got []string
+	for _, op := range coalesceOps(p.Ops) {
+		got = append(got, op.Op.String()+" "+pathSegsStr(op.Path))
+	}
+	// a.b is overwritten by a; the copy keeps the set of c before it; list
+	// paths never overwrite.
+	want := "- items[0]|= items[0].x|= a|* d|= c|= items[0].x"
+	if strings.Join(got, "|") != want {
+		t.Errorf("got  %s\nwant %s", strings.Join(got, "|"), want)
+	}
+}
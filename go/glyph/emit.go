@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // EmitOptions configures the canonical emitter.
@@ -26,6 +27,43 @@ type EmitOptions struct {
 
 	// SortFields sorts struct/map fields alphabetically (for canonical output)
 	SortFields bool
+
+	// RawStrings emits strings containing newlines, quotes, or backslashes as
+	// triple-quoted raw strings ("""...""" or '''...''') when the content
+	// allows it, so code, SQL, and markdown stay readable.
+	RawStrings bool
+
+	// Base85 emits bytes as b85"..." instead of b64"...".
+	Base85 bool
+
+	// Blobs receives bytes values longer than BlobThreshold, which are then
+	// emitted as @blob <id> <n-bytes> declarations. If Put fails the value is
+	// emitted inline.
+	Blobs         BlobStore
+	BlobThreshold int
+
+	// Sparse omits optional struct fields holding their schema default or
+	// zero value (see OmitDefaults). Requires Schema.
+	Sparse bool
+
+	// Principal, if set, leaves out the fields whose @acl does not let it
+	// read them (see StripUnreadable). Requires Schema.
+	Principal *Principal
+
+	// RenameDeprecated writes deprecated fields under the replacement their
+	// @deprecated(use=...) names (see RenameDeprecated). Requires Schema.
+	RenameDeprecated bool
+
+	// Summarizer replaces subtrees larger than SummaryThreshold estimated
+	// tokens with Summary{of=^#hash value=...} stand-ins (see
+	// SummarizeSubtrees). Replaced subtrees are put in Subtrees if set.
+	Summarizer       Summarizer
+	SummaryThreshold int
+	Subtrees         SubtreeStore
+
+	// Verify re-parses the output and panics with an *IntegrityError if it
+	// does not round-trip (see integrity.go). A debugging aid for tests.
+	Verify bool
 }
 
 // DefaultEmitOptions returns sensible defaults.
@@ -61,9 +99,37 @@ func EmitCompact(v *GValue) string {
 
 // EmitWithOptions converts a GValue with custom options.
 func EmitWithOptions(v *GValue, opts EmitOptions) string {
+	if opts.Principal != nil {
+		v = StripUnreadable(opts.Schema, v, opts.Principal)
+	}
+	if opts.RenameDeprecated {
+		v = RenameDeprecated(opts.Schema, v)
+	}
+	if opts.Sparse {
+		v = OmitDefaults(opts.Schema, v)
+	}
+	if opts.Summarizer != nil {
+		v = SummarizeSubtrees(v, opts.SummaryThreshold, opts.Summarizer, opts.Subtrees)
+	}
 	e := &emitter{opts: opts}
 	e.emit(v, 0)
-	return e.sb.String()
+	out := e.sb.String()
+	if opts.Verify {
+		err := verifyEmission("typed", v, out, func(s string) (*GValue, error) {
+			r, err := ParseWithOptions(s, ParseOptions{Schema: opts.Schema, Blobs: opts.Blobs})
+			if err != nil {
+				return nil, err
+			}
+			if r.HasErrors() {
+				return nil, &r.Errors[0]
+			}
+			return r.Value, nil
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+	return out
 }
 
 type emitter struct {
@@ -72,6 +138,14 @@ type emitter struct {
 }
 
 func (e *emitter) emit(v *GValue, depth int) {
+	if raw, ok := v.unparsed(); ok {
+		e.sb.WriteString(raw)
+		return
+	}
+	if v.IsTruncated() && v.typ == TypeNull {
+		e.sb.WriteString(omissionMarker(v.ext.omitted))
+		return
+	}
 	if v == nil || v.IsNull() {
 		e.sb.WriteString("∅")
 		return
@@ -98,16 +172,16 @@ func (e *emitter) emit(v *GValue, depth int) {
 		e.emitString(v.strVal)
 
 	case TypeBytes:
-		// Emit as base64
-		e.sb.WriteString("b64\"")
-		e.sb.WriteString(base64.StdEncoding.EncodeToString(v.bytesVal))
-		e.sb.WriteString("\"")
+		e.emitBytes(v.bytesVal())
 
 	case TypeTime:
-		e.sb.WriteString(canonTime(v.timeVal))
+		e.sb.WriteString(canonTime(v.timeVal()))
 
 	case TypeID:
-		e.sb.WriteString(canonRef(v.idVal))
+		e.sb.WriteString(canonRef(v.idVal()))
+
+	case TypeCustom:
+		e.sb.WriteString(canonCustom(v.customVal()))
 
 	case TypeList:
 		e.emitList(v, depth)
@@ -123,6 +197,26 @@ func (e *emitter) emit(v *GValue, depth int) {
 	}
 }
 
+func (e *emitter) emitBytes(data []byte) {
+	if e.opts.Blobs != nil && len(data) > e.opts.BlobThreshold {
+		if id, err := e.opts.Blobs.Put(data); err == nil {
+			e.sb.WriteString("@blob ")
+			e.emitString(id)
+			e.sb.WriteByte(' ')
+			e.sb.WriteString(strconv.Itoa(len(data)))
+			return
+		}
+	}
+	if e.opts.Base85 {
+		e.sb.WriteString("b85\"")
+		e.sb.WriteString(encodeBase85(data))
+	} else {
+		e.sb.WriteString("b64\"")
+		e.sb.WriteString(base64.StdEncoding.EncodeToString(data))
+	}
+	e.sb.WriteString("\"")
+}
+
 func (e *emitter) emitFloat(f float64) {
 	// Delegate entirely to canonFloat (D4-compliant, handles NaN/Inf correctly).
 	e.sb.WriteString(canonFloat(f))
@@ -131,6 +225,14 @@ func (e *emitter) emitFloat(f float64) {
 func (e *emitter) emitString(s string) {
 	if isValidBareString(s) {
 		e.sb.WriteString(s)
+	} else if delim := rawStringDelim(s); e.opts.RawStrings && delim != "" {
+		e.sb.WriteString(delim)
+		if strings.Contains(s, "\n") {
+			// The lexer drops a newline right after the opening delimiter.
+			e.sb.WriteByte('\n')
+		}
+		e.sb.WriteString(s)
+		e.sb.WriteString(delim)
 	} else {
 		e.sb.WriteString("\"")
 		e.sb.WriteString(escapeString(s))
@@ -165,6 +267,7 @@ func (e *emitter) emitList(v *GValue, depth int) {
 		}
 	}
 
+	e.emitOmission(v, len(v.listVal), depth)
 	if e.opts.Pretty && len(v.listVal) > 0 {
 		e.writeIndent(depth)
 	}
@@ -201,6 +304,7 @@ func (e *emitter) emitMap(v *GValue, depth int) {
 		}
 	}
 
+	e.emitOmission(v, len(entries), depth)
 	if e.opts.Pretty && len(entries) > 0 {
 		e.writeIndent(depth)
 	}
@@ -236,7 +340,11 @@ func (e *emitter) emitStruct(v *GValue, depth int) {
 
 		e.sb.WriteString(key)
 		e.sb.WriteString("=")
-		e.emit(field.Value, depth+1)
+		if lit, ok := e.codecField(sv.TypeName, field); ok {
+			e.sb.WriteString(lit)
+		} else {
+			e.emit(field.Value, depth+1)
+		}
 
 		if i < len(fields)-1 {
 			e.sb.WriteString(" ")
@@ -247,12 +355,40 @@ func (e *emitter) emitStruct(v *GValue, depth int) {
 		}
 	}
 
+	e.emitOmission(v, len(fields), depth)
 	if e.opts.Pretty && len(fields) > 0 {
 		e.writeIndent(depth)
 	}
 	e.sb.WriteString("}")
 }
 
+// emitOmission writes the truncation marker of a container with n items
+// after the last of them (see truncate.go).
+func (e *emitter) emitOmission(v *GValue, n, depth int) {
+	if !v.IsTruncated() {
+		return
+	}
+	switch {
+	case e.opts.Pretty && n > 0:
+		e.writeIndent(depth + 1)
+	case n > 0:
+		e.sb.WriteString(" ")
+	}
+	e.sb.WriteString(omissionMarker(v.ext.omitted))
+	if e.opts.Pretty && n > 0 {
+		e.sb.WriteString("\n")
+	}
+}
+
+// codecField returns the geo or vector literal for a struct field whose
+// schema declares such a codec (see geo.go and vec.go).
+func (e *emitter) codecField(typeName string, field MapEntry) (string, bool) {
+	if e.opts.Schema == nil {
+		return "", false
+	}
+	return codecFieldLiteral(field.Value, e.opts.Schema.GetField(typeName, field.Key))
+}
+
 func (e *emitter) emitSum(v *GValue, depth int) {
 	sv := v.sumVal
 	e.sb.WriteString(sv.Tag)
@@ -294,6 +430,27 @@ func (e *emitter) writeIndent(depth int) {
 	}
 }
 
+// rawStringDelim returns the triple-quote delimiter to emit s as a raw string,
+// or "" if s has nothing worth escaping or cannot be written raw. Raw strings
+// cannot hold their own delimiter, end in its quote character, or carry
+// control characters other than newline and tab.
+func rawStringDelim(s string) string {
+	if !strings.ContainsAny(s, "\n\"\\") || !utf8.ValidString(s) {
+		return ""
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return ""
+		}
+	}
+	for _, delim := range []string{`"""`, "'''"} {
+		if !strings.Contains(s, delim) && !strings.HasSuffix(s, delim[:1]) {
+			return delim
+		}
+	}
+	return ""
+}
+
 // escapeString escapes a string for quoted output. Control characters below
 // U+0020 (other than \n, \r, \t) are emitted as \uXXXX so the output stays
 // printable and safe; scanString decodes these back identically.
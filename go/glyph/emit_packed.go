@@ -14,6 +14,8 @@ type PackedOptions struct {
 	Schema    *Schema
 	UseBitmap bool    // Use bitmap for sparse optionals (default true)
 	KeyMode   KeyMode // For nested struct emission (Wire/Name/Fid)
+	Sparse    bool    // Omit optional fields holding their default or zero value (see OmitDefaults)
+	Verify    bool    // Re-parse the output; return an *IntegrityError if it does not round-trip
 }
 
 // KeyMode specifies how field keys are encoded.
@@ -54,11 +56,23 @@ func EmitPackedWithOptions(v *GValue, opts PackedOptions) (string, error) {
 		return "", fmt.Errorf("type %s is not a struct", v.structVal.TypeName)
 	}
 
+	if opts.Sparse {
+		v = OmitDefaults(opts.Schema, v)
+	}
+
 	var buf bytes.Buffer
 	if err := emitPackedStruct(&buf, v, td, opts); err != nil {
 		return "", err
 	}
 
+	if opts.Verify {
+		err := verifyEmission("packed", v, buf.String(), func(s string) (*GValue, error) {
+			return ParsePacked(s, opts.Schema)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
 	return buf.String(), nil
 }
 
@@ -126,6 +140,9 @@ func emitPackedDense(out *bytes.Buffer, v *GValue, td *TypeDef, opts PackedOptio
 		}
 	}
 
+	if err := emitUnknownBag(out, v, td, len(fields) > 0, opts); err != nil {
+		return err
+	}
 	out.WriteByte(')')
 	return nil
 }
@@ -179,10 +196,31 @@ func emitPackedBitmap(out *bytes.Buffer, v *GValue, td *TypeDef, opts PackedOpti
 		}
 	}
 
+	if err := emitUnknownBag(out, v, td, !first, opts); err != nil {
+		return err
+	}
 	out.WriteByte(')')
 	return nil
 }
 
+// emitUnknownBag writes the trailing @unknown{key:value ...} bag holding the
+// fields of an @open struct that the schema does not declare. Closed types
+// never carry a bag; their unknown fields are dropped.
+func emitUnknownBag(out *bytes.Buffer, v *GValue, td *TypeDef, sep bool, opts PackedOptions) error {
+	if !td.Open {
+		return nil
+	}
+	unknown := td.UnknownFields(v)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if sep {
+		out.WriteByte(' ')
+	}
+	out.WriteString("@unknown")
+	return emitPackedValue(out, Map(unknown...), nil, opts)
+}
+
 // computeOptionalMask computes the presence mask for optional fields.
 // Returns a boolean slice where mask[i] = true if optFields[i] is present.
 func computeOptionalMask(td *TypeDef, v *GValue, opts PackedOptions) []bool {
@@ -238,6 +276,12 @@ func getFieldValue(v *GValue, fd *FieldDef) *GValue {
 
 // emitPackedValue writes a single value in packed format.
 func emitPackedValue(out *bytes.Buffer, val *GValue, fd *FieldDef, opts PackedOptions) error {
+	val.force()
+	if lit, ok := codecFieldLiteral(val, fd); ok {
+		out.WriteString(lit)
+		return nil
+	}
+
 	if val == nil {
 		out.WriteString(canonNull())
 		return nil
@@ -260,13 +304,16 @@ func emitPackedValue(out *bytes.Buffer, val *GValue, fd *FieldDef, opts PackedOp
 		out.WriteString(canonString(val.strVal))
 
 	case TypeID:
-		out.WriteString(canonRef(val.idVal))
+		out.WriteString(canonRef(val.idVal()))
 
 	case TypeTime:
-		out.WriteString(canonTime(val.timeVal))
+		out.WriteString(canonTime(val.timeVal()))
 
 	case TypeBytes:
-		out.WriteString(canonBytes(val.bytesVal))
+		out.WriteString(canonBytes(val.bytesVal()))
+
+	case TypeCustom:
+		out.WriteString(canonCustom(val.customVal()))
 
 	case TypeList:
 		out.WriteByte('[')
@@ -387,6 +434,21 @@ func emitStructMode(out *bytes.Buffer, v *GValue, td *TypeDef, opts PackedOption
 		}
 	}
 
+	// Struct mode is keyed, so @open unknowns follow as ordinary fields.
+	if td.Open {
+		for _, entry := range td.UnknownFields(v) {
+			if !first {
+				out.WriteByte(' ')
+			}
+			first = false
+			out.WriteString(canonString(entry.Key))
+			out.WriteByte('=')
+			if err := emitPackedValue(out, entry.Value, nil, opts); err != nil {
+				return err
+			}
+		}
+	}
+
 	out.WriteByte('}')
 	return nil
 }
@@ -446,6 +508,7 @@ func (m Mode) String() string {
 
 // SelectMode determines the best encoding mode for a value.
 func SelectMode(v *GValue, schema *Schema, tabThreshold int) Mode {
+	v.force()
 	if v == nil {
 		return ModeStruct
 	}
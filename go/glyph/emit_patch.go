@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,6 +31,27 @@ import (
 //   +  Append (add to list, or add field)
 //   -  Delete (remove field or list element)
 //   ~  Delta (numeric increment/decrement)
+//   >  Move (relocate the subtree at one path to another: > from to)
+//   *  Copy (duplicate the subtree at one path to another: * from to)
+//   ?  Test (assert the current value, or its @hash=, before later ops apply)
+//   ~str  Text edit (replace rune ranges of a string: ~str body 10:15="new")
+//   +tab  Append rows (bulk-append structs or maps to a list as a @tab block)
+//   !…    Custom operations registered with RegisterPatchOp (patch_ops.go)
+//
+// A +tab op spans several lines; its rows follow the header and it is closed
+// by its own @end before the patch continues:
+//
+//   +tab results Result [id score]
+//   1 0.93
+//   2 0.88
+//   @end
+//
+// Map rows with a shared key set use the untyped loose form:
+//
+//   +tab events _ rows=2 cols=2 [id note]
+//   |1|a|
+//   |2|b|
+//   @end
 //
 // FID paths (v2):
 //   @keys=fid -> paths use .#<fid> instead of .fieldName
@@ -75,7 +98,7 @@ func (ps PathSeg) String() string {
 	case PathSegListIdx:
 		return fmt.Sprintf("[%d]", ps.ListIdx)
 	case PathSegMapKey:
-		return fmt.Sprintf("[%q]", ps.MapKey)
+		return "[" + quoteString(ps.MapKey) + "]"
 	default:
 		return "?"
 	}
@@ -83,10 +106,22 @@ func (ps PathSeg) String() string {
 
 // PatchOp represents a single patch operation.
 type PatchOp struct {
-	Op    PatchOpKind // =, +, -, ~
+	Op    PatchOpKind // =, +, -, ~, >, *
 	Path  []PathSeg   // Path segments (struct field, list index, map key)
 	Value *GValue     // The value (for =, +) or delta amount (for ~)
 	Index int         // For list operations: -1 = append, >= 0 = specific index
+	From  []PathSeg   // Source path (for >, *); Path is the destination
+	Hash  string      // For ?: expected FingerprintLoose (prefix) instead of Value
+	Edits []TextEdit  // For ~str: range replacements against the current string
+	Name  string      // For custom ops: the registered symbol
+}
+
+// TextEdit replaces the runes [Start, End) of a string with Text. Offsets count
+// Unicode code points so they mean the same thing in every implementation.
+type TextEdit struct {
+	Start int
+	End   int
+	Text  string
 }
 
 // PatchOpKind is the type of patch operation.
@@ -97,13 +132,41 @@ const (
 	OpAppend PatchOpKind = '+' // Append to list or add field
 	OpDelete PatchOpKind = '-' // Delete field or list element
 	OpDelta  PatchOpKind = '~' // Numeric delta
+	OpMove   PatchOpKind = '>' // Move subtree from one path to another
+	OpCopy   PatchOpKind = '*' // Copy subtree from one path to another
+	OpTest   PatchOpKind = '?' // Assert current value before later ops apply
+	OpText   PatchOpKind = 's' // Text edit (~str): replace ranges of a string
+	OpRows   PatchOpKind = 't' // Append rows (+tab): bulk-append structs or maps to a list
+	OpCustom PatchOpKind = 'x' // Registered custom op; PatchOp.Name is its symbol
 )
 
 // String returns the operation symbol.
 func (k PatchOpKind) String() string {
+	switch k {
+	case OpText:
+		return "~str"
+	case OpRows:
+		return "+tab"
+	case OpCustom:
+		return "custom"
+	}
 	return string(k)
 }
 
+// pinsOrder reports whether an op observes document state beyond the path it
+// writes, so that sorting must not move other ops across it.
+func (k PatchOpKind) pinsOrder() bool {
+	return k == OpMove || k == OpCopy || k == OpTest || k == OpCustom
+}
+
+// symbol returns the op's symbol as written in patch text.
+func (op *PatchOp) symbol() string {
+	if op.Op == OpCustom {
+		return op.Name
+	}
+	return op.Op.String()
+}
+
 // Patch represents a set of patches to apply to a target.
 type Patch struct {
 	Target          RefID      // Target document reference
@@ -172,6 +235,76 @@ func (p *Patch) Delta(path string, amount float64) *Patch {
 	return p
 }
 
+// Move adds a move operation relocating the value at from to to.
+func (p *Patch) Move(from, to string) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:   OpMove,
+		Path: parsePathToSegs(to),
+		From: parsePathToSegs(from),
+	})
+	return p
+}
+
+// Copy adds a copy operation duplicating the value at from to to.
+func (p *Patch) Copy(from, to string) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:   OpCopy,
+		Path: parsePathToSegs(to),
+		From: parsePathToSegs(from),
+	})
+	return p
+}
+
+// Test adds a test operation asserting that the value at path equals want
+// (compared with EqualLoose) when the patch is applied.
+func (p *Patch) Test(path string, want *GValue) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:    OpTest,
+		Path:  parsePathToSegs(path),
+		Value: want,
+	})
+	return p
+}
+
+// TestHash adds a test operation asserting that the FingerprintLoose of the
+// value at path starts with hash. At least 16 hex characters are required; a
+// hash longer than a fingerprint (64) is cut to that length.
+func (p *Patch) TestHash(path string, hash string) *Patch {
+	if len(hash) > maxTestHashLen {
+		hash = hash[:maxTestHashLen]
+	}
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:   OpTest,
+		Path: parsePathToSegs(path),
+		Hash: hash,
+	})
+	return p
+}
+
+// AppendRows adds an append-rows operation appending rows to the list at path.
+// Rows are either structs of a single schema type or maps sharing one key set
+// (columns are the sorted keys). It is emitted as one +tab block.
+func (p *Patch) AppendRows(path string, rows ...*GValue) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:    OpRows,
+		Path:  parsePathToSegs(path),
+		Value: List(rows...),
+		Index: -1,
+	})
+	return p
+}
+
+// EditText adds a text edit operation applying edits to the string at path.
+// Edit offsets refer to the string before any of the edits are applied.
+func (p *Patch) EditText(path string, edits ...TextEdit) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:    OpText,
+		Path:  parsePathToSegs(path),
+		Edits: edits,
+	})
+	return p
+}
+
 // InsertAt adds an insert operation at a specific index.
 func (p *Patch) InsertAt(path string, index int, value *GValue) *Patch {
 	p.Ops = append(p.Ops, &PatchOp{
@@ -184,7 +317,8 @@ func (p *Patch) InsertAt(path string, index int, value *GValue) *Patch {
 }
 
 // parsePathToSegs parses a dot-separated path into PathSeg slice.
-// Supports: .fieldName, .#fid, [N], ["key"]
+// Supports: .fieldName, ."quoted name", .#fid, [N], ["key"]. Quoted names and
+// map keys use the GLYPH string escapes, so they may contain any character.
 func parsePathToSegs(path string) []PathSeg {
 	if path == "" {
 		return nil
@@ -201,6 +335,18 @@ func parsePathToSegs(path string) []PathSeg {
 			continue
 		}
 
+		// Map key: ["key"]. Scan the quoted key with the shared string parser so
+		// escaped quotes and a literal ']' inside the key don't end the segment.
+		if path[i] == '[' && i+1 < n && path[i+1] == '"' {
+			key, end, err := parseQuotedStringShared(path, i+1)
+			if err == nil && end < n && path[end] == ']' {
+				segs = append(segs, MapKeySeg(key))
+				i = end + 1
+				continue
+			}
+			// Malformed quoted key: fall through to the plain bracket scan.
+		}
+
 		// List index: [N]
 		if path[i] == '[' {
 			end := strings.IndexByte(path[i:], ']')
@@ -247,6 +393,16 @@ func parsePathToSegs(path string) []PathSeg {
 			continue
 		}
 
+		// Quoted field name: "na.me" (emitted when a name needs quoting)
+		if path[i] == '"' {
+			field, end, err := parseQuotedStringShared(path, i)
+			if err == nil {
+				segs = append(segs, FieldSeg(field, 0))
+				i = end
+				continue
+			}
+		}
+
 		// Field name: until . or [ or end
 		j := i
 		inQuote := false
@@ -332,12 +488,19 @@ func EmitPatchWithOptions(p *Patch, opts PatchOptions) (string, error) {
 		buf.WriteString(p.BaseFingerprint)
 	}
 
+	// Wire keys and FIDs are relative to the root type, which a reader needs
+	// to map them back to field names.
+	if p.TargetType != "" && opts.Schema != nil && opts.KeyMode != KeyModeName {
+		buf.WriteString(" @type=")
+		buf.WriteString(p.TargetType)
+	}
+
 	buf.WriteByte('\n')
 
 	// Operations
 	ops := p.Ops
 	if opts.SortOps {
-		ops = sortPatchOps(ops, opts.KeyMode)
+		ops = sortPatchOps(ops, opts.KeyMode, opts.Schema, p.TargetType)
 	}
 
 	packOpts := PackedOptions{
@@ -348,7 +511,7 @@ func EmitPatchWithOptions(p *Patch, opts PatchOptions) (string, error) {
 
 	for _, op := range ops {
 		buf.WriteString(opts.IndentPrefix)
-		if err := emitPatchOp(&buf, op, opts, packOpts); err != nil {
+		if err := emitPatchOp(&buf, op, p.TargetType, opts, packOpts); err != nil {
 			return "", err
 		}
 		buf.WriteByte('\n')
@@ -360,36 +523,85 @@ func EmitPatchWithOptions(p *Patch, opts PatchOptions) (string, error) {
 	return buf.String(), nil
 }
 
-// sortPatchOps returns a copy of ops sorted by path for determinism.
-func sortPatchOps(ops []*PatchOp, keyMode KeyMode) []*PatchOp {
+// sortPatchOps returns a copy of ops sorted by path for determinism. Paths are
+// compared in their emitted form so that a parsed patch (whose segments already
+// hold wire keys or FIDs) re-emits in the same order as the original.
+//
+// Move, copy and test ops observe state at a path other than (or before) the
+// one they write, so reordering across them could change what they see; they
+// stay in place and only the runs of ops between them are sorted.
+func sortPatchOps(ops []*PatchOp, keyMode KeyMode, schema *Schema, rootType string) []*PatchOp {
 	sorted := make([]*PatchOp, len(ops))
 	copy(sorted, ops)
 
-	sort.Slice(sorted, func(i, j int) bool {
-		// Compare paths using canonical string form
-		pi := pathSegsToString(sorted[i].Path, keyMode)
-		pj := pathSegsToString(sorted[j].Path, keyMode)
-		if pi != pj {
-			return pi < pj
+	keys := make(map[*PatchOp]string, len(sorted))
+	for _, op := range sorted {
+		var buf bytes.Buffer
+		emitPathSegs(&buf, op.Path, keyMode, schema, rootType)
+		keys[op] = buf.String()
+	}
+
+	sortRun := func(run []*PatchOp) {
+		sort.SliceStable(run, func(i, j int) bool {
+			pi, pj := keys[run[i]], keys[run[j]]
+			if pi != pj {
+				return pi < pj
+			}
+			// Same path, sort by op kind
+			return run[i].Op < run[j].Op
+		})
+	}
+
+	start := 0
+	for i, op := range sorted {
+		if op.Op.pinsOrder() {
+			sortRun(sorted[start:i])
+			start = i + 1
 		}
-		// Same path, sort by op kind
-		return sorted[i].Op < sorted[j].Op
-	})
+	}
+	sortRun(sorted[start:])
 
 	return sorted
 }
 
 // emitPatchOp writes a single patch operation.
-func emitPatchOp(out *bytes.Buffer, op *PatchOp, patchOpts PatchOptions, packOpts PackedOptions) error {
+func emitPatchOp(out *bytes.Buffer, op *PatchOp, rootType string, patchOpts PatchOptions, packOpts PackedOptions) error {
 	// Operation symbol
-	out.WriteRune(rune(op.Op))
+	out.WriteString(op.symbol())
 	out.WriteByte(' ')
 
+	// Move/copy: source path first, then destination
+	if op.Op == OpMove || op.Op == OpCopy {
+		emitPathSegs(out, op.From, patchOpts.KeyMode, patchOpts.Schema, rootType)
+		out.WriteByte(' ')
+	}
+
 	// Path - emit according to KeyMode
-	emitPathSegs(out, op.Path, patchOpts.KeyMode, patchOpts.Schema)
+	emitPathSegs(out, op.Path, patchOpts.KeyMode, patchOpts.Schema, rootType)
 
-	// Value (for =, +, ~)
+	// Value (for =, +, ~, ?, ~str, +tab)
 	switch op.Op {
+	case OpRows:
+		out.WriteByte(' ')
+		if err := emitPatchRows(out, op, patchOpts); err != nil {
+			return err
+		}
+
+	case OpTest:
+		out.WriteByte(' ')
+		if op.Hash != "" {
+			out.WriteString("@hash=")
+			out.WriteString(op.Hash)
+		} else if err := emitPackedValue(out, op.Value, nil, packOpts); err != nil {
+			return err
+		}
+
+	case OpText:
+		for _, e := range op.Edits {
+			fmt.Fprintf(out, " %d:%d=", e.Start, e.End)
+			out.WriteString(quoteString(e.Text))
+		}
+
 	case OpSet, OpAppend:
 		if op.Value != nil {
 			out.WriteByte(' ')
@@ -418,40 +630,143 @@ func emitPatchOp(out *bytes.Buffer, op *PatchOp, patchOpts PatchOptions, packOpt
 			out.WriteString(canonInt(n))
 		}
 
-	case OpDelete:
+	case OpCustom:
+		if err := emitCustomOpArgs(out, op, packOpts); err != nil {
+			return err
+		}
+
+	case OpDelete, OpMove, OpCopy:
 		// No value needed
 	}
 
 	return nil
 }
 
+// emitPatchRows writes the body of a +tab op: the "Type [cols]" header, one
+// tabular row per line, and the closing @end. Map rows are written as an
+// untyped "_" table instead (see emitPatchMapRows).
+func emitPatchRows(out *bytes.Buffer, op *PatchOp, opts PatchOptions) error {
+	if op.Value == nil || op.Value.typ != TypeList || len(op.Value.listVal) == 0 {
+		return fmt.Errorf("+tab op requires a non-empty list of rows")
+	}
+	first := op.Value.listVal[0]
+	if first.typ == TypeMap {
+		return emitPatchMapRows(out, op.Value.listVal, opts)
+	}
+	if first.typ != TypeStruct {
+		return fmt.Errorf("+tab rows must be structs or maps, got %s", first.typ)
+	}
+	td := opts.Schema.GetType(first.structVal.TypeName)
+	if td == nil || td.Kind != TypeDefStruct || td.Struct == nil {
+		return fmt.Errorf("+tab requires schema struct type: %s", first.structVal.TypeName)
+	}
+	for i, row := range op.Value.listVal[1:] {
+		if row.typ != TypeStruct || row.structVal.TypeName != td.Name {
+			return fmt.Errorf("+tab row %d is not a %s", i+1, td.Name)
+		}
+	}
+
+	tabOpts := DefaultTabularOptions(opts.Schema)
+	tabOpts.KeyMode = opts.KeyMode
+	tabOpts.IndentPrefix = opts.IndentPrefix
+
+	var table bytes.Buffer
+	if err := emitTabularTable(&table, op.Value.listVal, td, tabOpts); err != nil {
+		return err
+	}
+	out.Write(bytes.TrimPrefix(table.Bytes(), []byte("@tab ")))
+	return nil
+}
+
+// emitPatchMapRows writes map rows as "_ rows=N cols=M [cols]" followed by
+// |cell| rows and @end, the loose tabular form. Every row must have the same
+// keys; the sorted keys are the columns. No schema is needed.
+func emitPatchMapRows(out *bytes.Buffer, rows []*GValue, opts PatchOptions) error {
+	cols := getObjectKeys(rows[0])
+	sort.Strings(cols)
+	for i, row := range rows {
+		if row.typ != TypeMap {
+			return fmt.Errorf("+tab row %d is not a map", i)
+		}
+		keys := getObjectKeys(row)
+		sort.Strings(keys)
+		if !slices.Equal(keys, cols) {
+			return fmt.Errorf("+tab row %d has keys %v, want %v", i, keys, cols)
+		}
+	}
+
+	looseOpts := DefaultLooseCanonOpts()
+	t := untypedLooseTable(cols, looseOpts)
+
+	body := getPooledBuilder()
+	writeTabularRowsLoose(body, rows, len(t.labels), t.cell, looseOpts)
+	lines := strings.Split(strings.TrimSuffix(body.String(), "\n"), "\n")
+	putPooledBuilder(body)
+
+	out.WriteString("_ rows=")
+	out.WriteString(strconv.Itoa(len(rows)))
+	out.WriteString(" cols=")
+	out.WriteString(strconv.Itoa(len(t.labels)))
+	out.WriteString(" [")
+	out.WriteString(strings.Join(t.labels, " "))
+	out.WriteString("]\n")
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			out.WriteString(opts.IndentPrefix)
+		}
+		out.WriteString(line)
+		if i < len(lines)-1 {
+			out.WriteByte('\n')
+		}
+	}
+	return nil
+}
+
 // emitPathSegs writes path segments according to KeyMode.
-func emitPathSegs(out *bytes.Buffer, path []PathSeg, keyMode KeyMode, schema *Schema) {
+//
+// rootType is the schema type the path starts from (Patch.TargetType). When it
+// and the schema are known, the walk tracks the type reached at each segment so
+// wire mode can substitute each field's @k wire key and FID mode can fill in a
+// FID the caller left unresolved. Without type context (no schema, unknown root,
+// or a path that passes through a primitive) segments fall back to field names.
+func emitPathSegs(out *bytes.Buffer, path []PathSeg, keyMode KeyMode, schema *Schema, rootType string) {
+	var cur *TypeSpec
+	if schema != nil && rootType != "" {
+		cur = &TypeSpec{Kind: TypeSpecRef, Name: rootType}
+	}
+
 	for i, seg := range path {
+		var fd *FieldDef
+		fd, cur = stepPathType(schema, cur, seg)
+
 		switch seg.Kind {
 		case PathSegField:
 			if i > 0 {
 				out.WriteByte('.')
 			}
-			if keyMode == KeyModeFID && seg.FID > 0 {
+			fid := seg.FID
+			if fid == 0 && fd != nil {
+				fid = fd.FID
+			}
+			name := seg.Field
+			if name == "" && fd != nil {
+				name = fd.Name
+			}
+
+			if keyMode == KeyModeFID && fid > 0 {
 				// FID mode: emit .#<fid>
 				out.WriteByte('#')
-				out.WriteString(strconv.Itoa(seg.FID))
-			} else if keyMode == KeyModeWire && schema != nil && seg.Field != "" {
-				// Wire mode: use wire key if available
-				// Need type context to resolve - for now just use field name
-				if needsQuoting(seg.Field) {
-					out.WriteString(quoteString(seg.Field))
-				} else {
-					out.WriteString(seg.Field)
-				}
+				out.WriteString(strconv.Itoa(fid))
+				continue
+			}
+			if keyMode == KeyModeWire && fd != nil && fd.WireKey != "" {
+				// Wire mode: use the field's @k wire key
+				name = fd.WireKey
+			}
+			if needsQuoting(name) {
+				out.WriteString(quoteString(name))
 			} else {
-				// Name mode or fallback
-				if needsQuoting(seg.Field) {
-					out.WriteString(quoteString(seg.Field))
-				} else {
-					out.WriteString(seg.Field)
-				}
+				out.WriteString(name)
 			}
 
 		case PathSegListIdx:
@@ -468,32 +783,78 @@ func emitPathSegs(out *bytes.Buffer, path []PathSeg, keyMode KeyMode, schema *Sc
 	}
 }
 
-// pathSegsToString converts path segments to canonical string for sorting.
-func pathSegsToString(path []PathSeg, keyMode KeyMode) string {
-	var buf bytes.Buffer
-	for i, seg := range path {
-		switch seg.Kind {
-		case PathSegField:
-			if i > 0 {
-				buf.WriteByte('.')
+// stepPathType advances a schema type walk by one path segment. It returns the
+// field definition matched by a field segment (nil otherwise) and the type spec
+// reached after the segment, or nil once the schema can no longer say (unknown
+// type, sum type, primitive, or a segment that does not fit the current type).
+func stepPathType(schema *Schema, cur *TypeSpec, seg PathSeg) (*FieldDef, *TypeSpec) {
+	if schema == nil || cur == nil {
+		return nil, nil
+	}
+
+	switch seg.Kind {
+	case PathSegField:
+		var fields []*FieldDef
+		switch cur.Kind {
+		case TypeSpecRef:
+			td := schema.GetType(cur.Name)
+			if td == nil || td.Kind != TypeDefStruct || td.Struct == nil {
+				return nil, nil
 			}
-			if keyMode == KeyModeFID && seg.FID > 0 {
-				buf.WriteByte('#')
-				buf.WriteString(strconv.Itoa(seg.FID))
-			} else {
-				buf.WriteString(seg.Field)
+			fields = td.Struct.Fields
+		case TypeSpecInlineStruct:
+			if cur.Struct == nil {
+				return nil, nil
 			}
-		case PathSegListIdx:
-			buf.WriteByte('[')
-			buf.WriteString(strconv.Itoa(seg.ListIdx))
-			buf.WriteByte(']')
-		case PathSegMapKey:
-			buf.WriteByte('[')
-			buf.WriteString(quoteString(seg.MapKey))
-			buf.WriteByte(']')
+			fields = cur.Struct.Fields
+		default:
+			return nil, nil
+		}
+		fd := fieldForPathSeg(fields, seg)
+		if fd == nil {
+			return nil, nil
+		}
+		return fd, &fd.Type
+
+	case PathSegListIdx:
+		if cur.Kind == TypeSpecList {
+			return nil, cur.Elem
+		}
+
+	case PathSegMapKey:
+		if cur.Kind == TypeSpecMap {
+			return nil, cur.ValType
 		}
 	}
-	return buf.String()
+	return nil, nil
+}
+
+// fieldForPathSeg finds the field a path segment names: by FID when the
+// segment carries no name, otherwise by wire key then field name (the same
+// precedence as TypeDef.FieldByKey).
+func fieldForPathSeg(fields []*FieldDef, seg PathSeg) *FieldDef {
+	if seg.Field == "" {
+		if seg.FID <= 0 {
+			return nil
+		}
+		for _, f := range fields {
+			if f.FID == seg.FID {
+				return f
+			}
+		}
+		return nil
+	}
+	for _, f := range fields {
+		if f.WireKey != "" && f.WireKey == seg.Field {
+			return f
+		}
+	}
+	for _, f := range fields {
+		if f.Name == seg.Field {
+			return f
+		}
+	}
+	return nil
 }
 
 // pathSegsStr returns path as string for error messages.
@@ -546,16 +907,20 @@ func ResolvePathFIDs(path []PathSeg, rootType string, schema *Schema) error {
 		return nil
 	}
 
-	currentType := rootType
+	// The type of the value reached so far.
+	current := TypeSpec{Kind: TypeSpecRef, Name: rootType}
 
 	for i := range path {
 		seg := &path[i]
 
 		switch seg.Kind {
 		case PathSegField:
-			td := schema.GetType(currentType)
+			td := schema.GetType(current.Name)
+			if current.Kind == TypeSpecInlineStruct {
+				td = &TypeDef{Name: "struct", Kind: TypeDefStruct, Struct: current.Struct}
+			}
 			if td == nil {
-				return fmt.Errorf("unknown type: %s", currentType)
+				return fmt.Errorf("unknown type: %s", current.Name)
 			}
 
 			var fd *FieldDef
@@ -575,32 +940,24 @@ func ResolvePathFIDs(path []PathSeg, rootType string, schema *Schema) error {
 			}
 
 			if fd == nil {
-				return fmt.Errorf("unknown field in %s: %s (fid=%d)", currentType, seg.Field, seg.FID)
-			}
-
-			// Update current type for next segment
-			if fd.Type.Kind == TypeSpecRef {
-				currentType = fd.Type.Name
-			} else {
-				currentType = "" // Can't navigate further into primitives
+				return fmt.Errorf("unknown field in %s: %s (fid=%d)", td.Name, seg.Field, seg.FID)
 			}
+			current = fd.Type
 
 		case PathSegListIdx:
 			// For list, get element type
-			td := schema.GetType(currentType)
-			if td != nil && td.Struct != nil {
-				for _, fd := range td.Struct.Fields {
-					if fd.Type.Kind == TypeSpecList && fd.Type.Elem != nil {
-						if fd.Type.Elem.Kind == TypeSpecRef {
-							currentType = fd.Type.Elem.Name
-						}
-					}
-				}
+			if current.Kind == TypeSpecList && current.Elem != nil {
+				current = *current.Elem
+			} else {
+				current = TypeSpec{}
 			}
 
 		case PathSegMapKey:
-			// For maps, type context is lost for now
-			currentType = ""
+			if current.Kind == TypeSpecMap && current.ValType != nil {
+				current = *current.ValType
+			} else {
+				current = TypeSpec{}
+			}
 		}
 	}
 
@@ -613,6 +970,11 @@ func ResolvePathFIDs(path []PathSeg, rootType string, schema *Schema) error {
 
 // ApplyPatch applies a patch set to a value and returns the modified copy.
 //
+// v is never modified. Only the nodes on a patched path are copied; every
+// other subtree of the result is shared with v, as are values taken from the
+// patch's ops. Treat v and the result as immutable, or deepCopy before
+// mutating either in place.
+//
 // Paths must already be name-resolved (seg.Field populated). FID-mode patches —
 // whose parsed segments carry only a FID with an empty Field — must instead be
 // applied with ApplyPatchWithSchema, which runs the FID-resolution pre-pass.
@@ -620,21 +982,66 @@ func ApplyPatch(v *GValue, p *Patch) (*GValue, error) {
 	if v == nil {
 		return nil, fmt.Errorf("cannot apply patch to nil value")
 	}
+	return applyPatch(v, p, nil)
+}
 
-	// Deep copy the value
-	result := deepCopy(v)
-
+// applyPatch applies the ops of p in order, first passing each to check, if
+// set, with the value it applies to.
+func applyPatch(v *GValue, p *Patch, check func(*GValue, *PatchOp) error) (*GValue, error) {
+	a := &patchApplier{owned: make(map[*GValue]bool)}
+	result := v
 	for _, op := range p.Ops {
 		var err error
-		result, err = applyOp(result, op)
+		if check != nil {
+			err = check(result, op)
+		}
+		if err == nil {
+			result, err = a.applyOp(result, op)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("patch op %s %s: %w", op.Op, pathSegsStr(op.Path), err)
+			return nil, fmt.Errorf("patch op %s %s: %w", op.symbol(), pathSegsStr(op.Path), err)
 		}
 	}
 
 	return result, nil
 }
 
+// patchApplier applies ops by path copying. A node is copied the first time
+// an op changes it; owned records those copies so later ops in the same
+// patch change them in place instead of copying again.
+type patchApplier struct {
+	owned map[*GValue]bool
+}
+
+// own returns a node the applier may mutate: v itself if it was copied during
+// this patch, otherwise a shallow copy of v.
+func (a *patchApplier) own(v *GValue) *GValue {
+	if a.owned[v] {
+		return v
+	}
+	cp := shallowCopy(v)
+	a.owned[cp] = true
+	return cp
+}
+
+// shallowCopy copies v and its own entry slices, sharing the children.
+func shallowCopy(v *GValue) *GValue {
+	v.force()
+	cp := *v
+	if v.listVal != nil {
+		cp.listVal = append(make([]*GValue, 0, len(v.listVal)+1), v.listVal...)
+	}
+	if v.mapVal != nil {
+		cp.mapVal = append(make([]MapEntry, 0, len(v.mapVal)+1), v.mapVal...)
+	}
+	if v.structVal != nil {
+		sv := *v.structVal
+		sv.Fields = append(make([]MapEntry, 0, len(sv.Fields)+1), sv.Fields...)
+		cp.structVal = &sv
+	}
+	return &cp
+}
+
 // ApplyPatchWithSchema resolves FID/wire-key path segments using the schema (a
 // required pre-pass for FID-mode patches, whose parsed segments have an empty
 // Field) and then applies the patch. The root type for resolution is taken from
@@ -644,20 +1051,29 @@ func ApplyPatchWithSchema(v *GValue, p *Patch, schema *Schema) (*GValue, error)
 	if v == nil {
 		return nil, fmt.Errorf("cannot apply patch to nil value")
 	}
-	if schema != nil {
-		rootType := p.TargetType
-		if rootType == "" && v.typ == TypeStruct && v.structVal != nil {
-			rootType = v.structVal.TypeName
-		}
-		if rootType != "" {
-			if err := p.ResolveFIDs(rootType, schema); err != nil {
-				return nil, err
-			}
-		}
+	if err := resolvePatchFIDs(v, p, schema); err != nil {
+		return nil, err
 	}
 	return ApplyPatch(v, p)
 }
 
+// resolvePatchFIDs runs the FID-resolution pre-pass of ApplyPatchWithSchema.
+func resolvePatchFIDs(v *GValue, p *Patch, schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	return p.ResolveFIDs(patchRootType(v, p), schema)
+}
+
+// patchRootType returns the type p's paths start from: p.TargetType, or the
+// type of the struct v.
+func patchRootType(v *GValue, p *Patch) string {
+	if p.TargetType == "" && v.typ == TypeStruct && v.structVal != nil {
+		return v.structVal.TypeName
+	}
+	return p.TargetType
+}
+
 // ResolveFIDs resolves every operation path against the schema, populating
 // seg.Field from FIDs (and normalizing wire keys / names to canonical field
 // names). It is the single FID-resolution pre-pass shared by build, parse and
@@ -670,12 +1086,51 @@ func (p *Patch) ResolveFIDs(rootType string, schema *Schema) error {
 		if err := ResolvePathFIDs(op.Path, rootType, schema); err != nil {
 			return err
 		}
+		if err := ResolvePathFIDs(op.From, rootType, schema); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// resolveKnownPaths resolves paths as ResolveFIDs does, but only as far as
+// the schema knows them: a patch may name fields its schema does not
+// declare, and those segments are left as parsed.
+func (p *Patch) resolveKnownPaths(rootType string, schema *Schema) {
+	if schema == nil || rootType == "" {
+		return
+	}
+	resolve := func(path []PathSeg) {
+		// The longest prefix that resolves; segments after it stay as parsed.
+		for n := len(path); n > 0; n-- {
+			prefix := append([]PathSeg(nil), path[:n]...)
+			if ResolvePathFIDs(prefix, rootType, schema) == nil {
+				copy(path, prefix)
+				return
+			}
+		}
+	}
+	for _, op := range p.Ops {
+		resolve(op.Path)
+		resolve(op.From)
+	}
+}
+
 // applyOp applies a single operation to a value.
-func applyOp(v *GValue, op *PatchOp) (*GValue, error) {
+func (a *patchApplier) applyOp(v *GValue, op *PatchOp) (*GValue, error) {
+	switch op.Op {
+	case OpMove, OpCopy:
+		return a.applyTransfer(v, op)
+	case OpTest:
+		return v, applyTest(v, op)
+	case OpText:
+		return a.applyTextEdits(v, op)
+	case OpRows:
+		return a.applyRows(v, op)
+	case OpCustom:
+		return a.applyCustom(v, op)
+	}
+
 	if len(op.Path) == 0 {
 		// Root-level operation
 		switch op.Op {
@@ -687,14 +1142,234 @@ func applyOp(v *GValue, op *PatchOp) (*GValue, error) {
 	}
 
 	// Navigate to parent, apply at leaf
-	return applyAtPathSegs(v, op.Path, op)
+	return a.applyAtPathSegs(v, op.Path, op)
+}
+
+// applyTransfer applies a move or copy. The value at op.From is read (and, for
+// a move, removed) and then placed at op.Path: a list-index destination inserts
+// before that index as + does, any other destination is set as = does. For a
+// move, destination list indices refer to the list after the removal.
+func (a *patchApplier) applyTransfer(v *GValue, op *PatchOp) (*GValue, error) {
+	if len(op.From) == 0 {
+		return nil, fmt.Errorf("%s requires a source path", op.Op)
+	}
+
+	src, err := lookupPathSegs(v, op.From)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", pathSegsStr(op.From), err)
+	}
+
+	if op.Op == OpCopy {
+		src = deepCopy(src)
+	} else {
+		if pathSegsEqual(op.From, op.Path) {
+			return v, nil
+		}
+		if len(op.Path) > len(op.From) && pathSegsEqual(op.From, op.Path[:len(op.From)]) {
+			return nil, fmt.Errorf("cannot move %s into its own subtree", pathSegsStr(op.From))
+		}
+		v, err = a.applyOp(v, &PatchOp{Op: OpDelete, Path: op.From})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	place := &PatchOp{Op: OpSet, Path: op.Path, Value: src, Index: -1}
+	if len(op.Path) > 0 && op.Path[len(op.Path)-1].Kind == PathSegListIdx {
+		place.Op = OpAppend
+	}
+	return a.applyOp(v, place)
+}
+
+// PatchTestFailed is returned (wrapped) by ApplyPatch when a test (?) op does
+// not hold. Got and Want are no-tabular canonical forms for a value test, or
+// fingerprints for an @hash= test; Got is empty when the path did not resolve.
+type PatchTestFailed struct {
+	Path string
+	Got  string
+	Want string
+}
+
+func (e *PatchTestFailed) Error() string {
+	if e.Got == "" {
+		return fmt.Sprintf("patch test failed at %s: path not found, want %s", e.Path, e.Want)
+	}
+	return fmt.Sprintf("patch test failed at %s: got %s, want %s", e.Path, e.Got, e.Want)
+}
+
+// minTestHashLen is the shortest @hash= prefix a test op may carry; it matches
+// the 16-character @base fingerprint.
+const minTestHashLen = 16
+
+// maxTestHashLen is the length of a whole FingerprintLoose.
+const maxTestHashLen = 64
+
+// applyTest checks a test op against the current value at its path.
+func applyTest(v *GValue, op *PatchOp) error {
+	cur, err := lookupPathSegs(v, op.Path)
+	if err != nil {
+		want := op.Hash
+		if want == "" {
+			want = CanonicalizeLooseNoTabular(op.Value)
+		}
+		return &PatchTestFailed{Path: pathSegsStr(op.Path), Want: want}
+	}
+
+	if op.Hash != "" {
+		if len(op.Hash) < minTestHashLen {
+			return fmt.Errorf("test hash %q shorter than %d characters", op.Hash, minTestHashLen)
+		}
+		if len(op.Hash) > maxTestHashLen {
+			return fmt.Errorf("test hash %q longer than %d characters", op.Hash, maxTestHashLen)
+		}
+		got := FingerprintLoose(cur)
+		if !strings.HasPrefix(got, strings.ToLower(op.Hash)) {
+			return &PatchTestFailed{Path: pathSegsStr(op.Path), Got: got, Want: op.Hash}
+		}
+		return nil
+	}
+
+	if !EqualLoose(cur, op.Value) {
+		return &PatchTestFailed{
+			Path: pathSegsStr(op.Path),
+			Got:  CanonicalizeLooseNoTabular(cur),
+			Want: CanonicalizeLooseNoTabular(op.Value),
+		}
+	}
+	return nil
+}
+
+// applyRows applies a +tab op by appending each row to the list at op.Path,
+// creating the list if the field is absent (as + does).
+func (a *patchApplier) applyRows(v *GValue, op *PatchOp) (*GValue, error) {
+	if len(op.Path) == 0 || op.Path[len(op.Path)-1].Kind == PathSegListIdx {
+		return nil, fmt.Errorf("%s path must name a list field or map key", op.Op)
+	}
+	rows, err := op.Value.AsList()
+	if err != nil {
+		return nil, fmt.Errorf("%s value: %w", op.Op, err)
+	}
+	for _, row := range rows {
+		v, err = a.applyOp(v, &PatchOp{Op: OpAppend, Path: op.Path, Value: row, Index: -1})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// applyTextEdits applies a ~str op by splicing its edits into the string at
+// op.Path. Edits must be in ascending order and must not overlap; every offset
+// is checked against the current string before anything is changed.
+func (a *patchApplier) applyTextEdits(v *GValue, op *PatchOp) (*GValue, error) {
+	cur, err := lookupPathSegs(v, op.Path)
+	if err != nil {
+		return nil, err
+	}
+	if cur.typ != TypeStr {
+		return nil, fmt.Errorf("cannot apply text edit to %s", cur.typ)
+	}
+
+	runes := []rune(cur.strVal)
+	prevEnd := 0
+	for i, e := range op.Edits {
+		if e.Start < prevEnd || e.End < e.Start || e.End > len(runes) {
+			return nil, fmt.Errorf("text edit %d (%d:%d) out of order or out of range (len=%d)", i, e.Start, e.End, len(runes))
+		}
+		prevEnd = e.End
+	}
+
+	var b strings.Builder
+	b.Grow(len(cur.strVal))
+	pos := 0
+	for _, e := range op.Edits {
+		b.WriteString(string(runes[pos:e.Start]))
+		b.WriteString(e.Text)
+		pos = e.End
+	}
+	b.WriteString(string(runes[pos:]))
+
+	return a.applyOp(v, &PatchOp{Op: OpSet, Path: op.Path, Value: Str(b.String())})
+}
+
+// lookupPathSegs returns the value at path without modifying it.
+func lookupPathSegs(v *GValue, path []PathSeg) (*GValue, error) {
+	cur := v
+	for _, seg := range path {
+		cur.force()
+		switch seg.Kind {
+		case PathSegField:
+			if seg.Field == "" && seg.FID > 0 {
+				return nil, fmt.Errorf("unresolved FID #%d in path; apply with ApplyPatchWithSchema", seg.FID)
+			}
+			if cur == nil || (cur.typ != TypeStruct && cur.typ != TypeMap) {
+				return nil, fmt.Errorf("cannot navigate into %s with field", typeName(cur))
+			}
+			next := cur.Get(seg.Field)
+			if next == nil {
+				return nil, fmt.Errorf("field not found: %s", seg.Field)
+			}
+			cur = next
+
+		case PathSegListIdx:
+			if cur == nil || cur.typ != TypeList {
+				return nil, fmt.Errorf("cannot index into %s", typeName(cur))
+			}
+			if seg.ListIdx < 0 || seg.ListIdx >= len(cur.listVal) {
+				return nil, fmt.Errorf("list index out of bounds: %d (len=%d)", seg.ListIdx, len(cur.listVal))
+			}
+			cur = cur.listVal[seg.ListIdx]
+
+		case PathSegMapKey:
+			if cur == nil || cur.typ != TypeMap {
+				return nil, fmt.Errorf("cannot access map key in %s", typeName(cur))
+			}
+			next := cur.Get(seg.MapKey)
+			if next == nil {
+				return nil, fmt.Errorf("key not found: %s", seg.MapKey)
+			}
+			cur = next
+
+		default:
+			return nil, fmt.Errorf("unknown path segment kind")
+		}
+	}
+	return cur, nil
+}
+
+// pathSegsEqual reports whether two paths address the same location.
+func pathSegsEqual(a, b []PathSeg) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Kind != b[i].Kind {
+			return false
+		}
+		switch a[i].Kind {
+		case PathSegField:
+			if a[i].Field != b[i].Field || (a[i].Field == "" && a[i].FID != b[i].FID) {
+				return false
+			}
+		case PathSegListIdx:
+			if a[i].ListIdx != b[i].ListIdx {
+				return false
+			}
+		case PathSegMapKey:
+			if a[i].MapKey != b[i].MapKey {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // applyAtPathSegs navigates to a path and applies the operation.
-func applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
+func (a *patchApplier) applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
+	v.force()
 	if len(path) == 1 {
 		// We're at the parent, apply to this level
-		return applyToParentSeg(v, path[0], op)
+		return a.applyToParentSeg(v, path[0], op)
 	}
 
 	// Navigate deeper
@@ -712,10 +1387,11 @@ func applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
 		}
 		for i, f := range v.structVal.Fields {
 			if f.Key == key {
-				newChild, err := applyAtPathSegs(f.Value, rest, op)
+				newChild, err := a.applyAtPathSegs(f.Value, rest, op)
 				if err != nil {
 					return nil, err
 				}
+				v = a.own(v)
 				v.structVal.Fields[i].Value = newChild
 				return v, nil
 			}
@@ -730,10 +1406,11 @@ func applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
 		if idx < 0 || idx >= len(v.listVal) {
 			return nil, fmt.Errorf("index out of bounds: %d", idx)
 		}
-		newChild, err := applyAtPathSegs(v.listVal[idx], rest, op)
+		newChild, err := a.applyAtPathSegs(v.listVal[idx], rest, op)
 		if err != nil {
 			return nil, err
 		}
+		v = a.own(v)
 		v.listVal[idx] = newChild
 		return v, nil
 
@@ -744,10 +1421,11 @@ func applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
 		key := seg.MapKey
 		for i, e := range v.mapVal {
 			if e.Key == key {
-				newChild, err := applyAtPathSegs(e.Value, rest, op)
+				newChild, err := a.applyAtPathSegs(e.Value, rest, op)
 				if err != nil {
 					return nil, err
 				}
+				v = a.own(v)
 				v.mapVal[i].Value = newChild
 				return v, nil
 			}
@@ -760,11 +1438,12 @@ func applyAtPathSegs(v *GValue, path []PathSeg, op *PatchOp) (*GValue, error) {
 }
 
 // applyToParentSeg applies an operation to a field/key of the parent value.
-func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
+func (a *patchApplier) applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
+	v.force()
 	// A list-index leaf operates positionally on the list itself, not via a
 	// keyed Set/Get (which would panic on a non-map/struct).
 	if seg.Kind == PathSegListIdx {
-		return applyToListSeg(v, seg, op)
+		return a.applyToListSeg(v, seg, op)
 	}
 
 	key := seg.Field
@@ -786,6 +1465,7 @@ func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 
 	switch op.Op {
 	case OpSet:
+		v = a.own(v)
 		v.Set(key, op.Value)
 		return v, nil
 
@@ -793,8 +1473,12 @@ func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 		existing := v.Get(key)
 		if existing == nil {
 			// Create new list with the value
+			v = a.own(v)
 			v.Set(key, List(op.Value))
 		} else if existing.typ == TypeList {
+			existing = a.own(existing)
+			v = a.own(v)
+			v.Set(key, existing)
 			if op.Index >= 0 && op.Index <= len(existing.listVal) {
 				// Insert at index
 				newList := make([]*GValue, 0, len(existing.listVal)+1)
@@ -812,6 +1496,7 @@ func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 		return v, nil
 
 	case OpDelete:
+		v = a.own(v)
 		switch v.typ {
 		case TypeStruct:
 			newFields := make([]MapEntry, 0, len(v.structVal.Fields))
@@ -845,6 +1530,9 @@ func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 			return nil, fmt.Errorf("delta value must be numeric")
 		}
 
+		existing = a.own(existing)
+		v = a.own(v)
+		v.Set(key, existing)
 		switch existing.typ {
 		case TypeInt:
 			// Guard against silent float→int truncation for hand-crafted patches.
@@ -872,11 +1560,13 @@ func applyToParentSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 // applyToListSeg applies an operation positionally at a list index. This covers
 // both a list-index leaf reached via a parent (e.g. items[0]) and a root-list
 // operation (a path whose only segment is a list index).
-func applyToListSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
+func (a *patchApplier) applyToListSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
+	v.force()
 	if v == nil || v.typ != TypeList {
 		return nil, fmt.Errorf("cannot apply %s at list index to %s", op.Op, typeName(v))
 	}
 	idx := seg.ListIdx
+	v = a.own(v)
 
 	switch op.Op {
 	case OpSet:
@@ -909,7 +1599,8 @@ func applyToListSeg(v *GValue, seg PathSeg, op *PatchOp) (*GValue, error) {
 		if idx < 0 || idx >= len(v.listVal) {
 			return nil, fmt.Errorf("list index out of bounds: %d (len=%d)", idx, len(v.listVal))
 		}
-		existing := v.listVal[idx]
+		existing := a.own(v.listVal[idx])
+		v.listVal[idx] = existing
 		delta, ok := op.Value.Number()
 		if !ok {
 			return nil, fmt.Errorf("delta value must be numeric")
@@ -957,15 +1648,15 @@ func deepCopy(v *GValue) *GValue {
 		intVal:   v.intVal,
 		floatVal: v.floatVal,
 		strVal:   v.strVal,
-		idVal:    v.idVal,
-		timeVal:  v.timeVal,
-		pos:      v.pos,
+		ext:      v.ext, // immutable, safe to share
 	}
 
 	// Deep copy bytes
-	if v.bytesVal != nil {
-		cp.bytesVal = make([]byte, len(v.bytesVal))
-		copy(cp.bytesVal, v.bytesVal)
+	if v.bytesVal() != nil {
+		x := *v.ext
+		x.bytesVal = make([]byte, len(v.ext.bytesVal))
+		copy(x.bytesVal, v.ext.bytesVal)
+		cp.ext = &x
 	}
 
 	// Deep copy list
@@ -1099,6 +1790,48 @@ func (pb *PatchBuilder) Delta(path string, amount float64) *PatchBuilder {
 	return pb
 }
 
+// Move adds a move operation.
+func (pb *PatchBuilder) Move(from, to string) *PatchBuilder {
+	pb.patch.Move(from, to)
+	return pb
+}
+
+// Copy adds a copy operation.
+func (pb *PatchBuilder) Copy(from, to string) *PatchBuilder {
+	pb.patch.Copy(from, to)
+	return pb
+}
+
+// AppendRows adds an append-rows operation.
+func (pb *PatchBuilder) AppendRows(path string, rows ...*GValue) *PatchBuilder {
+	pb.patch.AppendRows(path, rows...)
+	return pb
+}
+
+// EditText adds a text edit operation.
+func (pb *PatchBuilder) EditText(path string, edits ...TextEdit) *PatchBuilder {
+	pb.patch.EditText(path, edits...)
+	return pb
+}
+
+// Test adds a test operation asserting the current value at path.
+func (pb *PatchBuilder) Test(path string, want *GValue) *PatchBuilder {
+	pb.patch.Test(path, want)
+	return pb
+}
+
+// TestHash adds a test operation asserting the fingerprint of the value at path.
+func (pb *PatchBuilder) TestHash(path string, hash string) *PatchBuilder {
+	pb.patch.TestHash(path, hash)
+	return pb
+}
+
+// Custom adds a registered custom operation.
+func (pb *PatchBuilder) Custom(symbol, path string, value *GValue) *PatchBuilder {
+	pb.patch.Custom(symbol, path, value)
+	return pb
+}
+
 // Build returns the completed patch set. When a schema and target type are set,
 // FID/wire-key path segments are resolved as a pre-pass so the patch can be
 // applied directly. Resolution errors are deferred to apply time (Build has no
@@ -1164,16 +1897,93 @@ func VerifyPatchBase(base *GValue, patch *Patch) error {
 // Diff Generation
 // ============================================================
 
+// DiffOpts configures patch generation in DiffWithOptions.
+type DiffOpts struct {
+	// Deltas emits ~ ops for numeric changes (int→int, float→float) when the
+	// signed delta encodes shorter than the new value and applying it
+	// reproduces the new value exactly. Otherwise = is used.
+	Deltas bool
+
+	// IgnorePaths lists paths (patch path syntax, e.g. "meta.request_id" or
+	// `headers["x-trace"]`) whose subtrees never produce ops. A "*" segment
+	// (".*" or "[*]") matches any single field, map key or list index, and a
+	// field segment also matches a map key of the same name.
+	IgnorePaths []string
+
+	// Equal, if set, is consulted wherever both sides are present; returning
+	// true treats the values at path as unchanged. Returning false falls
+	// through to the normal comparison. path is in patch path syntax.
+	Equal func(path string, a, b *GValue) bool
+
+	ignore [][]PathSeg // IgnorePaths, parsed once per DiffWithOptions call
+}
+
+// ignored reports whether path lies at or under one of the ignore patterns.
+func (o *DiffOpts) ignored(path []PathSeg) bool {
+	for _, pat := range o.ignore {
+		if len(pat) > len(path) {
+			continue
+		}
+		match := true
+		for i, ps := range pat {
+			if !ignoreSegMatches(ps, path[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreSegMatches reports whether an ignore-pattern segment matches seg.
+func ignoreSegMatches(pat, seg PathSeg) bool {
+	if pat.Kind == PathSegField && pat.Field == "*" {
+		return true
+	}
+	switch seg.Kind {
+	case PathSegField:
+		return pat.Kind == PathSegField && pat.Field == seg.Field
+	case PathSegMapKey:
+		return (pat.Kind == PathSegMapKey && pat.MapKey == seg.MapKey) ||
+			(pat.Kind == PathSegField && pat.Field == seg.MapKey)
+	case PathSegListIdx:
+		return pat.Kind == PathSegListIdx && pat.ListIdx == seg.ListIdx
+	}
+	return false
+}
+
 // Diff computes the patch set needed to transform 'from' into 'to'.
 func Diff(from, to *GValue, typeName string) *Patch {
+	return DiffWithOptions(from, to, typeName, DiffOpts{})
+}
+
+// DiffWithOptions computes the patch set needed to transform 'from' into 'to'
+// with custom options.
+func DiffWithOptions(from, to *GValue, typeName string, opts DiffOpts) *Patch {
+	opts.ignore = nil
+	for _, path := range opts.IgnorePaths {
+		if segs := parsePathToSegs(path); len(segs) > 0 {
+			opts.ignore = append(opts.ignore, segs)
+		}
+	}
+
 	p := NewPatch(RefID{}, "")
 	p.TargetType = typeName
-	diffValues(from, to, nil, p)
+	diffValues(from, to, nil, p, opts)
 	return p
 }
 
 // diffValues recursively computes differences.
-func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
+func diffValues(from, to *GValue, path []PathSeg, p *Patch, opts DiffOpts) {
+	from.force()
+	to.force()
+	if opts.ignored(path) {
+		return
+	}
+
 	// Handle nil cases
 	if from == nil && to == nil {
 		return
@@ -1196,6 +2006,10 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 		return
 	}
 
+	if opts.Equal != nil && opts.Equal(pathSegsStr(path), from, to) {
+		return
+	}
+
 	// Type mismatch: replace
 	if from.typ != to.typ {
 		p.Ops = append(p.Ops, &PatchOp{
@@ -1222,15 +2036,16 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 
 	case TypeInt:
 		if from.intVal != to.intVal {
-			p.Ops = append(p.Ops, &PatchOp{
-				Op:    OpSet,
-				Path:  copyPath(path),
-				Value: to,
-			})
+			p.Ops = append(p.Ops, numericChangeOp(from, to, path, opts))
 		}
 
 	case TypeFloat:
 		if from.floatVal != to.floatVal {
+			p.Ops = append(p.Ops, numericChangeOp(from, to, path, opts))
+		}
+
+	case TypeStr:
+		if from.strVal != to.strVal {
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:    OpSet,
 				Path:  copyPath(path),
@@ -1238,8 +2053,8 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 			})
 		}
 
-	case TypeStr:
-		if from.strVal != to.strVal {
+	case TypeID:
+		if from.idVal() != to.idVal() {
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:    OpSet,
 				Path:  copyPath(path),
@@ -1247,8 +2062,8 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 			})
 		}
 
-	case TypeID:
-		if from.idVal != to.idVal {
+	case TypeCustom:
+		if !valuesEqual(from, to) {
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:    OpSet,
 				Path:  copyPath(path),
@@ -1257,14 +2072,14 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 		}
 
 	case TypeStruct:
-		diffStructValues(from, to, path, p)
+		diffStructValues(from, to, path, p, opts)
 
 	case TypeMap:
-		diffMapValues(from, to, path, p)
+		diffMapValues(from, to, path, p, opts)
 
 	case TypeList:
 		// For now, just replace if different
-		if !listsEqual(from.listVal, to.listVal) {
+		if !listsEqualWithOpts(from.listVal, to.listVal, path, opts) {
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:    OpSet,
 				Path:  copyPath(path),
@@ -1282,6 +2097,47 @@ func diffValues(from, to *GValue, path []PathSeg, p *Patch) {
 	}
 }
 
+// numericChangeOp returns the op for a changed int or float of the same type:
+// a ~ delta when opts.Deltas is set and the delta is exact and shorter to
+// encode than the new value, otherwise a plain =.
+func numericChangeOp(from, to *GValue, path []PathSeg, opts DiffOpts) *PatchOp {
+	set := &PatchOp{Op: OpSet, Path: copyPath(path), Value: to}
+	if !opts.Deltas {
+		return set
+	}
+
+	var delta *GValue
+	var deltaText, setText string
+	switch from.typ {
+	case TypeInt:
+		d := to.intVal - from.intVal
+		// Reject overflowed subtraction (sign disagrees with the comparison)
+		// and deltas that ApplyPatch's float64 arithmetic cannot carry exactly.
+		if (d > 0) != (to.intVal > from.intVal) || d > 1<<53 || d < -(1<<53) {
+			return set
+		}
+		delta = Int(d)
+		deltaText, setText = canonInt(d), canonInt(to.intVal)
+	case TypeFloat:
+		d := to.floatVal - from.floatVal
+		if math.IsInf(d, 0) || math.IsNaN(d) || from.floatVal+d != to.floatVal {
+			return set
+		}
+		delta = Float(d)
+		deltaText, setText = canonFloat(d), canonFloat(to.floatVal)
+	default:
+		return set
+	}
+	if !strings.HasPrefix(deltaText, "-") {
+		deltaText = "+" + deltaText
+	}
+
+	if len(deltaText) >= len(setText) {
+		return set
+	}
+	return &PatchOp{Op: OpDelta, Path: copyPath(path), Value: delta}
+}
+
 // copyPath creates a copy of the path slice.
 func copyPath(path []PathSeg) []PathSeg {
 	if path == nil {
@@ -1293,7 +2149,7 @@ func copyPath(path []PathSeg) []PathSeg {
 }
 
 // diffStructValues computes differences between two structs.
-func diffStructValues(from, to *GValue, path []PathSeg, p *Patch) {
+func diffStructValues(from, to *GValue, path []PathSeg, p *Patch, opts DiffOpts) {
 	fromFields := make(map[string]*GValue)
 	for _, f := range from.structVal.Fields {
 		fromFields[f.Key] = f.Value
@@ -1308,13 +2164,16 @@ func diffStructValues(from, to *GValue, path []PathSeg, p *Patch) {
 	for key, toVal := range toFields {
 		fromVal := fromFields[key]
 		childPath := append(copyPath(path), FieldSeg(key, 0))
-		diffValues(fromVal, toVal, childPath, p)
+		diffValues(fromVal, toVal, childPath, p, opts)
 	}
 
 	// Check for deleted fields
 	for key := range fromFields {
 		if _, exists := toFields[key]; !exists {
 			childPath := append(copyPath(path), FieldSeg(key, 0))
+			if opts.ignored(childPath) {
+				continue
+			}
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:   OpDelete,
 				Path: childPath,
@@ -1324,7 +2183,7 @@ func diffStructValues(from, to *GValue, path []PathSeg, p *Patch) {
 }
 
 // diffMapValues computes differences between two maps.
-func diffMapValues(from, to *GValue, path []PathSeg, p *Patch) {
+func diffMapValues(from, to *GValue, path []PathSeg, p *Patch, opts DiffOpts) {
 	fromMap := make(map[string]*GValue)
 	for _, e := range from.mapVal {
 		fromMap[e.Key] = e.Value
@@ -1338,12 +2197,15 @@ func diffMapValues(from, to *GValue, path []PathSeg, p *Patch) {
 	for key, toVal := range toMap {
 		fromVal := fromMap[key]
 		childPath := append(copyPath(path), MapKeySeg(key))
-		diffValues(fromVal, toVal, childPath, p)
+		diffValues(fromVal, toVal, childPath, p, opts)
 	}
 
 	for key := range fromMap {
 		if _, exists := toMap[key]; !exists {
 			childPath := append(copyPath(path), MapKeySeg(key))
+			if opts.ignored(childPath) {
+				continue
+			}
 			p.Ops = append(p.Ops, &PatchOp{
 				Op:   OpDelete,
 				Path: childPath,
@@ -1352,6 +2214,26 @@ func diffMapValues(from, to *GValue, path []PathSeg, p *Patch) {
 	}
 }
 
+// listsEqualWithOpts compares lists element by element, honoring the ignore
+// patterns and Equal hook so that volatile fields inside list elements do not
+// force a whole-list replacement.
+func listsEqualWithOpts(a, b []*GValue, path []PathSeg, opts DiffOpts) bool {
+	if len(opts.ignore) == 0 && opts.Equal == nil {
+		return listsEqual(a, b)
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		scratch := NewPatch(RefID{}, "")
+		diffValues(a[i], b[i], append(copyPath(path), ListIdxSeg(i)), scratch, opts)
+		if len(scratch.Ops) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // listsEqual checks if two lists are deeply equal.
 func listsEqual(a, b []*GValue) bool {
 	if len(a) != len(b) {
@@ -1367,6 +2249,8 @@ func listsEqual(a, b []*GValue) bool {
 
 // valuesEqual checks if two values are deeply equal.
 func valuesEqual(a, b *GValue) bool {
+	a.force()
+	b.force()
 	if a == nil && b == nil {
 		return true
 	}
@@ -1389,7 +2273,10 @@ func valuesEqual(a, b *GValue) bool {
 	case TypeStr:
 		return a.strVal == b.strVal
 	case TypeID:
-		return a.idVal == b.idVal
+		return a.idVal() == b.idVal()
+	case TypeCustom:
+		ca, cb := a.customVal(), b.customVal()
+		return ca.Tag == cb.Tag && ca.Text == cb.Text
 	case TypeList:
 		return listsEqual(a.listVal, b.listVal)
 	case TypeStruct:
@@ -1413,3 +2300,56 @@ func valuesEqual(a, b *GValue) bool {
 		return false
 	}
 }
+
+// ============================================================
+// Patch vs Snapshot Selection
+// ============================================================
+
+// EncodingChoice reports the sizes of both candidate encodings of an update
+// and carries the rendered payload of the cheaper one.
+type EncodingChoice struct {
+	UsePatch bool   // true when the patch is cheaper than the snapshot
+	Payload  string // rendered @patch block, or the loose canonical snapshot
+	Patch    *Patch // the diff from base to next (nil when base is nil)
+
+	PatchBytes     int // length of the rendered patch (0 when base is nil)
+	SnapshotBytes  int // length of CanonicalizeLoose(next)
+	PatchTokens    int // EstimateTokens of the rendered patch
+	SnapshotTokens int // EstimateTokens of the snapshot
+}
+
+// ChooseEncoding renders next both as a patch against base and as a full
+// loose canonical snapshot, and picks whichever is cheaper: fewer estimated
+// tokens, then fewer bytes, with ties going to the patch. Numeric changes use
+// ~ deltas where shorter. A nil base always selects the snapshot.
+//
+// The patch is emitted without a schema or target, so it applies with
+// ParsePatch + ApplyPatch; callers that need @target or @base can set them on
+// Patch and re-emit.
+func ChooseEncoding(base, next *GValue) (*EncodingChoice, error) {
+	snapshot := CanonicalizeLoose(next)
+	choice := &EncodingChoice{
+		Payload:        snapshot,
+		SnapshotBytes:  len(snapshot),
+		SnapshotTokens: EstimateTokens(snapshot),
+	}
+	if base == nil {
+		return choice, nil
+	}
+
+	patch := DiffWithOptions(base, next, "", DiffOpts{Deltas: true})
+	rendered, err := EmitPatch(patch, nil)
+	if err != nil {
+		return nil, err
+	}
+	choice.Patch = patch
+	choice.PatchBytes = len(rendered)
+	choice.PatchTokens = EstimateTokens(rendered)
+
+	if choice.PatchTokens < choice.SnapshotTokens ||
+		(choice.PatchTokens == choice.SnapshotTokens && choice.PatchBytes <= choice.SnapshotBytes) {
+		choice.UsePatch = true
+		choice.Payload = rendered
+	}
+	return choice, nil
+}
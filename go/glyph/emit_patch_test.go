@@ -1,6 +1,7 @@
 package glyph
 
 import (
+	"math"
 	"strings"
 	"testing"
 )
@@ -565,3 +566,245 @@ func TestPatchWithExplicitFingerprint(t *testing.T) {
 		t.Errorf("Expected explicit base fingerprint, got: %s", got)
 	}
 }
+
+func TestDiffWithOptions_Deltas(t *testing.T) {
+	from := Struct("Match",
+		FieldVal("clicks", Int(1048576)),
+		FieldVal("score", Int(2)),
+		FieldVal("rating", Float(1234.5)),
+		FieldVal("ratio", Float(0.1)),
+		FieldVal("big", Int(1<<62)),
+	)
+	to := Struct("Match",
+		FieldVal("clicks", Int(1048577)), // +1 is shorter than 1048577
+		FieldVal("score", Int(3)),        // +1 is not shorter than 3
+		FieldVal("rating", Float(1236.5)),
+		FieldVal("ratio", Float(0.3)), // 0.1+0.2 != 0.3: delta is inexact
+		FieldVal("big", Int(-(1<<62))),
+	)
+
+	ops := map[string]*PatchOp{}
+	for _, op := range DiffWithOptions(from, to, "Match", DiffOpts{Deltas: true}).Ops {
+		ops[pathSegsStr(op.Path)] = op
+	}
+
+	want := map[string]PatchOpKind{
+		"clicks": OpDelta,
+		"score":  OpSet,
+		"rating": OpDelta,
+		"ratio":  OpSet,
+		"big":    OpSet,
+	}
+	for path, kind := range want {
+		if ops[path] == nil || ops[path].Op != kind {
+			t.Errorf("%s: got %v, want %s", path, ops[path], kind)
+		}
+	}
+
+	// Without the option every numeric change is a set.
+	for _, op := range Diff(from, to, "Match").Ops {
+		if op.Op != OpSet {
+			t.Errorf("Diff without Deltas produced %s for %s", op.Op, pathSegsStr(op.Path))
+		}
+	}
+
+	// The delta patch survives text round-trip and reproduces 'to'.
+	patch := DiffWithOptions(from, to, "Match", DiffOpts{Deltas: true})
+	text, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	if !strings.Contains(text, "~ clicks +1") {
+		t.Errorf("expected ~ clicks +1 in:\n%s", text)
+	}
+	parsed, err := ParsePatch(text, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	got, err := ApplyPatch(from, parsed)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if !EqualLoose(got, to) {
+		t.Errorf("applied result mismatch:\n got %s\nwant %s", CanonicalizeLoose(got), CanonicalizeLoose(to))
+	}
+}
+
+func TestDiffWithOptions_IgnorePathsAndEqual(t *testing.T) {
+	from := Struct("Resp",
+		FieldVal("status", Str("ok")),
+		FieldVal("request_id", Str("r-1")),
+		FieldVal("meta", Map(MapEntry{Key: "trace", Value: Str("t-1")}, MapEntry{Key: "region", Value: Str("eu")})),
+		FieldVal("items", List(
+			Struct("Item", FieldVal("id", Int(1)), FieldVal("updated_at", Str("2025-01-01T00:00:00Z"))),
+		)),
+		FieldVal("price", Float(9.99)),
+	)
+	to := Struct("Resp",
+		FieldVal("status", Str("ok")),
+		FieldVal("request_id", Str("r-2")),
+		FieldVal("meta", Map(MapEntry{Key: "trace", Value: Str("t-2")})),
+		FieldVal("items", List(
+			Struct("Item", FieldVal("id", Int(1)), FieldVal("updated_at", Str("2025-06-01T00:00:00Z"))),
+		)),
+		FieldVal("price", Float(9.990001)),
+	)
+
+	opts := DiffOpts{
+		IgnorePaths: []string{"request_id", `meta["trace"]`, "items[*].updated_at"},
+		Equal: func(path string, a, b *GValue) bool {
+			if path != "price" {
+				return false
+			}
+			x, _ := a.Number()
+			y, _ := b.Number()
+			return math.Abs(x-y) < 0.01
+		},
+	}
+	patch := DiffWithOptions(from, to, "Resp", opts)
+
+	// Only the removed meta.region remains; everything else is ignored or equal.
+	if len(patch.Ops) != 1 {
+		t.Fatalf("expected 1 op, got %d: %+v", len(patch.Ops), patch.Ops)
+	}
+	if op := patch.Ops[0]; op.Op != OpDelete || pathSegsStr(op.Path) != `meta["region"]` {
+		t.Errorf("unexpected op %s %s", op.Op, pathSegsStr(op.Path))
+	}
+
+	// A real change inside a list element still replaces the list.
+	to.Get("items").listVal[0].Set("id", Int(2))
+	patch = DiffWithOptions(from, to, "Resp", opts)
+	found := false
+	for _, op := range patch.Ops {
+		if pathSegsStr(op.Path) == "items" && op.Op == OpSet {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected items replacement, got %+v", patch.Ops)
+	}
+
+	// Ignoring a field also suppresses its deletion; "*" matches any field.
+	gone := Struct("Resp", FieldVal("status", Str("ok")))
+	if ops := DiffWithOptions(from, gone, "Resp", DiffOpts{IgnorePaths: []string{"*"}}).Ops; len(ops) != 0 {
+		t.Errorf("expected no ops with * ignored, got %d", len(ops))
+	}
+}
+
+func TestChooseEncoding(t *testing.T) {
+	events := List()
+	for i := 0; i < 40; i++ {
+		events.Append(Str("event-with-a-reasonably-long-description"))
+	}
+	base := Struct("Match",
+		FieldVal("id", ID("m", "123")),
+		FieldVal("minute", Int(10)),
+		FieldVal("events", events),
+	)
+
+	// Small change to a large document: the patch wins and applies cleanly.
+	next := deepCopy(base)
+	next.Set("minute", Int(11))
+	choice, err := ChooseEncoding(base, next)
+	if err != nil {
+		t.Fatalf("ChooseEncoding error: %v", err)
+	}
+	if !choice.UsePatch || choice.PatchTokens >= choice.SnapshotTokens {
+		t.Fatalf("expected patch to win: %+v", choice)
+	}
+	parsed, err := ParsePatch(choice.Payload, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	got, err := ApplyPatch(base, parsed)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if !EqualLoose(got, next) {
+		t.Errorf("patch payload did not reproduce next")
+	}
+
+	// Rewriting everything: the snapshot wins.
+	small := Struct("Match", FieldVal("id", ID("m", "999")))
+	choice, err = ChooseEncoding(base, small)
+	if err != nil {
+		t.Fatalf("ChooseEncoding error: %v", err)
+	}
+	if choice.UsePatch || choice.Payload != CanonicalizeLoose(small) {
+		t.Errorf("expected snapshot to win: %+v", choice)
+	}
+
+	// No base: always a snapshot.
+	choice, _ = ChooseEncoding(nil, small)
+	if choice.UsePatch || choice.Patch != nil || choice.PatchBytes != 0 {
+		t.Errorf("nil base should select snapshot: %+v", choice)
+	}
+}
+
+func cowState(tasks int) *GValue {
+	items := make([]*GValue, tasks)
+	for i := range items {
+		items[i] = Struct("Task",
+			FieldVal("id", Int(int64(i))),
+			FieldVal("status", Str("todo")),
+			FieldVal("notes", Str(strings.Repeat("n", 40))),
+		)
+	}
+	return Struct("State",
+		FieldVal("count", Int(0)),
+		FieldVal("meta", Struct("Meta", FieldVal("owner", Str("ops")))),
+		FieldVal("log", List(Str("start"))),
+		FieldVal("tasks", List(items...)),
+	)
+}
+
+func TestApplyPatch_SharesUnpatchedSubtrees(t *testing.T) {
+	state := cowState(3)
+	before := CanonicalizeLoose(state)
+
+	p := NewPatchBuilder(RefID{}).
+		Delta("count", 1).
+		Delta("count", 1).
+		Set("tasks[1].status", Str("done")).
+		Append("log", Str("a")).
+		Append("log", Str("b")).
+		Build()
+	got, err := ApplyPatch(state, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if CanonicalizeLoose(state) != before {
+		t.Error("ApplyPatch modified its input")
+	}
+	if n := mustAsInt(t, got.Get("count")); n != 2 {
+		t.Errorf("count = %d, want 2", n)
+	}
+	if got.Get("log").Len() != 3 {
+		t.Errorf("log = %s", Emit(got.Get("log")))
+	}
+
+	tasks, old := got.Get("tasks").listVal, state.Get("tasks").listVal
+	if got.Get("meta") != state.Get("meta") || tasks[0] != old[0] || tasks[2] != old[2] {
+		t.Error("unpatched subtrees should be shared with the input")
+	}
+	if tasks[1] == old[1] || mustAsStr(t, tasks[1].Get("status")) != "done" {
+		t.Error("patched task should be a new node")
+	}
+}
+
+func BenchmarkApplyPatch_LargeState(b *testing.B) {
+	state := cowState(10000)
+	p := NewPatchBuilder(RefID{}).
+		Delta("count", 1).
+		Set("tasks[5000].status", Str("done")).
+		Build()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ApplyPatch(state, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -26,6 +26,7 @@ type TabularOptions struct {
 	KeyMode      KeyMode // Column header format
 	UseBitmap    bool    // Use bitmap for sparse optionals in cells
 	IndentPrefix string  // Prefix for each row (e.g., "  ")
+	Verify       bool    // Re-parse the output; return an *IntegrityError if it does not round-trip
 }
 
 // DefaultTabularOptions returns default tabular encoding options.
@@ -47,6 +48,7 @@ func EmitTabular(v *GValue, schema *Schema) (string, error) {
 
 // EmitTabularWithOptions encodes a list of structs with custom options.
 func EmitTabularWithOptions(v *GValue, opts TabularOptions) (string, error) {
+	v.force()
 	if v == nil || v.typ != TypeList {
 		return "", fmt.Errorf("tabular encoding requires list value")
 	}
@@ -85,6 +87,18 @@ func EmitTabularWithOptions(v *GValue, opts TabularOptions) (string, error) {
 		return "", err
 	}
 
+	if opts.Verify {
+		err := verifyEmission("tabular", v, buf.String(), func(s string) (*GValue, error) {
+			rows, err := NewTabularReaderFromString(s, opts.Schema).ReadAll()
+			if err != nil {
+				return nil, err
+			}
+			return List(rows...), nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
 	return buf.String(), nil
 }
 
@@ -171,6 +185,12 @@ func emitTabularRow(out *bytes.Buffer, row *GValue, fields []*FieldDef, opts Tab
 
 // emitTabularCell writes a single cell value in tabular format.
 func emitTabularCell(out *bytes.Buffer, val *GValue, fd *FieldDef, opts PackedOptions) error {
+	val.force()
+	if lit, ok := codecFieldLiteral(val, fd); ok {
+		out.WriteString(lit)
+		return nil
+	}
+
 	if val == nil {
 		out.WriteString(canonNull())
 		return nil
@@ -193,13 +213,16 @@ func emitTabularCell(out *bytes.Buffer, val *GValue, fd *FieldDef, opts PackedOp
 		out.WriteString(canonString(val.strVal))
 
 	case TypeID:
-		out.WriteString(canonRef(val.idVal))
+		out.WriteString(canonRef(val.idVal()))
 
 	case TypeTime:
-		out.WriteString(canonTime(val.timeVal))
+		out.WriteString(canonTime(val.timeVal()))
 
 	case TypeBytes:
-		out.WriteString(canonBytes(val.bytesVal))
+		out.WriteString(canonBytes(val.bytesVal()))
+
+	case TypeCustom:
+		out.WriteString(canonCustom(val.customVal()))
 
 	case TypeList:
 		out.WriteByte('[')
@@ -290,6 +313,7 @@ func EmitInlineTabular(v *GValue, schema *Schema) (string, error) {
 }
 
 func emitInlineTabularWithOptions(v *GValue, opts TabularOptions) (string, error) {
+	v.force()
 	if v == nil || v.typ != TypeList {
 		return "", fmt.Errorf("inline tabular requires list value")
 	}
@@ -0,0 +1,168 @@
+package glyph
+
+import (
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Emission Quality
+// ============================================================
+//
+// Teams that ask models for GLYPH want to know how often the output is
+// usable as is, how often only because the tolerant parser repaired it, and
+// what it costs in tokens. EvalOutput measures one output; an EvalReport
+// adds the measurements up.
+
+// EvalOptions configures EvalOutput.
+type EvalOptions struct {
+	Schema    *Schema         // Schema to parse and validate with (optional)
+	Type      string          // Type outputs must have; empty validates by their own type name
+	Estimator *TokenEstimator // Counts tokens; nil uses DefaultTokenEstimator
+}
+
+// EvalSample is the measurement of one model output.
+type EvalSample struct {
+	ID              string
+	Parsed          bool // Produced a value without parse errors
+	Valid           bool // Parsed and, with a schema, validated
+	ParseError      string
+	Repairs         []string          // Parse warning codes, in order
+	Validation      []ValidationError // Validation errors, with a schema
+	Tokens          int               // Tokens in the output as written
+	CanonicalTokens int               // Tokens in its canonical form; 0 unless parsed
+}
+
+// EvalOutput parses output in tolerant mode and, with a schema, validates
+// the value.
+func EvalOutput(id, output string, opts EvalOptions) EvalSample {
+	est := DefaultTokenEstimator
+	if opts.Estimator != nil {
+		est = *opts.Estimator
+	}
+	sample := EvalSample{ID: id, Tokens: est.Count(output)}
+	if strings.TrimSpace(output) == "" {
+		sample.ParseError = "empty output"
+		return sample
+	}
+
+	result, err := ParseWithSchema(output, opts.Schema)
+	if err == nil && result.HasErrors() {
+		err = &result.Errors[0]
+	}
+	if err != nil {
+		sample.ParseError = err.Error()
+		return sample
+	}
+	for _, w := range result.Warnings {
+		sample.Repairs = append(sample.Repairs, w.Code)
+	}
+	if result.Value == nil {
+		sample.ParseError = "no value"
+		return sample
+	}
+	sample.Parsed = true
+	sample.CanonicalTokens = est.Count(CanonicalizeLoose(result.Value))
+
+	sample.Valid = true
+	if opts.Schema != nil {
+		var check *ValidationResult
+		if opts.Type != "" {
+			check = ValidateAs(result.Value, opts.Schema, opts.Type)
+		} else {
+			check = ValidateWithSchema(result.Value, opts.Schema)
+		}
+		sample.Valid = check.Valid
+		sample.Validation = check.Errors
+	}
+	return sample
+}
+
+// EvalReport aggregates EvalSamples.
+type EvalReport struct {
+	Samples  []EvalSample
+	Parsed   int            // Samples that parsed
+	Valid    int            // Samples that parsed and validated
+	Clean    int            // Valid samples that needed no repair
+	Repairs  map[string]int // Parse warnings by code
+	Failures map[string]int // Validation errors by code
+	Tokens   TokenStats     // Over all samples, as written
+}
+
+// TokenStats summarizes token counts.
+type TokenStats struct {
+	Min   int
+	Max   int
+	Total int
+}
+
+// NewEvalReport returns an empty report.
+func NewEvalReport() *EvalReport {
+	return &EvalReport{Repairs: make(map[string]int), Failures: make(map[string]int)}
+}
+
+// Add records s.
+func (r *EvalReport) Add(s EvalSample) {
+	if len(r.Samples) == 0 || s.Tokens < r.Tokens.Min {
+		r.Tokens.Min = s.Tokens
+	}
+	if s.Tokens > r.Tokens.Max {
+		r.Tokens.Max = s.Tokens
+	}
+	r.Tokens.Total += s.Tokens
+	r.Samples = append(r.Samples, s)
+
+	if s.Parsed {
+		r.Parsed++
+	}
+	if s.Valid {
+		r.Valid++
+		if len(s.Repairs) == 0 {
+			r.Clean++
+		}
+	}
+	for _, code := range s.Repairs {
+		r.Repairs[code]++
+	}
+	for _, e := range s.Validation {
+		r.Failures[e.Code]++
+	}
+}
+
+// ParseRate returns the fraction of samples that parsed, or 0 with none.
+func (r *EvalReport) ParseRate() float64 {
+	return r.rate(r.Parsed)
+}
+
+// ValidRate returns the fraction of samples that parsed and validated.
+func (r *EvalReport) ValidRate() float64 {
+	return r.rate(r.Valid)
+}
+
+func (r *EvalReport) rate(n int) float64 {
+	if len(r.Samples) == 0 {
+		return 0
+	}
+	return float64(n) / float64(len(r.Samples))
+}
+
+// MeanTokens returns the mean token count of the samples, or 0 with none.
+func (r *EvalReport) MeanTokens() float64 {
+	return r.rate(r.Tokens.Total)
+}
+
+// RankCounts returns the keys of counts, most frequent first and ties in
+// name order.
+func RankCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,62 @@
+package glyph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalOutput(t *testing.T) {
+	schema, err := ParseSchema(`@schema{ Call struct{ name: str  n: int [min=0] } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := EvalOptions{Schema: schema, Type: "Call"}
+
+	tests := []struct {
+		output        string
+		parsed, valid bool
+		repairs       []string
+		failure       string
+	}{
+		{"Call{name=search n=1}", true, true, nil, ""},
+		{"Call{name=search n=+1,000", true, true, []string{"repaired_number", "auto_closed"}, ""},
+		{"Call{name=search n=-2}", true, false, nil, "constraint_min"},
+		{"Call{name=search}", true, false, nil, "required_field"},
+		{"", false, false, nil, ""},
+		{`Call{name="search`, false, false, nil, ""},
+	}
+	report := NewEvalReport()
+	for _, tt := range tests {
+		s := EvalOutput("", tt.output, opts)
+		report.Add(s)
+		if s.Parsed != tt.parsed || s.Valid != tt.valid || !reflect.DeepEqual(s.Repairs, tt.repairs) {
+			t.Errorf("%q: parsed=%v valid=%v repairs=%v", tt.output, s.Parsed, s.Valid, s.Repairs)
+		}
+		if tt.failure != "" && (len(s.Validation) == 0 || s.Validation[0].Code != tt.failure) {
+			t.Errorf("%q: validation %v, want %s", tt.output, s.Validation, tt.failure)
+		}
+		if s.Tokens == 0 && tt.output != "" {
+			t.Errorf("%q: no tokens counted", tt.output)
+		}
+	}
+
+	if report.Parsed != 4 || report.ParseRate() != 4.0/6 || report.Valid != 2 || report.Clean != 1 {
+		t.Errorf("parsed=%d valid=%d clean=%d", report.Parsed, report.Valid, report.Clean)
+	}
+	if report.ValidRate() != 2.0/6 {
+		t.Errorf("ValidRate = %v", report.ValidRate())
+	}
+	if report.Repairs["auto_closed"] != 1 || report.Failures["required_field"] != 1 {
+		t.Errorf("repairs=%v failures=%v", report.Repairs, report.Failures)
+	}
+	if report.Tokens.Min != 0 || report.Tokens.Max < report.Tokens.Min {
+		t.Errorf("tokens = %+v", report.Tokens)
+	}
+}
+
+func TestRankCounts(t *testing.T) {
+	got := RankCounts(map[string]int{"b": 2, "a": 2, "c": 5})
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RankCounts = %v, want %v", got, want)
+	}
+}
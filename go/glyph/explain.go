@@ -0,0 +1,183 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// Explain Mode
+// ============================================================
+//
+// Loose emission makes silent choices: a list becomes a @tab block or not,
+// and compact keys pay off or not. EmitExplain returns the output together
+// with the reasons, so a surprising encoding can be traced to the rule that
+// produced it.
+
+// TableDecision records whether one list was emitted as a @tab block.
+type TableDecision struct {
+	Path        string   // Validator-style path of the list ("" for the root)
+	Rows        int      // List length
+	Tabular     bool     // Emitted as @tab
+	ColumnMajor bool     // Emitted as @tabc, when Tabular
+	Type        string   // Struct type of a @tab Type block ("" for @tab _)
+	Columns     []string // Table columns, when Tabular
+	Reason      string   // Why not, when !Tabular
+}
+
+// Explanation is the report returned by EmitExplain.
+type Explanation struct {
+	Tables []TableDecision   // Every non-empty list, depth first
+	Keys   KeyEncodingChoice // Compact-key header amortization for one document
+}
+
+// String renders the report one decision per line.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	for _, t := range e.Tables {
+		at := t.Path
+		if at == "" {
+			at = "root"
+		}
+		if t.Tabular {
+			tag := "@tab"
+			if t.ColumnMajor {
+				tag = "@tabc"
+			}
+			if t.Type != "" {
+				tag += " " + t.Type
+			}
+			fmt.Fprintf(&b, "%s: %s, %d rows x %d cols %v\n", at, tag, t.Rows, len(t.Columns), t.Columns)
+		} else {
+			fmt.Fprintf(&b, "%s: list, %s\n", at, t.Reason)
+		}
+	}
+	k := e.Keys
+	verdict := "full keys"
+	if k.Compact {
+		verdict = "compact keys"
+	}
+	fmt.Fprintf(&b, "keys: %s (full %d tokens; compact %d + header %d = %d tokens)\n",
+		verdict, k.FullTokens, k.CompactTokens, k.HeaderTokens, k.CompactTokens+k.HeaderTokens)
+	return b.String()
+}
+
+// EmitExplain emits v like CanonicalizeLooseWithSchema when opts selects a
+// key dictionary, and like CanonicalizeLooseWithOpts otherwise, and explains
+// the table and key-encoding decisions. Keys reports whether compact keys
+// would pay off for v, whether or not opts uses them.
+func EmitExplain(v *GValue, opts LooseCanonOpts) (string, *Explanation) {
+	if opts.MinRows == 0 {
+		opts.MinRows = 3
+	}
+	if opts.MaxCols == 0 {
+		opts.MaxCols = 20
+	}
+
+	var out string
+	if opts.Schema != nil || opts.SchemaRef != "" || len(opts.KeyDict) > 0 {
+		out = CanonicalizeLooseWithSchema(v, opts)
+	} else {
+		out = CanonicalizeLooseWithOpts(v, opts)
+	}
+
+	e := &Explanation{Keys: ChooseKeyEncoding(v, opts, 1)}
+	e.walk(v, "", opts)
+	return out, e
+}
+
+// walk mirrors writeCanonLoose's traversal, recording list decisions.
+func (e *Explanation) walk(v *GValue, path string, opts LooseCanonOpts) {
+	v.force()
+	if v == nil {
+		return
+	}
+	switch v.typ {
+	case TypeList:
+		if len(v.listVal) == 0 {
+			return
+		}
+		d := TableDecision{Path: path, Rows: len(v.listVal)}
+		if !opts.AutoTabular {
+			d.Reason = "auto-tabular disabled"
+		} else if t := planLooseTable(v.listVal, opts); t != nil {
+			d.Tabular, d.Columns = true, t.columns
+			if t.typeName != "_" {
+				d.Type = t.typeName
+			}
+			d.ColumnMajor = t.columnMajor(v.listVal, opts)
+		} else {
+			d.Reason = whyNotTabular(v.listVal, opts)
+		}
+		e.Tables = append(e.Tables, d)
+		for i, item := range v.listVal {
+			e.walk(item, fmt.Sprintf("%s[%d]", path, i), opts)
+		}
+	case TypeMap:
+		for _, entry := range v.mapVal {
+			e.walk(entry.Value, joinPath(path, entry.Key), opts)
+		}
+	case TypeStruct:
+		for _, f := range v.structVal.Fields {
+			e.walk(f.Value, joinPath(path, f.Key), opts)
+		}
+	case TypeSum:
+		if v.sumVal != nil {
+			e.walk(v.sumVal.Value, path, opts)
+		}
+	}
+}
+
+// whyNotTabular restates the first detectTabular rule that items fail.
+func whyNotTabular(items []*GValue, opts LooseCanonOpts) string {
+	if len(items) < opts.MinRows {
+		return fmt.Sprintf("%d rows, fewer than MinRows=%d", len(items), opts.MinRows)
+	}
+
+	all := make(map[string]int) // key -> number of rows having it
+	var first []string
+	for i, item := range items {
+		keys := getObjectKeys(item)
+		if keys == nil {
+			return fmt.Sprintf("element %d is a %s, not an object", i, item.Type())
+		}
+		if len(keys) == 0 {
+			return fmt.Sprintf("element %d is an empty object", i)
+		}
+		if i == 0 {
+			first = keys
+		} else if !opts.AllowMissing && !sameKeySet(first, keys) {
+			return fmt.Sprintf("element %d has different keys and AllowMissing is off", i)
+		}
+		for _, k := range keys {
+			all[k]++
+		}
+	}
+
+	if len(all) > opts.MaxCols {
+		return fmt.Sprintf("%d columns, more than MaxCols=%d", len(all), opts.MaxCols)
+	}
+	shared := 0
+	for _, n := range all {
+		if n == len(items) {
+			shared++
+		}
+	}
+	return fmt.Sprintf("only %d of %d keys are in every row, fewer than half", shared, len(all))
+}
+
+func sameKeySet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, k := range a {
+		set[k] = true
+	}
+	for _, k := range b {
+		if !set[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmitExplain(t *testing.T) {
+	row := func(id int64) *GValue {
+		return Map(MapEntry{Key: "id", Value: Int(id)}, MapEntry{Key: "name", Value: Str("n")})
+	}
+	flag := func(key string) *GValue { return Map(MapEntry{Key: key, Value: Bool(true)}) }
+	v := Map(
+		MapEntry{Key: "users", Value: List(row(1), row(2), row(3))},
+		MapEntry{Key: "pair", Value: List(row(1), row(2))},
+		MapEntry{Key: "mixed", Value: List(flag("a"), flag("b"), flag("c"))},
+		MapEntry{Key: "tags", Value: List(Str("a"), Str("b"), Str("c"))},
+	)
+
+	out, e := EmitExplain(v, DefaultLooseCanonOpts())
+	if out != CanonicalizeLooseWithOpts(v, DefaultLooseCanonOpts()) {
+		t.Errorf("output differs from CanonicalizeLooseWithOpts: %s", out)
+	}
+
+	want := map[string]string{
+		"users": "",
+		"pair":  "2 rows, fewer than MinRows=3",
+		"mixed": "only 0 of 3 keys are in every row, fewer than half",
+		"tags":  "element 0 is a str, not an object",
+	}
+	if len(e.Tables) != len(want) {
+		t.Fatalf("got %d decisions, want %d: %+v", len(e.Tables), len(want), e.Tables)
+	}
+	for _, d := range e.Tables {
+		reason, ok := want[d.Path]
+		if !ok {
+			t.Errorf("unexpected decision at %q", d.Path)
+			continue
+		}
+		if d.Tabular != (reason == "") || d.Reason != reason {
+			t.Errorf("%s: tabular=%v reason=%q, want %q", d.Path, d.Tabular, d.Reason, reason)
+		}
+	}
+	if d := e.Tables[0]; len(d.Columns) != 2 || d.Rows != 3 {
+		t.Errorf("users: got %d rows, columns %v", d.Rows, d.Columns)
+	}
+
+	report := e.String()
+	if !strings.Contains(report, "users: @tab, 3 rows x 2 cols") || !strings.Contains(report, "keys: ") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestEmitExplain_TypedTable(t *testing.T) {
+	opts := DefaultLooseCanonOpts()
+	opts.Types = makeHikeSchema()
+	v := List(
+		makeHikeValue(1, "Ridge", 12.5, 800, "ana", true),
+		makeHikeValue(2, "Lake", 8, 200, "bo", false),
+		makeHikeValue(3, "Summit", 15, 1200, "cy", true),
+	)
+
+	out, e := EmitExplain(v, opts)
+	if !strings.HasPrefix(out, "@tab Hike ") {
+		t.Fatalf("expected typed table, got:\n%s", out)
+	}
+	d := e.Tables[0]
+	if !d.Tabular || d.Type != "Hike" || strings.Join(d.Columns, " ") != "i n d e c s" {
+		t.Errorf("decision = %+v", d)
+	}
+	if !strings.Contains(e.String(), "root: @tab Hike, 3 rows x 6 cols") {
+		t.Errorf("unexpected report:\n%s", e.String())
+	}
+}
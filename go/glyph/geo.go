@@ -0,0 +1,172 @@
+package glyph
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Geo Coordinate Codec
+// ============================================================
+//
+// Location lists are expensive in tokens: every coordinate is a long float.
+// A field declared @codec(geo) holding a [lon lat] point or a polyline (a list
+// of points) is emitted as a geo literal instead. Coordinates are scaled to
+// fixed-point integers and polyline points after the first are written as
+// deltas from the previous point:
+//
+//	[[-0.12345 51.50735] [-0.12333 51.50732]]  →  geo5"-12345 5150735;12 -3"
+//	[-0.12345 51.50735]                        →  geo5"-12345 5150735"
+//
+// The digit after "geo" is the number of decimal places kept: @codec(geo)
+// uses 5 (about 1 m), @codec(geo0) … @codec(geo9) choose explicitly.
+// Coordinates are rounded to that precision, so the codec is lossy beyond it.
+// A polyline body always contains ';' (a single-point polyline is written
+// "x y;"), which distinguishes it from a point. Geo literals are
+// self-describing: every parser decodes them without a schema, back to
+// float lists.
+
+// defaultGeoPrecision is the precision of @codec(geo).
+const defaultGeoPrecision = 5
+
+// geoCodecPrecision returns the precision selected by a field codec name, and
+// false if the codec is not a geo codec.
+func geoCodecPrecision(codec string) (int, bool) {
+	switch {
+	case codec == "geo":
+		return defaultGeoPrecision, true
+	case len(codec) == 4 && strings.HasPrefix(codec, "geo") && codec[3] >= '0' && codec[3] <= '9':
+		return int(codec[3] - '0'), true
+	}
+	return 0, false
+}
+
+// geoFieldLiteral returns the geo literal for val if fd declares a geo codec
+// and val is a point or polyline.
+func geoFieldLiteral(val *GValue, fd *FieldDef) (string, bool) {
+	if fd == nil || fd.Codec == "" {
+		return "", false
+	}
+	precision, ok := geoCodecPrecision(fd.Codec)
+	if !ok {
+		return "", false
+	}
+	return encodeGeoLiteral(val, precision)
+}
+
+// encodeGeoLiteral encodes a point or polyline as a geo literal. It returns
+// false if v is neither, or a coordinate does not fit at the precision.
+func encodeGeoLiteral(v *GValue, precision int) (string, bool) {
+	v.force()
+	if v == nil || v.typ != TypeList || len(v.listVal) == 0 {
+		return "", false
+	}
+	scale := math.Pow10(precision)
+	prefix := "geo" + strconv.Itoa(precision) + `"`
+
+	if x, y, ok := geoPoint(v, scale); ok {
+		return prefix + strconv.FormatInt(x, 10) + " " + strconv.FormatInt(y, 10) + `"`, true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	var px, py int64
+	for i, pt := range v.listVal {
+		x, y, ok := geoPoint(pt, scale)
+		if !ok {
+			return "", false
+		}
+		if i > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(strconv.FormatInt(x-px, 10))
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.FormatInt(y-py, 10))
+		px, py = x, y
+	}
+	if len(v.listVal) == 1 {
+		sb.WriteByte(';')
+	}
+	sb.WriteByte('"')
+	return sb.String(), true
+}
+
+// geoPoint scales a [lon lat] list of numbers to fixed-point integers.
+func geoPoint(v *GValue, scale float64) (int64, int64, bool) {
+	v.force()
+	if v == nil || v.typ != TypeList || len(v.listVal) != 2 {
+		return 0, 0, false
+	}
+	var xy [2]int64
+	for i, c := range v.listVal {
+		if c == nil || (c.typ != TypeInt && c.typ != TypeFloat) {
+			return 0, 0, false
+		}
+		n, _ := c.Number()
+		scaled := math.Round(n * scale)
+		if math.IsNaN(scaled) || math.Abs(scaled) > 1<<53 {
+			return 0, 0, false
+		}
+		xy[i] = int64(scaled)
+	}
+	return xy[0], xy[1], true
+}
+
+// geoLiteralPrefix returns the geo literal prefix (geo0 … geo9) if s starts
+// with one immediately followed by '"', or "".
+func geoLiteralPrefix(s string) string {
+	if len(s) > 4 && s[4] == '"' && strings.HasPrefix(s, "geo") && s[3] >= '0' && s[3] <= '9' {
+		return s[:4]
+	}
+	return ""
+}
+
+// decodeGeoLiteral decodes the unquoted body of a geo literal with the given
+// prefix into a point or polyline of floats.
+func decodeGeoLiteral(prefix, body string) (*GValue, error) {
+	scale := math.Pow10(int(prefix[3] - '0'))
+	parsePair := func(s string) (int64, int64, error) {
+		fields := strings.Fields(s)
+		if len(fields) != 2 {
+			return 0, 0, fmt.Errorf("invalid geo literal %q: want 2 coordinates, got %d", body, len(fields))
+		}
+		x, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid geo literal %q: bad coordinate %q", body, fields[0])
+		}
+		y, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid geo literal %q: bad coordinate %q", body, fields[1])
+		}
+		return x, y, nil
+	}
+	point := func(x, y int64) *GValue {
+		return List(Float(float64(x)/scale), Float(float64(y)/scale))
+	}
+
+	if !strings.Contains(body, ";") {
+		x, y, err := parsePair(body)
+		if err != nil {
+			return nil, err
+		}
+		return point(x, y), nil
+	}
+
+	parts := strings.Split(body, ";")
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) == "" {
+		parts = parts[:1] // single-point polyline
+	}
+	points := make([]*GValue, 0, len(parts))
+	var x, y int64
+	for _, part := range parts {
+		dx, dy, err := parsePair(part)
+		if err != nil {
+			return nil, err
+		}
+		x, y = x+dx, y+dy
+		points = append(points, point(x, y))
+	}
+	return List(points...), nil
+}
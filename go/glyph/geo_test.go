@@ -0,0 +1,124 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func geoTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := ParseSchema(`@schema{
+		Route:v1 @pack struct{
+			name: str @fid(1)
+			start: list<float> @fid(2) @codec(geo)
+			path: list<list<float>> @fid(3) @codec(geo6)
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func geoRoute() *GValue {
+	return Struct("Route",
+		MapEntry{Key: "name", Value: Str("thames")},
+		MapEntry{Key: "start", Value: List(Float(-0.12345), Float(51.50735))},
+		MapEntry{Key: "path", Value: List(
+			List(Float(-0.123451), Float(51.507351)),
+			List(Float(-0.123331), Float(51.507321)),
+			List(Float(-0.1232), Int(51)),
+		)},
+	)
+}
+
+func TestGeoCodec_Packed(t *testing.T) {
+	schema := geoTestSchema(t)
+	out, err := EmitPacked(geoRoute(), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Route@(thames geo5"-12345 5150735" geo6"-123451 51507351;120 -30;131 -507321")`
+	if out != want {
+		t.Fatalf("got  %s\nwant %s", out, want)
+	}
+
+	back, err := ParsePacked(out, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLoose(back.Get("path")); got != "[[-0.123451 51.507351] [-0.123331 51.507321] [-0.1232 51.0]]" {
+		t.Errorf("path: got %s", got)
+	}
+	if got := CanonicalizeLoose(back.Get("start")); got != "[-0.12345 51.50735]" {
+		t.Errorf("start: got %s", got)
+	}
+}
+
+func TestGeoCodec_TextAndTabular(t *testing.T) {
+	schema := geoTestSchema(t)
+	opts := DefaultEmitOptions()
+	opts.Schema = schema
+	text := EmitWithOptions(geoRoute(), opts)
+	if !strings.Contains(text, `start=geo5"-12345 5150735"`) {
+		t.Fatalf("expected geo literal in %s", text)
+	}
+	result, err := ParseWithOptions(text, ParseOptions{})
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse: %v %v", err, result.Errors)
+	}
+	if got := len(result.Value.Get("path").listVal); got != 3 {
+		t.Errorf("path: got %d points", got)
+	}
+
+	rows := List(geoRoute(), geoRoute(), geoRoute())
+	tab, err := EmitTabular(rows, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tab, `geo6"`) {
+		t.Fatalf("expected geo literal in %s", tab)
+	}
+	parsed, err := NewTabularReaderFromString(tab, schema).ReadAll()
+	if err != nil || len(parsed) != 3 {
+		t.Fatalf("tabular parse: %v\n%s", err, tab)
+	}
+	if got := CanonicalizeLoose(parsed[1].Get("start")); got != "[-0.12345 51.50735]" {
+		t.Errorf("tabular start: got %s", got)
+	}
+}
+
+func TestGeoLiteral_Decode(t *testing.T) {
+	tests := []struct {
+		lit, want string
+	}{
+		{`geo5"-12345 5150735"`, "[-0.12345 51.50735]"},
+		{`geo0"3 4;"`, "[[3.0 4.0]]"},
+		{`geo2"100 200;1 -1;1 -1"`, "[[1.0 2.0] [1.01 1.99] [1.02 1.98]]"},
+	}
+	for _, tt := range tests {
+		v, err := parseLooseValue(tt.lit)
+		if err != nil {
+			t.Errorf("%s: %v", tt.lit, err)
+			continue
+		}
+		if got := CanonicalizeLoose(v); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.lit, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`geo5"1"`, `geo5"1 x"`, `geo5"1 2;3"`} {
+		result, _ := ParseWithOptions(bad, ParseOptions{})
+		if result == nil || !result.HasErrors() {
+			t.Errorf("%s: expected parse error", bad)
+		}
+	}
+
+	// Values that aren't points or polylines are emitted normally.
+	fd := &FieldDef{Name: "p", Codec: "geo"}
+	for _, v := range []*GValue{List(), List(Float(1)), List(Str("a"), Str("b")), Str("x")} {
+		if lit, ok := geoFieldLiteral(v, fd); ok {
+			t.Errorf("%s: unexpected geo literal %s", CanonicalizeLoose(v), lit)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package glyph
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -157,6 +158,9 @@ func TestLexer_Comments(t *testing.T) {
 	if tokens[0].Value != "123" || tokens[1].Value != "456" {
 		t.Errorf("Unexpected token values: %v, %v", tokens[0].Value, tokens[1].Value)
 	}
+	if tokens[1].Pos.Line != 2 || tokens[1].Pos.Column != 1 {
+		t.Errorf("456 at %s, want 2:1", tokens[1].Pos)
+	}
 }
 
 func TestLexer_NullSymbol(t *testing.T) {
@@ -675,3 +679,43 @@ func TestEmit_WithWireKeys(t *testing.T) {
 		t.Errorf("Expected wire keys, got: %s", result)
 	}
 }
+
+func TestGValue_Iterators(t *testing.T) {
+	m := Map(MapEntry{Key: "b", Value: Int(1)}, MapEntry{Key: "a", Value: Int(2)}, MapEntry{Key: "c", Value: Int(3)})
+
+	var keys []string
+	for k, v := range m.Fields() {
+		if k == "c" {
+			break
+		}
+		keys = append(keys, k+"="+Emit(v))
+	}
+	if strings.Join(keys, ",") != "b=1,a=2" {
+		t.Errorf("Fields: %v", keys)
+	}
+
+	n := 0
+	Struct("P", MapEntry{Key: "x", Value: Int(1)}, MapEntry{Key: "y", Value: Int(2)}).Range(func(string, *GValue) bool {
+		n++
+		return true
+	})
+	if n != 2 {
+		t.Errorf("Range over struct visited %d fields", n)
+	}
+
+	sum := int64(0)
+	for i, item := range List(Int(10), Int(20)).Items() {
+		sum += int64(i) * item.intVal
+	}
+	if sum != 20 {
+		t.Errorf("Items: sum = %d", sum)
+	}
+
+	for range Int(1).Fields() {
+		t.Error("scalar should yield no fields")
+	}
+	var nilVal *GValue
+	for range nilVal.Items() {
+		t.Error("nil should yield no items")
+	}
+}
@@ -0,0 +1,197 @@
+// Package glyphtest runs GLYPH's JSON corpus checks and size reports against
+// a directory of payloads, so downstream repos can prove the codec on their
+// own data:
+//
+//	func TestPayloads(t *testing.T) {
+//		c, err := glyphtest.LoadCorpus("testdata/payloads")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		c.Run(t)
+//	}
+//
+// Measure produces the same CSV and markdown reports as cmd/bench.
+package glyphtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// Case is one JSON payload of a corpus.
+type Case struct {
+	Name string
+	File string // Path relative to the corpus directory
+	JSON []byte
+}
+
+// Corpus is a set of JSON payloads loaded from a directory.
+type Corpus struct {
+	Dir     string
+	Version string // From manifest.json, else the directory name
+	Cases   []Case
+}
+
+type manifest struct {
+	Version string `json:"version"`
+	Cases   []struct {
+		Name string `json:"name"`
+		File string `json:"file"`
+	} `json:"cases"`
+}
+
+// LoadCorpus reads the payloads in dir. If dir holds a manifest.json in the
+// format of glyph/testdata/loose_json, its cases are loaded in order;
+// otherwise every *.json file in dir is a case named after the file, in
+// name order.
+func LoadCorpus(dir string) (*Corpus, error) {
+	c := &Corpus{Dir: dir, Version: filepath.Base(dir)}
+
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	switch {
+	case err == nil:
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("glyphtest: %s/manifest.json: %w", dir, err)
+		}
+		if m.Version != "" {
+			c.Version = m.Version
+		}
+		for _, mc := range m.Cases {
+			if err := c.add(mc.Name, mc.File); err != nil {
+				return nil, err
+			}
+		}
+	case os.IsNotExist(err):
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			name := strings.TrimSuffix(filepath.Base(f), ".json")
+			if err := c.add(name, filepath.Base(f)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("glyphtest: %w", err)
+	}
+
+	if len(c.Cases) == 0 {
+		return nil, fmt.Errorf("glyphtest: no JSON cases in %s", dir)
+	}
+	return c, nil
+}
+
+func (c *Corpus) add(name, file string) error {
+	data, err := os.ReadFile(filepath.Join(c.Dir, file))
+	if err != nil {
+		return fmt.Errorf("glyphtest: case %s: %w", name, err)
+	}
+	c.Cases = append(c.Cases, Case{Name: name, File: file, JSON: data})
+	return nil
+}
+
+// Mode is one loose emission setting the cross-mode check round-trips
+// through. A mode with UseCompactKeys and no Schema gets a key dictionary
+// built from each case.
+type Mode struct {
+	Name string
+	Opts glyph.LooseCanonOpts
+}
+
+// Modes are the emission settings Run checks, in order.
+var Modes = []Mode{
+	{Name: "default", Opts: glyph.DefaultLooseCanonOpts()},
+	{Name: "pretty", Opts: glyph.PrettyLooseCanonOpts()},
+	{Name: "no-tabular", Opts: glyph.NoTabularLooseCanonOpts()},
+	{Name: "compact-keys", Opts: glyph.SchemaLooseCanonOpts(nil)},
+}
+
+// Run checks every case as a subtest:
+//
+//   - roundtrip: JSON -> GLYPH value -> JSON is structurally equal
+//   - canonical: canonicalization is deterministic and a fixed point of
+//     parse then re-canonicalize
+//   - mode/<name>: the output of each of Modes parses back to an equal value
+func (c *Corpus) Run(t *testing.T) {
+	t.Helper()
+	for _, tc := range c.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := glyph.FromJSONLoose(tc.JSON)
+			if err != nil {
+				t.Fatalf("FromJSONLoose: %v", err)
+			}
+			t.Run("roundtrip", func(t *testing.T) { checkRoundTrip(t, tc, v) })
+			t.Run("canonical", func(t *testing.T) { checkCanonical(t, tc, v) })
+			for _, m := range Modes {
+				t.Run("mode/"+m.Name, func(t *testing.T) { checkMode(t, v, m) })
+			}
+		})
+	}
+}
+
+func checkRoundTrip(t *testing.T, tc Case, v *glyph.GValue) {
+	out, err := glyph.ToJSONLoose(v)
+	if err != nil {
+		t.Fatalf("ToJSONLoose: %v", err)
+	}
+	equal, err := glyph.JSONEqual(tc.JSON, out)
+	if err != nil {
+		t.Fatalf("JSONEqual: %v", err)
+	}
+	if !equal {
+		t.Errorf("round-trip changed the payload\nwant: %s\ngot:  %s", tc.JSON, out)
+	}
+}
+
+func checkCanonical(t *testing.T, tc Case, v *glyph.GValue) {
+	canon := glyph.CanonicalizeLoose(v)
+
+	again, err := glyph.FromJSONLoose(tc.JSON)
+	if err != nil {
+		t.Fatalf("FromJSONLoose: %v", err)
+	}
+	if canon2 := glyph.CanonicalizeLoose(again); canon2 != canon {
+		t.Fatalf("canonicalization not deterministic\nfirst:  %s\nsecond: %s", canon, canon2)
+	}
+
+	parsed, err := glyph.ParseLoose(canon, nil)
+	if err != nil {
+		t.Fatalf("ParseLoose(canonical): %v\n%s", err, canon)
+	}
+	if canon2 := glyph.CanonicalizeLoose(parsed); canon2 != canon {
+		t.Errorf("canonical form is not a fixed point\nfirst:  %s\nsecond: %s", canon, canon2)
+	}
+}
+
+func checkMode(t *testing.T, v *glyph.GValue, m Mode) {
+	opts := m.Opts
+	if opts.UseCompactKeys && opts.Schema == nil {
+		opts.Schema = glyph.NewSchemaContext(glyph.BuildKeyDictFromValue(v))
+	}
+
+	var out string
+	if opts.Schema != nil {
+		out = glyph.CanonicalizeLooseWithSchema(v, opts)
+	} else {
+		out = glyph.CanonicalizeLooseWithOpts(v, opts)
+	}
+
+	got, _, err := glyph.ParseLoosePayload(out, nil)
+	if err != nil {
+		t.Fatalf("ParseLoosePayload: %v\n%s", err, out)
+	}
+	if !glyph.EqualLoose(got, v) {
+		t.Errorf("value changed through %s mode\noutput: %s\ngot:    %s",
+			m.Name, out, glyph.CanonicalizeLoose(got))
+	}
+}
@@ -0,0 +1,111 @@
+package glyphtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorpus_Repo(t *testing.T) {
+	c, err := LoadCorpus(filepath.Join("..", "testdata", "loose_json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != "v2.2.1-loose" || len(c.Cases) < 50 {
+		t.Fatalf("loaded %s with %d cases", c.Version, len(c.Cases))
+	}
+	c.Run(t)
+}
+
+func TestLoadCorpus_Directory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"users":[{"id":1},{"id":2},{"id":3}]}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`[1,2.5,"x",null]`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0o644)
+
+	c, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Cases) != 2 || c.Cases[0].Name != "a" || c.Cases[1].Name != "b" {
+		t.Fatalf("cases = %+v", c.Cases)
+	}
+	if c.Version != filepath.Base(dir) {
+		t.Errorf("Version = %q", c.Version)
+	}
+	c.Run(t)
+}
+
+func TestLoadCorpus_Errors(t *testing.T) {
+	if _, err := LoadCorpus(t.TempDir()); err == nil {
+		t.Error("empty directory: expected error")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"cases":[{"name":"gone","file":"gone.json"}]}`), 0o644)
+	if _, err := LoadCorpus(dir); err == nil || !strings.Contains(err.Error(), "gone") {
+		t.Errorf("missing case file: err = %v", err)
+	}
+}
+
+func TestMeasure_Reports(t *testing.T) {
+	c := &Corpus{Version: "test", Cases: []Case{
+		{Name: "rows", JSON: []byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"},{"id":3,"name":"c"}]`)},
+		{Name: "broken", JSON: []byte(`{"a":`)},
+	}}
+	r := c.Measure()
+	if len(r.Results) != 1 || len(r.Errors) != 1 {
+		t.Fatalf("results %d, errors %v", len(r.Results), r.Errors)
+	}
+	res := r.Results[0]
+	if res.GLYPHBytes >= res.JSONBytes || res.BytesSaved != res.JSONBytes-res.GLYPHBytes {
+		t.Errorf("bytes: json %d glyph %d saved %d", res.JSONBytes, res.GLYPHBytes, res.BytesSaved)
+	}
+	if len(res.Baselines) != len(Baselines) || len(r.Formats) != len(Baselines) {
+		t.Fatalf("baselines %v formats %v", res.Baselines, r.Formats)
+	}
+	for i, n := range res.Baselines {
+		if n <= 0 || r.BaselineBytes[i] != n {
+			t.Errorf("%s: case %d total %d", r.Formats[i], n, r.BaselineBytes[i])
+		}
+	}
+
+	var csv bytes.Buffer
+	r.WriteCSV(&csv)
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ",messagepack_bytes,cbor_bytes,gzip_json_bytes") ||
+		!strings.HasPrefix(lines[1], "rows,") {
+		t.Errorf("csv:\n%s", csv.String())
+	}
+
+	r.Date = "2000-01-02"
+	var md bytes.Buffer
+	r.WriteMarkdown(&md)
+	for _, want := range []string{"**Date:** 2000-01-02", "**Corpus:** test (1 cases)", "| MessagePack |", "| rows |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q", want)
+		}
+	}
+}
+
+func TestEncoders_KnownBytes(t *testing.T) {
+	v := map[string]any{"a": []any{1.0, -1.0, 1.5, true, nil}, "b": "hi"}
+	tests := []struct {
+		enc  func(any) ([]byte, error)
+		want []byte
+	}{
+		{encodeMsgpack, []byte{0x82, 0xa1, 'a', 0x95, 0x01, 0xff, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xc3, 0xc0, 0xa1, 'b', 0xa2, 'h', 'i'}},
+		{encodeCBOR, []byte{0xa2, 0x61, 'a', 0x85, 0x01, 0x20, 0xfa, 0x3f, 0xc0, 0, 0, 0xf5, 0xf6, 0x61, 'b', 0x62, 'h', 'i'}},
+	}
+	for i, tt := range tests {
+		got, err := tt.enc(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("encoder %d: got % x, want % x", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package glyphtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Encoder is a baseline format the reports compare GLYPH against. Encode
+// receives the case as decoded by encoding/json (nil, bool, float64, string,
+// []any, map[string]any) and returns its wire bytes.
+//
+// To add a format, append to Baselines before calling Measure.
+type Encoder struct {
+	Name   string
+	Encode func(v any) ([]byte, error)
+}
+
+// Baselines are reported in this order after JSON and GLYPH.
+var Baselines = []Encoder{
+	{Name: "MessagePack", Encode: encodeMsgpack},
+	{Name: "CBOR", Encode: encodeCBOR},
+	{Name: "gzip-JSON", Encode: encodeGzipJSON},
+}
+
+func encodeGzipJSON(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// asInt reports whether f is an integer that JSON can carry exactly. The
+// binary encoders write those as integers, as their libraries do for ints.
+func asInt(f float64) (int64, bool) {
+	if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// sortedKeys returns m's keys in byte order, so the output is deterministic.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ============================================================
+// MessagePack (smallest encoding for each value)
+// ============================================================
+
+func encodeMsgpack(v any) ([]byte, error) {
+	var b []byte
+	var enc func(v any) error
+	enc = func(v any) error {
+		switch x := v.(type) {
+		case nil:
+			b = append(b, 0xc0)
+		case bool:
+			if x {
+				b = append(b, 0xc3)
+			} else {
+				b = append(b, 0xc2)
+			}
+		case float64:
+			if n, ok := asInt(x); ok {
+				b = msgpackInt(b, n)
+			} else {
+				b = append(b, 0xcb)
+				b = binary.BigEndian.AppendUint64(b, math.Float64bits(x))
+			}
+		case string:
+			b = msgpackHeader(b, len(x), 0xa0, 32, 0xd9, 0xda, 0xdb)
+			b = append(b, x...)
+		case []any:
+			b = msgpackHeader(b, len(x), 0x90, 16, 0, 0xdc, 0xdd)
+			for _, item := range x {
+				if err := enc(item); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			b = msgpackHeader(b, len(x), 0x80, 16, 0, 0xde, 0xdf)
+			for _, k := range sortedKeys(x) {
+				if err := enc(k); err != nil {
+					return err
+				}
+				if err := enc(x[k]); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("msgpack: unsupported %T", v)
+		}
+		return nil
+	}
+	err := enc(v)
+	return b, err
+}
+
+// msgpackHeader writes a length header: the fix form below fixMax, then the
+// 8-bit (if the type has one), 16-bit, or 32-bit form.
+func msgpackHeader(b []byte, n int, fix byte, fixMax int, c8, c16, c32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case c8 != 0 && n <= math.MaxUint8:
+		return append(b, c8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, c16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, c32), uint32(n))
+	}
+}
+
+func msgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= 0 && n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= math.MinInt8 && n < 0:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16 && n < 0:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n < 0:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	case n < 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	}
+}
+
+// ============================================================
+// CBOR (RFC 8949 preferred serialization)
+// ============================================================
+
+func encodeCBOR(v any) ([]byte, error) {
+	var b []byte
+	var enc func(v any) error
+	enc = func(v any) error {
+		switch x := v.(type) {
+		case nil:
+			b = append(b, 0xf6)
+		case bool:
+			if x {
+				b = append(b, 0xf5)
+			} else {
+				b = append(b, 0xf4)
+			}
+		case float64:
+			if n, ok := asInt(x); ok {
+				if n >= 0 {
+					b = cborHead(b, 0, uint64(n))
+				} else {
+					b = cborHead(b, 1, uint64(-1-n))
+				}
+			} else {
+				b = cborFloat(b, x)
+			}
+		case string:
+			b = cborHead(b, 3, uint64(len(x)))
+			b = append(b, x...)
+		case []any:
+			b = cborHead(b, 4, uint64(len(x)))
+			for _, item := range x {
+				if err := enc(item); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			b = cborHead(b, 5, uint64(len(x)))
+			for _, k := range sortedKeys(x) {
+				if err := enc(k); err != nil {
+					return err
+				}
+				if err := enc(x[k]); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("cbor: unsupported %T", v)
+		}
+		return nil
+	}
+	err := enc(v)
+	return b, err
+}
+
+func cborHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+// cborFloat writes f as float32 when that is exact, else as float64.
+// (Half precision is skipped; JSON decimals rarely fit it.)
+func cborFloat(b []byte, f float64) []byte {
+	if f32 := float32(f); float64(f32) == f {
+		return binary.BigEndian.AppendUint32(append(b, 0xfa), math.Float32bits(f32))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(f))
+}
@@ -0,0 +1,235 @@
+package glyphtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// Result holds the sizes of one case.
+type Result struct {
+	Name        string
+	JSONBytes   int
+	GLYPHBytes  int
+	BytesSaved  int
+	BytesPct    float64
+	JSONTokens  int
+	GLYPHTokens int
+	TokensSaved int
+	TokensPct   float64
+	Baselines   []int // Bytes per Report.Formats entry; -1 if it failed
+}
+
+// Report compares GLYPH-Loose canonical output against minified JSON and
+// the Baselines for a whole corpus.
+type Report struct {
+	Corpus  string
+	Date    string   // Markdown header date; empty means today
+	Formats []string // Names of the baselines measured, in column order
+	Results []Result
+	Errors  []error // Cases that could not be measured and were skipped
+
+	JSONBytes, GLYPHBytes   int
+	JSONTokens, GLYPHTokens int
+	BaselineBytes           []int // Totals per Formats entry
+}
+
+// Measure sizes every case with CanonicalizeLoose, minified JSON, and each
+// of Baselines, counting tokens with glyph.EstimateTokens.
+func (c *Corpus) Measure() *Report {
+	return c.MeasureWith(nil)
+}
+
+// MeasureWith is Measure counting tokens with tok; nil means
+// glyph.EstimateTokens.
+func (c *Corpus) MeasureWith(tok glyph.Tokenizer) *Report {
+	if tok == nil {
+		tok = glyph.EstimateTokens
+	}
+	r := &Report{Corpus: c.Version, BaselineBytes: make([]int, len(Baselines))}
+	for _, enc := range Baselines {
+		r.Formats = append(r.Formats, enc.Name)
+	}
+
+	for _, tc := range c.Cases {
+		gv, err := glyph.FromJSONLoose(tc.JSON)
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s: parse error: %w", tc.Name, err))
+			continue
+		}
+		glyphStr := glyph.CanonicalizeLoose(gv)
+
+		// Minify JSON for fair comparison
+		var minified any
+		if err := json.Unmarshal(tc.JSON, &minified); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s: JSON unmarshal error: %w", tc.Name, err))
+			continue
+		}
+		jsonMin, _ := json.Marshal(minified)
+
+		res := Result{
+			Name:        tc.Name,
+			JSONBytes:   len(jsonMin),
+			GLYPHBytes:  len(glyphStr),
+			JSONTokens:  tok(string(jsonMin)),
+			GLYPHTokens: tok(glyphStr),
+			Baselines:   make([]int, len(Baselines)),
+		}
+		res.BytesSaved = res.JSONBytes - res.GLYPHBytes
+		res.TokensSaved = res.JSONTokens - res.GLYPHTokens
+		res.BytesPct = percent(res.BytesSaved, res.JSONBytes)
+		res.TokensPct = percent(res.TokensSaved, res.JSONTokens)
+
+		for i, enc := range Baselines {
+			out, err := enc.Encode(minified)
+			if err != nil {
+				r.Errors = append(r.Errors, fmt.Errorf("%s: %s: %w", tc.Name, enc.Name, err))
+				res.Baselines[i] = -1
+				continue
+			}
+			res.Baselines[i] = len(out)
+			r.BaselineBytes[i] += len(out)
+		}
+
+		r.Results = append(r.Results, res)
+		r.JSONBytes += res.JSONBytes
+		r.GLYPHBytes += res.GLYPHBytes
+		r.JSONTokens += res.JSONTokens
+		r.GLYPHTokens += res.GLYPHTokens
+	}
+	return r
+}
+
+func percent(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+// WriteCSV writes one row per case.
+func (r *Report) WriteCSV(w io.Writer) {
+	fmt.Fprint(w, "name,json_bytes,glyph_bytes,bytes_saved,bytes_pct,json_tokens,glyph_tokens,tokens_saved,tokens_pct")
+	for _, name := range r.Formats {
+		fmt.Fprintf(w, ",%s_bytes", columnName(name))
+	}
+	fmt.Fprintln(w)
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%s,%d,%d,%d,%.1f,%d,%d,%d,%.1f",
+			res.Name, res.JSONBytes, res.GLYPHBytes, res.BytesSaved, res.BytesPct,
+			res.JSONTokens, res.GLYPHTokens, res.TokensSaved, res.TokensPct)
+		for _, n := range res.Baselines {
+			fmt.Fprintf(w, ",%d", n)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// columnName turns an encoder name into a CSV column prefix ("gzip-JSON" -> "gzip_json").
+func columnName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
+
+// WriteMarkdown writes the summary, baseline comparison, best and worst
+// cases, methodology, and per-case table.
+func (r *Report) WriteMarkdown(w io.Writer) {
+	date := r.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	totalJSON, totalGLYPH := r.JSONBytes, r.GLYPHBytes
+
+	fmt.Fprintf(w, "# GLYPH Benchmark Results\n\n")
+	fmt.Fprintf(w, "**Date:** %s  \n", date)
+	fmt.Fprintf(w, "**Corpus:** %s (%d cases)  \n", r.Corpus, len(r.Results))
+	fmt.Fprintf(w, "**GLYPH Version:** 0.2.3 (spec 2.2.2-gs1)  \n\n")
+
+	fmt.Fprintf(w, "## Summary\n\n")
+	fmt.Fprintf(w, "| Metric | JSON (minified) | GLYPH-Loose | Savings |\n")
+	fmt.Fprintf(w, "|--------|-----------------|-------------|--------|\n")
+	bytesSaved := totalJSON - totalGLYPH
+	tokensSaved := r.JSONTokens - r.GLYPHTokens
+	fmt.Fprintf(w, "| **Bytes** | %d | %d | %d (%.1f%%) |\n", totalJSON, totalGLYPH, bytesSaved, percent(bytesSaved, totalJSON))
+	fmt.Fprintf(w, "| **Tokens** (est.) | ~%d | ~%d | ~%d (%.1f%%) |\n\n", r.JSONTokens, r.GLYPHTokens, tokensSaved, percent(tokensSaved, r.JSONTokens))
+
+	fmt.Fprintf(w, "## Binary Baselines\n\n")
+	fmt.Fprintf(w, "Binary formats are not readable by a model, so only bytes are compared.\n\n")
+	fmt.Fprintf(w, "| Format | Bytes | vs JSON | GLYPH vs format |\n")
+	fmt.Fprintf(w, "|--------|-------|---------|-----------------|\n")
+	for i, name := range r.Formats {
+		n := r.BaselineBytes[i]
+		fmt.Fprintf(w, "| %s | %d | %.1f%% | %+.1f%% |\n", name, n,
+			percent(totalJSON-n, totalJSON), percent(totalGLYPH-n, n))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "## Analysis\n\n")
+
+	// Find best/worst cases
+	sorted := make([]Result, len(r.Results))
+	copy(sorted, r.Results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].BytesPct > sorted[j].BytesPct
+	})
+
+	fmt.Fprintf(w, "### Top 5 Space Savings (by bytes)\n\n")
+	fmt.Fprintf(w, "| Case | JSON | GLYPH | Saved |\n")
+	fmt.Fprintf(w, "|------|------|-------|-------|\n")
+	for _, res := range sorted[:min(5, len(sorted))] {
+		fmt.Fprintf(w, "| %s | %d | %d | %.1f%% |\n", res.Name, res.JSONBytes, res.GLYPHBytes, res.BytesPct)
+	}
+
+	fmt.Fprintf(w, "\n### Cases Where JSON is Smaller\n\n")
+	var worse []Result
+	for _, res := range r.Results {
+		if res.BytesSaved < 0 {
+			worse = append(worse, res)
+		}
+	}
+	if len(worse) == 0 {
+		fmt.Fprintf(w, "_None - GLYPH is smaller or equal in all cases._\n\n")
+	} else {
+		fmt.Fprintf(w, "| Case | JSON | GLYPH | Overhead |\n")
+		fmt.Fprintf(w, "|------|------|-------|----------|\n")
+		for _, res := range worse {
+			fmt.Fprintf(w, "| %s | %d | %d | +%d bytes |\n", res.Name, res.JSONBytes, res.GLYPHBytes, -res.BytesSaved)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "## Methodology\n\n")
+	fmt.Fprintf(w, "- **JSON:** Minified (no whitespace), using Go's `json.Marshal`\n")
+	fmt.Fprintf(w, "- **GLYPH:** Canonical GLYPH-Loose format via `glyph.CanonicalizeLoose`\n")
+	fmt.Fprintf(w, "- **Tokens:** Estimated using cl100k_base-like heuristics (~4 chars/token for words, punctuation as separate tokens)\n")
+	fmt.Fprintf(w, "- **MessagePack / CBOR:** Smallest encoding of each value, map keys sorted, integral numbers as integers\n")
+	fmt.Fprintf(w, "- **gzip-JSON:** Minified JSON compressed with gzip at best compression\n\n")
+
+	fmt.Fprintf(w, "## Detailed Results\n\n")
+	fmt.Fprintf(w, "| Case | JSON Bytes | GLYPH Bytes | Bytes %% | JSON Tok | GLYPH Tok | Tok %% |\n")
+	fmt.Fprintf(w, "|------|------------|-------------|---------|----------|-----------|-------|\n")
+	for _, res := range r.Results {
+		sign := ""
+		if res.BytesPct > 0 {
+			sign = "+"
+		}
+		tokSign := ""
+		if res.TokensPct > 0 {
+			tokSign = "+"
+		}
+		fmt.Fprintf(w, "| %s | %d | %d | %s%.1f%% | %d | %d | %s%.1f%% |\n",
+			truncateName(res.Name, 25), res.JSONBytes, res.GLYPHBytes, sign, res.BytesPct,
+			res.JSONTokens, res.GLYPHTokens, tokSign, res.TokensPct)
+	}
+}
+
+func truncateName(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
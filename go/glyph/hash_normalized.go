@@ -0,0 +1,46 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ============================================================
+// Format-Insensitive Hashing
+// ============================================================
+//
+// A .glyph file edited by hand gets reindented, rewrapped and commented,
+// and a file written by a program may be compact, pretty, or use wire keys.
+// None of that changes the value. HashNormalized hashes the value: it
+// parses the text and hashes one fixed emission of it, so every layout of a
+// value shares a digest, and a digest changes only when the value does.
+//
+// Unlike FingerprintLoose of the parsed value, the normalized form keeps
+// struct type names and sum tags, so Point{x=1} and {x=1} hash apart.
+
+// normalizedEmitOptions is the emission HashNormalized hashes: compact,
+// fields sorted, full field names.
+var normalizedEmitOptions = EmitOptions{Compact: true, SortFields: true}
+
+// HashNormalized returns the SHA-256 hex digest of the value GLYPH-T text
+// holds, independent of whitespace, comments, field order, layout (pretty
+// or compact) and quoting. With a schema, wire keys are read as the
+// field names they stand for. Text that does not parse cleanly is an error;
+// it is not repaired.
+func HashNormalized(text string, schema *Schema) (string, error) {
+	result, err := ParseWithOptions(text, ParseOptions{Schema: schema})
+	if err != nil {
+		return "", err
+	}
+	if result.HasErrors() {
+		return "", &result.Errors[0]
+	}
+	return HashNormalizedValue(result.Value), nil
+}
+
+// HashNormalizedValue returns the digest HashNormalized gives any text
+// holding v.
+func HashNormalizedValue(v *GValue) string {
+	sum := sha256.Sum256([]byte(EmitWithOptions(v, normalizedEmitOptions)))
+	return hex.EncodeToString(sum[:])
+}
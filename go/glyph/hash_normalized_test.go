@@ -0,0 +1,46 @@
+package glyph
+
+import "testing"
+
+func TestHashNormalized(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		Doc struct{
+			title: str @k(ti)
+			tags: list<str> [optional] @k(tg)
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := Struct("Doc", MapEntry{Key: "title", Value: Str("hi there")}, MapEntry{Key: "tags", Value: List(Str("a"), Str("b"))})
+	want := HashNormalizedValue(v)
+
+	compact := CompactEmitOptions()
+	compact.Schema = schema
+	texts := []string{
+		Emit(v),
+		EmitWithOptions(v, compact), // wire keys
+		EmitWithOptions(v, EmitOptions{Pretty: true, Indent: "    ", SortFields: true}),
+		"// edited by hand\nDoc{\n  title = \"hi there\"\n  tags = [a\n          b]\n}\n",
+	}
+	for _, text := range texts {
+		got, err := HashNormalized(text, schema)
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+		} else if got != want {
+			t.Errorf("%q hashes differently", text)
+		}
+	}
+
+	for _, text := range []string{
+		`Doc{title="hi  there" tags=[a b]}`, // a different value
+		`{title="hi there" tags=[a b]}`,     // a map, not a Doc
+	} {
+		if got, _ := HashNormalized(text, schema); got == want {
+			t.Errorf("%q hashes like the original", text)
+		}
+	}
+	if _, err := HashNormalized(`Doc{title="hi`, schema); err == nil {
+		t.Error("unterminated text hashed")
+	}
+}
@@ -742,6 +742,9 @@ func (p *IncrementalParser) parseRef() int {
 
 	// Bare ref: ^prefix:value — consume isRefChar bytes.
 	j := p.pos + 1 // skip ^
+	if j < len(p.buffer) && p.buffer[j] == '#' {
+		j++ // content-hash ref: ^#hash
+	}
 	for j < len(p.buffer) && isRefChar(p.buffer[j]) {
 		j++
 	}
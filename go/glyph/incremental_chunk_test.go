@@ -29,7 +29,7 @@ func incrValueRepr(v *GValue) string {
 	case TypeStr:
 		return fmt.Sprintf("s:%q", v.strVal)
 	case TypeID:
-		return fmt.Sprintf("r:%s:%s", v.idVal.Prefix, v.idVal.Value)
+		return fmt.Sprintf("r:%s:%s", v.idVal().Prefix, v.idVal().Value)
 	default:
 		return v.Type().String()
 	}
@@ -0,0 +1,337 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Path Indexes
+// ============================================================
+//
+// Lazy parsing still scans every container on the way to a value, so the
+// n-th record of a large archive costs a scan of the n-1 before it. An Index
+// records where the values of a GLYPH-Loose text lie, by path, down to a
+// given depth: a reader seeks straight to the value, or to its nearest
+// indexed ancestor and scans only that.
+//
+// An index is kept next to its file as a sidecar (path + IndexSuffix),
+// itself a GLYPH-Loose document. OpenMapped picks it up and EmitSubtree
+// takes it directly. Offsets are byte offsets into the whole file, headers
+// included, so an index is only good for the exact text it was built from.
+// A sidecar records the size and modification time of its file, and
+// OpenMapped ignores one that does not match; rebuild the index whenever the
+// file is written.
+//
+// Paths are keyed with every map key as ["key"] and every list item as [N],
+// so .users.alice and ["users"]["alice"] find the same entry. Tables (@tab)
+// are indexed as a whole, not by row.
+
+// IndexSuffix is appended to a file's path to name its sidecar index.
+const IndexSuffix = ".gidx"
+
+// IndexSpan is the byte range of one value's text.
+type IndexSpan struct {
+	Offset int
+	Length int
+}
+
+// Index maps paths of a GLYPH-Loose text to the spans of their values.
+type Index struct {
+	Size    int   // Length of the indexed text
+	ModTime int64 // Of the indexed file, in Unix nanoseconds (0: not a file)
+	Depth   int   // Levels indexed below the root
+	Spans   map[string]IndexSpan
+}
+
+// BuildIndex emits v as no-tabular canonical GLYPH-Loose and indexes the
+// text to depth levels.
+func BuildIndex(v *GValue, depth int) (string, *Index, error) {
+	text := CanonicalizeLooseNoTabular(v)
+	idx, err := IndexText(text, depth)
+	if err != nil {
+		return "", nil, err
+	}
+	return text, idx, nil
+}
+
+// BuildIndexFile indexes the GLYPH-Loose file at path to depth levels and
+// writes the sidecar next to it.
+func BuildIndexFile(path string, depth int) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := IndexText(string(data), depth)
+	if err != nil {
+		return nil, fmt.Errorf("glyph: %s: %w", path, err)
+	}
+	idx.ModTime = info.ModTime().UnixNano()
+	if err := os.WriteFile(path+IndexSuffix, []byte(idx.Encode()), 0o644); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// IndexText indexes a GLYPH-Loose text, which may start with version and
+// @doc headers, to depth levels below its root.
+func IndexText(text string, depth int) (*Index, error) {
+	_, _, body, err := splitDocHeaders(text)
+	if err != nil {
+		return nil, err
+	}
+	off := len(text) - len(body)
+	trimmed := strings.TrimLeft(body, " \t\r\n")
+	off += len(body) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, " \t\r\n")
+
+	idx := &Index{Size: len(text), Depth: depth, Spans: make(map[string]IndexSpan)}
+	idx.Spans[""] = IndexSpan{Offset: off, Length: len(trimmed)}
+	if err := idx.add(text, nil, idx.Spans[""], depth); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// add records the children of the container at span, and theirs down to
+// depth levels.
+func (idx *Index) add(text string, path []PathSeg, span IndexSpan, depth int) error {
+	if depth <= 0 {
+		return nil
+	}
+	return eachLooseChild(text, span, func(seg PathSeg, child IndexSpan) (bool, error) {
+		p := append(path[:len(path):len(path)], seg)
+		idx.Spans[indexKey(p)] = child
+		return true, idx.add(text, p, child, depth-1)
+	})
+}
+
+// Lookup returns the span of the value at path, if it is indexed.
+func (idx *Index) Lookup(path string) (IndexSpan, bool) {
+	span, ok := idx.Spans[indexKey(parsePathToSegs(path))]
+	return span, ok
+}
+
+// locate returns the span of the value at path in text: the indexed span,
+// or a scan from the nearest indexed ancestor.
+func (idx *Index) locate(text string, path []PathSeg) (IndexSpan, error) {
+	if len(text) != idx.Size {
+		return IndexSpan{}, fmt.Errorf("index is for %d bytes of text, not %d", idx.Size, len(text))
+	}
+	for _, seg := range path {
+		if seg.Kind == PathSegField && seg.Field == "" {
+			return IndexSpan{}, fmt.Errorf("unresolved FID #%d in path", seg.FID)
+		}
+	}
+	n := len(path)
+	span, ok := idx.Spans[indexKey(path)]
+	for !ok {
+		n--
+		span, ok = idx.Spans[indexKey(path[:n])]
+	}
+	for _, want := range path[n:] {
+		found := false
+		err := eachLooseChild(text, span, func(seg PathSeg, child IndexSpan) (bool, error) {
+			if segKey(seg) == segKey(want) {
+				span, found = child, true
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			return IndexSpan{}, err
+		}
+		if !found {
+			return IndexSpan{}, fmt.Errorf("path not found: %s", pathSegsStr(path))
+		}
+	}
+	return span, nil
+}
+
+// EmitSubtree returns the text of the value at path in the GLYPH-Loose text
+// idx was built from, as written there. Only the part below the nearest
+// indexed ancestor of path is scanned.
+func EmitSubtree(text string, idx *Index, path string) (string, error) {
+	span, err := idx.locate(text, parsePathToSegs(path))
+	if err != nil {
+		return "", err
+	}
+	return text[span.Offset : span.Offset+span.Length], nil
+}
+
+// indexKey returns the key of path in Index.Spans.
+func indexKey(path []PathSeg) string {
+	var b strings.Builder
+	for _, seg := range path {
+		b.WriteString(segKey(seg))
+	}
+	return b.String()
+}
+
+// segKey writes field and map key segments alike, since loose text does not
+// tell them apart.
+func segKey(seg PathSeg) string {
+	if seg.Kind == PathSegField {
+		return MapKeySeg(seg.Field).String()
+	}
+	return seg.String()
+}
+
+// eachLooseChild calls fn with the key or position of each child of the
+// map or list at span in text, and the child's span, until fn returns
+// false. Omitted entries are skipped; anything else has no children.
+func eachLooseChild(text string, span IndexSpan, fn func(PathSeg, IndexSpan) (bool, error)) error {
+	s := text[span.Offset : span.Offset+span.Length]
+	isMap := strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+	if !isMap && !(strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]")) {
+		return nil
+	}
+	base := span.Offset + 1
+	inner := s[1 : len(s)-1]
+	pos := func(rest string) int { return base + len(inner) - len(rest) }
+
+	rest := strings.TrimLeft(inner, " \t\r\n")
+	for i := 0; rest != ""; {
+		if _, after, ok, err := cutOmission(rest); ok || err != nil {
+			if err != nil {
+				return err
+			}
+			rest = strings.TrimLeft(after, " \t\r\n")
+			continue
+		}
+
+		var seg PathSeg
+		if isMap {
+			eq := findUnnestedChar(rest, '=')
+			if eq == -1 {
+				return fmt.Errorf("missing '=' in map entry at offset %d", pos(rest))
+			}
+			key := strings.TrimSpace(rest[:eq])
+			if strings.HasPrefix(key, `"`) {
+				var err error
+				if key, err = unquoteString(key); err != nil {
+					return err
+				}
+			}
+			seg = MapKeySeg(key)
+			rest = strings.TrimLeft(rest[eq+1:], " ")
+			if _, after, ok, err := cutOmission(rest); ok || err != nil {
+				if err != nil {
+					return err
+				}
+				rest = strings.TrimLeft(after, " \t\r\n")
+				continue
+			}
+		} else {
+			seg = ListIdxSeg(i)
+			i++
+		}
+
+		end := findValueEnd(rest)
+		child := IndexSpan{Offset: pos(rest), Length: len(strings.TrimRight(rest[:end], " \t\r\n"))}
+		if more, err := fn(seg, child); !more || err != nil {
+			return err
+		}
+		rest = strings.TrimLeft(rest[end:], " \t\r\n")
+	}
+	return nil
+}
+
+// ============================================================
+// Sidecar Encoding
+// ============================================================
+
+// Encode returns the sidecar form of idx: a GLYPH-Loose map holding the
+// size, the modification time if known, the depth, and a table of spans in
+// text order.
+func (idx *Index) Encode() string {
+	paths := make([]string, 0, len(idx.Spans))
+	for p := range idx.Spans {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := idx.Spans[paths[i]], idx.Spans[paths[j]]
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Length > b.Length
+	})
+	rows := make([]*GValue, len(paths))
+	for i, p := range paths {
+		rows[i] = Map(
+			MapEntry{Key: "len", Value: Int(int64(idx.Spans[p].Length))},
+			MapEntry{Key: "off", Value: Int(int64(idx.Spans[p].Offset))},
+			MapEntry{Key: "path", Value: Str(p)},
+		)
+	}
+	v := Map(
+		MapEntry{Key: "depth", Value: Int(int64(idx.Depth))},
+		MapEntry{Key: "size", Value: Int(int64(idx.Size))},
+		MapEntry{Key: "spans", Value: List(rows...)},
+	)
+	if idx.ModTime != 0 {
+		v.Set("mtime", Int(idx.ModTime))
+	}
+	return CanonicalizeLoose(v) + "\n"
+}
+
+// DecodeIndex parses the sidecar form written by Encode.
+func DecodeIndex(s string) (*Index, error) {
+	v, err := ParseLoose(strings.TrimSpace(s), nil)
+	if err != nil {
+		return nil, fmt.Errorf("glyph: index: %w", err)
+	}
+	if v.Type() != TypeMap {
+		return nil, fmt.Errorf("glyph: index: not a map")
+	}
+	size, err1 := v.Get("size").AsInt()
+	depth, err2 := v.Get("depth").AsInt()
+	spans, err3 := v.Get("spans").AsList()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("glyph: index: %w", err)
+	}
+	idx := &Index{Size: int(size), Depth: int(depth), Spans: make(map[string]IndexSpan, len(spans))}
+	if m := v.Get("mtime"); m != nil {
+		if idx.ModTime, err = m.AsInt(); err != nil {
+			return nil, fmt.Errorf("glyph: index: %w", err)
+		}
+	}
+	for _, row := range spans {
+		p, err1 := row.Get("path").AsStr()
+		off, err2 := row.Get("off").AsInt()
+		n, err3 := row.Get("len").AsInt()
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return nil, fmt.Errorf("glyph: index: %w", err)
+		}
+		if off < 0 || n < 0 || off+n > size {
+			return nil, fmt.Errorf("glyph: index: span %q out of range", p)
+		}
+		idx.Spans[p] = IndexSpan{Offset: int(off), Length: int(n)}
+	}
+	if _, ok := idx.Spans[""]; !ok {
+		return nil, fmt.Errorf("glyph: index: no root span")
+	}
+	return idx, nil
+}
+
+// ReadIndexFile reads the sidecar index of the file at path.
+func ReadIndexFile(path string) (*Index, error) {
+	data, err := os.ReadFile(path + IndexSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeIndex(string(data))
+}
@@ -0,0 +1,163 @@
+package glyph
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexText(t *testing.T) {
+	text := "@glyph 2.6\n{a=[1 {x=\"a b\"} @omitted n=2 3] \"k k\"=@omitted n=1 m={y=2}}\n"
+	idx, err := IndexText(text, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"":           `{a=[1 {x="a b"} @omitted n=2 3] "k k"=@omitted n=1 m={y=2}}`,
+		`["a"]`:      `[1 {x="a b"} @omitted n=2 3]`,
+		`["a"][0]`:   `1`,
+		`["a"][1]`:   `{x="a b"}`,
+		`["a"][2]`:   `3`,
+		`["m"]`:      `{y=2}`,
+		`["m"]["y"]`: `2`,
+	}
+	for path, sub := range want {
+		span, ok := idx.Spans[path]
+		if !ok {
+			t.Errorf("%s not indexed", path)
+			continue
+		}
+		if got := text[span.Offset : span.Offset+span.Length]; got != sub {
+			t.Errorf("%s = %q, want %q", path, got, sub)
+		}
+	}
+	if len(idx.Spans) != len(want) {
+		t.Errorf("indexed %d paths, want %d", len(idx.Spans), len(want))
+	}
+}
+
+func TestEmitSubtree(t *testing.T) {
+	v := Map(
+		MapEntry{Key: "users", Value: Map(
+			MapEntry{Key: "alice", Value: Map(MapEntry{Key: "roles", Value: List(Str("admin"), Str("dev"))})},
+			MapEntry{Key: "bob", Value: Map(MapEntry{Key: "roles", Value: List()})},
+		)},
+	)
+	text, idx, err := BuildIndex(v, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Lookup(".users.alice"); ok {
+		t.Error("indexed below depth")
+	}
+	for path, want := range map[string]string{
+		".users":                "{alice={roles=[admin dev]} bob={roles=[]}}",
+		`["users"].alice`:       "{roles=[admin dev]}",
+		".users.alice.roles[1]": "dev",
+	} {
+		got, err := EmitSubtree(text, idx, path)
+		if err != nil || got != want {
+			t.Errorf("EmitSubtree(%s) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := EmitSubtree(text, idx, ".users.carol"); err == nil {
+		t.Error("missing path found")
+	}
+	if _, err := EmitSubtree(text+" ", idx, ".users"); err == nil {
+		t.Error("stale index used")
+	}
+}
+
+func TestIndexEncode(t *testing.T) {
+	_, idx, err := BuildIndex(Map(MapEntry{Key: "a", Value: List(Int(1), Int(2), Int(3))}), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := idx.Encode()
+	if !strings.Contains(enc, "@tab") {
+		t.Errorf("spans not tabular: %s", enc)
+	}
+	back, err := DecodeIndex(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Size != idx.Size || back.Depth != 2 || len(back.Spans) != len(idx.Spans) {
+		t.Fatalf("decoded %+v, want %+v", back, idx)
+	}
+	for p, span := range idx.Spans {
+		if back.Spans[p] != span {
+			t.Errorf("%s = %+v, want %+v", p, back.Spans[p], span)
+		}
+	}
+	if _, err := DecodeIndex("{size=1}"); err == nil {
+		t.Error("incomplete index decoded")
+	}
+}
+
+func TestOpenMapped_Index(t *testing.T) {
+	path := writeArchive(t, 500)
+	if _, err := BuildIndexFile(path, 2); err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenMappedWithThreshold(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if d.index == nil {
+		t.Fatal("sidecar not used")
+	}
+
+	v, err := d.Get(".records.r00321.tags[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := v.AsStr(); s != "b" {
+		t.Errorf("tags[1] = %s", Emit(v))
+	}
+	if !d.Root().IsLazy() {
+		t.Error("root parsed despite index")
+	}
+	if _, err := d.Get(".records.nope"); err == nil {
+		t.Error("missing key read")
+	}
+	if err := d.UseIndex(&Index{Size: 1}); err == nil {
+		t.Error("index for another file accepted")
+	}
+}
+
+func TestOpenMapped_StaleIndex(t *testing.T) {
+	path := writeArchive(t, 50)
+	if _, err := BuildIndexFile(path, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same length, new content and time: the sidecar no longer applies.
+	data, _ := os.ReadFile(path)
+	data = []byte(strings.Replace(string(data), "r00001", "r0000z", 1))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	d, err := OpenMappedWithThreshold(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if d.index != nil {
+		t.Fatal("stale sidecar used")
+	}
+	if _, err := d.Get(".records.r0000z.id"); err != nil {
+		t.Error(err)
+	}
+	idx, err := ReadIndexFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UseIndex(idx); err == nil {
+		t.Error("index for an older file accepted")
+	}
+}
@@ -0,0 +1,275 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ============================================================
+// Integrity Mode
+// ============================================================
+//
+// An emitter that writes something its own parser cannot read back fails
+// silently: the text looks fine until a consumer chokes on it. Setting Verify
+// on EmitOptions, LooseCanonOpts, PackedOptions, or TabularOptions re-parses
+// every emission and compares the result with the input under EqualLoose.
+// A mismatch is reported as an *IntegrityError naming the first differing
+// path. Functions that return an error return it; the string-only emitters
+// (EmitWithOptions, CanonicalizeLooseWithOpts, CanonicalizeLooseWithSchema)
+// panic with it instead, so run CI with Verify on and production without.
+//
+// Verification doubles emission cost. The comparison is against the value
+// actually emitted, i.e. after Sparse or Summarizer rewriting. For packed and
+// tabular output, struct fields holding null count as absent, since those
+// encodings don't distinguish the two.
+
+// IntegrityError reports an emission that does not parse back to its input.
+type IntegrityError struct {
+	Mode   string // "typed", "loose", "packed" or "tabular"
+	Output string // The emitted text
+	Path   string // First differing path ("" for the root)
+	Want   string // Loose canonical form of the input at Path
+	Got    string // Loose canonical form of the parsed value at Path
+	Err    error  // Parse error, if the output did not parse at all
+}
+
+func (e *IntegrityError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("glyph: %s emission does not parse back: %v\noutput: %s", e.Mode, e.Err, clipText(e.Output, 200))
+	}
+	at := e.Path
+	if at == "" {
+		at = "root"
+	}
+	return fmt.Sprintf("glyph: %s emission does not round-trip at %s: want %s, got %s\noutput: %s",
+		e.Mode, at, clipText(e.Want, 80), clipText(e.Got, 80), clipText(e.Output, 200))
+}
+
+func (e *IntegrityError) Unwrap() error { return e.Err }
+
+// verifyEmission parses out and compares the result with v.
+func verifyEmission(mode string, v *GValue, out string, parse func(string) (*GValue, error)) error {
+	got, err := parse(out)
+	if err != nil {
+		return &IntegrityError{Mode: mode, Output: out, Err: err}
+	}
+	if mode == "packed" || mode == "tabular" {
+		v, got = dropNullFields(v), dropNullFields(got)
+	}
+	if EqualLoose(v, got) {
+		return nil
+	}
+	path, want, have := firstDifference(v, got, "")
+	return &IntegrityError{
+		Mode:   mode,
+		Output: out,
+		Path:   path,
+		Want:   describeLoose(want),
+		Got:    describeLoose(have),
+	}
+}
+
+// verifyLoose checks loose output, which may carry an @schema header.
+func verifyLoose(v *GValue, out string, opts LooseCanonOpts) error {
+	parse := func(s string) (*GValue, error) {
+		return reparseLoose(s, opts)
+	}
+	if opts.Types == nil {
+		return verifyEmission("loose", v, out, parse)
+	}
+
+	// @tab Type rows name columns by wire key and write absent fields as
+	// null, as typed tabular output does. dropNullFields copies v, so its
+	// fields can be renamed in place.
+	want := dropNullFields(v)
+	ResolveTypedColumns(want, opts.Types)
+	return verifyEmission("loose", want, out, func(s string) (*GValue, error) {
+		got, err := parse(s)
+		if err != nil {
+			return nil, err
+		}
+		ResolveTypedColumns(got, opts.Types)
+		return dropNullFields(got), nil
+	})
+}
+
+// reparseLoose parses loose output written with opts, resolving #N keys
+// through its Schema or, with UseCompactKeys, its KeyDict.
+func reparseLoose(s string, opts LooseCanonOpts) (*GValue, error) {
+	registry := NewSchemaRegistry()
+	switch {
+	case opts.Schema != nil:
+		registry.Define(opts.Schema)
+	case opts.UseCompactKeys && len(opts.KeyDict) > 0:
+		registry.Define(NewSchemaContext(opts.KeyDict))
+	}
+	return ParseDocumentWithRegistries(s, registry)
+}
+
+// firstDifference descends into a and b while their shapes agree and returns
+// the path and values of the first subtree that differs.
+func firstDifference(a, b *GValue, path string) (string, *GValue, *GValue) {
+	a.force()
+	b.force()
+	if a == nil || b == nil || a.typ != b.typ {
+		return path, a, b
+	}
+	switch a.typ {
+	case TypeList:
+		if len(a.listVal) != len(b.listVal) {
+			return path, a, b
+		}
+		for i := range a.listVal {
+			if !EqualLoose(a.listVal[i], b.listVal[i]) {
+				return firstDifference(a.listVal[i], b.listVal[i], path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	case TypeMap, TypeStruct:
+		if a.typ == TypeStruct && a.structVal.TypeName != b.structVal.TypeName {
+			return path, a, b
+		}
+		ka, kb := entryKeys(a), entryKeys(b)
+		if fmt.Sprint(ka) != fmt.Sprint(kb) {
+			return path, a, b
+		}
+		for _, k := range ka {
+			if !EqualLoose(a.Get(k), b.Get(k)) {
+				return firstDifference(a.Get(k), b.Get(k), joinPath(path, k))
+			}
+		}
+	case TypeSum:
+		if a.sumVal.Tag == b.sumVal.Tag {
+			return firstDifference(a.sumVal.Value, b.sumVal.Value, path)
+		}
+	}
+	return path, a, b
+}
+
+func entryKeys(v *GValue) []string {
+	v.force()
+	entries := v.mapVal
+	if v.typ == TypeStruct {
+		entries = v.structVal.Fields
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dropNullFields returns v with null-valued struct fields removed.
+func dropNullFields(v *GValue) *GValue {
+	v.force()
+	if v == nil {
+		return v
+	}
+	switch v.typ {
+	case TypeList:
+		out := make([]*GValue, len(v.listVal))
+		for i, elem := range v.listVal {
+			out[i] = dropNullFields(elem)
+		}
+		return List(out...)
+	case TypeMap:
+		out := make([]MapEntry, len(v.mapVal))
+		for i, e := range v.mapVal {
+			out[i] = MapEntry{Key: e.Key, Value: dropNullFields(e.Value)}
+		}
+		return Map(out...)
+	case TypeStruct:
+		var out []MapEntry
+		for _, f := range v.structVal.Fields {
+			if f.Value != nil && !f.Value.IsNull() {
+				out = append(out, MapEntry{Key: f.Key, Value: dropNullFields(f.Value)})
+			}
+		}
+		return Struct(v.structVal.TypeName, out...)
+	case TypeSum:
+		return Sum(v.sumVal.Tag, dropNullFields(v.sumVal.Value))
+	}
+	return v
+}
+
+func describeLoose(v *GValue) string {
+	if v == nil {
+		return "<missing>"
+	}
+	return fmt.Sprintf("%s %s", v.typ, CanonicalizeLooseNoTabular(v))
+}
+
+func clipText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// ============================================================
+// Idempotence
+// ============================================================
+//
+// Canonical text must be a fixed point: parsing it and canonicalizing the
+// result gives the same bytes, or two parties hashing "the same" document
+// disagree after one round trip. The built-in profiles keep this invariant
+// (the tests check each one). Options that lose information the profile
+// relies on break it. For example, the schema-order profile without a root
+// type orders structs by their type names, which loose text does not carry.
+// CheckIdempotent lets code that configures its own options, or adds
+// extension types, check that the invariant still holds.
+
+// IdempotenceError reports canonical text that changes when parsed and
+// canonicalized again.
+type IdempotenceError struct {
+	First  string // Canonical form of the value
+	Second string // Canonical form of First, parsed back
+	Offset int    // First byte at which they differ
+	Err    error  // Error parsing First or canonicalizing its value
+}
+
+func (e *IdempotenceError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("glyph: canonical form does not parse back: %v\noutput: %s", e.Err, clipText(e.First, 200))
+	}
+	from := e.Offset - 20
+	if from < 0 {
+		from = 0
+	}
+	return fmt.Sprintf("glyph: canonicalization is not idempotent at byte %d: %s became %s",
+		e.Offset, clipText(e.First[from:], 60), clipText(e.Second[from:], 60))
+}
+
+func (e *IdempotenceError) Unwrap() error { return e.Err }
+
+// CheckIdempotent canonicalizes v with opts, parses the output back and
+// canonicalizes it again. It returns an *IdempotenceError if the two
+// outputs differ, and the canonicalization error if v has no loose form.
+// Verify in opts is ignored.
+func CheckIdempotent(v *GValue, opts LooseCanonOpts) error {
+	opts.Verify = false
+	first, err := CanonicalizeLooseErr(v, opts)
+	if err != nil {
+		return err
+	}
+	parsed, err := reparseLoose(first, opts)
+	if err != nil {
+		return &IdempotenceError{First: first, Err: err}
+	}
+	if opts.Types != nil {
+		ResolveTypedColumns(parsed, opts.Types)
+	}
+	second, err := CanonicalizeLooseErr(parsed, opts)
+	if err != nil {
+		return &IdempotenceError{First: first, Err: err}
+	}
+	if first == second {
+		return nil
+	}
+	i := 0
+	for i < len(first) && i < len(second) && first[i] == second[i] {
+		i++
+	}
+	return &IdempotenceError{First: first, Second: second, Offset: i}
+}
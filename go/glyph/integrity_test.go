@@ -0,0 +1,200 @@
+package glyph
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func integrityOrders() *GValue {
+	var rows []*GValue
+	for i := 0; i < 4; i++ {
+		rows = append(rows, Map(
+			MapEntry{Key: "sku", Value: Str("A-" + string(rune('0'+i)))},
+			MapEntry{Key: "qty", Value: Int(int64(i + 1))},
+		))
+	}
+	return Map(
+		MapEntry{Key: "customer", Value: ID("c", "42")},
+		MapEntry{Key: "placed", Value: Time(time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC))},
+		MapEntry{Key: "note", Value: Null()},
+		MapEntry{Key: "items", Value: List(rows...)},
+	)
+}
+
+func TestIntegrity_LooseAndTyped(t *testing.T) {
+	v := integrityOrders()
+
+	opts := DefaultLooseCanonOpts()
+	opts.Verify = true
+	if _, err := CanonicalizeLooseErr(v, opts); err != nil {
+		t.Errorf("loose: %v", err)
+	}
+	_ = CanonicalizeLooseWithOpts(v, opts) // must not panic
+
+	emitOpts := DefaultEmitOptions()
+	emitOpts.Verify = true
+	_ = EmitWithOptions(v, emitOpts)
+}
+
+func TestIntegrity_CatchesUnreadableOutput(t *testing.T) {
+	// A bare schema reference only parses where the receiver already holds
+	// the schema, so the output alone cannot be read back.
+	opts := DefaultLooseCanonOpts()
+	opts.SchemaRef = "external"
+	opts.Verify = true
+
+	defer func() {
+		var ie *IntegrityError
+		err, _ := recover().(error)
+		if !errors.As(err, &ie) {
+			t.Fatalf("expected an *IntegrityError panic, got %v", err)
+		}
+		if ie.Mode != "loose" || ie.Err == nil || !strings.Contains(ie.Output, "@tab") {
+			t.Errorf("unexpected diagnostics: %+v", ie)
+		}
+	}()
+	CanonicalizeLooseWithSchema(integrityOrders(), opts)
+}
+
+func TestIntegrity_Diagnostics(t *testing.T) {
+	v := integrityOrders()
+	err := verifyEmission("loose", v, "", func(string) (*GValue, error) {
+		got := deepCopy(v)
+		got.Get("items").listVal[2].Get("qty").intVal = 99
+		return got, nil
+	})
+	var ie *IntegrityError
+	if !errors.As(err, &ie) {
+		t.Fatalf("expected *IntegrityError, got %v", err)
+	}
+	if ie.Path != "items[2].qty" || ie.Want != "int 3" || ie.Got != "int 99" {
+		t.Errorf("got path=%q want=%q got=%q", ie.Path, ie.Want, ie.Got)
+	}
+	if !strings.Contains(ie.Error(), "at items[2].qty") {
+		t.Errorf("message should name the path: %s", ie.Error())
+	}
+
+	err = verifyEmission("loose", v, "", func(string) (*GValue, error) {
+		return Map(MapEntry{Key: "customer", Value: ID("c", "42")}), nil
+	})
+	if !errors.As(err, &ie) || ie.Path != "" {
+		t.Errorf("differing key sets should be reported at the root: %v", err)
+	}
+}
+
+func TestIntegrity_PackedAndTabular(t *testing.T) {
+	schema := sparseTestSchema()
+	rows := []*GValue{
+		Struct("Config", MapEntry{Key: "name", Value: Str("a")}, MapEntry{Key: "port", Value: Int(1)}),
+		Struct("Config", MapEntry{Key: "name", Value: Str("b")}, MapEntry{Key: "tags", Value: List(Str("x"))}),
+		Struct("Config", MapEntry{Key: "name", Value: Str("c")}, MapEntry{Key: "debug", Value: Bool(true)}),
+	}
+
+	popts := DefaultPackedOptions(schema)
+	popts.Verify = true
+	for _, row := range rows {
+		if _, err := EmitPackedWithOptions(row, popts); err != nil {
+			t.Errorf("packed: %v", err)
+		}
+	}
+
+	topts := DefaultTabularOptions(schema)
+	topts.Verify = true
+	if _, err := EmitTabularWithOptions(List(rows...), topts); err != nil {
+		t.Errorf("tabular: %v", err)
+	}
+}
+
+// idempotenceProfiles returns every built-in option profile, and the option
+// switches that change the text, for values typed by schema's Entry.
+func idempotenceProfiles(schema *Schema) map[string]LooseCanonOpts {
+	profiles := map[string]LooseCanonOpts{
+		"default": DefaultLooseCanonOpts(),
+		"llm":     LLMLooseCanonOpts(),
+		"pretty":  PrettyLooseCanonOpts(),
+		"notab":   NoTabularLooseCanonOpts(),
+		"schema":  SchemaLooseCanonOpts(NewSchemaContextWithID("S1", []string{"id", "name", "rows", "tags"})),
+		"order":   SchemaOrderLooseCanonOpts(schema, "Doc"),
+	}
+	with := func(name string, set func(*LooseCanonOpts)) {
+		opts := DefaultLooseCanonOpts()
+		set(&opts)
+		profiles[name] = opts
+	}
+	with("keydict", func(o *LooseCanonOpts) { o.KeyDict, o.UseCompactKeys = []string{"id", "name"}, true })
+	with("symbol-null", func(o *LooseCanonOpts) { o.NullStyle = NullStyleSymbol })
+	with("columns", func(o *LooseCanonOpts) { o.TableLayout = TableColumnMajor })
+	with("dedup", func(o *LooseCanonOpts) { o.DedupRows = true })
+	with("hex", func(o *LooseCanonOpts) { o.BytesEncoding = BytesHex })
+	with("header", func(o *LooseCanonOpts) { o.VersionHeader = true })
+	with("types", func(o *LooseCanonOpts) { o.Types = schema })
+	with("types-fid", func(o *LooseCanonOpts) { o.Types, o.TypeKeys = schema, KeyModeFID })
+	return profiles
+}
+
+func idempotenceCorpus(t *testing.T) map[string]*GValue {
+	t.Helper()
+	corpus := map[string]*GValue{
+		"orders": integrityOrders(),
+		"scalars": List(Null(), Bool(true), Int(-7), Float(0.1), Float(1e21), Str(""), Str("t"), Str("a|b \"q\""),
+			Bytes([]byte{0, 0xff}), ID("u", "1"), ID("", "x y"), Time(time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC))),
+		"entries": Map(MapEntry{Key: "rows", Value: List(
+			Struct("Entry", FieldVal("id", Int(1)), FieldVal("name", Str("a"))),
+			Struct("Entry", FieldVal("id", Int(2)), FieldVal("name", Str("b"))),
+			Struct("Entry", FieldVal("id", Int(2)), FieldVal("name", Str("b"))),
+		)}),
+		"sum":   Sum("Ok", Map(MapEntry{Key: "b", Value: Int(1)}, MapEntry{Key: "a", Value: List()})),
+		"empty": Map(MapEntry{Key: "m", Value: Map()}, MapEntry{Key: "l", Value: List()}),
+	}
+	for name, src := range map[string]string{
+		"json-nested": `{"b":{"y":[1,{"d":1,"c":2}],"x":null},"a":[{"k":"v"},{"k":"w"},{"k":"v"}]}`,
+		"json-ragged": `[{"a":1},{"b":2.5},{"a":null,"b":"x|y"},{"a":[1,2]}]`,
+		"json-keys":   `{"ü":1,"with space":2,"a=b":3,"":4,"#0":5,"k\"q":6}`,
+	} {
+		v, err := FromJSONLoose([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus[name] = v
+	}
+	return corpus
+}
+
+func TestCheckIdempotent(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Entry", "v1",
+			Field("name", PrimitiveType("str"), WithFID(1), WithWireKey("n")),
+			Field("id", PrimitiveType("int"), WithFID(2)),
+		).
+		AddStruct("Doc", "v1", Field("rows", ListType(RefType("Entry")), WithFID(1))).
+		Build()
+
+	corpus := idempotenceCorpus(t)
+	for pname, opts := range idempotenceProfiles(schema) {
+		for vname, v := range corpus {
+			if err := CheckIdempotent(v, opts); err != nil {
+				t.Errorf("%s/%s: %v", pname, vname, err)
+			}
+		}
+	}
+}
+
+func TestCheckIdempotent_Violation(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("P", "v1", Field("z", PrimitiveType("int")), Field("a", PrimitiveType("int"))).
+		Build()
+	// Ordered by type name, which the loose text drops.
+	opts := SchemaOrderLooseCanonOpts(schema, "")
+	err := CheckIdempotent(Struct("P", FieldVal("a", Int(1)), FieldVal("z", Int(2))), opts)
+	var ierr *IdempotenceError
+	if !errors.As(err, &ierr) || ierr.First != "{z=2 a=1}" || ierr.Second != "{a=1 z=2}" || ierr.Offset != 1 {
+		t.Fatalf("err = %v", err)
+	}
+
+	if err := CheckIdempotent(Float(math.NaN()), DefaultLooseCanonOpts()); err == nil {
+		t.Error("NaN passed")
+	}
+}
@@ -0,0 +1,103 @@
+package glyph
+
+import (
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// String Interning
+// ============================================================
+//
+// Decoded documents repeat the same keys ("id", "name", "score") and short
+// values ("ok", "user") thousands of times. An Interner shared by the parsers
+// makes each distinct string one allocation. Set ParseOptions.Interner or
+// BridgeOpts.Interner to use one; a nil Interner interns nothing.
+
+// Interner deduplicates short strings. It is safe for concurrent use and may
+// be shared across parses; strings it stores never retain a parser's input.
+type Interner struct {
+	MaxLen     int // Longer strings pass through (0 = no limit)
+	MaxEntries int // Once the table holds this many, new strings pass through (0 = no limit)
+
+	mu    sync.Mutex
+	table map[string]string
+	stats InternStats
+}
+
+// InternStats reports how an Interner has been used, for tuning MaxLen and
+// MaxEntries.
+type InternStats struct {
+	Lookups    int   // Calls to Intern
+	Hits       int   // Lookups answered from the table
+	Skipped    int   // Lookups over MaxLen or after the table filled
+	Entries    int   // Distinct strings stored
+	BytesSaved int64 // Bytes of the strings returned on hits
+}
+
+// HitRate returns Hits/Lookups, or 0 before any lookup.
+func (s InternStats) HitRate() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Lookups)
+}
+
+// NewInterner returns an Interner for strings up to 64 bytes, holding at
+// most 65536 of them.
+func NewInterner() *Interner {
+	return &Interner{MaxLen: 64, MaxEntries: 1 << 16}
+}
+
+// Intern returns the stored copy of s, storing one first if there is room.
+// A nil Interner returns s.
+func (in *Interner) Intern(s string) string {
+	if in == nil {
+		return s
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.stats.Lookups++
+	if in.MaxLen > 0 && len(s) > in.MaxLen {
+		in.stats.Skipped++
+		return s
+	}
+	if v, ok := in.table[s]; ok {
+		in.stats.Hits++
+		in.stats.BytesSaved += int64(len(v))
+		return v
+	}
+	if in.MaxEntries > 0 && len(in.table) >= in.MaxEntries {
+		in.stats.Skipped++
+		return s
+	}
+	if in.table == nil {
+		in.table = make(map[string]string)
+	}
+	v := strings.Clone(s)
+	in.table[v] = v
+	in.stats.Entries = len(in.table)
+	return v
+}
+
+// Stats returns a snapshot of the usage counters.
+func (in *Interner) Stats() InternStats {
+	if in == nil {
+		return InternStats{}
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// Reset empties the table and zeroes the counters.
+func (in *Interner) Reset() {
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.table = nil
+	in.stats = InternStats{}
+}
@@ -0,0 +1,76 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func sameString(a, b string) bool {
+	return unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+// entryKey returns the stored key string of v's entry named key.
+func entryKey(v *GValue, key string) string {
+	for _, e := range v.mapVal {
+		if e.Key == key {
+			return e.Key
+		}
+	}
+	return ""
+}
+
+func TestInterner(t *testing.T) {
+	in := &Interner{MaxLen: 8, MaxEntries: 2}
+
+	a := in.Intern(strings.Clone("score"))
+	b := in.Intern(strings.Clone("score"))
+	if !sameString(a, b) {
+		t.Error("equal strings should share storage")
+	}
+	in.Intern("a very long string")
+	in.Intern("id")
+	in.Intern("name") // table full
+
+	want := InternStats{Lookups: 5, Hits: 1, Skipped: 2, Entries: 2, BytesSaved: 5}
+	if got := in.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	if r := in.Stats().HitRate(); r != 0.2 {
+		t.Errorf("hit rate = %v", r)
+	}
+
+	var none *Interner
+	none.Reset()
+	if none.Intern("x") != "x" || none.Stats() != (InternStats{}) {
+		t.Error("nil Interner should pass strings through")
+	}
+}
+
+func TestInterner_Parsers(t *testing.T) {
+	in := NewInterner()
+
+	v, err := FromJSONLooseWithOpts([]byte(`[{"id":1,"role":"user"},{"id":2,"role":"user"}]`), BridgeOpts{Interner: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r0, r1 := v.listVal[0], v.listVal[1]
+	if !sameString(entryKey(r0, "id"), entryKey(r1, "id")) || !sameString(entryKey(r0, "role"), entryKey(r1, "role")) {
+		t.Error("JSON keys should be interned")
+	}
+	if !sameString(r0.Get("role").strVal, r1.Get("role").strVal) {
+		t.Error("JSON string values should be interned")
+	}
+
+	res, err := ParseWithOptions(`[{id=1 role="user"} {id=2 role="user"}]`, ParseOptions{Interner: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p0, p1 := res.Value.listVal[0], res.Value.listVal[1]
+	if !sameString(entryKey(p0, "id"), entryKey(p1, "id")) || !sameString(p0.Get("role").strVal, r0.Get("role").strVal) {
+		t.Error("parser keys and values should come from the shared table")
+	}
+	if s := in.Stats(); s.Entries != 3 || s.Hits == 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
@@ -30,8 +30,9 @@ import (
 // integer literal via json.Number (see toJSONValue).
 //
 // Supports two modes:
-//   - Strict (default): time/id/bytes become strings, fully JSON compatible
-//   - Extended: uses $glyph markers for lossless round-trip of time/id/bytes.
+//   - Strict (default): time/id/bytes/custom become strings, fully JSON compatible
+//   - Extended: uses $glyph markers for lossless round-trip of time/id/bytes
+//     and custom scalars.
 //     In extended mode the "$glyph" object key is RESERVED; emitting a map or
 //     struct that uses it is a loud error rather than a silently ambiguous
 //     marker (see toJSONValue), and only exactly-shaped marker objects are
@@ -39,9 +40,13 @@ import (
 
 // BridgeOpts configures JSON bridge behavior.
 type BridgeOpts struct {
-	// Extended enables $glyph markers for lossless round-trip of time/id/bytes.
-	// When false (default), these types are converted to plain strings.
+	// Extended enables $glyph markers for lossless round-trip of
+	// time/id/bytes and custom scalars. When false (default), these types are converted to plain strings.
 	Extended bool
+
+	// Interner, if set, deduplicates object keys and string values decoded
+	// by FromJSONLooseWithOpts. Emitting ignores it.
+	Interner *Interner
 }
 
 // DefaultBridgeOpts returns the default (strict/JSON-compatible) options.
@@ -110,7 +115,7 @@ func fromJSONValue(v interface{}, opts BridgeOpts) (*GValue, error) {
 		return Float(val), nil
 
 	case string:
-		return Str(val), nil
+		return Str(opts.Interner.Intern(val)), nil
 
 	case []interface{}:
 		items := make([]*GValue, 0, len(val))
@@ -138,7 +143,7 @@ func fromJSONValue(v interface{}, opts BridgeOpts) (*GValue, error) {
 			if err != nil {
 				return nil, fmt.Errorf("object[%q]: %w", k, err)
 			}
-			entries = append(entries, MapEntry{Key: k, Value: gv})
+			entries = append(entries, MapEntry{Key: opts.Interner.Intern(k), Value: gv})
 		}
 		return Map(entries...), nil
 
@@ -220,6 +225,20 @@ func fromGlyphMarker(markerType string, obj map[string]interface{}) (*GValue, er
 		}
 		return Bytes(data), nil
 
+	case "custom":
+		if err := exactKeys("$glyph", "tag", "value"); err != nil {
+			return nil, err
+		}
+		tag, ok := obj["tag"].(string)
+		if !ok {
+			return nil, fmt.Errorf("$glyph custom marker missing tag")
+		}
+		text, ok := obj["value"].(string)
+		if !ok {
+			return nil, fmt.Errorf("$glyph custom marker missing value")
+		}
+		return DefaultScalarRegistry.Decode(tag, text)
+
 	default:
 		return nil, fmt.Errorf("unknown $glyph marker type: %s", markerType)
 	}
@@ -272,6 +291,9 @@ func ToJSONValueLooseWithOpts(v *GValue, opts BridgeOpts) (interface{}, error) {
 }
 
 func toJSONValue(v *GValue, opts BridgeOpts) (interface{}, error) {
+	if err := v.force(); err != nil {
+		return nil, err
+	}
 	if v == nil {
 		return nil, nil
 	}
@@ -301,22 +323,22 @@ func toJSONValue(v *GValue, opts BridgeOpts) (interface{}, error) {
 		if opts.Extended {
 			return map[string]interface{}{
 				"$glyph": "bytes",
-				"base64": base64.StdEncoding.EncodeToString(v.bytesVal),
+				"base64": base64.StdEncoding.EncodeToString(v.bytesVal()),
 			}, nil
 		}
-		return base64.StdEncoding.EncodeToString(v.bytesVal), nil
+		return base64.StdEncoding.EncodeToString(v.bytesVal()), nil
 
 	case TypeTime:
 		if opts.Extended {
 			return map[string]interface{}{
 				"$glyph": "time",
-				"value":  v.timeVal.Format(time.RFC3339),
+				"value":  v.timeVal().Format(time.RFC3339),
 			}, nil
 		}
-		return v.timeVal.Format(time.RFC3339), nil
+		return v.timeVal().Format(time.RFC3339), nil
 
 	case TypeID:
-		idStr := canonRef(v.idVal)
+		idStr := canonRef(v.idVal())
 		if opts.Extended {
 			return map[string]interface{}{
 				"$glyph": "id",
@@ -325,6 +347,17 @@ func toJSONValue(v *GValue, opts BridgeOpts) (interface{}, error) {
 		}
 		return idStr, nil
 
+	case TypeCustom:
+		cv := v.customVal()
+		if opts.Extended {
+			return map[string]interface{}{
+				"$glyph": "custom",
+				"tag":    cv.Tag,
+				"value":  cv.Text,
+			}, nil
+		}
+		return cv.Text, nil
+
 	case TypeList:
 		items := make([]interface{}, 0, len(v.listVal))
 		for _, elem := range v.listVal {
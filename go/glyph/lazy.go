@@ -0,0 +1,198 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// Lazy Parsing
+// ============================================================
+//
+// ParseLooseLazy keeps large containers as raw source and parses them on
+// first read. A deferred container already has its final type (map or list)
+// and an empty body; ext.lazy holds the span. GValue accessors and the
+// package's own traversals call force before reading a container's body.
+//
+// ParseLooseLazy checks the structure of the whole text before deferring
+// any of it (checkLooseStructure), so a span only fails to parse over a bad
+// scalar or @tab block, or once the mapping of a MappedDocument is gone.
+// Emitters write such a span as its source (see unparsed) rather than as the
+// empty container it reads as, so no data is dropped silently.
+
+// lazySpan is the unparsed source of a deferred container.
+type lazySpan struct {
+	raw       string
+	keyDict   []string // Resolves #N compact keys
+	threshold int      // Defer nested containers at least this long
+	err       error    // Why raw failed to parse, once it has been tried
+	src       *mapping // Memory raw lives in, for spans of a MappedDocument
+}
+
+// deferLoose returns a deferred value for the loose container s, or nil if s
+// is not a map, list, or @tab block.
+func deferLoose(s string, keyDict []string, threshold int) *GValue {
+	var t GType
+	switch {
+	case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+		t = TypeMap
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"),
+		isTabHeader(s):
+		t = TypeList
+	default:
+		return nil
+	}
+	return newExtValue(t, valueExt{lazy: &lazySpan{raw: s, keyDict: keyDict, threshold: threshold}})
+}
+
+// IsLazy reports whether v is a container whose source has not been parsed
+// yet.
+func (v *GValue) IsLazy() bool {
+	return v != nil && v.ext != nil && v.ext.lazy != nil
+}
+
+// Materialize parses v's deferred source, if any, in place. Only one level
+// is parsed: child containers above the threshold stay deferred until they
+// are read. It does nothing for values that were not deferred. On error v
+// stays deferred and keeps the error: later calls return it without parsing
+// again.
+func (v *GValue) Materialize() error {
+	if !v.IsLazy() {
+		return nil
+	}
+	l := v.ext.lazy
+	if l.err != nil {
+		return l.err
+	}
+	if l.src != nil {
+		l.src.mu.RLock()
+		defer l.src.mu.RUnlock()
+		if l.src.data == nil {
+			l.err = fmt.Errorf("materialize: %w", errMappingClosed)
+			return l.err
+		}
+	}
+
+	var parsed *GValue
+	var err error
+	switch {
+	case strings.HasPrefix(l.raw, "{"):
+		parsed, err = parseLooseMapLazy(l.raw, l.keyDict, l.threshold)
+	case strings.HasPrefix(l.raw, "["):
+		parsed, err = parseLooseListLazy(l.raw, l.keyDict, l.threshold)
+	default:
+		parsed, _, err = parseTabularLoose(l.raw, l.keyDict)
+	}
+	if err != nil {
+		l.err = fmt.Errorf("materialize: %w", err)
+		return l.err
+	}
+	if l.src != nil {
+		l.src.detach(parsed)
+	}
+
+	v.listVal, v.mapVal = parsed.listVal, parsed.mapVal
+	x := *v.ext
+	x.lazy = nil
+	if parsed.ext != nil {
+		x.omitted = parsed.ext.omitted
+	}
+	v.ext = &x
+	return nil
+}
+
+// MaterializeAll parses every deferred container in v, returning the first
+// error. Afterwards v holds no raw spans and is safe for concurrent reads.
+func (v *GValue) MaterializeAll() error {
+	if v == nil {
+		return nil
+	}
+	if err := v.Materialize(); err != nil {
+		return err
+	}
+	switch v.typ {
+	case TypeList:
+		for i, item := range v.listVal {
+			if err := item.MaterializeAll(); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	case TypeMap:
+		for _, e := range v.mapVal {
+			if err := e.Value.MaterializeAll(); err != nil {
+				return fmt.Errorf("%s: %w", e.Key, err)
+			}
+		}
+	case TypeStruct:
+		if v.structVal != nil {
+			for _, f := range v.structVal.Fields {
+				if err := f.Value.MaterializeAll(); err != nil {
+					return fmt.Errorf("%s: %w", f.Key, err)
+				}
+			}
+		}
+	case TypeSum:
+		if v.sumVal != nil {
+			return v.sumVal.Value.MaterializeAll()
+		}
+	}
+	return nil
+}
+
+// parseLazyRoot is parseLooseValueLazy for a whole payload: with lazy > 0
+// it first checks the structure of s, so that a malformed container is
+// reported now rather than when it is read.
+func parseLazyRoot(s string, keyDict []string, lazy int) (*GValue, error) {
+	if lazy > 0 {
+		if err := checkLooseStructure(strings.TrimSpace(s)); err != nil {
+			return nil, err
+		}
+	}
+	return parseLooseValueLazy(s, keyDict, lazy)
+}
+
+// checkLooseStructure checks that every map entry of the loose text s, at
+// any depth, has a key and a value, without parsing the values.
+func checkLooseStructure(s string) error {
+	return eachLooseChild(s, IndexSpan{Length: len(s)}, func(seg PathSeg, span IndexSpan) (bool, error) {
+		child := s[span.Offset : span.Offset+span.Length]
+		err := fmt.Errorf("empty input")
+		if child != "" {
+			err = checkLooseStructure(child)
+		}
+		if err != nil && seg.Kind == PathSegMapKey {
+			err = fmt.Errorf("map value for %s: %w", seg.MapKey, err)
+		}
+		return err == nil, err
+	})
+}
+
+// unparsed returns the text to emit for v if it is a deferred container
+// that fails to parse: its source, so the output fails to parse as the input
+// did, or an omission marker once the source is unmapped.
+func (v *GValue) unparsed() (string, bool) {
+	if v.force() == nil {
+		return "", false
+	}
+	l := v.ext.lazy
+	if l.src != nil {
+		l.src.mu.RLock()
+		defer l.src.mu.RUnlock()
+		if l.src.data == nil {
+			return omissionMarker(-1), true
+		}
+		return strings.Clone(l.raw), true
+	}
+	return l.raw, true
+}
+
+// force materializes v if it is deferred, so the caller can read listVal or
+// mapVal directly. A span that fails to parse reads as an empty container;
+// force returns the error, for the accessors that can report it, and
+// Materialize returns it again. Emitters use unparsed instead.
+func (v *GValue) force() error {
+	if v != nil && v.ext != nil && v.ext.lazy != nil {
+		return v.Materialize()
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package glyph
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func lazyTestDoc(rows int) string {
+	var items []*GValue
+	for i := 0; i < rows; i++ {
+		items = append(items, Map(
+			MapEntry{Key: "id", Value: Int(int64(i))},
+			MapEntry{Key: "text", Value: Str("row " + strconv.Itoa(i))},
+		))
+	}
+	v := Map(
+		MapEntry{Key: "status", Value: Str("ok")},
+		MapEntry{Key: "history", Value: List(items...)},
+		MapEntry{Key: "meta", Value: Map(MapEntry{Key: "tags", Value: List(items[:3]...)})},
+	)
+	return CanonicalizeLooseNoTabular(v)
+}
+
+func TestParseLooseLazy(t *testing.T) {
+	input := lazyTestDoc(50)
+	eager, err := ParseLoose(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := ParseLooseLazy(input, nil, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.IsLazy() {
+		t.Fatal("root should be deferred")
+	}
+	if s, _ := v.Get("status").AsStr(); s != "ok" {
+		t.Errorf("status = %q", s)
+	}
+	if v.IsLazy() || !v.Get("history").IsLazy() {
+		t.Error("reading status should parse the root only")
+	}
+
+	if n := v.Get("history").Len(); n != 50 {
+		t.Errorf("history has %d items", n)
+	}
+	if CanonicalizeLoose(v) != CanonicalizeLoose(eager) {
+		t.Error("lazy value canonicalizes differently from the eager parse")
+	}
+	if err := v.MaterializeAll(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Get("meta").IsLazy() {
+		t.Error("MaterializeAll left a deferred container")
+	}
+}
+
+func TestParseLooseLazy_CompactKeysAndTabs(t *testing.T) {
+	var rows []*GValue
+	for i := 0; i < 5; i++ {
+		rows = append(rows, Map(MapEntry{Key: "name", Value: Str("n" + strconv.Itoa(i))}, MapEntry{Key: "score", Value: Int(int64(i))}))
+	}
+	v := Map(MapEntry{Key: "results", Value: List(rows...)}, MapEntry{Key: "total", Value: Int(5)})
+
+	opts := DefaultLooseCanonOpts()
+	opts.Schema = NewSchemaContext(BuildKeyDictFromValue(v))
+	opts.UseCompactKeys = true
+	out := CanonicalizeLooseWithSchema(v, opts)
+
+	got, err := ParseLooseLazy(out, nil, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(got, v) {
+		t.Errorf("got %s\nwant %s", CanonicalizeLoose(got), CanonicalizeLoose(v))
+	}
+}
+
+func TestParseLooseLazy_StructureError(t *testing.T) {
+	for _, input := range []string{`{ok=1 bad={a=1 oops}}`, `{ok=1 big=[1 {x=}]}`} {
+		if _, err := ParseLoose(input, nil); err == nil {
+			t.Fatalf("eager parse of %s should fail", input)
+		}
+		if _, err := ParseLooseLazy(input, nil, 8); err == nil {
+			t.Errorf("lazy parse of %s should fail", input)
+		}
+	}
+}
+
+func TestParseLooseLazy_DeferredError(t *testing.T) {
+	input := `{ok=1 bad={a=b64"!!!"}}`
+	if _, err := ParseLoose(input, nil); err == nil {
+		t.Fatal("eager parse should fail")
+	}
+	v, err := ParseLooseLazy(input, nil, 8)
+	if err != nil {
+		t.Fatalf("scalar errors inside a deferred span should wait: %v", err)
+	}
+	if err := v.MaterializeAll(); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("MaterializeAll should report the bad span, got %v", err)
+	}
+
+	// Reading the bad span keeps its error for the accessors that return one.
+	bad := v.Get("bad")
+	if n := bad.Len(); n != 0 {
+		t.Errorf("bad span reads as %d entries", n)
+	}
+	if _, err := bad.AsMap(); err == nil {
+		t.Error("AsMap should report the parse error")
+	}
+	if err := bad.Materialize(); err == nil || !bad.IsLazy() {
+		t.Errorf("Materialize after a failed read: err=%v lazy=%v", err, bad.IsLazy())
+	}
+
+	// Emitters keep the bad span as written, so nothing is dropped.
+	for name, out := range map[string]string{"Emit": Emit(v), "CanonicalizeLoose": CanonicalizeLoose(v)} {
+		if !strings.Contains(out, `{a=b64"!!!"}`) {
+			t.Errorf("%s = %s", name, out)
+		}
+		if _, err := ParseLoose(out, nil); err == nil {
+			t.Errorf("%s output parses", name)
+		}
+	}
+	if _, err := CanonicalizeLooseErr(v, DefaultLooseCanonOpts()); err == nil {
+		t.Error("CanonicalizeLooseErr should report the bad span")
+	}
+	if _, err := ToJSONLoose(v); err == nil {
+		t.Error("ToJSONLoose should report the bad span")
+	}
+}
+
+func BenchmarkParseLoose_OneField(b *testing.B) {
+	input := lazyTestDoc(5000)
+	b.Run("eager", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			v, _ := ParseLoose(input, nil)
+			_ = v.Get("status")
+		}
+	})
+	b.Run("lazy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			v, _ := ParseLooseLazy(input, nil, 1024)
+			_ = v.Get("status")
+		}
+	})
+}
@@ -0,0 +1,395 @@
+package glyph
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Token Lint
+// ============================================================
+//
+// Profile says where the tokens go; Lint says what to do about it. It looks
+// for structures that cost tokens for nothing and suggests the change that
+// removes them, with an estimate of the tokens saved:
+//
+//	repeated_subtree  the same map, list or struct appears several times
+//	long_key          a key costing several tokens is written many times
+//	table_candidate   a list of maps would be a @tab block with other
+//	                  options (MinRows, AllowMissing, AutoTabular)
+//	float_precision   floats carry more significant digits than needed
+//
+// Lint is a dry run: it only reads v. Estimates compare emitted text, so
+// they are approximate; findings below LintOpts.MinSavings are dropped.
+
+// LintFinding is one token anti-pattern found by Lint.
+type LintFinding struct {
+	Rule       string // repeated_subtree, long_key, table_candidate, float_precision
+	Path       string // Validator-style; [*] marks any index in long_key and float_precision paths
+	Message    string // What was found
+	Suggestion string // What to change
+	Savings    int    // Estimated tokens saved by the suggestion
+}
+
+// String renders the finding on one line.
+func (f LintFinding) String() string {
+	at := f.Path
+	if at == "" {
+		at = "root"
+	}
+	return fmt.Sprintf("%s: %s: %s; %s (~%d tokens)", at, f.Rule, f.Message, f.Suggestion, f.Savings)
+}
+
+// LintOpts configures Lint.
+type LintOpts struct {
+	Base       *LooseCanonOpts // Encoding v is emitted with; nil means DefaultLooseCanonOpts
+	Tokenizer  Tokenizer       // nil means EstimateTokens
+	MinSavings int             // Smallest saving worth reporting (default 8 tokens)
+	MaxDigits  int             // Significant float digits that suffice (default 6)
+}
+
+// Lint reports token anti-patterns in v under the default loose encoding.
+func Lint(v *GValue) []LintFinding {
+	return LintWithOptions(v, LintOpts{})
+}
+
+// LintWithOptions reports token anti-patterns in v, largest savings first.
+func LintWithOptions(v *GValue, opts LintOpts) []LintFinding {
+	l := &linter{opts: opts, keys: make(map[string]*keyCount), floats: make(map[string]*floatGroup)}
+	if opts.Base != nil {
+		l.base = *opts.Base
+	} else {
+		l.base = DefaultLooseCanonOpts()
+	}
+	if l.base.MinRows == 0 {
+		l.base.MinRows = 3
+	}
+	if l.base.MaxCols == 0 {
+		l.base.MaxCols = 20
+	}
+	l.tok = opts.Tokenizer
+	if l.tok == nil {
+		l.tok = EstimateTokens
+	}
+	if l.opts.MinSavings <= 0 {
+		l.opts.MinSavings = 8
+	}
+	if l.opts.MaxDigits <= 0 {
+		l.opts.MaxDigits = 6
+	}
+
+	l.subtrees = make(map[[32]byte]*subtreeCount)
+	l.countSubtrees(v)
+	l.walk(v, "", "", make(map[[32]byte]bool))
+	l.keyFindings()
+	l.floatFindings()
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.Savings != b.Savings {
+			return a.Savings > b.Savings
+		}
+		return a.Path < b.Path
+	})
+	return l.findings
+}
+
+type linter struct {
+	opts     LintOpts
+	base     LooseCanonOpts
+	tok      Tokenizer
+	subtrees map[[32]byte]*subtreeCount
+	keys     map[string]*keyCount   // Key -> times written in the output
+	floats   map[string]*floatGroup // Path pattern -> floats found there
+	findings []LintFinding
+}
+
+type subtreeCount struct {
+	n      int
+	tokens int
+}
+
+type keyCount struct {
+	n  int
+	at string // Path pattern of the first occurrence
+}
+
+type floatGroup struct {
+	n      int
+	digits int // Most significant digits seen
+	saving int // Tokens saved by rounding all of them
+	inList bool
+}
+
+func (l *linter) add(f LintFinding) {
+	if f.Savings >= l.opts.MinSavings {
+		l.findings = append(l.findings, f)
+	}
+}
+
+// subtreeKey returns the identity of a container subtree, or false for
+// scalars and empty containers.
+func (l *linter) subtreeKey(v *GValue) ([32]byte, string, bool) {
+	v.force()
+	if v == nil {
+		return [32]byte{}, "", false
+	}
+	switch v.typ {
+	case TypeList, TypeMap, TypeStruct:
+		if v.Len() == 0 {
+			return [32]byte{}, "", false
+		}
+	case TypeSum:
+	default:
+		return [32]byte{}, "", false
+	}
+	out := canonLooseWithOpts(v, l.base)
+	return sha256.Sum256([]byte(out)), out, true
+}
+
+// countSubtrees counts the occurrences of every container subtree.
+func (l *linter) countSubtrees(v *GValue) {
+	key, out, ok := l.subtreeKey(v)
+	if !ok {
+		return
+	}
+	c := l.subtrees[key]
+	if c == nil {
+		c = &subtreeCount{tokens: l.tok(out)}
+		l.subtrees[key] = c
+	}
+	c.n++
+	for _, child := range lintChildren(v) {
+		l.countSubtrees(child)
+	}
+}
+
+// walk visits v top-down. pattern is path with list indices as [*]. seen
+// holds repeated subtrees already reported, whose copies are not entered.
+func (l *linter) walk(v *GValue, path, pattern string, seen map[[32]byte]bool) {
+	v.force()
+	if v == nil || l.repeated(v, path, seen) {
+		return
+	}
+
+	switch v.typ {
+	case TypeFloat:
+		l.noteFloat(v.floatVal, pattern)
+	case TypeList:
+		tabular := l.base.AutoTabular && planLooseTable(v.listVal, l.base) != nil
+		if !tabular {
+			l.tableCandidate(v, path)
+		}
+		counted := make(map[string]bool)
+		for i, item := range v.listVal {
+			itemPath, itemPattern := fmt.Sprintf("%s[%d]", path, i), pattern+"[*]"
+			if tabular {
+				// Keys are written once, in the table header.
+				for _, k := range getObjectKeys(item) {
+					if !counted[k] {
+						counted[k] = true
+						l.noteKey(k, joinPath(itemPattern, k))
+					}
+				}
+				if l.repeated(item, itemPath, seen) {
+					continue
+				}
+				for _, child := range lintFields(item) {
+					l.walk(child.Value, joinPath(itemPath, child.Key), joinPath(itemPattern, child.Key), seen)
+				}
+				continue
+			}
+			l.walk(item, itemPath, itemPattern, seen)
+		}
+	case TypeMap, TypeStruct:
+		for _, e := range lintFields(v) {
+			l.noteKey(e.Key, joinPath(pattern, e.Key))
+			l.walk(e.Value, joinPath(path, e.Key), joinPath(pattern, e.Key), seen)
+		}
+	case TypeSum:
+		if v.sumVal != nil {
+			l.walk(v.sumVal.Value, path, pattern, seen)
+		}
+	}
+}
+
+// repeated reports v if it is a subtree that occurs more than once and
+// writing it once would save MinSavings, and returns true if v was reported
+// here or before.
+func (l *linter) repeated(v *GValue, path string, seen map[[32]byte]bool) bool {
+	key, _, ok := l.subtreeKey(v)
+	if !ok {
+		return false
+	}
+	if seen[key] {
+		return true
+	}
+	c := l.subtrees[key]
+	saving := (c.n - 1) * (c.tokens - 2)
+	if c.n < 2 || saving < l.opts.MinSavings {
+		return false
+	}
+	seen[key] = true
+	l.add(LintFinding{
+		Rule:       "repeated_subtree",
+		Path:       path,
+		Message:    fmt.Sprintf("identical %s of %d tokens appears %d times", v.typ, c.tokens, c.n),
+		Suggestion: "write it once and refer to it by ^ref or a shared field (DedupRows for repeated table rows)",
+		Savings:    saving,
+	})
+	return true
+}
+
+// noteKey counts one written occurrence of key, first seen at pattern.
+func (l *linter) noteKey(key, pattern string) {
+	k := l.keys[key]
+	if k == nil {
+		k = &keyCount{at: pattern}
+		l.keys[key] = k
+	}
+	k.n++
+}
+
+// tableCandidate reports a list that is not a table under the base options
+// but would be one, and cheaper, with AutoTabular on, MinRows lowered to
+// its length, or AllowMissing on.
+func (l *linter) tableCandidate(v *GValue, path string) {
+	items := v.listVal
+	if len(items) < 2 {
+		return
+	}
+	try := l.base
+	var changes []string
+	if !try.AutoTabular {
+		try.AutoTabular = true
+		changes = append(changes, "AutoTabular=true")
+	}
+	if len(items) < try.MinRows {
+		try.MinRows = len(items)
+		changes = append(changes, fmt.Sprintf("MinRows=%d", len(items)))
+	}
+	if planLooseTable(items, try) == nil && !try.AllowMissing {
+		try.AllowMissing = true
+		changes = append(changes, "AllowMissing=true")
+	}
+	if len(changes) == 0 || planLooseTable(items, try) == nil {
+		return
+	}
+	saving := l.tok(canonLooseWithOpts(v, l.base)) - l.tok(canonLooseWithOpts(v, try))
+	l.add(LintFinding{
+		Rule:       "table_candidate",
+		Path:       path,
+		Message:    fmt.Sprintf("list of %d similar objects is not a table", len(items)),
+		Suggestion: "set " + strings.Join(changes, ", ") + " to emit it as @tab",
+		Savings:    saving,
+	})
+}
+
+// keyFindings reports keys whose repetition a key dictionary would save.
+// Keys are already dictionary-coded when the base options use compact keys.
+func (l *linter) keyFindings() {
+	if l.base.UseCompactKeys {
+		return
+	}
+	for key, k := range l.keys {
+		cost := l.tok(canonString(key))
+		// With a dictionary each use costs about one token (#N), and the
+		// key is written once in the header.
+		saving := k.n*(cost-1) - cost
+		l.add(LintFinding{
+			Rule:       "long_key",
+			Path:       k.at,
+			Message:    fmt.Sprintf("key %q (%d tokens) is written %d times", key, cost, k.n),
+			Suggestion: "use compact keys (UseCompactKeys with a key dictionary) or a short @k wire key",
+			Savings:    saving,
+		})
+	}
+}
+
+// noteFloat records f at pattern if it has more significant digits than
+// MaxDigits.
+func (l *linter) noteFloat(f float64, pattern string) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return
+	}
+	digits := significantDigits(f)
+	if digits <= l.opts.MaxDigits {
+		return
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', l.opts.MaxDigits, 64), 64)
+	g := l.floats[pattern]
+	if g == nil {
+		g = &floatGroup{inList: strings.HasSuffix(pattern, "[*]")}
+		l.floats[pattern] = g
+	}
+	g.n++
+	if digits > g.digits {
+		g.digits = digits
+	}
+	g.saving += l.tok(canonFloat(f)) - l.tok(canonFloat(rounded))
+}
+
+// floatFindings reports the float groups noted during the walk.
+func (l *linter) floatFindings() {
+	for pattern, g := range l.floats {
+		suggestion := fmt.Sprintf("round to %d significant digits", l.opts.MaxDigits)
+		if g.inList && g.n >= 16 {
+			suggestion += ", or declare the field @codec(vec16) or @codec(vec8)"
+		}
+		l.add(LintFinding{
+			Rule:       "float_precision",
+			Path:       pattern,
+			Message:    fmt.Sprintf("%d floats with up to %d significant digits", g.n, g.digits),
+			Suggestion: suggestion,
+			Savings:    g.saving,
+		})
+	}
+}
+
+// significantDigits returns the number of significant digits in the
+// shortest representation of f.
+func significantDigits(f float64) int {
+	s := strconv.FormatFloat(math.Abs(f), 'e', -1, 64)
+	mant, _, _ := strings.Cut(s, "e")
+	return len(strings.Replace(mant, ".", "", 1))
+}
+
+// lintChildren returns the direct children of a container.
+func lintChildren(v *GValue) []*GValue {
+	switch v.typ {
+	case TypeList:
+		return v.listVal
+	case TypeSum:
+		if v.sumVal != nil && v.sumVal.Value != nil {
+			return []*GValue{v.sumVal.Value}
+		}
+		return nil
+	}
+	fields := lintFields(v)
+	out := make([]*GValue, len(fields))
+	for i, e := range fields {
+		out[i] = e.Value
+	}
+	return out
+}
+
+// lintFields returns the entries of a map or the fields of a struct.
+func lintFields(v *GValue) []MapEntry {
+	v.force()
+	if v == nil {
+		return nil
+	}
+	switch v.typ {
+	case TypeMap:
+		return v.mapVal
+	case TypeStruct:
+		if v.structVal != nil {
+			return v.structVal.Fields
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func lintRules(findings []LintFinding) map[string]LintFinding {
+	out := make(map[string]LintFinding)
+	for _, f := range findings {
+		if _, ok := out[f.Rule]; !ok {
+			out[f.Rule] = f
+		}
+	}
+	return out
+}
+
+func TestLint_Rules(t *testing.T) {
+	address := Map(
+		MapEntry{Key: "street", Value: Str("221B Baker Street")},
+		MapEntry{Key: "city", Value: Str("London")},
+		MapEntry{Key: "postcode", Value: Str("NW1 6XE")},
+	)
+	var people []*GValue
+	for _, name := range []string{"ada", "alan", "grace", "edsger"} {
+		people = append(people, Map(
+			MapEntry{Key: "name", Value: Str(name)},
+			MapEntry{Key: "home", Value: address},
+			MapEntry{Key: "description_of_preferences", Value: Str("none")},
+		))
+	}
+	var scores []*GValue
+	for _, f := range []float64{0.123456789012, 0.987654321098, 0.555555555555, 0.314159265358} {
+		scores = append(scores, Float(f))
+	}
+	doc := Map(
+		MapEntry{Key: "people", Value: List(people...)},
+		MapEntry{Key: "scores", Value: List(scores...)},
+	)
+
+	got := lintRules(LintWithOptions(doc, LintOpts{MinSavings: 2}))
+	for rule, path := range map[string]string{
+		"repeated_subtree": "people[0].home",
+		"float_precision":  "scores[*]",
+	} {
+		f, ok := got[rule]
+		if !ok {
+			t.Errorf("no %s finding in %v", rule, got)
+			continue
+		}
+		if f.Path != path || f.Savings <= 0 || f.Suggestion == "" {
+			t.Errorf("%s = %+v, want path %s", rule, f, path)
+		}
+	}
+	if f := got["repeated_subtree"]; !strings.Contains(f.Message, "4 times") {
+		t.Errorf("repeated message = %q", f.Message)
+	}
+	if s := got["float_precision"].String(); !strings.HasPrefix(s, "scores[*]: float_precision: 4 floats with up to 12") {
+		t.Errorf("String = %s", s)
+	}
+}
+
+func TestLint_TableCandidate(t *testing.T) {
+	var rows []*GValue
+	for i, status := range []string{"open", "closed", "open", "merged"} {
+		rows = append(rows, Map(
+			MapEntry{Key: "number", Value: Int(int64(100 + i))},
+			MapEntry{Key: "status", Value: Str(status)},
+			MapEntry{Key: "author", Value: Str("octocat")},
+			MapEntry{Key: "comments", Value: Int(int64(i))},
+		))
+	}
+	// The last row lacks a key.
+	last := rows[3].mapVal
+	rows[3] = Map(last[:3]...)
+	v := Map(MapEntry{Key: "issues", Value: List(rows...)})
+
+	opts := DefaultLooseCanonOpts()
+	opts.MinRows, opts.AllowMissing = 5, false
+	f, ok := lintRules(LintWithOptions(v, LintOpts{Base: &opts, MinSavings: 1}))["table_candidate"]
+	if !ok || f.Path != "issues" || f.Suggestion != "set MinRows=4, AllowMissing=true to emit it as @tab" {
+		t.Fatalf("finding = %+v", f)
+	}
+
+	// Already a table under the defaults.
+	if f, ok := lintRules(LintWithOptions(v, LintOpts{MinSavings: 1}))["table_candidate"]; ok {
+		t.Errorf("finding with defaults: %+v", f)
+	}
+}
+
+func TestLint_LongKey(t *testing.T) {
+	var entries []*GValue
+	for i := 0; i < 6; i++ {
+		entries = append(entries, Map(MapEntry{Key: "x", Value: Map(
+			MapEntry{Key: "customer_account_identifier", Value: Int(int64(i))},
+		)}))
+	}
+	opts := NoTabularLooseCanonOpts()
+	findings := LintWithOptions(Map(MapEntry{Key: "rows", Value: List(entries...)}), LintOpts{Base: &opts})
+	f, ok := lintRules(findings)["long_key"]
+	if !ok || f.Path != "rows[*].x.customer_account_identifier" || !strings.Contains(f.Message, "6 times") {
+		t.Fatalf("findings = %v", findings)
+	}
+
+	// Compact keys already code every key.
+	opts.UseCompactKeys = true
+	if f, ok := lintRules(LintWithOptions(Map(MapEntry{Key: "rows", Value: List(entries...)}), LintOpts{Base: &opts}))["long_key"]; ok {
+		t.Errorf("long_key with compact keys: %v", f)
+	}
+}
+
+func TestLint_Clean(t *testing.T) {
+	v := Map(
+		MapEntry{Key: "id", Value: Int(7)},
+		MapEntry{Key: "tags", Value: List(Str("a"), Str("b"))},
+		MapEntry{Key: "score", Value: Float(0.25)},
+	)
+	if findings := Lint(v); len(findings) != 0 {
+		t.Errorf("findings = %v", findings)
+	}
+	if findings := Lint(nil); len(findings) != 0 {
+		t.Errorf("nil findings = %v", findings)
+	}
+}
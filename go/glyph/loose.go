@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -102,15 +103,19 @@ func canonBytes(data []byte) string {
 	return "b64" + quoteString(encoded)
 }
 
-// writeCanonBytes writes canonical bytes representation to builder.
-func writeCanonBytes(b *strings.Builder, data []byte) {
-	if len(data) == 0 {
-		b.WriteString(`b64""`)
-		return
+// writeCanonBytes writes the bytes literal for data in the given encoding.
+func writeCanonBytes(b *strings.Builder, data []byte, enc BytesEncoding) {
+	switch enc {
+	case BytesBase64URL:
+		b.WriteString(`b64u"`)
+		b.WriteString(base64.RawURLEncoding.EncodeToString(data))
+	case BytesHex:
+		b.WriteString(`hex"`)
+		b.WriteString(hex.EncodeToString(data))
+	default:
+		b.WriteString(`b64"`)
+		b.WriteString(base64.StdEncoding.EncodeToString(data))
 	}
-	encoded := base64.StdEncoding.EncodeToString(data)
-	b.WriteString(`b64"`)
-	b.WriteString(encoded)
 	b.WriteByte('"')
 }
 
@@ -141,6 +146,7 @@ func EqualLoose(a, b *GValue) bool {
 // hasNonFiniteFloat returns true if v or any descendant Float is NaN or Inf.
 // Used by CanonicalizeLooseErr to enforce D3: NaN/Inf are a hard error in Loose mode.
 func hasNonFiniteFloat(v *GValue) bool {
+	v.force()
 	if v == nil {
 		return false
 	}
@@ -174,13 +180,17 @@ func hasNonFiniteFloat(v *GValue) bool {
 }
 
 // CanonicalizeLooseErr is like CanonicalizeLoose but returns an error for
-// values that contain NaN or Inf (D3: non-finite floats are illegal in Loose mode).
+// values that contain NaN or Inf (D3: non-finite floats are illegal in Loose mode),
+// and for deferred containers (see ParseLooseLazy) that fail to parse.
 // Use this when you need the D3 hard-error guarantee; CanonicalizeLoose itself
 // does not error (for backward compat) but may produce Typed-only tokens.
 func CanonicalizeLooseErr(v *GValue, opts LooseCanonOpts) (string, error) {
 	if v == nil {
 		return canonNullWithStyle(opts.NullStyle), nil
 	}
+	if err := v.MaterializeAll(); err != nil {
+		return "", err
+	}
 	if hasNonFiniteFloat(v) {
 		return "", &ParseError{Message: "non-finite float (NaN/Inf) is not allowed in Loose mode (D3)"}
 	}
@@ -190,7 +200,16 @@ func CanonicalizeLooseErr(v *GValue, opts LooseCanonOpts) (string, error) {
 	if opts.MaxCols == 0 {
 		opts.MaxCols = 20
 	}
-	return canonLooseWithOpts(v, opts), nil
+	out := canonLooseWithOpts(v, opts)
+	if opts.VersionHeader {
+		out = EmitVersionHeader() + "\n" + out
+	}
+	if opts.Verify {
+		if err := verifyLoose(v, out, opts); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
 }
 
 
@@ -218,6 +237,11 @@ func CanonicalizeLooseErr(v *GValue, opts LooseCanonOpts) (string, error) {
 func CanonicalizeLooseWithSchema(v *GValue, opts LooseCanonOpts) string {
 	var b strings.Builder
 
+	if opts.VersionHeader {
+		b.WriteString(EmitVersionHeader())
+		b.WriteByte('\n')
+	}
+
 	// Emit schema header if configured
 	if opts.Schema != nil || opts.SchemaRef != "" || len(opts.KeyDict) > 0 {
 		b.WriteString(emitSchemaHeader(opts))
@@ -227,6 +251,11 @@ func CanonicalizeLooseWithSchema(v *GValue, opts LooseCanonOpts) string {
 	// Emit the value
 	b.WriteString(canonLooseWithOpts(v, opts))
 
+	if opts.Verify {
+		if err := verifyLoose(v, b.String(), opts); err != nil {
+			panic(err)
+		}
+	}
 	return b.String()
 }
 
@@ -279,6 +308,7 @@ func BuildKeyDictFromValue(v *GValue) []string {
 
 // collectKeys recursively collects all map/struct keys.
 func collectKeys(v *GValue, keySet map[string]struct{}) {
+	v.force()
 	if v == nil {
 		return
 	}
@@ -370,6 +400,30 @@ const (
 	NullStyleUnderscore
 )
 
+// BytesEncoding controls how bytes values are emitted.
+type BytesEncoding uint8
+
+const (
+	// BytesBase64 emits b64"..." with standard, padded base64 (canonical default)
+	BytesBase64 BytesEncoding = iota
+	// BytesBase64URL emits b64u"..." with URL-safe, unpadded base64
+	BytesBase64URL
+	// BytesHex emits hex"..." with lowercase hex digits
+	BytesHex
+)
+
+// TableLayout controls how auto-tabular blocks are laid out.
+type TableLayout uint8
+
+const (
+	// TableRowMajor writes @tab blocks, one |cell| line per row (default)
+	TableRowMajor TableLayout = iota
+	// TableColumnMajor writes @tabc blocks, one |cell| line per column
+	TableColumnMajor
+	// TableAutoLayout picks per table whichever costs fewer EstimateTokens
+	TableAutoLayout
+)
+
 // LooseCanonOpts configures loose canonicalization behavior.
 type LooseCanonOpts struct {
 	AutoTabular  bool // Enable tabular detection for homogeneous arrays (default: true)
@@ -378,14 +432,60 @@ type LooseCanonOpts struct {
 	AllowMissing bool // Fill missing keys with null (default: true)
 
 	// v2.4.0: Null style and schema support
-	NullStyle      NullStyle // How to emit null values (default: _)
-	SchemaRef      string    // Optional schema hash/id for @schema header
-	KeyDict        []string  // Optional key dictionary for compact keys
-	UseCompactKeys bool      // Emit #N instead of field names when KeyDict is set
+	NullStyle      NullStyle      // How to emit null values (default: _)
+	SchemaRef      string         // Optional schema hash/id for @schema header
+	KeyDict        []string       // Optional key dictionary for compact keys
+	UseCompactKeys bool           // Emit #N instead of field names when KeyDict is set
+	keyIndex       map[string]int // KeyDict by key, built once per batch
 
 	// v2.6.0: Schema context (alternative to KeyDict)
 	// If set, takes precedence over KeyDict
 	Schema *SchemaContext
+
+	// Types lets auto-tabular emit a list of structs of one struct type
+	// defined here as @tab TypeName, with the type's fields as columns in
+	// FID order, named per TypeKeys (wire keys by default). Rows must not
+	// hold fields the type lacks. Loose parsers read such rows as structs
+	// keyed by column; TabularReader resolves them with the schema. Under
+	// Verify, struct fields holding null count as absent.
+	Types    *Schema
+	TypeKeys KeyMode
+
+	// FieldOrder selects the schema-order canonical profile: struct fields,
+	// and the keys of maps the schema types as structs, are written in the
+	// order their type in FieldOrder declares them (FID order first) rather
+	// than sorted (see canon_order.go). FieldOrderRoot names the type of the
+	// root value; without it, only structs are ordered, by their type
+	// names. Hash with FingerprintLooseProfile, never FingerprintLoose.
+	FieldOrder     *Schema
+	FieldOrderRoot string
+	fieldOrder     *TypeSpec // Type of the value being written, under FieldOrder
+
+	// TableLayout selects row-major @tab or column-major @tabc blocks.
+	// Column-major keeps each column's values together, which suits wide
+	// tables and sorted or repetitive columns. Not canonical; fingerprints
+	// are unaffected since they never use tables.
+	TableLayout TableLayout
+
+	// DedupRows writes a row-major row identical to an earlier row of the
+	// same block as =N, a reference to row N (1-based). Suits logs and
+	// monitoring tables where rows repeat verbatim. Not canonical.
+	DedupRows bool
+
+	// BytesEncoding selects the bytes literal form (default: b64"...").
+	// Non-default encodings are not canonical: hashes and fingerprints
+	// always use b64.
+	BytesEncoding BytesEncoding
+
+	// Verify re-parses the output and reports an *IntegrityError if it does
+	// not round-trip: CanonicalizeLooseErr returns it, the other
+	// canonicalizers panic with it (see integrity.go).
+	Verify bool
+
+	// VersionHeader starts the output with an "@glyph 2.6" line naming
+	// FormatVersion, so readers can tell which syntax to expect (see
+	// version.go). Not canonical; fingerprints never include it.
+	VersionHeader bool
 }
 
 // DefaultLooseCanonOpts returns default options with smart auto-tabular ENABLED.
@@ -472,7 +572,16 @@ func CanonicalizeLooseWithOpts(v *GValue, opts LooseCanonOpts) string {
 		opts.MaxCols = 20
 	}
 
-	return canonLooseWithOpts(v, opts)
+	out := canonLooseWithOpts(v, opts)
+	if opts.VersionHeader {
+		out = EmitVersionHeader() + "\n" + out
+	}
+	if opts.Verify {
+		if err := verifyLoose(v, out, opts); err != nil {
+			panic(err)
+		}
+	}
+	return out
 }
 
 // canonNullWithStyle returns the null representation based on style.
@@ -496,7 +605,7 @@ func writeNullWithStyle(b *strings.Builder, style NullStyle) {
 // This version builds a string and returns it.
 func canonLooseWithOpts(v *GValue, opts LooseCanonOpts) string {
 	b := getPooledBuilder()
-	writeCanonLoose(b, v, opts)
+	writeCanonLoose(b, v, rootFieldOrder(opts))
 	result := b.String()
 	putPooledBuilder(b)
 	return result
@@ -505,10 +614,18 @@ func canonLooseWithOpts(v *GValue, opts LooseCanonOpts) string {
 // writeCanonLoose writes the canonical representation to the builder.
 // This is the core buffer-based implementation that avoids intermediate allocations.
 func writeCanonLoose(b *strings.Builder, v *GValue, opts LooseCanonOpts) {
+	if raw, ok := v.unparsed(); ok {
+		b.WriteString(raw)
+		return
+	}
 	if v == nil {
 		writeNullWithStyle(b, opts.NullStyle)
 		return
 	}
+	if carriesMarker(v) {
+		writeTruncatedLoose(b, v, opts)
+		return
+	}
 
 	switch v.typ {
 	case TypeNull:
@@ -533,11 +650,11 @@ func writeCanonLoose(b *strings.Builder, v *GValue, opts LooseCanonOpts) {
 	case TypeStr:
 		writeCanonString(b, v.strVal)
 	case TypeBytes:
-		writeCanonBytes(b, v.bytesVal)
+		writeCanonBytes(b, v.bytesVal(), opts.BytesEncoding)
 	case TypeTime:
-		b.WriteString(canonTime(v.timeVal))
+		b.WriteString(canonTime(v.timeVal()))
 	case TypeID:
-		writeCanonRef(b, v.idVal)
+		writeCanonRef(b, v.idVal())
 	case TypeList:
 		writeListLoose(b, v.listVal, opts)
 	case TypeMap:
@@ -546,6 +663,8 @@ func writeCanonLoose(b *strings.Builder, v *GValue, opts LooseCanonOpts) {
 		writeStructLoose(b, v.structVal, opts)
 	case TypeSum:
 		writeSumLoose(b, v.sumVal, opts)
+	case TypeCustom:
+		b.WriteString(canonCustom(v.customVal()))
 	default:
 		b.WriteString("∅")
 	}
@@ -610,14 +729,20 @@ func writeListLoose(b *strings.Builder, items []*GValue, opts LooseCanonOpts) {
 		b.WriteString("[]")
 		return
 	}
+	var elem *TypeSpec
+	if opts.fieldOrder != nil && opts.fieldOrder.Kind == TypeSpecList {
+		elem = opts.fieldOrder.Elem
+	}
+	opts.fieldOrder = nil
 
 	// Try tabular detection if enabled
 	if opts.AutoTabular {
-		if cols, ok := detectTabular(items, opts); ok {
-			writeTabularLoose(b, items, cols, opts)
+		if t := planLooseTable(items, opts); t != nil {
+			writeTableLoose(b, items, t, opts)
 			return
 		}
 	}
+	opts.fieldOrder = elem
 
 	// Fall back to standard list format
 	b.WriteByte('[')
@@ -657,15 +782,25 @@ func writeMapLoose(b *strings.Builder, entries []MapEntry, opts LooseCanonOpts)
 	}
 
 	// Sort by pre-computed canonical key
-	sort.Slice(sortable, func(i, j int) bool {
-		return sortable[i].canonKey < sortable[j].canonKey
-	})
+	slices.SortFunc(sortable, compareCanonKeys)
+
+	// Schema-order profile: fields first, in declaration order
+	order := opts.fieldOrder
+	opts.fieldOrder = nil
+	var orderDef *TypeDef
+	if opts.FieldOrder != nil {
+		if orderDef = opts.orderStruct(order); orderDef != nil {
+			sortByFields(sortable, orderDef)
+		}
+	}
 
 	// Build key index map for O(1) lookup (if using compact keys)
 	var keyIndex map[string]int
 	if opts.UseCompactKeys {
 		if opts.Schema != nil {
 			keyIndex = nil // Will use Schema.LookupKey instead
+		} else if opts.keyIndex != nil {
+			keyIndex = opts.keyIndex
 		} else if len(opts.KeyDict) > 0 {
 			keyIndex = make(map[string]int, len(opts.KeyDict))
 			for i, k := range opts.KeyDict {
@@ -699,6 +834,9 @@ func writeMapLoose(b *strings.Builder, entries []MapEntry, opts LooseCanonOpts)
 			b.WriteString(se.canonKey)
 		}
 		b.WriteByte('=')
+		if opts.FieldOrder != nil {
+			opts.fieldOrder = entryOrder(order, orderDef, se.entry.Key)
+		}
 		writeCanonLoose(b, se.entry.Value, opts)
 	}
 	b.WriteByte('}')
@@ -714,6 +852,10 @@ func writeStructLoose(b *strings.Builder, s *StructValue, opts LooseCanonOpts) {
 		b.WriteString("{}")
 		return
 	}
+	if opts.FieldOrder != nil && opts.orderStruct(opts.fieldOrder) == nil && s.TypeName != "" {
+		ts := RefType(s.TypeName)
+		opts.fieldOrder = &ts
+	}
 	writeMapLoose(b, s.Fields, opts)
 }
 
@@ -726,6 +868,9 @@ func writeSumLoose(b *strings.Builder, s *SumValue, opts LooseCanonOpts) {
 	b.WriteByte('{')
 	writeCanonString(b, s.Tag)
 	b.WriteByte('=')
+	if opts.FieldOrder != nil {
+		opts.fieldOrder = opts.variantOrder(opts.fieldOrder, s.Tag)
+	}
 	writeCanonLoose(b, s.Value, opts)
 	b.WriteByte('}')
 }
@@ -740,6 +885,12 @@ type sortableMapEntry struct {
 	entry    MapEntry
 }
 
+// compareCanonKeys orders map entries by canonical key. A plain function,
+// unlike a sort.Slice closure, sorts without allocating.
+func compareCanonKeys(a, b sortableMapEntry) int {
+	return strings.Compare(a.canonKey, b.canonKey)
+}
+
 // sortableCol holds a column name with its pre-computed canonical form for sorting.
 type sortableCol struct {
 	canonKey string
@@ -836,6 +987,7 @@ func detectTabular(items []*GValue, opts LooseCanonOpts) ([]string, bool) {
 
 // getObjectKeys returns the keys of a map/struct/object, or nil if not an object type.
 func getObjectKeys(v *GValue) []string {
+	v.force()
 	if v == nil {
 		return nil
 	}
@@ -863,6 +1015,7 @@ func getObjectKeys(v *GValue) []string {
 
 // getObjectValue returns the value for a key in a map/struct, or nil if not found.
 func getObjectValue(v *GValue, key string) *GValue {
+	v.force()
 	if v == nil {
 		return nil
 	}
@@ -893,6 +1046,31 @@ func getObjectValue(v *GValue, key string) *GValue {
 // writeTabularLoose writes tabular format to the builder.
 // v2.4.0: Includes rows/cols metadata for streaming resync.
 func writeTabularLoose(b *strings.Builder, items []*GValue, cols []string, opts LooseCanonOpts) {
+	writeTableLoose(b, items, untypedLooseTable(cols, opts), opts)
+}
+
+// looseTable is a list auto-tabular writes as a block: the header type
+// ("_" when untyped), the columns, and how to read a cell.
+type looseTable struct {
+	typeName string
+	columns  []string                          // Column names
+	labels   []string                          // Header labels as written (compact keys, quoting)
+	cell     func(item *GValue, i int) *GValue // Column i of item, or nil if absent
+}
+
+// planLooseTable returns the block auto-tabular would write items as, or
+// nil if items stay a list.
+func planLooseTable(items []*GValue, opts LooseCanonOpts) *looseTable {
+	if td := detectTypedTabular(items, opts); td != nil {
+		return typedLooseTable(td, opts)
+	}
+	if cols, ok := detectTabular(items, opts); ok {
+		return untypedLooseTable(cols, opts)
+	}
+	return nil
+}
+
+func untypedLooseTable(cols []string, opts LooseCanonOpts) *looseTable {
 	// Build key index map for O(1) lookup (if using compact keys)
 	var keyIndex map[string]int
 	if opts.UseCompactKeys && len(opts.KeyDict) > 0 {
@@ -902,57 +1080,243 @@ func writeTabularLoose(b *strings.Builder, items []*GValue, cols []string, opts
 		}
 	}
 
+	labels := make([]string, len(cols))
+	for i, col := range cols {
+		if idx, ok := keyIndex[col]; ok {
+			labels[i] = "#" + strconv.Itoa(idx)
+		} else {
+			labels[i] = canonString(col)
+		}
+	}
+	return &looseTable{
+		typeName: "_",
+		columns:  cols,
+		labels:   labels,
+		cell: func(item *GValue, i int) *GValue {
+			return getObjectValue(item, cols[i])
+		},
+	}
+}
+
+func typedLooseTable(td *TypeDef, opts LooseCanonOpts) *looseTable {
+	fields := td.FieldsByFID()
+	labels := make([]string, len(fields))
+	for i, fd := range fields {
+		labels[i] = getColumnName(fd, opts.TypeKeys)
+	}
+	return &looseTable{
+		typeName: td.Name,
+		columns:  labels,
+		labels:   labels,
+		cell: func(item *GValue, i int) *GValue {
+			val := getFieldValue(item, fields[i])
+			if !isFieldPresent(val, fields[i]) {
+				return nil
+			}
+			return val
+		},
+	}
+}
+
+// columnMajor reports whether t is written as @tabc under opts.TableLayout.
+// TableAutoLayout renders both bodies and keeps column-major only if
+// EstimateTokens counts fewer tokens for it.
+func (t *looseTable) columnMajor(items []*GValue, opts LooseCanonOpts) bool {
+	switch opts.TableLayout {
+	case TableColumnMajor:
+		return true
+	case TableAutoLayout:
+		rows, cols := getPooledBuilder(), getPooledBuilder()
+		writeTabularRowsLoose(rows, items, len(t.labels), t.cell, opts)
+		writeTabularColumnsLoose(cols, items, len(t.labels), t.cell, opts)
+		better := EstimateTokens(cols.String()) < EstimateTokens(rows.String())
+		putPooledBuilder(rows)
+		putPooledBuilder(cols)
+		return better
+	}
+	return false
+}
+
+// writeTableLoose writes items as a @tab block, or a @tabc block when t is
+// column-major.
+func writeTableLoose(b *strings.Builder, items []*GValue, t *looseTable, opts LooseCanonOpts) {
+	writeTablePageLoose(b, items, t, "", opts)
+}
+
+// writeTablePageLoose is writeTableLoose with attrs (such as "page=2/7")
+// added to the header after cols=M.
+func writeTablePageLoose(b *strings.Builder, items []*GValue, t *looseTable, attrs string, opts LooseCanonOpts) {
+	columnMajor := t.columnMajor(items, opts)
+
 	// Header: @tab _ rows=N cols=M [col1 col2 ...]
-	b.WriteString("@tab _ rows=")
+	if columnMajor {
+		b.WriteString("@tabc ")
+	} else {
+		b.WriteString("@tab ")
+	}
+	b.WriteString(t.typeName)
+	b.WriteString(" rows=")
 	b.WriteString(strconv.Itoa(len(items)))
 	b.WriteString(" cols=")
-	b.WriteString(strconv.Itoa(len(cols)))
+	b.WriteString(strconv.Itoa(len(t.labels)))
+	if attrs != "" {
+		b.WriteByte(' ')
+		b.WriteString(attrs)
+	}
 	b.WriteString(" [")
-	for i, col := range cols {
-		if i > 0 {
-			b.WriteByte(' ')
+	b.WriteString(strings.Join(t.labels, " "))
+	b.WriteString("]\n")
+
+	if columnMajor {
+		writeTabularColumnsLoose(b, items, len(t.labels), t.cell, opts)
+	} else {
+		writeTabularRowsLoose(b, items, len(t.labels), t.cell, opts)
+	}
+}
+
+// writeTabularRowsLoose writes |cell|...| rows and the @end footer. cell
+// returns column i of item, or nil if item lacks it.
+func writeTabularRowsLoose(b *strings.Builder, items []*GValue, ncols int, cell func(item *GValue, i int) *GValue, opts LooseCanonOpts) {
+	// Rows: |val1|val2|...|
+	// Use a temporary builder for cell values to enable escaping
+	cellBuilder := getPooledBuilder()
+	rowBuilder := getPooledBuilder()
+	var seen map[string]int // Row text -> first row number, with DedupRows
+	if opts.DedupRows {
+		seen = make(map[string]int)
+	}
+	for r, item := range items {
+		rowBuilder.Reset()
+		rowBuilder.WriteByte('|')
+		for i := 0; i < ncols; i++ {
+			if i > 0 {
+				rowBuilder.WriteByte('|')
+			}
+			val := cell(item, i)
+			if val == nil {
+				writeNullWithStyle(rowBuilder, opts.NullStyle)
+			} else {
+				// Write to temp builder, then escape and write to the row
+				cellBuilder.Reset()
+				writeCanonLoose(cellBuilder, val, opts)
+				cellStr := cellBuilder.String()
+				writeEscapedTabularCell(rowBuilder, cellStr)
+			}
 		}
-		if keyIndex != nil {
-			if idx, ok := keyIndex[col]; ok {
-				b.WriteByte('#')
-				b.WriteString(strconv.Itoa(idx))
+		rowBuilder.WriteByte('|')
+		row := rowBuilder.String()
+
+		if seen != nil {
+			if n, ok := seen[row]; ok {
+				if ref := "=" + strconv.Itoa(n); len(ref) < len(row) {
+					row = ref
+				}
 			} else {
-				writeCanonString(b, col)
+				seen[row] = r + 1
 			}
-		} else {
-			writeCanonString(b, col)
 		}
+		b.WriteString(row)
+		b.WriteByte('\n')
 	}
-	b.WriteString("]\n")
+	putPooledBuilder(rowBuilder)
+	putPooledBuilder(cellBuilder)
 
-	// Rows: |val1|val2|...|
-	// Use a temporary builder for cell values to enable escaping
+	// Footer
+	b.WriteString("@end")
+}
+
+// writeTabularColumnsLoose writes one |cell|...| line per column, holding
+// that column's value in every row, and the @end footer.
+func writeTabularColumnsLoose(b *strings.Builder, items []*GValue, ncols int, cell func(item *GValue, i int) *GValue, opts LooseCanonOpts) {
 	cellBuilder := getPooledBuilder()
-	for _, item := range items {
+	for i := 0; i < ncols; i++ {
 		b.WriteByte('|')
-		for i, col := range cols {
-			if i > 0 {
+		for r, item := range items {
+			if r > 0 {
 				b.WriteByte('|')
 			}
-			val := getObjectValue(item, col)
+			val := cell(item, i)
 			if val == nil {
 				writeNullWithStyle(b, opts.NullStyle)
 			} else {
-				// Write to temp builder, then escape and write to main builder
 				cellBuilder.Reset()
 				writeCanonLoose(cellBuilder, val, opts)
-				cellStr := cellBuilder.String()
-				writeEscapedTabularCell(b, cellStr)
+				writeEscapedTabularCell(b, cellBuilder.String())
 			}
 		}
 		b.WriteString("|\n")
 	}
 	putPooledBuilder(cellBuilder)
 
-	// Footer
 	b.WriteString("@end")
 }
 
+// detectTypedTabular returns the struct type of items if they can be
+// emitted as a @tab TypeName block: at least MinRows structs, all of one
+// struct type in opts.Types with at most MaxCols fields, and no row holding
+// a field the type does not define.
+func detectTypedTabular(items []*GValue, opts LooseCanonOpts) *TypeDef {
+	if opts.Types == nil || len(items) < opts.MinRows {
+		return nil
+	}
+	first := items[0]
+	if first == nil || first.typ != TypeStruct || first.structVal == nil {
+		return nil
+	}
+	td := opts.Types.GetType(first.structVal.TypeName)
+	if td == nil || td.Kind != TypeDefStruct || td.Struct == nil || len(td.Struct.Fields) > opts.MaxCols {
+		return nil
+	}
+	for _, item := range items {
+		if item == nil || item.typ != TypeStruct || item.structVal == nil ||
+			item.structVal.TypeName != td.Name {
+			return nil
+		}
+		for _, f := range item.structVal.Fields {
+			if findFieldByColumnName(td, f.Key) == nil {
+				return nil
+			}
+		}
+	}
+	return td
+}
+
+// ResolveTypedColumns renames the fields of structs in v whose type types
+// defines from @tab column names (wire keys, #FIDs) to field names, in
+// place. ParseLoose keeps the column names of an @tab Type table as they
+// were written; with the schema the table was emitted with (see
+// LooseCanonOpts.Types), this gives back the structs that were emitted.
+func ResolveTypedColumns(v *GValue, types *Schema) {
+	v.force()
+	if v == nil {
+		return
+	}
+	switch v.typ {
+	case TypeList:
+		for _, item := range v.listVal {
+			ResolveTypedColumns(item, types)
+		}
+	case TypeMap:
+		for _, e := range v.mapVal {
+			ResolveTypedColumns(e.Value, types)
+		}
+	case TypeStruct:
+		td := types.GetType(v.structVal.TypeName)
+		for i, f := range v.structVal.Fields {
+			if td != nil && td.Kind == TypeDefStruct && td.Struct != nil {
+				if fd := findFieldByColumnName(td, f.Key); fd != nil {
+					v.structVal.Fields[i].Key = fd.Name
+				}
+			}
+			ResolveTypedColumns(f.Value, types)
+		}
+	case TypeSum:
+		if v.sumVal != nil {
+			ResolveTypedColumns(v.sumVal.Value, types)
+		}
+	}
+}
+
 // writeEscapedTabularCell writes an escaped cell value to the builder.
 func writeEscapedTabularCell(b *strings.Builder, s string) {
 	// Fast path: no escaping needed
@@ -1030,40 +1394,77 @@ func unescapeTabularCell(s string) string {
 //	@end
 //
 // Returns a list of maps, where each map has the column names as keys.
+// Header metadata is discarded; use ParseTabularLooseWithMeta to keep it.
 func ParseTabularLoose(input string) (*GValue, error) {
+	v, _, err := ParseTabularLooseWithMeta(input)
+	return v, err
+}
+
+// ParseTabularLooseWithMeta is like ParseTabularLoose but also returns the
+// header metadata, with the row count actually read and warnings for any
+// mismatch between the declared rows=/cols= and the block's contents. A
+// block with fewer rows than declared was most likely truncated.
+func ParseTabularLooseWithMeta(input string) (*GValue, *TabularMetadata, error) {
+	return parseTabularLoose(input, nil)
+}
+
+// parseTabularLoose parses a @tab _ block, resolving #N keys in cell values
+// through keyDict.
+func parseTabularLoose(input string, keyDict []string) (*GValue, *TabularMetadata, error) {
 	lines := strings.Split(input, "\n")
 	if len(lines) == 0 {
-		return nil, fmt.Errorf("empty tabular input")
+		return nil, nil, fmt.Errorf("empty tabular input")
 	}
 
 	// Find and parse header
 	headerIdx := -1
-	var cols []string
+	var meta *TabularMetadata
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		if strings.HasPrefix(line, "@tab _ ") {
+		if isTabHeader(line) {
 			var err error
-			cols, err = parseTabularLooseHeader(line)
+			meta, err = parseTabularLooseHeaderWithMeta(line)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			headerIdx = i
 			break
 		}
-		return nil, fmt.Errorf("expected @tab _ header, got: %s", line)
+		return nil, nil, fmt.Errorf("expected @tab header, got: %s", line)
 	}
 
 	if headerIdx == -1 {
-		return nil, fmt.Errorf("missing @tab _ header")
+		return nil, nil, fmt.Errorf("missing @tab header")
+	}
+
+	// #N column names of an untyped table resolve like map keys; those of
+	// @tab Type name FIDs (see ResolveTypedColumns).
+	if keyDict != nil && meta.Type == "" {
+		for i, k := range meta.Keys {
+			if strings.HasPrefix(k, "#") {
+				if idx, err := parseCompactKeyIndex(k[1:]); err == nil && idx < len(keyDict) {
+					meta.Keys[i] = keyDict[idx]
+				}
+			}
+		}
+	}
+
+	body := lines[headerIdx+1:]
+	if meta.ColumnMajor {
+		var err error
+		if body, err = transposeTabularColumns(body, len(meta.Keys)); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Parse rows
 	var rows []*GValue
-	for i := headerIdx + 1; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
+	var seen []string // Row text, for resolving =N references
+	for i, line := range body {
+		line = strings.TrimSpace(line)
 
 		// Skip empty lines
 		if line == "" {
@@ -1072,44 +1473,79 @@ func ParseTabularLoose(input string) (*GValue, error) {
 
 		// Check for @end
 		if line == "@end" {
+			meta.Ended = true
 			break
 		}
 
 		// Parse row
-		row, err := parseTabularLooseRow(line, cols)
+		line, err := resolveRowRef(line, seen)
 		if err != nil {
-			return nil, fmt.Errorf("row %d: %w", i-headerIdx, err)
+			return nil, nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		seen = append(seen, line)
+		row, err := parseTabularLooseRow(line, meta.Keys, keyDict)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		if meta.Type != "" {
+			row = Struct(meta.Type, row.mapVal...)
 		}
 		rows = append(rows, row)
 	}
 
-	return List(rows...), nil
+	meta.ActualRows = len(rows)
+	if meta.Rows >= 0 && meta.Rows != meta.ActualRows {
+		meta.Warnings = append(meta.Warnings,
+			fmt.Sprintf("header declares rows=%d but block has %d", meta.Rows, meta.ActualRows))
+	}
+	if meta.Cols >= 0 && meta.Cols != len(meta.Keys) {
+		meta.Warnings = append(meta.Warnings,
+			fmt.Sprintf("header declares cols=%d but lists %d columns", meta.Cols, len(meta.Keys)))
+	}
+
+	return List(rows...), meta, nil
 }
 
 // TabularMetadata contains metadata from a tabular header.
 type TabularMetadata struct {
-	Rows int      // Expected row count (-1 if not specified)
-	Cols int      // Expected column count (-1 if not specified)
-	Keys []string // Column names
+	Type        string   // Struct type of a @tab Type block ("" for @tab _)
+	ColumnMajor bool     // @tabc block: one line per column
+	Rows        int      // Expected row count (-1 if not specified)
+	Cols        int      // Expected column count (-1 if not specified)
+	Keys        []string // Column names
+	Page        int      // page=P of a paginated table (0 if not paged)
+	Pages       int      // page=P/N: total page count (0 if not paged)
+	Cont        string   // cont= token for the next page ("" on the last)
+
+	// Filled in by ParseTabularLooseWithMeta.
+	ActualRows int      // Rows actually read
+	Ended      bool     // Block was closed by @end
+	Warnings   []string // Declared vs actual mismatches
 }
 
-// parseTabularLooseHeader parses: @tab _ [col1 col2 col3]
-// Also accepts v2.4.0 format: @tab _ rows=N cols=M [col1 col2 col3]
-func parseTabularLooseHeader(line string) ([]string, error) {
-	meta, err := parseTabularLooseHeaderWithMeta(line)
-	if err != nil {
-		return nil, err
-	}
-	return meta.Keys, nil
+// Truncated reports whether fewer rows were read than the header declared.
+func (m *TabularMetadata) Truncated() bool {
+	return m.Rows >= 0 && m.ActualRows < m.Rows
 }
 
-// parseTabularLooseHeaderWithMeta parses header with full metadata.
+// parseTabularLooseHeaderWithMeta parses: @tab _ [col1 col2 col3]
+// Also accepts v2.4.0 format: @tab _ rows=N cols=M [col1 col2 col3]
+// and a struct type in place of _: @tab Type rows=N cols=M [col1 col2]
 func parseTabularLooseHeaderWithMeta(line string) (*TabularMetadata, error) {
-	// Remove @tab _ prefix
-	rest := strings.TrimPrefix(line, "@tab _ ")
-	rest = strings.TrimSpace(rest)
+	// Remove @tab or @tabc prefix and the type (_ for untyped)
+	columnMajor := strings.HasPrefix(line, "@tabc ")
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "@tabc "), "@tab "))
+	typeEnd := strings.IndexAny(rest, " [")
+	if typeEnd <= 0 {
+		return nil, fmt.Errorf("expected type or _ after @tab, got: %s", rest)
+	}
+	typeName := rest[:typeEnd]
+	rest = strings.TrimSpace(rest[typeEnd:])
 
-	meta := &TabularMetadata{Rows: -1, Cols: -1}
+	meta := &TabularMetadata{Rows: -1, Cols: -1, ColumnMajor: columnMajor}
+	if typeName != "_" {
+		meta.Type = typeName
+	}
 
 	// Parse optional rows=N and cols=M before [
 	for !strings.HasPrefix(rest, "[") && len(rest) > 0 {
@@ -1135,6 +1571,25 @@ func parseTabularLooseHeaderWithMeta(line string) (*TabularMetadata, error) {
 				meta.Cols = n
 			}
 			rest = strings.TrimSpace(rest[end:])
+		} else if strings.HasPrefix(rest, "page=") {
+			rest = rest[5:]
+			end := strings.IndexAny(rest, " [")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid page= value")
+			}
+			if _, err := fmt.Sscanf(rest[:end], "%d/%d", &meta.Page, &meta.Pages); err != nil ||
+				meta.Page < 1 || meta.Page > meta.Pages {
+				return nil, fmt.Errorf("invalid page= value: %s", rest[:end])
+			}
+			rest = strings.TrimSpace(rest[end:])
+		} else if strings.HasPrefix(rest, "cont=") {
+			rest = rest[5:]
+			end := strings.IndexAny(rest, " [")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid cont= value")
+			}
+			meta.Cont = rest[:end]
+			rest = strings.TrimSpace(rest[end:])
 		} else {
 			// Skip unknown attributes
 			spaceIdx := strings.IndexByte(rest, ' ')
@@ -1173,11 +1628,25 @@ func parseTabularLooseHeaderWithMeta(line string) (*TabularMetadata, error) {
 	return meta, nil
 }
 
+// resolveRowRef returns the text of the row that a =N line refers to,
+// given the text of the rows before it. Any other line is returned as is.
+func resolveRowRef(line string, seen []string) (string, error) {
+	if !strings.HasPrefix(line, "=") {
+		return line, nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(seen) {
+		return "", fmt.Errorf("invalid row reference %s after %d rows", line, len(seen))
+	}
+	return seen[n-1], nil
+}
+
 // parseTabularLooseRow parses: |val1|val2|val3|
-func parseTabularLooseRow(line string, cols []string) (*GValue, error) {
-	// Must start and end with |
+// A row not starting with '|' is read as space-separated values, the row
+// grammar of EmitTabular.
+func parseTabularLooseRow(line string, cols []string, keyDict []string) (*GValue, error) {
 	if !strings.HasPrefix(line, "|") {
-		return nil, fmt.Errorf("row must start with '|'")
+		return parseSpacedTabularRow(line, cols)
 	}
 	if !strings.HasSuffix(line, "|") {
 		return nil, fmt.Errorf("row must end with '|'")
@@ -1208,6 +1677,9 @@ func parseTabularLooseRow(line string, cols []string) (*GValue, error) {
 
 		// Parse the cell value as GLYPH loose
 		val, err := parseLooseValue(cellStr)
+		if keyDict != nil && strings.TrimSpace(cellStr) != "" {
+			val, err = parseLooseValueWithDict(cellStr, keyDict)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("cell %d (%s): %w", i, col, err)
 		}
@@ -1218,6 +1690,79 @@ func parseTabularLooseRow(line string, cols []string) (*GValue, error) {
 	return Map(entries...), nil
 }
 
+// isTabHeader reports whether s starts a @tab or @tabc block.
+func isTabHeader(s string) bool {
+	return strings.HasPrefix(s, "@tab ") || strings.HasPrefix(s, "@tabc ")
+}
+
+// transposeTabularColumns turns the column lines of a @tabc block, up to
+// and including @end, into the equivalent |cell| row lines. Every column
+// must hold the same number of cells.
+func transposeTabularColumns(lines []string, ncols int) ([]string, error) {
+	var columns [][]string
+	var rest []string
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "@end" {
+			rest = lines[i:]
+			break
+		}
+		if len(line) < 2 || line[0] != '|' || line[len(line)-1] != '|' {
+			return nil, fmt.Errorf("column %d: must be |cell|...|", len(columns))
+		}
+		cells := splitTabularCells(line[1 : len(line)-1])
+		if len(columns) > 0 && len(cells) != len(columns[0]) {
+			return nil, fmt.Errorf("column %d: expected %d cells, got %d", len(columns), len(columns[0]), len(cells))
+		}
+		columns = append(columns, cells)
+	}
+	if len(columns) != ncols {
+		return nil, fmt.Errorf("expected %d columns, got %d", ncols, len(columns))
+	}
+	if len(columns) == 0 {
+		return rest, nil
+	}
+
+	rows := make([]string, 0, len(columns[0])+len(rest))
+	var b strings.Builder
+	for r := range columns[0] {
+		b.Reset()
+		b.WriteByte('|')
+		for c, col := range columns {
+			if c > 0 {
+				b.WriteByte('|')
+			}
+			b.WriteString(col[r])
+		}
+		b.WriteByte('|')
+		rows = append(rows, b.String())
+	}
+	return append(rows, rest...), nil
+}
+
+// parseSpacedTabularRow parses a row of space-separated values, as written
+// by EmitTabular. Missing trailing values are null. Nested packed structs
+// need a schema and are rejected.
+func parseSpacedTabularRow(line string, cols []string) (*GValue, error) {
+	p := &tabularRowParser{input: line}
+	entries := make([]MapEntry, len(cols))
+	for i, col := range cols {
+		val, err := p.parseValue(nil)
+		if err != nil {
+			return nil, fmt.Errorf("cell %d (%s): %w", i, col, err)
+		}
+		entries[i] = MapEntry{Key: col, Value: val}
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("extra values starting at %d", p.pos+1)
+	}
+	return Map(entries...), nil
+}
+
 // splitTabularCells splits a row by | respecting \| escapes.
 func splitTabularCells(s string) []string {
 	var cells []string
@@ -1260,6 +1805,17 @@ func parseLooseValue(s string) (*GValue, error) {
 		return Null(), nil
 	}
 
+	// Truncation marker standing for the whole value
+	if omitted, rest, ok, err := cutOmission(s); ok && rest == "" || err != nil {
+		return withOmitted(Null(), omitted), err
+	}
+
+	// Embedded tabular block: @tab _ [cols] ... @end
+	if isTabHeader(s) {
+		v, _, err := parseTabularLoose(s, nil)
+		return v, err
+	}
+
 	// Bool
 	if s == "t" {
 		return Bool(true), nil
@@ -1318,14 +1874,28 @@ func parseLooseValue(s string) (*GValue, error) {
 		return val, nil
 	}
 
-	// Bytes literal: b64"<base64>" (D6)
-	if strings.HasPrefix(s, `b64"`) && strings.HasSuffix(s, `"`) && len(s) >= 5 {
-		body := s[4 : len(s)-1]
-		decoded, err := base64.StdEncoding.DecodeString(body)
+	// Bytes literal: b64"<base64>" (D6), or the b64u/b85/hex aliases
+	if prefix := bytesLiteralPrefix(s); prefix != "" && strings.HasSuffix(s, `"`) && len(s) >= len(prefix)+2 {
+		return decodeBytesLiteral(prefix, s[len(prefix)+1:len(s)-1])
+	}
+
+	// Geo literal: geo5"x y;dx dy"
+	if prefix := geoLiteralPrefix(s); prefix != "" && strings.HasSuffix(s, `"`) && len(s) >= len(prefix)+2 {
+		return decodeGeoLiteral(prefix, s[len(prefix)+1:len(s)-1])
+	}
+
+	// Vector literal: vec16"<base64>"
+	if prefix := vecLiteralPrefix(s); prefix != "" && strings.HasSuffix(s, `"`) && len(s) >= len(prefix)+2 {
+		return decodeVecLiteral(prefix, s[len(prefix)+1:len(s)-1])
+	}
+
+	// Custom scalar literal: money"12.34 USD"
+	if tag := scalarLiteralPrefix(s); tag != "" && strings.HasSuffix(s, `"`) && len(s) >= len(tag)+2 {
+		text, err := unquoteString(s[len(tag):])
 		if err != nil {
-			return nil, fmt.Errorf("invalid base64 in bytes literal: %v", err)
+			return nil, err
 		}
-		return Bytes(decoded), nil
+		return decodeScalarLiteral(tag, text)
 	}
 
 	// Bare string
@@ -1335,13 +1905,21 @@ func parseLooseValue(s string) (*GValue, error) {
 // unquoteString removes quotes and unescapes a string.
 // It supports the standard GLYPH escapes (\n \r \t \\ \") and \uXXXX
 // unicode escapes (used by the canonical emitter for control characters).
+// s must be exactly one quoted string. Raw strings ("""...""") are GLYPH-T
+// syntax and are rejected rather than read as "" followed by junk.
 func unquoteString(s string) (string, error) {
+	if strings.HasPrefix(s, `"""`) {
+		return "", fmt.Errorf("raw string %q not supported in loose mode; use a quoted string", s)
+	}
 	// Delegate to the shared cursor-based parser which handles \uXXXX.
 	// parseQuotedStringShared expects to start at the opening '"'.
-	result, _, err := parseQuotedStringShared(s, 0)
+	result, end, err := parseQuotedStringShared(s, 0)
 	if err != nil {
 		return "", fmt.Errorf("invalid quoted string %q: %w", s, err)
 	}
+	if end != len(s) {
+		return "", fmt.Errorf("invalid quoted string %q: text after closing quote", s)
+	}
 	return result, nil
 }
 
@@ -1401,6 +1979,12 @@ func parseLooseMap(s string) (*GValue, error) {
 
 // parseLooseMapWithDict parses a map with optional key dictionary for compact keys.
 func parseLooseMapWithDict(s string, keyDict []string) (*GValue, error) {
+	return parseLooseMapLazy(s, keyDict, 0)
+}
+
+// parseLooseMapLazy parses a map, deferring child containers whose source is
+// at least lazy bytes long (see ParseLooseLazy). lazy <= 0 parses everything.
+func parseLooseMapLazy(s string, keyDict []string, lazy int) (*GValue, error) {
 	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
 		return nil, fmt.Errorf("invalid map: %s", s)
 	}
@@ -1412,7 +1996,17 @@ func parseLooseMapWithDict(s string, keyDict []string) (*GValue, error) {
 
 	// Parse key=value pairs
 	var entries []MapEntry
+	omitted := 0
 	for len(inner) > 0 {
+		if n, rest, ok, err := cutOmission(inner); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			omitted = addOmitted(omitted, n)
+			inner = strings.TrimSpace(rest)
+			continue
+		}
+
 		// Find key
 		eqIdx := findUnnestedChar(inner, '=')
 		if eqIdx == -1 {
@@ -1443,12 +2037,22 @@ func parseLooseMapWithDict(s string, keyDict []string) (*GValue, error) {
 
 		rest := inner[eqIdx+1:]
 
+		// A marker eliding the whole value may contain a space (@omitted n=K)
+		if n, after, ok, err := cutOmission(strings.TrimLeft(rest, " ")); ok || err != nil {
+			if err != nil {
+				return nil, fmt.Errorf("map value for %s: %w", key, err)
+			}
+			entries = append(entries, MapEntry{Key: key, Value: withOmitted(Null(), n)})
+			inner = strings.TrimSpace(after)
+			continue
+		}
+
 		// Find value (ends at space or end of string, respecting nesting)
 		valEnd := findValueEnd(rest)
 		valStr := strings.TrimSpace(rest[:valEnd])
 
 		// Parse value with key dictionary for nested structures
-		val, err := parseLooseValueWithDict(valStr, keyDict)
+		val, err := parseLooseValueLazy(valStr, keyDict, lazy)
 		if err != nil {
 			return nil, fmt.Errorf("map value for %s: %w", key, err)
 		}
@@ -1459,7 +2063,7 @@ func parseLooseMapWithDict(s string, keyDict []string) (*GValue, error) {
 		inner = strings.TrimSpace(rest[valEnd:])
 	}
 
-	return Map(entries...), nil
+	return withOmitted(Map(entries...), omitted), nil
 }
 
 // parseCompactKeyIndex parses a compact key index from "#N" format.
@@ -1490,7 +2094,17 @@ func parseLooseList(s string) (*GValue, error) {
 
 	// Parse values
 	var items []*GValue
+	omitted := 0
 	for len(inner) > 0 {
+		if n, rest, ok, err := cutOmission(inner); ok || err != nil {
+			if err != nil {
+				return nil, fmt.Errorf("list element: %w", err)
+			}
+			omitted = addOmitted(omitted, n)
+			inner = strings.TrimSpace(rest)
+			continue
+		}
+
 		// Find value end
 		valEnd := findValueEnd(inner)
 		valStr := strings.TrimSpace(inner[:valEnd])
@@ -1507,7 +2121,7 @@ func parseLooseList(s string) (*GValue, error) {
 		inner = strings.TrimSpace(inner[valEnd:])
 	}
 
-	return List(items...), nil
+	return withOmitted(List(items...), omitted), nil
 }
 
 // findUnnestedChar finds the first occurrence of char not inside {} [] or "".
@@ -1566,6 +2180,15 @@ func findValueEnd(s string) int {
 			continue
 		}
 
+		if c == '@' && isTabHeader(s[i:]) {
+			end := tabBlockEnd(s, i)
+			if depth == 0 {
+				return end
+			}
+			i = end - 1
+			continue
+		}
+
 		if c == '{' || c == '[' {
 			depth++
 		} else if c == '}' || c == ']' {
@@ -1578,6 +2201,36 @@ func findValueEnd(s string) int {
 	return len(s)
 }
 
+// tabBlockEnd returns the index just past the @tab block that starts at
+// start. After the header line, every line starting with '|' is a row, as
+// is a =N row reference; the block ends after a line starting with @end, or
+// before the first line that is neither (the rest of the enclosing
+// container). Cells never contain a raw newline, so these rules cannot be
+// fooled by cell contents.
+func tabBlockEnd(s string, start int) int {
+	nl := strings.IndexByte(s[start:], '\n')
+	if nl < 0 {
+		return len(s)
+	}
+	pos := start + nl
+	for pos < len(s) {
+		line := strings.TrimLeft(s[pos+1:], " \t")
+		switch {
+		case strings.HasPrefix(line, "@end"):
+			return len(s) - len(line) + len("@end")
+		case strings.HasPrefix(line, "|"), strings.HasPrefix(line, "="):
+			next := strings.IndexByte(line, '\n')
+			if next < 0 {
+				return len(s)
+			}
+			pos = len(s) - len(line) + next
+		default:
+			return pos
+		}
+	}
+	return len(s)
+}
+
 // ParseLoosePayload parses a GLYPH payload that may include a @schema directive.
 // Returns the parsed value, the schema context (if any), and any error.
 //
@@ -1586,8 +2239,17 @@ func findValueEnd(s string) int {
 //   - @schema#id\n{...} - schema reference (registry lookup)
 //   - @schema.clear\n{...} - clear active schema
 //   - {...} - regular value (no schema)
+//
+// The active schema is kept in registry and shared by all its callers; a
+// ParseSession keeps it per stream instead.
 func ParseLoosePayload(input string, registry *SchemaRegistry) (*GValue, *SchemaContext, error) {
+	return registrySession(registry, 0).parsePayload(input)
+}
+
+// parsePayload implements ParseLoosePayload and ParseSession.ParsePayload.
+func (s *ParseSession) parsePayload(input string) (*GValue, *SchemaContext, error) {
 	input = strings.TrimSpace(input)
+	lazy := s.lazy
 
 	// Check for @schema directive
 	if strings.HasPrefix(input, "@schema") {
@@ -1613,72 +2275,85 @@ func ParseLoosePayload(input string, registry *SchemaRegistry) (*GValue, *Schema
 
 		// Handle @schema.clear
 		if directive == "@schema.clear" {
-			if registry != nil {
-				registry.ClearActive()
-			}
+			s.ClearActive()
 			// Parse the value without a schema
-			val, err := parseLooseValue(valueStr)
+			val, err := parseLazyRoot(valueStr, nil, lazy)
 			return val, nil, err
 		}
 
 		// If defining, register the schema
-		if isDef && registry != nil {
-			registry.Define(ctx)
-		} else if !isDef && registry != nil {
+		if isDef {
+			s.define(ctx)
+		} else {
 			// Reference only - lookup from registry
-			existing := registry.Get(ctx.ID)
+			existing, err := s.use(ctx.ID)
+			if err != nil {
+				return nil, nil, err
+			}
 			if existing != nil {
 				ctx = existing
-				registry.SetActive(ctx.ID)
-			} else {
-				return nil, nil, fmt.Errorf("schema not found: %s", ctx.ID)
 			}
 		}
 
 		// Parse value with schema context
-		val, err := parseLooseValueWithSchema(valueStr, ctx)
+		val, err := parseLazyRoot(valueStr, schemaKeys(ctx), lazy)
 		return val, ctx, err
 	}
 
 	// No schema directive - parse normally
-	var ctx *SchemaContext
-	if registry != nil {
-		ctx = registry.Active()
-	}
-
-	if ctx != nil {
-		val, err := parseLooseValueWithSchema(input, ctx)
+	if ctx := s.Active(); ctx != nil {
+		val, err := parseLazyRoot(input, schemaKeys(ctx), lazy)
 		return val, ctx, err
 	}
 
+	if lazy > 0 {
+		val, err := parseLazyRoot(input, nil, lazy)
+		return val, nil, err
+	}
 	val, err := parseLooseValue(input)
 	return val, nil, err
 }
 
-// parseLooseValueWithSchema parses a loose value with schema context for key resolution.
-func parseLooseValueWithSchema(s string, schema *SchemaContext) (*GValue, error) {
-	var keyDict []string
-	if schema != nil {
-		keyDict = schema.Keys
+// schemaKeys returns the key dictionary of schema, or nil.
+func schemaKeys(schema *SchemaContext) []string {
+	if schema == nil {
+		return nil
 	}
-	return parseLooseValueWithDict(s, keyDict)
+	return schema.Keys
 }
 
 // parseLooseValueWithDict parses a loose value with optional key dictionary.
 func parseLooseValueWithDict(s string, keyDict []string) (*GValue, error) {
+	return parseLooseValueLazy(s, keyDict, 0)
+}
+
+// parseLooseValueLazy parses a loose value, deferring it and any nested
+// container whose source is at least lazy bytes long (see ParseLooseLazy).
+func parseLooseValueLazy(s string, keyDict []string, lazy int) (*GValue, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return nil, fmt.Errorf("empty input")
 	}
+	if lazy > 0 && len(s) >= lazy {
+		if v := deferLoose(s, keyDict, lazy); v != nil {
+			return v, nil
+		}
+	}
 
 	// Nested map with key dictionary
 	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
-		return parseLooseMapWithDict(s, keyDict)
+		return parseLooseMapLazy(s, keyDict, lazy)
 	}
 
 	// Nested list
 	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
-		return parseLooseListWithDict(s, keyDict)
+		return parseLooseListLazy(s, keyDict, lazy)
+	}
+
+	// Embedded tabular block
+	if isTabHeader(s) {
+		v, _, err := parseTabularLoose(s, keyDict)
+		return v, err
 	}
 
 	// Everything else - no key dictionary needed
@@ -1696,6 +2371,12 @@ func parseLooseListWithSchema(s string, schema *SchemaContext) (*GValue, error)
 
 // parseLooseListWithDict parses a list with optional key dictionary.
 func parseLooseListWithDict(s string, keyDict []string) (*GValue, error) {
+	return parseLooseListLazy(s, keyDict, 0)
+}
+
+// parseLooseListLazy parses a list, deferring large child containers like
+// parseLooseMapLazy.
+func parseLooseListLazy(s string, keyDict []string, lazy int) (*GValue, error) {
 	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
 		return nil, fmt.Errorf("invalid list: %s", s)
 	}
@@ -1706,11 +2387,21 @@ func parseLooseListWithDict(s string, keyDict []string) (*GValue, error) {
 	}
 
 	var elements []*GValue
+	omitted := 0
 	for len(inner) > 0 {
+		if n, rest, ok, err := cutOmission(inner); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			omitted = addOmitted(omitted, n)
+			inner = strings.TrimSpace(rest)
+			continue
+		}
+
 		valEnd := findValueEnd(inner)
 		valStr := strings.TrimSpace(inner[:valEnd])
 
-		val, err := parseLooseValueWithDict(valStr, keyDict)
+		val, err := parseLooseValueLazy(valStr, keyDict, lazy)
 		if err != nil {
 			return nil, err
 		}
@@ -1719,5 +2410,5 @@ func parseLooseListWithDict(s string, keyDict []string) (*GValue, error) {
 		inner = strings.TrimSpace(inner[valEnd:])
 	}
 
-	return List(elements...), nil
+	return withOmitted(List(elements...), omitted), nil
 }
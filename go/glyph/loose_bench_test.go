@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 )
 
@@ -765,3 +766,76 @@ func BenchmarkEscapeTabularCell_NoPipes(b *testing.B) {
 		_ = escapeTabularCell(s)
 	}
 }
+
+// ============================================================
+// Batch Benchmarks
+// ============================================================
+//
+// Thousands of small tool results per request, with the session's key
+// dictionary. Compare:
+//   go test -tags heavy -bench='BenchmarkCanonicalize(Batch|Loop)_ToolResults' -benchmem ./go/glyph/
+
+// toolResultKeys is the key dictionary of toolResults.
+var toolResultKeys = []string{
+	"args", "call_id", "content", "duration_ms", "error", "exit_code",
+	"id", "is_error", "mime", "name", "path", "result", "score", "size",
+	"status", "stderr", "stdout", "title", "tool", "url",
+}
+
+// toolResults returns n small tool results.
+func toolResults(n int) []*GValue {
+	vs := make([]*GValue, n)
+	for i := range vs {
+		vs[i] = Map(
+			MapEntry{Key: "call_id", Value: Str("call_" + strconv.Itoa(i))},
+			MapEntry{Key: "tool", Value: Str("read_file")},
+			MapEntry{Key: "is_error", Value: Bool(i%17 == 0)},
+			MapEntry{Key: "duration_ms", Value: Int(int64(i % 250))},
+			MapEntry{Key: "result", Value: Map(
+				MapEntry{Key: "path", Value: Str("src/pkg/file_" + strconv.Itoa(i%40) + ".go")},
+				MapEntry{Key: "size", Value: Int(int64(1024 + i))},
+				MapEntry{Key: "status", Value: Str("ok")},
+			)},
+		)
+	}
+	return vs
+}
+
+func toolResultOpts() LooseCanonOpts {
+	opts := DefaultLooseCanonOpts()
+	opts.KeyDict = toolResultKeys
+	opts.UseCompactKeys = true
+	return opts
+}
+
+// BenchmarkCanonicalizeLoop_ToolResults canonicalizes 1000 tool results one
+// call at a time.
+func BenchmarkCanonicalizeLoop_ToolResults(b *testing.B) {
+	vs, opts := toolResults(1000), toolResultOpts()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range vs {
+			_ = CanonicalizeLooseWithOpts(v, opts)
+		}
+	}
+}
+
+// BenchmarkCanonicalizeBatch_ToolResults canonicalizes the same results as
+// one batch.
+func BenchmarkCanonicalizeBatch_ToolResults(b *testing.B) {
+	vs, opts := toolResults(1000), toolResultOpts()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = CanonicalizeBatch(vs, opts)
+	}
+}
+
+// BenchmarkCanonicalizeBatchParallel_ToolResults spreads the batch over
+// GOMAXPROCS workers.
+func BenchmarkCanonicalizeBatchParallel_ToolResults(b *testing.B) {
+	vs, opts := toolResults(1000), toolResultOpts()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = CanonicalizeBatchParallel(vs, opts, 0)
+	}
+}
@@ -644,6 +644,256 @@ func TestAutoTabular_StructsAsObjects(t *testing.T) {
 	}
 }
 
+func TestAutoTabular_TypedStructs(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Entry", "v1",
+			Field("id", PrimitiveType("int"), WithFID(1), WithWireKey("i")),
+			Field("name", PrimitiveType("str"), WithFID(2), WithWireKey("n")),
+			Field("notes", PrimitiveType("str"), WithFID(3), WithOptional()),
+		).
+		Build()
+	items := List(
+		Struct("Entry", FieldVal("name", Str("A")), FieldVal("id", Int(1)), FieldVal("notes", Str("x|y"))),
+		Struct("Entry", FieldVal("id", Int(2)), FieldVal("name", Str("B"))),
+		Struct("Entry", FieldVal("id", Int(3)), FieldVal("n", Str("C"))),
+	)
+	doc := Map(MapEntry{Key: "entries", Value: items})
+
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+	opts.Verify = true
+	got := CanonicalizeLooseWithOpts(doc, opts)
+	want := "{entries=@tab Entry rows=3 cols=3 [i n notes]\n|1|A|\"x\\|y\"|\n|2|B|_|\n|3|C|_|\n@end}"
+	if got != want {
+		t.Fatalf("typed @tab:\nGot:\n%s\n\nWant:\n%s", got, want)
+	}
+
+	opts.TypeKeys = KeyModeFID
+	fids := CanonicalizeLooseWithOpts(items, opts)
+	if !strings.HasPrefix(fids, "@tab Entry rows=3 cols=3 [#1 #2 #3]\n") {
+		t.Errorf("FID columns:\n%s", fids)
+	}
+
+	// Loose parsers keep the type and the column names.
+	parsed, err := ParseLoose(fids, nil)
+	if err != nil {
+		t.Fatalf("ParseLoose: %v", err)
+	}
+	row := parsed.listVal[1]
+	if row.Type() != TypeStruct || row.structVal.TypeName != "Entry" || row.Get("#1").intVal != 2 {
+		t.Fatalf("parsed row = %s", CanonicalizeLooseNoTabular(row))
+	}
+	ResolveTypedColumns(parsed, schema)
+	if name, _ := parsed.listVal[2].Get("name").AsStr(); name != "C" {
+		t.Errorf("resolved row = %s", CanonicalizeLooseNoTabular(parsed.listVal[2]))
+	}
+}
+
+func TestAutoTabular_TypedRoundTrip(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Row", "v1",
+			Field("id", PrimitiveType("int"), WithWireKey("i")),
+			Field("name", PrimitiveType("str"), WithWireKey("n")),
+			Field("note", PrimitiveType("str"), WithWireKey("x"), WithOptional()),
+		).
+		Build()
+	rows := List(
+		Struct("Row", FieldVal("id", Int(1)), FieldVal("name", Str("a")), FieldVal("note", Str("p"))),
+		Struct("Row", FieldVal("id", Int(2)), FieldVal("name", Str("b")), FieldVal("note", Str("|"))),
+		Struct("Row", FieldVal("id", Int(3)), FieldVal("name", Str("c")), FieldVal("note", Str("q"))),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+	text := CanonicalizeLooseWithOpts(rows, opts)
+	if !strings.HasPrefix(text, "@tab Row rows=3 cols=3 [i n x]\n") {
+		t.Fatalf("emitted:\n%s", text)
+	}
+
+	parsed, err := ParseLoose(text, nil)
+	if err != nil {
+		t.Fatalf("ParseLoose: %v", err)
+	}
+	ResolveTypedColumns(parsed, schema)
+	got, want := CanonicalizeLooseNoTabular(parsed), CanonicalizeLooseNoTabular(rows)
+	if got != want {
+		t.Errorf("round trip:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestAutoTabular_TypedFallback(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Entry", "v1",
+			Field("id", PrimitiveType("int"), WithFID(1)),
+		).
+		Build()
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+
+	tests := []struct {
+		name  string
+		items *GValue
+	}{
+		{"unknown type", List(
+			Struct("Other", FieldVal("id", Int(1))),
+			Struct("Other", FieldVal("id", Int(2))),
+			Struct("Other", FieldVal("id", Int(3))),
+		)},
+		{"mixed types", List(
+			Struct("Entry", FieldVal("id", Int(1))),
+			Struct("Entry", FieldVal("id", Int(2))),
+			Struct("Other", FieldVal("id", Int(3))),
+		)},
+		{"undeclared field", List(
+			Struct("Entry", FieldVal("id", Int(1))),
+			Struct("Entry", FieldVal("id", Int(2)), FieldVal("extra", Int(0))),
+			Struct("Entry", FieldVal("id", Int(3))),
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CanonicalizeLooseWithOpts(tt.items, opts)
+			if !strings.HasPrefix(got, "@tab _ ") {
+				t.Errorf("expected untyped @tab, got:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestAutoTabular_ColumnMajor(t *testing.T) {
+	items := List(
+		Map(MapEntry{Key: "id", Value: Int(1)}, MapEntry{Key: "tag", Value: Str("a|b")}),
+		Map(MapEntry{Key: "id", Value: Int(2)}),
+		Map(MapEntry{Key: "id", Value: Int(3)}, MapEntry{Key: "tag", Value: Str("c")}),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.TableLayout = TableColumnMajor
+
+	got := CanonicalizeLooseWithOpts(items, opts)
+	want := "@tabc _ rows=3 cols=2 [id tag]\n|1|2|3|\n|\"a\\|b\"|_|c|\n@end"
+	if got != want {
+		t.Fatalf("column-major:\nGot:\n%s\n\nWant:\n%s", got, want)
+	}
+
+	rows, meta, err := ParseTabularLooseWithMeta(got)
+	if err != nil {
+		t.Fatalf("ParseTabularLooseWithMeta: %v", err)
+	}
+	if !meta.ColumnMajor || !meta.Ended || meta.ActualRows != 3 {
+		t.Errorf("meta = %+v", meta)
+	}
+	if s, _ := rows.listVal[0].Get("tag").AsStr(); s != "a|b" {
+		t.Errorf("row 0 = %s", CanonicalizeLooseNoTabular(rows.listVal[0]))
+	}
+
+	// Embedded, and lazily parsed.
+	doc := Map(MapEntry{Key: "items", Value: items}, MapEntry{Key: "z", Value: Int(9)})
+	out := CanonicalizeLooseWithOpts(doc, opts)
+	lazy, err := ParseLooseLazy(out, nil, 1)
+	if err != nil {
+		t.Fatalf("ParseLooseLazy: %v", err)
+	}
+	if err := lazy.MaterializeAll(); err != nil {
+		t.Fatalf("MaterializeAll: %v", err)
+	}
+	if z, _ := lazy.Get("z").AsInt(); z != 9 || len(lazy.Get("items").listVal) != 3 {
+		t.Errorf("embedded @tabc parsed as %s", CanonicalizeLooseNoTabular(lazy))
+	}
+
+	// Ragged columns are rejected.
+	if _, err := ParseTabularLoose("@tabc _ [a b]\n|1|2|\n|3|\n@end"); err == nil {
+		t.Error("expected error for ragged columns")
+	}
+}
+
+func TestAutoTabular_DedupRows(t *testing.T) {
+	logRow := func(level, msg string) *GValue {
+		return Map(MapEntry{Key: "level", Value: Str(level)}, MapEntry{Key: "msg", Value: Str(msg)})
+	}
+	items := List(
+		logRow("info", "tick"),
+		logRow("warn", "slow disk"),
+		logRow("info", "tick"),
+		logRow("info", "tick"),
+		logRow("warn", "slow disk"),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.DedupRows = true
+	opts.Verify = true
+
+	got := CanonicalizeLooseWithOpts(items, opts)
+	want := "@tab _ rows=5 cols=2 [level msg]\n|info|tick|\n|warn|\"slow disk\"|\n=1\n=1\n=2\n@end"
+	if got != want {
+		t.Fatalf("dedup:\nGot:\n%s\n\nWant:\n%s", got, want)
+	}
+
+	// Embedded, eagerly and lazily parsed.
+	doc := Map(MapEntry{Key: "logs", Value: items}, MapEntry{Key: "n", Value: Int(5)})
+	out := CanonicalizeLooseWithOpts(doc, opts)
+	back, err := ParseLoose(out, nil)
+	if err != nil || !EqualLoose(back, doc) {
+		t.Fatalf("ParseLoose: %v\n%s", err, out)
+	}
+	lazy, err := ParseLooseLazy(out, nil, 1)
+	if err != nil {
+		t.Fatalf("ParseLooseLazy: %v", err)
+	}
+	if err := lazy.MaterializeAll(); err != nil || !EqualLoose(lazy, doc) {
+		t.Errorf("lazy parse: %v", err)
+	}
+
+	// Referenced rows are separate values.
+	back.Get("logs").listVal[2].mapVal[1].Value = Str("changed")
+	if s, _ := back.Get("logs").listVal[0].Get("msg").AsStr(); s != "tick" {
+		t.Errorf("row 1 changed through its reference: %s", s)
+	}
+
+	for _, bad := range []string{"=0", "=3", "=x"} {
+		input := "@tab _ [a]\n|1|\n|2|\n" + bad + "\n@end"
+		if _, err := ParseTabularLoose(input); err == nil {
+			t.Errorf("expected error for row %s", bad)
+		}
+	}
+}
+
+func TestAutoTabular_AutoLayout(t *testing.T) {
+	opts := DefaultLooseCanonOpts()
+	opts.TableLayout = TableAutoLayout
+
+	var tall, wide []*GValue
+	for i := 0; i < 12; i++ {
+		tall = append(tall, Map(MapEntry{Key: "id", Value: Int(int64(i))}, MapEntry{Key: "ok", Value: Bool(true)}))
+	}
+	for i := 0; i < 3; i++ {
+		var cols []MapEntry
+		for c := 0; c < 8; c++ {
+			cols = append(cols, MapEntry{Key: fmt.Sprintf("c%d", c), Value: Int(int64(i))})
+		}
+		wide = append(wide, Map(cols...))
+	}
+
+	for _, tt := range []struct {
+		name  string
+		items *GValue
+		tag   string
+	}{
+		{"tall", List(tall...), "@tabc _ "},
+		{"wide", List(wide...), "@tab _ "},
+	} {
+		got := CanonicalizeLooseWithOpts(tt.items, opts)
+		if !strings.HasPrefix(got, tt.tag) {
+			t.Errorf("%s: expected %q block, got:\n%s", tt.name, tt.tag, got)
+		}
+		back, err := ParseLoose(got, nil)
+		if err != nil || !EqualLoose(back, tt.items) {
+			t.Errorf("%s: round-trip failed: %v", tt.name, err)
+		}
+		_, e := EmitExplain(tt.items, opts)
+		if e.Tables[0].ColumnMajor != (tt.tag == "@tabc _ ") {
+			t.Errorf("%s: explain ColumnMajor = %v", tt.name, e.Tables[0].ColumnMajor)
+		}
+	}
+}
+
 func TestAutoTabular_PreservesOriginalOrder(t *testing.T) {
 	// Keys should be sorted alphabetically, but rows preserve original order
 	items := List(
@@ -1214,6 +1464,48 @@ func TestTabularHeaderWithMeta(t *testing.T) {
 	}
 }
 
+func TestParseTabularLooseWithMeta(t *testing.T) {
+	rows := []*GValue{
+		Map(MapEntry{Key: "id", Value: Int(1)}, MapEntry{Key: "name", Value: Str("a")}),
+		Map(MapEntry{Key: "id", Value: Int(2)}, MapEntry{Key: "name", Value: Str("b")}),
+		Map(MapEntry{Key: "id", Value: Int(3)}, MapEntry{Key: "name", Value: Str("c")}),
+	}
+	full := CanonicalizeLoose(List(rows...))
+
+	v, meta, err := ParseTabularLooseWithMeta(full)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if meta.Rows != 3 || meta.Cols != 2 || meta.ActualRows != 3 || !meta.Ended {
+		t.Errorf("meta: %+v", meta)
+	}
+	if meta.Truncated() || len(meta.Warnings) != 0 {
+		t.Errorf("complete block flagged: %+v", meta)
+	}
+	if !EqualLoose(v, List(rows...)) {
+		t.Errorf("rows: got %s", CanonicalizeLoose(v))
+	}
+
+	// Cut off after the second row, as a dropped stream would.
+	lines := strings.Split(full, "\n")
+	cut := strings.Join(lines[:3], "\n")
+	v, meta, err = ParseTabularLooseWithMeta(cut)
+	if err != nil {
+		t.Fatalf("parse truncated: %v", err)
+	}
+	if !meta.Truncated() || meta.Ended || meta.ActualRows != 2 || len(v.listVal) != 2 {
+		t.Errorf("truncation not detected: %+v", meta)
+	}
+	if len(meta.Warnings) != 1 || meta.Warnings[0] != "header declares rows=3 but block has 2" {
+		t.Errorf("warnings: %q", meta.Warnings)
+	}
+
+	_, meta, _ = ParseTabularLooseWithMeta("@tab _ cols=3 [id name]\n|1|a|\n@end")
+	if meta.Rows != -1 || meta.Truncated() || len(meta.Warnings) != 1 {
+		t.Errorf("cols mismatch: %+v", meta)
+	}
+}
+
 // ============================================================
 // v2.4.0 Cross-Implementation Parity Tests
 // ============================================================
@@ -0,0 +1,271 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// ============================================================
+// Memory-Mapped Documents
+// ============================================================
+//
+// An archive of several gigabytes is too big to parse for the sake of one
+// record. OpenMapped maps the file read-only and parses it lazily (see
+// ParseLooseLazy): opening scans the top-level container once to find its
+// entries, without parsing them, and a read parses only the containers on
+// the way to the value asked for. The text is not copied as a whole, so the
+// file's pages are read in by the OS as the parser touches them and shared
+// with other readers of the file; each container parsed copies its own
+// strings out of the mapping.
+//
+// With a sidecar index (see BuildIndexFile) next to the file, Get parses
+// only the value asked for, found through the index, and nothing on the way
+// to it.
+//
+// Parsed values never point into the mapping, so they stay valid after
+// Close and do not change if the file is rewritten. A container still
+// deferred at Close reads as a parse error (see GValue.Materialize). Where
+// the platform cannot map files the document is read into memory instead,
+// with the same API.
+
+// DefaultMappedThreshold is the container size, in bytes, above which a
+// mapped document defers parsing.
+const DefaultMappedThreshold = 4096
+
+// MappedDocument is a GLYPH document read lazily from a memory-mapped file.
+// Get is safe for concurrent use.
+type MappedDocument struct {
+	Meta *DocMeta // @doc and version headers (nil if absent)
+
+	mu      sync.Mutex
+	m       *mapping
+	modTime int64 // Of the file when opened, in Unix nanoseconds
+	root    *GValue
+	index   *Index
+}
+
+// mapping is the memory a mapped document's text lives in. The deferred
+// spans of the document hold it, and parse only while it is mapped.
+type mapping struct {
+	mu    sync.RWMutex // Held for reading while a span is parsed
+	data  []byte       // nil once unmapped
+	unmap func() error
+}
+
+// errMappingClosed is the parse error of a span read after Close.
+var errMappingClosed = errors.New("glyph: mapped document is closed")
+
+// OpenMapped maps the GLYPH-Loose file at path and parses it lazily, with
+// DefaultMappedThreshold. A sidecar index that is missing, unreadable, or
+// built before the file was last written is not used.
+func OpenMapped(path string) (*MappedDocument, error) {
+	return OpenMappedWithThreshold(path, DefaultMappedThreshold)
+}
+
+// OpenMappedWithThreshold is OpenMapped with containers of at least
+// threshold bytes deferred.
+func OpenMappedWithThreshold(path string, threshold int) (*MappedDocument, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > math.MaxInt {
+		return nil, fmt.Errorf("glyph: %s: file too large to map", path)
+	}
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("glyph: map %s: %w", path, err)
+	}
+
+	d := &MappedDocument{
+		m:       &mapping{data: data, unmap: unmap},
+		modTime: info.ModTime().UnixNano(),
+	}
+	if err := d.parse(threshold); err != nil {
+		unmap()
+		return nil, fmt.Errorf("glyph: %s: %w", path, err)
+	}
+	if idx, err := ReadIndexFile(path); err == nil && idx.ModTime == d.modTime {
+		d.UseIndex(idx)
+	}
+	return d, nil
+}
+
+// UseIndex makes Get locate values through idx, which must have been built
+// from this file. An index that records a modification time (see
+// BuildIndexFile) must have been built from the file as it is now.
+func (d *MappedDocument) UseIndex(idx *Index) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if idx.Size != len(d.m.data) {
+		return fmt.Errorf("glyph: index is for %d bytes, file has %d", idx.Size, len(d.m.data))
+	}
+	if idx.ModTime != 0 && idx.ModTime != d.modTime {
+		return fmt.Errorf("glyph: index is for an older version of the file")
+	}
+	d.index = idx
+	return nil
+}
+
+// parse reads the headers and defers the body. A body that is not a plain
+// map, list or table, such as one under a @schema directive, goes through
+// ParseLooseLazy, which still defers the containers inside it.
+func (d *MappedDocument) parse(threshold int) error {
+	text := d.text()
+	_, _, body, err := splitDocHeaders(text)
+	if err != nil {
+		return err
+	}
+	// Headers are parsed again from a copy, so Meta does not point into
+	// the mapping.
+	version, meta, _, err := splitDocHeaders(strings.Clone(text[:len(text)-len(body)]))
+	if err != nil {
+		return err
+	}
+	if version != "" {
+		if meta == nil {
+			meta = &DocMeta{}
+		}
+		meta.Version = version
+		if w := versionWarning(version); w != "" {
+			meta.Warnings = append(meta.Warnings, w)
+		}
+	}
+	d.Meta = meta
+
+	body = strings.TrimSpace(body)
+	if threshold > 0 && len(body) >= threshold {
+		if v := deferLoose(body, nil, threshold); v != nil {
+			d.root = v
+			d.m.detach(v)
+			return nil
+		}
+	}
+	d.root, err = ParseLooseLazy(body, nil, threshold)
+	d.m.detach(d.root)
+	return err
+}
+
+// text returns the mapped file as a string, without copying it.
+func (d *MappedDocument) text() string {
+	if len(d.m.data) == 0 {
+		return ""
+	}
+	return unsafe.String(&d.m.data[0], len(d.m.data))
+}
+
+// detach makes v, just parsed from the mapping, independent of it: strings
+// are copied to the heap, and containers still deferred are tied to m so
+// that they are parsed only while it is mapped.
+func (m *mapping) detach(v *GValue) {
+	if v == nil {
+		return
+	}
+	v.strVal = strings.Clone(v.strVal)
+	if v.ext != nil && (v.ext.lazy != nil || v.ext.idVal != (RefID{}) || v.ext.custom != nil) {
+		x := *v.ext
+		if x.lazy != nil {
+			l := *x.lazy
+			l.src = m
+			l.keyDict = cloneStrings(l.keyDict)
+			x.lazy = &l
+		}
+		x.idVal = RefID{Prefix: strings.Clone(x.idVal.Prefix), Value: strings.Clone(x.idVal.Value)}
+		if x.custom != nil {
+			c := *x.custom
+			c.Tag, c.Text = strings.Clone(c.Tag), strings.Clone(c.Text)
+			x.custom = &c
+		}
+		v.ext = &x
+	}
+	for _, item := range v.listVal {
+		m.detach(item)
+	}
+	for i := range v.mapVal {
+		v.mapVal[i].Key = strings.Clone(v.mapVal[i].Key)
+		m.detach(v.mapVal[i].Value)
+	}
+	if v.structVal != nil {
+		v.structVal.TypeName = strings.Clone(v.structVal.TypeName)
+		for i := range v.structVal.Fields {
+			v.structVal.Fields[i].Key = strings.Clone(v.structVal.Fields[i].Key)
+			m.detach(v.structVal.Fields[i].Value)
+		}
+	}
+	if v.sumVal != nil {
+		v.sumVal.Tag = strings.Clone(v.sumVal.Tag)
+		m.detach(v.sumVal.Value)
+	}
+}
+
+func cloneStrings(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strings.Clone(s)
+	}
+	return out
+}
+
+// Get returns the value at path, in patch path syntax (.field, ["key"],
+// [N]), parsing what it passes through. The value is fully parsed, so it
+// may be read from several goroutines; asking for a large subtree parses
+// all of it. With an index, the value is parsed afresh on each call and the
+// lazy root is left as it was.
+func (d *MappedDocument) Get(path string) (*GValue, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.root == nil {
+		return nil, fmt.Errorf("glyph: mapped document is closed")
+	}
+	segs := parsePathToSegs(path)
+	if d.index != nil && len(segs) > 0 {
+		text := d.text()
+		span, err := d.index.locate(text, segs)
+		if err != nil {
+			return nil, err
+		}
+		return parseLooseValueLazy(strings.Clone(text[span.Offset:span.Offset+span.Length]), nil, 0)
+	}
+	v, err := lookupPathSegs(d.root, segs)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.MaterializeAll(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Root returns the document's root value, with its large containers still
+// deferred. Unlike Get, reading it is not safe for concurrent use.
+func (d *MappedDocument) Root() *GValue {
+	return d.root
+}
+
+// Close unmaps the file. Values read from the document stay valid; those
+// of its containers that are still deferred fail to parse.
+func (d *MappedDocument) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.root == nil {
+		return nil
+	}
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	err := d.m.unmap()
+	d.m.data, d.m.unmap, d.root, d.index = nil, nil, nil, nil
+	return err
+}
@@ -0,0 +1,154 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeArchive(t *testing.T, n int) string {
+	t.Helper()
+	records := make([]MapEntry, n)
+	for i := range records {
+		records[i] = MapEntry{Key: fmt.Sprintf("r%05d", i), Value: Map(
+			MapEntry{Key: "id", Value: Int(int64(i))},
+			MapEntry{Key: "tags", Value: List(Str("a"), Str("b"))},
+			MapEntry{Key: "note", Value: Str(strings.Repeat("x", 300))},
+		)}
+	}
+	doc := Map(MapEntry{Key: "records", Value: Map(records...)}, MapEntry{Key: "title", Value: Str("archive")})
+	path := filepath.Join(t.TempDir(), "archive.glyph")
+	text := EmitDocument(&DocMeta{Version: "2.6", Producer: "test"}, doc)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenMapped(t *testing.T) {
+	path := writeArchive(t, 2000)
+	d, err := OpenMappedWithThreshold(path, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if d.Meta == nil || d.Meta.Producer != "test" || d.Meta.Version != "2.6" {
+		t.Errorf("meta = %+v", d.Meta)
+	}
+	if !d.Root().IsLazy() {
+		t.Error("root parsed on open")
+	}
+
+	v, err := d.Get(".records.r01234.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := v.AsInt(); n != 1234 {
+		t.Errorf("id = %s", Emit(v))
+	}
+
+	// Only the records on the way were parsed.
+	records := d.Root().Get("records")
+	if records.IsLazy() || !records.Get("r00001").IsLazy() {
+		t.Errorf("records lazy=%v, r00001 lazy=%v", records.IsLazy(), records.Get("r00001").IsLazy())
+	}
+
+	if _, err := d.Get(".records.nope"); err == nil {
+		t.Error("missing key read")
+	}
+	if tags, err := d.Get(`.records["r00007"].tags[1]`); err != nil || Emit(tags) != "b" {
+		t.Errorf("tags[1] = %v, %v", tags, err)
+	}
+}
+
+func TestOpenMapped_Concurrent(t *testing.T) {
+	d, err := OpenMappedWithThreshold(writeArchive(t, 500), 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < 500; i += 8 {
+				v, err := d.Get(fmt.Sprintf(".records.r%05d", i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if n, _ := v.Get("id").AsInt(); n != int64(i) {
+					t.Errorf("record %d has id %d", i, n)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestOpenMapped_Small(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{"small": "{a=1}", "empty": ""} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(text), 0o644)
+		d, err := OpenMapped(path)
+		if name == "empty" {
+			if err == nil {
+				t.Error("empty file opened")
+				d.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := d.Get("a"); err != nil || Emit(v) != "1" {
+			t.Errorf("a = %v, %v", v, err)
+		}
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+		if _, err := d.Get("a"); err == nil {
+			t.Error("read after Close")
+		}
+	}
+}
+
+func TestOpenMapped_ValuesOutliveClose(t *testing.T) {
+	d, err := OpenMappedWithThreshold(writeArchive(t, 100), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := d.Get(".records.r00042")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := d.Root().Get("records")
+	read := records.Get("r00041")
+	read.Len()
+	deferred := records.Get("r00043")
+	if !deferred.IsLazy() {
+		t.Fatal("r00043 parsed")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if note, _ := v.Get("note").AsStr(); note != strings.Repeat("x", 300) {
+		t.Errorf("note after Close = %q", note)
+	}
+	if got := CanonicalizeLoose(read); !strings.Contains(got, "id=41") {
+		t.Errorf("r00041 after Close = %s", got)
+	}
+	if err := deferred.Materialize(); !errors.Is(err, errMappingClosed) {
+		t.Errorf("deferred span after Close: %v", err)
+	}
+	if got := CanonicalizeLoose(deferred); got != ellipsisMarker {
+		t.Errorf("deferred span after Close emits as %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package glyph
+
+import "fmt"
+
+// ============================================================
+// Deep Merge
+// ============================================================
+//
+// Merge folds a partial document into a fuller one, e.g. a tool result into
+// agent state. Maps and same-typed structs merge key by key; everything else
+// in src replaces what is in dst. Lists follow MergeOpts.Lists.
+
+// ListMerge selects how Merge combines two lists.
+type ListMerge struct {
+	mode int
+	key  string
+}
+
+const (
+	listReplace = iota
+	listAppend
+	listByKey
+)
+
+var (
+	// MergeReplace makes the src list replace the dst list. It is the zero
+	// value of ListMerge.
+	MergeReplace = ListMerge{mode: listReplace}
+
+	// MergeAppend appends the src items after the dst items.
+	MergeAppend = ListMerge{mode: listAppend}
+)
+
+// MergeByKey matches list items (maps or structs) by the value of field
+// key. A src item merges into the dst item with an equal key; src items
+// with no match are appended. Every item on both sides must have the key.
+func MergeByKey(key string) ListMerge {
+	return ListMerge{mode: listByKey, key: key}
+}
+
+// MergeOpts configures Merge.
+type MergeOpts struct {
+	Lists ListMerge
+
+	// NullDeletes removes a map key or struct field when src sets it to
+	// null, as in JSON Merge Patch. Otherwise the null is stored.
+	NullDeletes bool
+}
+
+// Merge returns a deep merge of src into dst. Neither input is modified.
+// A nil src returns a copy of dst, and a nil dst a copy of src. The error
+// names the path of the first list item MergeByKey cannot match.
+func Merge(dst, src *GValue, opts MergeOpts) (*GValue, error) {
+	out, err := mergeValue(deepCopy(dst), src, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mergeValue merges src into dst, which the caller owns and may be reused.
+func mergeValue(dst, src *GValue, path string, opts MergeOpts) (*GValue, error) {
+	dst.force()
+	src.force()
+	if src == nil {
+		return dst, nil
+	}
+	if dst == nil {
+		return deepCopy(src), nil
+	}
+
+	switch {
+	case dst.typ == TypeMap && src.typ == TypeMap:
+		entries, err := mergeEntries(dst.mapVal, src.mapVal, path, opts)
+		dst.mapVal = entries
+		return dst, err
+	case dst.typ == TypeStruct && src.typ == TypeStruct &&
+		dst.structVal != nil && src.structVal != nil &&
+		dst.structVal.TypeName == src.structVal.TypeName:
+		fields, err := mergeEntries(dst.structVal.Fields, src.structVal.Fields, path, opts)
+		dst.structVal.Fields = fields
+		return dst, err
+	case dst.typ == TypeList && src.typ == TypeList:
+		return mergeLists(dst, src, path, opts)
+	}
+	return deepCopy(src), nil
+}
+
+func mergeEntries(dst, src []MapEntry, path string, opts MergeOpts) ([]MapEntry, error) {
+	for _, se := range src {
+		i := 0
+		for i < len(dst) && dst[i].Key != se.Key {
+			i++
+		}
+		if opts.NullDeletes && se.Value != nil && se.Value.typ == TypeNull {
+			if i < len(dst) {
+				dst = append(dst[:i], dst[i+1:]...)
+			}
+			continue
+		}
+		if i == len(dst) {
+			dst = append(dst, MapEntry{Key: se.Key, Value: deepCopy(se.Value)})
+			continue
+		}
+		v, err := mergeValue(dst[i].Value, se.Value, joinPath(path, se.Key), opts)
+		if err != nil {
+			return dst, err
+		}
+		dst[i].Value = v
+	}
+	return dst, nil
+}
+
+func mergeLists(dst, src *GValue, path string, opts MergeOpts) (*GValue, error) {
+	switch opts.Lists.mode {
+	case listReplace:
+		return deepCopy(src), nil
+	case listAppend:
+		for _, item := range src.listVal {
+			dst.listVal = append(dst.listVal, deepCopy(item))
+		}
+		return dst, nil
+	}
+
+	key := opts.Lists.key
+	index := make(map[string]int, len(dst.listVal))
+	for i, item := range dst.listVal {
+		k := objectField(item, key)
+		if k == nil {
+			return dst, fmt.Errorf("merge %s[%d]: dst item has no %q", path, i, key)
+		}
+		ck := CanonicalizeLooseNoTabular(k)
+		if _, dup := index[ck]; !dup {
+			index[ck] = i
+		}
+	}
+	for i, item := range src.listVal {
+		k := objectField(item, key)
+		if k == nil {
+			return dst, fmt.Errorf("merge %s[%d]: src item has no %q", path, i, key)
+		}
+		ck := CanonicalizeLooseNoTabular(k)
+		j, ok := index[ck]
+		if !ok {
+			index[ck] = len(dst.listVal)
+			dst.listVal = append(dst.listVal, deepCopy(item))
+			continue
+		}
+		v, err := mergeValue(dst.listVal[j], item, fmt.Sprintf("%s[%d]", path, j), opts)
+		if err != nil {
+			return dst, err
+		}
+		dst.listVal[j] = v
+	}
+	return dst, nil
+}
+
+// objectField returns field key of a map or struct, or nil.
+func objectField(v *GValue, key string) *GValue {
+	if v == nil || v.typ != TypeMap && (v.typ != TypeStruct || v.structVal == nil) {
+		return nil
+	}
+	return v.Get(key)
+}
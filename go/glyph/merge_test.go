@@ -0,0 +1,58 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	task := func(id int64, status string) *GValue {
+		return Map(MapEntry{Key: "id", Value: Int(id)}, MapEntry{Key: "status", Value: Str(status)})
+	}
+	state := Map(
+		MapEntry{Key: "goal", Value: Str("ship")},
+		MapEntry{Key: "scratch", Value: Str("tmp")},
+		MapEntry{Key: "tasks", Value: List(task(1, "todo"), task(2, "todo"))},
+	)
+	result := Map(
+		MapEntry{Key: "scratch", Value: Null()},
+		MapEntry{Key: "tasks", Value: List(task(2, "done"), task(3, "todo"))},
+	)
+	before := CanonicalizeLoose(state)
+
+	cases := []struct {
+		name string
+		opts MergeOpts
+		want string
+	}{
+		{"replace", MergeOpts{}, "{goal=ship scratch=∅ tasks=[{id=2 status=done} {id=3 status=todo}]}"},
+		{"append", MergeOpts{Lists: MergeAppend, NullDeletes: true},
+			"{goal=ship tasks=[{id=1 status=todo} {id=2 status=todo} {id=2 status=done} {id=3 status=todo}]}"},
+		{"by key", MergeOpts{Lists: MergeByKey("id"), NullDeletes: true},
+			"{goal=ship tasks=[{id=1 status=todo} {id=2 status=done} {id=3 status=todo}]}"},
+	}
+	for _, tc := range cases {
+		got, err := Merge(state, result, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if s := CanonicalizeLooseNoTabular(got); s != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.name, s, tc.want)
+		}
+	}
+	if CanonicalizeLoose(state) != before {
+		t.Error("Merge modified dst")
+	}
+
+	_, err := Merge(state, Map(MapEntry{Key: "tasks", Value: List(Str("x"))}), MergeOpts{Lists: MergeByKey("id")})
+	if err == nil || !strings.Contains(err.Error(), `tasks[0]: src item has no "id"`) {
+		t.Errorf("expected a keyless-item error, got %v", err)
+	}
+
+	// Structs of different types do not merge field by field.
+	a := Struct("A", MapEntry{Key: "x", Value: Int(1)})
+	b := Struct("B", MapEntry{Key: "y", Value: Int(2)})
+	if got, _ := Merge(a, b, MergeOpts{}); got.Get("x") != nil || got.Get("y") == nil {
+		t.Errorf("differently typed struct should replace: %s", Emit(got))
+	}
+}
@@ -0,0 +1,76 @@
+package glyph
+
+// ============================================================
+// Key Encoding Negotiation
+// ============================================================
+//
+// Compact keys (#N with an @schema header listing the key names) only pay
+// off when keys repeat enough to cover the header. For one small object the
+// header costs more than it saves; for a list of a hundred records, or for
+// many documents sharing one header, it wins easily. ChooseKeyEncoding
+// computes both encodings and picks the smaller, charging the header once
+// across docCount documents.
+
+// KeyEncodingChoice is the outcome of ChooseKeyEncoding. Token counts are
+// estimates (see EstimateTokens).
+type KeyEncodingChoice struct {
+	Compact       bool           // Compact keys are smaller overall
+	FullTokens    int            // Per document, with full key names
+	CompactTokens int            // Per document, with #N keys (header excluded)
+	HeaderTokens  int            // The @schema header, paid once
+	Schema        *SchemaContext // Key dictionary used for the compact encoding
+}
+
+// Total returns the estimated tokens for docCount documents in the chosen
+// encoding.
+func (c KeyEncodingChoice) Total(docCount int) int {
+	if docCount < 1 {
+		docCount = 1
+	}
+	if c.Compact {
+		return c.HeaderTokens + docCount*c.CompactTokens
+	}
+	return docCount * c.FullTokens
+}
+
+// ChooseKeyEncoding decides whether v, sent as one of docCount documents
+// sharing a header, is smaller with compact keys. The key dictionary is
+// opts.Schema if set, else built from v's keys. Other options (tabular, null
+// style) apply to both encodings. A docCount below 1 counts as 1.
+func ChooseKeyEncoding(v *GValue, opts LooseCanonOpts, docCount int) KeyEncodingChoice {
+	if docCount < 1 {
+		docCount = 1
+	}
+
+	full := opts
+	full.Schema, full.SchemaRef, full.KeyDict, full.UseCompactKeys = nil, "", nil, false
+
+	compact := opts
+	if compact.Schema == nil {
+		compact.Schema = NewSchemaContext(BuildKeyDictFromValue(v))
+	}
+	compact.UseCompactKeys = true
+
+	choice := KeyEncodingChoice{
+		FullTokens:    EstimateTokens(canonLooseWithOpts(v, full)),
+		CompactTokens: EstimateTokens(canonLooseWithOpts(v, compact)),
+		HeaderTokens:  EstimateTokens(emitSchemaHeader(compact) + "\n"),
+		Schema:        compact.Schema,
+	}
+	choice.Compact = choice.HeaderTokens+docCount*choice.CompactTokens < docCount*choice.FullTokens
+	return choice
+}
+
+// CanonicalizeLooseAuto emits v with compact keys and an @schema header when
+// ChooseKeyEncoding finds that smaller for docCount documents, and with full
+// keys otherwise.
+func CanonicalizeLooseAuto(v *GValue, opts LooseCanonOpts, docCount int) string {
+	choice := ChooseKeyEncoding(v, opts, docCount)
+	if !choice.Compact {
+		opts.Schema, opts.SchemaRef, opts.KeyDict, opts.UseCompactKeys = nil, "", nil, false
+		return canonLooseWithOpts(v, opts)
+	}
+	opts.Schema = choice.Schema
+	opts.UseCompactKeys = true
+	return CanonicalizeLooseWithSchema(v, opts)
+}
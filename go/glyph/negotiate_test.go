@@ -0,0 +1,59 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChooseKeyEncoding(t *testing.T) {
+	small := Map(MapEntry{Key: "a", Value: Int(1)})
+	choice := ChooseKeyEncoding(small, DefaultLooseCanonOpts(), 1)
+	if choice.Compact {
+		t.Errorf("a one-key object should keep full keys: %+v", choice)
+	}
+	if out := CanonicalizeLooseAuto(small, DefaultLooseCanonOpts(), 1); out != "{a=1}" {
+		t.Errorf("got %s", out)
+	}
+
+	var items []*GValue
+	for i := 0; i < 20; i++ {
+		items = append(items, Map(
+			MapEntry{Key: "conversation_identifier", Value: Int(int64(i))},
+			MapEntry{Key: "message_content", Value: Str(fmt.Sprintf("m%d", i))},
+		))
+	}
+	big := List(items...)
+	opts := NoTabularLooseCanonOpts()
+	choice = ChooseKeyEncoding(big, opts, 1)
+	if !choice.Compact || choice.Total(1) >= choice.FullTokens {
+		t.Errorf("repeated long keys should favour compact keys: %+v", choice)
+	}
+	out := CanonicalizeLooseAuto(big, opts, 1)
+	if !strings.HasPrefix(out, "@schema#") || strings.Contains(out, "{conversation_identifier") {
+		t.Errorf("expected compact output, got %.80s", out)
+	}
+	back, err := ParseDocument(out)
+	if err != nil || !EqualLoose(back, big) {
+		t.Errorf("compact output should round-trip: %v", err)
+	}
+}
+
+func TestChooseKeyEncoding_Amortization(t *testing.T) {
+	doc := Map(
+		MapEntry{Key: "request_identifier", Value: Int(7)},
+		MapEntry{Key: "status", Value: Str("ok")},
+	)
+	opts := DefaultLooseCanonOpts()
+	one := ChooseKeyEncoding(doc, opts, 1)
+	many := ChooseKeyEncoding(doc, opts, 1000)
+	if one.Compact || !many.Compact {
+		t.Errorf("header should only pay off across documents: one=%+v many=%+v", one, many)
+	}
+	if many.Total(1000) >= 1000*many.FullTokens {
+		t.Errorf("Total should beat full keys: %d vs %d", many.Total(1000), 1000*many.FullTokens)
+	}
+	if ChooseKeyEncoding(doc, opts, 0).Compact != one.Compact {
+		t.Error("docCount < 1 should count as 1")
+	}
+}
@@ -0,0 +1,133 @@
+package glyph
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Locale-Safe Number Repair
+// ============================================================
+//
+// LLMs write numbers the way people do: "1,234", "+5", "12%", "3,5". When the
+// schema says a value is numeric, repairNumber turns such text into a proper
+// int or float and names each repair so callers can record it:
+//
+//   - plus_sign:           "+5"        -> 5
+//   - thousands_separator: "1,234,567" -> 1234567 (groups of exactly 3 digits)
+//   - percent:             "12.5%"     -> 0.125 (float fields only)
+//   - decimal_comma:       "3,5"       -> 3.5, "1.234,56" -> 1234.56 (float fields only)
+//
+// A lone "1,234" is read as a thousands separator even for floats; a comma is
+// a decimal comma only when the digits after it are not a 3-digit group, or
+// when it follows '.' thousands separators.
+
+// Number repair kinds, as reported in parse warnings and Coercion.Repairs.
+const (
+	RepairPlusSign           = "plus_sign"
+	RepairThousandsSeparator = "thousands_separator"
+	RepairPercent            = "percent"
+	RepairDecimalComma       = "decimal_comma"
+)
+
+// repairNumber parses s as a number of the given kind (TypeSpecInt or
+// TypeSpecFloat), applying the repairs above. It returns nil when s needs no
+// repair or cannot be read as that kind.
+func repairNumber(s string, kind TypeSpecKind) (*GValue, []string) {
+	if kind != TypeSpecInt && kind != TypeSpecFloat {
+		return nil, nil
+	}
+	body := strings.TrimSpace(s)
+	var repairs []string
+
+	percent := false
+	if rest, ok := strings.CutSuffix(body, "%"); ok {
+		if kind != TypeSpecFloat {
+			return nil, nil
+		}
+		body = strings.TrimSpace(rest)
+		percent = true
+		repairs = append(repairs, RepairPercent)
+	}
+
+	sign := ""
+	switch {
+	case strings.HasPrefix(body, "+"):
+		body = body[1:]
+		repairs = append(repairs, RepairPlusSign)
+	case strings.HasPrefix(body, "-"):
+		body, sign = body[1:], "-"
+	}
+
+	lastComma, lastDot := strings.LastIndexByte(body, ','), strings.LastIndexByte(body, '.')
+	switch {
+	case lastComma < 0:
+		// Nothing locale-specific; strconv handles the rest.
+	case lastDot > lastComma:
+		// "1,234.5": commas group thousands.
+		intPart, ok := ungroup(body[:lastDot], ',')
+		if !ok {
+			return nil, nil
+		}
+		body = intPart + body[lastDot:]
+		repairs = append(repairs, RepairThousandsSeparator)
+	case lastDot >= 0:
+		// "1.234,56": dots group thousands, the comma is the decimal point.
+		intPart, ok := ungroup(body[:lastComma], '.')
+		if !ok || kind != TypeSpecFloat {
+			return nil, nil
+		}
+		body = intPart + "." + body[lastComma+1:]
+		repairs = append(repairs, RepairThousandsSeparator, RepairDecimalComma)
+	default:
+		if grouped, ok := ungroup(body, ','); ok {
+			body = grouped
+			repairs = append(repairs, RepairThousandsSeparator)
+		} else if strings.Count(body, ",") == 1 && kind == TypeSpecFloat {
+			body = strings.Replace(body, ",", ".", 1)
+			repairs = append(repairs, RepairDecimalComma)
+		} else {
+			return nil, nil
+		}
+	}
+
+	if len(repairs) == 0 || body == "" || !isDigit(body[0]) {
+		return nil, nil
+	}
+	body = sign + body
+
+	if kind == TypeSpecInt {
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, nil
+		}
+		return Int(n), repairs
+	}
+	f, err := strconv.ParseFloat(body, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, nil
+	}
+	if percent {
+		f /= 100
+	}
+	return Float(f), repairs
+}
+
+// ungroup removes sep from s when it separates digit groups the way a
+// thousands separator does: 1-3 leading digits, then groups of exactly 3.
+func ungroup(s string, sep byte) (string, bool) {
+	groups := strings.Split(s, string(sep))
+	if len(groups) < 2 || len(groups[0]) == 0 || len(groups[0]) > 3 {
+		return "", false
+	}
+	for i, g := range groups {
+		if i > 0 && len(g) != 3 {
+			return "", false
+		}
+		if strings.Trim(g, "0123456789") != "" {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), true
+}
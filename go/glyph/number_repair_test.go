@@ -0,0 +1,126 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepairNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		kind    TypeSpecKind
+		want    string // loose canonical result, "" for no repair
+		repairs string
+	}{
+		{"1,234", TypeSpecInt, "1234", "thousands_separator"},
+		{"-1,234,567", TypeSpecInt, "-1234567", "thousands_separator"},
+		{"+5", TypeSpecInt, "5", "plus_sign"},
+		{"+1,000", TypeSpecInt, "1000", "plus_sign thousands_separator"},
+		{"1,234.5", TypeSpecFloat, "1234.5", "thousands_separator"},
+		{"12.5%", TypeSpecFloat, "0.125", "percent"},
+		{" 50 % ", TypeSpecFloat, "0.5", "percent"},
+		{"3,5", TypeSpecFloat, "3.5", "decimal_comma"},
+		{"1.234,56", TypeSpecFloat, "1234.56", "thousands_separator decimal_comma"},
+		{"1,234", TypeSpecFloat, "1234.0", "thousands_separator"},
+
+		{"42", TypeSpecInt, "", ""},       // nothing to repair
+		{"3,5", TypeSpecInt, "", ""},      // decimal comma needs a float field
+		{"12%", TypeSpecInt, "", ""},      // percent needs a float field
+		{"1,23,456", TypeSpecInt, "", ""}, // bad grouping
+		{"1234,567", TypeSpecInt, "", ""}, // leading group too long
+		{"1,234.5", TypeSpecInt, "", ""},  // not an int
+		{"3,5,7", TypeSpecFloat, "", ""},  // ambiguous
+		{"+NaN", TypeSpecFloat, "", ""},   // not a number
+		{"1,234", TypeSpecStr, "", ""},    // not numeric
+	}
+	for _, tt := range tests {
+		v, repairs := repairNumber(tt.in, tt.kind)
+		got := ""
+		if v != nil {
+			got = CanonicalizeLoose(v)
+		}
+		if got != tt.want || strings.Join(repairs, " ") != tt.repairs {
+			t.Errorf("repairNumber(%q) = %q %v, want %q [%s]", tt.in, got, repairs, tt.want, tt.repairs)
+		}
+	}
+}
+
+func TestParse_RepairsNumericFields(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Quote", "v1",
+			Field("qty", PrimitiveType("int")),
+			Field("price", PrimitiveType("float")),
+			Field("rate", PrimitiveType("float"), WithWireKey("r")),
+			Field("pts", ListType(PrimitiveType("int")), WithOptional()),
+		).
+		Build()
+
+	result, err := ParseWithSchema(`Quote{qty=1,234 price="1.234,56" r=12.5% pts=[1,234]}`, schema)
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse failed: %v %v", err, result.Errors)
+	}
+	v := result.Value
+	if q := v.Get("qty"); q.typ != TypeInt || q.intVal != 1234 {
+		t.Errorf("qty = %s", CanonicalizeLoose(q))
+	}
+	if p := v.Get("price"); p.typ != TypeFloat || p.floatVal != 1234.56 {
+		t.Errorf("price = %s", CanonicalizeLoose(p))
+	}
+	if r := v.Get("rate"); r.typ != TypeFloat || r.floatVal != 0.125 {
+		t.Errorf("rate = %s", CanonicalizeLoose(r))
+	}
+	// Lists are not schema-scalar: the comma stays a separator.
+	if pts := v.Get("pts"); len(pts.listVal) != 2 {
+		t.Errorf("pts = %s", CanonicalizeLoose(pts))
+	}
+	if len(result.Warnings) != 3 {
+		t.Errorf("expected 3 repair warnings, got %v", result.Warnings)
+	}
+	if len(result.Warnings) > 0 && !strings.Contains(result.Warnings[0].Message, `repaired number "1,234" -> 1234 (thousands_separator)`) {
+		t.Errorf("unexpected warning: %s", result.Warnings[0].Message)
+	}
+
+	// Whitespace after the comma means two values, not one number.
+	result, _ = ParseWithSchema(`Quote{qty=1, 234 price=1}`, schema)
+	if q := result.Value.Get("qty"); q.intVal != 1 {
+		t.Errorf("spaced comma should not join, got %s", CanonicalizeLoose(q))
+	}
+}
+
+func TestParse_PlusAndPercentTokens(t *testing.T) {
+	result, err := Parse(`[+5 50% -2]`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if got := CanonicalizeLoose(result.Value); got != "[5 0.5 -2]" {
+		t.Errorf("got %s", got)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("expected 2 repair warnings, got %v", result.Warnings)
+	}
+
+	strict, _ := ParseWithOptions(`+5`, ParseOptions{})
+	if strict == nil || !strict.HasErrors() {
+		t.Error("strict parse should reject a leading +")
+	}
+}
+
+func TestCoerce_RepairsNumbers(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Row", "v1",
+			Field("n", PrimitiveType("int")),
+			Field("x", PrimitiveType("float")),
+		).
+		Build()
+
+	out, report, err := Coerce(Map(FieldVal("n", Str("12,000")), FieldVal("x", Str("2,5"))), schema, "Row")
+	if err != nil {
+		t.Fatalf("Coerce error: %v", err)
+	}
+	if got := CanonicalizeLoose(out); got != "{n=12000 x=2.5}" {
+		t.Errorf("got %s", got)
+	}
+	if len(report.Coercions) != 2 || report.Coercions[0].String() != `n: str "12,000" -> int (thousands_separator)` {
+		t.Errorf("unexpected report: %v", report.Coercions)
+	}
+}
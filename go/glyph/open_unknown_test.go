@@ -0,0 +1,123 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// open_unknown_test.go covers the @unknown bag for @open structs: packed
+// emission, ParsePacked round trips, closed-type behavior, and exclusion of
+// unknown fields from typed canonicalization.
+
+func makeOpenSchema() *Schema {
+	return NewSchemaBuilder().
+		AddOpenPackedStruct("Config", "v1",
+			Field("name", PrimitiveType("str"), WithFID(1)),
+			Field("port", PrimitiveType("int"), WithFID(2)),
+			Field("debug", PrimitiveType("bool"), WithOptional(), WithFID(3)),
+		).
+		AddPackedStruct("Closed", "v1",
+			Field("name", PrimitiveType("str"), WithFID(1)),
+		).
+		Build()
+}
+
+func TestOpenStruct_UnknownBagRoundTrip(t *testing.T) {
+	schema := makeOpenSchema()
+
+	cases := map[string]*GValue{
+		"dense": Struct("Config",
+			FieldVal("region", Str("eu west")),
+			FieldVal("name", Str("api")),
+			FieldVal("port", Int(8080)),
+			FieldVal("debug", Bool(true)),
+			FieldVal("replicas", Int(3)),
+		),
+		"bitmap": Struct("Config",
+			FieldVal("name", Str("api")),
+			FieldVal("port", Int(8080)),
+			FieldVal("labels", Map(MapEntry{Key: "tier", Value: Str("web")})),
+		),
+	}
+	for name, v := range cases {
+		t.Run(name, func(t *testing.T) {
+			packed, err := EmitPacked(v, schema)
+			if err != nil {
+				t.Fatalf("EmitPacked error: %v", err)
+			}
+			if !strings.Contains(packed, " @unknown{") {
+				t.Fatalf("expected @unknown bag in %s", packed)
+			}
+
+			parsed, err := ParsePacked(packed, schema)
+			if err != nil {
+				t.Fatalf("ParsePacked(%s) error: %v", packed, err)
+			}
+			unknown := schema.GetType("Config").UnknownFields(parsed)
+			if want := schema.GetType("Config").UnknownFields(v); len(unknown) != len(want) {
+				t.Fatalf("unknown fields: got %d, want %d", len(unknown), len(want))
+			}
+
+			again, err := EmitPacked(parsed, schema)
+			if err != nil || again != packed {
+				t.Errorf("round-trip mismatch (err=%v):\n%s\n%s", err, packed, again)
+			}
+		})
+	}
+}
+
+func TestOpenStruct_EmitShape(t *testing.T) {
+	schema := makeOpenSchema()
+	v := Struct("Config",
+		FieldVal("name", Str("api")),
+		FieldVal("port", Int(1)),
+		FieldVal("debug", Bool(false)),
+		FieldVal("zone", Str("b")),
+	)
+	packed, err := EmitPacked(v, schema)
+	if err != nil {
+		t.Fatalf("EmitPacked error: %v", err)
+	}
+	if want := "Config@(api 1 f @unknown{zone:b})"; packed != want {
+		t.Errorf("got %s, want %s", packed, want)
+	}
+}
+
+func TestOpenStruct_ClosedAndErrors(t *testing.T) {
+	schema := makeOpenSchema()
+
+	closed, err := EmitPacked(Struct("Closed", FieldVal("name", Str("x")), FieldVal("extra", Int(1))), schema)
+	if err != nil {
+		t.Fatalf("EmitPacked error: %v", err)
+	}
+	if closed != "Closed@(x)" {
+		t.Errorf("closed type should drop unknowns, got %s", closed)
+	}
+
+	for _, input := range []string{
+		"Closed@(x @unknown{extra:1})",
+		"Config@(api 1 f @unknown{port:2})",
+		"Config@(api 1 f @unknown{zone:b)",
+	} {
+		if _, err := ParsePacked(input, schema); err == nil {
+			t.Errorf("%s: expected parse error", input)
+		}
+	}
+}
+
+func TestOpenStruct_TypedCanonIgnoresUnknown(t *testing.T) {
+	schema := makeOpenSchema()
+	a := Struct("Config", FieldVal("name", Str("api")), FieldVal("port", Int(1)))
+	b := Struct("Config", FieldVal("name", Str("api")), FieldVal("port", Int(1)), FieldVal("zone", Str("b")))
+
+	packed, _ := EmitPacked(b, schema)
+	parsed, err := ParsePacked(packed, schema)
+	if err != nil {
+		t.Fatalf("ParsePacked error: %v", err)
+	}
+	ca, _ := CanonicalizeTyped(a, schema, "Config")
+	cb, _ := CanonicalizeTyped(parsed, schema, "Config")
+	if ca != cb {
+		t.Errorf("unknown fields changed typed form: %s vs %s", ca, cb)
+	}
+}
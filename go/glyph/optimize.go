@@ -0,0 +1,107 @@
+package glyph
+
+// ============================================================
+// Combined Encoding Optimizer
+// ============================================================
+//
+// Auto-tabular and compact keys each shrink output on their own, but they
+// interact: a table already names each key once in its header, so adding a
+// key dictionary on top may cost more than it saves. Optimize emits every
+// combination, discards any that would not parse back, and keeps the one
+// with the fewest tokens. The header of a compact-key encoding is paid once
+// across DocCount documents, as in ChooseKeyEncoding.
+
+// Tokenizer returns the number of tokens s costs. EstimateTokens is the
+// default; pass a model-specific counter for exact costs.
+type Tokenizer func(s string) int
+
+// OptimizeOpts configures Optimize.
+type OptimizeOpts struct {
+	// Base supplies the remaining loose options (null style, bytes encoding,
+	// table limits). Its AutoTabular and compact-key settings are ignored;
+	// Base.Schema, if set, is the key dictionary to try.
+	Base LooseCanonOpts
+
+	Tokenizer Tokenizer // nil means EstimateTokens
+	DocCount  int       // Documents sharing one header (default 1)
+}
+
+// OptimizeCandidate is one encoding considered by Optimize.
+type OptimizeCandidate struct {
+	Tabular      bool
+	CompactKeys  bool
+	BodyTokens   int   // Per document
+	HeaderTokens int   // @schema header, paid once (0 without compact keys)
+	Total        int   // HeaderTokens + DocCount*BodyTokens
+	Err          error // Non-nil if the encoding does not round-trip
+}
+
+// OptimizeResult is the outcome of Optimize.
+type OptimizeResult struct {
+	Output     string              // Best encoding, including any header
+	Chosen     OptimizeCandidate   // Its costs
+	Candidates []OptimizeCandidate // Every combination tried, in order
+}
+
+// Optimize encodes v with the combination of auto-tabular and compact keys
+// that costs the fewest tokens. Candidates are tried plain first, then
+// tabular, compact, and both; a later candidate wins only if strictly
+// cheaper. Each candidate is re-parsed, so Optimize costs several
+// emit/parse passes. If no candidate round-trips, Output is empty.
+func Optimize(v *GValue, opts OptimizeOpts) OptimizeResult {
+	count := opts.Tokenizer
+	if count == nil {
+		count = EstimateTokens
+	}
+	docs := opts.DocCount
+	if docs < 1 {
+		docs = 1
+	}
+
+	dict := opts.Base.Schema
+	if dict == nil {
+		dict = NewSchemaContext(BuildKeyDictFromValue(v))
+	}
+
+	var res OptimizeResult
+	best := -1
+	for _, compact := range []bool{false, true} {
+		for _, tabular := range []bool{false, true} {
+			if compact && len(dict.Keys) == 0 {
+				continue
+			}
+			o := opts.Base
+			o.AutoTabular = tabular
+			o.Schema, o.SchemaRef, o.KeyDict, o.UseCompactKeys = nil, "", nil, false
+			if o.MinRows == 0 {
+				o.MinRows = 3
+			}
+			if o.MaxCols == 0 {
+				o.MaxCols = 20
+			}
+
+			c := OptimizeCandidate{Tabular: tabular, CompactKeys: compact}
+			var out string
+			if compact {
+				o.Schema = dict
+				o.UseCompactKeys = true
+				header := emitSchemaHeader(o) + "\n"
+				body := canonLooseWithOpts(v, o)
+				out = header + body
+				c.HeaderTokens, c.BodyTokens = count(header), count(body)
+			} else {
+				out = canonLooseWithOpts(v, o)
+				c.BodyTokens = count(out)
+			}
+			c.Total = c.HeaderTokens + docs*c.BodyTokens
+			c.Err = verifyLoose(v, out, o)
+
+			res.Candidates = append(res.Candidates, c)
+			if c.Err == nil && (best < 0 || c.Total < res.Chosen.Total) {
+				best = len(res.Candidates) - 1
+				res.Output, res.Chosen = out, c
+			}
+		}
+	}
+	return res
+}
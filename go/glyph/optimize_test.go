@@ -0,0 +1,65 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOptimize_ChoosesCheapest(t *testing.T) {
+	small := Map(MapEntry{Key: "a", Value: Int(1)})
+	res := Optimize(small, OptimizeOpts{Base: DefaultLooseCanonOpts()})
+	if res.Output != "{a=1}" || res.Chosen.CompactKeys || res.Chosen.Tabular {
+		t.Errorf("small value should stay plain: %+v", res)
+	}
+
+	var rows []*GValue
+	for i := 0; i < 30; i++ {
+		rows = append(rows, Map(
+			MapEntry{Key: "conversation_identifier", Value: Int(int64(i))},
+			MapEntry{Key: "message_content", Value: Str(fmt.Sprintf("m%d", i))},
+			MapEntry{Key: "metadata", Value: Map(MapEntry{Key: "sentiment_label", Value: Str("neutral")})},
+		))
+	}
+	big := List(rows...)
+	res = Optimize(big, OptimizeOpts{Base: DefaultLooseCanonOpts()})
+	if len(res.Candidates) != 4 {
+		t.Fatalf("expected 4 candidates, got %d", len(res.Candidates))
+	}
+	for _, c := range res.Candidates {
+		if c.Err != nil {
+			t.Errorf("candidate %+v should round-trip", c)
+		}
+		if c.Total < res.Chosen.Total {
+			t.Errorf("chose %+v over cheaper %+v", res.Chosen, c)
+		}
+	}
+	if !res.Chosen.Tabular || !res.Chosen.CompactKeys {
+		t.Errorf("a table with nested repeated keys should use both: %+v", res.Candidates)
+	}
+	back, err := ParseLoose(res.Output, nil)
+	if err != nil || !EqualLoose(back, big) {
+		t.Errorf("output should parse back: %v", err)
+	}
+}
+
+func TestOptimize_TokenizerAndDocCount(t *testing.T) {
+	doc := Map(
+		MapEntry{Key: "request_identifier", Value: Int(7)},
+		MapEntry{Key: "status", Value: Str("ok")},
+	)
+	if res := Optimize(doc, OptimizeOpts{}); res.Chosen.CompactKeys {
+		t.Errorf("one document should not pay for a header: %+v", res.Chosen)
+	}
+	res := Optimize(doc, OptimizeOpts{DocCount: 1000})
+	if !res.Chosen.CompactKeys || !strings.HasPrefix(res.Output, "@schema#") {
+		t.Errorf("header should pay off across documents: %+v", res.Chosen)
+	}
+
+	// A tokenizer that charges only for '#' makes compact keys the worst choice.
+	hashes := func(s string) int { return strings.Count(s, "#") }
+	res = Optimize(doc, OptimizeOpts{DocCount: 1000, Tokenizer: hashes})
+	if res.Chosen.CompactKeys {
+		t.Errorf("custom tokenizer should drive the choice: %+v", res.Chosen)
+	}
+}
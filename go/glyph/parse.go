@@ -1,16 +1,23 @@
 package glyph
 
 import (
-	"encoding/base64"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // ParseError represents a parsing error with location.
+//
+// Warnings carry a Code naming what the tolerant parser did: auto_closed,
+// missing_separator, bad_key, duplicate_key, coerced_null, time_as_string,
+// repaired_number, trailing_token, deprecated_type, deprecated_field or
+// unresolved_schema_ref. Errors have no Code.
 type ParseError struct {
 	Message string
+	Code    string // Machine-readable warning kind
 	Pos     Position
 }
 
@@ -40,14 +47,72 @@ type Parser struct {
 	schema   *Schema
 	errors   []ParseError
 	warnings []ParseError
-	tolerant bool // Enable tolerant parsing mode
-	depth    int  // Current recursive descent depth
+	tolerant bool      // Enable tolerant parsing mode
+	blobs    BlobStore // Resolves @blob declarations (optional)
+	intern   *Interner // Deduplicates keys and short strings (optional)
+	depth    int       // Current recursive descent depth
+	trace    func(TraceEvent)
+	rule     string // Rule being parsed, for trace events
 }
 
 // ParseOptions configures the parser behavior.
 type ParseOptions struct {
 	Schema   *Schema // Schema for type-aware parsing
 	Tolerant bool    // Enable tolerant/repair mode
+
+	// Blobs resolves @blob <id> <n-bytes> declarations to bytes values.
+	Blobs BlobStore
+
+	// FillDefaults restores optional struct fields omitted by sparse
+	// emission (see FillDefaults). Requires Schema.
+	FillDefaults bool
+
+	// Interner, if set, deduplicates map keys, field names, and string
+	// values across this and any other parse sharing it.
+	Interner *Interner
+
+	// TraceFn, if set, is called with each decision the parser makes that
+	// shapes the value: every repair and error, and every choice between
+	// readings of the same tokens. It shows why tolerant parsing produced
+	// what it did.
+	TraceFn func(TraceEvent)
+}
+
+// TraceEvent is one parser decision reported to ParseOptions.TraceFn.
+//
+// Rule is the grammar rule deciding: document, value, list, map, struct,
+// sum, ident, number or annotation. Action is what the parser did: the Code
+// of a warning it added (see ParseError), "error" for an error, or one of
+//
+//	struct, sum, bare_string  how ident read a name (Name{, Name(, or alone)
+//	not_repaired              number left a numeric field as written
+//	skip_schema               annotation skipped an inline @schema{...}
+type TraceEvent struct {
+	Rule   string
+	Action string
+	Token  Token    // Token the parser was at
+	Pos    Position // Where the decision applies
+	Detail string
+}
+
+// tracef reports a decision at pos to the TraceFn, if any.
+func (p *Parser) tracef(action string, pos Position, format string, args ...interface{}) {
+	if p.trace == nil {
+		return
+	}
+	p.trace(TraceEvent{Rule: p.rule, Action: action, Token: p.stream.Peek(), Pos: pos, Detail: fmt.Sprintf(format, args...)})
+}
+
+// enter sets the rule trace events report and returns the one it replaces,
+// for leave.
+func (p *Parser) enter(rule string) string {
+	prev := p.rule
+	p.rule = rule
+	return prev
+}
+
+func (p *Parser) leave(prev string) {
+	p.rule = prev
 }
 
 // Parse parses GLYPH-T text into a GValue.
@@ -72,9 +137,16 @@ func ParseWithOptions(input string, opts ParseOptions) (*ParseResult, error) {
 		stream:   NewTokenStream(tokens),
 		schema:   opts.Schema,
 		tolerant: opts.Tolerant,
+		blobs:    opts.Blobs,
+		intern:   opts.Interner,
+		trace:    opts.TraceFn,
+		rule:     "document",
 	}
 
 	value := p.parseValue()
+	if opts.FillDefaults {
+		value = FillDefaults(opts.Schema, value)
+	}
 	result := &ParseResult{
 		Value:    value,
 		Errors:   p.errors,
@@ -85,7 +157,7 @@ func ParseWithOptions(input string, opts ParseOptions) (*ParseResult, error) {
 	if next := p.stream.Peek(); next.Type != TokenEOF {
 		msg := fmt.Sprintf("unexpected trailing token %s", next.Type)
 		if p.tolerant {
-			p.addWarning(next.Pos, "%s", msg)
+			p.addWarning(next.Pos, "trailing_token", "%s", msg)
 		} else {
 			p.addError(next.Pos, "%s", msg)
 		}
@@ -101,6 +173,7 @@ func ParseWithOptions(input string, opts ParseOptions) (*ParseResult, error) {
 // parseValue parses any value.
 func (p *Parser) parseValue() *GValue {
 	p.depth++
+	defer p.leave(p.enter("value"))
 	defer func() { p.depth-- }()
 	// p.depth counts every value frame, including the top-level value and the
 	// innermost leaf; the nesting depth (enclosing containers) is p.depth-1.
@@ -110,6 +183,10 @@ func (p *Parser) parseValue() *GValue {
 		return nil
 	}
 
+	if omitted, ok := p.parseOmission(); ok {
+		return withOmitted(Null(), omitted)
+	}
+
 	tok := p.stream.Peek()
 
 	switch tok.Type {
@@ -127,6 +204,9 @@ func (p *Parser) parseValue() *GValue {
 
 	case TokenInt:
 		p.stream.Advance()
+		if isRepairableNumber(tok.Value) {
+			return p.parseRepairableNumber(tok)
+		}
 		v, err := strconv.ParseInt(tok.Value, 10, 64)
 		if err != nil {
 			p.addError(tok.Pos, "invalid integer %q: %v", tok.Value, err)
@@ -136,6 +216,9 @@ func (p *Parser) parseValue() *GValue {
 
 	case TokenFloat:
 		p.stream.Advance()
+		if isRepairableNumber(tok.Value) {
+			return p.parseRepairableNumber(tok)
+		}
 		v, err := strconv.ParseFloat(tok.Value, 64)
 		if err != nil {
 			p.addError(tok.Pos, "invalid float %q: %v", tok.Value, err)
@@ -145,12 +228,42 @@ func (p *Parser) parseValue() *GValue {
 
 	case TokenString:
 		p.stream.Advance()
-		return Str(tok.Value)
+		return Str(p.intern.Intern(tok.Value))
 
 	case TokenBytes:
 		p.stream.Advance()
 		return p.parseBytes(tok.Value, tok.Pos)
 
+	case TokenGeo:
+		p.stream.Advance()
+		prefix, body, _ := strings.Cut(tok.Value, ":")
+		v, err := decodeGeoLiteral(prefix, body)
+		if err != nil {
+			p.addError(tok.Pos, "%v", err)
+			return Null()
+		}
+		return v
+
+	case TokenVec:
+		p.stream.Advance()
+		prefix, body, _ := strings.Cut(tok.Value, ":")
+		v, err := decodeVecLiteral(prefix, body)
+		if err != nil {
+			p.addError(tok.Pos, "%v", err)
+			return Null()
+		}
+		return v
+
+	case TokenCustom:
+		p.stream.Advance()
+		tag, text, _ := strings.Cut(tok.Value, ":")
+		v, err := decodeScalarLiteral(tag, text)
+		if err != nil {
+			p.addError(tok.Pos, "%v", err)
+			return Null()
+		}
+		return v
+
 	case TokenRef:
 		p.stream.Advance()
 		return p.parseRef(tok.Value)
@@ -171,7 +284,7 @@ func (p *Parser) parseValue() *GValue {
 
 	case TokenBareStr:
 		p.stream.Advance()
-		return Str(tok.Value)
+		return Str(p.intern.Intern(tok.Value))
 
 	case TokenAt:
 		// Schema block or annotation - skip for value parsing
@@ -182,7 +295,7 @@ func (p *Parser) parseValue() *GValue {
 			// Recover by coercing to null, but make the substitution loud: a
 			// silent null could be mistaken for an intentional value by a
 			// downstream tool-execution consumer.
-			p.addWarning(tok.Pos, "unexpected token %s; coercing to null (value discarded)", tok.Type)
+			p.addWarning(tok.Pos, "coerced_null", "unexpected token %s; coercing to null (value discarded)", tok.Type)
 			p.stream.Advance()
 			return Null()
 		}
@@ -201,16 +314,62 @@ func (p *Parser) parseRef(value string) *GValue {
 	return ID("", value)
 }
 
-// parseBytes decodes the base64 body of a b64"..." literal into TypeBytes.
-// Invalid base64 is a hard error (never silently coerced to a string), so that
-// corrupt binary payloads can't masquerade as valid data.
+// parseBytes decodes the body of a bytes literal (token value
+// "<prefix>:<body>") into TypeBytes. Invalid encodings are a hard error (never
+// silently coerced to a string), so that corrupt binary payloads can't
+// masquerade as valid data.
 func (p *Parser) parseBytes(value string, pos Position) *GValue {
-	decoded, err := base64.StdEncoding.DecodeString(value)
+	prefix, body, _ := strings.Cut(value, ":")
+	v, err := decodeBytesLiteral(prefix, body)
+	if err != nil {
+		p.addError(pos, "%v", err)
+		return Null()
+	}
+	return v
+}
+
+// parseBlob resolves an @blob <id> <n-bytes> declaration (the "@blob" has been
+// consumed) through the BlobStore in ParseOptions.Blobs. A missing store, an
+// unknown id, or a length mismatch is an error; tolerant mode downgrades it to
+// a warning and yields null.
+func (p *Parser) parseBlob(pos Position) *GValue {
+	idTok := p.stream.Peek()
+	if idTok.Type != TokenString && idTok.Type != TokenIdent {
+		p.addError(idTok.Pos, "expected blob id after @blob, got %s", idTok.Type)
+		return Null()
+	}
+	p.stream.Advance()
+	sizeTok := p.stream.Peek()
+	if sizeTok.Type != TokenInt {
+		p.addError(sizeTok.Pos, "expected byte length after @blob %s, got %s", idTok.Value, sizeTok.Type)
+		return Null()
+	}
+	p.stream.Advance()
+	size, err := strconv.Atoi(sizeTok.Value)
+	if err != nil || size < 0 {
+		p.addError(sizeTok.Pos, "invalid blob length %q", sizeTok.Value)
+		return Null()
+	}
+
+	var data []byte
+	switch {
+	case p.blobs == nil:
+		err = fmt.Errorf("no blob store to resolve @blob %s", idTok.Value)
+	default:
+		data, err = p.blobs.Get(idTok.Value)
+		if err == nil && len(data) != size {
+			err = fmt.Errorf("blob %s has %d bytes, declared %d", idTok.Value, len(data), size)
+		}
+	}
 	if err != nil {
-		p.addError(pos, "invalid base64 in bytes literal: %v", err)
+		if p.tolerant {
+			p.addWarning(pos, "coerced_null", "%v; coercing to null (value discarded)", err)
+		} else {
+			p.addError(pos, "%v", err)
+		}
 		return Null()
 	}
-	return Bytes(decoded)
+	return Bytes(data)
 }
 
 // parseTime parses an ISO-8601 time value.
@@ -232,7 +391,7 @@ func (p *Parser) parseTime(value string, pos Position) *GValue {
 	}
 
 	if p.tolerant {
-		p.addWarning(pos, "invalid time format, treating as string: %s", value)
+		p.addWarning(pos, "time_as_string", "invalid time format, treating as string: %s", value)
 		return Str(value)
 	}
 	p.addError(pos, "invalid time format: %s", value)
@@ -241,9 +400,11 @@ func (p *Parser) parseTime(value string, pos Position) *GValue {
 
 // parseList parses a list: [v1 v2 v3] or [v1, v2, v3]
 func (p *Parser) parseList() *GValue {
+	defer p.leave(p.enter("list"))
 	p.stream.Advance() // consume [
 
 	var elements []*GValue
+	omitted := 0
 	for {
 		tok := p.stream.Peek()
 
@@ -254,7 +415,7 @@ func (p *Parser) parseList() *GValue {
 
 		if tok.Type == TokenEOF {
 			if p.tolerant {
-				p.addWarning(tok.Pos, "unterminated list, auto-closing")
+				p.addWarning(tok.Pos, "auto_closed", "unterminated list, auto-closing")
 				break
 			}
 			p.addError(tok.Pos, "unterminated list")
@@ -267,13 +428,18 @@ func (p *Parser) parseList() *GValue {
 			continue
 		}
 
+		if n, ok := p.parseOmission(); ok {
+			omitted = addOmitted(omitted, n)
+			continue
+		}
+
 		elem := p.parseValue()
 		if elem != nil {
 			elements = append(elements, elem)
 		}
 	}
 
-	return List(elements...)
+	return withOmitted(List(elements...), omitted)
 }
 
 // parseMap parses a map: {k:v k2:v2} or {k=v, k2=v2}
@@ -282,9 +448,11 @@ func (p *Parser) parseList() *GValue {
 // entry in place (preserving the original key position) and emits a warning, so
 // the result is deterministic and free of ambiguous duplicate keys.
 func (p *Parser) parseMap() *GValue {
+	defer p.leave(p.enter("map"))
 	p.stream.Advance() // consume {
 
 	var entries []MapEntry
+	omitted := 0
 	for {
 		tok := p.stream.Peek()
 
@@ -295,7 +463,7 @@ func (p *Parser) parseMap() *GValue {
 
 		if tok.Type == TokenEOF {
 			if p.tolerant {
-				p.addWarning(tok.Pos, "unterminated map, auto-closing")
+				p.addWarning(tok.Pos, "auto_closed", "unterminated map, auto-closing")
 				break
 			}
 			p.addError(tok.Pos, "unterminated map")
@@ -308,20 +476,25 @@ func (p *Parser) parseMap() *GValue {
 			continue
 		}
 
+		if n, ok := p.parseOmission(); ok {
+			omitted = addOmitted(omitted, n)
+			continue
+		}
+
 		entry := p.parseMapEntry()
 		if entry != nil {
 			entries = p.appendMapEntry(entries, *entry, tok.Pos)
 		}
 	}
 
-	return Map(entries...)
+	return withOmitted(Map(entries...), omitted)
 }
 
 // appendMapEntry adds an entry, applying the last-wins duplicate-key policy.
 func (p *Parser) appendMapEntry(entries []MapEntry, entry MapEntry, pos Position) []MapEntry {
 	for i := range entries {
 		if entries[i].Key == entry.Key {
-			p.addWarning(pos, "duplicate map key %q; last value wins", entry.Key)
+			p.addWarning(pos, "duplicate_key", "duplicate map key %q; last value wins", entry.Key)
 			entries[i].Value = entry.Value
 			return entries
 		}
@@ -337,14 +510,14 @@ func (p *Parser) parseMapEntry() *MapEntry {
 
 	switch keyTok.Type {
 	case TokenIdent:
-		key = keyTok.Value
+		key = p.intern.Intern(keyTok.Value)
 		p.stream.Advance()
 	case TokenString:
-		key = keyTok.Value
+		key = p.intern.Intern(keyTok.Value)
 		p.stream.Advance()
 	default:
 		if p.tolerant {
-			p.addWarning(keyTok.Pos, "expected key, got %s", keyTok.Type)
+			p.addWarning(keyTok.Pos, "bad_key", "expected key, got %s", keyTok.Type)
 			p.stream.Advance()
 			return nil
 		}
@@ -357,7 +530,7 @@ func (p *Parser) parseMapEntry() *MapEntry {
 	if !p.stream.Match(TokenEq) {
 		if p.tolerant {
 			// Try to continue - maybe the value follows directly
-			p.addWarning(p.stream.Peek().Pos, "expected = or :, continuing")
+			p.addWarning(p.stream.Peek().Pos, "missing_separator", "expected = or :, continuing")
 		} else {
 			p.addError(p.stream.Peek().Pos, "expected = or :")
 			p.advanceAfterError()
@@ -376,6 +549,7 @@ func (p *Parser) parseMapEntry() *MapEntry {
 // - Tag(value) or Tag{...} (sum)
 // - bare string value
 func (p *Parser) parseIdentValue() *GValue {
+	defer p.leave(p.enter("ident"))
 	identTok := p.stream.Advance()
 	name := identTok.Value
 
@@ -384,23 +558,31 @@ func (p *Parser) parseIdentValue() *GValue {
 	switch next.Type {
 	case TokenLBrace:
 		// TypeName{...} - struct or inline sum variant
+		p.tracef("struct", identTok.Pos, "%s followed by {", name)
+		if td := p.schema.GetType(name); td != nil && td.Deprecated != nil {
+			p.addWarning(identTok.Pos, "deprecated_type", "%s", td.Deprecated.describe("type "+name))
+		}
 		return p.parseStruct(name)
 
 	case TokenLParen:
 		// Tag(value) - sum type
+		p.tracef("sum", identTok.Pos, "%s followed by (", name)
 		return p.parseSum(name)
 
 	default:
 		// Just a bare string
-		return Str(name)
+		p.tracef("bare_string", identTok.Pos, "%s followed by %s", name, next.Type)
+		return Str(p.intern.Intern(name))
 	}
 }
 
 // parseStruct parses a typed struct: TypeName{field=value ...}
 func (p *Parser) parseStruct(typeName string) *GValue {
+	defer p.leave(p.enter("struct"))
 	p.stream.Advance() // consume {
 
 	var fields []MapEntry
+	omitted := 0
 	for {
 		tok := p.stream.Peek()
 
@@ -411,7 +593,7 @@ func (p *Parser) parseStruct(typeName string) *GValue {
 
 		if tok.Type == TokenEOF {
 			if p.tolerant {
-				p.addWarning(tok.Pos, "unterminated struct, auto-closing")
+				p.addWarning(tok.Pos, "auto_closed", "unterminated struct, auto-closing")
 				break
 			}
 			p.addError(tok.Pos, "unterminated struct")
@@ -424,13 +606,18 @@ func (p *Parser) parseStruct(typeName string) *GValue {
 			continue
 		}
 
+		if n, ok := p.parseOmission(); ok {
+			omitted = addOmitted(omitted, n)
+			continue
+		}
+
 		entry := p.parseStructField(typeName)
 		if entry != nil {
 			fields = append(fields, *entry)
 		}
 	}
 
-	return Struct(typeName, fields...)
+	return withOmitted(Struct(typeName, fields...), omitted)
 }
 
 // parseStructField parses a struct field, resolving wire keys if schema present.
@@ -441,14 +628,14 @@ func (p *Parser) parseStructField(typeName string) *MapEntry {
 
 	switch keyTok.Type {
 	case TokenIdent:
-		key = keyTok.Value
+		key = p.intern.Intern(keyTok.Value)
 		p.stream.Advance()
 	case TokenString:
-		key = keyTok.Value
+		key = p.intern.Intern(keyTok.Value)
 		p.stream.Advance()
 	default:
 		if p.tolerant {
-			p.addWarning(keyTok.Pos, "expected field name, got %s", keyTok.Type)
+			p.addWarning(keyTok.Pos, "bad_key", "expected field name, got %s", keyTok.Type)
 			p.stream.Advance()
 			return nil
 		}
@@ -463,12 +650,15 @@ func (p *Parser) parseStructField(typeName string) *MapEntry {
 		if fullName != key {
 			key = fullName
 		}
+		if fd := p.schema.GetField(typeName, key); fd != nil && fd.Deprecated != nil {
+			p.addWarning(keyTok.Pos, "deprecated_field", "%s", fd.Deprecated.describe("field "+typeName+"."+fd.Name))
+		}
 	}
 
 	// Expect = or :
 	if !p.stream.Match(TokenEq) {
 		if p.tolerant {
-			p.addWarning(p.stream.Peek().Pos, "expected = or :, continuing")
+			p.addWarning(p.stream.Peek().Pos, "missing_separator", "expected = or :, continuing")
 		} else {
 			p.addError(p.stream.Peek().Pos, "expected = or :")
 			p.advanceAfterError()
@@ -477,13 +667,112 @@ func (p *Parser) parseStructField(typeName string) *MapEntry {
 	}
 
 	// Parse value
+	if kind, ok := p.numericFieldKind(typeName, key); ok {
+		return &MapEntry{Key: key, Value: p.parseNumericField(kind)}
+	}
 	value := p.parseValue()
 
 	return &MapEntry{Key: key, Value: value}
 }
 
+// isRepairableNumber reports whether a number token carries a leading '+' or
+// trailing '%', which the lexer accepts only so the parser can repair them.
+func isRepairableNumber(lit string) bool {
+	return strings.HasPrefix(lit, "+") || strings.HasSuffix(lit, "%")
+}
+
+// parseRepairableNumber handles a "+5" or "12%" token outside a schema-typed
+// field. Tolerant mode repairs it with a warning (a percentage becomes a float
+// fraction); strict mode rejects it.
+func (p *Parser) parseRepairableNumber(tok Token) *GValue {
+	defer p.leave(p.enter("number"))
+	kind := TypeSpecInt
+	if tok.Type == TokenFloat || strings.HasSuffix(tok.Value, "%") {
+		kind = TypeSpecFloat
+	}
+	if p.tolerant {
+		if v, repairs := repairNumber(tok.Value, kind); v != nil {
+			p.addRepairWarning(tok.Pos, tok.Value, v, repairs)
+			return v
+		}
+	}
+	p.addError(tok.Pos, "invalid number %q", tok.Value)
+	return Null()
+}
+
+// numericFieldKind returns TypeSpecInt or TypeSpecFloat when tolerant parsing
+// with a schema and field key of typeName is declared as that kind.
+func (p *Parser) numericFieldKind(typeName, key string) (TypeSpecKind, bool) {
+	if !p.tolerant || p.schema == nil {
+		return 0, false
+	}
+	fd := p.schema.GetField(typeName, key)
+	if fd == nil || (fd.Type.Kind != TypeSpecInt && fd.Type.Kind != TypeSpecFloat) {
+		return 0, false
+	}
+	return fd.Type.Kind, true
+}
+
+// parseNumericField parses the value of a schema-declared int or float field
+// in tolerant mode, repairing locale-style numbers instead of letting them
+// split into several values or survive as strings. Unquoted "1,234" lexes as
+// INT , INT; the pieces are rejoined only when no whitespace separates them.
+func (p *Parser) parseNumericField(kind TypeSpecKind) *GValue {
+	defer p.leave(p.enter("number"))
+	tok := p.stream.Peek()
+	switch tok.Type {
+	case TokenInt, TokenFloat:
+		lit := tok.Value
+		end := tok.Pos.Offset + len(tok.Value)
+		n := 1
+		for {
+			comma, next := p.stream.PeekN(n), p.stream.PeekN(n+1)
+			if comma.Type != TokenComma || comma.Pos.Offset != end ||
+				(next.Type != TokenInt && next.Type != TokenFloat) || next.Pos.Offset != end+1 {
+				break
+			}
+			lit += "," + next.Value
+			end = next.Pos.Offset + len(next.Value)
+			n += 2
+		}
+		if n == 1 && !isRepairableNumber(lit) {
+			return p.parseValue()
+		}
+		if v, repairs := repairNumber(lit, kind); v != nil {
+			for i := 0; i < n; i++ {
+				p.stream.Advance()
+			}
+			p.addRepairWarning(tok.Pos, lit, v, repairs)
+			return v
+		}
+		p.tracef("not_repaired", tok.Pos, "%q is not a repairable %s", lit, numericKindName(kind))
+		return p.parseValue()
+
+	case TokenString:
+		if v, repairs := repairNumber(tok.Value, kind); v != nil {
+			p.stream.Advance()
+			p.addRepairWarning(tok.Pos, tok.Value, v, repairs)
+			return v
+		}
+		p.tracef("not_repaired", tok.Pos, "%q is not a repairable %s", tok.Value, numericKindName(kind))
+	}
+	return p.parseValue()
+}
+
+func numericKindName(kind TypeSpecKind) string {
+	if kind == TypeSpecInt {
+		return "int"
+	}
+	return "float"
+}
+
+func (p *Parser) addRepairWarning(pos Position, lit string, v *GValue, repairs []string) {
+	p.addWarning(pos, "repaired_number", "repaired number %q -> %s (%s)", lit, CanonicalizeLoose(v), strings.Join(repairs, ", "))
+}
+
 // parseSum parses a sum type: Tag(value)
 func (p *Parser) parseSum(tag string) *GValue {
+	defer p.leave(p.enter("sum"))
 	p.stream.Advance() // consume (
 
 	var value *GValue
@@ -497,7 +786,7 @@ func (p *Parser) parseSum(tag string) *GValue {
 
 		if !p.stream.Match(TokenRParen) {
 			if p.tolerant {
-				p.addWarning(p.stream.Peek().Pos, "expected ), auto-closing sum")
+				p.addWarning(p.stream.Peek().Pos, "auto_closed", "expected ), auto-closing sum")
 			} else {
 				p.addError(p.stream.Peek().Pos, "expected )")
 				p.advanceAfterError()
@@ -510,10 +799,16 @@ func (p *Parser) parseSum(tag string) *GValue {
 
 // parseSchemaAnnotatedValue handles @schema{...} or @schema#hash references.
 func (p *Parser) parseSchemaAnnotatedValue() *GValue {
-	p.stream.Advance() // consume @
+	defer p.leave(p.enter("annotation"))
+	at := p.stream.Advance() // consume @
 
 	tok := p.stream.Peek()
 
+	if tok.Type == TokenIdent && tok.Value == "blob" {
+		p.stream.Advance()
+		return p.parseBlob(at.Pos)
+	}
+
 	if tok.Type == TokenIdent && tok.Value == "schema" {
 		p.stream.Advance()
 
@@ -525,10 +820,11 @@ func (p *Parser) parseSchemaAnnotatedValue() *GValue {
 			if hashTok.Type == TokenIdent || hashTok.Type == TokenBareStr {
 				p.stream.Advance()
 				// Store schema hash for later lookup
-				p.addWarning(hashTok.Pos, "schema reference: %s (lookup not implemented)", hashTok.Value)
+				p.addWarning(hashTok.Pos, "unresolved_schema_ref", "schema reference: %s (lookup not implemented)", hashTok.Value)
 			}
 		} else if next.Type == TokenLBrace {
 			// @schema{...} - inline schema
+			p.tracef("skip_schema", next.Pos, "inline schema block")
 			p.skipSchemaBlock()
 		}
 	}
@@ -562,13 +858,16 @@ func (p *Parser) addError(pos Position, format string, args ...interface{}) {
 		Message: fmt.Sprintf(format, args...),
 		Pos:     pos,
 	})
+	p.tracef("error", pos, "%s", p.errors[len(p.errors)-1].Message)
 }
 
-func (p *Parser) addWarning(pos Position, format string, args ...interface{}) {
+func (p *Parser) addWarning(pos Position, code, format string, args ...interface{}) {
 	p.warnings = append(p.warnings, ParseError{
 		Message: fmt.Sprintf(format, args...),
+		Code:    code,
 		Pos:     pos,
 	})
+	p.tracef(code, pos, "%s", p.warnings[len(p.warnings)-1].Message)
 }
 
 func (p *Parser) advanceAfterError() {
@@ -581,9 +880,10 @@ func (p *Parser) advanceAfterError() {
 // Schema Parsing
 // ============================================================
 
-// ParseSchema parses a GLYPH schema definition.
+// ParseSchema parses a GLYPH schema definition. Syntax errors are
+// *ParseError values carrying the position of the offending token.
 func ParseSchema(input string) (*Schema, error) {
-	lexer := NewLexer(input)
+	lexer := newSchemaLexer(input)
 	tokens, err := lexer.Tokenize()
 	if err != nil {
 		return nil, err
@@ -591,6 +891,7 @@ func ParseSchema(input string) (*Schema, error) {
 
 	p := &schemaParser{
 		stream: NewTokenStream(tokens),
+		docs:   lexer.docs,
 	}
 
 	return p.parseSchema()
@@ -599,24 +900,63 @@ func ParseSchema(input string) (*Schema, error) {
 type schemaParser struct {
 	stream *TokenStream
 	errors []ParseError
+	docs   map[int]string // /// doc comments by offset of the next token
+}
+
+// errorf returns a syntax error at tok.
+func (p *schemaParser) errorf(tok Token, format string, args ...interface{}) error {
+	return &ParseError{Message: fmt.Sprintf(format, args...), Pos: tok.Pos}
+}
+
+// expect consumes a token of type typ, or fails with "expected <what>".
+func (p *schemaParser) expect(typ TokenType, what string) (Token, error) {
+	tok := p.stream.Peek()
+	if tok.Type != typ {
+		return tok, p.errorf(tok, "expected %s, got %s", what, describeToken(tok))
+	}
+	return p.stream.Advance(), nil
+}
+
+// number consumes a number, or fails with "expected number <context>".
+func (p *schemaParser) number(context string) (float64, error) {
+	tok := p.stream.Peek()
+	if tok.Type == TokenInt || tok.Type == TokenFloat {
+		if v, err := strconv.ParseFloat(tok.Value, 64); err == nil && !math.IsNaN(v) {
+			p.stream.Advance()
+			return v, nil
+		}
+	}
+	return 0, p.errorf(tok, "expected number %s, got %s", context, describeToken(tok))
+}
+
+// describeToken names tok in an error message.
+func describeToken(tok Token) string {
+	switch tok.Type {
+	case TokenEOF:
+		return "end of input"
+	case TokenString:
+		return "string " + strconv.Quote(tok.Value)
+	}
+	return strconv.Quote(tok.Value)
 }
 
 func (p *schemaParser) parseSchema() (*Schema, error) {
 	schema := &Schema{Types: make(map[string]*TypeDef)}
 
 	// Expect @schema{
-	if !p.stream.Match(TokenAt) {
-		return nil, fmt.Errorf("expected @schema")
+	if _, err := p.expect(TokenAt, "@schema"); err != nil {
+		return nil, err
 	}
 
 	tok := p.stream.Peek()
 	if tok.Type != TokenIdent || tok.Value != "schema" {
-		return nil, fmt.Errorf("expected @schema, got @%s", tok.Value)
+		return nil, p.errorf(tok, "expected @schema, got @%s", tok.Value)
 	}
 	p.stream.Advance()
 
-	if !p.stream.Match(TokenLBrace) {
-		return nil, fmt.Errorf("expected { after @schema")
+	open, err := p.expect(TokenLBrace, "{ after @schema")
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse type definitions
@@ -627,7 +967,7 @@ func (p *schemaParser) parseSchema() (*Schema, error) {
 			break
 		}
 		if tok.Type == TokenEOF {
-			return nil, fmt.Errorf("unterminated schema")
+			return nil, p.errorf(tok, "unterminated schema opened at %s", open.Pos)
 		}
 
 		typeDef, err := p.parseTypeDef()
@@ -635,17 +975,25 @@ func (p *schemaParser) parseSchema() (*Schema, error) {
 			return nil, err
 		}
 		if typeDef != nil {
+			if _, dup := schema.Types[typeDef.Name]; dup {
+				return nil, p.errorf(tok, "duplicate type %s", typeDef.Name)
+			}
 			schema.Types[typeDef.Name] = typeDef
 		}
 	}
 
+	if tok := p.stream.Peek(); tok.Type != TokenEOF {
+		return nil, p.errorf(tok, "unexpected %s after schema", describeToken(tok))
+	}
+
+	schema.qualifyRefs()
 	schema.ComputeHash()
 	return schema, nil
 }
 
 func (p *schemaParser) parseTypeDef() (*TypeDef, error) {
 	// Name[:version] struct{...} or Name sum{...}
-	nameTok, err := p.stream.Expect(TokenIdent)
+	nameTok, err := p.expect(TokenIdent, "type name")
 	if err != nil {
 		return nil, err
 	}
@@ -653,27 +1001,32 @@ func (p *schemaParser) parseTypeDef() (*TypeDef, error) {
 	name := nameTok.Value
 	version := ""
 
-	// Check for :version
-	if p.stream.Match(TokenEq) { // : was lexed as TokenEq
+	// :version is an identifier (v2) or a number (2, 1.5).
+	if p.stream.Match(TokenColon) {
 		verTok := p.stream.Peek()
-		if verTok.Type == TokenIdent {
+		switch {
+		case verTok.Type == TokenIdent && verTok.Value != "struct" && verTok.Value != "sum",
+			verTok.Type == TokenInt, verTok.Type == TokenFloat:
 			version = verTok.Value
 			p.stream.Advance()
+		default:
+			return nil, p.errorf(verTok, "expected version after %s:, got %s", name, describeToken(verTok))
 		}
 	}
 
 	td := &TypeDef{
 		Name:    name,
 		Version: version,
+		Doc:     p.docs[nameTok.Pos.Offset],
 	}
 
-	// Type-level flags: @pack @tab @open (emitted by writeTypeDef). These
+	// Type-level flags: @pack @tab @open @deprecated (emitted by writeTypeDef). These
 	// appear between the name/version and the struct/sum keyword.
 	for p.stream.Peek().Type == TokenAt {
 		p.stream.Advance() // consume @
-		flag, err := p.stream.Expect(TokenIdent)
+		flag, err := p.expect(TokenIdent, "type annotation name after @")
 		if err != nil {
-			return nil, fmt.Errorf("expected type annotation name after @")
+			return nil, err
 		}
 		switch flag.Value {
 		case "pack":
@@ -682,13 +1035,17 @@ func (p *schemaParser) parseTypeDef() (*TypeDef, error) {
 			td.TabEnabled = true
 		case "open":
 			td.Open = true
+		case "deprecated":
+			if td.Deprecated, err = p.parseDeprecation(); err != nil {
+				return nil, err
+			}
 		default:
-			return nil, fmt.Errorf("unknown type annotation @%s", flag.Value)
+			return nil, p.errorf(flag, "unknown type annotation @%s", flag.Value)
 		}
 	}
 
 	// struct or sum
-	kindTok, err := p.stream.Expect(TokenIdent)
+	kindTok, err := p.expect(TokenIdent, "struct or sum after type "+name)
 	if err != nil {
 		return nil, err
 	}
@@ -711,18 +1068,19 @@ func (p *schemaParser) parseTypeDef() (*TypeDef, error) {
 		td.Sum = sumDef
 
 	default:
-		return nil, fmt.Errorf("expected struct or sum, got %s", kindTok.Value)
+		return nil, p.errorf(kindTok, "expected struct or sum after type %s, got %s", name, describeToken(kindTok))
 	}
 
 	return td, nil
 }
 
 func (p *schemaParser) parseStructDef() (*StructDef, error) {
-	if !p.stream.Match(TokenLBrace) {
-		return nil, fmt.Errorf("expected { after struct")
+	open, err := p.expect(TokenLBrace, "{ after struct")
+	if err != nil {
+		return nil, err
 	}
 
-	var fields []*FieldDef
+	def := &StructDef{}
 	for {
 		tok := p.stream.Peek()
 		if tok.Type == TokenRBrace {
@@ -730,28 +1088,45 @@ func (p *schemaParser) parseStructDef() (*StructDef, error) {
 			break
 		}
 		if tok.Type == TokenEOF {
-			return nil, fmt.Errorf("unterminated struct definition")
+			return nil, p.errorf(tok, "unterminated struct opened at %s", open.Pos)
+		}
+
+		if p.isAssertStart() {
+			a, err := p.parseAssert()
+			if err != nil {
+				return nil, err
+			}
+			def.Asserts = append(def.Asserts, a)
+			continue
+		}
+		if p.isOneOfStart() {
+			group, err := p.parseOneOf()
+			if err != nil {
+				return nil, err
+			}
+			def.OneOf = append(def.OneOf, group)
+			continue
 		}
 
 		field, err := p.parseFieldDef()
 		if err != nil {
 			return nil, err
 		}
-		fields = append(fields, field)
+		def.Fields = append(def.Fields, field)
 	}
 
-	return &StructDef{Fields: fields}, nil
+	return def, nil
 }
 
 func (p *schemaParser) parseFieldDef() (*FieldDef, error) {
 	// name: Type [constraints] @k(wireKey)
-	nameTok, err := p.stream.Expect(TokenIdent)
+	nameTok, err := p.expect(TokenIdent, "field name")
 	if err != nil {
 		return nil, err
 	}
 
-	if !p.stream.Match(TokenEq) { // : was lexed as TokenEq
-		return nil, fmt.Errorf("expected : after field name")
+	if _, err := p.expect(TokenColon, ": after field "+nameTok.Value); err != nil {
+		return nil, err
 	}
 
 	typeSpec, err := p.parseTypeSpec()
@@ -762,6 +1137,7 @@ func (p *schemaParser) parseFieldDef() (*FieldDef, error) {
 	field := &FieldDef{
 		Name: nameTok.Value,
 		Type: typeSpec,
+		Doc:  p.docs[nameTok.Pos.Offset],
 	}
 
 	// Parse optional constraints and annotations
@@ -781,73 +1157,83 @@ func (p *schemaParser) parseFieldDef() (*FieldDef, error) {
 			continue
 		}
 
-		if tok.Type == TokenAt {
+		if tok.Type == TokenAt && !p.isAssertStart() && !p.isOneOfStart() {
 			p.stream.Advance() // consume @
-			annot, err := p.stream.Expect(TokenIdent)
+			annot, err := p.expect(TokenIdent, "annotation name after @")
 			if err != nil {
-				return nil, fmt.Errorf("expected annotation name after @")
+				return nil, err
 			}
 			switch annot.Value {
 			case "k":
 				// @k(wireKey)
-				if !p.stream.Match(TokenLParen) {
-					return nil, fmt.Errorf("expected ( after @k")
+				if _, err := p.expect(TokenLParen, "( after @k"); err != nil {
+					return nil, err
 				}
-				keyTok := p.stream.Peek()
-				if keyTok.Type == TokenIdent {
-					field.WireKey = keyTok.Value
-					p.stream.Advance()
+				keyTok, err := p.expect(TokenIdent, "wire key in @k(...)")
+				if err != nil {
+					return nil, err
 				}
-				if !p.stream.Match(TokenRParen) {
-					return nil, fmt.Errorf("expected ) after wire key")
+				field.WireKey = keyTok.Value
+				if _, err := p.expect(TokenRParen, ") after wire key"); err != nil {
+					return nil, err
 				}
 			case "fid":
 				// @fid(N)
-				if !p.stream.Match(TokenLParen) {
-					return nil, fmt.Errorf("expected ( after @fid")
+				if _, err := p.expect(TokenLParen, "( after @fid"); err != nil {
+					return nil, err
 				}
-				numTok, err := p.stream.Expect(TokenInt)
+				numTok, err := p.expect(TokenInt, "integer in @fid(...)")
 				if err != nil {
-					return nil, fmt.Errorf("expected integer in @fid(...)")
+					return nil, err
 				}
 				fid, err := strconv.Atoi(numTok.Value)
-				if err != nil {
-					return nil, fmt.Errorf("invalid @fid value %q: %w", numTok.Value, err)
+				if err != nil || fid < 1 {
+					return nil, p.errorf(numTok, "invalid @fid value %s, want a positive integer", numTok.Value)
 				}
 				field.FID = fid
-				if !p.stream.Match(TokenRParen) {
-					return nil, fmt.Errorf("expected ) after @fid value")
+				if _, err := p.expect(TokenRParen, ") after @fid value"); err != nil {
+					return nil, err
 				}
 			case "codec":
 				// @codec(name)
-				if !p.stream.Match(TokenLParen) {
-					return nil, fmt.Errorf("expected ( after @codec")
+				if _, err := p.expect(TokenLParen, "( after @codec"); err != nil {
+					return nil, err
 				}
-				codecTok, err := p.stream.Expect(TokenIdent)
+				codecTok, err := p.expect(TokenIdent, "codec name in @codec(...)")
 				if err != nil {
-					return nil, fmt.Errorf("expected codec name in @codec(...)")
+					return nil, err
 				}
 				field.Codec = codecTok.Value
-				if !p.stream.Match(TokenRParen) {
-					return nil, fmt.Errorf("expected ) after @codec name")
+				if _, err := p.expect(TokenRParen, ") after @codec name"); err != nil {
+					return nil, err
 				}
 			case "keepnull":
 				field.KeepNull = true
+			case "deprecated":
+				// @deprecated or @deprecated(since=v2, use=field)
+				if field.Deprecated, err = p.parseDeprecation(); err != nil {
+					return nil, err
+				}
+			case "acl":
+				// @acl(read=[lead, reviewer], write=lead)
+				if field.ACL, err = p.parseACL(); err != nil {
+					return nil, err
+				}
 			case "default":
 				// @default(value) — scalar values only (see parseSchemaDefault).
-				if !p.stream.Match(TokenLParen) {
-					return nil, fmt.Errorf("expected ( after @default")
+				if _, err := p.expect(TokenLParen, "( after @default"); err != nil {
+					return nil, err
 				}
 				def, err := p.parseSchemaDefault()
 				if err != nil {
 					return nil, err
 				}
 				field.Default = def
-				if !p.stream.Match(TokenRParen) {
-					return nil, fmt.Errorf("expected ) after @default value")
+				if _, err := p.expect(TokenRParen, ") after @default value"); err != nil {
+					return nil, err
 				}
 			default:
-				return nil, fmt.Errorf("unknown field annotation @%s", annot.Value)
+				return nil, p.errorf(annot, "unknown field annotation @%s", annot.Value)
 			}
 			continue
 		}
@@ -867,13 +1253,13 @@ func (p *schemaParser) parseSchemaDefault() (*GValue, error) {
 	case TokenInt:
 		n, err := strconv.ParseInt(tok.Value, 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid @default int %q: %w", tok.Value, err)
+			return nil, p.errorf(tok, "invalid @default int %q: %v", tok.Value, err)
 		}
 		return Int(n), nil
 	case TokenFloat:
 		f, err := strconv.ParseFloat(tok.Value, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid @default float %q: %w", tok.Value, err)
+			return nil, p.errorf(tok, "invalid @default float %q: %v", tok.Value, err)
 		}
 		return Float(f), nil
 	case TokenString, TokenBareStr, TokenIdent:
@@ -901,17 +1287,18 @@ func (p *schemaParser) parseSchemaDefault() (*GValue, error) {
 				return Time(t), nil
 			}
 		}
-		return nil, fmt.Errorf("invalid @default time %q", tok.Value)
+		return nil, p.errorf(tok, "invalid @default time %q", tok.Value)
 	default:
-		return nil, fmt.Errorf("unsupported @default value token %s", tok.Type)
+		return nil, p.errorf(tok, "unsupported @default value %s", describeToken(tok))
 	}
 }
 
 func (p *schemaParser) parseTypeSpec() (TypeSpec, error) {
 	tok := p.stream.Peek()
 
-	if tok.Type != TokenIdent {
-		return TypeSpec{}, fmt.Errorf("expected type name, got %s", tok.Type)
+	// null lexes as a keyword; every other type name is an identifier.
+	if tok.Type != TokenIdent && (tok.Type != TokenNull || tok.Value != "null") {
+		return TypeSpec{}, p.errorf(tok, "expected type, got %s", describeToken(tok))
 	}
 
 	name := tok.Value
@@ -919,36 +1306,36 @@ func (p *schemaParser) parseTypeSpec() (TypeSpec, error) {
 
 	// Check for parameterized types: list<T>, map<K,V>
 	if name == "list" {
-		if _, err := p.stream.Expect(TokenLT); err != nil {
-			return TypeSpec{}, fmt.Errorf("expected < after list")
+		if _, err := p.expect(TokenLT, "< after list"); err != nil {
+			return TypeSpec{}, err
 		}
 		elem, err := p.parseTypeSpec()
 		if err != nil {
 			return TypeSpec{}, err
 		}
-		if _, err := p.stream.Expect(TokenGT); err != nil {
-			return TypeSpec{}, fmt.Errorf("expected > after list type")
+		if _, err := p.expect(TokenGT, "> after list element type"); err != nil {
+			return TypeSpec{}, err
 		}
 		return ListType(elem), nil
 	}
 
 	if name == "map" {
-		if _, err := p.stream.Expect(TokenLT); err != nil {
-			return TypeSpec{}, fmt.Errorf("expected < after map")
+		if _, err := p.expect(TokenLT, "< after map"); err != nil {
+			return TypeSpec{}, err
 		}
 		keyType, err := p.parseTypeSpec()
 		if err != nil {
 			return TypeSpec{}, err
 		}
-		if _, err := p.stream.Expect(TokenComma); err != nil {
-			return TypeSpec{}, fmt.Errorf("expected , in map type")
+		if _, err := p.expect(TokenComma, ", after map key type"); err != nil {
+			return TypeSpec{}, err
 		}
 		valType, err := p.parseTypeSpec()
 		if err != nil {
 			return TypeSpec{}, err
 		}
-		if _, err := p.stream.Expect(TokenGT); err != nil {
-			return TypeSpec{}, fmt.Errorf("expected > after map type")
+		if _, err := p.expect(TokenGT, "> after map value type"); err != nil {
+			return TypeSpec{}, err
 		}
 		return MapType(keyType, valType), nil
 	}
@@ -961,123 +1348,177 @@ func (p *schemaParser) parseTypeSpec() (TypeSpec, error) {
 		return TypeSpec{Kind: TypeSpecInlineStruct, Struct: structDef}, nil
 	}
 
+	if next := p.stream.Peek(); next.Type == TokenLT {
+		return TypeSpec{}, p.errorf(next, "type %s takes no parameters (only list and map do)", name)
+	}
 	return PrimitiveType(name), nil
 }
 
+// parseConstraint parses one [constraint]; the [ is current.
 func (p *schemaParser) parseConstraint() (Constraint, error) {
 	p.stream.Advance() // consume [
 
 	var constraint Constraint
 
-	tok := p.stream.Peek()
+	tok := p.stream.Advance()
 	switch tok.Type {
 	case TokenIdent:
 		switch tok.Value {
 		case "optional":
-			p.stream.Advance()
 			constraint = OptionalConstraint()
 		case "nonempty":
-			p.stream.Advance()
 			constraint = NonEmptyConstraint()
-		case "min":
-			p.stream.Advance()
-			p.stream.Match(TokenEq)
-			numTok := p.stream.Advance()
-			if v, err := strconv.ParseFloat(numTok.Value, 64); err == nil {
-				constraint = MinConstraint(v)
+		case "min", "max":
+			if _, err := p.expect(TokenEq, "= after "+tok.Value); err != nil {
+				return constraint, err
 			}
-		case "max":
-			p.stream.Advance()
-			p.stream.Match(TokenEq)
-			numTok := p.stream.Advance()
-			if v, err := strconv.ParseFloat(numTok.Value, 64); err == nil {
+			v, err := p.number("after " + tok.Value + "=")
+			if err != nil {
+				return constraint, err
+			}
+			if tok.Value == "min" {
+				constraint = MinConstraint(v)
+			} else {
 				constraint = MaxConstraint(v)
 			}
 		case "len":
+			// len=N (exact), len>=N (minlen), len<=N (maxlen); >= and <= lex
+			// as two tokens.
+			op := "="
+			switch p.stream.Peek().Type {
+			case TokenGT:
+				op = ">="
+				p.stream.Advance()
+			case TokenLT:
+				op = "<="
+				p.stream.Advance()
+			}
+			if _, err := p.expect(TokenEq, "=, >= or <= after len"); err != nil {
+				return constraint, err
+			}
+			numTok := p.stream.Peek()
+			n, err := strconv.Atoi(numTok.Value)
+			if numTok.Type != TokenInt || err != nil || n < 0 {
+				return constraint, p.errorf(numTok, "expected length after len%s, got %s", op, describeToken(numTok))
+			}
 			p.stream.Advance()
-			// Distinguish len=N (exact), len>=N (minlen), len<=N (maxlen)
-			if p.stream.Peek().Type == TokenGT {
-				// len>=N  → two-token sequence > =
-				p.stream.Advance() // consume >
-				p.stream.Match(TokenEq)
-				numTok := p.stream.Advance()
-				if v, err := strconv.Atoi(numTok.Value); err == nil {
-					constraint = MinLenConstraint(v)
-				}
-			} else if p.stream.Peek().Type == TokenLT {
-				// len<=N  → two-token sequence < =
-				p.stream.Advance() // consume <
-				p.stream.Match(TokenEq)
-				numTok := p.stream.Advance()
-				if v, err := strconv.Atoi(numTok.Value); err == nil {
-					constraint = MaxLenConstraint(v)
-				}
-			} else {
-				// len=N (exact)
-				p.stream.Match(TokenEq)
-				numTok := p.stream.Advance()
-				if v, err := strconv.Atoi(numTok.Value); err == nil {
-					constraint = LenConstraint(v)
-				}
+			switch op {
+			case ">=":
+				constraint = MinLenConstraint(n)
+			case "<=":
+				constraint = MaxLenConstraint(n)
+			default:
+				constraint = LenConstraint(n)
 			}
 		case "unique":
-			p.stream.Advance()
 			constraint = Constraint{Kind: ConstraintUnique}
+		case "uuid":
+			constraint = UUIDConstraint()
+		case "ulid":
+			constraint = ULIDConstraint()
 		case "regex":
-			p.stream.Advance()
-			p.stream.Match(TokenEq)
-			strTok := p.stream.Advance()
+			if _, err := p.expect(TokenEq, "= after regex"); err != nil {
+				return constraint, err
+			}
 			// TokenString value is already unquoted by the lexer
+			strTok, err := p.expect(TokenString, "quoted pattern after regex=")
+			if err != nil {
+				return constraint, err
+			}
+			if _, err := regexp.Compile(strTok.Value); err != nil {
+				return constraint, p.errorf(strTok, "invalid regex: %v", err)
+			}
 			constraint = RegexConstraint(strTok.Value)
 		case "enum":
-			p.stream.Advance()
-			p.stream.Match(TokenEq)
-			if !p.stream.Match(TokenLBracket) {
-				break
-			}
-			var vals []string
-			for {
-				t := p.stream.Peek()
-				if t.Type == TokenRBracket || t.Type == TokenEOF {
-					break
-				}
-				// Enum values may be TokenIdent, TokenBareStr, or TokenString
-				if t.Type == TokenIdent || t.Type == TokenBareStr || t.Type == TokenString {
-					vals = append(vals, t.Value)
-					p.stream.Advance()
-				} else {
-					// skip unexpected tokens inside enum list
-					p.stream.Advance()
-				}
+			vals, err := p.parseConstraintList("enum")
+			if err != nil {
+				return constraint, err
 			}
-			p.stream.Match(TokenRBracket) // consume ]
 			constraint = EnumConstraint(vals)
+		case "prefix":
+			prefixes, err := p.parseConstraintList("prefix")
+			if err != nil {
+				return constraint, err
+			}
+			constraint = PrefixConstraint(prefixes...)
+		case "requiredIf":
+			r, err := p.parseRequiredIf()
+			if err != nil {
+				return constraint, err
+			}
+			constraint = Constraint{Kind: ConstraintRequiredIf, Value: r}
 		default:
-			p.stream.Advance()
+			return constraint, p.errorf(tok, "unknown constraint %s", tok.Value)
 		}
 
 	case TokenInt, TokenFloat:
 		// Range constraint: [0..10]
-		v1, err := strconv.ParseFloat(tok.Value, 64)
-		p.stream.Advance()
-		if err == nil && p.stream.Match(TokenDotDot) {
-			v2Tok := p.stream.Advance()
-			if v2, err := strconv.ParseFloat(v2Tok.Value, 64); err == nil {
-				constraint = RangeConstraint(v1, v2)
-			}
+		lo, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil || math.IsNaN(lo) {
+			return constraint, p.errorf(tok, "invalid range bound %s", tok.Value)
+		}
+		if _, err := p.expect(TokenDotDot, ".. in range"); err != nil {
+			return constraint, err
+		}
+		hiTok := p.stream.Peek()
+		hi, err := p.number("after ..")
+		if err != nil {
+			return constraint, err
 		}
+		if hi < lo {
+			return constraint, p.errorf(hiTok, "empty range %v..%v", lo, hi)
+		}
+		constraint = RangeConstraint(lo, hi)
+
+	case TokenRBracket:
+		return constraint, p.errorf(tok, "empty constraint []")
+
+	default:
+		return constraint, p.errorf(tok, "expected constraint, got %s", describeToken(tok))
 	}
 
-	if !p.stream.Match(TokenRBracket) {
-		return constraint, fmt.Errorf("expected ] after constraint")
+	if _, err := p.expect(TokenRBracket, "] after constraint"); err != nil {
+		return constraint, err
 	}
 
 	return constraint, nil
 }
 
+// parseConstraintList parses the =[a b ...] of an enum or prefix
+// constraint. Items are names or strings; one-letter items such as t and f
+// lex as keywords and are taken as written. Commas are optional.
+func (p *schemaParser) parseConstraintList(name string) ([]string, error) {
+	if _, err := p.expect(TokenEq, "= after "+name); err != nil {
+		return nil, err
+	}
+	open, err := p.expect(TokenLBracket, "[ after "+name+"=")
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for {
+		tok := p.stream.Advance()
+		switch tok.Type {
+		case TokenRBracket:
+			if len(items) == 0 {
+				return nil, p.errorf(open, "empty %s list", name)
+			}
+			return items, nil
+		case TokenIdent, TokenBareStr, TokenString, TokenTrue, TokenFalse, TokenNull:
+			items = append(items, tok.Value)
+		case TokenComma:
+		case TokenEOF:
+			return nil, p.errorf(tok, "unterminated %s list opened at %s", name, open.Pos)
+		default:
+			return nil, p.errorf(tok, "invalid %s value %s", name, describeToken(tok))
+		}
+	}
+}
+
 func (p *schemaParser) parseSumDef() (*SumDef, error) {
-	if !p.stream.Match(TokenLBrace) {
-		return nil, fmt.Errorf("expected { after sum")
+	open, err := p.expect(TokenLBrace, "{ after sum")
+	if err != nil {
+		return nil, err
 	}
 
 	var variants []*VariantDef
@@ -1088,7 +1529,7 @@ func (p *schemaParser) parseSumDef() (*SumDef, error) {
 			break
 		}
 		if tok.Type == TokenEOF {
-			return nil, fmt.Errorf("unterminated sum definition")
+			return nil, p.errorf(tok, "unterminated sum opened at %s", open.Pos)
 		}
 		if tok.Type == TokenPipe {
 			p.stream.Advance()
@@ -1096,13 +1537,13 @@ func (p *schemaParser) parseSumDef() (*SumDef, error) {
 		}
 
 		// Tag: Type
-		tagTok, err := p.stream.Expect(TokenIdent)
+		tagTok, err := p.expect(TokenIdent, "variant tag")
 		if err != nil {
 			return nil, err
 		}
 
-		if !p.stream.Match(TokenEq) { // :
-			return nil, fmt.Errorf("expected : after variant tag")
+		if _, err := p.expect(TokenColon, ": after variant "+tagTok.Value); err != nil {
+			return nil, err
 		}
 
 		typeSpec, err := p.parseTypeSpec()
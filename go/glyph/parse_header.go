@@ -26,6 +26,7 @@ type Header struct {
 	Mode            Mode    // Encoding mode
 	KeyMode         KeyMode // Key format
 	Target          RefID   // For patch mode: target document
+	TargetType      string  // For patch mode: type the paths start from (@type=)
 	BaseFingerprint string  // For patch mode: base state fingerprint (v2.4.0)
 	Raw             string  // Original header text
 }
@@ -208,12 +209,21 @@ func EmitHeader(h *Header) string {
 // Document Structure
 // ============================================================
 
-// Document represents a complete GLYPH v2 document.
+// Document represents a complete GLYPH v2 document. Changes made to Body
+// through its methods are reported to watchers (see document_track.go).
 type Document struct {
 	Header *Header // Parsed header
 	Body   *GValue // Main content
 	Patch  *Patch  // For patch mode
 	Errors []error // Parse errors (tolerant mode)
+	Schema *Schema // If set, ApplyPatch resolves wire keys and FIDs (see ApplyPatchWithSchema)
+
+	// Check, if set, is called with the Body a change would produce, before
+	// it is kept. An error rejects the change: the Body is left as it was,
+	// watchers are not told, and the error is returned.
+	Check func(body *GValue) error
+
+	watchers []*DocumentWatcher
 }
 
 // DetectMode examines input to determine the document mode.
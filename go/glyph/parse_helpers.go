@@ -2,7 +2,9 @@ package glyph
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 	"unicode/utf8"
 )
@@ -123,17 +125,62 @@ func parseQuotedStringShared(input string, pos int) (string, int, error) {
 	return "", pos, fmt.Errorf("unterminated string")
 }
 
-// decodeBytesLiteral decodes a b64"<base64>" literal where the opening 'b', '6', '4'
-// have already been consumed and s is the quoted body (the string returned by parseQuotedString).
-// Used by both parse_packed.go and parse_tabular.go to avoid duplication.
-func decodeBytesLiteral(quotedBody string) (*GValue, error) {
-	decoded, err := base64.StdEncoding.DecodeString(quotedBody)
+// bytesLiteralPrefixes lists the bytes literal prefixes, longest first so
+// that b64u"..." is not mistaken for b64 followed by a bare u.
+var bytesLiteralPrefixes = []string{"b64u", "b64", "b85", "hex"}
+
+// bytesLiteralPrefix returns the bytes literal prefix (b64, b64u, b85, hex)
+// that s starts with, immediately followed by '"', or "" if there is none.
+func bytesLiteralPrefix(s string) string {
+	for _, prefix := range bytesLiteralPrefixes {
+		if len(s) > len(prefix) && s[len(prefix)] == '"' && strings.HasPrefix(s, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// decodeBytesLiteral decodes the unquoted body of a bytes literal with the
+// given prefix. Used by the GLYPH-T, packed, tabular, and loose parsers so all
+// accept the same encodings:
+//
+//	b64"..."   standard base64, padded
+//	b64u"..."  URL-safe base64, padding optional
+//	b85"..."   base85, RFC 1924 alphabet
+//	hex"..."   hexadecimal, either case
+func decodeBytesLiteral(prefix, body string) (*GValue, error) {
+	var decoded []byte
+	var err error
+	switch prefix {
+	case "b64":
+		decoded, err = base64.StdEncoding.DecodeString(body)
+	case "b64u":
+		decoded, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(body, "="))
+	case "b85":
+		decoded, err = decodeBase85(body)
+	case "hex":
+		decoded, err = hex.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("unknown bytes literal prefix %q", prefix)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid base64 in bytes literal: %v", err)
+		return nil, fmt.Errorf("invalid %s in bytes literal: %v", bytesEncodingName(prefix), err)
 	}
 	return Bytes(decoded), nil
 }
 
+func bytesEncodingName(prefix string) string {
+	switch prefix {
+	case "b64u":
+		return "url-safe base64"
+	case "b85":
+		return "base85"
+	case "hex":
+		return "hex"
+	}
+	return "base64"
+}
+
 // replaceInvalidUTF8 replaces invalid UTF-8 sequences with U+FFFD, mirroring
 // the typed lexer's behaviour.
 func replaceInvalidUTF8(s string) string {
@@ -170,7 +170,7 @@ func (p *packedParser) parseDenseValues(td *TypeDef) (*GValue, error) {
 	for i, fd := range fields {
 		p.skipWhitespace()
 
-		if p.peek() == ')' {
+		if p.peek() == ')' || p.atUnknownBag() {
 			// Remaining fields are null/optional
 			for j := i; j < len(fields); j++ {
 				if !fields[j].Optional {
@@ -189,6 +189,11 @@ func (p *packedParser) parseDenseValues(td *TypeDef) (*GValue, error) {
 		entries = append(entries, MapEntry{Key: fd.Name, Value: val})
 	}
 
+	entries, err := p.parseUnknownBag(td, entries)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GValue{
 		typ: TypeStruct,
 		structVal: &StructValue{
@@ -231,6 +236,11 @@ func (p *packedParser) parseBitmapValues(td *TypeDef, mask []bool) (*GValue, err
 		}
 	}
 
+	entries, err := p.parseUnknownBag(td, entries)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GValue{
 		typ: TypeStruct,
 		structVal: &StructValue{
@@ -240,6 +250,34 @@ func (p *packedParser) parseBitmapValues(td *TypeDef, mask []bool) (*GValue, err
 	}, nil
 }
 
+// atUnknownBag reports whether the input continues with an @unknown{...} bag.
+func (p *packedParser) atUnknownBag() bool {
+	return strings.HasPrefix(p.input[p.pos:], "@unknown{")
+}
+
+// parseUnknownBag appends the entries of a trailing @unknown{...} bag to the
+// struct fields. Only @open types may carry one.
+func (p *packedParser) parseUnknownBag(td *TypeDef, entries []MapEntry) ([]MapEntry, error) {
+	p.skipWhitespace()
+	if !p.atUnknownBag() {
+		return entries, nil
+	}
+	if !td.Open {
+		return nil, fmt.Errorf("@unknown fields in closed type %s at pos %d", td.Name, p.pos)
+	}
+	p.pos += len("@unknown")
+	bag, err := p.parseMap()
+	if err != nil {
+		return nil, fmt.Errorf("@unknown: %w", err)
+	}
+	for _, e := range bag.mapVal {
+		if td.FieldByKey(e.Key) != nil {
+			return nil, fmt.Errorf("@unknown: %s is a declared field of %s", e.Key, td.Name)
+		}
+	}
+	return append(entries, bag.mapVal...), nil
+}
+
 func (p *packedParser) parseValue(fd *FieldDef) (*GValue, error) {
 	p.skipWhitespace()
 
@@ -251,6 +289,28 @@ func (p *packedParser) parseValue(fd *FieldDef) (*GValue, error) {
 		return Null(), nil
 	}
 
+	// Vector literal: vec16"..." (see vec.go).
+	if prefix := vecLiteralPrefix(p.input[p.pos:]); prefix != "" {
+		p.pos += len(prefix)
+		s, err := p.parseQuotedString()
+		if err != nil {
+			return nil, err
+		}
+		body, _ := s.AsStr()
+		return decodeVecLiteral(prefix, body)
+	}
+
+	// Custom scalar literal: money"12.34 USD" (see scalar_ext.go).
+	if tag := scalarLiteralPrefix(p.input[p.pos:]); tag != "" {
+		p.pos += len(tag)
+		s, err := p.parseQuotedString()
+		if err != nil {
+			return nil, err
+		}
+		text, _ := s.AsStr()
+		return decodeScalarLiteral(tag, text)
+	}
+
 	switch c {
 	case 't':
 		// true or bare string starting with t
@@ -289,16 +349,35 @@ func (p *packedParser) parseValue(fd *FieldDef) (*GValue, error) {
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		return p.parseNumberOrTime()
 
-	case 'b':
-		// Bytes literal: b64"<base64>" — intercept before the generic type-name path.
-		if strings.HasPrefix(p.input[p.pos:], `b64"`) {
-			p.pos += 3 // consume 'b', '6', '4'
+	case 'g':
+		// Geo literal: geo5"..." (see geo.go).
+		if prefix := geoLiteralPrefix(p.input[p.pos:]); prefix != "" {
+			p.pos += len(prefix)
 			s, err := p.parseQuotedString()
 			if err != nil {
 				return nil, err
 			}
 			body, _ := s.AsStr()
-			return decodeBytesLiteral(body)
+			return decodeGeoLiteral(prefix, body)
+		}
+		saved := p.pos
+		typeName, err := p.parseTypeName()
+		if err == nil && p.peek() == '@' {
+			p.pos = saved
+			return p.parseNestedPacked()
+		}
+		return Str(typeName), nil
+
+	case 'b', 'h':
+		// Bytes literal: b64"..." etc. — intercept before the generic type-name path.
+		if prefix := bytesLiteralPrefix(p.input[p.pos:]); prefix != "" {
+			p.pos += len(prefix)
+			s, err := p.parseQuotedString()
+			if err != nil {
+				return nil, err
+			}
+			body, _ := s.AsStr()
+			return decodeBytesLiteral(prefix, body)
 		}
 		// Not a bytes literal — fall through to type-name / bare-string handling.
 		saved := p.pos
@@ -479,6 +558,28 @@ func (p *packedParser) parseBareOrQuotedString() (*GValue, error) {
 	return Str(p.input[start:p.pos]), nil
 }
 
+// parseMapKey parses a bare or quoted map key. Bare keys end at the ':' or '='
+// separator, which canonical bare strings never contain.
+func (p *packedParser) parseMapKey() (*GValue, error) {
+	if p.peek() == '"' {
+		return p.parseQuotedString()
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == ':' || c == '=' || c == ' ' || c == '}' || c == '\n' {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, fmt.Errorf("expected map key at pos %d", p.pos)
+	}
+
+	return Str(p.input[start:p.pos]), nil
+}
+
 func (p *packedParser) parseRef() (*GValue, error) {
 	if !p.expect('^') {
 		return nil, fmt.Errorf("expected '^'")
@@ -547,7 +648,7 @@ func (p *packedParser) parseMap() (*GValue, error) {
 		}
 
 		// Parse key
-		key, err := p.parseBareOrQuotedString()
+		key, err := p.parseMapKey()
 		if err != nil {
 			return nil, err
 		}
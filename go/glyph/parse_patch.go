@@ -18,6 +18,15 @@ import (
 //   + events "Goal!"
 //   - odds
 //   ~ home.rating +0.15
+//   > lineup[3] lineup[0]
+//   * home.kit away.kit
+//   ? status "live"
+//   ~str body 120:134="revised clause"
+//   +tab results Result [id score]
+//   1 0.93
+//   2 0.88
+//   @end
+//   !sum total
 //   @end
 //
 // This complements emit_patch.go which handles encoding.
@@ -52,6 +61,7 @@ func ParsePatchWithOptions(input string, schema *Schema, defaultKeyMode KeyMode)
 		Target:          header.Target,
 		SchemaID:        header.SchemaID,
 		BaseFingerprint: header.BaseFingerprint,
+		TargetType:      header.TargetType,
 		Ops:             make([]*PatchOp, 0),
 	}
 
@@ -69,6 +79,24 @@ func ParsePatchWithOptions(input string, schema *Schema, defaultKeyMode KeyMode)
 			break
 		}
 
+		// Append-rows block: consumes its rows and its own @end.
+		if strings.HasPrefix(line, "+tab ") {
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "@end" {
+				end++
+			}
+			if end == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated +tab block (missing @end)", i+1)
+			}
+			op, err := parseRowsOp(line, lines[i+1:end], schema)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			patch.Ops = append(patch.Ops, op)
+			i = end
+			continue
+		}
+
 		// Parse operation
 		op, err := parsePatchOp(line, keyMode, schema)
 		if err != nil {
@@ -78,6 +106,10 @@ func ParsePatchWithOptions(input string, schema *Schema, defaultKeyMode KeyMode)
 		patch.Ops = append(patch.Ops, op)
 	}
 
+	// Wire keys and FIDs name fields only relative to the root type; resolve
+	// them now so ApplyPatch navigates by field name.
+	patch.resolveKnownPaths(patch.TargetType, schema)
+
 	return patch, nil
 }
 
@@ -125,6 +157,9 @@ func parsePatchHeader(line string) (*Header, error) {
 
 		case strings.HasPrefix(tok, "@base="):
 			h.BaseFingerprint = tok[6:]
+
+		case strings.HasPrefix(tok, "@type="):
+			h.TargetType = tok[6:]
 		}
 	}
 
@@ -139,14 +174,25 @@ func parsePatchHeader(line string) (*Header, error) {
 //	+ events "Goal!"
 //	- odds
 //	~ rating +0.15
+//	> items[2] items[0]
+//	? status "live"
+//	? home @hash=3f2a9c01d4e5b6a7
+//	~str body 0:5="Hello" 40:40=" (new)"
 func parsePatchOp(line string, keyMode KeyMode, schema *Schema) (*PatchOp, error) {
 	if len(line) == 0 {
 		return nil, &ParseError{Message: "empty operation line"}
 	}
 
-	// First character is the operation
+	// Custom ops start with a character no built-in op uses.
+	if strings.ContainsRune(patchOpSymbolStart, rune(line[0])) {
+		return parseCustomOp(line, schema)
+	}
+
+	// First character is the operation; "~str " (no space after ~) selects
+	// the text edit op rather than a numeric delta.
 	opChar := rune(line[0])
 	var opKind PatchOpKind
+	opLen := 1
 
 	switch opChar {
 	case '=':
@@ -157,12 +203,22 @@ func parsePatchOp(line string, keyMode KeyMode, schema *Schema) (*PatchOp, error
 		opKind = OpDelete
 	case '~':
 		opKind = OpDelta
+		if strings.HasPrefix(line, "~str ") {
+			opKind = OpText
+			opLen = 4
+		}
+	case '>':
+		opKind = OpMove
+	case '*':
+		opKind = OpCopy
+	case '?':
+		opKind = OpTest
 	default:
 		return nil, &ParseError{Message: fmt.Sprintf("unknown operation: %c", opChar)}
 	}
 
 	// Rest of line after op character
-	rest := strings.TrimSpace(line[1:])
+	rest := strings.TrimSpace(line[opLen:])
 	if rest == "" {
 		return nil, &ParseError{Message: "missing path in operation"}
 	}
@@ -220,6 +276,43 @@ func parsePatchOp(line string, keyMode KeyMode, schema *Schema) (*PatchOp, error
 		}
 		op.Value = delta
 
+	case OpText:
+		edits, err := parseTextEdits(valueStr)
+		if err != nil {
+			return nil, err
+		}
+		op.Edits = edits
+
+	case OpTest:
+		if valueStr == "" {
+			return nil, &ParseError{Message: "test operation requires a value or @hash="}
+		}
+		if strings.HasPrefix(valueStr, "@hash=") {
+			hash := valueStr[6:]
+			if len(hash) < minTestHashLen || len(hash) > maxTestHashLen || !isHexString(hash) {
+				return nil, &ParseError{Message: fmt.Sprintf("invalid @hash value: %s", hash)}
+			}
+			op.Hash = hash
+			break
+		}
+		val, err := parseInlineValue(valueStr, schema)
+		if err != nil {
+			return nil, err
+		}
+		op.Value = val
+
+	case OpMove, OpCopy:
+		// The first path is the source; the second is the destination.
+		if valueStr == "" {
+			return nil, &ParseError{Message: fmt.Sprintf("%s operation requires a destination path", opKind)}
+		}
+		destEnd := findPathEnd(valueStr)
+		if destEnd < len(valueStr) {
+			return nil, &ParseError{Message: fmt.Sprintf("unexpected trailing input after destination path: %s", strings.TrimSpace(valueStr[destEnd:]))}
+		}
+		op.From = path
+		op.Path = parsePathToSegs(valueStr)
+
 	case OpDelete:
 		// No value needed
 	}
@@ -333,6 +426,105 @@ func parseDeltaValue(s string) (*GValue, error) {
 	return Float(f), nil
 }
 
+// parseRowsOp parses a +tab op from its header line ("+tab path Type [cols]")
+// and its row lines. Typed rows are decoded with TabularReader, so a schema is
+// required and columns may be names, wire keys or #FIDs. An untyped header
+// ("_ rows=N cols=M [cols]") holds map rows in the loose tabular form and
+// needs no schema.
+func parseRowsOp(header string, rows []string, schema *Schema) (*PatchOp, error) {
+	rest := strings.TrimSpace(header[len("+tab"):])
+	pathEnd := findPathEnd(rest)
+	pathStr := rest[:pathEnd]
+	tabHeader := strings.TrimSpace(rest[pathEnd:])
+	if pathStr == "" || tabHeader == "" {
+		return nil, &ParseError{Message: "+tab operation requires a path and a table header"}
+	}
+
+	table := "@tab " + tabHeader + "\n" + strings.Join(rows, "\n") + "\n@end"
+	var values []*GValue
+	if strings.HasPrefix(tabHeader, "_ ") {
+		v, err := ParseTabularLoose(table)
+		if err != nil {
+			return nil, err
+		}
+		values = v.listVal
+	} else {
+		if schema == nil {
+			return nil, &ParseError{Message: "+tab operation requires a schema"}
+		}
+		var err error
+		values, err = NewTabularReaderFromString(table, schema).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 0 {
+		return nil, &ParseError{Message: "+tab operation has no rows"}
+	}
+
+	return &PatchOp{
+		Op:    OpRows,
+		Path:  parsePathToSegs(pathStr),
+		Value: List(values...),
+		Index: -1,
+	}, nil
+}
+
+// parseTextEdits parses the edit list of a ~str op: one or more
+// START:END="text" items separated by spaces.
+func parseTextEdits(s string) ([]TextEdit, error) {
+	if s == "" {
+		return nil, &ParseError{Message: "text edit operation requires at least one edit"}
+	}
+
+	var edits []TextEdit
+	i := 0
+	for i < len(s) {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, &ParseError{Message: fmt.Sprintf("invalid text edit: %s", s[i:])}
+		}
+		rng := s[i : i+eq]
+		colon := strings.IndexByte(rng, ':')
+		if colon < 0 {
+			return nil, &ParseError{Message: fmt.Sprintf("invalid text edit range: %s", rng)}
+		}
+		start, err1 := strconv.Atoi(rng[:colon])
+		end, err2 := strconv.Atoi(rng[colon+1:])
+		if err1 != nil || err2 != nil || start < 0 || end < start {
+			return nil, &ParseError{Message: fmt.Sprintf("invalid text edit range: %s", rng)}
+		}
+
+		text, next, err := parseQuotedStringShared(s, i+eq+1)
+		if err != nil {
+			return nil, &ParseError{Message: fmt.Sprintf("invalid text edit %s: %v", rng, err)}
+		}
+		edits = append(edits, TextEdit{Start: start, End: end, Text: text})
+		i = next
+	}
+
+	return edits, nil
+}
+
+// isHexString reports whether s is non-empty and all hex digits.
+func isHexString(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
 // ============================================================
 // Patch Round-Trip Helpers
 // ============================================================
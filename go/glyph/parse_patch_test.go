@@ -206,6 +206,13 @@ func TestParsePatchApplyRoundTrip(t *testing.T) {
 	if ftAVal == nil || ftAVal.intVal != 1 {
 		t.Errorf("ft_a = %v, want 1", ftAVal)
 	}
+
+	// Wire keys map back to their fields rather than adding new ones.
+	for _, wire := range []string{"fh", "fa"} {
+		if result.Get(wire) != nil {
+			t.Errorf("patch added field %s: %s", wire, Emit(result))
+		}
+	}
 }
 
 func TestParsePatchListIndex(t *testing.T) {
@@ -312,7 +319,7 @@ func TestParsePatchErrors(t *testing.T) {
 		input string
 	}{
 		{"missing header", "= foo 1\n@end"},
-		{"unknown op", "@patch @target=m:1\n? foo 1\n@end"},
+		{"unknown op", "@patch @target=m:1\n! foo 1\n@end"},
 		{"missing path", "@patch @target=m:1\n= \n@end"},
 		{"invalid delta", "@patch @target=m:1\n~ foo abc\n@end"},
 	}
@@ -371,6 +378,13 @@ func TestParsePatchEscapedMapKey(t *testing.T) {
 		{"double-quote", `k"ey`},
 		{"newline", "key\nline"},
 		{"tab", "key\ttab"},
+		{"close-bracket", "a]b"},
+		{"bracketed-quote", `x"]y`},
+		{"url", "https://example.com/a?b=1&c=[2]"},
+		{"spaces", "key with spaces"},
+		{"dots", "a.b.c"},
+		{"empty", ""},
+		{"unicode", "ключ ✓"},
 	}
 
 	for _, tc := range cases {
@@ -422,3 +436,40 @@ func TestParsePatchBadListIndex(t *testing.T) {
 		t.Errorf("expected FieldSeg(abc), got kind=%v field=%q", segs[1].Kind, segs[1].Field)
 	}
 }
+
+// TestParsePatchQuotedFieldName checks that field names needing quotes (dots,
+// spaces, brackets) survive EmitPatch -> ParsePatch as a single field segment.
+func TestParsePatchQuotedFieldName(t *testing.T) {
+	names := []string{"a.b", "has space", "x[0]", `q"uote`}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			patch := NewPatch(RefID{Prefix: "x", Value: "1"}, "")
+			patch.Ops = append(patch.Ops, &PatchOp{
+				Op:    OpSet,
+				Path:  []PathSeg{FieldSeg(name, 0), MapKeySeg("k]")},
+				Value: Int(1),
+			})
+
+			emitted, err := EmitPatch(patch, nil)
+			if err != nil {
+				t.Fatalf("EmitPatch error: %v", err)
+			}
+			parsed, err := ParsePatch(emitted, nil)
+			if err != nil {
+				t.Fatalf("ParsePatch error: %v (emitted: %q)", err, emitted)
+			}
+
+			path := parsed.Ops[0].Path
+			if len(path) != 2 {
+				t.Fatalf("expected 2 segs, got %d: %v (emitted: %q)", len(path), path, emitted)
+			}
+			if path[0].Kind != PathSegField || path[0].Field != name {
+				t.Errorf("field seg: got kind=%v field=%q, want %q", path[0].Kind, path[0].Field, name)
+			}
+			if path[1].Kind != PathSegMapKey || path[1].MapKey != "k]" {
+				t.Errorf("map key seg: got kind=%v key=%q, want %q", path[1].Kind, path[1].MapKey, "k]")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package glyph
+
+import "fmt"
+
+// ============================================================
+// Parse Sessions
+// ============================================================
+//
+// A stream of GLYPH-Loose payloads may define a key dictionary once
+// (@schema#id @keys=[...]) and then send bodies that use it without naming
+// it again. ParseLoose remembers that dictionary as the registry's active
+// schema, so the result of one call depends on whatever any other caller
+// last did with the same registry. A ParseSession keeps the active schema
+// itself instead:
+//
+//	registry := glyph.NewSchemaRegistry() // may be shared by many sessions
+//	s := glyph.NewParseSession(registry)
+//	v, err := s.Parse("@schema#u1 @keys=[id name]\n{#0=1 #1=Ada}")
+//	v, err = s.Parse("{#0=2 #1=Bob}") // read with u1, whatever else uses registry
+//
+// Definitions go into the registry, so that other sessions can refer to
+// them by id, but activating one in a session does not affect the others.
+
+// ParseSession reads one stream of GLYPH-Loose payloads. It holds the
+// stream's active schema; the key dictionaries themselves live in a
+// SchemaRegistry that sessions may share. Not safe for concurrent use; use
+// a session per stream.
+type ParseSession struct {
+	registry *SchemaRegistry
+	active   *SchemaContext
+	lazy     int
+	compat   CompatLevel
+
+	// shared makes the session use the registry's active schema, for
+	// ParseLoose and the other registry-only functions.
+	shared bool
+}
+
+// NewParseSession creates a session that defines and looks up key
+// dictionaries in registry. A nil registry gives the session one of its own.
+func NewParseSession(registry *SchemaRegistry) *ParseSession {
+	if registry == nil {
+		registry = NewSchemaRegistry()
+	}
+	return &ParseSession{registry: registry}
+}
+
+// registrySession is the session behind ParseLoose and ParseLoosePayload:
+// it keeps the active schema in registry, which may be nil.
+func registrySession(registry *SchemaRegistry, lazy int) *ParseSession {
+	return &ParseSession{registry: registry, lazy: lazy, shared: true}
+}
+
+// Registry returns the registry the session defines schemas in.
+func (s *ParseSession) Registry() *SchemaRegistry {
+	return s.registry
+}
+
+// SetLazy makes the session defer containers whose source is at least
+// threshold bytes long, as ParseLooseLazy does. threshold <= 0 parses
+// eagerly, which is the default.
+func (s *ParseSession) SetLazy(threshold int) {
+	s.lazy = threshold
+}
+
+// Active returns the session's active schema, or nil if none.
+func (s *ParseSession) Active() *SchemaContext {
+	if s.shared {
+		if s.registry == nil {
+			return nil
+		}
+		return s.registry.Active()
+	}
+	return s.active
+}
+
+// SetActive makes the registered schema id the session's active schema, as
+// an @schema#id directive does.
+func (s *ParseSession) SetActive(id string) error {
+	_, err := s.use(id)
+	return err
+}
+
+// ClearActive clears the session's active schema, as @schema.clear does.
+func (s *ParseSession) ClearActive() {
+	if s.shared {
+		if s.registry != nil {
+			s.registry.ClearActive()
+		}
+		return
+	}
+	s.active = nil
+}
+
+// Parse parses a payload as ParseLoose does, keeping the active schema in
+// the session.
+func (s *ParseSession) Parse(input string) (*GValue, error) {
+	return s.parseDocument(input)
+}
+
+// ParsePayload parses a payload as ParseLoosePayload does, keeping the
+// active schema in the session. It returns the schema the payload was read
+// with, if any.
+func (s *ParseSession) ParsePayload(input string) (*GValue, *SchemaContext, error) {
+	input, err := applyCompat(input, s.compat)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.parsePayload(input)
+}
+
+// define registers ctx and makes it active.
+func (s *ParseSession) define(ctx *SchemaContext) {
+	switch {
+	case s.shared:
+		if s.registry != nil {
+			s.registry.Define(ctx)
+		}
+	default:
+		s.registry.Add(ctx)
+		s.active = ctx
+	}
+}
+
+// use makes the registered schema id active and returns it. Without a
+// registry, the shared session cannot resolve id and returns nil.
+func (s *ParseSession) use(id string) (*SchemaContext, error) {
+	if s.shared {
+		if s.registry == nil {
+			return nil, nil
+		}
+		return s.registry.Use(id)
+	}
+	ctx := s.registry.Get(id)
+	if ctx == nil {
+		return nil, fmt.Errorf("schema not found: %s", id)
+	}
+	s.active = ctx
+	return ctx, nil
+}
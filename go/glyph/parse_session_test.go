@@ -0,0 +1,80 @@
+package glyph
+
+import "testing"
+
+func TestParseSession_OwnActiveSchema(t *testing.T) {
+	reg := NewSchemaRegistry()
+	a := NewParseSession(reg)
+	b := NewParseSession(reg)
+
+	if _, err := a.Parse("@schema#A @keys=[id name]\n{#0=1 #1=Ada}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Parse("@schema#B @keys=[sku qty]\n{#0=X1 #1=2}"); err != nil {
+		t.Fatal(err)
+	}
+	if reg.Active() != nil {
+		t.Errorf("sessions changed the registry's active schema to %s", reg.Active().ID)
+	}
+
+	v, err := a.Parse("{#0=2 #1=Bob}")
+	if err != nil || v.Get("name") == nil {
+		t.Fatalf("session A read %s: %v", Emit(v), err)
+	}
+	v, err = b.Parse("{#0=Y2 #1=5}")
+	if err != nil || v.Get("qty") == nil {
+		t.Fatalf("session B read %s: %v", Emit(v), err)
+	}
+
+	// A schema defined by one session can be named by another.
+	v, ctx, err := b.ParsePayload("@schema#A\n{#0=3 #1=Cy}")
+	if err != nil || ctx.ID != "A" || v.Get("name") == nil {
+		t.Fatalf("reference to A: %v %v", ctx, err)
+	}
+	if b.Active().ID != "A" || a.Active().ID != "A" {
+		t.Errorf("active = %s, %s", b.Active().ID, a.Active().ID)
+	}
+
+	if _, err := a.Parse("@schema.clear\n{x=1}"); err != nil {
+		t.Fatal(err)
+	}
+	if a.Active() != nil || b.Active() == nil {
+		t.Error("@schema.clear reached the other session")
+	}
+	if _, err := a.Parse("@schema#missing\n{#0=1}"); err == nil {
+		t.Error("expected error for unknown schema")
+	}
+	if err := a.SetActive("B"); err != nil || a.Active().ID != "B" {
+		t.Errorf("SetActive: %v", err)
+	}
+}
+
+func TestParseSession_RegistryFlowUnchanged(t *testing.T) {
+	reg := NewSchemaRegistry()
+	if _, err := ParseLoose("@schema#A @keys=[id name]\n{#0=1 #1=Ada}", reg); err != nil {
+		t.Fatal(err)
+	}
+	if reg.Active() == nil || reg.Active().ID != "A" {
+		t.Fatalf("ParseLoose did not activate A")
+	}
+	v, err := ParseLoose("{#0=2 #1=Bob}", reg)
+	if err != nil || v.Get("name") == nil {
+		t.Fatalf("ParseLoose read %s: %v", Emit(v), err)
+	}
+
+	reg.Add(NewSchemaContextWithID("B", []string{"x"}))
+	if reg.Active().ID != "A" || reg.Get("B") == nil {
+		t.Error("Add changed the active schema or did not register")
+	}
+
+	s := NewParseSession(nil)
+	if s.Registry() == nil {
+		t.Fatal("NewParseSession(nil) has no registry")
+	}
+	if _, err := s.Parse("@schema#C @keys=[k]\n{#0=1}"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Parse("{#0=2}"); err != nil || v.Get("k") == nil {
+		t.Errorf("own registry: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package glyph
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -17,7 +19,11 @@ import (
 //   val2a val2b val2c
 //   @end
 //
-// TabularReader provides streaming access to tabular data.
+// TabularReader provides streaming access to tabular data. It also reads
+// the loose form written by auto-tabular: a header may carry rows=/cols=
+// and use _ for an untyped table, and a row starting with '|' is a
+// |cell|cell| row. The grammar is detected per row. A =N line repeats row
+// N, so the reader keeps the text of every row it has read.
 
 // TabularReader reads tabular data row by row.
 type TabularReader struct {
@@ -30,8 +36,25 @@ type TabularReader struct {
 	started  bool
 	finished bool
 	rowNum   int
+
+	columnMajor bool     // @tabc block
+	transposed  []string // Its row lines, read in full on the first Next
+	seen        []string // Text of the rows read so far, for =N references
+
+	check     RowCheck
+	validator *Validator
+	warnings  []*RowError
+
+	maxRows  int   // 0: no limit
+	maxBytes int64 // 0: no limit
+	nbytes   int64 // Input read so far
+	err      error // Sticky limit error
 }
 
+// ErrReadLimit is wrapped by the error a TabularReader returns once its
+// input goes past the limits set with SetLimits.
+var ErrReadLimit = errors.New("glyph: read limit exceeded")
+
 const (
 	tabularScannerBufSize = 64 * 1024
 	tabularScannerMaxSize = 4 * 1024 * 1024
@@ -59,13 +82,13 @@ func (tr *TabularReader) ReadHeader() (typeName string, columns []string, err er
 	if tr.started {
 		return tr.typeName, tr.columns, nil
 	}
-	if tr.schema == nil {
-		return "", nil, fmt.Errorf("schema is required for tabular parsing")
-	}
 
 	// Read lines until we find @tab
-	for tr.scanner.Scan() {
-		line := strings.TrimSpace(tr.scanner.Text())
+	for {
+		line, err := tr.scanLine()
+		if err != nil {
+			return "", nil, err
+		}
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -73,7 +96,7 @@ func (tr *TabularReader) ReadHeader() (typeName string, columns []string, err er
 		}
 
 		// Parse @tab header
-		if strings.HasPrefix(line, "@tab ") {
+		if isTabHeader(line) {
 			if err := tr.parseHeader(line); err != nil {
 				return "", nil, err
 			}
@@ -83,45 +106,38 @@ func (tr *TabularReader) ReadHeader() (typeName string, columns []string, err er
 
 		return "", nil, fmt.Errorf("expected @tab header, got: %s", line)
 	}
-
-	if err := tr.scanner.Err(); err != nil {
-		return "", nil, err
-	}
-
-	return "", nil, io.EOF
 }
 
 // parseHeader parses: @tab Type [col1 col2 col3]
+// Also accepts the loose forms @tab Type rows=N cols=M [...] and @tab _ [...];
+// an untyped table needs no schema and reads rows as maps.
 func (tr *TabularReader) parseHeader(line string) error {
-	// Remove @tab prefix
-	rest := strings.TrimPrefix(line, "@tab ")
-	rest = strings.TrimSpace(rest)
-
-	// Find type name (everything before '[')
-	bracketIdx := strings.Index(rest, "[")
-	if bracketIdx == -1 {
+	if !strings.Contains(line, "[") {
 		return fmt.Errorf("missing column list in @tab header")
 	}
+	if !strings.HasSuffix(line, "]") {
+		return fmt.Errorf("invalid column list format")
+	}
+	meta, err := parseTabularLooseHeaderWithMeta(line)
+	if err != nil {
+		return err
+	}
+	tr.typeName = meta.Type
+	tr.columns = meta.Keys
+	tr.columnMajor = meta.ColumnMajor
+	if tr.typeName == "" {
+		return nil
+	}
 
-	tr.typeName = strings.TrimSpace(rest[:bracketIdx])
+	if tr.schema == nil {
+		return fmt.Errorf("schema is required for tabular parsing")
+	}
 
 	// Get type definition
 	tr.td = tr.schema.GetType(tr.typeName)
 	if tr.td == nil {
 		return fmt.Errorf("unknown type: %s", tr.typeName)
 	}
-
-	// Parse column list: [col1 col2 col3]
-	colPart := rest[bracketIdx:]
-	if !strings.HasPrefix(colPart, "[") || !strings.HasSuffix(colPart, "]") {
-		return fmt.Errorf("invalid column list format")
-	}
-
-	colPart = strings.TrimPrefix(colPart, "[")
-	colPart = strings.TrimSuffix(colPart, "]")
-	colPart = strings.TrimSpace(colPart)
-
-	tr.columns = strings.Fields(colPart)
 	if len(tr.columns) == 0 {
 		return fmt.Errorf("empty column list")
 	}
@@ -172,7 +188,8 @@ func (tr *TabularReader) findField(col string) *FieldDef {
 	return nil
 }
 
-// TypeName returns the struct type name from the header.
+// TypeName returns the struct type name from the header, or "" for an
+// untyped @tab _ table.
 func (tr *TabularReader) TypeName() string {
 	return tr.typeName
 }
@@ -185,6 +202,9 @@ func (tr *TabularReader) Columns() []string {
 // Next reads and parses the next data row.
 // Returns io.EOF when @end is reached.
 func (tr *TabularReader) Next() (*GValue, error) {
+	if tr.err != nil {
+		return nil, tr.err
+	}
 	if !tr.started {
 		if _, _, err := tr.ReadHeader(); err != nil {
 			return nil, err
@@ -194,9 +214,19 @@ func (tr *TabularReader) Next() (*GValue, error) {
 	if tr.finished {
 		return nil, io.EOF
 	}
+	if tr.columnMajor {
+		return tr.nextTransposed()
+	}
 
-	for tr.scanner.Scan() {
-		line := strings.TrimSpace(tr.scanner.Text())
+	for {
+		line, err := tr.scanLine()
+		if err == io.EOF {
+			// No @end found - that's an error
+			return nil, fmt.Errorf("unexpected end of input (missing @end)")
+		}
+		if err != nil {
+			return nil, err
+		}
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -210,20 +240,71 @@ func (tr *TabularReader) Next() (*GValue, error) {
 		}
 
 		// Parse data row
-		tr.rowNum++
-		return tr.parseRow(line)
+		if err := tr.countRow(); err != nil {
+			return nil, err
+		}
+		line, err = resolveRowRef(line, tr.seen)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", tr.rowNum, err)
+		}
+		tr.seen = append(tr.seen, line)
+		return tr.checkRow(tr.parseRow(line))
 	}
+}
 
-	if err := tr.scanner.Err(); err != nil {
-		return nil, err
+// nextTransposed returns the next row of a @tabc block. A column holds a
+// value of every row, so the first call reads the whole block.
+func (tr *TabularReader) nextTransposed() (*GValue, error) {
+	if tr.transposed == nil {
+		var lines []string
+		for {
+			line, err := tr.scanLine()
+			if err == io.EOF {
+				return nil, fmt.Errorf("unexpected end of input (missing @end)")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			lines = append(lines, line)
+			if line == "@end" {
+				break
+			}
+		}
+		rows, err := transposeTabularColumns(lines, len(tr.columns))
+		if err != nil {
+			return nil, err
+		}
+		tr.transposed = rows
 	}
 
-	// No @end found - that's an error
-	return nil, fmt.Errorf("unexpected end of input (missing @end)")
+	line := tr.transposed[0]
+	tr.transposed = tr.transposed[1:]
+	if line == "@end" {
+		tr.finished = true
+		return nil, io.EOF
+	}
+	if err := tr.countRow(); err != nil {
+		return nil, err
+	}
+	return tr.checkRow(tr.parseRow(line))
 }
 
 // parseRow parses a single data row.
 func (tr *TabularReader) parseRow(line string) (*GValue, error) {
+	if tr.td == nil {
+		row, err := parseTabularLooseRow(line, tr.columns, nil)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", tr.rowNum, err)
+		}
+		return row, nil
+	}
+	if strings.HasPrefix(line, "|") {
+		return tr.parsePipeRow(line)
+	}
+
 	p := &tabularRowParser{
 		input:  line,
 		schema: tr.schema,
@@ -265,6 +346,154 @@ func (tr *TabularReader) parseRow(line string) (*GValue, error) {
 	}, nil
 }
 
+// parsePipeRow parses a |cell|cell| row of a typed table. Each cell holds
+// one value; an empty cell is null.
+func (tr *TabularReader) parsePipeRow(line string) (*GValue, error) {
+	if len(line) < 2 || !strings.HasSuffix(line, "|") {
+		return nil, fmt.Errorf("row %d: row must end with '|'", tr.rowNum)
+	}
+	cells := splitTabularCells(line[1 : len(line)-1])
+	if len(cells) != len(tr.fields) {
+		return nil, fmt.Errorf("row %d: expected %d cells, got %d", tr.rowNum, len(tr.fields), len(cells))
+	}
+
+	entries := make([]MapEntry, 0, len(tr.fields))
+	for i, fd := range tr.fields {
+		p := &tabularRowParser{input: unescapeTabularCell(cells[i]), schema: tr.schema}
+		p.skipWhitespace()
+		if p.pos >= len(p.input) {
+			entries = append(entries, MapEntry{Key: fd.Name, Value: Null()})
+			continue
+		}
+		val, err := p.parseValue(fd)
+		if err != nil {
+			return nil, fmt.Errorf("row %d, column %d (%s): %w", tr.rowNum, i+1, tr.columns[i], err)
+		}
+		p.skipWhitespace()
+		if p.pos < len(p.input) {
+			return nil, fmt.Errorf("row %d, column %d (%s): unexpected %q after value",
+				tr.rowNum, i+1, tr.columns[i], p.input[p.pos:])
+		}
+		entries = append(entries, MapEntry{Key: fd.Name, Value: val})
+	}
+
+	return &GValue{
+		typ: TypeStruct,
+		structVal: &StructValue{
+			TypeName: tr.typeName,
+			Fields:   entries,
+		},
+	}, nil
+}
+
+// ============================================================
+// Row Checks
+// ============================================================
+//
+// A table of thousands of rows is usually read to be stored or handed on,
+// and validating the rows afterwards finds a bad one only once all are in
+// memory. With a RowCheck the reader validates each typed row as Next
+// parses it, against its struct type (field types, constraints, required
+// fields and cross-field rules, as Validator.ValidateAs), and reports the
+// problems with the row number. Rows of an untyped @tab _ table are not
+// checked.
+
+// RowCheck says what a TabularReader does with a row that fails validation.
+type RowCheck uint8
+
+const (
+	RowCheckOff   RowCheck = iota // Rows are not validated (the default)
+	RowCheckWarn                  // Rows are returned; problems are kept in Warnings
+	RowCheckError                 // Next returns a *RowError instead of the row
+)
+
+// RowError reports the validation errors of one table row.
+type RowError struct {
+	Row    int // 1-based data row number
+	Errors []ValidationError
+}
+
+func (e *RowError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, strings.Join(msgs, "; "))
+}
+
+// SetRowCheck makes Next validate each typed row. Under RowCheckError a
+// row that fails is skipped: Next returns its *RowError, and the following
+// call reads on from the next row.
+func (tr *TabularReader) SetRowCheck(check RowCheck) {
+	tr.check = check
+	if check != RowCheckOff && tr.validator == nil {
+		tr.validator = NewValidator(tr.schema)
+	}
+}
+
+// Warnings returns the rows that failed validation under RowCheckWarn, in
+// the order they were read.
+func (tr *TabularReader) Warnings() []*RowError {
+	return tr.warnings
+}
+
+// checkRow validates a row just parsed, as SetRowCheck asks.
+func (tr *TabularReader) checkRow(row *GValue, err error) (*GValue, error) {
+	if err != nil || tr.check == RowCheckOff || tr.td == nil {
+		return row, err
+	}
+	res := tr.validator.ValidateAs(row, tr.typeName)
+	if res.Valid {
+		return row, nil
+	}
+	rowErr := &RowError{Row: tr.rowNum, Errors: res.Errors}
+	if tr.check == RowCheckError {
+		return nil, rowErr
+	}
+	tr.warnings = append(tr.warnings, rowErr)
+	return row, nil
+}
+
+// SetLimits bounds the input a reader takes in, so that a runaway or hostile
+// producer cannot make ReadAll, or the row text the reader keeps for =N
+// references, grow without end. Next fails with an error wrapping
+// ErrReadLimit, and keeps failing, once the table has more than maxRows
+// rows or more than maxBytes bytes of input (header and comments included)
+// have been read. Zero means no limit. A line is read in full before it is
+// counted, so a single line may go past maxBytes by up to the 4 MiB line
+// limit.
+func (tr *TabularReader) SetLimits(maxRows int, maxBytes int64) {
+	tr.maxRows = maxRows
+	tr.maxBytes = maxBytes
+}
+
+// scanLine returns the next input line, trimmed, counting it against the
+// byte limit. It returns io.EOF at the end of input.
+func (tr *TabularReader) scanLine() (string, error) {
+	if !tr.scanner.Scan() {
+		if err := tr.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	tr.nbytes += int64(len(tr.scanner.Bytes())) + 1
+	if tr.maxBytes > 0 && tr.nbytes > tr.maxBytes {
+		tr.err = fmt.Errorf("%w: input over %d bytes", ErrReadLimit, tr.maxBytes)
+		return "", tr.err
+	}
+	return strings.TrimSpace(tr.scanner.Text()), nil
+}
+
+// countRow counts a data row against the row limit.
+func (tr *TabularReader) countRow() error {
+	tr.rowNum++
+	if tr.maxRows > 0 && tr.rowNum > tr.maxRows {
+		tr.err = fmt.Errorf("%w: more than %d rows", ErrReadLimit, tr.maxRows)
+		return tr.err
+	}
+	return nil
+}
+
 // RowNum returns the number of data rows read so far.
 func (tr *TabularReader) RowNum() int {
 	return tr.rowNum
@@ -272,9 +501,19 @@ func (tr *TabularReader) RowNum() int {
 
 // ReadAll reads all remaining rows into a slice.
 func (tr *TabularReader) ReadAll() ([]*GValue, error) {
+	return tr.ReadAllContext(context.Background())
+}
+
+// ReadAllContext is ReadAll that gives up with ctx.Err() once ctx is done.
+// ctx is checked between rows; a read blocked on the underlying io.Reader
+// ends only when that reader returns.
+func (tr *TabularReader) ReadAllContext(ctx context.Context) ([]*GValue, error) {
 	var rows []*GValue
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		row, err := tr.Next()
 		if err == io.EOF {
 			break
@@ -321,6 +560,28 @@ func (p *tabularRowParser) parseValue(fd *FieldDef) (*GValue, error) {
 		return Null(), nil
 	}
 
+	// Vector literal: vec16"..." (see vec.go).
+	if prefix := vecLiteralPrefix(p.input[p.pos:]); prefix != "" {
+		p.pos += len(prefix)
+		s, err := p.parseQuotedString()
+		if err != nil {
+			return nil, err
+		}
+		body, _ := s.AsStr()
+		return decodeVecLiteral(prefix, body)
+	}
+
+	// Custom scalar literal: money"12.34 USD" (see scalar_ext.go).
+	if tag := scalarLiteralPrefix(p.input[p.pos:]); tag != "" {
+		p.pos += len(tag)
+		s, err := p.parseQuotedString()
+		if err != nil {
+			return nil, err
+		}
+		text, _ := s.AsStr()
+		return decodeScalarLiteral(tag, text)
+	}
+
 	switch c {
 	case 't':
 		// true or bare string
@@ -353,16 +614,29 @@ func (p *tabularRowParser) parseValue(fd *FieldDef) (*GValue, error) {
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		return p.parseNumberOrTime()
 
-	case 'b':
-		// Bytes literal: b64"<base64>" — intercept before the generic type-name path.
-		if strings.HasPrefix(p.input[p.pos:], `b64"`) {
-			p.pos += 3 // consume 'b', '6', '4'
+	case 'g':
+		// Geo literal: geo5"..." (see geo.go).
+		if prefix := geoLiteralPrefix(p.input[p.pos:]); prefix != "" {
+			p.pos += len(prefix)
+			s, err := p.parseQuotedString()
+			if err != nil {
+				return nil, err
+			}
+			body, _ := s.AsStr()
+			return decodeGeoLiteral(prefix, body)
+		}
+		return p.parseNestedPackedOrBareString()
+
+	case 'b', 'h':
+		// Bytes literal: b64"..." etc. — intercept before the generic type-name path.
+		if prefix := bytesLiteralPrefix(p.input[p.pos:]); prefix != "" {
+			p.pos += len(prefix)
 			s, err := p.parseQuotedString()
 			if err != nil {
 				return nil, err
 			}
 			body, _ := s.AsStr()
-			return decodeBytesLiteral(body)
+			return decodeBytesLiteral(prefix, body)
 		}
 		// Not a bytes literal — fall through to type-name / bare-string handling.
 		return p.parseNestedPackedOrBareString()
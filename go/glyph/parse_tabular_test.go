@@ -1,6 +1,8 @@
 package glyph
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -122,7 +124,7 @@ func TestTabularReaderWithPackedCells(t *testing.T) {
 	}
 
 	homeId := home.Get("id")
-	if homeId == nil || homeId.idVal.Value != "ARS" {
+	if homeId == nil || homeId.idVal().Value != "ARS" {
 		t.Errorf("row1.home.id = %v, want 'ARS'", homeId)
 	}
 
@@ -182,7 +184,7 @@ func TestInlineTabular(t *testing.T) {
 	}
 
 	id := rows[0].Get("id")
-	if id == nil || id.idVal.Value != "ARS" {
+	if id == nil || id.idVal().Value != "ARS" {
 		t.Errorf("rows[0].id = %v, want 'ARS'", id)
 	}
 }
@@ -207,7 +209,7 @@ func TestTabularReaderFIDColumns(t *testing.T) {
 	}
 
 	// FID 1 = id, FID 2 = name, FID 3 = league
-	if rows[0].Get("id").idVal.Value != "ARS" {
+	if rows[0].Get("id").idVal().Value != "ARS" {
 		t.Errorf("rows[0].id = %v, want 'ARS'", rows[0].Get("id"))
 	}
 	if mustAsStr(t, rows[0].Get("name")) != "Arsenal" {
@@ -303,8 +305,8 @@ func TestTabularReaderWithTime(t *testing.T) {
 	}
 
 	expected := time.Date(2025, 12, 19, 20, 0, 0, 0, time.UTC)
-	if !kickoff.timeVal.Equal(expected) {
-		t.Errorf("kickoff = %v, want %v", kickoff.timeVal, expected)
+	if !kickoff.timeVal().Equal(expected) {
+		t.Errorf("kickoff = %v, want %v", kickoff.timeVal(), expected)
 	}
 }
 
@@ -331,8 +333,8 @@ func TestTabularReaderDateOnly(t *testing.T) {
 	}
 
 	expected := time.Date(2025, 12, 19, 0, 0, 0, 0, time.UTC)
-	if !kickoff.timeVal.Equal(expected) {
-		t.Errorf("kickoff = %v, want %v", kickoff.timeVal, expected)
+	if !kickoff.timeVal().Equal(expected) {
+		t.Errorf("kickoff = %v, want %v", kickoff.timeVal(), expected)
 	}
 }
 
@@ -464,6 +466,137 @@ func TestTabularReaderLargeRow(t *testing.T) {
 	}
 }
 
+func TestTabularReaderLoosePipeRows(t *testing.T) {
+	schema := makeHikeSchema()
+	hikes := List(
+		makeHikeValue(1, "Blue Lake Trail", 7.5, 320, "ana", true),
+		makeHikeValue(2, "a|b", 9.2, 540, "luis", false),
+		makeHikeValue(3, "Wildflower Loop", 5, 180, "sam", true),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+	out := CanonicalizeLooseWithOpts(hikes, opts)
+
+	tr := NewTabularReaderFromString(out, schema)
+	rows, err := tr.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll error: %v\n%s", err, out)
+	}
+	if tr.TypeName() != "Hike" || !EqualLoose(List(rows...), hikes) {
+		t.Errorf("read %s %s\nfrom:\n%s", tr.TypeName(), CanonicalizeLooseNoTabular(List(rows...)), out)
+	}
+}
+
+func TestTabularReaderColumnMajor(t *testing.T) {
+	schema := makeHikeSchema()
+	hikes := List(
+		makeHikeValue(1, "Blue Lake Trail", 7.5, 320, "ana", true),
+		makeHikeValue(2, "Ridge Overlook", 9.2, 540, "luis", false),
+		makeHikeValue(3, "Wildflower Loop", 5, 180, "sam", true),
+	)
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+	opts.TableLayout = TableColumnMajor
+	out := CanonicalizeLooseWithOpts(hikes, opts)
+	if !strings.HasPrefix(out, "@tabc Hike rows=3 cols=6 [i n d e c s]\n|1|2|3|\n") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	tr := NewTabularReaderFromString(out, schema)
+	var rows []*GValue
+	for {
+		row, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if tr.RowNum() != 3 || !EqualLoose(List(rows...), hikes) {
+		t.Errorf("read %s", CanonicalizeLooseNoTabular(List(rows...)))
+	}
+
+	truncated := strings.TrimSuffix(out, "@end")
+	if _, err := NewTabularReaderFromString(truncated, schema).ReadAll(); err == nil {
+		t.Error("expected error for @tabc block without @end")
+	}
+}
+
+func TestTabularReaderRowRefs(t *testing.T) {
+	input := "@tab _ [host status]\n|web1|up|\n|web2|down|\n=1\n=2\n=3\n@end"
+	rows, err := NewTabularReaderFromString(input, nil).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	want := []string{"web1", "web2", "web1", "web2", "web1"}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, host := range want {
+		if got := mustAsStr(t, rows[i].Get("host")); got != host {
+			t.Errorf("row %d host = %q, want %q", i+1, got, host)
+		}
+	}
+
+	if _, err := NewTabularReaderFromString("@tab _ [a]\n|1|\n=2\n@end", nil).ReadAll(); err == nil {
+		t.Error("expected error for forward reference")
+	}
+}
+
+func TestTabularReaderUntypedLoose(t *testing.T) {
+	input := "@tab _ rows=2 cols=2 [id name]\n|1|Alice|\n|2|\"Bob \\| Jr\"|\n@end"
+
+	tr := NewTabularReaderFromString(input, nil)
+	rows, err := tr.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll error: %v", err)
+	}
+	if tr.TypeName() != "" || len(rows) != 2 {
+		t.Fatalf("type %q, %d rows", tr.TypeName(), len(rows))
+	}
+	if rows[1].Type() != TypeMap || mustAsStr(t, rows[1].Get("name")) != "Bob | Jr" {
+		t.Errorf("row 2 = %s", CanonicalizeLooseNoTabular(rows[1]))
+	}
+}
+
+func TestTabularReaderPipeRowErrors(t *testing.T) {
+	schema := makeHikeSchema()
+	for _, row := range []string{
+		"|1|x|",                // too few cells
+		"|1|x|1.5|2|^p:a|t",    // no closing pipe
+		"|1|x y|1.5|2|^p:a|t|", // two values in one cell
+	} {
+		input := "@tab Hike [i n d e c s]\n" + row + "\n@end"
+		if _, err := NewTabularReaderFromString(input, schema).ReadAll(); err == nil {
+			t.Errorf("%s: expected error", row)
+		}
+	}
+}
+
+func TestParseTabularLooseSpacedRows(t *testing.T) {
+	schema := makeHikeSchema()
+	hikes := List(
+		makeHikeValue(1, "Blue Lake Trail", 7.5, 320, "ana", true),
+		makeHikeValue(2, "Ridge Overlook", 9.2, 540, "luis", false),
+		makeHikeValue(3, "Wildflower Loop", 5.1, 180, "sam", true),
+	)
+	out, err := EmitTabular(hikes, schema)
+	if err != nil {
+		t.Fatalf("EmitTabular error: %v", err)
+	}
+
+	got, err := ParseLoose(out, nil)
+	if err != nil {
+		t.Fatalf("ParseLoose error: %v\n%s", err, out)
+	}
+	ResolveTypedColumns(got, schema)
+	if !EqualLoose(got, hikes) || got.listVal[0].structVal.TypeName != "Hike" {
+		t.Errorf("got %s\nfrom:\n%s", CanonicalizeLooseNoTabular(got), out)
+	}
+}
+
 func makeListMapSchemaForTabularTest() *Schema {
 	return NewSchemaBuilder().
 		AddStruct("Foo", "v1",
@@ -480,3 +613,114 @@ func makeSingleStringSchemaForTabularTest() *Schema {
 		).
 		Build()
 }
+
+func TestTabularReaderRowCheck(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		Reading struct{
+			sensor: str [len>=2]
+			value: float [0..100]
+			unit: str [enum=[c f]] [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `@tab Reading [sensor value unit]
+s1 20.5 c
+s2 140 c
+x 30 k
+s4 50 _
+@end`
+
+	tr := NewTabularReaderFromString(input, schema)
+	tr.SetRowCheck(RowCheckWarn)
+	rows, err := tr.ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("ReadAll = %d rows, %v", len(rows), err)
+	}
+	warnings := tr.Warnings()
+	if len(warnings) != 2 || warnings[0].Row != 2 || warnings[1].Row != 3 {
+		t.Fatalf("warnings %v", warnings)
+	}
+	if len(warnings[1].Errors) != 2 || !strings.HasPrefix(warnings[1].Error(), "row 3: sensor: ") {
+		t.Errorf("row 3: %v", warnings[1])
+	}
+
+	// Under RowCheckError a bad row is skipped and reading goes on.
+	tr = NewTabularReaderFromString(input, schema)
+	tr.SetRowCheck(RowCheckError)
+	var good []int
+	var bad []int
+	for {
+		row, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			bad = append(bad, rowErr.Row)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row == nil {
+			t.Fatal("nil row without error")
+		}
+		good = append(good, tr.RowNum())
+	}
+	if fmt.Sprint(good) != "[1 4]" || fmt.Sprint(bad) != "[2 3]" {
+		t.Errorf("good rows %v, bad rows %v", good, bad)
+	}
+	if len(tr.Warnings()) != 0 {
+		t.Errorf("warnings under RowCheckError: %v", tr.Warnings())
+	}
+
+	// Without a check every row is returned.
+	rows, err = NewTabularReaderFromString(input, schema).ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Errorf("unchecked ReadAll = %d rows, %v", len(rows), err)
+	}
+}
+
+func TestTabularReaderLimits(t *testing.T) {
+	schema := makeHikeSchemaForTabularTest()
+	input := `@tab Hike [i n d e c s]
+1 "Blue Lake Trail" 7.5 320 ^p:ana t
+2 "Ridge Overlook" 9.2 540 ^p:luis f
+3 "Wildflower Loop" 5.1 180 ^p:sam t
+@end`
+
+	tr := NewTabularReaderFromString(input, schema)
+	tr.SetLimits(2, 0)
+	if _, err := tr.ReadAll(); !errors.Is(err, ErrReadLimit) {
+		t.Errorf("row limit: %v", err)
+	}
+	if _, err := tr.Next(); !errors.Is(err, ErrReadLimit) {
+		t.Errorf("Next after the limit: %v", err)
+	}
+
+	tr = NewTabularReaderFromString(input, schema)
+	tr.SetLimits(0, int64(strings.Index(input, "3 ")))
+	for i := 1; i <= 2; i++ {
+		if _, err := tr.Next(); err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+	}
+	if _, err := tr.Next(); !errors.Is(err, ErrReadLimit) {
+		t.Errorf("byte limit: %v", err)
+	}
+
+	// Limits the table stays within change nothing.
+	tr = NewTabularReaderFromString(input, schema)
+	tr.SetLimits(3, int64(len(input)+1))
+	if rows, err := tr.ReadAll(); err != nil || len(rows) != 3 {
+		t.Errorf("within limits: %d rows, %v", len(rows), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewTabularReaderFromString(input, schema).ReadAllContext(ctx); err != context.Canceled {
+		t.Errorf("canceled: %v", err)
+	}
+}
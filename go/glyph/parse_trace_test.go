@@ -0,0 +1,47 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	schema, err := ParseSchema(`@schema{ Order struct{ qty: int  note: str } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	opts := ParseOptions{Schema: schema, Tolerant: true, TraceFn: func(e TraceEvent) {
+		events = append(events, fmt.Sprintf("%s/%s@%d", e.Rule, e.Action, e.Pos.Offset))
+	}}
+	result, err := ParseWithOptions(`[Order{qty=1,234 note=ok} Order{qty="x"} {a=1 a=2} [1 2`, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) == 0 {
+		t.Fatal("no repairs")
+	}
+
+	want := []string{
+		"ident/struct@1",
+		"number/repaired_number@11",
+		"ident/bare_string@22",
+		"ident/struct@26",
+		"number/not_repaired@36",
+		"map/duplicate_key@46",
+		"list/auto_closed@55",
+		"list/auto_closed@55",
+	}
+	if got := strings.Join(events, " "); got != strings.Join(want, " ") {
+		t.Errorf("trace\n got %s\nwant %s", got, strings.Join(want, " "))
+	}
+
+	// Warnings and errors are traced with their codes.
+	events = nil
+	opts.Tolerant = false
+	ParseWithOptions(`{a=[1}`, opts)
+	if len(events) == 0 || !strings.HasSuffix(strings.SplitN(events[0], "@", 2)[0], "/error") {
+		t.Errorf("strict trace = %v", events)
+	}
+}
@@ -0,0 +1,336 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Patch Conflicts
+// ============================================================
+//
+// Two agents editing one document each send a patch against the same base.
+// MergePatches applies both: changes to different parts of the document
+// combine, and where both patches change the same part to different results
+// it reports a conflict instead of picking a side. Deltas to a number and
+// appends to a list do not conflict; both apply, ours first. A list counts
+// as one value, so edits to different items of one list conflict, since an
+// insert or delete by one side moves the items the other addresses.
+//
+// RenderConflicts writes the merged document as indented GLYPH-T with each
+// conflict marked as in a text merge, for a person to resolve:
+//
+//	Task{
+//	  count=4
+//	  <<< ours status
+//	  status=done
+//	  ||| base
+//	  status=open
+//	  ===
+//	  status=review
+//	  >>> theirs status
+//	  title="Fix login flow"
+//	}
+//
+// Each side holds the whole field, or nothing if that side deleted it, so
+// a conflict is resolved by deleting the marker lines and the sides not
+// kept. The text parses once every conflict is resolved.
+
+// PatchConflict is a path two patches change to different values. A value
+// is nil where its document has nothing at Path.
+type PatchConflict struct {
+	Path   []PathSeg
+	Base   *GValue
+	Ours   *GValue
+	Theirs *GValue
+}
+
+// MergePatches applies ours and theirs, two patches against base, and
+// returns base with ours and every change of theirs outside the conflicts
+// applied, and the conflicts in path order. Neither patch may need a
+// schema to apply (see ApplyPatch). base is not modified.
+func MergePatches(base *GValue, ours, theirs *Patch) (*GValue, []PatchConflict, error) {
+	oursDoc, err := ApplyPatch(base, ours)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ours: %w", err)
+	}
+	theirsDoc, err := ApplyPatch(base, theirs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("theirs: %w", err)
+	}
+
+	// The outermost paths both patches touch.
+	var overlaps [][]PathSeg
+	for _, o := range ours.Ops {
+		for _, t := range theirs.Ops {
+			if opsCommute(base, o, t) {
+				continue
+			}
+			for _, po := range touchedPaths(o) {
+				for _, pt := range touchedPaths(t) {
+					switch {
+					case pathHasPrefix(pt, po):
+						overlaps = addOverlap(overlaps, po)
+					case pathHasPrefix(po, pt):
+						overlaps = addOverlap(overlaps, pt)
+					}
+				}
+			}
+		}
+	}
+
+	// Where both ended with the same value, the patches agree.
+	var conflicts []PatchConflict
+	for _, p := range overlaps {
+		ov, tv := valueAtPath(oursDoc, p), valueAtPath(theirsDoc, p)
+		if ov == nil && tv == nil || ov != nil && tv != nil && EqualLoose(ov, tv) {
+			continue
+		}
+		conflicts = append(conflicts, PatchConflict{Path: p, Base: valueAtPath(base, p), Ours: ov, Theirs: tv})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return pathSegsStr(conflicts[i].Path) < pathSegsStr(conflicts[j].Path)
+	})
+
+	// Theirs outside the overlaps touches nothing ours did.
+	rest := &Patch{Target: theirs.Target, SchemaID: theirs.SchemaID, TargetType: theirs.TargetType}
+	for _, t := range theirs.Ops {
+		if !overlapsAny(touchedPaths(t), overlaps) {
+			rest.Ops = append(rest.Ops, t)
+		}
+	}
+	merged, err := ApplyPatch(oursDoc, rest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("theirs after ours: %w", err)
+	}
+	return merged, conflicts, nil
+}
+
+// opsCommute reports whether o and t give the same result in either order:
+// two deltas, or two appends to the end of the same list.
+func opsCommute(base *GValue, o, t *PatchOp) bool {
+	switch {
+	case o.Op == OpDelta && t.Op == OpDelta:
+		return true
+	case o.Op == OpAppend && t.Op == OpAppend && o.Index < 0 && t.Index < 0 && pathSegsEqual(o.Path, t.Path):
+		list := valueAtPath(base, o.Path)
+		return list != nil && list.Type() == TypeList
+	}
+	return false
+}
+
+// touchedPaths returns the paths op reads or writes, each cut at its first
+// list index.
+func touchedPaths(op *PatchOp) [][]PathSeg {
+	paths := [][]PathSeg{cutAtListIndex(op.Path)}
+	if op.From != nil {
+		paths = append(paths, cutAtListIndex(op.From))
+	}
+	return paths
+}
+
+func cutAtListIndex(path []PathSeg) []PathSeg {
+	for i, seg := range path {
+		if seg.Kind == PathSegListIdx {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// pathHasPrefix reports whether path starts with prefix. Field and map key
+// segments with the same name match, as they do when a patch applies.
+func pathHasPrefix(path, prefix []PathSeg) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if pathSegKey(path[i]) != pathSegKey(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func pathSegKey(seg PathSeg) string {
+	switch seg.Kind {
+	case PathSegMapKey:
+		return seg.MapKey
+	case PathSegListIdx:
+		return "[" + strconv.Itoa(seg.ListIdx) + "]"
+	}
+	if seg.Field == "" {
+		return "#" + strconv.Itoa(seg.FID)
+	}
+	return seg.Field
+}
+
+// addOverlap adds p to overlaps unless a path there contains it, and drops
+// the paths p contains.
+func addOverlap(overlaps [][]PathSeg, p []PathSeg) [][]PathSeg {
+	kept := overlaps[:0]
+	for _, q := range overlaps {
+		if pathHasPrefix(p, q) {
+			return overlaps
+		}
+		if !pathHasPrefix(q, p) {
+			kept = append(kept, q)
+		}
+	}
+	return append(kept, p)
+}
+
+func overlapsAny(paths, overlaps [][]PathSeg) bool {
+	for _, p := range paths {
+		for _, q := range overlaps {
+			if pathHasPrefix(p, q) || pathHasPrefix(q, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueAtPath returns the value at path in v, or nil if there is none.
+func valueAtPath(v *GValue, path []PathSeg) *GValue {
+	x, err := lookupPathSegs(v, path)
+	if err != nil {
+		return nil
+	}
+	return x
+}
+
+// RenderConflicts writes doc, as returned by MergePatches, as indented
+// GLYPH-T with conflicts marked. Maps and structs are written one entry per
+// line, other values as Emit writes them. With showBase each conflict also
+// shows the base value, between ||| base and ===.
+func RenderConflicts(doc *GValue, conflicts []PatchConflict, showBase bool) string {
+	r := &conflictRenderer{
+		e:        &emitter{opts: DefaultEmitOptions()},
+		at:       make(map[string]*PatchConflict, len(conflicts)),
+		showBase: showBase,
+	}
+	for i := range conflicts {
+		r.at[conflictKey(conflicts[i].Path)] = &conflicts[i]
+	}
+	if c := r.at[""]; c != nil {
+		r.conflict(c, "", 0)
+	} else {
+		r.value(doc, nil, 0)
+		r.e.sb.WriteByte('\n')
+	}
+	return r.e.sb.String()
+}
+
+type conflictRenderer struct {
+	e        *emitter // Output, and how keys and scalars are written
+	at       map[string]*PatchConflict
+	showBase bool
+}
+
+// conflictKey identifies a path for matching against the paths walked.
+func conflictKey(path []PathSeg) string {
+	keys := make([]string, len(path))
+	for i, seg := range path {
+		keys[i] = pathSegKey(seg)
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// value writes v at path, on a line indented depth levels. The line is
+// already started; value does not end it.
+func (r *conflictRenderer) value(v *GValue, path []PathSeg, depth int) {
+	v.force()
+	var entries []MapEntry
+	switch {
+	case v != nil && v.typ == TypeMap:
+		entries = v.mapVal
+	case v != nil && v.typ == TypeStruct:
+		entries = v.structVal.Fields
+	default:
+		r.e.emit(v, depth)
+		return
+	}
+
+	// Entries a conflict has but doc does not, because ours deleted them.
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		present[e.Key] = true
+	}
+	for _, c := range r.at {
+		if len(c.Path) == len(path)+1 && pathHasPrefix(c.Path, path) {
+			if name := pathSegKey(c.Path[len(path)]); !present[name] {
+				entries = append(entries, MapEntry{Key: name})
+				present[name] = true
+			}
+		}
+	}
+	if len(entries) == 0 {
+		r.e.emit(v, depth)
+		return
+	}
+
+	if v.typ == TypeStruct {
+		r.e.sb.WriteString(v.structVal.TypeName)
+	}
+	r.e.sb.WriteString("{\n")
+	for _, e := range sortMapEntries(entries) {
+		child := append(path[:len(path):len(path)], FieldSeg(e.Key, 0))
+		if c := r.at[conflictKey(child)]; c != nil {
+			r.conflict(c, r.key(v, e.Key), depth+1)
+			continue
+		}
+		r.e.writeIndent(depth + 1)
+		r.e.sb.WriteString(r.key(v, e.Key))
+		r.value(e.Value, child, depth+1)
+		r.e.sb.WriteByte('\n')
+	}
+	r.e.writeIndent(depth)
+	r.e.sb.WriteString("}")
+}
+
+// key returns how an entry of parent starts: key= in a struct, key: in a
+// map.
+func (r *conflictRenderer) key(parent *GValue, key string) string {
+	if parent.typ == TypeStruct {
+		return key + "="
+	}
+	e := &emitter{opts: r.e.opts}
+	e.emitString(key)
+	return e.sb.String() + ":"
+}
+
+// conflict writes the lines of c, each side as a whole entry starting with
+// key ("" at the root).
+func (r *conflictRenderer) conflict(c *PatchConflict, key string, depth int) {
+	path := pathSegsStr(c.Path)
+	marker := func(text string) {
+		r.e.writeIndent(depth)
+		r.e.sb.WriteString(strings.TrimSpace(text + " " + path))
+		r.e.sb.WriteByte('\n')
+	}
+	plain := &conflictRenderer{e: r.e} // Conflicts never nest
+	side := func(v *GValue) {
+		if v == nil {
+			return
+		}
+		r.e.writeIndent(depth)
+		r.e.sb.WriteString(key)
+		plain.value(v, nil, depth)
+		r.e.sb.WriteByte('\n')
+	}
+
+	marker("<<< ours")
+	side(c.Ours)
+	if r.showBase {
+		r.e.writeIndent(depth)
+		r.e.sb.WriteString("||| base\n")
+		side(c.Base)
+	}
+	r.e.writeIndent(depth)
+	r.e.sb.WriteString("===\n")
+	side(c.Theirs)
+	marker(">>> theirs")
+}
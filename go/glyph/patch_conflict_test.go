@@ -0,0 +1,210 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// patch_conflict_test.go covers MergePatches (what combines, what
+// conflicts) and RenderConflicts (the marked-up text and resolving it).
+
+func conflictBase(t *testing.T) *GValue {
+	t.Helper()
+	base, err := ParseWithOptions(`Task{title="Fix login" status=open count=1 tags=[auth] notes={a:1 b:2}}`, ParseOptions{})
+	if err != nil {
+		t.Fatalf("parse base: %v", err)
+	}
+	return base.Value
+}
+
+func TestMergePatches(t *testing.T) {
+	base := conflictBase(t)
+	ours := NewPatch(RefID{}, "").
+		Set("status", Str("done")).
+		Delta("count", 1).
+		Append("tags", Str("urgent")).
+		Set(`notes["a"]`, Int(10))
+	theirs := NewPatch(RefID{}, "").
+		Set("status", Str("review")).
+		Delta("count", 2).
+		Append("tags", Str("ui")).
+		Delete("notes").
+		Set("title", Str("Fix login flow"))
+
+	merged, conflicts, err := MergePatches(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("MergePatches: %v", err)
+	}
+	if got := Emit(base); got != `Task{count=1 notes={a:1 b:2} status=open tags=[auth] title="Fix login"}` {
+		t.Errorf("base modified: %s", got)
+	}
+	if got, want := Emit(merged), `Task{count=4 notes={a:10 b:2} status=done tags=[auth urgent ui] title="Fix login flow"}`; got != want {
+		t.Errorf("merged:\n got %s\nwant %s", got, want)
+	}
+
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d", len(conflicts))
+	}
+	notes, status := conflicts[0], conflicts[1]
+	if pathSegsStr(notes.Path) != "notes" || notes.Theirs != nil || Emit(notes.Ours) != "{a:10 b:2}" || Emit(notes.Base) != "{a:1 b:2}" {
+		t.Errorf("notes conflict: %s base=%s ours=%s theirs=%v", pathSegsStr(notes.Path), Emit(notes.Base), Emit(notes.Ours), notes.Theirs)
+	}
+	if pathSegsStr(status.Path) != "status" || Emit(status.Ours) != "done" || Emit(status.Theirs) != "review" {
+		t.Errorf("status conflict: %s ours=%s theirs=%s", pathSegsStr(status.Path), Emit(status.Ours), Emit(status.Theirs))
+	}
+}
+
+func TestMergePatches_NoConflict(t *testing.T) {
+	tests := []struct {
+		name         string
+		ours, theirs *Patch
+		want         string
+	}{
+		{
+			name:   "same change",
+			ours:   NewPatch(RefID{}, "").Set("status", Str("done")),
+			theirs: NewPatch(RefID{}, "").Set("status", Str("done")).Set("count", Int(5)),
+			want:   `Task{count=5 notes={a:1 b:2} status=done tags=[auth] title="Fix login"}`,
+		},
+		{
+			name:   "both delete",
+			ours:   NewPatch(RefID{}, "").Delete("notes"),
+			theirs: NewPatch(RefID{}, "").Delete("notes"),
+			want:   `Task{count=1 status=open tags=[auth] title="Fix login"}`,
+		},
+		{
+			name:   "different map keys",
+			ours:   NewPatch(RefID{}, "").Set(`notes["a"]`, Int(3)),
+			theirs: NewPatch(RefID{}, "").Set(`notes["b"]`, Int(4)),
+			want:   `Task{count=1 notes={a:3 b:4} status=open tags=[auth] title="Fix login"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts, err := MergePatches(conflictBase(t), tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatalf("MergePatches: %v", err)
+			}
+			if len(conflicts) != 0 {
+				t.Errorf("unexpected conflicts: %+v", conflicts)
+			}
+			if got := Emit(merged); got != tt.want {
+				t.Errorf("merged:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatches_ListItems(t *testing.T) {
+	// Edits to different items still conflict: the list is one value.
+	base := conflictBase(t)
+	ours := NewPatch(RefID{}, "").Append("tags", Str("a"))
+	theirs := NewPatch(RefID{}, "").Set("tags[0]", Str("login"))
+
+	merged, conflicts, err := MergePatches(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("MergePatches: %v", err)
+	}
+	if len(conflicts) != 1 || pathSegsStr(conflicts[0].Path) != "tags" {
+		t.Fatalf("expected a conflict at tags, got %+v", conflicts)
+	}
+	if got := Emit(merged.Get("tags")); got != "[auth a]" {
+		t.Errorf("merged tags = %s, want ours", got)
+	}
+}
+
+func TestMergePatches_Errors(t *testing.T) {
+	base := conflictBase(t)
+	ok := NewPatch(RefID{}, "")
+	bad := NewPatch(RefID{}, "").Delta("title", 1)
+
+	if _, _, err := MergePatches(base, bad, ok); err == nil || !strings.HasPrefix(err.Error(), "ours: ") {
+		t.Errorf("bad ours: got %v", err)
+	}
+	if _, _, err := MergePatches(base, ok, bad); err == nil || !strings.HasPrefix(err.Error(), "theirs: ") {
+		t.Errorf("bad theirs: got %v", err)
+	}
+}
+
+func TestRenderConflicts(t *testing.T) {
+	base := conflictBase(t)
+	ours := NewPatch(RefID{}, "").Set("status", Str("done")).Set(`notes["a"]`, Int(10))
+	theirs := NewPatch(RefID{}, "").Set("status", Str("review")).Delete("notes").Set("title", Str("Fix login flow"))
+
+	merged, conflicts, err := MergePatches(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("MergePatches: %v", err)
+	}
+	got := RenderConflicts(merged, conflicts, true)
+	want := `Task{
+  count=1
+  <<< ours notes
+  notes={
+    a:10
+    b:2
+  }
+  ||| base
+  notes={
+    a:1
+    b:2
+  }
+  ===
+  >>> theirs notes
+  <<< ours status
+  status=done
+  ||| base
+  status=open
+  ===
+  status=review
+  >>> theirs status
+  tags=[auth]
+  title="Fix login flow"
+}
+`
+	if got != want {
+		t.Errorf("RenderConflicts:\n%s\nwant:\n%s", got, want)
+	}
+	if noBase := RenderConflicts(merged, conflicts, false); strings.Contains(noBase, "|||") || strings.Contains(noBase, "status=open") {
+		t.Errorf("base shown without showBase:\n%s", noBase)
+	}
+
+	// Keep theirs everywhere: what is left parses to theirs.
+	var kept []string
+	keep := true
+	for _, line := range strings.Split(got, "\n") {
+		switch strings.Fields(line + " x")[0] {
+		case "<<<", "|||":
+			keep = false
+		case "===":
+			keep = true
+		case ">>>":
+		default:
+			if keep {
+				kept = append(kept, line)
+			}
+		}
+	}
+	resolved, err := ParseWithOptions(strings.Join(kept, "\n"), ParseOptions{})
+	if err != nil {
+		t.Fatalf("parse resolved: %v\n%s", err, strings.Join(kept, "\n"))
+	}
+	theirsDoc, _ := ApplyPatch(base, theirs)
+	if !EqualLoose(resolved.Value, theirsDoc) {
+		t.Errorf("resolved = %s, want %s", Emit(resolved.Value), Emit(theirsDoc))
+	}
+}
+
+func TestRenderConflicts_Root(t *testing.T) {
+	base := conflictBase(t)
+	ours := NewPatch(RefID{}, "").SetWithSegs(nil, Int(1))
+	theirs := NewPatch(RefID{}, "").SetWithSegs(nil, Int(2))
+
+	merged, conflicts, err := MergePatches(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("MergePatches: %v", err)
+	}
+	want := "<<< ours\n1\n===\n2\n>>> theirs\n"
+	if got := RenderConflicts(merged, conflicts, false); got != want {
+		t.Errorf("RenderConflicts = %q, want %q", got, want)
+	}
+}
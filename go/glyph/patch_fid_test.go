@@ -297,11 +297,18 @@ func TestKeyModeOutput(t *testing.T) {
 		t.Errorf("FID mode should output #3, got:\n%s", fidOut)
 	}
 
-	// Wire/name mode should output field name
+	// Wire mode should output the wire key
 	wireOpts := PatchOptions{Schema: schema, KeyMode: KeyModeWire}
 	wireOut, _ := EmitPatchWithOptions(patch, wireOpts)
-	if !strings.Contains(wireOut, "home") {
-		t.Errorf("Wire mode should output 'home', got:\n%s", wireOut)
+	if !strings.Contains(wireOut, "= H ") {
+		t.Errorf("Wire mode should output 'H', got:\n%s", wireOut)
+	}
+
+	// Name mode should output the field name
+	nameOpts := PatchOptions{Schema: schema, KeyMode: KeyModeName}
+	nameOut, _ := EmitPatchWithOptions(patch, nameOpts)
+	if !strings.Contains(nameOut, "= home ") {
+		t.Errorf("Name mode should output 'home', got:\n%s", nameOut)
 	}
 
 	t.Logf("FID mode:\n%s", fidOut)
@@ -337,3 +344,53 @@ func TestNestedFIDPaths(t *testing.T) {
 
 	t.Logf("Nested FID path:\n%s", got)
 }
+
+// Test: Nested wire-key paths resolve through the target type
+func TestNestedWireKeyPaths(t *testing.T) {
+	schema := makePatchTestSchema()
+
+	patch := NewPatch(RefID{Prefix: "m", Value: "ARS-LIV"}, schema.Hash)
+	patch.TargetType = "Match"
+	patch.Set("home.score", Int(2))
+	patch.Set("status", Str("live"))
+	patch.Set("pred.xh", Float(1.85)) // Pred fields have no wire keys
+
+	got, err := EmitPatchWithOptions(patch, PatchOptions{Schema: schema, KeyMode: KeyModeWire, SortOps: true})
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+
+	for _, want := range []string{"= H.sc 2", "= s live", "= P.xh 1.85"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in wire output, got:\n%s", want, got)
+		}
+	}
+
+	// Without a target type there is no context, so names pass through.
+	patch.TargetType = ""
+	untyped, _ := EmitPatchWithOptions(patch, PatchOptions{Schema: schema, KeyMode: KeyModeWire})
+	if !strings.Contains(untyped, "= home.score 2") {
+		t.Errorf("Expected field names without target type, got:\n%s", untyped)
+	}
+
+	// Wire output parses back and applies through the schema.
+	parsed, err := ParsePatch(got, schema)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	match := Struct("Match",
+		FieldVal("status", Str("pre")),
+		FieldVal("home", Struct("Team", FieldVal("score", Int(0)))),
+		FieldVal("pred", Struct("Pred", FieldVal("xh", Float(0)))),
+	)
+	result, err := ApplyPatchWithSchema(match, parsed, schema)
+	if err != nil {
+		t.Fatalf("ApplyPatchWithSchema error: %v", err)
+	}
+	if score, _ := result.Get("home").Get("score").AsInt(); score != 2 {
+		t.Errorf("home.score = %d, want 2", score)
+	}
+	if status, _ := result.Get("status").AsStr(); status != "live" {
+		t.Errorf("status = %q, want live", status)
+	}
+}
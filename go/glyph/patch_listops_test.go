@@ -3,6 +3,7 @@ package glyph
 import (
 	"bytes"
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -37,11 +38,11 @@ func patchEqual(a, b *GValue) bool {
 	case TypeStr:
 		return a.strVal == b.strVal
 	case TypeBytes:
-		return bytes.Equal(a.bytesVal, b.bytesVal)
+		return bytes.Equal(a.bytesVal(), b.bytesVal())
 	case TypeTime:
-		return a.timeVal.Equal(b.timeVal)
+		return a.timeVal().Equal(b.timeVal())
 	case TypeID:
-		return a.idVal == b.idVal
+		return a.idVal() == b.idVal()
 	case TypeList:
 		if len(a.listVal) != len(b.listVal) {
 			return false
@@ -338,9 +339,10 @@ func TestDiffApplyRoundTripListIndex(t *testing.T) {
 
 // ---- FID-resolution pre-pass ----------------------------------------------
 
-// TestFIDModeApplyRoundTrip proves the required FID-resolution pre-pass: a
-// FID-mode patch emitted with only #fid path segments parses back with empty
-// Field names and must be resolved (via ApplyPatchWithSchema) before it applies.
+// TestFIDModeApplyRoundTrip proves the FID-resolution pre-pass: a FID-mode
+// patch emitted with only #fid path segments names its root type (@type=), so
+// it parses back resolved. Without @type its Field names stay empty and it
+// must be resolved (via ApplyPatchWithSchema) before it applies.
 func TestFIDModeApplyRoundTrip(t *testing.T) {
 	schema := makePatchTestSchema()
 	base := Struct("Match",
@@ -364,10 +366,19 @@ func TestFIDModeApplyRoundTrip(t *testing.T) {
 		t.Fatalf("EmitPatch error: %v", err)
 	}
 
-	parsed, err := ParsePatch(emitted, schema)
+	resolved, err := ParsePatch(emitted, schema)
 	if err != nil {
 		t.Fatalf("ParsePatch error: %v\n%s", err, emitted)
 	}
+	if _, err := ApplyPatch(base, resolved); err != nil {
+		t.Errorf("ApplyPatch of a patch with @type: %v\n%s", err, emitted)
+	}
+
+	untyped := strings.Replace(emitted, " @type=Match", "", 1)
+	parsed, err := ParsePatch(untyped, schema)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v\n%s", err, untyped)
+	}
 	// Parsed FID paths have empty Field — plain ApplyPatch must refuse them.
 	if _, err := ApplyPatch(base, parsed); err == nil {
 		t.Errorf("expected ApplyPatch to refuse unresolved FID path")
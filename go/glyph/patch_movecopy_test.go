@@ -0,0 +1,159 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// patch_movecopy_test.go covers the move (>) and copy (*) patch ops: text
+// round-trip through EmitPatch/ParsePatch, ApplyPatch semantics for fields,
+// map keys and list indices, and the error cases.
+
+func TestPatchMoveCopy_EmitParseRoundTrip(t *testing.T) {
+	patch := NewPatch(RefID{Prefix: "d", Value: "1"}, "")
+	patch.Set("title", Str("x"))
+	patch.Move("items[3]", "items[0]")
+	patch.Copy(`cfg["a b"]`, `cfg["c]"]`)
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	for _, want := range []string{"> items[3] items[0]", `* cfg["a b"] cfg["c]"]`} {
+		if !strings.Contains(emitted, want) {
+			t.Errorf("expected %q in:\n%s", want, emitted)
+		}
+	}
+
+	parsed, err := ParsePatch(emitted, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	if len(parsed.Ops) != 3 {
+		t.Fatalf("expected 3 ops, got %d", len(parsed.Ops))
+	}
+	mv := parsed.Ops[1]
+	if mv.Op != OpMove || pathSegsStr(mv.From) != "items[3]" || pathSegsStr(mv.Path) != "items[0]" {
+		t.Errorf("move op: got %s %s -> %s", mv.Op, pathSegsStr(mv.From), pathSegsStr(mv.Path))
+	}
+	cp := parsed.Ops[2]
+	if cp.Op != OpCopy || cp.From[1].MapKey != "a b" || cp.Path[1].MapKey != "c]" {
+		t.Errorf("copy op: got %s %v -> %v", cp.Op, cp.From, cp.Path)
+	}
+
+	reEmitted, err := EmitPatch(parsed, nil)
+	if err != nil {
+		t.Fatalf("re-emit error: %v", err)
+	}
+	if reEmitted != emitted {
+		t.Errorf("round-trip mismatch:\n%s\nvs\n%s", emitted, reEmitted)
+	}
+}
+
+func TestPatchMoveCopy_SortKeepsOrder(t *testing.T) {
+	patch := NewPatch(RefID{Prefix: "d", Value: "1"}, "")
+	patch.Set("z", Int(1))
+	patch.Set("a", Int(1))
+	patch.Move("a", "b")
+	patch.Set("y", Int(2))
+	patch.Set("b", Int(2))
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	lines := strings.Split(emitted, "\n")
+	want := []string{"= a 1", "= z 1", "> a b", "= b 2", "= y 2"}
+	for i, w := range want {
+		if lines[i+1] != w {
+			t.Errorf("line %d: got %q, want %q\n%s", i+1, lines[i+1], w, emitted)
+		}
+	}
+}
+
+func TestPatchMoveCopy_Apply(t *testing.T) {
+	base := func() *GValue {
+		return Struct("Doc",
+			FieldVal("items", List(Str("a"), Str("b"), Str("c"), Str("d"))),
+			FieldVal("cfg", Map(MapEntry{Key: "x", Value: Int(1)})),
+			FieldVal("home", Struct("Team", FieldVal("name", Str("ARS")))),
+		)
+	}
+
+	t.Run("list-reorder", func(t *testing.T) {
+		p := NewPatch(RefID{}, "").Move("items[3]", "items[0]")
+		got, err := ApplyPatch(base(), p)
+		if err != nil {
+			t.Fatalf("ApplyPatch: %v", err)
+		}
+		want := List(Str("d"), Str("a"), Str("b"), Str("c"))
+		if !patchEqual(got.Get("items"), want) {
+			t.Errorf("items = %s, want %s", Emit(got.Get("items")), Emit(want))
+		}
+	})
+
+	t.Run("move-field", func(t *testing.T) {
+		p := NewPatch(RefID{}, "").Move("home", "away")
+		got, err := ApplyPatch(base(), p)
+		if err != nil {
+			t.Fatalf("ApplyPatch: %v", err)
+		}
+		if got.Get("home") != nil {
+			t.Error("home should be removed after move")
+		}
+		if name, _ := got.Get("away").Get("name").AsStr(); name != "ARS" {
+			t.Errorf("away.name = %q, want ARS", name)
+		}
+	})
+
+	t.Run("copy-is-independent", func(t *testing.T) {
+		p := NewPatch(RefID{}, "").Copy("home", "away").Set("away.name", Str("LIV"))
+		got, err := ApplyPatch(base(), p)
+		if err != nil {
+			t.Fatalf("ApplyPatch: %v", err)
+		}
+		if name, _ := got.Get("home").Get("name").AsStr(); name != "ARS" {
+			t.Errorf("home.name = %q, want ARS (copy must not alias)", name)
+		}
+		if name, _ := got.Get("away").Get("name").AsStr(); name != "LIV" {
+			t.Errorf("away.name = %q, want LIV", name)
+		}
+	})
+
+	t.Run("copy-into-map-and-list", func(t *testing.T) {
+		p := NewPatch(RefID{}, "").Copy(`cfg["x"]`, `cfg["y"]`).Copy("items[0]", "items[4]")
+		got, err := ApplyPatch(base(), p)
+		if err != nil {
+			t.Fatalf("ApplyPatch: %v", err)
+		}
+		if n, _ := got.Get("cfg").Get("y").AsInt(); n != 1 {
+			t.Errorf(`cfg["y"] = %d, want 1`, n)
+		}
+		items, _ := got.Get("items").AsList()
+		if len(items) != 5 || items[4].strVal != "a" {
+			t.Errorf("items = %s, want trailing copy of a", Emit(got.Get("items")))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := map[string]*Patch{
+			"missing-source": NewPatch(RefID{}, "").Move("nope", "x"),
+			"into-own-child": NewPatch(RefID{}, "").Move("home", "home.inner"),
+			"bad-dest-index": NewPatch(RefID{}, "").Copy("items[0]", "items[9]"),
+		}
+		for name, p := range cases {
+			if _, err := ApplyPatch(base(), p); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestPatchMoveCopy_ParseErrors(t *testing.T) {
+	for _, line := range []string{"> items[0]", "* a b c"} {
+		input := "@patch @target=d:1\n" + line + "\n@end"
+		if _, err := ParsePatch(input, nil); err == nil {
+			t.Errorf("%q: expected parse error", line)
+		}
+	}
+}
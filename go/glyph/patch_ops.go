@@ -0,0 +1,232 @@
+package glyph
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// Custom Patch Operations
+// ============================================================
+//
+// The patch language keeps a small fixed set of operations. Applications
+// that need a domain operation — recomputing a derived field, bumping a
+// version, merging a counter — register it instead of encoding it as a
+// sequence of sets:
+//
+//	glyph.RegisterPatchOp(glyph.PatchOpType{
+//	    Symbol: "!sum",
+//	    Apply: func(doc, cur *glyph.GValue, op *glyph.PatchOp) (*glyph.GValue, error) {
+//	        return sumPrices(doc.Get("items")), nil
+//	    },
+//	})
+//	p.Custom("!sum", "total", nil) // emits: !sum total
+//
+// A custom op line is its symbol, a path, and optional argument text, which
+// the Parse hook turns into the op's Value (by default the text is read as a
+// value, as for =). Apply receives the document as it stands before the op
+// and the current value at the path (nil if absent), and returns the value
+// to set there; returning nil deletes it. Apply must not modify doc or cur.
+//
+// A symbol is one of ! $ % & / ; < ^ | followed by letters, digits and '_',
+// so it can never be mistaken for a built-in op or a comment. Because a
+// custom op may read any part of the document, EmitPatch does not reorder
+// other ops across it.
+
+// PatchOpType describes one custom patch operation.
+type PatchOpType struct {
+	Symbol string // e.g. "!" or "!sum"
+
+	// Parse converts the text after the path to the op's Value (optional).
+	// The default reads the text as a value; empty text gives nil.
+	Parse func(args string) (*GValue, error)
+
+	// Emit converts the op's Value to the text after the path (optional).
+	// The default writes the value as = does, and nothing for nil.
+	Emit func(value *GValue) (string, error)
+
+	// Apply returns the new value at the op's path.
+	Apply func(doc, cur *GValue, op *PatchOp) (*GValue, error)
+}
+
+// PatchOpRegistry maps symbols to custom patch operations. Safe for
+// concurrent use.
+type PatchOpRegistry struct {
+	mu  sync.RWMutex
+	ops map[string]*PatchOpType
+}
+
+// NewPatchOpRegistry creates an empty registry.
+func NewPatchOpRegistry() *PatchOpRegistry {
+	return &PatchOpRegistry{ops: make(map[string]*PatchOpType)}
+}
+
+// DefaultPatchOpRegistry is the registry used by RegisterPatchOp and by
+// EmitPatch, ParsePatch and ApplyPatch.
+var DefaultPatchOpRegistry = NewPatchOpRegistry()
+
+// Register adds a custom op. The symbol must be valid and not already
+// registered; Apply is required.
+func (r *PatchOpRegistry) Register(pt PatchOpType) error {
+	if !isPatchOpSymbol(pt.Symbol) {
+		return fmt.Errorf("glyph: invalid patch op symbol %q", pt.Symbol)
+	}
+	if pt.Apply == nil {
+		return fmt.Errorf("glyph: patch op %q needs Apply", pt.Symbol)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.ops[pt.Symbol]; ok {
+		return fmt.Errorf("glyph: patch op %q already registered", pt.Symbol)
+	}
+	r.ops[pt.Symbol] = &pt
+	return nil
+}
+
+// Lookup returns the custom op registered for symbol.
+func (r *PatchOpRegistry) Lookup(symbol string) (*PatchOpType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pt, ok := r.ops[symbol]
+	return pt, ok
+}
+
+// Ops returns all registered custom ops sorted by symbol.
+func (r *PatchOpRegistry) Ops() []PatchOpType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]PatchOpType, 0, len(r.ops))
+	for _, pt := range r.ops {
+		out = append(out, *pt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// RegisterPatchOp adds a custom op to DefaultPatchOpRegistry.
+func RegisterPatchOp(pt PatchOpType) error {
+	return DefaultPatchOpRegistry.Register(pt)
+}
+
+// Custom adds a custom operation registered under symbol, applied at path
+// with value as its argument (nil for none).
+func (p *Patch) Custom(symbol, path string, value *GValue) *Patch {
+	p.Ops = append(p.Ops, &PatchOp{
+		Op:    OpCustom,
+		Name:  symbol,
+		Path:  parsePathToSegs(path),
+		Value: value,
+		Index: -1,
+	})
+	return p
+}
+
+// patchOpSymbolStart holds the characters a custom op symbol may start with.
+const patchOpSymbolStart = "!$%&/;<^|"
+
+// isPatchOpSymbol reports whether s is a valid custom op symbol.
+func isPatchOpSymbol(s string) bool {
+	if s == "" || !strings.ContainsRune(patchOpSymbolStart, rune(s[0])) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPatchOp returns the registered type of a custom op.
+func lookupPatchOp(symbol string) (*PatchOpType, error) {
+	pt, ok := DefaultPatchOpRegistry.Lookup(symbol)
+	if !ok {
+		return nil, fmt.Errorf("unknown operation: %s", symbol)
+	}
+	return pt, nil
+}
+
+// emitCustomOpArgs writes the argument text of a custom op, preceded by a
+// space, if it has any.
+func emitCustomOpArgs(out *bytes.Buffer, op *PatchOp, packOpts PackedOptions) error {
+	pt, err := lookupPatchOp(op.Name)
+	if err != nil {
+		return err
+	}
+	if pt.Emit == nil {
+		if op.Value == nil {
+			return nil
+		}
+		out.WriteByte(' ')
+		return emitPackedValue(out, op.Value, nil, packOpts)
+	}
+	args, err := pt.Emit(op.Value)
+	if err != nil {
+		return fmt.Errorf("emit %s: %w", op.Name, err)
+	}
+	if args != "" {
+		out.WriteByte(' ')
+		out.WriteString(args)
+	}
+	return nil
+}
+
+// parseCustomOp parses a custom op line: symbol, path, optional arguments.
+func parseCustomOp(line string, schema *Schema) (*PatchOp, error) {
+	symbol, rest, _ := strings.Cut(line, " ")
+	pt, err := lookupPatchOp(symbol)
+	if err != nil {
+		return nil, &ParseError{Message: err.Error()}
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return nil, &ParseError{Message: "missing path in operation"}
+	}
+	pathEnd := findPathEnd(rest)
+	args := strings.TrimSpace(rest[pathEnd:])
+
+	op := &PatchOp{Op: OpCustom, Name: symbol, Path: parsePathToSegs(rest[:pathEnd]), Index: -1}
+	if pt.Parse != nil {
+		op.Value, err = pt.Parse(args)
+		if err != nil {
+			return nil, &ParseError{Message: fmt.Sprintf("%s arguments: %v", symbol, err)}
+		}
+	} else if op.Value, err = parseInlineValue(args, schema); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// applyCustom applies a custom op: the value its Apply hook returns is set
+// at the op's path, or the path is deleted if it returns nil.
+func (a *patchApplier) applyCustom(v *GValue, op *PatchOp) (*GValue, error) {
+	pt, err := lookupPatchOp(op.Name)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := lookupPathSegs(v, op.Path)
+	if err != nil {
+		cur = nil
+	}
+	next, err := pt.Apply(v, cur, op)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case next != nil && len(op.Path) == 0:
+		return next, nil
+	case next != nil:
+		return a.applyOp(v, &PatchOp{Op: OpSet, Path: op.Path, Value: next, Index: -1})
+	case cur == nil:
+		return v, nil
+	case len(op.Path) == 0:
+		return nil, fmt.Errorf("cannot delete root")
+	}
+	return a.applyOp(v, &PatchOp{Op: OpDelete, Path: op.Path})
+}
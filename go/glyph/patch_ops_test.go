@@ -0,0 +1,165 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// patch_ops_test.go covers custom patch ops: registration rules, text
+// round-trip with default and custom hooks, and apply (set, delete, errors).
+
+// registerTestPatchOps registers !sum, which sets a field to the sum of the
+// prices of the list its argument names, and %scale, which multiplies a
+// number by its "xN" argument or deletes it for "x0".
+func registerTestPatchOps(t *testing.T) {
+	t.Helper()
+	sum := PatchOpType{
+		Symbol: "!sum",
+		Apply: func(doc, cur *GValue, op *PatchOp) (*GValue, error) {
+			field, err := op.Value.AsStr()
+			if err != nil {
+				return nil, fmt.Errorf("!sum needs a field name")
+			}
+			total := 0.0
+			for _, item := range doc.Get(field).Items() {
+				n, _ := item.Get("price").Number()
+				total += n
+			}
+			return Float(total), nil
+		},
+	}
+	scale := PatchOpType{
+		Symbol: "%scale",
+		Parse: func(args string) (*GValue, error) {
+			var n int64
+			if _, err := fmt.Sscanf(args, "x%d", &n); err != nil {
+				return nil, err
+			}
+			return Int(n), nil
+		},
+		Emit: func(v *GValue) (string, error) {
+			n, err := v.AsInt()
+			return fmt.Sprintf("x%d", n), err
+		},
+		Apply: func(doc, cur *GValue, op *PatchOp) (*GValue, error) {
+			n, _ := cur.AsInt()
+			if op.Value.intVal == 0 {
+				return nil, nil
+			}
+			return Int(n * op.Value.intVal), nil
+		},
+	}
+	for _, pt := range []PatchOpType{sum, scale} {
+		if err := RegisterPatchOp(pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		DefaultPatchOpRegistry.mu.Lock()
+		delete(DefaultPatchOpRegistry.ops, "!sum")
+		delete(DefaultPatchOpRegistry.ops, "%scale")
+		DefaultPatchOpRegistry.mu.Unlock()
+	})
+}
+
+func customOpBase() *GValue {
+	return Struct("Order",
+		FieldVal("items", List(
+			Map(MapEntry{Key: "price", Value: Float(2.5)}),
+			Map(MapEntry{Key: "price", Value: Int(4)}),
+		)),
+		FieldVal("qty", Int(3)),
+		FieldVal("total", Float(0)),
+	)
+}
+
+func TestPatchOpRegistry_Register(t *testing.T) {
+	r := NewPatchOpRegistry()
+	apply := func(doc, cur *GValue, op *PatchOp) (*GValue, error) { return cur, nil }
+	if err := r.Register(PatchOpType{Symbol: "!", Apply: apply}); err != nil {
+		t.Fatal(err)
+	}
+	for _, pt := range []PatchOpType{
+		{Symbol: "!", Apply: apply},        // Duplicate
+		{Symbol: "=x", Apply: apply},       // Built-in op character
+		{Symbol: "#note", Apply: apply},    // Comment
+		{Symbol: "!a-b", Apply: apply},     // Bad character
+		{Symbol: "", Apply: apply},         // Empty
+		{Symbol: "!recompute", Apply: nil}, // No Apply
+	} {
+		if err := r.Register(pt); err == nil {
+			t.Errorf("registered %q", pt.Symbol)
+		}
+	}
+	if ops := r.Ops(); len(ops) != 1 || ops[0].Symbol != "!" {
+		t.Errorf("Ops = %v", ops)
+	}
+}
+
+func TestPatchCustomOp_RoundTripAndApply(t *testing.T) {
+	registerTestPatchOps(t)
+
+	patch := NewPatch(RefID{Prefix: "o", Value: "1"}, "")
+	patch.Set("qty", Int(4))
+	patch.Custom("%scale", "qty", Int(2))
+	patch.Custom("!sum", "total", Str("items"))
+	patch.Set("items[0].price", Float(1))
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ops are not reordered across a custom op.
+	want := "= qty 4\n%scale qty x2\n!sum total items\n= items[0].price 1.0\n"
+	if !strings.Contains(emitted, want) {
+		t.Fatalf("emitted:\n%s\nwant ops:\n%s", emitted, want)
+	}
+
+	parsed, err := ParsePatch(emitted, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := parsed.Ops[1]; op.Op != OpCustom || op.Name != "%scale" || op.Value.intVal != 2 {
+		t.Fatalf("parsed op = %+v", op)
+	}
+	if again, _ := EmitPatch(parsed, nil); again != emitted {
+		t.Errorf("re-emitted:\n%s", again)
+	}
+
+	got, err := ApplyPatch(customOpBase(), parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLoose(got); got != "{items=[{price=1.0} {price=4}] qty=8 total=6.5}" {
+		t.Errorf("applied = %s", got)
+	}
+
+	// A nil result deletes the field.
+	got, err = ApplyPatch(customOpBase(), NewPatch(RefID{}, "").Custom("%scale", "qty", Int(0)))
+	if err != nil || got.Get("qty") != nil {
+		t.Errorf("delete: %s, %v", CanonicalizeLoose(got), err)
+	}
+}
+
+func TestPatchCustomOp_Errors(t *testing.T) {
+	registerTestPatchOps(t)
+
+	for _, text := range []string{
+		"@patch\n!nope total\n@end",
+		"@patch\n!sum\n@end",
+		"@patch\n%scale qty double\n@end",
+	} {
+		if _, err := ParsePatch(text, nil); err == nil {
+			t.Errorf("parsed %q", text)
+		}
+	}
+
+	if _, err := EmitPatch(NewPatch(RefID{}, "").Custom("!nope", "total", nil), nil); err == nil {
+		t.Error("emitted an unregistered op")
+	}
+	_, err := ApplyPatch(customOpBase(), NewPatch(RefID{}, "").Custom("!sum", "total", Int(1)))
+	if err == nil || !strings.Contains(err.Error(), "patch op !sum total") {
+		t.Errorf("apply err = %v", err)
+	}
+}
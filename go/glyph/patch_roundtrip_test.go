@@ -1,6 +1,7 @@
 package glyph
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -96,6 +97,9 @@ func TestPatchRoundTripProperty(t *testing.T) {
 			"nl\nkey",     // newline
 			"tab\tkey",    // tab
 			`normal/slash`, // no escaping needed but explicit check
+			"a]b",         // close bracket
+			"https://example.com/x?y=[1]",
+			"key with spaces",
 		}
 
 		for _, key := range escapedKeys {
@@ -249,6 +253,13 @@ func TestPatchRoundTripProperty(t *testing.T) {
 		if err != nil {
 			t.Fatalf("EmitPatch: %v", err)
 		}
+		if resolved, err := ParsePatch(emitted, schema); err != nil {
+			t.Fatalf("ParsePatch: %v\n%s", err, emitted)
+		} else if _, err := ApplyPatch(base, resolved); err != nil {
+			t.Errorf("ApplyPatch of a patch with @type: %v", err)
+		}
+		// Without @type the FIDs stay unresolved.
+		emitted = strings.Replace(emitted, " @type=Match", "", 1)
 		parsed, err := ParsePatch(emitted, schema)
 		if err != nil {
 			t.Fatalf("ParsePatch: %v\n%s", err, emitted)
@@ -0,0 +1,178 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// patch_rows_test.go covers the +tab append-rows op: multi-line emission in
+// @tab form, parsing back with name/wire/FID column headers, and application.
+
+func makeRowsSchema() *Schema {
+	return NewSchemaBuilder().
+		AddStruct("Run", "v1",
+			Field("name", PrimitiveType("str")),
+			Field("results", ListType(RefType("Result")), WithOptional()),
+		).
+		AddPackedStruct("Result", "v1",
+			Field("id", PrimitiveType("int"), WithWireKey("i")),
+			Field("score", PrimitiveType("float"), WithWireKey("s")),
+			Field("note", PrimitiveType("str"), WithOptional()),
+		).
+		Build()
+}
+
+func makeResultRow(id int64, score float64) *GValue {
+	return Struct("Result", FieldVal("id", Int(id)), FieldVal("score", Float(score)))
+}
+
+func TestPatchRows_EmitParseRoundTrip(t *testing.T) {
+	schema := makeRowsSchema()
+
+	for _, mode := range []KeyMode{KeyModeWire, KeyModeName, KeyModeFID} {
+		patch := NewPatch(RefID{Prefix: "run", Value: "7"}, schema.Hash)
+		patch.Set("name", Str("nightly"))
+		patch.AppendRows("results", makeResultRow(1, 0.93), makeResultRow(2, 0.88), makeResultRow(3, 0.5))
+
+		emitted, err := EmitPatchWithOptions(patch, PatchOptions{Schema: schema, KeyMode: mode, SortOps: true})
+		if err != nil {
+			t.Fatalf("mode %d: EmitPatch error: %v", mode, err)
+		}
+		if strings.Count(emitted, "@end") != 2 {
+			t.Errorf("mode %d: expected block and patch @end, got:\n%s", mode, emitted)
+		}
+		if mode == KeyModeWire && !strings.Contains(emitted, "+tab results Result [i s note]\n1 0.93 ∅\n") {
+			t.Errorf("unexpected wire output:\n%s", emitted)
+		}
+
+		parsed, err := ParsePatch(emitted, schema)
+		if err != nil {
+			t.Fatalf("mode %d: ParsePatch error: %v\n%s", mode, err, emitted)
+		}
+		if len(parsed.Ops) != 2 {
+			t.Fatalf("mode %d: expected 2 ops, got %d", mode, len(parsed.Ops))
+		}
+		rows := parsed.Ops[1]
+		if rows.Op != OpRows || rows.Value.Len() != 3 {
+			t.Fatalf("mode %d: unexpected rows op: %s len=%d", mode, rows.Op, rows.Value.Len())
+		}
+
+		reEmitted, err := EmitPatchWithOptions(parsed, PatchOptions{Schema: schema, KeyMode: mode, SortOps: true})
+		if err != nil || reEmitted != emitted {
+			t.Errorf("mode %d: round-trip mismatch (err=%v):\n%s\nvs\n%s", mode, err, emitted, reEmitted)
+		}
+	}
+}
+
+func TestPatchRows_Apply(t *testing.T) {
+	schema := makeRowsSchema()
+	base := Struct("Run",
+		FieldVal("name", Str("nightly")),
+		FieldVal("results", List(makeResultRow(1, 0.93))),
+	)
+
+	text := "@patch @target=run:7\n" +
+		"+tab results Result [id score]\n" +
+		"2 0.88\n" +
+		"3 0.5\n" +
+		"@end\n" +
+		"= name done\n" +
+		"@end"
+	patch, err := ParsePatch(text, schema)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+
+	got, err := ApplyPatch(base, patch)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	results, _ := got.Get("results").AsList()
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if id, _ := results[2].Get("id").AsInt(); id != 3 {
+		t.Errorf("results[2].id = %d, want 3", id)
+	}
+	if s, _ := got.Get("name").AsStr(); s != "done" {
+		t.Errorf("name = %q, want done (ops after the block must still parse)", s)
+	}
+
+	// Appending to an absent field creates the list.
+	fresh := Struct("Run", FieldVal("name", Str("x")))
+	got, err = ApplyPatch(fresh, NewPatch(RefID{}, "").AppendRows("results", makeResultRow(9, 1)))
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if got.Get("results").Len() != 1 {
+		t.Errorf("expected new results list with 1 row")
+	}
+}
+
+func TestPatchRows_MapRows(t *testing.T) {
+	row := func(id int64, note string) *GValue {
+		return Map(MapEntry{Key: "id", Value: Int(id)}, MapEntry{Key: "note", Value: Str(note)})
+	}
+	patch := NewPatch(RefID{}, "").AppendRows("events", row(1, "a|b"), row(2, "c"))
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	if !strings.Contains(emitted, "+tab events _ rows=2 cols=2 [id note]\n|1|\"a\\|b\"|\n|2|c|\n@end\n") {
+		t.Errorf("unexpected output:\n%s", emitted)
+	}
+
+	parsed, err := ParsePatch(emitted, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v\n%s", err, emitted)
+	}
+	reEmitted, err := EmitPatch(parsed, nil)
+	if err != nil {
+		t.Fatalf("re-emit error: %v", err)
+	}
+	if reEmitted != emitted {
+		t.Errorf("round trip changed patch:\n%s\nvs\n%s", emitted, reEmitted)
+	}
+
+	doc := Map(MapEntry{Key: "events", Value: List(row(0, "z"))})
+	got, err := ApplyPatch(doc, parsed)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if want := "{events=[{id=0 note=z} {id=1 note=\"a|b\"} {id=2 note=c}]}"; CanonicalizeLooseNoTabular(got) != want {
+		t.Errorf("got %s, want %s", CanonicalizeLooseNoTabular(got), want)
+	}
+}
+
+func TestPatchRows_Errors(t *testing.T) {
+	schema := makeRowsSchema()
+
+	parseCases := map[string]string{
+		"unterminated":   "@patch @target=r:1\n+tab results Result [id score]\n1 0.5",
+		"unknown-type":   "@patch @target=r:1\n+tab results Nope [id]\n1\n@end\n@end",
+		"no-rows":        "@patch @target=r:1\n+tab results Result [id score]\n@end\n@end",
+		"bad-row":        "@patch @target=r:1\n+tab results Result [id score]\n1 0.5 extra\n@end\n@end",
+		"missing-header": "@patch @target=r:1\n+tab results\n1 0.5\n@end\n@end",
+	}
+	for name, input := range parseCases {
+		if _, err := ParsePatch(input, schema); err == nil {
+			t.Errorf("%s: expected parse error", name)
+		}
+	}
+	if _, err := ParsePatch("@patch @target=r:1\n+tab results Result [id score]\n1 0.5\n@end\n@end", nil); err == nil {
+		t.Error("expected error without schema")
+	}
+
+	mixed := NewPatch(RefID{}, "").AppendRows("results", makeResultRow(1, 1), Struct("Run"))
+	if _, err := EmitPatch(mixed, schema); err == nil {
+		t.Error("expected emit error for mixed row types")
+	}
+
+	ragged := NewPatch(RefID{}, "").AppendRows("events",
+		Map(MapEntry{Key: "id", Value: Int(1)}),
+		Map(MapEntry{Key: "id", Value: Int(2)}, MapEntry{Key: "extra", Value: Null()}))
+	if _, err := EmitPatch(ragged, nil); err == nil {
+		t.Error("expected emit error for map rows with different keys")
+	}
+}
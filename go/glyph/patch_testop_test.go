@@ -0,0 +1,131 @@
+package glyph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// patch_testop_test.go covers the test (?) patch op: value and @hash= forms,
+// text round-trip, ordering under SortOps, and the typed PatchTestFailed error.
+
+func testOpBase() *GValue {
+	return Struct("Match",
+		FieldVal("status", Str("live")),
+		FieldVal("home", Struct("Team", FieldVal("name", Str("ARS")), FieldVal("score", Int(1)))),
+		FieldVal("tags", List(Str("epl"), Str("derby"))),
+	)
+}
+
+func TestPatchTestOp_EmitParseRoundTrip(t *testing.T) {
+	hash := FingerprintLoose(Str("ARS"))[:16]
+	patch := NewPatch(RefID{Prefix: "m", Value: "1"}, "")
+	patch.Test("status", Str("live"))
+	patch.TestHash("home.name", hash)
+	patch.Set("status", Str("final"))
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	for _, want := range []string{"? status live", "? home.name @hash=" + hash} {
+		if !strings.Contains(emitted, want) {
+			t.Errorf("expected %q in:\n%s", want, emitted)
+		}
+	}
+
+	parsed, err := ParsePatch(emitted, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	if len(parsed.Ops) != 3 || parsed.Ops[1].Op != OpTest || parsed.Ops[1].Hash != hash {
+		t.Fatalf("unexpected parsed ops: %+v", parsed.Ops)
+	}
+	reEmitted, _ := EmitPatch(parsed, nil)
+	if reEmitted != emitted {
+		t.Errorf("round-trip mismatch:\n%s\nvs\n%s", emitted, reEmitted)
+	}
+
+	result, err := ApplyPatch(testOpBase(), parsed)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if s, _ := result.Get("status").AsStr(); s != "final" {
+		t.Errorf("status = %q, want final", s)
+	}
+}
+
+func TestPatchTestOp_SortKeepsAssertionFirst(t *testing.T) {
+	patch := NewPatch(RefID{Prefix: "m", Value: "1"}, "")
+	patch.Test("status", Str("live"))
+	patch.Set("status", Str("final"))
+	patch.Set("home.score", Int(2))
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	lines := strings.Split(emitted, "\n")
+	if lines[1] != "? status live" {
+		t.Errorf("test op should stay first, got:\n%s", emitted)
+	}
+}
+
+func TestPatchTestOp_Failures(t *testing.T) {
+	cases := map[string]*Patch{
+		"value-mismatch": NewPatch(RefID{}, "").Test("status", Str("pre")),
+		"hash-mismatch":  NewPatch(RefID{}, "").TestHash("home", strings.Repeat("0", 16)),
+		"missing-path":   NewPatch(RefID{}, "").Test("away.name", Str("LIV")),
+		"list-element":   NewPatch(RefID{}, "").Test("tags[1]", Str("epl")),
+	}
+	for name, p := range cases {
+		t.Run(name, func(t *testing.T) {
+			p.Set("status", Str("final"))
+			_, err := ApplyPatch(testOpBase(), p)
+			var tf *PatchTestFailed
+			if !errors.As(err, &tf) {
+				t.Fatalf("expected PatchTestFailed, got %v", err)
+			}
+		})
+	}
+
+	// Whole-subtree assertion passes when the value matches.
+	ok := NewPatch(RefID{}, "").
+		Test("home", Struct("Team", FieldVal("score", Int(1)), FieldVal("name", Str("ARS")))).
+		TestHash("tags", FingerprintLoose(List(Str("epl"), Str("derby")))).
+		Delta("home.score", 1)
+	if _, err := ApplyPatch(testOpBase(), ok); err != nil {
+		t.Errorf("expected matching tests to pass, got %v", err)
+	}
+}
+
+func TestPatchTestOp_ParseErrors(t *testing.T) {
+	for _, line := range []string{"? status", "? status @hash=abc", "? status @hash=zzzzzzzzzzzzzzzz", "? status @hash=" + strings.Repeat("a", 65)} {
+		input := "@patch @target=m:1\n" + line + "\n@end"
+		if _, err := ParsePatch(input, nil); err == nil {
+			t.Errorf("%q: expected parse error", line)
+		}
+	}
+}
+
+func TestPatchTestOp_LongHash(t *testing.T) {
+	// A hash longer than a fingerprint fails cleanly rather than panicking.
+	long := &Patch{Ops: []*PatchOp{{Op: OpTest, Path: parsePathToSegs("status"), Hash: strings.Repeat("0", 80)}}}
+	if _, err := ApplyPatch(testOpBase(), long); err == nil || !strings.Contains(err.Error(), "longer than 64") {
+		t.Errorf("expected a too-long hash error, got %v", err)
+	}
+
+	// TestHash cuts it to fingerprint length.
+	hash := FingerprintLoose(testOpBase().Get("status"))
+	p := NewPatch(RefID{}, "").TestHash("status", hash+"ffff")
+	if _, err := ApplyPatch(testOpBase(), p); err != nil {
+		t.Errorf("TestHash with a long hash: %v", err)
+	}
+
+	// A mismatch reports the whole fingerprint.
+	p = NewPatch(RefID{}, "").TestHash("status", strings.Repeat("0", 64))
+	var tf *PatchTestFailed
+	if _, err := ApplyPatch(testOpBase(), p); !errors.As(err, &tf) || tf.Got != hash {
+		t.Errorf("expected PatchTestFailed with got %s, got %v", hash, err)
+	}
+}
@@ -0,0 +1,115 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+// patch_textedit_test.go covers the ~str text edit op: emission and parsing of
+// range-replace payloads, rune-offset application, and range validation.
+
+func TestPatchTextEdit_EmitParseRoundTrip(t *testing.T) {
+	patch := NewPatch(RefID{Prefix: "doc", Value: "1"}, "")
+	patch.EditText("body", TextEdit{Start: 0, End: 5, Text: "Howdy"}, TextEdit{Start: 12, End: 12, Text: " \"quoted\"\n"})
+	patch.Delta("rev", 1)
+
+	emitted, err := EmitPatch(patch, nil)
+	if err != nil {
+		t.Fatalf("EmitPatch error: %v", err)
+	}
+	want := `~str body 0:5="Howdy" 12:12=" \"quoted\"\n"`
+	if !strings.Contains(emitted, want) {
+		t.Errorf("expected %q in:\n%s", want, emitted)
+	}
+	if !strings.Contains(emitted, "~ rev +1") {
+		t.Errorf("numeric delta should still emit as ~, got:\n%s", emitted)
+	}
+
+	parsed, err := ParsePatch(emitted, nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	var textOp *PatchOp
+	for _, op := range parsed.Ops {
+		if op.Op == OpText {
+			textOp = op
+		}
+	}
+	if textOp == nil || len(textOp.Edits) != 2 || textOp.Edits[1].Text != " \"quoted\"\n" {
+		t.Fatalf("unexpected parsed text op: %+v", textOp)
+	}
+	if reEmitted, _ := EmitPatch(parsed, nil); reEmitted != emitted {
+		t.Errorf("round-trip mismatch:\n%s\nvs\n%s", emitted, reEmitted)
+	}
+}
+
+func TestPatchTextEdit_ParsesStrFieldAsDelta(t *testing.T) {
+	// "~ str" (with a space) is still a numeric delta on a field named str.
+	parsed, err := ParsePatch("@patch @target=d:1\n~ str +2\n@end", nil)
+	if err != nil {
+		t.Fatalf("ParsePatch error: %v", err)
+	}
+	if op := parsed.Ops[0]; op.Op != OpDelta || op.Path[0].Field != "str" {
+		t.Errorf("expected delta on str, got %s %v", op.Op, op.Path)
+	}
+}
+
+func TestPatchTextEdit_Apply(t *testing.T) {
+	base := Struct("Doc",
+		FieldVal("body", Str("Hello, wörld! Goodbye.")),
+		FieldVal("notes", List(Str("abc"))),
+	)
+
+	p := NewPatch(RefID{}, "").
+		EditText("body", TextEdit{Start: 7, End: 12, Text: "world"}, TextEdit{Start: 14, End: 21, Text: "See you"}).
+		EditText("notes[0]", TextEdit{Start: 3, End: 3, Text: "d"})
+	got, err := ApplyPatch(base, p)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if s, _ := got.Get("body").AsStr(); s != "Hello, world! See you." {
+		t.Errorf("body = %q", s)
+	}
+	if s, _ := got.Get("notes").listVal[0].AsStr(); s != "abcd" {
+		t.Errorf("notes[0] = %q", s)
+	}
+	if s, _ := base.Get("body").AsStr(); s != "Hello, wörld! Goodbye." {
+		t.Errorf("base mutated: %q", s)
+	}
+
+	bad := map[string]TextEdit{
+		"past-end":   {Start: 20, End: 99},
+		"reversed":   {Start: 5, End: 2},
+		"negative":   {Start: -1, End: 0},
+		"not-string": {Start: 0, End: 0},
+	}
+	for name, e := range bad {
+		path := "body"
+		if name == "not-string" {
+			path = "notes"
+		}
+		if _, err := ApplyPatch(base, NewPatch(RefID{}, "").EditText(path, e)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	overlap := NewPatch(RefID{}, "").EditText("body", TextEdit{Start: 0, End: 5}, TextEdit{Start: 3, End: 6})
+	if _, err := ApplyPatch(base, overlap); err == nil {
+		t.Error("overlapping edits: expected error")
+	}
+}
+
+func TestPatchTextEdit_ParseErrors(t *testing.T) {
+	for _, line := range []string{
+		"~str body",
+		`~str body 5:2="x"`,
+		`~str body 1-2="x"`,
+		`~str body 1:2=x`,
+		`~str body 1:2="x`,
+	} {
+		input := "@patch @target=d:1\n" + line + "\n@end"
+		if _, err := ParsePatch(input, nil); err == nil {
+			t.Errorf("%q: expected parse error", line)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package pipeline runs GLYPH codec flows over channels. A Pipeline is a
+// list of stages, each a step of encoding or decoding:
+//
+//	decode := pipeline.New(
+//		pipeline.Unframe(),
+//		pipeline.Parse(glyph.ParseOptions{Schema: schema, Tolerant: true}),
+//		pipeline.Validate(schema, "Event").Workers(8),
+//	)
+//	encode := pipeline.New(
+//		pipeline.Transform("redact", redact).Workers(4),
+//		pipeline.Emit(),
+//		pipeline.Frame(1, stream.KindDoc),
+//	)
+//	for item := range encode.Run(ctx, in) { ... }
+//
+// Each stage runs up to its Workers items at once, and items leave every
+// stage, and the pipeline, in the order they came in. An item a stage fails
+// on keeps its error in Err and passes through the later stages untouched,
+// so every input yields exactly one output. Pipelines count, per stage, the
+// items handled, the errors and the time spent (Metrics).
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+// Item is one unit of work. Stages read and fill in its fields: Parse
+// reads Text and sets Value, Emit the reverse, Frame wraps Text in a Frame
+// and Unframe takes it out.
+type Item struct {
+	Index int // Position in the input, set by Run
+	Text  []byte
+	Value *glyph.GValue
+	Frame *stream.Frame
+	Err   error // Set by the stage that failed, which Stage names
+	Stage string
+}
+
+// Stage is one step of a pipeline. Stages are values: Workers returns a
+// changed copy.
+type Stage struct {
+	name    string
+	workers int
+	serial  bool // Runs one item at a time whatever workers says
+	fn      func(ctx context.Context, it *Item) error
+}
+
+// Func returns a stage named name that runs fn on each item.
+func Func(name string, fn func(ctx context.Context, it *Item) error) Stage {
+	return Stage{name: name, workers: 1, fn: fn}
+}
+
+// Name returns the stage name, as used in Metrics and Item.Stage.
+func (s Stage) Name() string {
+	return s.name
+}
+
+// Workers returns s running up to n items at once. Stages that number
+// their output, such as Frame, always run one at a time.
+func (s Stage) Workers(n int) Stage {
+	if n < 1 {
+		n = 1
+	}
+	s.workers = n
+	return s
+}
+
+// Metrics counts the work of one stage.
+type Metrics struct {
+	Stage  string
+	Items  uint64        // Items the stage ran on
+	Errors uint64        // Items it failed
+	Busy   time.Duration // Time spent in the stage, summed over workers
+}
+
+// Pipeline is a sequence of stages. A Pipeline may be run more than once,
+// also concurrently; its metrics add up over all runs.
+type Pipeline struct {
+	stages []Stage
+	stats  []stageStats
+}
+
+type stageStats struct {
+	items  atomic.Uint64
+	errors atomic.Uint64
+	busy   atomic.Int64 // nanoseconds
+}
+
+// New returns a pipeline running stages in order.
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages, stats: make([]stageStats, len(stages))}
+}
+
+// Metrics returns the counts of each stage so far.
+func (p *Pipeline) Metrics() []Metrics {
+	out := make([]Metrics, len(p.stages))
+	for i := range p.stages {
+		out[i] = Metrics{
+			Stage:  p.stages[i].name,
+			Items:  p.stats[i].items.Load(),
+			Errors: p.stats[i].errors.Load(),
+			Busy:   time.Duration(p.stats[i].busy.Load()),
+		}
+	}
+	return out
+}
+
+// Run feeds the items of in through the stages and returns the results,
+// in input order. The output is closed once in is closed and drained, or
+// when ctx is done; the caller must read it to the end or cancel ctx.
+func (p *Pipeline) Run(ctx context.Context, in <-chan *Item) <-chan *Item {
+	numbered := make(chan *Item)
+	go func() {
+		defer close(numbered)
+		for i := 0; ; i++ {
+			it, ok := receive(ctx, in)
+			if !ok {
+				return
+			}
+			it.Index = i
+			select {
+			case numbered <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	out := (<-chan *Item)(numbered)
+	for i := range p.stages {
+		out = p.runStage(ctx, i, out)
+	}
+	return out
+}
+
+// Process runs the pipeline over items and returns them once all are done.
+// The error is ctx's, if it ended the run; item errors are in the items.
+func (p *Pipeline) Process(ctx context.Context, items []*Item) ([]*Item, error) {
+	in := make(chan *Item)
+	go func() {
+		defer close(in)
+		for _, it := range items {
+			select {
+			case in <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var out []*Item
+	for it := range p.Run(ctx, in) {
+		out = append(out, it)
+	}
+	if len(out) < len(items) {
+		return out, ctx.Err()
+	}
+	return out, nil
+}
+
+// receive reads the next item of in, reporting false once in is closed or
+// ctx is done.
+func receive(ctx context.Context, in <-chan *Item) (*Item, bool) {
+	select {
+	case it, ok := <-in:
+		return it, ok
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// runStage starts stage i over in. Up to workers items run at once. Each
+// gets a slot in pending, in arrival order, that the collector waits on in
+// turn, so a slow item holds back the ones after it.
+func (p *Pipeline) runStage(ctx context.Context, i int, in <-chan *Item) <-chan *Item {
+	st, stats := &p.stages[i], &p.stats[i]
+	workers := st.workers
+	if st.serial || workers < 1 {
+		workers = 1
+	}
+	out := make(chan *Item)
+	pending := make(chan chan *Item, workers)
+	sem := make(chan struct{}, workers)
+
+	go func() {
+		defer close(pending)
+		for {
+			it, ok := receive(ctx, in)
+			if !ok {
+				return
+			}
+			done := make(chan *Item, 1)
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case pending <- done:
+			case <-ctx.Done():
+				<-sem
+				return
+			}
+			go func() {
+				defer func() { <-sem }()
+				if it.Err == nil {
+					start := time.Now()
+					err := st.fn(ctx, it)
+					stats.busy.Add(int64(time.Since(start)))
+					stats.items.Add(1)
+					if err != nil {
+						stats.errors.Add(1)
+						it.Err, it.Stage = err, st.name
+					}
+				}
+				done <- it
+			}()
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		for done := range pending {
+			select {
+			case it := <-done:
+				select {
+				case out <- it:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ============================================================
+// Codec Stages
+// ============================================================
+
+// Parse parses Text into Value. In tolerant mode an item fails only on
+// errors, not warnings.
+func Parse(opts glyph.ParseOptions) Stage {
+	return Func("parse", func(_ context.Context, it *Item) error {
+		result, err := glyph.ParseWithOptions(string(it.Text), opts)
+		if err != nil {
+			return err
+		}
+		if result.HasErrors() {
+			return &result.Errors[0]
+		}
+		it.Value = result.Value
+		return nil
+	})
+}
+
+// Validate checks Value against schema, as typeName or, if that is empty,
+// as its own type. An item that does not validate fails with a
+// *ValidationError.
+func Validate(schema *glyph.Schema, typeName string) Stage {
+	return Func("validate", func(_ context.Context, it *Item) error {
+		var check *glyph.ValidationResult
+		if typeName != "" {
+			check = glyph.ValidateAs(it.Value, schema, typeName)
+		} else {
+			check = glyph.ValidateWithSchema(it.Value, schema)
+		}
+		if !check.Valid {
+			return &ValidationError{Errors: check.Errors}
+		}
+		return nil
+	})
+}
+
+// ValidationError is the error of an item that failed Validate.
+type ValidationError struct {
+	Errors []glyph.ValidationError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more)", e.Errors[0].Error(), len(e.Errors)-1)
+}
+
+// Transform replaces Value with fn's result.
+func Transform(name string, fn func(ctx context.Context, v *glyph.GValue) (*glyph.GValue, error)) Stage {
+	return Func(name, func(ctx context.Context, it *Item) error {
+		v, err := fn(ctx, it.Value)
+		if err != nil {
+			return err
+		}
+		it.Value = v
+		return nil
+	})
+}
+
+// Emit writes Value as GLYPH-T into Text.
+func Emit() Stage {
+	return Func("emit", func(_ context.Context, it *Item) error {
+		it.Text = []byte(glyph.Emit(it.Value))
+		return nil
+	})
+}
+
+// Frame wraps Text in a frame of the given kind on sid. It runs one item
+// at a time and numbers the frames from seq 1, skipping failed items; the
+// numbering carries on across runs of the pipeline.
+func Frame(sid uint64, kind stream.FrameKind) Stage {
+	var seq atomic.Uint64
+	s := Func("frame", func(_ context.Context, it *Item) error {
+		it.Frame = &stream.Frame{Version: stream.Version, SID: sid, Seq: seq.Add(1), Kind: kind, Payload: it.Text}
+		return nil
+	})
+	s.serial = true
+	return s
+}
+
+// Unframe takes Text from the payload of Frame.
+func Unframe() Stage {
+	return Func("unframe", func(_ context.Context, it *Item) error {
+		if it.Frame == nil {
+			return fmt.Errorf("pipeline: item has no frame")
+		}
+		it.Text = it.Frame.Payload
+		return nil
+	})
+}
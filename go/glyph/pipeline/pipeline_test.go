@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+	"github.com/Neumenon/glyph/stream"
+)
+
+func testSchema(t *testing.T) *glyph.Schema {
+	t.Helper()
+	schema, err := glyph.ParseSchema(`@schema{ Event struct{ n: int [min=0] } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestPipeline_RoundTrip(t *testing.T) {
+	schema := testSchema(t)
+	double := func(_ context.Context, v *glyph.GValue) (*glyph.GValue, error) {
+		n, _ := v.Get("n").AsInt()
+		return glyph.Struct("Event", glyph.MapEntry{Key: "n", Value: glyph.Int(2 * n)}), nil
+	}
+	encode := New(Transform("double", double).Workers(4), Emit().Workers(4), Frame(7, stream.KindRow).Workers(4))
+	decode := New(Unframe(), Parse(glyph.ParseOptions{Schema: schema}).Workers(4), Validate(schema, "Event").Workers(4))
+
+	var items []*Item
+	for i := 0; i < 50; i++ {
+		items = append(items, &Item{Value: glyph.Struct("Event", glyph.MapEntry{Key: "n", Value: glyph.Int(int64(i))})})
+	}
+	encoded, err := encode.Process(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, it := range encoded {
+		if it.Err != nil || it.Frame.SID != 7 || it.Frame.Seq != uint64(i+1) {
+			t.Fatalf("item %d: %v %+v", i, it.Err, it.Frame)
+		}
+	}
+
+	frames := make([]*Item, len(encoded))
+	for i, it := range encoded {
+		frames[i] = &Item{Frame: it.Frame}
+	}
+	decoded, err := decode.Process(context.Background(), frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, it := range decoded {
+		if n, _ := it.Value.Get("n").AsInt(); it.Err != nil || it.Index != i || n != int64(2*i) {
+			t.Fatalf("item %d: index %d, n %d, %v", i, it.Index, n, it.Err)
+		}
+	}
+	for _, m := range decode.Metrics() {
+		if m.Items != 50 || m.Errors != 0 {
+			t.Errorf("%s: %+v", m.Stage, m)
+		}
+	}
+}
+
+// TestPipeline_Order runs items that finish in reverse order through a
+// parallel stage.
+func TestPipeline_Order(t *testing.T) {
+	var running, peak atomic.Int32
+	slow := Func("slow", func(_ context.Context, it *Item) error {
+		if n := running.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		defer running.Add(-1)
+		time.Sleep(time.Duration(20-it.Index) * time.Millisecond)
+		return nil
+	}).Workers(5)
+
+	items := make([]*Item, 20)
+	for i := range items {
+		items[i] = &Item{}
+	}
+	out, err := New(slow).Process(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, it := range out {
+		if it.Index != i {
+			t.Fatalf("position %d holds item %d", i, it.Index)
+		}
+	}
+	if p := peak.Load(); p > 5 || p < 2 {
+		t.Errorf("peak concurrency %d, want 2..5", p)
+	}
+}
+
+func TestPipeline_Errors(t *testing.T) {
+	schema := testSchema(t)
+	p := New(Parse(glyph.ParseOptions{Schema: schema}), Validate(schema, "Event"), Emit())
+	items := []*Item{
+		{Text: []byte("Event{n=1}")},
+		{Text: []byte(`Event{n="x`)},
+		{Text: []byte("Event{n=-1}")},
+	}
+	out, err := p.Process(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].Err != nil || string(out[0].Text) != "Event{n=1}" {
+		t.Errorf("item 0: %v %s", out[0].Err, out[0].Text)
+	}
+	if out[1].Err == nil || out[1].Stage != "parse" {
+		t.Errorf("item 1: %v in %q", out[1].Err, out[1].Stage)
+	}
+	var verr *ValidationError
+	if !errors.As(out[2].Err, &verr) || out[2].Stage != "validate" || verr.Errors[0].Code != "constraint_min" {
+		t.Errorf("item 2: %v in %q", out[2].Err, out[2].Stage)
+	}
+
+	if m := p.Metrics(); m[0].Errors != 1 || m[1].Items != 2 || m[1].Errors != 1 || m[2].Items != 1 {
+		t.Errorf("metrics: %+v", m)
+	}
+}
+
+func TestPipeline_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	block := Func("block", func(ctx context.Context, it *Item) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	in := make(chan *Item) // never closed
+	out := New(block).Run(ctx, in)
+	in <- &Item{}
+	cancel()
+	for range out {
+	}
+}
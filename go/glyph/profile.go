@@ -0,0 +1,90 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ============================================================
+// Size Profiling
+// ============================================================
+//
+// Profile answers "what is taking up my context?" It measures every
+// subtree of a value as loose canonical output, so the parts worth a
+// schema, compact keys, or a summarizer stand out.
+
+// ProfileNode is the size of one subtree.
+type ProfileNode struct {
+	Path     string         // Validator-style path ("" for the root)
+	Bytes    int            // Length of the subtree's loose canonical form
+	Tokens   int            // Tokenizer cost of that form
+	Children []*ProfileNode // Map/struct fields, list items, sum payload
+}
+
+// Profile measures v and each of its subtrees, emitted on their own with
+// opts. tok counts tokens; nil means EstimateTokens. A subtree's size is
+// what it costs when emitted alone, so children do not add up exactly to
+// their parent: the parent also pays for keys, separators, and any @tab
+// header. Every subtree is emitted once, so cost grows with size times
+// depth.
+func Profile(v *GValue, opts LooseCanonOpts, tok Tokenizer) *ProfileNode {
+	if tok == nil {
+		tok = EstimateTokens
+	}
+	if opts.MinRows == 0 {
+		opts.MinRows = 3
+	}
+	if opts.MaxCols == 0 {
+		opts.MaxCols = 20
+	}
+	return profileNode(v, "", opts, tok)
+}
+
+func profileNode(v *GValue, path string, opts LooseCanonOpts, tok Tokenizer) *ProfileNode {
+	v.force()
+	out := canonLooseWithOpts(v, opts)
+	n := &ProfileNode{Path: path, Bytes: len(out), Tokens: tok(out)}
+	if v == nil {
+		return n
+	}
+	switch v.typ {
+	case TypeList:
+		for i, item := range v.listVal {
+			n.Children = append(n.Children, profileNode(item, fmt.Sprintf("%s[%d]", path, i), opts, tok))
+		}
+	case TypeMap:
+		for _, e := range v.mapVal {
+			n.Children = append(n.Children, profileNode(e.Value, joinPath(path, e.Key), opts, tok))
+		}
+	case TypeStruct:
+		if v.structVal != nil {
+			for _, f := range v.structVal.Fields {
+				n.Children = append(n.Children, profileNode(f.Value, joinPath(path, f.Key), opts, tok))
+			}
+		}
+	case TypeSum:
+		if v.sumVal != nil && v.sumVal.Value != nil {
+			n.Children = append(n.Children, profileNode(v.sumVal.Value, path, opts, tok))
+		}
+	}
+	return n
+}
+
+// Heaviest returns up to limit descendants of n (n itself excluded) with
+// the most tokens, largest first. Ties keep depth-first order.
+func (n *ProfileNode) Heaviest(limit int) []*ProfileNode {
+	var all []*ProfileNode
+	var walk func(*ProfileNode)
+	walk = func(p *ProfileNode) {
+		for _, c := range p.Children {
+			all = append(all, c)
+			walk(c)
+		}
+	}
+	walk(n)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Tokens > all[j].Tokens })
+	if limit >= 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	return all
+}
@@ -0,0 +1,35 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	v := Map(
+		MapEntry{Key: "goal", Value: Str("ship it")},
+		MapEntry{Key: "notes", Value: List(Str(strings.Repeat("x", 200)), Str("short"))},
+	)
+
+	root := Profile(v, DefaultLooseCanonOpts(), nil)
+	if root.Path != "" || root.Bytes != len(CanonicalizeLoose(v)) {
+		t.Errorf("root: path=%q bytes=%d", root.Path, root.Bytes)
+	}
+	if len(root.Children) != 2 || root.Children[1].Path != "notes" {
+		t.Fatalf("unexpected children: %+v", root.Children)
+	}
+	if got := root.Children[1].Children[0].Path; got != "notes[0]" {
+		t.Errorf("list item path = %q", got)
+	}
+
+	top := root.Heaviest(2)
+	if len(top) != 2 || top[0].Path != "notes" || top[1].Path != "notes[0]" {
+		t.Errorf("heaviest: %s, %s", top[0].Path, top[1].Path)
+	}
+
+	calls := 0
+	Profile(v, DefaultLooseCanonOpts(), func(s string) int { calls++; return 1 })
+	if calls != 5 {
+		t.Errorf("tokenizer called %d times, want once per subtree (5)", calls)
+	}
+}
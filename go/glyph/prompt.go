@@ -0,0 +1,279 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// Prompt Sections
+// ============================================================
+//
+// A model asked for GLYPH needs the format, the types, a few examples and a
+// warning about the mistakes models make. Written by hand, that text drifts
+// from what the parser accepts. PromptSection writes it from the schema and
+// the emitters instead: every sample in the format rules is written by Emit
+// or CanonicalizeLoose, and the rules cover only the value kinds the type
+// reaches. The schema block is PromptBlock of the types reachable from the
+// root. Each example is validated and written in typed canonical form
+// (CanonicalizeTyped), and the warnings list the root type's required fields
+// and constraints.
+
+// PromptOptions configures PromptSection.
+type PromptOptions struct {
+	Type     string    // Type the model must produce (required)
+	Examples []*GValue // Instances of Type, shown in canonical form; each must validate
+}
+
+// PromptSection returns a system-prompt section, in Markdown, telling a
+// model how to write a value of opts.Type. Examples that do not validate
+// are an error.
+func (s *Schema) PromptSection(opts PromptOptions) (string, error) {
+	root := s.GetType(opts.Type)
+	if root == nil {
+		return "", fmt.Errorf("glyph: prompt: unknown type: %s", opts.Type)
+	}
+	sub := s.reachable(opts.Type)
+
+	examples := make([]string, len(opts.Examples))
+	for i, ex := range opts.Examples {
+		check := ValidateAs(ex, s, opts.Type)
+		if !check.Valid {
+			return "", fmt.Errorf("glyph: prompt: example %d: %v", i+1, check.Errors[0])
+		}
+		text, err := CanonicalizeTyped(ex, s, opts.Type)
+		if err != nil {
+			return "", fmt.Errorf("glyph: prompt: example %d: %w", i+1, err)
+		}
+		examples[i] = text
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Output format: GLYPH\n\n")
+	fmt.Fprintf(&b, "Reply with a single %s value written in GLYPH, not JSON. ", opts.Type)
+	b.WriteString("GLYPH is JSON without the punctuation:\n\n")
+	for _, rule := range promptRules(sub) {
+		fmt.Fprintf(&b, "- %s\n", rule)
+	}
+
+	fmt.Fprintf(&b, "\n### Schema\n\n```\n%s\n```\n", sub.PromptBlock())
+
+	if len(examples) > 0 {
+		b.WriteString("\n### Examples\n\n```\n")
+		for _, ex := range examples {
+			b.WriteString(ex)
+			b.WriteByte('\n')
+		}
+		b.WriteString("```\n")
+	}
+
+	b.WriteString("\n### Common mistakes\n\n")
+	for _, warning := range promptWarnings(s, root) {
+		fmt.Fprintf(&b, "- %s\n", warning)
+	}
+	return b.String(), nil
+}
+
+// reachable returns the schema of the types name refers to, directly or
+// through other types, including name itself.
+func (s *Schema) reachable(name string) *Schema {
+	sub := &Schema{Types: make(map[string]*TypeDef)}
+	var addSpec func(ts *TypeSpec)
+	var addType func(name string)
+	addType = func(name string) {
+		td := s.GetType(name)
+		if td == nil || sub.Types[name] != nil {
+			return
+		}
+		sub.Types[name] = td
+		if td.Struct != nil {
+			for _, f := range td.Struct.Fields {
+				addSpec(&f.Type)
+			}
+		}
+		if td.Sum != nil {
+			for _, v := range td.Sum.Variants {
+				addSpec(&v.Type)
+			}
+		}
+	}
+	addSpec = func(ts *TypeSpec) {
+		switch ts.Kind {
+		case TypeSpecRef:
+			addType(ts.Name)
+		case TypeSpecList:
+			addSpec(ts.Elem)
+		case TypeSpecMap:
+			addSpec(ts.KeyType)
+			addSpec(ts.ValType)
+		case TypeSpecInlineStruct:
+			for _, f := range ts.Struct.Fields {
+				addSpec(&f.Type)
+			}
+		}
+	}
+	addType(name)
+	return sub
+}
+
+// promptRules returns the format rules for the value kinds sub uses, with
+// samples written the way the examples are.
+func promptRules(sub *Schema) []string {
+	kinds := make(map[TypeSpecKind]bool)
+	sums := false
+	for _, td := range sub.Types {
+		if td.Kind == TypeDefSum {
+			sums = true
+		}
+		if td.Struct == nil {
+			continue
+		}
+		for _, f := range td.Struct.Fields {
+			specKinds(&f.Type, kinds)
+		}
+	}
+
+	sample := Struct("Point", MapEntry{Key: "x", Value: Int(1)}, MapEntry{Key: "y", Value: Int(2)})
+	rules := []string{
+		fmt.Sprintf("A struct is its type name and its fields: `%s`. Fields are `name=value`, separated by spaces, in any order.", Emit(sample)),
+		fmt.Sprintf("A string that is one plain word needs no quotes (`%s`); quote anything else (`%s`).",
+			Emit(Str("running")), Emit(Str("two words"))),
+		fmt.Sprintf("Booleans are `%s` and `%s`; null is `%s`.", Emit(Bool(true)), Emit(Bool(false)), Emit(Null())),
+	}
+	if kinds[TypeSpecList] {
+		rules = append(rules, fmt.Sprintf("A list is its items in brackets, separated by spaces: `%s`.", Emit(List(Int(1), Int(2), Str("three")))))
+	}
+	if kinds[TypeSpecMap] {
+		rules = append(rules, fmt.Sprintf("A map is its entries in braces: `%s`.", CanonicalizeLoose(Map(MapEntry{Key: "a", Value: Int(1)}, MapEntry{Key: "b", Value: Int(2)}))))
+	}
+	if kinds[TypeSpecTime] {
+		rules = append(rules, fmt.Sprintf("Times are RFC 3339, unquoted: `%s`.", Emit(Time(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))))
+	}
+	if kinds[TypeSpecID] {
+		rules = append(rules, fmt.Sprintf("Ids are references: `%s`.", Emit(ID("user", "42"))))
+	}
+	if kinds[TypeSpecBytes] {
+		rules = append(rules, fmt.Sprintf("Bytes are base64 in a b64 string: `%s`.", Emit(Bytes([]byte("hi")))))
+	}
+	if sums {
+		rules = append(rules, fmt.Sprintf("A value of a union type is its variant tag and value: `%s`.", Emit(Sum("Ok", Int(1)))))
+	}
+	return rules
+}
+
+// specKinds records the kinds ts is made of.
+func specKinds(ts *TypeSpec, kinds map[TypeSpecKind]bool) {
+	if ts == nil {
+		return
+	}
+	kinds[ts.Kind] = true
+	switch ts.Kind {
+	case TypeSpecList:
+		specKinds(ts.Elem, kinds)
+	case TypeSpecMap:
+		specKinds(ts.KeyType, kinds)
+		specKinds(ts.ValType, kinds)
+	case TypeSpecInlineStruct:
+		for _, f := range ts.Struct.Fields {
+			specKinds(&f.Type, kinds)
+		}
+	}
+}
+
+// promptWarnings returns the mistakes to warn about for root: the general
+// ones, then those its fields invite.
+func promptWarnings(s *Schema, root *TypeDef) []string {
+	warnings := []string{
+		"Do not answer in JSON, and do not wrap the value in a code block or add text around it.",
+		fmt.Sprintf("Quote strings that would otherwise read as numbers, booleans or null: `%s`, `%s`, `%s`.",
+			Emit(Str("42")), Emit(Str("t")), Emit(Str("_"))),
+	}
+	if root.Struct == nil {
+		return warnings
+	}
+
+	var required []string
+	for _, f := range root.Struct.Fields {
+		if !f.Optional && f.Default == nil && f.Deprecated == nil {
+			required = append(required, "`"+f.Name+"`")
+		}
+	}
+	if len(required) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Always include %s.", joinWords(required, "and")))
+	}
+	if !root.Open {
+		warnings = append(warnings, fmt.Sprintf("Use only the fields the schema declares for %s; spell them exactly.", root.Name))
+	}
+	for _, f := range root.Struct.Fields {
+		if f.Deprecated != nil {
+			warnings = append(warnings, fmt.Sprintf("Do not set `%s`: it is deprecated.", f.Name))
+			continue
+		}
+		if rule := constraintWarning(f); rule != "" {
+			warnings = append(warnings, rule)
+		}
+	}
+	return warnings
+}
+
+// constraintWarning describes the constraints on f in words, or returns ""
+// if it has none worth stating.
+func constraintWarning(f *FieldDef) string {
+	var parts []string
+	for _, c := range f.Constraints {
+		switch c.Kind {
+		case ConstraintEnum:
+			values, _ := c.Value.([]string)
+			quoted := make([]string, len(values))
+			for i, v := range values {
+				quoted[i] = "`" + Emit(Str(v)) + "`"
+			}
+			parts = append(parts, "one of "+joinWords(quoted, "or"))
+		case ConstraintMin:
+			parts = append(parts, fmt.Sprintf("at least %v", c.Value))
+		case ConstraintMax:
+			parts = append(parts, fmt.Sprintf("at most %v", c.Value))
+		case ConstraintRange:
+			r := c.Value.([2]float64)
+			parts = append(parts, fmt.Sprintf("between %v and %v", r[0], r[1]))
+		case ConstraintNonEmpty:
+			parts = append(parts, "not empty")
+		case ConstraintMinLen:
+			parts = append(parts, fmt.Sprintf("at least %v long", c.Value))
+		case ConstraintMaxLen:
+			parts = append(parts, fmt.Sprintf("at most %v long", c.Value))
+		case ConstraintLen:
+			parts = append(parts, fmt.Sprintf("exactly %v long", c.Value))
+		case ConstraintRegex:
+			parts = append(parts, fmt.Sprintf("matching `%v`", c.Value))
+		case ConstraintUnique:
+			parts = append(parts, "free of duplicates")
+		case ConstraintPrefix:
+			prefixes, _ := c.Value.([]string)
+			refs := make([]string, len(prefixes))
+			for i, p := range prefixes {
+				refs[i] = "`^" + p + ":`"
+			}
+			parts = append(parts, "a reference starting with "+joinWords(refs, "or"))
+		case ConstraintIDFormat:
+			parts = append(parts, "a "+strings.ToUpper(c.Value.(string)))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("`%s` must be %s.", f.Name, joinWords(parts, "and"))
+}
+
+// joinWords joins items as "a", "a and b" or "a, b and c", with conj in
+// place of "and".
+func joinWords(items []string, conj string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conj + " " + items[len(items)-1]
+}
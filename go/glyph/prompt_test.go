@@ -0,0 +1,69 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptSection(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		/// A call the model asks the host to make.
+		ToolCall struct{
+			id: id [prefix=[call]]
+			name: str [enum=["search","fetch"]]
+			args: map<str,str> [optional]
+			limit: int [1..50] [optional]
+			legacy: str [optional] @deprecated(since="v2")
+		}
+		Unrelated struct{ x: bytes }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	example, err := ParseWithSchema(`ToolCall{id=^call:1 name=search args={q:"glyph spec"}}`, schema)
+	if err != nil || example.HasErrors() {
+		t.Fatal(err, example.Errors)
+	}
+
+	out, err := schema.PromptSection(PromptOptions{Type: "ToolCall", Examples: []*GValue{example.Value}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Reply with a single ToolCall value",
+		"`Point{x=1 y=2}`",
+		"Ids are references: `^user:42`",
+		"A map is its entries",
+		"/// A call the model asks the host to make.",
+		"`\"42\"`, `\"t\"`",
+		"`id` must be a reference starting with `^call:`.",
+		"Always include `id` and `name`.",
+		"`name` must be one of `search` or `fetch`.",
+		"`limit` must be between 1 and 50.",
+		"Do not set `legacy`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"Unrelated", "b64", "Times are"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("unexpected %q in:\n%s", unwanted, out)
+		}
+	}
+
+	// The example is shown in a form that parses back to the same value.
+	shown := strings.Split(out[strings.Index(out, "### Examples\n\n```\n")+len("### Examples\n\n```\n"):], "\n")[0]
+	back, err := ParseWithSchema(shown, schema)
+	if err != nil || back.HasErrors() || !EqualLoose(back.Value, example.Value) {
+		t.Errorf("example %q does not round-trip: %v %v", shown, err, back.Errors)
+	}
+
+	bad := Struct("ToolCall", MapEntry{Key: "id", Value: ID("call", "2")}, MapEntry{Key: "name", Value: Str("delete")})
+	if _, err := schema.PromptSection(PromptOptions{Type: "ToolCall", Examples: []*GValue{bad}}); err == nil {
+		t.Error("invalid example accepted")
+	}
+	if _, err := schema.PromptSection(PromptOptions{Type: "Nope"}); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
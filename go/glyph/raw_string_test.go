@@ -0,0 +1,109 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexer_RawStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"""a "quoted" \n word"""`, `a "quoted" \n word`},
+		{"'''it's \"\"\" here'''", `it's """ here`},
+		{"\"\"\"\nline1\n  line2\n\"\"\"", "line1\n  line2\n"},
+		{"\"\"\"\r\nwin\"\"\"", "win"},
+		{`""""leading quote"""`, `"leading quote`},
+		{`""""""`, ``},
+	}
+	for _, tt := range tests {
+		result, err := ParseWithOptions(tt.input, ParseOptions{})
+		if err != nil || result.HasErrors() {
+			t.Errorf("%q: parse error %v %v", tt.input, err, result)
+			continue
+		}
+		if got, _ := result.Value.AsStr(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := NewLexer(`"""never closed`).Tokenize(); err == nil {
+		t.Error("expected error for unterminated raw string")
+	}
+	if _, err := NewLexer(`'single'`).Tokenize(); err == nil {
+		t.Error("single-quoted strings are not part of the grammar")
+	}
+}
+
+func TestEmit_RawStrings(t *testing.T) {
+	opts := DefaultEmitOptions()
+	opts.RawStrings = true
+
+	code := "func main() {\n\tfmt.Println(\"hi\\n\")\n}\n"
+	v := Map(
+		MapEntry{Key: "code", Value: Str(code)},
+		MapEntry{Key: "sql", Value: Str(`SELECT "name" FROM t`)},
+		MapEntry{Key: "tail", Value: Str(`ends with "`)},
+		MapEntry{Key: "both", Value: Str(`""" and ''' and "`)},
+		MapEntry{Key: "ctrl", Value: Str("bell\a\n")},
+		MapEntry{Key: "plain", Value: Str("hello world")},
+	)
+
+	out := EmitWithOptions(v, opts)
+	for _, want := range []string{
+		"code:\"\"\"\nfunc main() {\n\tfmt.Println(\"hi\\n\")\n}\n\"\"\"",
+		`sql:"""SELECT "name" FROM t"""`,
+		`tail:'''ends with "'''`,
+		`both:"\"\"\" and ''' and \""`,
+		`ctrl:"bell\u0007\n"`,
+		`plain:"hello world"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+
+	result, err := ParseWithOptions(out, ParseOptions{})
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse error: %v", err)
+	}
+	if !EqualLoose(result.Value, v) {
+		t.Errorf("round-trip mismatch:\n%s", Emit(result.Value))
+	}
+
+	if strings.Contains(Emit(v), `"""`) {
+		t.Error("raw strings must be opt-in")
+	}
+}
+
+func TestParseLoose_RawStrings(t *testing.T) {
+	for _, input := range []string{
+		`{s="""code"""}`,
+		`["""x y""" 1]`,
+		`{"""k"""=1}`,
+		`"""z"""`,
+		`{s="a"b"}`,
+	} {
+		if v, err := ParseLoose(input, nil); err == nil {
+			t.Errorf("%s: expected error, got %s", input, CanonicalizeLoose(v))
+		}
+	}
+
+	// Content with triple quotes round-trips through the quoted form.
+	v := Map(
+		MapEntry{Key: "code", Value: Str(`"""code"""`)},
+		MapEntry{Key: "sql", Value: Str("SELECT \"name\"\nFROM t")},
+	)
+	text := CanonicalizeLoose(v)
+	if strings.Contains(text, `"""`) {
+		t.Errorf("canonical output uses a raw string: %s", text)
+	}
+	got, err := ParseLoose(text, nil)
+	if err != nil {
+		t.Fatalf("ParseLoose(%s): %v", text, err)
+	}
+	if !EqualLoose(got, v) {
+		t.Errorf("round trip: got %s, want %s", CanonicalizeLoose(got), text)
+	}
+}
@@ -0,0 +1,188 @@
+package glyph
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// Reference Namespaces
+// ============================================================
+//
+// A reference id ^t:ARS carries a prefix ("t") naming what kind of thing it
+// refers to. A RefNamespace records what a prefix means: a description, an
+// optional pattern the value must match, and optionally the Go type that ids
+// in the namespace identify. Registering namespaces lets callers construct ids
+// that are known to be well-formed:
+//
+//	glyph.RegisterRefNamespace(glyph.RefNamespace{
+//	    Prefix:      "t",
+//	    Description: "team",
+//	    Pattern:     regexp.MustCompile(`^[A-Z]{2,4}$`),
+//	})
+//	home := glyph.MustID("t", "ARS")
+//
+// Schema id fields can restrict the prefixes they accept with a prefix
+// constraint, e.g. `home: id [prefix=[t]]`. The validator checks values of
+// registered prefixes against the namespace pattern.
+
+// RefNamespace describes one reference id prefix.
+type RefNamespace struct {
+	Prefix      string         // e.g. "t"
+	Description string         // e.g. "team"
+	Pattern     *regexp.Regexp // values must match; nil accepts any value
+	GoType      reflect.Type   // Go type identified by ids in this namespace (optional)
+}
+
+// RefRegistry maps reference prefixes to namespaces. Safe for concurrent use.
+type RefRegistry struct {
+	mu         sync.RWMutex
+	namespaces map[string]*RefNamespace
+	byType     map[reflect.Type]string
+}
+
+// NewRefRegistry creates an empty registry.
+func NewRefRegistry() *RefRegistry {
+	return &RefRegistry{
+		namespaces: make(map[string]*RefNamespace),
+		byType:     make(map[reflect.Type]string),
+	}
+}
+
+// DefaultRefRegistry is the registry used by RegisterRefNamespace, NewID,
+// MustID and schema validation.
+var DefaultRefRegistry = NewRefRegistry()
+
+// Register adds a namespace. The prefix must be non-empty, contain only
+// characters valid in a bare ref (no ':'), and not already be registered; a
+// Go type may belong to only one namespace.
+func (r *RefRegistry) Register(ns RefNamespace) error {
+	if ns.Prefix == "" {
+		return fmt.Errorf("glyph: empty ref namespace prefix")
+	}
+	for i := 0; i < len(ns.Prefix); i++ {
+		if ns.Prefix[i] == ':' || !isRefChar(ns.Prefix[i]) {
+			return fmt.Errorf("glyph: invalid ref namespace prefix %q", ns.Prefix)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.namespaces[ns.Prefix]; ok {
+		return fmt.Errorf("glyph: ref namespace %q already registered", ns.Prefix)
+	}
+	if ns.GoType != nil {
+		if other, ok := r.byType[ns.GoType]; ok {
+			return fmt.Errorf("glyph: type %s already mapped to ref namespace %q", ns.GoType, other)
+		}
+		r.byType[ns.GoType] = ns.Prefix
+	}
+	r.namespaces[ns.Prefix] = &ns
+	return nil
+}
+
+// Lookup returns the namespace registered for prefix.
+func (r *RefRegistry) Lookup(prefix string) (*RefNamespace, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ns, ok := r.namespaces[prefix]
+	return ns, ok
+}
+
+// PrefixFor returns the prefix of the namespace mapped to Go type t.
+func (r *RefRegistry) PrefixFor(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prefix, ok := r.byType[t]
+	return prefix, ok
+}
+
+// Namespaces returns all registered namespaces sorted by prefix.
+func (r *RefRegistry) Namespaces() []RefNamespace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]RefNamespace, 0, len(r.namespaces))
+	for _, ns := range r.namespaces {
+		out = append(out, *ns)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix < out[j].Prefix })
+	return out
+}
+
+// Validate checks ref against its namespace. The prefix must be registered
+// and the value non-empty and matching the namespace pattern.
+func (r *RefRegistry) Validate(ref RefID) error {
+	ns, ok := r.Lookup(ref.Prefix)
+	if !ok {
+		return fmt.Errorf("glyph: unknown ref namespace %q", ref.Prefix)
+	}
+	return ns.check(ref.Value)
+}
+
+// NewID constructs a validated reference id.
+func (r *RefRegistry) NewID(prefix, value string) (*GValue, error) {
+	ref := RefID{Prefix: prefix, Value: value}
+	if err := r.Validate(ref); err != nil {
+		return nil, err
+	}
+	return IDFromRef(ref), nil
+}
+
+// MustID is like NewID but panics if the id is invalid.
+func (r *RefRegistry) MustID(prefix, value string) *GValue {
+	v, err := r.NewID(prefix, value)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// check validates a value against the namespace.
+func (ns *RefNamespace) check(value string) error {
+	if value == "" {
+		return fmt.Errorf("glyph: empty value for ref namespace %q", ns.Prefix)
+	}
+	if ns.Pattern != nil && !ns.Pattern.MatchString(value) {
+		return fmt.Errorf("glyph: ^%s:%s does not match %s pattern %s", ns.Prefix, value, ns.describe(), ns.Pattern)
+	}
+	return nil
+}
+
+func (ns *RefNamespace) describe() string {
+	if ns.Description != "" {
+		return ns.Description
+	}
+	return "namespace " + ns.Prefix
+}
+
+// RegisterRefNamespace adds a namespace to DefaultRefRegistry.
+func RegisterRefNamespace(ns RefNamespace) error {
+	return DefaultRefRegistry.Register(ns)
+}
+
+// NewID constructs a reference id validated against DefaultRefRegistry.
+func NewID(prefix, value string) (*GValue, error) {
+	return DefaultRefRegistry.NewID(prefix, value)
+}
+
+// MustID constructs a reference id validated against DefaultRefRegistry,
+// panicking if it is invalid.
+func MustID(prefix, value string) *GValue {
+	return DefaultRefRegistry.MustID(prefix, value)
+}
+
+// refFromValue extracts a RefID from an id value or its string form
+// ("^t:ARS" or "t:ARS"), as accepted by id fields.
+func refFromValue(v *GValue) (RefID, bool) {
+	switch v.typ {
+	case TypeID:
+		return v.idVal(), true
+	case TypeStr:
+		return parseRefIDFromTarget(strings.TrimPrefix(v.strVal, "^")), true
+	}
+	return RefID{}, false
+}
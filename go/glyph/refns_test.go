@@ -0,0 +1,130 @@
+package glyph
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type testTeam struct{ Code string }
+
+func TestRefRegistry_NewID(t *testing.T) {
+	reg := NewRefRegistry()
+	if err := reg.Register(RefNamespace{
+		Prefix:      "t",
+		Description: "team",
+		Pattern:     regexp.MustCompile(`^[A-Z]{2,4}$`),
+		GoType:      reflect.TypeOf(testTeam{}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(RefNamespace{Prefix: "m", Description: "match"}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := reg.NewID("t", "ARS")
+	if err != nil {
+		t.Fatalf("NewID: %v", err)
+	}
+	if ref, _ := v.AsID(); ref.String() != "^t:ARS" {
+		t.Errorf("got %s", ref)
+	}
+	if _, err := reg.NewID("m", "ENG-2024-001"); err != nil {
+		t.Errorf("unconstrained namespace: %v", err)
+	}
+
+	for _, tc := range []struct{ prefix, value, want string }{
+		{"t", "arsenal", "does not match team pattern"},
+		{"t", "", "empty value"},
+		{"x", "1", "unknown ref namespace"},
+	} {
+		if _, err := reg.NewID(tc.prefix, tc.value); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("NewID(%q, %q): got %v, want %q", tc.prefix, tc.value, err, tc.want)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustID should panic on an invalid id")
+			}
+		}()
+		reg.MustID("t", "1")
+	}()
+
+	if prefix, ok := reg.PrefixFor(reflect.TypeOf(testTeam{})); !ok || prefix != "t" {
+		t.Errorf("PrefixFor: got %q %v", prefix, ok)
+	}
+	if ns := reg.Namespaces(); len(ns) != 2 || ns[0].Prefix != "m" {
+		t.Errorf("Namespaces: got %v", ns)
+	}
+
+	for _, bad := range []RefNamespace{
+		{Prefix: ""},
+		{Prefix: "a:b"},
+		{Prefix: "t"},
+		{Prefix: "team", GoType: reflect.TypeOf(testTeam{})},
+	} {
+		if err := reg.Register(bad); err == nil {
+			t.Errorf("Register(%q) should fail", bad.Prefix)
+		}
+	}
+}
+
+func TestValidate_PrefixConstraint(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		Match:v1 struct{
+			home: id [prefix=[t]]
+			ref: id [prefix=[m match]] [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := schema.GetField("Match", "ref")
+	if fd == nil || !fd.Optional || len(fd.Constraints) != 1 {
+		t.Fatalf("unexpected field %+v", fd)
+	}
+	if got := fd.Constraints[0].String(); got != "prefix=[m match]" {
+		t.Errorf("String: got %s", got)
+	}
+
+	match := func(home *GValue) *GValue {
+		return Struct("Match", MapEntry{Key: "home", Value: home})
+	}
+	if r := ValidateAs(match(ID("t", "ARS")), schema, "Match"); !r.Valid {
+		t.Errorf("expected valid, got %v", r.Errors)
+	}
+	if r := ValidateAs(match(Str("^t:ARS")), schema, "Match"); !r.Valid {
+		t.Errorf("string form should be accepted, got %v", r.Errors)
+	}
+	r := ValidateAs(match(ID("m", "1")), schema, "Match")
+	if r.Valid || r.Errors[0].Code != "constraint_prefix" {
+		t.Errorf("expected prefix error, got %v", r.Errors)
+	}
+
+	// Registered namespaces also check the value pattern.
+	if err := RegisterRefNamespace(RefNamespace{
+		Prefix:  "t",
+		Pattern: regexp.MustCompile(`^[A-Z]{3}$`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(DefaultRefRegistry.namespaces, "t")
+
+	r = ValidateAs(match(ID("t", "arsenal")), schema, "Match")
+	if r.Valid || !strings.Contains(r.Errors[0].Message, "does not match") {
+		t.Errorf("expected pattern error, got %v", r.Errors)
+	}
+	if MustID("t", "ARS") == nil {
+		t.Error("MustID returned nil")
+	}
+
+	bad := NewSchemaBuilder().
+		AddStruct("Bad", "v1", Field("name", PrimitiveType("str"), WithConstraint(PrefixConstraint("t")))).
+		Build()
+	if errs := bad.Check(); len(errs) == 0 || errs[0].Code != "constraint_type_mismatch" {
+		t.Errorf("prefix on str field should fail schema check, got %v", errs)
+	}
+}
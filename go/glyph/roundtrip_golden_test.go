@@ -38,11 +38,11 @@ func gvalEqual(a, b *GValue) bool {
 	case TypeStr:
 		return a.strVal == b.strVal
 	case TypeBytes:
-		return bytes.Equal(a.bytesVal, b.bytesVal)
+		return bytes.Equal(a.bytesVal(), b.bytesVal())
 	case TypeTime:
-		return a.timeVal.Equal(b.timeVal)
+		return a.timeVal().Equal(b.timeVal())
 	case TypeID:
-		return a.idVal == b.idVal
+		return a.idVal() == b.idVal()
 	case TypeList:
 		if len(a.listVal) != len(b.listVal) {
 			return false
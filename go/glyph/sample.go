@@ -0,0 +1,147 @@
+package glyph
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+)
+
+// ============================================================
+// Sampled Lists
+// ============================================================
+//
+// A list of 50 000 log lines cut at whatever point the budget ran out
+// leaves the model guessing what is missing. SampleList keeps n items chosen
+// by a SampleStrategy and says how many it dropped:
+//
+//	Sample{index=[0 1 49998 49999] items=[...] omitted=49996 total=50000}
+//
+// index holds each kept item's position in the full list; it is left out
+// when the items are the list's first n. Stratified samples are seeded from
+// the list's content hash, so the same list always yields the same sample.
+// SampleSummarizer plugs sampling into EmitOptions.Summarizer, which adds a
+// ^#hash ref to the full list.
+
+// SampleTypeName is the struct type produced by SampleList.
+const SampleTypeName = "Sample"
+
+// SampleStrategy selects which items SampleList keeps.
+type SampleStrategy int
+
+const (
+	SampleHead       SampleStrategy = iota // The first n items
+	SampleTail                             // The last n items
+	SampleHeadTail                         // The first n/2 and last n-n/2 items
+	SampleStratified                       // One random item from each of n equal strata
+)
+
+// String returns the strategy name.
+func (s SampleStrategy) String() string {
+	switch s {
+	case SampleHead:
+		return "head"
+	case SampleTail:
+		return "tail"
+	case SampleHeadTail:
+		return "head_tail"
+	case SampleStratified:
+		return "stratified"
+	default:
+		return fmt.Sprintf("SampleStrategy(%d)", int(s))
+	}
+}
+
+// SampleList returns a Sample struct holding n items of list chosen by
+// strategy, in list order. A list of at most n items is returned unchanged.
+func SampleList(list *GValue, n int, strategy SampleStrategy) (*GValue, error) {
+	list.force()
+	if list == nil || list.typ != TypeList {
+		return nil, fmt.Errorf("glyph: sample: expected list, got %s", list.Type())
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("glyph: sample: n must be positive, got %d", n)
+	}
+	total := len(list.listVal)
+	if total <= n {
+		return list, nil
+	}
+
+	var index []int
+	switch strategy {
+	case SampleHead:
+		index = indexRange(0, n)
+	case SampleTail:
+		index = indexRange(total-n, total)
+	case SampleHeadTail:
+		index = append(indexRange(0, n/2), indexRange(total-(n-n/2), total)...)
+	case SampleStratified:
+		index = stratifiedIndex(list, n)
+	default:
+		return nil, fmt.Errorf("glyph: sample: unknown strategy %s", strategy)
+	}
+
+	items := make([]*GValue, len(index))
+	positions := make([]*GValue, len(index))
+	for i, at := range index {
+		items[i] = list.listVal[at]
+		positions[i] = Int(int64(at))
+	}
+	fields := []MapEntry{
+		{Key: "total", Value: Int(int64(total))},
+		{Key: "omitted", Value: Int(int64(total - n))},
+	}
+	if index[n-1] != n-1 {
+		fields = append(fields, MapEntry{Key: "index", Value: List(positions...)})
+	}
+	fields = append(fields, MapEntry{Key: "items", Value: List(items...)})
+	return Struct(SampleTypeName, fields...), nil
+}
+
+// EmitSampled emits SampleList(list, n, strategy) as GLYPH-T text.
+func EmitSampled(list *GValue, n int, strategy SampleStrategy) (string, error) {
+	sample, err := SampleList(list, n, strategy)
+	if err != nil {
+		return "", err
+	}
+	return Emit(sample), nil
+}
+
+// SampleSummarizer returns a Summarizer that replaces lists longer than n
+// items by their sample. Other subtrees are left to their children.
+func SampleSummarizer(n int, strategy SampleStrategy) Summarizer {
+	return func(path string, v *GValue) *GValue {
+		v.force()
+		if v.Type() != TypeList || len(v.listVal) <= n {
+			return nil
+		}
+		sample, err := SampleList(v, n, strategy)
+		if err != nil {
+			return nil
+		}
+		return sample
+	}
+}
+
+// indexRange returns lo, lo+1, ..., hi-1.
+func indexRange(lo, hi int) []int {
+	out := make([]int, 0, hi-lo)
+	for i := lo; i < hi; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+// stratifiedIndex splits list into n strata of near-equal size and picks one
+// position from each, seeding the choice from the list's content hash.
+func stratifiedIndex(list *GValue, n int) []int {
+	sum, _ := hex.DecodeString(FingerprintLoose(list))
+	rng := rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+	total := len(list.listVal)
+	index := make([]int, n)
+	for i := range index {
+		lo, hi := i*total/n, (i+1)*total/n
+		index[i] = lo + rng.IntN(hi-lo)
+	}
+	return index
+}
@@ -0,0 +1,106 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func sampleLog(n int) *GValue {
+	lines := make([]*GValue, n)
+	for i := range lines {
+		lines[i] = Str(fmt.Sprintf("line %d", i))
+	}
+	return List(lines...)
+}
+
+func TestSampleList(t *testing.T) {
+	log := sampleLog(10)
+	for _, tc := range []struct {
+		strategy SampleStrategy
+		want     string
+	}{
+		{SampleHead, `Sample{items=["line 0" "line 1" "line 2" "line 3"] omitted=6 total=10}`},
+		{SampleTail, `Sample{index=[6 7 8 9] items=["line 6" "line 7" "line 8" "line 9"] omitted=6 total=10}`},
+		{SampleHeadTail, `Sample{index=[0 1 8 9] items=["line 0" "line 1" "line 8" "line 9"] omitted=6 total=10}`},
+	} {
+		got, err := EmitSampled(log, 4, tc.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tc.strategy, got, tc.want)
+		}
+	}
+
+	// One item per stratum, in order, the same every time.
+	big := sampleLog(1000)
+	sample, err := SampleList(big, 10, SampleStratified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := sample.Get("index").listVal
+	for i, at := range index {
+		n, _ := at.AsInt()
+		if n < int64(i*100) || n >= int64((i+1)*100) {
+			t.Errorf("index[%d] = %d, outside its stratum", i, n)
+		}
+		if got, _ := sample.Get("items").listVal[i].AsStr(); got != fmt.Sprintf("line %d", n) {
+			t.Errorf("items[%d] = %q, want line %d", i, got, n)
+		}
+	}
+	again, _ := SampleList(big, 10, SampleStratified)
+	if !EqualLoose(sample, again) {
+		t.Error("stratified sample is not deterministic")
+	}
+
+	if got, _ := SampleList(log, 10, SampleHead); got != log {
+		t.Error("a short list should be returned unchanged")
+	}
+	for _, tc := range []struct {
+		list     *GValue
+		n        int
+		strategy SampleStrategy
+		want     string
+	}{
+		{Str("x"), 3, SampleHead, "expected list"},
+		{log, 0, SampleHead, "must be positive"},
+		{log, 3, SampleStrategy(9), "unknown strategy SampleStrategy(9)"},
+	} {
+		if _, err := SampleList(tc.list, tc.n, tc.strategy); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("err = %v, want %q", err, tc.want)
+		}
+	}
+}
+
+func TestSampleSummarizer(t *testing.T) {
+	state := Struct("Task",
+		MapEntry{Key: "log", Value: sampleLog(200)},
+		MapEntry{Key: "owners", Value: List(Str("ops"), Str("dev"))},
+	)
+	store := NewMemorySubtreeStore()
+	opts := DefaultEmitOptions()
+	opts.Summarizer = SampleSummarizer(4, SampleHeadTail)
+	opts.SummaryThreshold = 5
+	opts.Subtrees = store
+
+	out := EmitWithOptions(state, opts)
+	if !strings.Contains(out, `value=Sample{index=[0 1 198 199] items=["line 0" "line 1" "line 198" "line 199"] omitted=196 total=200}`) {
+		t.Fatalf("out = %s", out)
+	}
+	if !strings.Contains(out, "owners=[ops dev]") {
+		t.Errorf("short lists should be kept: %s", out)
+	}
+
+	parsed, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := ExpandSummaries(parsed.Value, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(full, state) {
+		t.Error("expanded state differs from the original")
+	}
+}
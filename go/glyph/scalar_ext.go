@@ -0,0 +1,233 @@
+package glyph
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ============================================================
+// Custom Scalar Types
+// ============================================================
+//
+// Some domain values are scalars with structure of their own: an amount of
+// money, an embedding vector, a semantic version. Writing them as strings
+// loses their type; writing them as maps costs tokens and loses the compact
+// form readers recognize. A ScalarType registers a tag for such a value
+// along with hooks to parse, emit and validate it:
+//
+//	glyph.RegisterScalar(glyph.ScalarType{
+//	    Tag:   "money",
+//	    Parse: func(text string) (any, error) { return ParseMoney(text) },
+//	    Emit:  func(v any) (string, error) { return v.(Money).String(), nil },
+//	})
+//	price := glyph.MustCustom("money", Money{1234, "USD"}) // money"12.34 USD"
+//
+// A custom scalar is written as its tag followed by its text as a quoted
+// string: money"12.34 USD", vec"0.1 0.2 0.3". Every parser reads the form.
+// For a registered tag the text goes through Parse, and the value keeps both
+// the Go value and the text Emit gives for it, so equal values canonicalize
+// identically. An unregistered tag is not an error: the value keeps its text
+// and a nil Go value, so documents pass unchanged through code that does not
+// know the type.
+//
+// A schema field whose type names a registered tag (price: money) accepts
+// that tag's values; the validator runs the Validate hook, and Coerce
+// converts a string field to the type through Parse. The JSON bridges write
+// the text in strict mode and a {"$glyph":"custom"} marker in extended mode.
+//
+// Tags are a lowercase letter followed by lowercase letters, digits and '_'.
+// Names that already mean something before a quote are reserved: the bytes
+// prefixes (b64, b64u, b85, hex), geo0 … geo9, vec8, vec16, vec32, and the
+// keywords.
+
+// ScalarType describes one custom scalar type.
+type ScalarType struct {
+	Tag string // e.g. "money"
+
+	// Parse converts the text of a literal to a Go value.
+	Parse func(text string) (any, error)
+
+	// Emit converts a Go value to literal text. Emit(Parse(text)) is the
+	// canonical text for a value.
+	Emit func(value any) (string, error)
+
+	// Validate checks a parsed value during schema validation (optional).
+	Validate func(value any) error
+}
+
+// CustomValue is the payload of a custom scalar.
+type CustomValue struct {
+	Tag   string // The scalar tag
+	Text  string // Literal text, canonical for registered tags
+	Value any    // Parsed Go value; nil if the tag is not registered
+}
+
+// ScalarRegistry maps tags to custom scalar types. Safe for concurrent use.
+type ScalarRegistry struct {
+	mu    sync.RWMutex
+	types map[string]*ScalarType
+}
+
+// NewScalarRegistry creates an empty registry.
+func NewScalarRegistry() *ScalarRegistry {
+	return &ScalarRegistry{types: make(map[string]*ScalarType)}
+}
+
+// DefaultScalarRegistry is the registry used by RegisterScalar, Custom, the
+// parsers and schema validation.
+var DefaultScalarRegistry = NewScalarRegistry()
+
+// Register adds a scalar type. The tag must be valid, not reserved and not
+// already registered; Parse and Emit are required.
+func (r *ScalarRegistry) Register(st ScalarType) error {
+	if !isScalarTag(st.Tag) {
+		return fmt.Errorf("glyph: invalid scalar tag %q", st.Tag)
+	}
+	if st.Parse == nil || st.Emit == nil {
+		return fmt.Errorf("glyph: scalar type %q needs Parse and Emit", st.Tag)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[st.Tag]; ok {
+		return fmt.Errorf("glyph: scalar type %q already registered", st.Tag)
+	}
+	r.types[st.Tag] = &st
+	return nil
+}
+
+// Lookup returns the scalar type registered for tag.
+func (r *ScalarRegistry) Lookup(tag string) (*ScalarType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	st, ok := r.types[tag]
+	return st, ok
+}
+
+// Types returns all registered scalar types sorted by tag.
+func (r *ScalarRegistry) Types() []ScalarType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ScalarType, 0, len(r.types))
+	for _, st := range r.types {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
+
+// Custom constructs a value of a registered scalar type from a Go value.
+func (r *ScalarRegistry) Custom(tag string, value any) (*GValue, error) {
+	st, ok := r.Lookup(tag)
+	if !ok {
+		return nil, fmt.Errorf("glyph: scalar type %q not registered", tag)
+	}
+	text, err := st.Emit(value)
+	if err != nil {
+		return nil, fmt.Errorf("glyph: emit %s: %w", tag, err)
+	}
+	return newCustom(tag, text, value), nil
+}
+
+// Decode constructs a custom scalar from literal text. A registered tag's
+// text is parsed and normalized; an unregistered tag's is kept as-is.
+func (r *ScalarRegistry) Decode(tag, text string) (*GValue, error) {
+	if !isScalarTag(tag) {
+		return nil, fmt.Errorf("glyph: invalid scalar tag %q", tag)
+	}
+	st, ok := r.Lookup(tag)
+	if !ok {
+		return newCustom(tag, text, nil), nil
+	}
+	value, err := st.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s literal %q: %w", tag, text, err)
+	}
+	if canon, err := st.Emit(value); err == nil {
+		text = canon
+	}
+	return newCustom(tag, text, value), nil
+}
+
+// RegisterScalar adds a scalar type to DefaultScalarRegistry.
+func RegisterScalar(st ScalarType) error {
+	return DefaultScalarRegistry.Register(st)
+}
+
+// Custom constructs a value of a scalar type registered in
+// DefaultScalarRegistry.
+func Custom(tag string, value any) (*GValue, error) {
+	return DefaultScalarRegistry.Custom(tag, value)
+}
+
+// MustCustom is like Custom but panics on error.
+func MustCustom(tag string, value any) *GValue {
+	v, err := Custom(tag, value)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func newCustom(tag, text string, value any) *GValue {
+	return newExtValue(TypeCustom, valueExt{custom: &CustomValue{Tag: tag, Text: text, Value: value}})
+}
+
+// AsCustom returns the custom scalar payload.
+func (v *GValue) AsCustom() (*CustomValue, error) {
+	if v == nil {
+		return nil, fmt.Errorf("glyph: nil value")
+	}
+	if v.typ != TypeCustom {
+		return nil, fmt.Errorf("glyph: expected custom, got %s", v.typ)
+	}
+	return v.customVal(), nil
+}
+
+// canonCustom returns the literal form of a custom scalar: tag"text".
+func canonCustom(cv *CustomValue) string {
+	return cv.Tag + quoteString(cv.Text)
+}
+
+// isScalarTag reports whether s is a valid, unreserved scalar tag.
+func isScalarTag(s string) bool {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	switch s {
+	case "t", "f", "true", "false", "null", "none", "nil":
+		return false
+	}
+	lit := s + `"`
+	return bytesLiteralPrefix(lit) == "" && geoLiteralPrefix(lit) == "" && vecLiteralPrefix(lit) == ""
+}
+
+// scalarLiteralPrefix returns the tag if s starts with a custom scalar
+// literal (a tag immediately followed by '"'), or "".
+func scalarLiteralPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] != '"' {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return ""
+		}
+		i++
+	}
+	if i == len(s) || !isScalarTag(s[:i]) {
+		return ""
+	}
+	return s[:i]
+}
+
+// decodeScalarLiteral decodes the unquoted text of a custom scalar literal
+// using DefaultScalarRegistry.
+func decodeScalarLiteral(tag, text string) (*GValue, error) {
+	return DefaultScalarRegistry.Decode(tag, text)
+}
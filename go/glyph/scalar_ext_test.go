@@ -0,0 +1,255 @@
+package glyph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testMoney struct {
+	Cents    int64
+	Currency string
+}
+
+// registerTestMoney registers money"12.34 USD" in DefaultScalarRegistry
+// until the test ends.
+func registerTestMoney(t *testing.T) {
+	t.Helper()
+	err := RegisterScalar(ScalarType{
+		Tag: "money",
+		Parse: func(text string) (any, error) {
+			var m testMoney
+			var units, cents int64
+			if _, err := fmt.Sscanf(text, "%d.%d %s", &units, &cents, &m.Currency); err != nil {
+				return nil, err
+			}
+			m.Cents = units*100 + cents
+			return m, nil
+		},
+		Emit: func(v any) (string, error) {
+			m, ok := v.(testMoney)
+			if !ok {
+				return "", fmt.Errorf("not money: %T", v)
+			}
+			return fmt.Sprintf("%d.%02d %s", m.Cents/100, m.Cents%100, m.Currency), nil
+		},
+		Validate: func(v any) error {
+			if v.(testMoney).Cents < 0 {
+				return fmt.Errorf("negative amount")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(DefaultScalarRegistry.types, "money") })
+}
+
+// parseTyped parses GLYPH-T text, failing on any parse error.
+func parseTyped(s string) (*GValue, error) {
+	r, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if r.HasErrors() {
+		return nil, &r.Errors[0]
+	}
+	return r.Value, nil
+}
+
+func TestScalar_RoundTrip(t *testing.T) {
+	registerTestMoney(t)
+
+	price := MustCustom("money", testMoney{1234, "USD"})
+	v := Map(
+		MapEntry{Key: "price", Value: price},
+		MapEntry{Key: "emb", Value: List(Int(1), Int(2))},
+	)
+	want := `{emb=[1 2] price=money"12.34 USD"}`
+	if got := CanonicalizeLoose(v); got != want {
+		t.Fatalf("canonical = %s", got)
+	}
+	if got := Emit(price); got != `money"12.34 USD"` {
+		t.Errorf("Emit = %s", got)
+	}
+
+	for _, parse := range []func(string) (*GValue, error){
+		parseTyped,
+		func(s string) (*GValue, error) { return ParseLoose(s, nil) },
+	} {
+		back, err := parse(`{price=money"0012.34 USD" emb=[1 2]}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cv, err := back.Get("price").AsCustom()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cv.Value != (testMoney{1234, "USD"}) || cv.Text != "12.34 USD" {
+			t.Errorf("parsed %+v", cv)
+		}
+		if !EqualLoose(back, v) {
+			t.Errorf("round trip: %s", CanonicalizeLoose(back))
+		}
+	}
+
+	if _, err := parseTyped(`{price=money"lots"}`); err == nil || !strings.Contains(err.Error(), "invalid money literal") {
+		t.Errorf("bad literal: %v", err)
+	}
+}
+
+func TestScalar_Tabular(t *testing.T) {
+	registerTestMoney(t)
+
+	rows := make([]*GValue, 3)
+	for i := range rows {
+		rows[i] = Map(
+			MapEntry{Key: "id", Value: Int(int64(i))},
+			MapEntry{Key: "price", Value: MustCustom("money", testMoney{int64(100 * i), "EUR"})},
+		)
+	}
+	v := List(rows...)
+	out := CanonicalizeLoose(v)
+	if !strings.HasPrefix(out, "@tab") || !strings.Contains(out, `money"2.00 EUR"`) {
+		t.Fatalf("tabular output = %s", out)
+	}
+	back, err := ParseLoose(out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(back, v) {
+		t.Errorf("round trip: %s", CanonicalizeLoose(back))
+	}
+}
+
+func TestScalar_Unregistered(t *testing.T) {
+	in := `{v=vec"0.1 0.2 \"x\""}`
+	v, err := parseTyped(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv, err := v.Get("v").AsCustom()
+	if err != nil || cv.Tag != "vec" || cv.Text != `0.1 0.2 "x"` || cv.Value != nil {
+		t.Fatalf("custom = %+v, %v", cv, err)
+	}
+	if got := CanonicalizeLoose(v); got != in {
+		t.Errorf("canonical = %s", got)
+	}
+	if _, err := Custom("vec", 1); err == nil {
+		t.Error("Custom with an unregistered tag should fail")
+	}
+
+	// Reserved prefixes keep their meaning.
+	v, err = parseTyped(`[hex"ff" geo5"1 2" t "x"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := v.AsList()
+	for i, typ := range []GType{TypeBytes, TypeList, TypeBool, TypeStr} {
+		if items[i].Type() != typ {
+			t.Errorf("item %d: %s, want %s", i, items[i].Type(), typ)
+		}
+	}
+}
+
+func TestScalar_Register(t *testing.T) {
+	reg := NewScalarRegistry()
+	parse := func(s string) (any, error) { return s, nil }
+	emit := func(v any) (string, error) { return fmt.Sprint(v), nil }
+	if err := reg.Register(ScalarType{Tag: "semver", Parse: parse, Emit: emit}); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []ScalarType{
+		{Tag: "semver", Parse: parse, Emit: emit},
+		{Tag: "Money", Parse: parse, Emit: emit},
+		{Tag: "b64", Parse: parse, Emit: emit},
+		{Tag: "geo5", Parse: parse, Emit: emit},
+		{Tag: "null", Parse: parse, Emit: emit},
+		{Tag: "uuid", Parse: parse},
+	} {
+		if err := reg.Register(bad); err == nil {
+			t.Errorf("Register(%q) should fail", bad.Tag)
+		}
+	}
+	if types := reg.Types(); len(types) != 1 || types[0].Tag != "semver" {
+		t.Errorf("Types = %v", types)
+	}
+}
+
+func TestScalar_Schema(t *testing.T) {
+	registerTestMoney(t)
+
+	schema, err := ParseSchema(`@schema{
+		Item:v1 struct{
+			price: money
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := func(price *GValue) *GValue {
+		return Struct("Item", MapEntry{Key: "price", Value: price})
+	}
+
+	if r := ValidateAs(item(MustCustom("money", testMoney{5, "USD"})), schema, "Item"); !r.Valid || len(r.Warnings) > 0 {
+		t.Errorf("valid money: %v %v", r.Errors, r.Warnings)
+	}
+	r := ValidateAs(item(MustCustom("money", testMoney{-5, "USD"})), schema, "Item")
+	if r.Valid || r.Errors[0].Code != "invalid_value" {
+		t.Errorf("negative money: %v", r.Errors)
+	}
+	r = ValidateAs(item(Str("12.34 USD")), schema, "Item")
+	if r.Valid || r.Errors[0].Code != "type_mismatch" {
+		t.Errorf("string: %v", r.Errors)
+	}
+
+	// Coerce recovers the type from a string, e.g. after strict JSON.
+	out, report, err := Coerce(item(Str("0.05 USD")), schema, "Item")
+	if err != nil || !report.Changed() {
+		t.Fatalf("Coerce: %v %v", report, err)
+	}
+	if r := ValidateAs(out, schema, "Item"); !r.Valid {
+		t.Errorf("coerced: %v", r.Errors)
+	}
+
+	packed, err := EmitPacked(out, schema)
+	if err != nil || packed != `Item@(money"0.05 USD")` {
+		t.Fatalf("packed = %s, %v", packed, err)
+	}
+	back, err := ParsePacked(packed, schema)
+	if err != nil || !EqualLoose(back, out) {
+		t.Errorf("packed round trip: %v, %v", back, err)
+	}
+}
+
+func TestScalar_JSON(t *testing.T) {
+	registerTestMoney(t)
+
+	v := Map(MapEntry{Key: "price", Value: MustCustom("money", testMoney{1234, "USD"})})
+	strict, err := ToJSONLoose(v)
+	if err != nil || string(strict) != `{"price":"12.34 USD"}` {
+		t.Errorf("strict = %s, %v", strict, err)
+	}
+
+	ext, err := ToJSONLooseWithOpts(v, BridgeOpts{Extended: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj map[string]map[string]string
+	if err := json.Unmarshal(ext, &obj); err != nil || obj["price"]["tag"] != "money" {
+		t.Fatalf("extended = %s, %v", ext, err)
+	}
+	back, err := FromJSONLooseWithOpts(ext, BridgeOpts{Extended: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(back, v) {
+		t.Errorf("extended round trip: %s", CanonicalizeLoose(back))
+	}
+
+	if _, err := FromJSONLooseWithOpts([]byte(`{"$glyph":"custom","tag":"money","value":"x","extra":1}`), BridgeOpts{Extended: true}); err == nil {
+		t.Error("expected error for marker with extra key")
+	}
+}
@@ -26,6 +26,9 @@ type TypeDef struct {
 	PackEnabled bool // @pack: enable packed encoding for this struct
 	TabEnabled  bool // @tab: enable tabular encoding for list<this> (default true)
 	Open        bool // @open: accept unknown fields (stored in @unknown map)
+
+	Deprecated *Deprecation // @deprecated: warn when the type appears
+	Doc        string       // /// doc comment (see Schema.PromptBlock)
 }
 
 // TypeDefKind indicates whether a type is a struct or sum.
@@ -38,7 +41,9 @@ const (
 
 // StructDef represents a struct type definition.
 type StructDef struct {
-	Fields []*FieldDef
+	Fields  []*FieldDef
+	Asserts []*Assert  // Cross-field rules checked by Validate
+	OneOf   [][]string // Groups of fields of which exactly one must be set
 }
 
 // SumDef represents a sum (tagged union) type definition.
@@ -65,6 +70,10 @@ type FieldDef struct {
 	FID      int    // Stable field ID for packed encoding (1+, never reuse)
 	KeepNull bool   // Emit null in packed even if optional
 	Codec    string // Encoding hint: "dict", "enum", "int", "f32", etc.
+
+	Deprecated *Deprecation // @deprecated: warn when the field appears
+	ACL        *ACL         // @acl: roles that may read and write the field
+	Doc        string       // /// doc comment (see Schema.PromptBlock)
 }
 
 // TypeSpec represents a type reference.
@@ -141,17 +150,20 @@ type Constraint struct {
 type ConstraintKind uint8
 
 const (
-	ConstraintMin      ConstraintKind = iota // min=N (numeric)
-	ConstraintMax                            // max=N (numeric)
-	ConstraintMinLen                         // len>=N (string/list/bytes)
-	ConstraintMaxLen                         // len<=N (string/list/bytes)
-	ConstraintLen                            // len=N (exact length)
-	ConstraintRegex                          // regex="pattern"
-	ConstraintEnum                           // enum=["a","b","c"]
-	ConstraintNonEmpty                       // nonempty
-	ConstraintUnique                         // unique (list elements)
-	ConstraintRange                          // range=[min,max]
-	ConstraintOptional                       // optional (field may be omitted)
+	ConstraintMin        ConstraintKind = iota // min=N (numeric)
+	ConstraintMax                              // max=N (numeric)
+	ConstraintMinLen                           // len>=N (string/list/bytes)
+	ConstraintMaxLen                           // len<=N (string/list/bytes)
+	ConstraintLen                              // len=N (exact length)
+	ConstraintRegex                            // regex="pattern"
+	ConstraintEnum                             // enum=["a","b","c"]
+	ConstraintNonEmpty                         // nonempty
+	ConstraintUnique                           // unique (list elements)
+	ConstraintRange                            // range=[min,max]
+	ConstraintOptional                         // optional (field may be omitted)
+	ConstraintPrefix                           // prefix=[t m] (id namespaces)
+	ConstraintIDFormat                         // uuid | ulid (id value format)
+	ConstraintRequiredIf                       // requiredIf(field=value)
 )
 
 // String returns the constraint as a string.
@@ -182,6 +194,12 @@ func (c Constraint) String() string {
 		return fmt.Sprintf("%v..%v", r[0], r[1])
 	case ConstraintOptional:
 		return "optional"
+	case ConstraintPrefix:
+		return fmt.Sprintf("prefix=%v", c.Value)
+	case ConstraintIDFormat:
+		return c.Value.(string)
+	case ConstraintRequiredIf:
+		return c.Value.(RequiredIf).String()
 	default:
 		return "unknown"
 	}
@@ -257,6 +275,8 @@ func (s *Schema) ComputeHash() string {
 // back), this form is exhaustive and machine-oriented: it serializes the
 // packed/tabular/open flags and, per field, FID, wire key, optionality, sorted
 // constraints, keep-null, codec and default — and orders struct fields by FID.
+// Doc comments are left out: rewording documentation changes neither what a
+// schema accepts nor how it decodes.
 func (s *Schema) hashCanonical() string {
 	var sb strings.Builder
 	sb.WriteString("glyph-schema-hash-v1\n")
@@ -277,6 +297,9 @@ func (s *Schema) hashCanonical() string {
 func writeTypeDefForHash(sb *strings.Builder, td *TypeDef) {
 	fmt.Fprintf(sb, "type name=%s version=%s pack=%t tab=%t open=%t",
 		td.Name, td.Version, td.PackEnabled, td.TabEnabled, td.Open)
+	if td.Deprecated != nil {
+		fmt.Fprintf(sb, " deprecated=%s", td.Deprecated)
+	}
 
 	switch td.Kind {
 	case TypeDefStruct:
@@ -287,6 +310,12 @@ func writeTypeDefForHash(sb *strings.Builder, td *TypeDef) {
 		for _, f := range td.FieldsByFID() {
 			writeFieldDefForHash(sb, f)
 		}
+		for _, a := range td.Struct.Asserts {
+			fmt.Fprintf(sb, "  assert %s\n", a)
+		}
+		for _, group := range td.Struct.OneOf {
+			fmt.Fprintf(sb, "  oneof %s\n", strings.Join(group, "|"))
+		}
 	case TypeDefSum:
 		sb.WriteString(" kind=sum\n")
 		if td.Sum != nil {
@@ -320,6 +349,16 @@ func writeFieldDefForHash(sb *strings.Builder, f *FieldDef) {
 		sb.WriteString(Emit(f.Default))
 	}
 
+	if f.Deprecated != nil {
+		sb.WriteString(" deprecated=")
+		sb.WriteString(f.Deprecated.String())
+	}
+
+	if f.ACL != nil {
+		sb.WriteString(" acl=")
+		sb.WriteString(f.ACL.String())
+	}
+
 	sb.WriteString("\n")
 }
 
@@ -349,14 +388,55 @@ func (s *Schema) Canonical() string {
 
 	for _, name := range names {
 		td := s.Types[name]
-		writeTypeDef(&sb, td)
+		writeTypeDef(&sb, td, false)
+	}
+
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// PromptBlock returns the schema text to show a model in a prompt: the
+// canonical text with its /// doc comments, less the encoding annotations
+// (@pack @tab @open @fid @k @codec @keepnull) that only matter on the wire.
+// The result is itself a schema ParseSchema accepts.
+func (s *Schema) PromptBlock() string {
+	var sb strings.Builder
+	sb.WriteString("@schema{\n")
+
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sortStrings(names)
+
+	for _, name := range names {
+		writeTypeDef(&sb, s.Types[name], true)
 	}
 
 	sb.WriteString("}")
 	return sb.String()
 }
 
-func writeTypeDef(sb *strings.Builder, td *TypeDef) {
+// writeDoc writes doc as /// comment lines at indent.
+func writeDoc(sb *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		sb.WriteString(indent)
+		sb.WriteString("///")
+		if line != "" {
+			sb.WriteString(" ")
+			sb.WriteString(line)
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// writeTypeDef writes td as schema text; prompt leaves out the encoding
+// annotations (see PromptBlock).
+func writeTypeDef(sb *strings.Builder, td *TypeDef, prompt bool) {
+	writeDoc(sb, "  ", td.Doc)
 	sb.WriteString("  ")
 	sb.WriteString(td.Name)
 	if td.Version != "" {
@@ -364,22 +444,27 @@ func writeTypeDef(sb *strings.Builder, td *TypeDef) {
 		sb.WriteString(td.Version)
 	}
 	sb.WriteString(" ")
+	if td.Deprecated != nil {
+		sb.WriteString(td.Deprecated.String())
+		sb.WriteString(" ")
+	}
 
 	switch td.Kind {
 	case TypeDefStruct:
 		// Type-level flags (round-trip via parseTypeDef). Emitted in a fixed
 		// order so EmitSchema is deterministic.
-		if td.PackEnabled {
+		if td.PackEnabled && !prompt {
 			sb.WriteString("@pack ")
 		}
-		if td.TabEnabled {
+		if td.TabEnabled && !prompt {
 			sb.WriteString("@tab ")
 		}
-		if td.Open {
+		if td.Open && !prompt {
 			sb.WriteString("@open ")
 		}
 		sb.WriteString("struct{\n")
 		for _, f := range td.Struct.Fields {
+			writeDoc(sb, "    ", f.Doc)
 			sb.WriteString("    ")
 			sb.WriteString(f.Name)
 			sb.WriteString(": ")
@@ -390,23 +475,33 @@ func writeTypeDef(sb *strings.Builder, td *TypeDef) {
 				sb.WriteString("]")
 			}
 			// Field-level annotations (round-trip via parseFieldDef).
-			if f.FID > 0 {
-				sb.WriteString(" @fid(")
-				sb.WriteString(fmt.Sprintf("%d", f.FID))
-				sb.WriteString(")")
-			}
-			if f.WireKey != "" {
-				sb.WriteString(" @k(")
-				sb.WriteString(f.WireKey)
-				sb.WriteString(")")
-			}
-			if f.Codec != "" {
-				sb.WriteString(" @codec(")
-				sb.WriteString(f.Codec)
-				sb.WriteString(")")
+			if !prompt {
+				if f.FID > 0 {
+					sb.WriteString(" @fid(")
+					sb.WriteString(fmt.Sprintf("%d", f.FID))
+					sb.WriteString(")")
+				}
+				if f.WireKey != "" {
+					sb.WriteString(" @k(")
+					sb.WriteString(f.WireKey)
+					sb.WriteString(")")
+				}
+				if f.Codec != "" {
+					sb.WriteString(" @codec(")
+					sb.WriteString(f.Codec)
+					sb.WriteString(")")
+				}
+				if f.KeepNull {
+					sb.WriteString(" @keepnull")
+				}
+				if f.ACL != nil {
+					sb.WriteString(" ")
+					sb.WriteString(f.ACL.String())
+				}
 			}
-			if f.KeepNull {
-				sb.WriteString(" @keepnull")
+			if f.Deprecated != nil {
+				sb.WriteString(" ")
+				sb.WriteString(f.Deprecated.String())
 			}
 			if f.Default != nil {
 				sb.WriteString(" @default(")
@@ -418,6 +513,16 @@ func writeTypeDef(sb *strings.Builder, td *TypeDef) {
 			}
 			sb.WriteString("\n")
 		}
+		for _, a := range td.Struct.Asserts {
+			sb.WriteString("    @assert(")
+			sb.WriteString(a.String())
+			sb.WriteString(")\n")
+		}
+		for _, group := range td.Struct.OneOf {
+			sb.WriteString("    @oneof(")
+			sb.WriteString(strings.Join(group, " | "))
+			sb.WriteString(")\n")
+		}
 		sb.WriteString("  }\n")
 	case TypeDefSum:
 		sb.WriteString("sum{\n")
@@ -569,6 +674,22 @@ func (td *TypeDef) FieldByKey(key string) *FieldDef {
 	return nil
 }
 
+// UnknownFields returns the fields of struct value v whose keys match neither a
+// field name nor a wire key of td, in their original order. For @open types
+// these make up the @unknown bag carried through packed encoding.
+func (td *TypeDef) UnknownFields(v *GValue) []MapEntry {
+	if v == nil || v.typ != TypeStruct || td.Kind != TypeDefStruct || td.Struct == nil {
+		return nil
+	}
+	var unknown []MapEntry
+	for _, f := range v.structVal.Fields {
+		if td.FieldByKey(f.Key) == nil {
+			unknown = append(unknown, f)
+		}
+	}
+	return unknown
+}
+
 // GetFIDForField returns the FID for a field by name or wire key.
 // Returns 0 if the field is not found or has no FID.
 func (td *TypeDef) GetFIDForField(key string) int {
@@ -623,6 +744,27 @@ func EnumConstraint(values []string) Constraint {
 	return Constraint{Kind: ConstraintEnum, Value: values}
 }
 
+// PrefixConstraint restricts an id field to the given reference prefixes.
+func PrefixConstraint(prefixes ...string) Constraint {
+	return Constraint{Kind: ConstraintPrefix, Value: prefixes}
+}
+
+// UUIDConstraint requires id values to be UUIDs, hyphenated or base32.
+func UUIDConstraint() Constraint {
+	return Constraint{Kind: ConstraintIDFormat, Value: "uuid"}
+}
+
+// ULIDConstraint requires id values to be 26-character base32 ULIDs.
+func ULIDConstraint() Constraint {
+	return Constraint{Kind: ConstraintIDFormat, Value: "ulid"}
+}
+
+// RequiredIfConstraint makes an optional field required when another
+// field of the struct equals value.
+func RequiredIfConstraint(field string, value *GValue) Constraint {
+	return Constraint{Kind: ConstraintRequiredIf, Value: RequiredIf{Field: field, Value: value}}
+}
+
 // NonEmptyConstraint creates a non-empty constraint.
 func NonEmptyConstraint() Constraint {
 	return Constraint{Kind: ConstraintNonEmpty}
@@ -816,6 +958,44 @@ func (b *SchemaBuilder) WithOpen(typeName string) *SchemaBuilder {
 	return b
 }
 
+// WithAssert adds cross-field assertions, such as "end >= start", to a
+// struct type. It panics if an expression does not parse (see
+// MustParseAssert).
+func (b *SchemaBuilder) WithAssert(typeName string, exprs ...string) *SchemaBuilder {
+	if td, ok := b.schema.Types[typeName]; ok && td.Struct != nil {
+		for _, expr := range exprs {
+			td.Struct.Asserts = append(td.Struct.Asserts, MustParseAssert(expr))
+		}
+	}
+	return b
+}
+
+// WithOneOf adds a group of fields of which exactly one must be set to a
+// struct type.
+func (b *SchemaBuilder) WithOneOf(typeName string, fields ...string) *SchemaBuilder {
+	if td, ok := b.schema.Types[typeName]; ok && td.Struct != nil {
+		td.Struct.OneOf = append(td.Struct.OneOf, fields)
+	}
+	return b
+}
+
+// WithDoc sets the doc comment of a type by name.
+func (b *SchemaBuilder) WithDoc(typeName, doc string) *SchemaBuilder {
+	if td, ok := b.schema.Types[typeName]; ok {
+		td.Doc = doc
+	}
+	return b
+}
+
+// WithDeprecated marks a type as deprecated by name; use names the
+// replacement type and may be "".
+func (b *SchemaBuilder) WithDeprecated(typeName, since, use string) *SchemaBuilder {
+	if td, ok := b.schema.Types[typeName]; ok {
+		td.Deprecated = &Deprecation{Since: since, Use: use}
+	}
+	return b
+}
+
 // Field creates a field definition.
 func Field(name string, typ TypeSpec, opts ...FieldOption) *FieldDef {
 	f := &FieldDef{Name: name, Type: typ}
@@ -877,6 +1057,29 @@ func WithCodec(codec string) FieldOption {
 	}
 }
 
+// WithDoc sets the doc comment of a field.
+func WithDoc(doc string) FieldOption {
+	return func(f *FieldDef) {
+		f.Doc = doc
+	}
+}
+
+// WithDeprecated marks a field as deprecated; use names the replacement
+// field and may be "".
+func WithDeprecated(since, use string) FieldOption {
+	return func(f *FieldDef) {
+		f.Deprecated = &Deprecation{Since: since, Use: use}
+	}
+}
+
+// WithACL sets the roles that may read and write a field; nil lets every
+// role in.
+func WithACL(read, write []string) FieldOption {
+	return func(f *FieldDef) {
+		f.ACL = &ACL{Read: read, Write: write}
+	}
+}
+
 // Variant creates a variant definition for a sum type.
 func Variant(tag string, typ TypeSpec) *VariantDef {
 	return &VariantDef{Tag: tag, Type: typ}
@@ -0,0 +1,472 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ============================================================
+// Access Control
+// ============================================================
+//
+// A document edited by several agents often has fields only some of them
+// may see or change: a budget only a lead may raise, review notes the
+// worker must not read. @acl on a field names the roles that may read and
+// write it:
+//
+//	Task struct{
+//	    title: str
+//	    budget: float @acl(write=lead)
+//	    review: str [optional] @acl(read=[lead, reviewer], write=reviewer)
+//	}
+//
+// Leaving read= or write= out lets every role in; an empty list lets none.
+// An @acl covers everything inside its field. The policy is enforced where
+// documents change and leave: ApplyPatchAs refuses a patch that changes a
+// field its principal may not write, or reads (with ? or as the source of
+// *) one it may not read, and an emitter with EmitOptions.Principal leaves
+// out the fields its principal may not read (see StripUnreadable).
+
+// ErrAccessDenied is wrapped by the error for a patch op its principal may
+// not apply.
+var ErrAccessDenied = errors.New("glyph: access denied")
+
+// ACL is an @acl annotation of a field.
+type ACL struct {
+	Read  []string // Roles that may read the field; nil for every role
+	Write []string // Roles that may write the field; nil for every role
+}
+
+// String returns the annotation as written in schema text.
+func (a *ACL) String() string {
+	roles := func(rs []string) string {
+		if len(rs) == 1 {
+			return rs[0]
+		}
+		return "[" + strings.Join(rs, ", ") + "]"
+	}
+	var args []string
+	if a.Read != nil {
+		args = append(args, "read="+roles(a.Read))
+	}
+	if a.Write != nil {
+		args = append(args, "write="+roles(a.Write))
+	}
+	return "@acl(" + strings.Join(args, ", ") + ")"
+}
+
+// Principal is who reads or writes a document, for @acl checks.
+type Principal struct {
+	Name  string // Names the principal in errors; may be ""
+	Roles []string
+}
+
+// may reports whether p has one of roles. A nil principal has no roles.
+func (p *Principal) may(roles []string) bool {
+	if roles == nil {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if slices.Contains(roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// String names p in errors: its name, or else its roles.
+func (p *Principal) String() string {
+	switch {
+	case p == nil:
+		return "anonymous"
+	case p.Name != "":
+		return p.Name
+	case len(p.Roles) > 0:
+		return "roles " + strings.Join(p.Roles, ", ")
+	}
+	return "principal with no roles"
+}
+
+// parseACL parses the (read=..., write=...) after @acl. Each takes a role
+// or a bracketed list of roles.
+func (p *schemaParser) parseACL() (*ACL, error) {
+	if _, err := p.expect(TokenLParen, "( after @acl"); err != nil {
+		return nil, err
+	}
+	acl := &ACL{}
+	for !p.stream.Match(TokenRParen) {
+		p.stream.Match(TokenComma)
+		nameTok, err := p.expect(TokenIdent, "read= or write= in @acl")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(TokenEq, "= after @acl "+nameTok.Value); err != nil {
+			return nil, err
+		}
+		roles, err := p.parseACLRoles()
+		if err != nil {
+			return nil, err
+		}
+		switch nameTok.Value {
+		case "read":
+			acl.Read = roles
+		case "write":
+			acl.Write = roles
+		default:
+			return nil, p.errorf(nameTok, "unknown @acl argument %s", nameTok.Value)
+		}
+	}
+	if acl.Read == nil && acl.Write == nil {
+		return nil, p.errorf(p.stream.Peek(), "@acl needs read= or write=")
+	}
+	return acl, nil
+}
+
+func (p *schemaParser) parseACLRoles() ([]string, error) {
+	if !p.stream.Match(TokenLBracket) {
+		tok, err := p.expect(TokenIdent, "role in @acl")
+		if err != nil {
+			return nil, err
+		}
+		return []string{tok.Value}, nil
+	}
+	roles := []string{}
+	for !p.stream.Match(TokenRBracket) {
+		p.stream.Match(TokenComma)
+		tok, err := p.expect(TokenIdent, "role or ] in @acl")
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, tok.Value)
+	}
+	return roles, nil
+}
+
+// StripUnreadable returns a copy of v without the struct fields whose @acl
+// does not let principal read them. A map is read as the struct type the
+// schema declares where it appears, so it loses the same fields. Unchanged
+// subtrees are shared with v.
+func StripUnreadable(schema *Schema, v *GValue, principal *Principal) *GValue {
+	return stripUnreadable(schema, v, TypeSpec{}, principal)
+}
+
+// stripUnreadable strips v, a value where the schema expects spec.
+func stripUnreadable(schema *Schema, v *GValue, spec TypeSpec, principal *Principal) *GValue {
+	if v == nil {
+		return nil
+	}
+	v.force()
+	spec = variantSpec(schema, v, spec)
+
+	td := structTypeFor(schema, v, spec)
+	switch {
+	case td != nil && (v.typ == TypeStruct || v.typ == TypeMap):
+		entries := v.mapVal
+		if v.typ == TypeStruct {
+			entries = v.structVal.Fields
+		}
+		var out []MapEntry
+		for i, e := range entries {
+			var nv *GValue
+			if fd := td.FieldByKey(e.Key); fd == nil {
+				nv = stripUnreadable(schema, e.Value, TypeSpec{}, principal)
+			} else if fd.ACL == nil || principal.may(fd.ACL.Read) {
+				nv = stripUnreadable(schema, e.Value, fd.Type, principal)
+			}
+			if out == nil && nv != e.Value {
+				out = append(make([]MapEntry, 0, len(entries)), entries[:i]...)
+			}
+			if out != nil && nv != nil {
+				out = append(out, MapEntry{Key: e.Key, Value: nv})
+			}
+		}
+		switch {
+		case out == nil:
+			return v
+		case v.typ == TypeStruct:
+			return Struct(v.structVal.TypeName, out...)
+		}
+		return Map(out...)
+
+	case v.typ == TypeList:
+		var items []*GValue
+		for i, item := range v.listVal {
+			if ni := stripUnreadable(schema, item, elemSpec(spec), principal); ni != item {
+				if items == nil {
+					items = append([]*GValue(nil), v.listVal...)
+				}
+				items[i] = ni
+			}
+		}
+		if items != nil {
+			return List(items...)
+		}
+
+	case v.typ == TypeMap || v.typ == TypeStruct:
+		entries := v.mapVal
+		if v.typ == TypeStruct {
+			entries = v.structVal.Fields
+		}
+		var out []MapEntry
+		for i, e := range entries {
+			if nv := stripUnreadable(schema, e.Value, valueSpec(spec), principal); nv != e.Value {
+				if out == nil {
+					out = append([]MapEntry(nil), entries...)
+				}
+				out[i].Value = nv
+			}
+		}
+		switch {
+		case out == nil:
+		case v.typ == TypeStruct:
+			return Struct(v.structVal.TypeName, out...)
+		default:
+			return Map(out...)
+		}
+
+	case v.typ == TypeSum:
+		if nv := stripUnreadable(schema, v.sumVal.Value, spec, principal); nv != v.sumVal.Value {
+			return Sum(v.sumVal.Tag, nv)
+		}
+	}
+	return v
+}
+
+// The schema walk below tracks the type the schema declares for each value
+// reached, so a map written where a struct is declared is checked as that
+// struct. A zero TypeSpec means the schema does not say; a struct value is
+// then read as its own type.
+
+// structTypeFor returns the struct type v is read as where spec is expected,
+// or nil if it is not read as a struct.
+func structTypeFor(schema *Schema, v *GValue, spec TypeSpec) *TypeDef {
+	switch spec.Kind {
+	case TypeSpecRef:
+		if td := schema.GetType(spec.Name); td != nil && td.Kind == TypeDefStruct && td.Struct != nil {
+			return td
+		}
+	case TypeSpecInlineStruct:
+		return &TypeDef{Name: "struct", Kind: TypeDefStruct, Struct: spec.Struct}
+	}
+	if v != nil && v.typ == TypeStruct {
+		if td := schema.GetType(v.structVal.TypeName); td != nil && td.Kind == TypeDefStruct && td.Struct != nil {
+			return td
+		}
+	}
+	return nil
+}
+
+// variantSpec returns the type of the variant v holds, if spec names a sum
+// type, and spec otherwise.
+func variantSpec(schema *Schema, v *GValue, spec TypeSpec) TypeSpec {
+	if spec.Kind != TypeSpecRef || v.typ != TypeSum {
+		return spec
+	}
+	if td := schema.GetType(spec.Name); td != nil && td.Kind == TypeDefSum && td.Sum != nil {
+		for _, variant := range td.Sum.Variants {
+			if variant.Tag == v.sumVal.Tag {
+				return variant.Type
+			}
+		}
+	}
+	return TypeSpec{}
+}
+
+func elemSpec(spec TypeSpec) TypeSpec {
+	if spec.Kind == TypeSpecList && spec.Elem != nil {
+		return *spec.Elem
+	}
+	return TypeSpec{}
+}
+
+func valueSpec(spec TypeSpec) TypeSpec {
+	if spec.Kind == TypeSpecMap && spec.ValType != nil {
+		return *spec.ValType
+	}
+	return TypeSpec{}
+}
+
+// ApplyPatchAs applies p to v as ApplyPatchWithSchema does, on behalf of
+// principal. Before each op it checks the @acl annotations in schema: the
+// fields on the op's path and, for a value it writes or replaces, every
+// field set inside that value, read as the type the schema declares there.
+// An op the principal may not apply fails with an error wrapping
+// ErrAccessDenied.
+func ApplyPatchAs(v *GValue, p *Patch, schema *Schema, principal *Principal) (*GValue, error) {
+	if v == nil {
+		return nil, fmt.Errorf("cannot apply patch to nil value")
+	}
+	if err := resolvePatchFIDs(v, p, schema); err != nil {
+		return nil, err
+	}
+	c := &aclChecker{schema: schema, principal: principal}
+	if rootType := patchRootType(v, p); rootType != "" {
+		c.root = TypeSpec{Kind: TypeSpecRef, Name: rootType}
+	}
+	return applyPatch(v, p, c.checkOp)
+}
+
+type aclChecker struct {
+	schema    *Schema
+	principal *Principal
+	root      TypeSpec // Type of the document
+}
+
+// checkOp checks op against doc, the document it applies to.
+func (c *aclChecker) checkOp(doc *GValue, op *PatchOp) error {
+	if op.Op == OpTest {
+		old, spec, err := c.path(doc, op.Path, "read")
+		if err != nil {
+			return err
+		}
+		return c.within(old, spec, "read")
+	}
+
+	written := op.Value
+	if op.Op == OpMove || op.Op == OpCopy {
+		access := "read"
+		if op.Op == OpMove {
+			access = "write"
+		}
+		src, spec, err := c.path(doc, op.From, access)
+		if err != nil {
+			return err
+		}
+		if err := c.within(src, spec, access); err != nil {
+			return err
+		}
+		written = src
+	}
+
+	old, spec, err := c.path(doc, op.Path, "write")
+	if err != nil {
+		return err
+	}
+	if opReplaces(op) {
+		if err := c.within(old, spec, "write"); err != nil {
+			return err
+		}
+	}
+	if op.Op == OpAppend && spec.Kind == TypeSpecList {
+		spec = elemSpec(spec) // An item of the list
+	}
+	return c.within(written, spec, "write")
+}
+
+// opReplaces reports whether op drops the value at its path, rather than
+// adding to it or changing it in place.
+func opReplaces(op *PatchOp) bool {
+	switch op.Op {
+	case OpSet, OpDelete, OpCustom:
+		return true
+	case OpMove, OpCopy:
+		return len(op.Path) == 0 || op.Path[len(op.Path)-1].Kind != PathSegListIdx
+	}
+	return false
+}
+
+// path checks the fields on path in doc and returns the value at path, or
+// nil if there is none, and the type the schema declares there.
+func (c *aclChecker) path(doc *GValue, path []PathSeg, access string) (*GValue, TypeSpec, error) {
+	cur, spec := doc, c.root
+	for i, seg := range path {
+		if cur != nil {
+			cur.force()
+			spec = variantSpec(c.schema, cur, spec)
+		}
+		switch seg.Kind {
+		case PathSegListIdx:
+			spec = elemSpec(spec)
+		default:
+			if td := structTypeFor(c.schema, cur, spec); td != nil {
+				fd := td.FieldByKey(pathSegKey(seg))
+				if err := c.allow(td, fd, access); err != nil {
+					return nil, TypeSpec{}, err
+				}
+				spec = TypeSpec{}
+				if fd != nil {
+					spec = fd.Type
+				}
+			} else {
+				spec = valueSpec(spec)
+			}
+		}
+		if cur != nil {
+			cur = valueAtPath(cur, path[i:i+1])
+		}
+	}
+	return cur, spec, nil
+}
+
+// within checks every field set inside v, a value where the schema expects
+// spec.
+func (c *aclChecker) within(v *GValue, spec TypeSpec, access string) error {
+	if v == nil {
+		return nil
+	}
+	v.force()
+	spec = variantSpec(c.schema, v, spec)
+
+	entries := v.mapVal
+	if v.typ == TypeStruct {
+		entries = v.structVal.Fields
+	}
+	td := structTypeFor(c.schema, v, spec)
+	switch {
+	case td != nil && (v.typ == TypeStruct || v.typ == TypeMap):
+		for _, e := range entries {
+			fd := td.FieldByKey(e.Key)
+			if fd == nil {
+				if err := c.within(e.Value, TypeSpec{}, access); err != nil {
+					return err
+				}
+				continue
+			}
+			if e.Value.IsNull() {
+				continue
+			}
+			if err := c.allow(td, fd, access); err != nil {
+				return err
+			}
+			if err := c.within(e.Value, fd.Type, access); err != nil {
+				return err
+			}
+		}
+	case v.typ == TypeList:
+		for _, item := range v.listVal {
+			if err := c.within(item, elemSpec(spec), access); err != nil {
+				return err
+			}
+		}
+	case v.typ == TypeMap || v.typ == TypeStruct:
+		for _, e := range entries {
+			if err := c.within(e.Value, valueSpec(spec), access); err != nil {
+				return err
+			}
+		}
+	case v.typ == TypeSum:
+		return c.within(v.sumVal.Value, spec, access)
+	}
+	return nil
+}
+
+// allow checks access to the field fd of td; a nil fd is not declared and
+// not protected.
+func (c *aclChecker) allow(td *TypeDef, fd *FieldDef, access string) error {
+	if fd == nil || fd.ACL == nil {
+		return nil
+	}
+	roles := fd.ACL.Write
+	if access == "read" {
+		roles = fd.ACL.Read
+	}
+	if c.principal.may(roles) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s may not %s %s.%s", ErrAccessDenied, c.principal, access, td.Name, fd.Name)
+}
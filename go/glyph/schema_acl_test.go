@@ -0,0 +1,191 @@
+package glyph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const taskACLSchema = `@schema{
+	Task struct{
+		title: str
+		budget: float [optional] @acl(write=lead)
+		review: str [optional] @acl(read=[lead, reviewer], write=reviewer)
+		owner: Person [optional]
+		subtasks: list<Task> [optional]
+		lead: Task [optional]
+		byName: map<str, Person> [optional]
+	}
+	Person struct{
+		name: str
+		salary: float [optional] @acl(read=[], write=hr)
+	}
+}`
+
+var (
+	worker   = &Principal{Name: "worker-1", Roles: []string{"worker"}}
+	lead     = &Principal{Name: "lead-1", Roles: []string{"worker", "lead"}}
+	reviewer = &Principal{Roles: []string{"reviewer"}}
+)
+
+func taskACLDoc(t *testing.T, schema *Schema) *GValue {
+	t.Helper()
+	res, err := ParseWithOptions(`Task{title=Ship budget=10.5 review="needs tests" owner=Person{name=Ann salary=100.0} subtasks=[Task{title=Docs review=ok}]}`, ParseOptions{Schema: schema})
+	if err != nil {
+		t.Fatalf("parse doc: %v", err)
+	}
+	return res.Value
+}
+
+func TestSchemaACL_Parse(t *testing.T) {
+	schema, err := ParseSchema(taskACLSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	if acl := schema.GetField("Task", "budget").ACL; acl == nil || acl.Read != nil || strings.Join(acl.Write, ",") != "lead" {
+		t.Errorf("budget ACL = %v", acl)
+	}
+	if acl := schema.GetField("Person", "salary").ACL; acl == nil || acl.Read == nil || len(acl.Read) != 0 {
+		t.Errorf("salary ACL = %v, want read=[]", acl)
+	}
+
+	text := schema.Canonical()
+	for _, want := range []string{
+		`budget: float @acl(write=lead) [optional]`,
+		`review: str @acl(read=[lead, reviewer], write=reviewer) [optional]`,
+		`salary: float @acl(read=[], write=hr) [optional]`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("canonical text missing %q:\n%s", want, text)
+		}
+	}
+	again, err := ParseSchema(text)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, text)
+	}
+	if again.Canonical() != text || again.Hash != schema.Hash {
+		t.Errorf("round-trip changed the schema:\n%s", again.Canonical())
+	}
+
+	// The policy is part of the schema's identity.
+	open, err := ParseSchema(strings.ReplaceAll(taskACLSchema, " @acl(write=lead)", ""))
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	if open.Hash == schema.Hash {
+		t.Error("removing an @acl did not change the hash")
+	}
+}
+
+func TestSchemaACL_ParseErrors(t *testing.T) {
+	for _, src := range []string{
+		`@schema{ A struct{ x: int @acl } }`,
+		`@schema{ A struct{ x: int @acl() } }`,
+		`@schema{ A struct{ x: int @acl(owner=a) } }`,
+		`@schema{ A struct{ x: int @acl(read) } }`,
+		`@schema{ A struct{ x: int @acl(read=[a, "b"]) } }`,
+		`@schema{ A struct{ x: int @acl(write=a } }`,
+	} {
+		if _, err := ParseSchema(src); err == nil {
+			t.Errorf("ParseSchema(%s): expected error", src)
+		}
+	}
+}
+
+func TestStripUnreadable(t *testing.T) {
+	schema, err := ParseSchema(taskACLSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	doc := taskACLDoc(t, schema)
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      string
+	}{
+		{"worker", worker, `Task{budget=10.5 owner=Person{name=Ann} subtasks=[Task{title=Docs}] title=Ship}`},
+		{"lead", lead, `Task{budget=10.5 owner=Person{name=Ann} review="needs tests" subtasks=[Task{review=ok title=Docs}] title=Ship}`},
+		{"anonymous", nil, `Task{budget=10.5 owner=Person{name=Ann} subtasks=[Task{title=Docs}] title=Ship}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Emit(StripUnreadable(schema, doc, tt.principal)); got != tt.want {
+				t.Errorf("StripUnreadable:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	// Maps where the schema declares a struct lose the same fields.
+	untyped, err := ParseWithOptions(`Task{title=Ship lead={title=t review=secret} byName={ann:{name=Ann salary=100.0}}}`, ParseOptions{})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, want := Emit(StripUnreadable(schema, untyped.Value, worker)), `Task{byName={ann:{name:Ann}} lead={title:t} title=Ship}`; got != want {
+		t.Errorf("StripUnreadable of maps:\n got %s\nwant %s", got, want)
+	}
+
+	opts := DefaultEmitOptions()
+	opts.Schema, opts.Principal = schema, worker
+	if got := EmitWithOptions(doc, opts); strings.Contains(got, "review") || strings.Contains(got, "salary") {
+		t.Errorf("EmitOptions.Principal did not strip: %s", got)
+	}
+	if got := Emit(doc); !strings.Contains(got, "salary") {
+		t.Errorf("doc modified: %s", got)
+	}
+}
+
+func TestApplyPatchAs(t *testing.T) {
+	schema, err := ParseSchema(taskACLSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		patch     *Patch
+		denied    string // "" if allowed, else the field denied
+	}{
+		{"unprotected field", worker, NewPatch(RefID{}, "").Set("title", Str("Ship it")), ""},
+		{"write protected", worker, NewPatch(RefID{}, "").Delta("budget", 5), "write Task.budget"},
+		{"write allowed", lead, NewPatch(RefID{}, "").Delta("budget", 5), ""},
+		{"delete protected", worker, NewPatch(RefID{}, "").Delete("budget"), "write Task.budget"},
+		{"inside a list", lead, NewPatch(RefID{}, "").Set("subtasks[0].review", Str("lgtm")), "write Task.review"},
+		{"inside a list allowed", reviewer, NewPatch(RefID{}, "").Set("subtasks[0].review", Str("lgtm")), ""},
+		{"replacing a value holding it", lead, NewPatch(RefID{}, "").Set("owner", Struct("Person", MapEntry{Key: "name", Value: Str("Bo")})), "write Person.salary"},
+		{"writing a value holding it", worker, NewPatch(RefID{}, "").Append("subtasks", Struct("Task", MapEntry{Key: "title", Value: Str("x")}, MapEntry{Key: "budget", Value: Float(1)})), "write Task.budget"},
+		{"appending without it", worker, NewPatch(RefID{}, "").Append("subtasks", Struct("Task", MapEntry{Key: "title", Value: Str("x")})), ""},
+		{"test reads", worker, testOpPatch("review", Str("needs tests")), "read Task.review"},
+		{"copy reads", worker, NewPatch(RefID{}, "").Copy("review", "title"), "read Task.review"},
+		{"move writes the source", reviewer, NewPatch(RefID{}, "").Move("budget", "title"), "write Task.budget"},
+		{"anonymous", nil, NewPatch(RefID{}, "").Set("budget", Float(1)), "write Task.budget"},
+		{"map as a struct", worker, NewPatch(RefID{}, "").Set("lead", Map(MapEntry{Key: "title", Value: Str("t")}, MapEntry{Key: "budget", Value: Float(999)})), "write Task.budget"},
+		{"map as a struct allowed", worker, NewPatch(RefID{}, "").Set("lead", Map(MapEntry{Key: "title", Value: Str("t")})), ""},
+		{"path into a map as a struct", worker, NewPatch(RefID{}, "").Set("lead", Map(MapEntry{Key: "title", Value: Str("t")})).Set("lead.budget", Float(999)), "write Task.budget"},
+		{"map values", lead, NewPatch(RefID{}, "").Set(`byName["bo"]`, Map(MapEntry{Key: "name", Value: Str("Bo")}, MapEntry{Key: "salary", Value: Float(1)})), "write Person.salary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := taskACLDoc(t, schema)
+			before := Emit(doc)
+			_, err := ApplyPatchAs(doc, tt.patch, schema, tt.principal)
+			if tt.denied == "" {
+				if err != nil {
+					t.Fatalf("ApplyPatchAs: %v", err)
+				}
+			} else if !errors.Is(err, ErrAccessDenied) || !strings.Contains(err.Error(), tt.denied) {
+				t.Fatalf("expected access denied to %s, got %v", tt.denied, err)
+			}
+			if Emit(doc) != before {
+				t.Errorf("doc modified")
+			}
+		})
+	}
+}
+
+func testOpPatch(path string, want *GValue) *Patch {
+	p := NewPatch(RefID{}, "")
+	p.Ops = append(p.Ops, &PatchOp{Op: OpTest, Path: parsePathToSegs(path), Value: want})
+	return p
+}
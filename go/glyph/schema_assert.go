@@ -0,0 +1,332 @@
+package glyph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Cross-Field Assertions
+// ============================================================
+//
+// A struct type may declare rules relating its fields, which per-field
+// constraints cannot express:
+//
+//	Booking struct{
+//	    start: time
+//	    end: time
+//	    count: int
+//	    items: list<str>
+//	    @assert(end >= start)
+//	    @assert(len(items) == count)
+//	}
+//
+// Validate checks every assertion of a struct after its fields. An
+// assertion naming a field that is absent or null is skipped; the missing
+// field itself is reported by the usual required-field check.
+
+// AssertOp is the comparison of an Assert.
+type AssertOp uint8
+
+const (
+	AssertEq AssertOp = iota // ==
+	AssertNe                 // !=
+	AssertLt                 // <
+	AssertLe                 // <=
+	AssertGt                 // >
+	AssertGe                 // >=
+)
+
+var assertOpText = [...]string{"==", "!=", "<", "<=", ">", ">="}
+
+// String returns the operator as written in schema text.
+func (op AssertOp) String() string {
+	if int(op) < len(assertOpText) {
+		return assertOpText[op]
+	}
+	return "?"
+}
+
+// AssertOperand is one side of an Assert: a field, the length of a field,
+// or a literal number or string.
+type AssertOperand struct {
+	Field string  // Field name or wire key; "" for a literal
+	Len   bool    // len(Field): length of a string, bytes, list, or map
+	Value *GValue // The literal when Field is ""
+}
+
+// String returns the operand as written in schema text.
+func (o AssertOperand) String() string {
+	switch {
+	case o.Len:
+		return "len(" + o.Field + ")"
+	case o.Field != "":
+		return o.Field
+	case o.Value != nil && o.Value.typ == TypeStr:
+		return strconv.Quote(o.Value.strVal)
+	case o.Value != nil:
+		return Emit(o.Value)
+	default:
+		return "null"
+	}
+}
+
+// Assert is a cross-field rule of a struct type, such as end >= start or
+// len(items) == count.
+type Assert struct {
+	Left  AssertOperand
+	Op    AssertOp
+	Right AssertOperand
+}
+
+// String returns the assertion as written inside @assert(...).
+func (a *Assert) String() string {
+	return a.Left.String() + " " + a.Op.String() + " " + a.Right.String()
+}
+
+// ParseAssert parses an assertion such as "end >= start" or
+// "len(items) == count".
+func ParseAssert(expr string) (*Assert, error) {
+	tokens, err := newSchemaLexer(expr).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	p := &schemaParser{stream: NewTokenStream(tokens)}
+	a, err := p.parseAssertExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.stream.Peek(); tok.Type != TokenEOF {
+		return nil, p.errorf(tok, "unexpected %s after assertion", describeToken(tok))
+	}
+	return a, nil
+}
+
+// MustParseAssert is like ParseAssert but panics if expr does not parse.
+func MustParseAssert(expr string) *Assert {
+	a, err := ParseAssert(expr)
+	if err != nil {
+		panic(fmt.Sprintf("glyph: ParseAssert(%q): %v", expr, err))
+	}
+	return a
+}
+
+// parseAssert parses @assert(expr) in a struct body; the @ is current.
+func (p *schemaParser) parseAssert() (*Assert, error) {
+	p.stream.Advance() // consume @
+	p.stream.Advance() // consume assert
+	if _, err := p.expect(TokenLParen, "( after @assert"); err != nil {
+		return nil, err
+	}
+	a, err := p.parseAssertExpr()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(TokenRParen, ") after @assert expression"); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// isAssertStart reports whether the stream is at @assert.
+func (p *schemaParser) isAssertStart() bool {
+	next := p.stream.PeekN(1)
+	return p.stream.Peek().Type == TokenAt && next.Type == TokenIdent && next.Value == "assert"
+}
+
+func (p *schemaParser) parseAssertExpr() (*Assert, error) {
+	start := p.stream.Peek()
+	left, err := p.parseAssertOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, err := p.parseAssertOp()
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.parseAssertOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.Field == "" && right.Field == "" {
+		return nil, p.errorf(start, "assertion %s %s %s compares no fields", left, op, right)
+	}
+	return &Assert{Left: left, Op: op, Right: right}, nil
+}
+
+func (p *schemaParser) parseAssertOp() (AssertOp, error) {
+	tok := p.stream.Advance()
+	switch tok.Type {
+	case TokenNotEq:
+		return AssertNe, nil
+	case TokenEq:
+		if tok.Value == "=" && p.stream.Peek().Type == TokenEq && p.stream.Peek().Value == "=" {
+			p.stream.Advance()
+			return AssertEq, nil
+		}
+	case TokenLT:
+		if p.stream.Peek().Type == TokenEq {
+			p.stream.Advance()
+			return AssertLe, nil
+		}
+		return AssertLt, nil
+	case TokenGT:
+		if p.stream.Peek().Type == TokenEq {
+			p.stream.Advance()
+			return AssertGe, nil
+		}
+		return AssertGt, nil
+	}
+	return 0, p.errorf(tok, "expected comparison (== != < <= > >=), got %s", describeToken(tok))
+}
+
+func (p *schemaParser) parseAssertOperand() (AssertOperand, error) {
+	tok := p.stream.Advance()
+	switch tok.Type {
+	case TokenIdent, TokenBareStr:
+		if tok.Value == "len" && p.stream.Match(TokenLParen) {
+			field, err := p.expect(TokenIdent, "field name in len(...)")
+			if err != nil {
+				return AssertOperand{}, err
+			}
+			if _, err := p.expect(TokenRParen, ") after len("+field.Value); err != nil {
+				return AssertOperand{}, err
+			}
+			return AssertOperand{Field: field.Value, Len: true}, nil
+		}
+		return AssertOperand{Field: tok.Value}, nil
+	case TokenInt:
+		n, err := strconv.ParseInt(tok.Value, 10, 64)
+		if err != nil {
+			return AssertOperand{}, p.errorf(tok, "invalid number %q in assertion", tok.Value)
+		}
+		return AssertOperand{Value: Int(n)}, nil
+	case TokenFloat:
+		f, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			return AssertOperand{}, p.errorf(tok, "invalid number %q in assertion", tok.Value)
+		}
+		return AssertOperand{Value: Float(f)}, nil
+	case TokenString:
+		return AssertOperand{Value: Str(tok.Value)}, nil
+	}
+	return AssertOperand{}, p.errorf(tok, "expected field, len(field), number, or string in assertion, got %s", describeToken(tok))
+}
+
+// structFieldValue returns the value of the field name (a field name or wire
+// key of td) among the fields of a struct value, or nil if it is absent.
+func structFieldValue(td *TypeDef, fieldValues map[string]*GValue, name string) *GValue {
+	if val, ok := fieldValues[name]; ok {
+		return val
+	}
+	if fd := td.FieldByKey(name); fd != nil {
+		if val, ok := fieldValues[fd.Name]; ok {
+			return val
+		}
+		if val, ok := fieldValues[fd.WireKey]; ok && fd.WireKey != "" {
+			return val
+		}
+	}
+	return nil
+}
+
+// validateAsserts checks the assertions of td against the fields of a
+// struct value, keyed by name or wire key.
+func (v *Validator) validateAsserts(td *TypeDef, fieldValues map[string]*GValue, path string) {
+	operand := func(o AssertOperand) (*GValue, error) {
+		if o.Field == "" {
+			return o.Value, nil
+		}
+		val := structFieldValue(td, fieldValues, o.Field)
+		if val == nil || val.IsNull() || !o.Len {
+			return val, nil
+		}
+		switch val.typ {
+		case TypeStr, TypeBytes, TypeList, TypeMap:
+			return Int(int64(valueLength(val))), nil
+		}
+		return nil, fmt.Errorf("len(%s) of %s", o.Field, val.typ)
+	}
+
+	for _, a := range td.Struct.Asserts {
+		left, err := operand(a.Left)
+		if err == nil && left != nil && !left.IsNull() {
+			var right *GValue
+			if right, err = operand(a.Right); err == nil && right != nil && !right.IsNull() {
+				var holds bool
+				if holds, err = a.Op.compare(left, right); err == nil && !holds {
+					v.addError(path, "assert_failed", "assertion %s failed: %s", a, assertDetail(a, left, right))
+				}
+			}
+		}
+		if err != nil {
+			v.addError(path, "assert_type", "assertion %s: %v", a, err)
+		}
+	}
+}
+
+// assertDetail shows the field values an assertion compared.
+func assertDetail(a *Assert, left, right *GValue) string {
+	var parts []string
+	if a.Left.Field != "" {
+		parts = append(parts, a.Left.String()+"="+Emit(left))
+	}
+	if a.Right.Field != "" {
+		parts = append(parts, a.Right.String()+"="+Emit(right))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compare applies op to two numbers, strings, times, or (for == and !=)
+// any two values.
+func (op AssertOp) compare(left, right *GValue) (bool, error) {
+	left.force()
+	right.force()
+
+	var cmp int
+	if left.typ == TypeInt && right.typ == TypeInt {
+		switch {
+		case left.intVal < right.intVal:
+			cmp = -1
+		case left.intVal > right.intVal:
+			cmp = 1
+		}
+	} else if ln, ok := left.Number(); ok {
+		rn, ok := right.Number()
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s with %s", left.typ, right.typ)
+		}
+		switch {
+		case ln < rn:
+			cmp = -1
+		case ln > rn:
+			cmp = 1
+		}
+	} else if left.typ == TypeStr && right.typ == TypeStr {
+		cmp = strings.Compare(left.strVal, right.strVal)
+	} else if left.typ == TypeTime && right.typ == TypeTime {
+		cmp = left.timeVal().Compare(right.timeVal())
+	} else if op == AssertEq || op == AssertNe {
+		if Emit(left) != Emit(right) {
+			cmp = 1
+		}
+	} else {
+		return false, fmt.Errorf("cannot order %s and %s", left.typ, right.typ)
+	}
+
+	switch op {
+	case AssertEq:
+		return cmp == 0, nil
+	case AssertNe:
+		return cmp != 0, nil
+	case AssertLt:
+		return cmp < 0, nil
+	case AssertLe:
+		return cmp <= 0, nil
+	case AssertGt:
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
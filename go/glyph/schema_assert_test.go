@@ -0,0 +1,146 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const bookingSchema = `@schema{
+	Booking:v1 struct{
+		start: time
+		end: time @k(e)
+		count: int
+		items: list<str>
+		note: str [optional]
+		@assert(end >= start)
+		@assert(len(items) == count)
+		@assert(count != 0)
+		@assert(note != "todo")
+	}
+}`
+
+func makeBooking(start, end string, count int64, items ...string) *GValue {
+	parse := func(s string) *GValue {
+		t, _ := time.Parse(time.RFC3339, s)
+		return Time(t)
+	}
+	list := make([]*GValue, len(items))
+	for i, it := range items {
+		list[i] = Str(it)
+	}
+	return Struct("Booking",
+		MapEntry{Key: "start", Value: parse(start)},
+		MapEntry{Key: "e", Value: parse(end)},
+		MapEntry{Key: "count", Value: Int(count)},
+		MapEntry{Key: "items", Value: List(list...)},
+	)
+}
+
+func TestSchemaAssert_Validate(t *testing.T) {
+	schema, err := ParseSchema(bookingSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	td := schema.GetType("Booking")
+	if len(td.Struct.Asserts) != 4 || td.Struct.Fields[1].WireKey != "e" {
+		t.Fatalf("asserts %v, fields %d", td.Struct.Asserts, len(td.Struct.Fields))
+	}
+
+	ok := makeBooking("2025-01-01T10:00:00Z", "2025-01-02T10:00:00Z", 2, "a", "b")
+	if r := ValidateAs(ok, schema, "Booking"); !r.Valid {
+		t.Fatalf("valid booking rejected: %v", r.Errors)
+	}
+
+	bad := makeBooking("2025-01-02T10:00:00Z", "2025-01-01T10:00:00Z", 3, "a", "b")
+	r := ValidateAs(bad, schema, "Booking")
+	if len(r.Errors) != 2 {
+		t.Fatalf("errors = %v", r.Errors)
+	}
+	for i, want := range []string{
+		"assertion end >= start failed: end=2025-01-01T10:00:00Z, start=2025-01-02T10:00:00Z",
+		"assertion len(items) == count failed: len(items)=2, count=3",
+	} {
+		if r.Errors[i].Code != "assert_failed" || r.Errors[i].Message != want {
+			t.Errorf("error %d = %s %q, want %q", i, r.Errors[i].Code, r.Errors[i].Message, want)
+		}
+	}
+
+	// A literal operand, and an optional field that is absent, then present.
+	zero := makeBooking("2025-01-01T10:00:00Z", "2025-01-01T10:00:00Z", 0)
+	if r := ValidateAs(zero, schema, "Booking"); len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, "count != 0") {
+		t.Errorf("count != 0: %v", r.Errors)
+	}
+	ok.structVal.Fields = append(ok.structVal.Fields, MapEntry{Key: "note", Value: Str("todo")})
+	if r := ValidateAs(ok, schema, "Booking"); len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, `note != "todo"`) {
+		t.Errorf(`note != "todo": %v`, r.Errors)
+	}
+
+	// Operands that cannot be compared.
+	mixed := makeBooking("2025-01-01T10:00:00Z", "2025-01-02T10:00:00Z", 0, "a")
+	mixed.structVal.Fields[2].Value = Str("one")
+	r = ValidateAs(mixed, schema, "Booking")
+	if len(r.Errors) == 0 || r.Errors[len(r.Errors)-1].Code != "assert_type" {
+		t.Errorf("mixed types: %v", r.Errors)
+	}
+}
+
+func TestSchemaAssert_CanonicalRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(bookingSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := schema.Canonical()
+	if !strings.Contains(text, "    @assert(len(items) == count)\n") {
+		t.Fatalf("canonical text:\n%s", text)
+	}
+	again, err := ParseSchema(text)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, text)
+	}
+	if again.Canonical() != text || again.Hash != schema.Hash {
+		t.Errorf("round-trip changed the schema:\n%s", again.Canonical())
+	}
+
+	without, err := ParseSchema(strings.ReplaceAll(bookingSchema, "@assert(count != 0)", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if without.Hash == schema.Hash {
+		t.Error("removing an assertion did not change the schema hash")
+	}
+}
+
+func TestSchemaAssert_Builder(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Range", "v1",
+			Field("lo", PrimitiveType("float")),
+			Field("hi", PrimitiveType("float")),
+		).
+		WithAssert("Range", "lo <= hi", "hi < 100.5").
+		Build()
+
+	v := Struct("Range", MapEntry{Key: "lo", Value: Float(5)}, MapEntry{Key: "hi", Value: Int(200)})
+	r := ValidateAs(v, schema, "Range")
+	if len(r.Errors) != 1 || r.Errors[0].Message != "assertion hi < 100.5 failed: hi=200" {
+		t.Errorf("errors = %v", r.Errors)
+	}
+}
+
+func TestParseAssert_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"end = start",
+		"end >=",
+		"1 < 2",
+		"len(3) > 1",
+		"a < b c",
+		"a ! b",
+	} {
+		if _, err := ParseAssert(expr); err == nil {
+			t.Errorf("ParseAssert(%q): expected error", expr)
+		}
+	}
+	if _, err := ParseSchema(`@schema{ A struct{ x: int @assert(x) } }`); err == nil {
+		t.Error("expected error for incomplete @assert in schema")
+	}
+}
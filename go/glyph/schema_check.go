@@ -31,6 +31,11 @@ func (e SchemaError) Error() string {
 //  8. Map key types must be str, int, or id (unsupported_map_key_type).
 //  9. If a required field has a Default value, emit a warning-level code
 //     required_field_has_default (flagged fork: warn, not error).
+//  10. @assert, @oneof, requiredIf, and @deprecated(use=...) name declared
+//     fields (unknown_field_reference); requiredIf is on an optional field
+//     (required_if_not_optional).
+//  11. @deprecated(use=...) on a type names a declared type
+//     (unknown_type_reference).
 func (s *Schema) Check() []SchemaError {
 	var errs []SchemaError
 
@@ -44,6 +49,15 @@ func (s *Schema) Check() []SchemaError {
 			})
 		}
 
+		// Rule 11: a deprecated type's replacement is declared
+		if d := td.Deprecated; d != nil && d.Use != "" && s.GetType(d.Use) == nil {
+			errs = append(errs, SchemaError{
+				TypeName: td.Name,
+				Code:     "unknown_type_reference",
+				Message:  fmt.Sprintf("%s on type %s refers to undeclared type %q", d, td.Name, d.Use),
+			})
+		}
+
 		if td.Kind != TypeDefStruct || td.Struct == nil {
 			continue
 		}
@@ -152,11 +166,62 @@ func (s *Schema) Check() []SchemaError {
 				}
 			}
 		}
+
+		// Rule 10: cross-field rules name declared fields
+		checkFieldReferences(td, &errs)
 	}
 
 	return errs
 }
 
+// checkFieldReferences reports @assert, @oneof, requiredIf, and
+// @deprecated(use=...) rules of td that name undeclared fields, and
+// requiredIf on required fields.
+func checkFieldReferences(td *TypeDef, errs *[]SchemaError) {
+	check := func(fieldName, rule, ref string) {
+		if td.FieldByKey(ref) == nil {
+			*errs = append(*errs, SchemaError{
+				TypeName:  td.Name,
+				FieldName: fieldName,
+				Code:      "unknown_field_reference",
+				Message:   fmt.Sprintf("%s refers to undeclared field %q in struct %s", rule, ref, td.Name),
+			})
+		}
+	}
+	for _, a := range td.Struct.Asserts {
+		for _, o := range []AssertOperand{a.Left, a.Right} {
+			if o.Field != "" {
+				check("", "@assert("+a.String()+")", o.Field)
+			}
+		}
+	}
+	for _, group := range td.Struct.OneOf {
+		for _, name := range group {
+			check("", "@oneof", name)
+		}
+	}
+	for _, fd := range td.Struct.Fields {
+		if fd.Deprecated != nil && fd.Deprecated.Use != "" {
+			check(fd.Name, fd.Deprecated.String(), fd.Deprecated.Use)
+		}
+		for _, c := range fd.Constraints {
+			if c.Kind != ConstraintRequiredIf {
+				continue
+			}
+			r := c.Value.(RequiredIf)
+			check(fd.Name, r.String(), r.Field)
+			if !fd.Optional {
+				*errs = append(*errs, SchemaError{
+					TypeName:  td.Name,
+					FieldName: fd.Name,
+					Code:      "required_if_not_optional",
+					Message:   fmt.Sprintf("%s has no effect on required field %q in struct %s", r, fd.Name, td.Name),
+				})
+			}
+		}
+	}
+}
+
 // checkMapKeyType recursively checks that any map in the TypeSpec has a valid key type.
 func checkMapKeyType(typeName, fieldName string, ts TypeSpec, errs *[]SchemaError) {
 	switch ts.Kind {
@@ -223,6 +288,15 @@ func checkConstraintType(typeName, fieldName string, c Constraint, ts TypeSpec)
 				Message:   fmt.Sprintf("constraint enum requires str field, got %s", ts.String()),
 			}
 		}
+	case ConstraintPrefix, ConstraintIDFormat:
+		if kind != TypeSpecID {
+			return &SchemaError{
+				TypeName:  typeName,
+				FieldName: fieldName,
+				Code:      "constraint_type_mismatch",
+				Message:   fmt.Sprintf("constraint %s requires id field, got %s", c.Kind.constraintName(), ts.String()),
+			}
+		}
 	case ConstraintUnique:
 		if kind != TypeSpecList {
 			return &SchemaError{
@@ -261,6 +335,12 @@ func (k ConstraintKind) constraintName() string {
 		return "unique"
 	case ConstraintOptional:
 		return "optional"
+	case ConstraintPrefix:
+		return "prefix"
+	case ConstraintIDFormat:
+		return "idformat"
+	case ConstraintRequiredIf:
+		return "requiredIf"
 	default:
 		return "unknown"
 	}
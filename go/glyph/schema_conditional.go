@@ -0,0 +1,163 @@
+package glyph
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Conditional Fields
+// ============================================================
+//
+// Tool argument schemas often make a field required only in some cases,
+// or accept one of several alternative fields:
+//
+//	Fetch struct{
+//	    source: str [enum=[web file]]
+//	    url: str [optional] [requiredIf(source="web")]
+//	    path: str [optional] [requiredIf(source="file")]
+//	    text: str [optional]
+//	    query: str [optional]
+//	    @oneof(text | query)
+//	}
+//
+// Validate reports a requiredIf field that is absent or null while its
+// condition holds (code required_if), and a @oneof group with no field or
+// more than one field set (code oneof). Null counts as not set.
+
+// RequiredIf is the condition of a requiredIf constraint: the field is
+// required when Field equals Value.
+type RequiredIf struct {
+	Field string
+	Value *GValue
+}
+
+// String returns the constraint as written in schema text.
+func (r RequiredIf) String() string {
+	return "requiredIf(" + r.Field + "=" + ruleLiteral(r.Value) + ")"
+}
+
+// ruleLiteral writes a literal of a schema rule; strings are always quoted
+// so that they cannot read back as a field name or keyword.
+func ruleLiteral(v *GValue) string {
+	if v != nil && v.typ == TypeStr {
+		return strconv.Quote(v.strVal)
+	}
+	return Emit(v)
+}
+
+// parseRequiredIf parses (field=value) after requiredIf.
+func (p *schemaParser) parseRequiredIf() (RequiredIf, error) {
+	if _, err := p.expect(TokenLParen, "( after requiredIf"); err != nil {
+		return RequiredIf{}, err
+	}
+	field, err := p.expect(TokenIdent, "field name in requiredIf(...)")
+	if err != nil {
+		return RequiredIf{}, err
+	}
+	if _, err := p.expect(TokenEq, "= after requiredIf("+field.Value); err != nil {
+		return RequiredIf{}, err
+	}
+
+	var value *GValue
+	tok := p.stream.Advance()
+	switch tok.Type {
+	case TokenIdent, TokenBareStr, TokenString:
+		value = Str(tok.Value)
+	case TokenInt:
+		n, err := strconv.ParseInt(tok.Value, 10, 64)
+		if err != nil {
+			return RequiredIf{}, p.errorf(tok, "invalid number %q in requiredIf", tok.Value)
+		}
+		value = Int(n)
+	case TokenFloat:
+		f, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			return RequiredIf{}, p.errorf(tok, "invalid number %q in requiredIf", tok.Value)
+		}
+		value = Float(f)
+	case TokenTrue:
+		value = Bool(true)
+	case TokenFalse:
+		value = Bool(false)
+	default:
+		return RequiredIf{}, p.errorf(tok, "expected value in requiredIf(%s=...), got %s", field.Value, describeToken(tok))
+	}
+
+	if _, err := p.expect(TokenRParen, ") after requiredIf condition"); err != nil {
+		return RequiredIf{}, err
+	}
+	return RequiredIf{Field: field.Value, Value: value}, nil
+}
+
+// isOneOfStart reports whether the stream is at @oneof.
+func (p *schemaParser) isOneOfStart() bool {
+	next := p.stream.PeekN(1)
+	return p.stream.Peek().Type == TokenAt && next.Type == TokenIdent && next.Value == "oneof"
+}
+
+// parseOneOf parses @oneof(a | b | ...) in a struct body; the @ is current.
+func (p *schemaParser) parseOneOf() ([]string, error) {
+	at := p.stream.Advance() // consume @
+	p.stream.Advance()       // consume oneof
+	if _, err := p.expect(TokenLParen, "( after @oneof"); err != nil {
+		return nil, err
+	}
+	var group []string
+	for {
+		field, err := p.expect(TokenIdent, "field name in @oneof(...)")
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, field.Value)
+		if !p.stream.Match(TokenPipe) {
+			break
+		}
+	}
+	if _, err := p.expect(TokenRParen, ") after @oneof fields"); err != nil {
+		return nil, err
+	}
+	if len(group) < 2 {
+		return nil, p.errorf(at, "@oneof needs at least two fields")
+	}
+	return group, nil
+}
+
+// requiredIfMet returns the first requiredIf condition of fd that holds for
+// a struct value's fields, as text for an error message.
+func requiredIfMet(td *TypeDef, fd *FieldDef, fieldValues map[string]*GValue) (string, bool) {
+	for _, c := range fd.Constraints {
+		if c.Kind != ConstraintRequiredIf {
+			continue
+		}
+		r := c.Value.(RequiredIf)
+		val := structFieldValue(td, fieldValues, r.Field)
+		if val.IsNull() {
+			continue
+		}
+		if eq, err := AssertEq.compare(val, r.Value); err == nil && eq {
+			return r.Field + "=" + ruleLiteral(r.Value), true
+		}
+	}
+	return "", false
+}
+
+// validateOneOf checks that exactly one field of each @oneof group of td is
+// set.
+func (v *Validator) validateOneOf(td *TypeDef, fieldValues map[string]*GValue, path string) {
+	for _, group := range td.Struct.OneOf {
+		var set []string
+		for _, name := range group {
+			if !structFieldValue(td, fieldValues, name).IsNull() {
+				set = append(set, name)
+			}
+		}
+		switch len(set) {
+		case 1:
+		case 0:
+			v.addError(path, "oneof", "exactly one of %s must be set, got none", strings.Join(group, ", "))
+		default:
+			v.addError(path, "oneof", "exactly one of %s must be set, got %s", strings.Join(group, ", "), strings.Join(set, ", "))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+const fetchSchema = `@schema{
+	Fetch:v1 struct{
+		source: str [enum=[web file]]
+		url: str [optional] [requiredIf(source="web")]
+		path: str [optional] [requiredIf(source=file)]
+		retries: int [optional] [requiredIf(source="web")]
+		text: str [optional]
+		query: str [optional]
+		@oneof(text | query)
+	}
+}`
+
+func makeFetch(fields ...string) *GValue {
+	var entries []MapEntry
+	for i := 0; i+1 < len(fields); i += 2 {
+		entries = append(entries, MapEntry{Key: fields[i], Value: Str(fields[i+1])})
+	}
+	return Struct("Fetch", entries...)
+}
+
+func TestSchemaConditional_Validate(t *testing.T) {
+	schema, err := ParseSchema(fetchSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	td := schema.GetType("Fetch")
+	if url := td.Struct.Fields[1]; len(td.Struct.OneOf) != 1 || !url.Optional || len(url.Constraints) != 1 {
+		t.Fatalf("oneof %v, url constraints %v", td.Struct.OneOf, td.Struct.Fields[1].Constraints)
+	}
+
+	ok := makeFetch("source", "file", "path", "/tmp/a", "text", "hi")
+	if r := ValidateAs(ok, schema, "Fetch"); !r.Valid {
+		t.Fatalf("valid fetch rejected: %v", r.Errors)
+	}
+
+	web := makeFetch("source", "web", "query", "q")
+	r := ValidateAs(web, schema, "Fetch")
+	if len(r.Errors) != 2 {
+		t.Fatalf("errors = %v", r.Errors)
+	}
+	for i, want := range []string{
+		`url is required when source="web"`,
+		`retries is required when source="web"`,
+	} {
+		if r.Errors[i].Code != "required_if" || r.Errors[i].Message != want {
+			t.Errorf("error %d = %s %q, want %q", i, r.Errors[i].Code, r.Errors[i].Message, want)
+		}
+	}
+
+	// Null counts as not set, for requiredIf and for @oneof.
+	web.structVal.Fields = append(web.structVal.Fields,
+		MapEntry{Key: "url", Value: Null()},
+		MapEntry{Key: "retries", Value: Int(2)},
+		MapEntry{Key: "text", Value: Null()},
+	)
+	r = ValidateAs(web, schema, "Fetch")
+	if len(r.Errors) != 1 || r.Errors[0].Path != "url" {
+		t.Errorf("null url: %v", r.Errors)
+	}
+
+	for _, tc := range []struct {
+		value *GValue
+		want  string
+	}{
+		{makeFetch("source", "file", "path", "p"), "exactly one of text, query must be set, got none"},
+		{makeFetch("source", "file", "path", "p", "text", "a", "query", "b"), "exactly one of text, query must be set, got text, query"},
+	} {
+		r := ValidateAs(tc.value, schema, "Fetch")
+		if len(r.Errors) != 1 || r.Errors[0].Code != "oneof" || r.Errors[0].Message != tc.want {
+			t.Errorf("errors = %v, want %q", r.Errors, tc.want)
+		}
+	}
+}
+
+func TestSchemaConditional_CanonicalRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(fetchSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := schema.Canonical()
+	for _, want := range []string{
+		`requiredIf(source="file")`,
+		"    @oneof(text | query)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("canonical text missing %q:\n%s", want, text)
+		}
+	}
+	again, err := ParseSchema(text)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, text)
+	}
+	if again.Canonical() != text || again.Hash != schema.Hash {
+		t.Errorf("round-trip changed the schema:\n%s", again.Canonical())
+	}
+
+	without, err := ParseSchema(strings.ReplaceAll(fetchSchema, "@oneof(text | query)", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if without.Hash == schema.Hash {
+		t.Error("removing @oneof did not change the schema hash")
+	}
+}
+
+func TestSchemaConditional_Builder(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Job", "v1",
+			Field("mode", PrimitiveType("int")),
+			Field("cron", PrimitiveType("str"), WithOptional(), WithConstraint(RequiredIfConstraint("mode", Int(2)))),
+			Field("a", PrimitiveType("str"), WithOptional()),
+			Field("b", PrimitiveType("str"), WithOptional()),
+		).
+		WithOneOf("Job", "a", "b").
+		Build()
+
+	v := Struct("Job", MapEntry{Key: "mode", Value: Int(2)}, MapEntry{Key: "b", Value: Str("x")})
+	r := ValidateAs(v, schema, "Job")
+	if len(r.Errors) != 1 || r.Errors[0].Message != "cron is required when mode=2" {
+		t.Errorf("errors = %v", r.Errors)
+	}
+	if errs := schema.Check(); len(errs) != 0 {
+		t.Errorf("Check: %v", errs)
+	}
+}
+
+func TestSchemaConditional_Check(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		A struct{
+			x: int [requiredIf(y=1)]
+			z: int [optional] [requiredIf(x=1)]
+			@oneof(x | w)
+			@assert(x < v)
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, e := range schema.Check() {
+		got[e.Code]++
+	}
+	if got["unknown_field_reference"] != 3 || got["required_if_not_optional"] != 1 {
+		t.Errorf("Check codes = %v", got)
+	}
+}
+
+func TestSchemaConditional_ParseErrors(t *testing.T) {
+	for _, text := range []string{
+		`@schema{ A struct{ x: int @oneof(x) } }`,
+		`@schema{ A struct{ x: int y: int @oneof(x y) } }`,
+		`@schema{ A struct{ x: int [optional] [requiredIf(y)] } }`,
+		`@schema{ A struct{ x: int [optional] [requiredIf(y=[1])] } }`,
+	} {
+		if _, err := ParseSchema(text); err == nil {
+			t.Errorf("expected error for %s", text)
+		}
+	}
+}
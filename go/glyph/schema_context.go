@@ -9,12 +9,16 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SchemaContext holds a key dictionary for compact object encoding.
 // Keys are mapped to numeric indices to reduce token count.
 //
 // SchemaContext is IMMUTABLE after creation. Do not modify fields after construction.
+// The one exception is Version, which a SchemaRegistry bumps atomically on
+// eviction; read it with CurrentVersion when the context is shared.
 //
 // Example:
 //
@@ -29,6 +33,11 @@ type SchemaContext struct {
 	Version int64             // Incremented on eviction to detect stale refs
 }
 
+// CurrentVersion returns Version, safely against a concurrent eviction.
+func (sc *SchemaContext) CurrentVersion() int64 {
+	return atomic.LoadInt64(&sc.Version)
+}
+
 // NewSchemaContext creates a new schema context from a list of keys.
 // The schema ID is computed as a SHA-256 hash (first 5 bytes, base32 encoded).
 func NewSchemaContext(keys []string) *SchemaContext {
@@ -128,26 +137,80 @@ func (sc *SchemaContext) EmitHeader(inline bool) string {
 
 // schemaEntry holds a schema context and its position in the LRU list.
 type schemaEntry struct {
-	ctx     *SchemaContext
-	element *list.Element // Pointer to LRU list element (stores schema ID)
+	ctx      *SchemaContext
+	element  *list.Element // Pointer to LRU list element (stores schema ID)
+	lastUsed time.Time     // Last Define, Get, or SetActive (for the TTL)
+}
+
+// EvictReason says why a SchemaRegistry dropped a schema.
+type EvictReason uint8
+
+const (
+	EvictLRU      EvictReason = iota // Least recently used, at the size cap
+	EvictExpired                     // Unused for longer than the TTL
+	EvictExplicit                    // Removed by Evict
+)
+
+// String returns the reason as a metric label.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictLRU:
+		return "lru"
+	case EvictExpired:
+		return "expired"
+	case EvictExplicit:
+		return "explicit"
+	default:
+		return "unknown"
+	}
+}
+
+// RegistryStats is a snapshot of a SchemaRegistry's size and activity.
+type RegistryStats struct {
+	Len       int           // Schemas held
+	MaxSize   int           // LRU cap
+	TTL       time.Duration // Idle limit; 0 means none
+	Defines   uint64        // Define calls
+	Hits      uint64        // Get and SetActive calls that found the schema
+	Misses    uint64        // Get and SetActive calls that did not
+	Evictions uint64        // Schemas dropped, for any EvictReason
+	Expired   uint64        // Evictions with reason EvictExpired
 }
 
 // SchemaRegistry manages schema contexts for a session with LRU eviction.
+//
+// Long-lived servers bound it by size (NewSchemaRegistryWithSize) and,
+// optionally, by idle time (SetTTL). An evicted schema's Version is bumped
+// so that references to it are detected as stale. The active schema is
+// never dropped for being idle.
+//
+// All methods are safe for concurrent use. The active schema, though, is
+// one per registry: a payload read by ParseLoose without an @schema
+// directive is read with whatever schema any goroutine last defined or
+// activated. Streams that share a registry should each read through a
+// ParseSession, which keeps its own active schema.
 type SchemaRegistry struct {
 	schemas map[string]*schemaEntry
 	lruList *list.List // Front = most recent, Back = least recent; stores schema IDs
 	active  *SchemaContext
 	mu      sync.RWMutex
 	maxSize int // LRU cap, default 64
+
+	ttl     time.Duration
+	onEvict func(*SchemaContext, EvictReason)
+	stats   RegistryStats
+	now     func() time.Time // time.Now; replaced in tests
+}
+
+// evicted is a schema dropped under the lock, reported to OnEvict after it.
+type evicted struct {
+	ctx    *SchemaContext
+	reason EvictReason
 }
 
 // NewSchemaRegistry creates a new schema registry.
 func NewSchemaRegistry() *SchemaRegistry {
-	return &SchemaRegistry{
-		schemas: make(map[string]*schemaEntry),
-		lruList: list.New(),
-		maxSize: 64,
-	}
+	return NewSchemaRegistryWithSize(64)
 }
 
 // NewSchemaRegistryWithSize creates a new schema registry with custom capacity.
@@ -159,82 +222,152 @@ func NewSchemaRegistryWithSize(maxSize int) *SchemaRegistry {
 		schemas: make(map[string]*schemaEntry),
 		lruList: list.New(),
 		maxSize: maxSize,
+		now:     time.Now,
 	}
 }
 
-// Define adds or replaces a schema context.
+// SetTTL drops schemas that have not been defined or used for longer than
+// ttl; 0 (the default) keeps them until the size cap evicts them. Expired
+// schemas are dropped when next looked up and by Prune.
+func (sr *SchemaRegistry) SetTTL(ttl time.Duration) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.ttl = ttl
+}
+
+// OnEvict sets a function called with each schema the registry drops and
+// why, for metrics or logging. It is called after the registry is unlocked
+// and may use the registry.
+func (sr *SchemaRegistry) OnEvict(fn func(ctx *SchemaContext, reason EvictReason)) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.onEvict = fn
+}
+
+// Define adds or replaces a schema context and makes it active.
 // If the registry is at capacity, the least recently used schema is evicted.
 func (sr *SchemaRegistry) Define(ctx *SchemaContext) {
 	sr.mu.Lock()
-	defer sr.mu.Unlock()
+	ev := sr.define(ctx, true)
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
+}
+
+// Add is Define without changing the active schema.
+func (sr *SchemaRegistry) Add(ctx *SchemaContext) {
+	sr.mu.Lock()
+	ev := sr.define(ctx, false)
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
+}
+
+func (sr *SchemaRegistry) define(ctx *SchemaContext, activate bool) []evicted {
+	sr.stats.Defines++
 
 	// Check if already exists
 	if entry, ok := sr.schemas[ctx.ID]; ok {
 		// Update existing entry and move to front (most recently used)
 		entry.ctx = ctx
+		entry.lastUsed = sr.now()
 		sr.lruList.MoveToFront(entry.element)
-		sr.active = ctx
-		return
+		if activate || sr.active == entry.ctx {
+			sr.active = ctx
+		}
+		return nil
 	}
 
 	// Evict LRU if at capacity
+	var ev []evicted
 	for sr.lruList.Len() >= sr.maxSize {
 		oldest := sr.lruList.Back()
 		if oldest == nil {
 			break
 		}
-		oldID := oldest.Value.(string)
-
-		// Invalidate stale references by incrementing version
-		if entry, ok := sr.schemas[oldID]; ok {
-			entry.ctx.Version++
-		}
-
-		sr.lruList.Remove(oldest)
-		delete(sr.schemas, oldID)
+		ev = sr.evict(oldest.Value.(string), EvictLRU, ev)
 	}
 
 	// Add new entry at front (most recently used)
 	elem := sr.lruList.PushFront(ctx.ID)
-	sr.schemas[ctx.ID] = &schemaEntry{ctx: ctx, element: elem}
-	sr.active = ctx
+	sr.schemas[ctx.ID] = &schemaEntry{ctx: ctx, element: elem, lastUsed: sr.now()}
+	if activate {
+		sr.active = ctx
+	}
+	return ev
 }
 
 // Get returns a schema by ID, or nil if not found.
 // Accessing a schema marks it as recently used.
 func (sr *SchemaRegistry) Get(id string) *SchemaContext {
 	sr.mu.Lock()
-	defer sr.mu.Unlock()
+	entry, ev := sr.lookup(id)
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
+
+	if entry == nil {
+		return nil
+	}
+	return entry.ctx
+}
 
+// lookup finds id for Get and SetActive, dropping it if it has expired,
+// and marks it as recently used.
+func (sr *SchemaRegistry) lookup(id string) (*schemaEntry, []evicted) {
 	entry, ok := sr.schemas[id]
 	if !ok {
-		return nil
+		sr.stats.Misses++
+		return nil, nil
+	}
+	now := sr.now()
+	if sr.expired(entry, now) {
+		sr.stats.Misses++
+		return nil, sr.evict(id, EvictExpired, nil)
 	}
 
 	// Move to front (most recently used)
+	sr.stats.Hits++
+	entry.lastUsed = now
 	sr.lruList.MoveToFront(entry.element)
-	return entry.ctx
+	return entry, nil
 }
 
 // SetActive sets the active schema by ID.
 // Returns error if schema not found.
 // Accessing a schema marks it as recently used.
+//
+// Deprecated: the active schema is shared by everything reading through the
+// registry. Use ParseSession.SetActive, which is scoped to one stream.
 func (sr *SchemaRegistry) SetActive(id string) error {
-	sr.mu.Lock()
-	defer sr.mu.Unlock()
+	_, err := sr.Use(id)
+	return err
+}
 
-	entry, ok := sr.schemas[id]
-	if !ok {
-		return fmt.Errorf("schema not found: %s", id)
+// Use looks up a schema by ID and makes it active in one step, so that no
+// other goroutine can evict it or activate another schema in between. It
+// marks the schema as recently used and returns an error if it is not
+// registered. A ParseSession resolves references without touching the
+// registry's active schema.
+func (sr *SchemaRegistry) Use(id string) (*SchemaContext, error) {
+	sr.mu.Lock()
+	entry, ev := sr.lookup(id)
+	if entry != nil {
+		sr.active = entry.ctx
 	}
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
 
-	// Move to front (most recently used)
-	sr.lruList.MoveToFront(entry.element)
-	sr.active = entry.ctx
-	return nil
+	if entry == nil {
+		return nil, fmt.Errorf("schema not found: %s", id)
+	}
+	return entry.ctx, nil
 }
 
 // Active returns the currently active schema, or nil if none.
+//
+// Deprecated: use ParseSession.Active.
 func (sr *SchemaRegistry) Active() *SchemaContext {
 	sr.mu.RLock()
 	defer sr.mu.RUnlock()
@@ -258,7 +391,90 @@ func (sr *SchemaRegistry) Clear(id string) {
 	delete(sr.schemas, id)
 }
 
+// Evict removes a schema by ID like Clear, but also bumps its Version so
+// that references to it are detected as stale, and reports it to OnEvict.
+// It returns false if id is not registered.
+func (sr *SchemaRegistry) Evict(id string) bool {
+	sr.mu.Lock()
+	var ev []evicted
+	if _, ok := sr.schemas[id]; ok {
+		ev = sr.evict(id, EvictExplicit, nil)
+	}
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
+	return ev != nil
+}
+
+// Prune drops every schema that has expired under the TTL and returns how
+// many it dropped. Servers call it periodically so that idle schemas do not
+// wait for a lookup to be released.
+func (sr *SchemaRegistry) Prune() int {
+	sr.mu.Lock()
+	var ev []evicted
+	now := sr.now()
+	for elem := sr.lruList.Back(); elem != nil; {
+		prev := elem.Prev()
+		id := elem.Value.(string)
+		if sr.expired(sr.schemas[id], now) {
+			ev = sr.evict(id, EvictExpired, ev)
+		}
+		elem = prev
+	}
+	hook := sr.onEvict
+	sr.mu.Unlock()
+	notifyEvicted(hook, ev)
+	return len(ev)
+}
+
+// Stats returns a snapshot of the registry's size and counters.
+func (sr *SchemaRegistry) Stats() RegistryStats {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	st := sr.stats
+	st.Len = len(sr.schemas)
+	st.MaxSize = sr.maxSize
+	st.TTL = sr.ttl
+	return st
+}
+
+// expired reports whether entry has been idle longer than the TTL. The
+// active schema never expires.
+func (sr *SchemaRegistry) expired(entry *schemaEntry, now time.Time) bool {
+	return sr.ttl > 0 && entry.ctx != sr.active && now.Sub(entry.lastUsed) > sr.ttl
+}
+
+// evict drops id, which must be registered, and appends it to ev.
+func (sr *SchemaRegistry) evict(id string, reason EvictReason, ev []evicted) []evicted {
+	entry := sr.schemas[id]
+
+	// Invalidate stale references by incrementing version
+	atomic.AddInt64(&entry.ctx.Version, 1)
+
+	if sr.active == entry.ctx {
+		sr.active = nil
+	}
+	sr.lruList.Remove(entry.element)
+	delete(sr.schemas, id)
+	sr.stats.Evictions++
+	if reason == EvictExpired {
+		sr.stats.Expired++
+	}
+	return append(ev, evicted{ctx: entry.ctx, reason: reason})
+}
+
+func notifyEvicted(hook func(*SchemaContext, EvictReason), ev []evicted) {
+	if hook == nil {
+		return
+	}
+	for _, e := range ev {
+		hook(e.ctx, e.reason)
+	}
+}
+
 // ClearActive clears the active schema (returns to normal string keys).
+//
+// Deprecated: use ParseSession.ClearActive.
 func (sr *SchemaRegistry) ClearActive() {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
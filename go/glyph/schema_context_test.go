@@ -1,8 +1,11 @@
 package glyph
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewSchemaContext(t *testing.T) {
@@ -618,3 +621,111 @@ func TestSchemaRegistry_UpdateExisting(t *testing.T) {
 		t.Errorf("A should have 2 keys, got %d", len(ctx.Keys))
 	}
 }
+
+func TestSchemaRegistry_TTLAndStats(t *testing.T) {
+	reg := NewSchemaRegistryWithSize(2)
+	now := time.Unix(1000, 0)
+	reg.now = func() time.Time { return now }
+	reg.SetTTL(time.Minute)
+
+	var dropped []string
+	reg.OnEvict(func(ctx *SchemaContext, reason EvictReason) {
+		dropped = append(dropped, ctx.ID+":"+reason.String())
+	})
+
+	a := NewSchemaContextWithID("A", []string{"x"})
+	reg.Define(a)
+	reg.Define(NewSchemaContextWithID("B", []string{"y"}))
+	reg.Define(NewSchemaContextWithID("C", []string{"z"})) // evicts A at the cap
+	if a.Version != 1 {
+		t.Errorf("evicted A has Version %d, want 1", a.Version)
+	}
+
+	// B is idle past the TTL; C is active and never expires.
+	now = now.Add(2 * time.Minute)
+	if reg.Get("B") != nil {
+		t.Error("Get returned expired B")
+	}
+	if reg.Get("C") == nil {
+		t.Error("active C expired")
+	}
+
+	reg.Define(NewSchemaContextWithID("D", []string{"w"}))
+	now = now.Add(2 * time.Minute)
+	if n := reg.Prune(); n != 1 || reg.Get("D") == nil {
+		t.Errorf("Prune dropped %d, want C only", n)
+	}
+	if !reg.Evict("D") || reg.Evict("D") || reg.Active() != nil {
+		t.Error("Evict of the active schema")
+	}
+
+	want := []string{"A:lru", "B:expired", "C:expired", "D:explicit"}
+	if strings.Join(dropped, " ") != strings.Join(want, " ") {
+		t.Errorf("evicted %v, want %v", dropped, want)
+	}
+	st := reg.Stats()
+	if st != (RegistryStats{Len: 0, MaxSize: 2, TTL: time.Minute, Defines: 4, Hits: 2, Misses: 1, Evictions: 4, Expired: 2}) {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+// TestSchemaRegistry_ConcurrentStreams reads several payload streams through
+// one registry at once while it is pruned and inspected. Each stream names
+// its schema on every payload, so the shared active schema does not matter.
+// Run with -race.
+func TestSchemaRegistry_ConcurrentStreams(t *testing.T) {
+	const streams, payloads = 8, 200
+	reg := NewSchemaRegistryWithSize(streams + 1)
+	reg.SetTTL(time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, streams)
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("S%d", i)
+			key := fmt.Sprintf("k%d", i)
+			def := fmt.Sprintf("@schema#%s @keys=[%s]\n{#0=%d}", id, key, i)
+			if _, _, err := ParseLoosePayload(def, reg); err != nil {
+				errs <- err
+				return
+			}
+			for n := 0; n < payloads; n++ {
+				v, ctx, err := ParseLoosePayload(fmt.Sprintf("@schema#%s\n{#0=%d}", id, n), reg)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if ctx.ID != id || v.Get(key) == nil {
+					errs <- fmt.Errorf("stream %s read %s with schema %s", id, Emit(v), ctx.ID)
+					return
+				}
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		extra := NewSchemaContextWithID("X", []string{"x"})
+		for n := 0; n < payloads; n++ {
+			reg.Prune()
+			_ = reg.Stats()
+			_ = reg.Active()
+			reg.Define(extra)
+			reg.Evict("X")
+			_ = extra.CurrentVersion()
+		}
+	}()
+
+	wg.Wait()
+	<-done
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if st := reg.Stats(); st.Len != streams || st.Misses != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
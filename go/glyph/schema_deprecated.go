@@ -0,0 +1,127 @@
+package glyph
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Deprecation
+// ============================================================
+//
+// A schema shared by many producers cannot drop a field at once. The field
+// is first marked @deprecated, naming the version it was deprecated in and
+// its replacement:
+//
+//	Fetch:v3 struct{
+//	    url: str [optional]
+//	    href: str [optional] @deprecated(since="v2", use=url)
+//	}
+//
+// Types take the same annotation among their flags, with use= naming the
+// replacement type. Parsing with a schema and Validate report each deprecated
+// field or type that appears as a warning, never an error. An emitter with
+// EmitOptions.RenameDeprecated writes deprecated fields under their
+// replacement (see RenameDeprecated), so producers migrate by re-emitting.
+
+// Deprecation is a @deprecated annotation of a field or type.
+type Deprecation struct {
+	Since string // Version or date of the deprecation; may be ""
+	Use   string // Replacement field (for a field) or type (for a type); may be ""
+}
+
+// String returns the annotation as written in schema text.
+func (d *Deprecation) String() string {
+	var args []string
+	if d.Since != "" {
+		args = append(args, "since="+strconv.Quote(d.Since))
+	}
+	if d.Use != "" {
+		args = append(args, "use="+d.Use)
+	}
+	if len(args) == 0 {
+		return "@deprecated"
+	}
+	return "@deprecated(" + strings.Join(args, ", ") + ")"
+}
+
+// describe returns a warning message for the deprecated thing named what,
+// such as "field Fetch.href".
+func (d *Deprecation) describe(what string) string {
+	msg := what + " is deprecated"
+	if d.Since != "" {
+		msg += " since " + d.Since
+	}
+	if d.Use != "" {
+		msg += "; use " + d.Use
+	}
+	return msg
+}
+
+// parseDeprecation parses the optional (since=..., use=...) after
+// @deprecated. A leading argument without a name is since.
+func (p *schemaParser) parseDeprecation() (*Deprecation, error) {
+	d := &Deprecation{}
+	if !p.stream.Match(TokenLParen) {
+		return d, nil
+	}
+	for first := true; !p.stream.Match(TokenRParen); first = false {
+		if tok := p.stream.Peek(); tok.Type == TokenEOF {
+			return nil, p.errorf(tok, "expected ) after @deprecated arguments")
+		}
+		p.stream.Match(TokenComma)
+
+		name, nameTok := "since", p.stream.Peek()
+		if tok, next := p.stream.Peek(), p.stream.PeekN(1); tok.Type == TokenIdent && next.Type == TokenEq {
+			name = tok.Value
+			p.stream.Advance()
+			p.stream.Advance()
+		} else if !first {
+			return nil, p.errorf(tok, "expected since= or use= in @deprecated, got %s", describeToken(tok))
+		}
+
+		tok := p.stream.Advance()
+		switch {
+		case name == "since" && (tok.Type == TokenIdent || tok.Type == TokenBareStr || tok.Type == TokenString ||
+			tok.Type == TokenInt || tok.Type == TokenFloat):
+			d.Since = tok.Value
+		case name == "use" && tok.Type == TokenIdent:
+			d.Use = tok.Value
+		case name == "since" || name == "use":
+			return nil, p.errorf(tok, "invalid %s= value %s in @deprecated", name, describeToken(tok))
+		default:
+			return nil, p.errorf(nameTok, "unknown @deprecated argument %s", name)
+		}
+	}
+	return d, nil
+}
+
+// RenameDeprecated returns a copy of v in which every deprecated struct field
+// whose use= names another field of the struct is renamed to that field. A
+// field is left as is when the struct already holds its replacement.
+// Unchanged subtrees are shared with v.
+func RenameDeprecated(schema *Schema, v *GValue) *GValue {
+	return rewriteStructs(schema, v, func(td *TypeDef, fields []MapEntry) []MapEntry {
+		var out []MapEntry
+		renamed := false
+		for i, f := range fields {
+			fd := td.FieldByKey(f.Key)
+			if fd == nil || fd.Deprecated == nil || fd.Deprecated.Use == "" {
+				continue
+			}
+			if out == nil {
+				out = append([]MapEntry(nil), fields...)
+			}
+			to := td.FieldByKey(fd.Deprecated.Use)
+			if to == nil || to == fd || fieldIndex(out, to) >= 0 {
+				continue
+			}
+			out[i].Key = to.Name
+			renamed = true
+		}
+		if !renamed {
+			return nil
+		}
+		return out
+	})
+}
@@ -0,0 +1,142 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+const linkSchema = `@schema{
+	Link:v3 struct{
+		url: str [optional]
+		href: str [optional] @deprecated(since="v2", use=url)
+		title: str [optional] @k(ti)
+		rel: str [optional] @deprecated
+	}
+	OldLink @deprecated(since=v3, use=Link) struct{
+		href: str
+	}
+}`
+
+func TestSchemaDeprecated_Parse(t *testing.T) {
+	schema, err := ParseSchema(linkSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	href := schema.GetField("Link", "href")
+	if href.Deprecated == nil || *href.Deprecated != (Deprecation{Since: "v2", Use: "url"}) {
+		t.Errorf("href.Deprecated = %v", href.Deprecated)
+	}
+	if rel := schema.GetField("Link", "rel"); rel.Deprecated == nil || *rel.Deprecated != (Deprecation{}) {
+		t.Errorf("rel.Deprecated = %v", rel.Deprecated)
+	}
+	if d := schema.GetType("OldLink").Deprecated; d == nil || *d != (Deprecation{Since: "v3", Use: "Link"}) {
+		t.Errorf("OldLink.Deprecated = %v", d)
+	}
+
+	text := schema.Canonical()
+	for _, want := range []string{
+		`href: str @deprecated(since="v2", use=url) [optional]`,
+		`OldLink @deprecated(since="v3", use=Link) struct{`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("canonical text missing %q:\n%s", want, text)
+		}
+	}
+	again, err := ParseSchema(text)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, text)
+	}
+	if again.Canonical() != text || again.Hash != schema.Hash {
+		t.Errorf("round-trip changed the schema:\n%s", again.Canonical())
+	}
+	if errs := schema.Check(); len(errs) != 0 {
+		t.Errorf("Check: %v", errs)
+	}
+
+	for _, bad := range []string{
+		`@schema{ A struct{ x: int @deprecated(v1, v2) } }`,
+		`@schema{ A struct{ x: int @deprecated(use="y") } }`,
+		`@schema{ A struct{ x: int @deprecated(until=v2) } }`,
+		`@schema{ A struct{ x: int @deprecated(since=v1 }`,
+	} {
+		if _, err := ParseSchema(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestSchemaDeprecated_Warnings(t *testing.T) {
+	schema, err := ParseSchema(linkSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ParseWithSchema(`[Link{href="a" ti="b"} OldLink{href="c"}]`, schema)
+	if err != nil || r.HasErrors() {
+		t.Fatalf("parse: %v %v", err, r.Errors)
+	}
+	if len(r.Warnings) != 2 ||
+		r.Warnings[0].Message != "field Link.href is deprecated since v2; use url" ||
+		r.Warnings[1].Message != "type OldLink is deprecated since v3; use Link" {
+		t.Errorf("parse warnings = %v", r.Warnings)
+	}
+
+	link := Struct("Link",
+		MapEntry{Key: "href", Value: Str("a")},
+		MapEntry{Key: "rel", Value: Null()},
+	)
+	res := ValidateAs(link, schema, "Link")
+	if !res.Valid || len(res.Warnings) != 1 || res.Warnings[0].Code != "deprecated_field" || res.Warnings[0].Path != "href" {
+		t.Errorf("validate link: %+v", res)
+	}
+	res = ValidateAs(Struct("OldLink", MapEntry{Key: "href", Value: Str("c")}), schema, "OldLink")
+	if !res.Valid || len(res.Warnings) != 1 || res.Warnings[0].Code != "deprecated_type" {
+		t.Errorf("validate old link: %+v", res)
+	}
+}
+
+func TestSchemaDeprecated_Rename(t *testing.T) {
+	schema, err := ParseSchema(linkSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := List(
+		Struct("Link", MapEntry{Key: "href", Value: Str("a")}, MapEntry{Key: "ti", Value: Str("x")}),
+		Struct("Link", MapEntry{Key: "href", Value: Str("old")}, MapEntry{Key: "url", Value: Str("new")}),
+	)
+
+	opts := DefaultEmitOptions()
+	opts.Schema = schema
+	opts.RenameDeprecated = true
+	got := EmitWithOptions(v, opts)
+	want := `[Link{ti=x url=a} Link{href=old url=new}]`
+	if got != want {
+		t.Errorf("emit = %s, want %s", got, want)
+	}
+	if Emit(v) == got {
+		t.Error("RenameDeprecated had no effect")
+	}
+	if RenameDeprecated(schema, v.listVal[1]) != v.listVal[1] {
+		t.Error("struct holding the replacement was copied")
+	}
+}
+
+func TestSchemaDeprecated_Builder(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Point", "v2",
+			Field("x", PrimitiveType("float")),
+			Field("lon", PrimitiveType("float"), WithOptional(), WithDeprecated("v2", "x")),
+		).
+		AddStruct("Pt", "v1", Field("x", PrimitiveType("float"))).
+		WithDeprecated("Pt", "v2", "Point").
+		WithDeprecated("Point", "", "Missing").
+		Build()
+
+	if d := schema.GetField("Point", "lon").Deprecated; d == nil || d.Use != "x" {
+		t.Errorf("lon.Deprecated = %v", d)
+	}
+	errs := schema.Check()
+	if len(errs) != 1 || errs[0].Code != "unknown_type_reference" || errs[0].TypeName != "Point" {
+		t.Errorf("Check: %v", errs)
+	}
+}
@@ -0,0 +1,214 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// Namespaced Types
+// ============================================================
+//
+// Schemas from several teams can share one Schema when their type names are
+// qualified by a namespace, as in billing.Invoice:
+//
+//	@schema{
+//	    billing.Invoice struct{ lines: list<LineItem> }
+//	    billing.LineItem struct{ sku: str }
+//	    shipping.Invoice struct{ carrier: str }
+//	}
+//
+// An unqualified reference inside a namespaced type means the type of the
+// same namespace when one is declared, and the unnamespaced type otherwise;
+// ParseSchema stores every reference fully qualified. Struct values carry
+// the qualified name (billing.Invoice{...}) in GLYPH-T text.
+//
+// Merge adds the types of another schema and Import adds them under a
+// namespace. Both refuse names that are already declared differently.
+
+// ErrTypeCollision is wrapped by the errors of Merge and Import when a type
+// name is already declared with a different definition.
+var ErrTypeCollision = errors.New("type name collision")
+
+// SplitTypeName splits a qualified type name into its namespace and local
+// name. The namespace is "" for an unqualified name.
+func SplitTypeName(name string) (namespace, local string) {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Merge adds the types of other to s, sharing their definitions with other.
+// It returns an error wrapping ErrTypeCollision, and leaves s unchanged, if
+// a type of other is declared in s with a different definition; a type
+// declared identically in both is kept once.
+func (s *Schema) Merge(other *Schema) error {
+	var collisions []string
+	for name, td := range other.Types {
+		if mine, ok := s.Types[name]; ok && !sameTypeDef(mine, td) {
+			collisions = append(collisions, name)
+		}
+	}
+	if len(collisions) > 0 {
+		sortStrings(collisions)
+		return fmt.Errorf("%w: %s", ErrTypeCollision, strings.Join(collisions, ", "))
+	}
+
+	if s.Types == nil {
+		s.Types = make(map[string]*TypeDef, len(other.Types))
+	}
+	for name, td := range other.Types {
+		if _, ok := s.Types[name]; !ok {
+			s.Types[name] = td
+		}
+	}
+	s.ComputeHash()
+	return nil
+}
+
+// Import adds the types of other to s under namespace, so that a type X of
+// other becomes namespace.X and references between them follow. other is
+// not modified. Collisions are handled as by Merge.
+func (s *Schema) Import(namespace string, other *Schema) error {
+	if !isQualifiedName(namespace) {
+		return fmt.Errorf("invalid namespace %q", namespace)
+	}
+	renamed := make(map[string]string, len(other.Types))
+	for name := range other.Types {
+		renamed[name] = namespace + "." + name
+	}
+	imported := &Schema{Types: make(map[string]*TypeDef, len(other.Types))}
+	for name, td := range other.Types {
+		td = cloneTypeDef(td, renamed)
+		td.Name = renamed[name]
+		imported.Types[td.Name] = td
+	}
+	return s.Merge(imported)
+}
+
+// qualifyRefs rewrites the unqualified type references of namespaced types
+// in s to the type of the same namespace, where s declares one.
+func (s *Schema) qualifyRefs() {
+	for name, td := range s.Types {
+		ns, _ := SplitTypeName(name)
+		if ns == "" {
+			continue
+		}
+		renamed := make(map[string]string)
+		for other := range s.Types {
+			if otherNS, local := SplitTypeName(other); otherNS == ns {
+				renamed[local] = other
+			}
+		}
+		rewriteTypeDefRefs(td, renamed)
+	}
+}
+
+// sameTypeDef reports whether a and b define the same type, doc comments
+// aside.
+func sameTypeDef(a, b *TypeDef) bool {
+	var sa, sb strings.Builder
+	writeTypeDefForHash(&sa, a)
+	writeTypeDefForHash(&sb, b)
+	return sa.String() == sb.String()
+}
+
+// cloneTypeDef returns a copy of td with references renamed; the copy shares
+// nothing with td that rewriteTypeDefRefs changes.
+func cloneTypeDef(td *TypeDef, renamed map[string]string) *TypeDef {
+	c := *td
+	if td.Struct != nil {
+		c.Struct = cloneStructDef(td.Struct)
+	}
+	if td.Sum != nil {
+		c.Sum = &SumDef{Variants: make([]*VariantDef, len(td.Sum.Variants))}
+		for i, v := range td.Sum.Variants {
+			vc := *v
+			vc.Type = cloneTypeSpec(v.Type)
+			c.Sum.Variants[i] = &vc
+		}
+	}
+	rewriteTypeDefRefs(&c, renamed)
+	return &c
+}
+
+func cloneStructDef(sd *StructDef) *StructDef {
+	c := *sd
+	c.Fields = make([]*FieldDef, len(sd.Fields))
+	for i, f := range sd.Fields {
+		fc := *f
+		fc.Type = cloneTypeSpec(f.Type)
+		c.Fields[i] = &fc
+	}
+	return &c
+}
+
+func cloneTypeSpec(ts TypeSpec) TypeSpec {
+	for _, p := range []**TypeSpec{&ts.Elem, &ts.KeyType, &ts.ValType} {
+		if *p != nil {
+			c := cloneTypeSpec(**p)
+			*p = &c
+		}
+	}
+	if ts.Struct != nil {
+		ts.Struct = cloneStructDef(ts.Struct)
+	}
+	return ts
+}
+
+// rewriteTypeDefRefs renames the type references of td in place.
+func rewriteTypeDefRefs(td *TypeDef, renamed map[string]string) {
+	if td.Struct != nil {
+		rewriteStructRefs(td.Struct, renamed)
+	}
+	if td.Sum != nil {
+		for _, v := range td.Sum.Variants {
+			rewriteTypeSpecRefs(&v.Type, renamed)
+		}
+	}
+	if d := td.Deprecated; d != nil && renamed[d.Use] != "" {
+		dc := *d
+		dc.Use = renamed[d.Use]
+		td.Deprecated = &dc
+	}
+}
+
+func rewriteStructRefs(sd *StructDef, renamed map[string]string) {
+	for _, f := range sd.Fields {
+		rewriteTypeSpecRefs(&f.Type, renamed)
+	}
+}
+
+func rewriteTypeSpecRefs(ts *TypeSpec, renamed map[string]string) {
+	switch ts.Kind {
+	case TypeSpecRef:
+		if to, ok := renamed[ts.Name]; ok {
+			ts.Name = to
+		}
+	case TypeSpecList:
+		rewriteTypeSpecRefs(ts.Elem, renamed)
+	case TypeSpecMap:
+		rewriteTypeSpecRefs(ts.KeyType, renamed)
+		rewriteTypeSpecRefs(ts.ValType, renamed)
+	case TypeSpecInlineStruct:
+		rewriteStructRefs(ts.Struct, renamed)
+	}
+}
+
+// isQualifiedName reports whether name is one or more identifiers joined
+// by dots.
+func isQualifiedName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" || !isIdentStart(part[0]) {
+			return false
+		}
+		for i := 1; i < len(part); i++ {
+			if !isIdentContinue(part[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
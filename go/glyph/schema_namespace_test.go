@@ -0,0 +1,124 @@
+package glyph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const namespacedSchema = `@schema{
+	billing.Invoice struct{
+		lines: list<LineItem>
+		ship: shipping.Invoice [optional]
+		owner: Party
+	}
+	billing.LineItem struct{ sku: str }
+	shipping.Invoice struct{ carrier: str }
+	Party struct{ name: str }
+}`
+
+func TestSchemaNamespace_Parse(t *testing.T) {
+	s, err := ParseSchema(namespacedSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	for field, want := range map[string]string{
+		"lines": "list<billing.LineItem>",
+		"ship":  "shipping.Invoice",
+		"owner": "Party",
+	} {
+		if got := s.GetField("billing.Invoice", field).Type.String(); got != want {
+			t.Errorf("%s: %s, want %s", field, got, want)
+		}
+	}
+	if ns, local := SplitTypeName("billing.Invoice"); ns != "billing" || local != "Invoice" {
+		t.Errorf("SplitTypeName = %q, %q", ns, local)
+	}
+
+	again, err := ParseSchema(s.Canonical())
+	if err != nil || again.Hash != s.Hash {
+		t.Fatalf("round-trip: %v\n%s", err, s.Canonical())
+	}
+
+	if _, err := ParseSchema(`@schema{ a.T struct{ x: int } a.T struct{ y: int } }`); err == nil {
+		t.Error("expected error for duplicate type")
+	}
+}
+
+func TestSchemaNamespace_Values(t *testing.T) {
+	s, err := ParseSchema(namespacedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := Struct("billing.Invoice",
+		MapEntry{Key: "lines", Value: List(Struct("billing.LineItem", MapEntry{Key: "sku", Value: Str("A1")}))},
+		MapEntry{Key: "owner", Value: Struct("Party", MapEntry{Key: "name", Value: Str("Ada")})},
+	)
+	text := Emit(v)
+	if !strings.HasPrefix(text, "billing.Invoice{lines=[billing.LineItem{sku=A1}]") {
+		t.Fatalf("emit = %s", text)
+	}
+	r, err := ParseWithSchema(text, s)
+	if err != nil || r.HasErrors() {
+		t.Fatalf("parse: %v %v", err, r.Errors)
+	}
+	if Emit(r.Value) != text {
+		t.Errorf("round-trip: %s", Emit(r.Value))
+	}
+	if res := ValidateAs(r.Value, s, "billing.Invoice"); !res.Valid {
+		t.Errorf("validate: %v", res.Errors)
+	}
+	bad := Struct("billing.Invoice",
+		MapEntry{Key: "lines", Value: List(Struct("shipping.Invoice", MapEntry{Key: "carrier", Value: Str("x")}))},
+		MapEntry{Key: "owner", Value: Struct("Party", MapEntry{Key: "name", Value: Str("Ada")})},
+	)
+	if res := ValidateAs(bad, s, "billing.Invoice"); res.Valid {
+		t.Error("a shipping.Invoice was accepted as a billing.LineItem")
+	}
+}
+
+func TestSchemaNamespace_MergeImport(t *testing.T) {
+	team, err := ParseSchema(`@schema{
+		Invoice struct{ lines: list<LineItem> total: float }
+		LineItem struct{ sku: str }
+		Party struct{ name: str id: int }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamCanon := team.Canonical()
+
+	s, err := ParseSchema(namespacedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Import("acct", team); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := s.GetField("acct.Invoice", "lines").Type.String(); got != "list<acct.LineItem>" {
+		t.Errorf("acct.Invoice.lines: %s", got)
+	}
+	if team.Canonical() != teamCanon {
+		t.Error("Import modified its argument")
+	}
+	if s.GetType("acct.Party") == nil || len(s.Types) != 7 {
+		t.Errorf("types after import: %d", len(s.Types))
+	}
+
+	// The same types again are not a collision; a different Party is.
+	hash := s.Hash
+	if err := s.Import("acct", team); err != nil || s.Hash != hash {
+		t.Errorf("re-import: %v", err)
+	}
+	err = s.Merge(team)
+	if !errors.Is(err, ErrTypeCollision) || !strings.Contains(err.Error(), "Party") {
+		t.Errorf("Merge: %v", err)
+	}
+	if s.GetType("Invoice") != nil || s.Hash != hash {
+		t.Error("failed Merge changed the schema")
+	}
+
+	if err := s.Import("bad ns", team); err == nil || errors.Is(err, ErrTypeCollision) {
+		t.Errorf("Import with invalid namespace: %v", err)
+	}
+}
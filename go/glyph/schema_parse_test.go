@@ -1,6 +1,9 @@
 package glyph
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseSchemaParameterizedTypes(t *testing.T) {
 	s, err := ParseSchema(`@schema{
@@ -81,3 +84,99 @@ func TestParseSchemaParameterizedTypesErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSchemaVersions(t *testing.T) {
+	for _, version := range []string{"v1", "2", "1.2"} {
+		s, err := ParseSchema(`@schema{ A:` + version + ` struct{ x: int } }`)
+		if err != nil {
+			t.Fatalf("version %s: %v", version, err)
+		}
+		if got := s.GetType("A").Version; got != version {
+			t.Errorf("version %s parsed as %q", version, got)
+		}
+		again, err := ParseSchema(s.Canonical())
+		if err != nil || again.Hash != s.Hash {
+			t.Errorf("version %s: round trip %v", version, err)
+		}
+	}
+}
+
+func TestParseSchemaInvalid(t *testing.T) {
+	// Each input is wrong at exactly one place; pos is where the error points.
+	cases := []struct {
+		name, input, pos, msg string
+	}{
+		{"no header", `schema{}`, "1:1", `expected @schema, got "schema"`},
+		{"wrong header", `@types{}`, "1:2", "expected @schema, got @types"},
+		{"unterminated schema", `@schema{ A struct{ x: int }`, "1:28", "unterminated schema opened at 1:8"},
+		{"trailing text", `@schema{ A struct{ x: int } } extra`, "1:31", `unexpected "extra" after schema`},
+		{"duplicate type", `@schema{ A struct{} A struct{} }`, "1:21", "duplicate type A"},
+		{"bad character", `@schema{ A struct{ x: int; } }`, "1:26", `unexpected character ';'`},
+
+		{"missing version", `@schema{ A: struct{ x: int } }`, "1:13", `expected version after A:, got "struct"`},
+		{"version string", `@schema{ A:"v1" struct{} }`, "1:12", `expected version after A:, got string "v1"`},
+		{"version equals", `@schema{ A=v1 struct{} }`, "1:11", `expected struct or sum after type A, got "="`},
+		{"unknown kind", `@schema{ A record{} }`, "1:12", `expected struct or sum after type A, got "record"`},
+		{"unknown type flag", `@schema{ A @sealed struct{} }`, "1:13", "unknown type annotation @sealed"},
+
+		{"field equals", `@schema{ A struct{ x = int } }`, "1:22", `expected : after field x, got "="`},
+		{"field without type", `@schema{ A struct{ x: } }`, "1:23", `expected type, got "}"`},
+		{"field keyword name", `@schema{ A struct{ t: int } }`, "1:20", `expected field name, got "t"`},
+		{"unterminated struct", `@schema{ A struct{ x: int`, "1:26", "unterminated struct opened at 1:18"},
+		{"params on primitive", `@schema{ A struct{ x: lst<int> } }`, "1:26", "type lst takes no parameters"},
+		{"map without comma", `@schema{ A struct{ x: map<str int> } }`, "1:31", `expected , after map key type, got "int"`},
+		{"unclosed list type", `@schema{ A struct{ x: list<int } }`, "1:32", `expected > after list element type, got "}"`},
+
+		{"empty constraint", `@schema{ A struct{ x: int [] } }`, "1:28", "empty constraint []"},
+		{"unknown constraint", `@schema{ A struct{ x: int [bogus] } }`, "1:28", "unknown constraint bogus"},
+		{"min without equals", `@schema{ A struct{ x: int [min 3] } }`, "1:32", `expected = after min, got "3"`},
+		{"min not a number", `@schema{ A struct{ x: int [min=x] } }`, "1:32", `expected number after min=, got "x"`},
+		{"max missing", `@schema{ A struct{ x: int [max=] } }`, "1:32", `expected number after max=, got "]"`},
+		{"len float", `@schema{ A struct{ x: str [len=1.5] } }`, "1:32", "expected length after len=, got \"1.5\""},
+		{"len negative", `@schema{ A struct{ x: str [len>=-1] } }`, "1:33", "expected length after len>=, got \"-1\""},
+		{"len operator", `@schema{ A struct{ x: str [len>3] } }`, "1:32", `expected =, >= or <= after len, got "3"`},
+		{"regex unquoted", `@schema{ A struct{ x: str [regex=abc] } }`, "1:34", `expected quoted pattern after regex=, got "abc"`},
+		{"regex invalid", `@schema{ A struct{ x: str [regex="a("] } }`, "1:34", "invalid regex"},
+		{"enum without list", `@schema{ A struct{ x: str [enum=a] } }`, "1:33", `expected [ after enum=, got "a"`},
+		{"enum number", `@schema{ A struct{ x: str [enum=[a 1 b]] } }`, "1:36", `invalid enum value "1"`},
+		{"enum empty", `@schema{ A struct{ x: str [enum=[]] } }`, "1:33", "empty enum list"},
+		{"enum unterminated", `@schema{ A struct{ x: str [enum=[a b`, "1:37", "unterminated enum list opened at 1:33"},
+		{"prefix number", `@schema{ A struct{ x: id [prefix=[u 2]] } }`, "1:37", `invalid prefix value "2"`},
+		{"open range", `@schema{ A struct{ x: int [0..] } }`, "1:31", `expected number after .., got "]"`},
+		{"range without dots", `@schema{ A struct{ x: int [0 10] } }`, "1:30", `expected .. in range, got "10"`},
+		{"empty range", `@schema{ A struct{ x: int [10..0] } }`, "1:32", "empty range 10..0"},
+		{"two constraints", `@schema{ A struct{ x: int [min=0 max=9] } }`, "1:34", `expected ] after constraint, got "max"`},
+		{"constraint keyword", `@schema{ A struct{ x: int [=3] } }`, "1:28", `expected constraint, got "="`},
+
+		{"empty wire key", `@schema{ A struct{ x: int @k() } }`, "1:30", `expected wire key in @k(...), got ")"`},
+		{"wire key number", `@schema{ A struct{ x: int @k(1) } }`, "1:30", `expected wire key in @k(...), got "1"`},
+		{"fid zero", `@schema{ A struct{ x: int @fid(0) } }`, "1:32", "invalid @fid value 0"},
+		{"fid name", `@schema{ A struct{ x: int @fid(x) } }`, "1:32", `expected integer in @fid(...), got "x"`},
+		{"unknown annotation", `@schema{ A struct{ x: int @index } }`, "1:28", "unknown field annotation @index"},
+		{"default list", `@schema{ A struct{ x: int @default([1]) } }`, "1:36", `unsupported @default value "["`},
+		{"requiredIf colon", `@schema{ A struct{ x: int [requiredIf(y:1)] } }`, "1:40", `expected = after requiredIf(y, got ":"`},
+		{"deprecated argument", `@schema{ A struct{ x: int @deprecated(v1, when=v2) } }`, "1:43", "unknown @deprecated argument when"},
+		{"assert operator", `@schema{ A struct{ x: int @assert(x = 1) } }`, "1:37", `expected comparison`},
+		{"oneof single", `@schema{ A struct{ x: int @oneof(x) } }`, "1:27", "@oneof needs at least two fields"},
+
+		{"variant equals", `@schema{ S sum{ a = int } }`, "1:19", `expected : after variant a, got "="`},
+		{"variant without type", `@schema{ S sum{ a: | b: int } }`, "1:20", `expected type, got "|"`},
+		{"unterminated sum", `@schema{ S sum{ a: int`, "1:23", "unterminated sum opened at 1:15"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseSchema(c.input)
+			if err == nil {
+				t.Fatalf("no error for %s", c.input)
+			}
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("error %T %v, want *ParseError", err, err)
+			}
+			if pe.Pos.String() != c.pos || !strings.Contains(pe.Message, c.msg) {
+				t.Errorf("error %q at %s, want %q at %s", pe.Message, pe.Pos, c.msg, c.pos)
+			}
+		})
+	}
+}
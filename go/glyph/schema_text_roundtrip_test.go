@@ -234,3 +234,81 @@ func TestSchemaText_ConstraintFixpoint(t *testing.T) {
 		t.Errorf("maxlen constraint not parsed back; constraints: %v", tagsField.Constraints)
 	}
 }
+
+const docSchema = `@schema{
+	/// A hyperlink shown to the user.
+	///
+	/// Relative URLs are resolved against the page.
+	Link:v1 @pack struct{
+		/// Target URL.
+		url: str @fid(1) @k(u)
+		title: str [optional] @fid(2) // not a doc comment
+		/// Detached by the blank line below.
+
+		rel: str [optional] @fid(3) /// trailing, not a doc comment
+	}
+}`
+
+func TestSchemaText_DocComments(t *testing.T) {
+	s, err := ParseSchema(docSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	td := s.GetType("Link")
+	if want := "A hyperlink shown to the user.\n\nRelative URLs are resolved against the page."; td.Doc != want {
+		t.Errorf("Link.Doc = %q, want %q", td.Doc, want)
+	}
+	for name, want := range map[string]string{"url": "Target URL.", "title": "", "rel": ""} {
+		if got := s.GetField("Link", name).Doc; got != want {
+			t.Errorf("%s.Doc = %q, want %q", name, got, want)
+		}
+	}
+
+	text := s.Canonical()
+	if !strings.Contains(text, "  /// A hyperlink shown to the user.\n  ///\n") ||
+		!strings.Contains(text, "    /// Target URL.\n    url: str @fid(1) @k(u)\n") {
+		t.Fatalf("canonical text:\n%s", text)
+	}
+	again, err := ParseSchema(text)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, text)
+	}
+	if again.Canonical() != text {
+		t.Errorf("round-trip changed the schema:\n%s", again.Canonical())
+	}
+
+	undocumented, err := ParseSchema(strings.ReplaceAll(docSchema, "/// Target URL.", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undocumented.Hash != s.Hash {
+		t.Error("a doc comment changed the schema hash")
+	}
+}
+
+func TestSchemaText_PromptBlock(t *testing.T) {
+	s := NewSchemaBuilder().
+		AddStruct("Fetch", "v1",
+			Field("url", PrimitiveType("str"), WithDoc("Page to fetch."), WithWireKey("u"), WithFID(1)),
+			Field("depth", PrimitiveType("int"), WithOptional(), WithCodec("int")),
+		).
+		WithDoc("Fetch", "Fetches a web page.").
+		WithPack("Fetch").
+		Build()
+
+	block := s.PromptBlock()
+	want := "@schema{\n" +
+		"  /// Fetches a web page.\n" +
+		"  Fetch:v1 struct{\n" +
+		"    /// Page to fetch.\n" +
+		"    url: str\n" +
+		"    depth: int [optional]\n" +
+		"  }\n" +
+		"}"
+	if block != want {
+		t.Errorf("PromptBlock:\n%s\nwant:\n%s", block, want)
+	}
+	if _, err := ParseSchema(block); err != nil {
+		t.Errorf("PromptBlock does not parse: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package glyph
+
+import (
+	"sort"
+	"strings"
+)
+
+// ============================================================
+// Wire Key Assignment
+// ============================================================
+//
+// Wire keys (@k) shorten field names in packed, tabular and patch output.
+// Picked by hand they tend to be first letters until two fields collide,
+// and nobody checks which fields are worth the shortest keys. AssignWireKeys
+// picks them: the fields that cost the most over a sample corpus (how often
+// they occur times the tokens of their name) choose first, each taking the
+// cheapest key still free, single letters before digraphs and letters of
+// the field's own name before others, so keys stay guessable.
+//
+// New wire keys change how values of the schema are written and its hash,
+// so assign them before a schema is first used, or treat the result as a
+// new schema version.
+
+// WireKeyAssignment is a wire key chosen by AssignWireKeys.
+type WireKeyAssignment struct {
+	Type  string
+	Field string
+	Key   string
+	Count int // Occurrences of the field in the samples (1 without samples)
+
+	// Savings over the samples: Count times the difference between the
+	// name and the key. A word-level tokenizer often prices both at one
+	// token, so bytes are counted too.
+	SavedTokens int
+	SavedBytes  int
+}
+
+// AssignWireKeys replaces the wire keys of every struct type in s with the
+// shortest keys that keep its fields unambiguous, weighted by how often each
+// field occurs in samples, and recomputes the hash. Struct values in samples
+// are matched to types by name; JSON samples should be coerced first (see
+// Coerce). A type absent from the samples weights its fields equally. tok
+// measures key costs (nil means EstimateTokens). A field keeps no wire key
+// if none is shorter than its name and no dearer in tokens. The assignments
+// are returned by type and field name.
+func (s *Schema) AssignWireKeys(tok Tokenizer, samples ...*GValue) []WireKeyAssignment {
+	if tok == nil {
+		tok = EstimateTokens
+	}
+	counts := make(map[string]map[string]int)
+	for _, v := range samples {
+		countStructFields(s, v, counts)
+	}
+
+	names := make([]string, 0, len(s.Types))
+	for name, td := range s.Types {
+		if td.Kind == TypeDefStruct && td.Struct != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var out []WireKeyAssignment
+	for _, name := range names {
+		out = append(out, assignTypeWireKeys(s.Types[name], counts[name], tok)...)
+	}
+	s.ComputeHash()
+	return out
+}
+
+// countStructFields adds the fields of each struct value within v to counts,
+// by type and field name.
+func countStructFields(s *Schema, v *GValue, counts map[string]map[string]int) {
+	v.force()
+	if v == nil {
+		return
+	}
+	switch v.typ {
+	case TypeList:
+		for _, item := range v.listVal {
+			countStructFields(s, item, counts)
+		}
+	case TypeMap:
+		for _, e := range v.mapVal {
+			countStructFields(s, e.Value, counts)
+		}
+	case TypeStruct:
+		if v.structVal == nil {
+			return
+		}
+		td := s.GetType(v.structVal.TypeName)
+		for _, e := range v.structVal.Fields {
+			if td != nil {
+				if fd := td.FieldByKey(e.Key); fd != nil {
+					if counts[td.Name] == nil {
+						counts[td.Name] = make(map[string]int)
+					}
+					counts[td.Name][fd.Name]++
+				}
+			}
+			countStructFields(s, e.Value, counts)
+		}
+	case TypeSum:
+		if v.sumVal != nil {
+			countStructFields(s, v.sumVal.Value, counts)
+		}
+	}
+}
+
+// assignTypeWireKeys assigns the wire keys of one struct type. counts holds
+// the occurrences of its fields by name, or is nil.
+func assignTypeWireKeys(td *TypeDef, counts map[string]int, tok Tokenizer) []WireKeyAssignment {
+	fields := declaredFields(td.Struct)
+	taken := make(map[string]bool)
+	for _, fd := range fields {
+		fd.WireKey = ""
+		taken[fd.Name] = true
+	}
+
+	count := func(fd *FieldDef) int {
+		if counts == nil {
+			return 1
+		}
+		return counts[fd.Name]
+	}
+	keyCost := func(key string) int { return tok(key + "=") }
+	weight := make(map[*FieldDef]int, len(fields))
+	for _, fd := range fields {
+		weight[fd] = count(fd) * keyCost(fd.Name)
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return weight[fields[i]] > weight[fields[j]] })
+
+	var out []WireKeyAssignment
+	for _, fd := range fields {
+		nameCost := keyCost(fd.Name)
+		best, bestCost := "", nameCost+1
+		for _, key := range wireKeyCandidates(fd.Name) {
+			if len(key) >= len(fd.Name) || taken[key] {
+				continue
+			}
+			if c := keyCost(key); c < bestCost {
+				best, bestCost = key, c
+			}
+		}
+		if best == "" {
+			continue
+		}
+		fd.WireKey = best
+		taken[best] = true
+		out = append(out, WireKeyAssignment{
+			Type:        td.Name,
+			Field:       fd.Name,
+			Key:         best,
+			Count:       count(fd),
+			SavedTokens: count(fd) * (nameCost - bestCost),
+			SavedBytes:  count(fd) * (len(fd.Name) - len(best)),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// wireKeyCandidates returns the keys a field may take, most mnemonic first:
+// single letters, then digraphs, each starting from the letters of name.
+// t and f are left out, since they read as booleans in value position.
+func wireKeyCandidates(name string) []string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz"
+	var own []byte
+	for _, c := range []byte(strings.ToLower(name)) {
+		if c >= 'a' && c <= 'z' && strings.IndexByte(string(own), c) < 0 {
+			own = append(own, c)
+		}
+	}
+	letters := string(own)
+	for i := 0; i < len(alphabet); i++ {
+		if strings.IndexByte(letters, alphabet[i]) < 0 {
+			letters += alphabet[i : i+1]
+		}
+	}
+
+	var keys []string
+	for i := 0; i < len(letters); i++ {
+		if letters[i] != 't' && letters[i] != 'f' {
+			keys = append(keys, letters[i:i+1])
+		}
+	}
+	for i := 0; i < len(letters); i++ {
+		for j := 0; j < len(letters); j++ {
+			keys = append(keys, letters[i:i+1]+letters[j:j+1])
+		}
+	}
+	return keys
+}
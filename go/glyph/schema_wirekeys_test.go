@@ -0,0 +1,95 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+const wireKeySchema = `@schema{
+  Event struct{
+    level: str @fid(1)
+    latency: float @fid(2)
+    message: str @fid(3)
+    tags: list<str> @fid(4) [optional]
+    id: id @fid(5) @k(x)
+  }
+}`
+
+func wireKeys(s *Schema, typeName string) map[string]string {
+	keys := make(map[string]string)
+	for _, fd := range s.GetType(typeName).Struct.Fields {
+		keys[fd.Name] = fd.WireKey
+	}
+	return keys
+}
+
+func TestAssignWireKeys(t *testing.T) {
+	schema, err := ParseSchema(wireKeySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHash := schema.Hash
+
+	// latency is in every sample, level in one: latency gets "l".
+	var samples []*GValue
+	for i := 0; i < 10; i++ {
+		fields := []MapEntry{
+			{Key: "latency", Value: Float(1.5)},
+			{Key: "message", Value: Str("ok")},
+		}
+		if i == 0 {
+			fields = append(fields, MapEntry{Key: "level", Value: Str("warn")})
+		}
+		samples = append(samples, Struct("Event", fields...))
+	}
+	got := schema.AssignWireKeys(nil, List(samples...))
+
+	keys := wireKeys(schema, "Event")
+	want := map[string]string{"latency": "l", "message": "m", "level": "e", "tags": "a", "id": "i"}
+	for field, key := range want {
+		if keys[field] != key {
+			t.Errorf("%s: wire key %q, want %q", field, keys[field], key)
+		}
+	}
+	if errs := schema.Check(); len(errs) > 0 {
+		t.Errorf("Check: %v", errs)
+	}
+	if schema.Hash == oldHash {
+		t.Error("hash not recomputed")
+	}
+
+	if len(got) != 5 || got[1].Field != "latency" || got[1].Count != 10 || got[1].SavedBytes != 60 {
+		t.Fatalf("assignments %+v", got)
+	}
+	for _, a := range got {
+		if a.Type != "Event" || a.Key != keys[a.Field] {
+			t.Errorf("assignment %+v", a)
+		}
+	}
+
+	// Typed tables name their columns by the new keys, and round-trip.
+	opts := DefaultLooseCanonOpts()
+	opts.Types = schema
+	opts.Verify = true
+	text := CanonicalizeLooseWithOpts(List(samples[:3]...), opts)
+	if !strings.HasPrefix(text, "@tab Event rows=3 cols=5 [e l m a i]\n") {
+		t.Errorf("typed table:\n%s", text)
+	}
+}
+
+func TestAssignWireKeys_NoSamples(t *testing.T) {
+	schema, err := ParseSchema(`@schema{ P struct{ a: int  flag: bool  file: str  total: int  type: str } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.AssignWireKeys(func(s string) int { return len(s) })
+
+	// a is as short as any key; t and f are never used alone.
+	keys := wireKeys(schema, "P")
+	want := map[string]string{"a": "", "flag": "l", "file": "i", "total": "o", "type": "y"}
+	for field, key := range want {
+		if keys[field] != key {
+			t.Errorf("%s: wire key %q, want %q", field, keys[field], key)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// Session Encoding
+// ============================================================
+//
+// Within one conversation the receiving model keeps everything it has already
+// been sent. SessionEncoder tracks that state and uses it to keep each new
+// payload small:
+//
+//   - Key dictionaries. A snapshot may use compact #N keys. The first payload
+//     using a dictionary carries the inline @schema#id @keys=[...] header;
+//     later ones reference it as just @schema#id.
+//   - Previously sent documents. Each payload is sent under a key. If a patch
+//     against the last document sent under that key — or against the most
+//     recent document of any key — is smaller than a snapshot, the patch is
+//     sent instead, with @target naming the base document's key.
+//
+// SessionDecoder is the receiving side: it applies the same rules to rebuild
+// each document. ResetSession forgets everything on either side, e.g. when the
+// model's context was truncated or a new conversation starts.
+
+// SessionEncoder emits payloads that reuse state already sent in the session.
+// Not safe for concurrent use.
+type SessionEncoder struct {
+	opts LooseCanonOpts
+	sent map[string]*GValue // last document sent per key
+	dict map[string]bool    // schema context ids whose keys were sent inline
+	last string             // key of the most recent document
+}
+
+// NewSessionEncoder creates a session encoder. opts controls snapshot
+// emission; any key dictionary in it is ignored (the encoder manages its own).
+func NewSessionEncoder(opts LooseCanonOpts) *SessionEncoder {
+	opts.Schema, opts.SchemaRef, opts.KeyDict, opts.UseCompactKeys = nil, "", nil, false
+	s := &SessionEncoder{opts: opts}
+	s.ResetSession()
+	return s
+}
+
+// ResetSession forgets all documents and dictionaries sent so far.
+func (s *SessionEncoder) ResetSession() {
+	s.sent = make(map[string]*GValue)
+	s.dict = make(map[string]bool)
+	s.last = ""
+}
+
+// Encode returns the payload for v sent under key: a patch, a compact-key
+// snapshot, or a plain snapshot, whichever is estimated cheapest.
+func (s *SessionEncoder) Encode(key string, v *GValue) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("glyph: session key must not be empty")
+	}
+
+	payload, dictID := s.snapshot(v)
+	best := EstimateTokens(payload)
+
+	for _, baseKey := range s.baseCandidates(key) {
+		patch := DiffWithOptions(s.sent[baseKey], v, "", DiffOpts{Deltas: true})
+		patch.Target = parseRefIDFromTarget(baseKey)
+		rendered, err := EmitPatch(patch, nil)
+		if err != nil {
+			return "", err
+		}
+		if tokens := EstimateTokens(rendered); tokens < best {
+			payload, dictID, best = rendered, "", tokens
+		}
+	}
+
+	if dictID != "" {
+		s.dict[dictID] = true
+	}
+	s.sent[key] = deepCopy(v)
+	s.last = key
+	return payload, nil
+}
+
+// baseCandidates returns the keys of documents a patch for key may be based
+// on: key itself, then the most recent document if different.
+func (s *SessionEncoder) baseCandidates(key string) []string {
+	var keys []string
+	if _, ok := s.sent[key]; ok {
+		keys = append(keys, key)
+	}
+	if s.last != "" && s.last != key {
+		keys = append(keys, s.last)
+	}
+	return keys
+}
+
+// snapshot renders v in full, with compact keys when that is smaller. It
+// returns the id of the key dictionary used, if any.
+func (s *SessionEncoder) snapshot(v *GValue) (string, string) {
+	full := CanonicalizeLooseWithOpts(v, s.opts)
+
+	keys := BuildKeyDictFromValue(v)
+	if len(keys) == 0 {
+		return full, ""
+	}
+	ctx := NewSchemaContext(keys)
+	compact := s.opts
+	compact.Schema = ctx
+	compact.UseCompactKeys = true
+	text := ctx.EmitHeader(!s.dict[ctx.ID]) + "\n" + canonLooseWithOpts(v, compact)
+
+	if EstimateTokens(text) < EstimateTokens(full) {
+		return text, ctx.ID
+	}
+	return full, ""
+}
+
+// SessionDecoder rebuilds documents from SessionEncoder payloads.
+// Not safe for concurrent use.
+type SessionDecoder struct {
+	parser *ParseSession
+	docs   map[string]*GValue
+}
+
+// NewSessionDecoder creates a session decoder.
+func NewSessionDecoder() *SessionDecoder {
+	d := &SessionDecoder{}
+	d.ResetSession()
+	return d
+}
+
+// ResetSession forgets all documents and dictionaries received so far.
+func (d *SessionDecoder) ResetSession() {
+	d.parser = NewParseSession(nil)
+	d.docs = make(map[string]*GValue)
+}
+
+// Decode parses a payload received under key and returns the full document.
+func (d *SessionDecoder) Decode(key, payload string) (*GValue, error) {
+	trimmed := strings.TrimSpace(payload)
+
+	var v *GValue
+	var err error
+	switch {
+	case strings.HasPrefix(trimmed, "@patch"):
+		v, err = d.applyPatch(key, trimmed)
+	default:
+		v, err = d.parser.Parse(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.docs[key] = v
+	return v, nil
+}
+
+func (d *SessionDecoder) applyPatch(key, payload string) (*GValue, error) {
+	patch, err := ParsePatch(payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	baseKey := key
+	if patch.Target.Value != "" {
+		baseKey = patch.Target.Value
+		if patch.Target.Prefix != "" {
+			baseKey = patch.Target.Prefix + ":" + patch.Target.Value
+		}
+	}
+	base, ok := d.docs[baseKey]
+	if !ok {
+		return nil, fmt.Errorf("glyph: patch base %q not in session", baseKey)
+	}
+	return ApplyPatch(base, patch)
+}
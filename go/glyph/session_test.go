@@ -0,0 +1,105 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func sessionTicket(id int, status string) *GValue {
+	return Map(
+		MapEntry{Key: "ticket_identifier", Value: Int(int64(id))},
+		MapEntry{Key: "customer_name", Value: Str("Ada Lovelace")},
+		MapEntry{Key: "status", Value: Str(status)},
+		MapEntry{Key: "description", Value: Str("Printer on floor three jams on every duplex job")},
+		MapEntry{Key: "assigned_team", Value: Str("facilities")},
+	)
+}
+
+func TestSessionEncoder_RoundTrip(t *testing.T) {
+	enc := NewSessionEncoder(DefaultLooseCanonOpts())
+	dec := NewSessionDecoder()
+
+	steps := []struct {
+		key string
+		v   *GValue
+	}{
+		{"t1", sessionTicket(1, "open")},
+		{"t1", sessionTicket(1, "closed")}, // patch against its own previous version
+		{"t2", sessionTicket(2, "open")},   // patch against t1
+		{"t2", sessionTicket(2, "open")},   // unchanged: empty patch
+		{"t3", Map(MapEntry{Key: "x", Value: Int(1)})},
+	}
+	var payloads []string
+	for i, st := range steps {
+		payload, err := enc.Encode(st.key, st.v)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		payloads = append(payloads, payload)
+		got, err := dec.Decode(st.key, payload)
+		if err != nil {
+			t.Fatalf("step %d decode: %v\n%s", i, err, payload)
+		}
+		if !EqualLoose(got, st.v) {
+			t.Errorf("step %d: got %s, want %s", i, CanonicalizeLoose(got), CanonicalizeLoose(st.v))
+		}
+	}
+
+	if strings.HasPrefix(payloads[0], "@patch") {
+		t.Errorf("first payload must be a snapshot: %s", payloads[0])
+	}
+	for _, i := range []int{1, 2, 3} {
+		if !strings.HasPrefix(payloads[i], "@patch") {
+			t.Errorf("payload %d should be a patch: %s", i, payloads[i])
+		}
+	}
+	if !strings.Contains(payloads[2], "@target=t1") {
+		t.Errorf("cross-document patch should target t1: %s", payloads[2])
+	}
+	if len(payloads[1]) >= len(payloads[0])/2 {
+		t.Errorf("patch should be much smaller than the snapshot: %d vs %d", len(payloads[1]), len(payloads[0]))
+	}
+}
+
+func TestSessionEncoder_DictionaryReuse(t *testing.T) {
+	rows := func(n int) *GValue {
+		var items []*GValue
+		for i := 0; i < n; i++ {
+			items = append(items, Map(
+				MapEntry{Key: "measurement_timestamp", Value: Int(int64(1000 + i))},
+				MapEntry{Key: "sensor_reading_value", Value: Int(int64(i * 7 % 13))},
+			))
+		}
+		return List(items...)
+	}
+
+	enc := NewSessionEncoder(NoTabularLooseCanonOpts())
+	dec := NewSessionDecoder()
+	first, _ := enc.Encode("a", rows(6))
+	if !strings.Contains(first, "@keys=[") {
+		t.Fatalf("first snapshot should define the dictionary: %s", first)
+	}
+	enc.sent, enc.last = map[string]*GValue{}, "" // force a snapshot next
+	second, _ := enc.Encode("b", rows(6))
+	if !strings.HasPrefix(second, "@schema#") || strings.Contains(second, "@keys=") {
+		t.Errorf("second snapshot should only reference the dictionary: %.60s", second)
+	}
+	for _, step := range [][2]string{{"a", first}, {"b", second}} {
+		if got, err := dec.Decode(step[0], step[1]); err != nil || !EqualLoose(got, rows(6)) {
+			t.Errorf("%s: decode failed: %v", step[0], err)
+		}
+	}
+
+	enc.ResetSession()
+	dec.ResetSession()
+	again, _ := enc.Encode("c", rows(6))
+	if again != first {
+		t.Errorf("after ResetSession the dictionary must be resent:\n%s", again)
+	}
+	if _, err := dec.Decode("d", "@patch @target=c\n@end"); err == nil {
+		t.Error("patch against an unknown base should fail")
+	}
+	if _, err := enc.Encode("", rows(1)); err == nil {
+		t.Error("empty key should be rejected")
+	}
+}
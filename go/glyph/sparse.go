@@ -0,0 +1,189 @@
+package glyph
+
+import "bytes"
+
+// ============================================================
+// Sparse Structs
+// ============================================================
+//
+// Many structs carry mostly default values. OmitDefaults drops every optional
+// struct field whose value equals what FillDefaults would restore:
+//
+//   - the field's schema default, if it has one;
+//   - otherwise the zero value of its type: 0, 0.0, f, "", empty bytes, [] or
+//     {}. Fields of other types (time, id, named types) have no zero value and
+//     are kept unless they equal a declared default.
+//
+// FillDefaults is the inverse: it inserts the default or zero value for every
+// absent optional field, so FillDefaults(OmitDefaults(v)) equals v. As in
+// packed encoding, a null optional field without KeepNull counts as absent.
+// Note the converse does not hold — an optional field that was absent or null
+// in the original comes back holding its zero value. Both recurse into nested
+// structs, lists, maps, and sum payloads, and leave structs of unknown types
+// untouched.
+//
+// EmitOptions.Sparse and PackedOptions.Sparse apply OmitDefaults before
+// emitting; ParseOptions.FillDefaults applies FillDefaults after parsing.
+
+// OmitDefaults returns a copy of v without optional fields that hold their
+// default or zero value. Unchanged subtrees are shared with v.
+func OmitDefaults(schema *Schema, v *GValue) *GValue {
+	return rewriteStructs(schema, v, func(td *TypeDef, fields []MapEntry) []MapEntry {
+		var out []MapEntry
+		for i, f := range fields {
+			fd := td.FieldByKey(f.Key)
+			if fd == nil || !fd.Optional || !sameValue(f.Value, sparseFill(fd)) {
+				if out != nil {
+					out = append(out, f)
+				}
+				continue
+			}
+			if out == nil {
+				out = append(make([]MapEntry, 0, len(fields)), fields[:i]...)
+			}
+		}
+		return out
+	})
+}
+
+// FillDefaults returns a copy of v with every absent optional field set to its
+// default or zero value. Unchanged subtrees are shared with v.
+func FillDefaults(schema *Schema, v *GValue) *GValue {
+	return rewriteStructs(schema, v, func(td *TypeDef, fields []MapEntry) []MapEntry {
+		var out []MapEntry
+		for _, fd := range td.Struct.Fields {
+			if !fd.Optional {
+				continue
+			}
+			idx := fieldIndex(fields, fd)
+			if idx >= 0 && isFieldPresent(fields[idx].Value, fd) {
+				continue
+			}
+			fill := sparseFill(fd)
+			if fill == nil {
+				continue
+			}
+			if out == nil {
+				out = append(make([]MapEntry, 0, len(td.Struct.Fields)), fields...)
+			}
+			if idx >= 0 {
+				out[idx].Value = fill
+			} else {
+				out = append(out, MapEntry{Key: fd.Name, Value: fill})
+			}
+		}
+		return out
+	})
+}
+
+// rewriteStructs applies rewrite to the fields of every struct in v whose
+// type is in schema. rewrite returns nil to keep the fields unchanged.
+func rewriteStructs(schema *Schema, v *GValue, rewrite func(*TypeDef, []MapEntry) []MapEntry) *GValue {
+	v.force()
+	if v == nil || schema == nil {
+		return v
+	}
+	rewriteEntries := func(entries []MapEntry) []MapEntry {
+		var out []MapEntry
+		for i, e := range entries {
+			nv := rewriteStructs(schema, e.Value, rewrite)
+			if nv != e.Value && out == nil {
+				out = append([]MapEntry(nil), entries...)
+			}
+			if out != nil {
+				out[i].Value = nv
+			}
+		}
+		return out
+	}
+
+	switch v.typ {
+	case TypeList:
+		var items []*GValue
+		for i, item := range v.listVal {
+			if ni := rewriteStructs(schema, item, rewrite); ni != item {
+				if items == nil {
+					items = append([]*GValue(nil), v.listVal...)
+				}
+				items[i] = ni
+			}
+		}
+		if items != nil {
+			return List(items...)
+		}
+
+	case TypeMap:
+		if entries := rewriteEntries(v.mapVal); entries != nil {
+			return Map(entries...)
+		}
+
+	case TypeSum:
+		if nv := rewriteStructs(schema, v.sumVal.Value, rewrite); nv != v.sumVal.Value {
+			return Sum(v.sumVal.Tag, nv)
+		}
+
+	case TypeStruct:
+		fields := v.structVal.Fields
+		changed := false
+		if entries := rewriteEntries(fields); entries != nil {
+			fields, changed = entries, true
+		}
+		td := schema.GetType(v.structVal.TypeName)
+		if td != nil && td.Kind == TypeDefStruct && td.Struct != nil {
+			if entries := rewrite(td, fields); entries != nil {
+				fields, changed = entries, true
+			}
+		}
+		if changed {
+			return Struct(v.structVal.TypeName, fields...)
+		}
+	}
+	return v
+}
+
+// sparseFill returns the value FillDefaults restores for an absent optional
+// field, or nil if there is none.
+func sparseFill(fd *FieldDef) *GValue {
+	if fd.Default != nil {
+		return fd.Default
+	}
+	switch fd.Type.Kind {
+	case TypeSpecBool:
+		return Bool(false)
+	case TypeSpecInt:
+		return Int(0)
+	case TypeSpecFloat:
+		return Float(0)
+	case TypeSpecStr:
+		return Str("")
+	case TypeSpecBytes:
+		return Bytes(nil)
+	case TypeSpecList:
+		return List()
+	case TypeSpecMap:
+		return Map()
+	}
+	return nil
+}
+
+// sameValue reports whether a and b are the same value, including type
+// (0 and 0.0 differ).
+func sameValue(a, b *GValue) bool {
+	if a == nil || b == nil || a.typ != b.typ {
+		return false
+	}
+	if a.typ == TypeBytes {
+		return bytes.Equal(a.bytesVal(), b.bytesVal())
+	}
+	return CanonicalizeLoose(a) == CanonicalizeLoose(b)
+}
+
+// fieldIndex returns the index of fd in fields by name or wire key, or -1.
+func fieldIndex(fields []MapEntry, fd *FieldDef) int {
+	for i, f := range fields {
+		if f.Key == fd.Name || (fd.WireKey != "" && f.Key == fd.WireKey) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,101 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func sparseTestSchema() *Schema {
+	return NewSchemaBuilder().
+		AddPackedStruct("Config", "v1",
+			Field("name", PrimitiveType("str"), WithFID(1)),
+			Field("port", PrimitiveType("int"), WithFID(2), WithOptional(), WithDefault(Int(8080))),
+			Field("debug", PrimitiveType("bool"), WithFID(3), WithOptional()),
+			Field("tags", ListType(PrimitiveType("str")), WithFID(4), WithOptional()),
+			Field("ratio", PrimitiveType("float"), WithFID(5), WithOptional()),
+			Field("child", RefType("Config"), WithFID(6), WithOptional()),
+		).
+		Build()
+}
+
+func TestSparse_EmitAndFill(t *testing.T) {
+	schema := sparseTestSchema()
+	v := Struct("Config",
+		MapEntry{Key: "name", Value: Str("")},
+		MapEntry{Key: "port", Value: Int(8080)},
+		MapEntry{Key: "debug", Value: Bool(false)},
+		MapEntry{Key: "tags", Value: List()},
+		MapEntry{Key: "ratio", Value: Float(0.5)},
+		MapEntry{Key: "child", Value: Struct("Config",
+			MapEntry{Key: "name", Value: Str("inner")},
+			MapEntry{Key: "port", Value: Int(9090)},
+			MapEntry{Key: "debug", Value: Bool(false)},
+			MapEntry{Key: "tags", Value: List()},
+			MapEntry{Key: "ratio", Value: Float(0)},
+		)},
+	)
+
+	opts := DefaultEmitOptions()
+	opts.Schema = schema
+	opts.Sparse = true
+	text := EmitWithOptions(v, opts)
+	want := `Config{child=Config{name=inner port=9090} name="" ratio=0.5}`
+	if text != want {
+		t.Fatalf("got  %s\nwant %s", text, want)
+	}
+
+	result, err := ParseWithOptions(text, ParseOptions{Schema: schema, FillDefaults: true})
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parse: %v %v", err, result.Errors)
+	}
+	if !sameValue(result.Value, FillDefaults(schema, v)) {
+		t.Errorf("fill mismatch:\n got  %s\n want %s", Emit(result.Value), Emit(v))
+	}
+	if got := Emit(result.Value); got != Emit(v) {
+		t.Errorf("round trip:\n got  %s\n want %s", got, Emit(v))
+	}
+
+	packed, err := EmitPackedWithOptions(v, PackedOptions{Schema: schema, UseBitmap: true, Sparse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, _ := EmitPacked(v, schema)
+	if len(packed) >= len(full) {
+		t.Errorf("sparse packed should be shorter: %s vs %s", packed, full)
+	}
+	back, err := ParsePacked(packed, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Packed parsing materializes absent optionals as null, so compare with
+	// the dense encoding after filling.
+	fullBack, _ := ParsePacked(full, schema)
+	if got, want := Emit(FillDefaults(schema, back)), Emit(FillDefaults(schema, fullBack)); got != want {
+		t.Errorf("packed round trip:\n got  %s\n want %s", got, want)
+	}
+}
+
+func TestSparse_KeepsNonZeroAndUnknown(t *testing.T) {
+	schema := sparseTestSchema()
+	v := List(
+		Struct("Config",
+			MapEntry{Key: "name", Value: Str("a")},
+			MapEntry{Key: "port", Value: Int(0)},
+			MapEntry{Key: "ratio", Value: Int(0)}, // int, not the float zero
+			MapEntry{Key: "debug", Value: Null()},
+		),
+		Struct("Other", MapEntry{Key: "port", Value: Int(0)}),
+	)
+	out := OmitDefaults(schema, v)
+	text := Emit(out)
+	for _, want := range []string{"port=0", "ratio=0", "debug=∅", "Other{port=0}"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in %s", want, text)
+		}
+	}
+
+	unchanged := Struct("Config", MapEntry{Key: "name", Value: Str("a")}, MapEntry{Key: "port", Value: Int(1)})
+	if OmitDefaults(schema, unchanged) != unchanged {
+		t.Error("values without omissions should be returned as is")
+	}
+}
@@ -0,0 +1,182 @@
+// Package stdschemas holds GLYPH schemas for the shapes most agent systems
+// exchange — tool calls and results, chat messages, artifacts, metrics, log
+// events and plans — with Go structs for each, so that teams interoperate
+// on the same types without writing them again:
+//
+//	schema := stdschemas.Schema()
+//	if err := schema.Merge(appSchema); err != nil { ... }
+//
+//	call := stdschemas.ToolCall{ID: "c1", Name: "search", Args: args}
+//	text := glyph.Emit(call.Value())
+//
+// The schema text is Text. Its hash is Hash, pinned by the tests: a change
+// to any type changes the hash, and therefore needs a new type version
+// rather than an edit. TypeHash hashes one type with the types it refers
+// to, for peers that share only some of them.
+//
+// Each struct has a Value method returning the schema-shaped struct value
+// (GValue for Metric, whose Value field is the measurement), and a
+// FromValue function that validates a value and reads it back.
+package stdschemas
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// Text declares the standard types other than Artifact, which comes from
+// glyph.StdSchema.
+const Text = `@schema{
+	/// A request to run a tool.
+	ToolCall:v1 struct{
+		id: str [nonempty]
+		name: str [regex="^[A-Za-z_][0-9A-Za-z_.-]*$"]
+		args: struct{} [optional]
+	}
+
+	/// The outcome of a tool call: text, structured data, artifacts or an error.
+	ToolResult:v1 struct{
+		call_id: str [nonempty]
+		content: str [optional]
+		data: struct{} [optional]
+		artifacts: list<Artifact> [optional]
+		error: str [optional]
+	}
+
+	/// One message of a conversation.
+	Message:v1 struct{
+		role: str [enum=[system user assistant tool]]
+		content: str [optional]
+		name: str [optional]
+		tool_calls: list<ToolCall> [optional]
+		tool_call_id: str [optional] [requiredIf(role="tool")]
+		artifacts: list<Artifact> [optional]
+	}
+
+	/// A measurement.
+	Metric:v1 struct{
+		name: str [nonempty]
+		value: float
+		unit: str [optional]
+		time: time [optional]
+		labels: map<str,str> [optional]
+	}
+
+	/// A structured log record.
+	LogEvent:v1 struct{
+		time: time
+		level: str [enum=[debug info warn error]]
+		msg: str
+		fields: struct{} [optional]
+	}
+
+	/// A goal broken into steps.
+	Plan:v1 struct{
+		goal: str
+		steps: list<Step> [nonempty]
+	}
+
+	/// One step of a plan. depends_on lists the ids of earlier steps.
+	Step:v1 struct{
+		id: str [nonempty]
+		title: str
+		status: str [enum=[pending running done failed skipped]]
+		depends_on: list<str> [optional]
+		tool: str [optional]
+	}
+}`
+
+// Hash is the hash of Schema. It changes whenever a standard type does.
+const Hash = "976610c5564ac46144dbb6d33bcba02c"
+
+// Schema returns a new schema holding the standard types, including
+// glyph's Artifact. Callers may modify it.
+func Schema() *glyph.Schema {
+	schema, err := glyph.ParseSchema(Text)
+	if err != nil {
+		panic(fmt.Sprintf("stdschemas: %v", err))
+	}
+	if err := schema.Merge(glyph.StdSchema()); err != nil {
+		panic(fmt.Sprintf("stdschemas: %v", err))
+	}
+	return schema
+}
+
+// shared is the Schema used for validation.
+var shared = sync.OnceValue(Schema)
+
+// TypeHash returns the hash of the named standard type together with the
+// types it refers to, or "" if there is no such type.
+func TypeHash(name string) string {
+	all := shared()
+	if all.GetType(name) == nil {
+		return ""
+	}
+	sub := &glyph.Schema{Types: make(map[string]*glyph.TypeDef)}
+	var add func(name string)
+	add = func(name string) {
+		td := all.GetType(name)
+		if td == nil || sub.Types[name] != nil {
+			return
+		}
+		sub.Types[name] = td
+		for _, ref := range typeRefs(td) {
+			add(ref)
+		}
+	}
+	add(name)
+	return sub.ComputeHash()
+}
+
+// Types returns the names of the standard types, sorted.
+func Types() []string {
+	names := make([]string, 0, len(shared().Types))
+	for name := range shared().Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// typeRefs returns the names of the types the fields of td refer to.
+func typeRefs(td *glyph.TypeDef) []string {
+	var refs []string
+	var walk func(ts *glyph.TypeSpec)
+	walk = func(ts *glyph.TypeSpec) {
+		if ts == nil {
+			return
+		}
+		switch ts.Kind {
+		case glyph.TypeSpecRef:
+			refs = append(refs, ts.Name)
+		case glyph.TypeSpecList:
+			walk(ts.Elem)
+		case glyph.TypeSpecMap:
+			walk(ts.KeyType)
+			walk(ts.ValType)
+		}
+	}
+	if td.Struct != nil {
+		for _, f := range td.Struct.Fields {
+			walk(&f.Type)
+		}
+	}
+	return refs
+}
+
+// check validates v as the standard type typeName.
+func check(v *glyph.GValue, typeName string) error {
+	if v == nil {
+		return fmt.Errorf("stdschemas: %s: nil value", typeName)
+	}
+	if sv, err := v.AsStruct(); err == nil && sv.TypeName != typeName {
+		return fmt.Errorf("stdschemas: expected %s, got %s", typeName, sv.TypeName)
+	}
+	if r := glyph.ValidateAs(v, shared(), typeName); !r.Valid {
+		return fmt.Errorf("stdschemas: %s: %w", typeName, &r.Errors[0])
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package stdschemas
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+func TestSchema_Hash(t *testing.T) {
+	schema := Schema()
+	if schema.Hash != Hash {
+		t.Errorf("Hash = %s, schema hashes to %s: a standard type changed; add a new version instead", Hash, schema.Hash)
+	}
+	if errs := schema.Check(); len(errs) > 0 {
+		t.Errorf("Check: %v", errs)
+	}
+	want := "Artifact LogEvent Message Metric Plan Step ToolCall ToolResult"
+	if got := strings.Join(Types(), " "); got != want {
+		t.Errorf("Types = %s", got)
+	}
+
+	// A type's hash covers the types it refers to, and nothing else.
+	if TypeHash("Step") == "" || TypeHash("Plan") == TypeHash("Step") || TypeHash("Nope") != "" {
+		t.Error("TypeHash")
+	}
+	sub := &glyph.Schema{Types: map[string]*glyph.TypeDef{
+		"Plan": schema.GetType("Plan"),
+		"Step": schema.GetType("Step"),
+	}}
+	if TypeHash("Plan") != sub.ComputeHash() {
+		t.Error("TypeHash(Plan) does not cover exactly Plan and Step")
+	}
+}
+
+func roundTrip(t *testing.T, v *glyph.GValue) *glyph.GValue {
+	t.Helper()
+	text := glyph.Emit(v)
+	r, err := glyph.Parse(text)
+	if err != nil || r.HasErrors() {
+		t.Fatalf("parse %s: %v %v", text, err, r.Errors)
+	}
+	return r.Value
+}
+
+func TestTypes_RoundTrip(t *testing.T) {
+	args := glyph.Map(glyph.MapEntry{Key: "q", Value: glyph.Str("weather")})
+	msg := Message{
+		Role:      RoleAssistant,
+		ToolCalls: []ToolCall{{ID: "c1", Name: "search", Args: args}},
+	}
+	gotMsg, err := MessageFromValue(roundTrip(t, msg.Value()))
+	if err != nil || len(gotMsg.ToolCalls) != 1 || gotMsg.ToolCalls[0].Name != "search" || gotMsg.ToolCalls[0].Args.Get("q") == nil {
+		t.Errorf("message = %+v, %v", gotMsg, err)
+	}
+
+	res := ToolResult{
+		CallID:    "c1",
+		Content:   "sunny",
+		Artifacts: []Artifact{{MIME: "image/png", URI: "map.png", Width: 64, Height: 64}},
+	}
+	gotRes, err := ToolResultFromValue(roundTrip(t, res.Value()))
+	if err != nil || gotRes.Content != "sunny" || len(gotRes.Artifacts) != 1 || gotRes.Artifacts[0] != res.Artifacts[0] {
+		t.Errorf("result = %+v, %v", gotRes, err)
+	}
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	metric := Metric{Name: "latency", Value: 12.5, Unit: "ms", Time: at, Labels: map[string]string{"tool": "search", "region": "eu"}}
+	gotMetric, err := MetricFromValue(roundTrip(t, metric.GValue()))
+	if err != nil || gotMetric.Value != 12.5 || !gotMetric.Time.Equal(at) || gotMetric.Labels["region"] != "eu" {
+		t.Errorf("metric = %+v, %v", gotMetric, err)
+	}
+
+	event := LogEvent{Time: at, Level: LevelWarn, Msg: "retrying", Fields: glyph.Map(glyph.MapEntry{Key: "attempt", Value: glyph.Int(2)})}
+	gotEvent, err := LogEventFromValue(roundTrip(t, event.Value()))
+	if err != nil || gotEvent.Msg != "retrying" || !gotEvent.Time.Equal(at) || gotEvent.Fields == nil {
+		t.Errorf("event = %+v, %v", gotEvent, err)
+	}
+
+	plan := Plan{Goal: "ship", Steps: []Step{
+		{ID: "a", Title: "build", Status: StatusDone},
+		{ID: "b", Title: "test", Status: StatusPending, DependsOn: []string{"a"}, Tool: "go"},
+	}}
+	gotPlan, err := PlanFromValue(roundTrip(t, plan.Value()))
+	if err != nil || len(gotPlan.Steps) != 2 || gotPlan.Steps[1].DependsOn[0] != "a" || gotPlan.Steps[1].Tool != "go" {
+		t.Errorf("plan = %+v, %v", gotPlan, err)
+	}
+}
+
+func TestTypes_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{second(MessageFromValue(Message{Role: "robot"}.Value())), "role"},
+		{second(MessageFromValue(Message{Role: RoleTool, Content: "x"}.Value())), "tool_call_id"},
+		{second(ToolCallFromValue(ToolCall{ID: "c1", Name: "bad name"}.Value())), "name"},
+		{second(ToolCallFromValue(Step{ID: "a", Title: "x", Status: StatusDone}.Value())), "expected ToolCall"},
+		{second(PlanFromValue(Plan{Goal: "x"}.Value())), "steps"},
+		{second(ToolResultFromValue(ToolResult{CallID: "c1", Artifacts: []Artifact{{MIME: "image/png"}}}.Value())), "uri"},
+		{second(LogEventFromValue(nil)), "nil"},
+	} {
+		if tc.err == nil || !strings.Contains(tc.err.Error(), tc.want) {
+			t.Errorf("err = %v, want %q", tc.err, tc.want)
+		}
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
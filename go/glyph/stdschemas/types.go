@@ -0,0 +1,363 @@
+package stdschemas
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// Artifact is glyph's standard artifact reference.
+type Artifact = glyph.Artifact
+
+// Roles of a Message.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Levels of a LogEvent.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Statuses of a Step.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// ToolCall is a request to run a tool.
+type ToolCall struct {
+	ID   string
+	Name string
+	Args *glyph.GValue // Map or struct of arguments (optional)
+}
+
+// Value returns the call as a ToolCall struct value.
+func (c ToolCall) Value() *glyph.GValue {
+	var b builder
+	b.str("id", c.ID, true)
+	b.str("name", c.Name, true)
+	b.value("args", c.Args)
+	return b.build("ToolCall")
+}
+
+// ToolCallFromValue validates v and reads it as a ToolCall.
+func ToolCallFromValue(v *glyph.GValue) (ToolCall, error) {
+	if err := check(v, "ToolCall"); err != nil {
+		return ToolCall{}, err
+	}
+	return ToolCall{ID: str(v, "id"), Name: str(v, "name"), Args: field(v, "args")}, nil
+}
+
+// ToolResult is the outcome of a tool call.
+type ToolResult struct {
+	CallID    string
+	Content   string        // Text output (optional)
+	Data      *glyph.GValue // Structured output, a map or struct (optional)
+	Artifacts []Artifact    // Images, audio and other binary outputs (optional)
+	Error     string        // Set if the call failed (optional)
+}
+
+// Value returns the result as a ToolResult struct value.
+func (r ToolResult) Value() *glyph.GValue {
+	var b builder
+	b.str("call_id", r.CallID, true)
+	b.str("content", r.Content, false)
+	b.value("data", r.Data)
+	b.artifacts(r.Artifacts)
+	b.str("error", r.Error, false)
+	return b.build("ToolResult")
+}
+
+// ToolResultFromValue validates v and reads it as a ToolResult.
+func ToolResultFromValue(v *glyph.GValue) (ToolResult, error) {
+	if err := check(v, "ToolResult"); err != nil {
+		return ToolResult{}, err
+	}
+	r := ToolResult{
+		CallID:  str(v, "call_id"),
+		Content: str(v, "content"),
+		Data:    field(v, "data"),
+		Error:   str(v, "error"),
+	}
+	var err error
+	r.Artifacts, err = artifacts(v)
+	return r, err
+}
+
+// Message is one message of a conversation.
+type Message struct {
+	Role       string // RoleSystem, RoleUser, RoleAssistant or RoleTool
+	Content    string
+	Name       string     // Speaker name (optional)
+	ToolCalls  []ToolCall // Calls requested by an assistant message
+	ToolCallID string     // Call answered by a tool message
+	Artifacts  []Artifact
+}
+
+// Value returns the message as a Message struct value.
+func (m Message) Value() *glyph.GValue {
+	var b builder
+	b.str("role", m.Role, true)
+	b.str("content", m.Content, false)
+	b.str("name", m.Name, false)
+	if len(m.ToolCalls) > 0 {
+		calls := make([]*glyph.GValue, len(m.ToolCalls))
+		for i, c := range m.ToolCalls {
+			calls[i] = c.Value()
+		}
+		b.value("tool_calls", glyph.List(calls...))
+	}
+	b.str("tool_call_id", m.ToolCallID, false)
+	b.artifacts(m.Artifacts)
+	return b.build("Message")
+}
+
+// MessageFromValue validates v and reads it as a Message.
+func MessageFromValue(v *glyph.GValue) (Message, error) {
+	if err := check(v, "Message"); err != nil {
+		return Message{}, err
+	}
+	m := Message{
+		Role:       str(v, "role"),
+		Content:    str(v, "content"),
+		Name:       str(v, "name"),
+		ToolCallID: str(v, "tool_call_id"),
+	}
+	for _, item := range list(v, "tool_calls") {
+		c, err := ToolCallFromValue(item)
+		if err != nil {
+			return Message{}, err
+		}
+		m.ToolCalls = append(m.ToolCalls, c)
+	}
+	var err error
+	m.Artifacts, err = artifacts(v)
+	return m, err
+}
+
+// Metric is a measurement.
+type Metric struct {
+	Name   string
+	Value  float64
+	Unit   string            // e.g. "ms", "tokens" (optional)
+	Time   time.Time         // When it was taken (optional)
+	Labels map[string]string // Dimensions (optional)
+}
+
+// GValue returns the metric as a Metric struct value. (Value is the
+// measurement.)
+func (m Metric) GValue() *glyph.GValue {
+	var b builder
+	b.str("name", m.Name, true)
+	b.value("value", glyph.Float(m.Value))
+	b.str("unit", m.Unit, false)
+	if !m.Time.IsZero() {
+		b.value("time", glyph.Time(m.Time))
+	}
+	if len(m.Labels) > 0 {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		entries := make([]glyph.MapEntry, len(keys))
+		for i, k := range keys {
+			entries[i] = glyph.MapEntry{Key: k, Value: glyph.Str(m.Labels[k])}
+		}
+		b.value("labels", glyph.Map(entries...))
+	}
+	return b.build("Metric")
+}
+
+// MetricFromValue validates v and reads it as a Metric.
+func MetricFromValue(v *glyph.GValue) (Metric, error) {
+	if err := check(v, "Metric"); err != nil {
+		return Metric{}, err
+	}
+	m := Metric{Name: str(v, "name"), Unit: str(v, "unit"), Time: timeField(v, "time")}
+	if n := field(v, "value"); n != nil {
+		m.Value, _ = n.Number()
+	}
+	if labels := field(v, "labels"); labels != nil {
+		m.Labels = make(map[string]string)
+		labels.Range(func(key string, val *glyph.GValue) bool {
+			m.Labels[key], _ = val.AsStr()
+			return true
+		})
+	}
+	return m, nil
+}
+
+// LogEvent is a structured log record.
+type LogEvent struct {
+	Time   time.Time
+	Level  string // LevelDebug, LevelInfo, LevelWarn or LevelError
+	Msg    string
+	Fields *glyph.GValue // Map or struct of attributes (optional)
+}
+
+// Value returns the event as a LogEvent struct value.
+func (e LogEvent) Value() *glyph.GValue {
+	var b builder
+	b.value("time", glyph.Time(e.Time))
+	b.str("level", e.Level, true)
+	b.str("msg", e.Msg, true)
+	b.value("fields", e.Fields)
+	return b.build("LogEvent")
+}
+
+// LogEventFromValue validates v and reads it as a LogEvent.
+func LogEventFromValue(v *glyph.GValue) (LogEvent, error) {
+	if err := check(v, "LogEvent"); err != nil {
+		return LogEvent{}, err
+	}
+	return LogEvent{
+		Time:   timeField(v, "time"),
+		Level:  str(v, "level"),
+		Msg:    str(v, "msg"),
+		Fields: field(v, "fields"),
+	}, nil
+}
+
+// Plan is a goal broken into steps.
+type Plan struct {
+	Goal  string
+	Steps []Step
+}
+
+// Step is one step of a plan.
+type Step struct {
+	ID        string
+	Title     string
+	Status    string   // StatusPending, StatusRunning, StatusDone, StatusFailed or StatusSkipped
+	DependsOn []string // IDs of steps that must finish first (optional)
+	Tool      string   // Tool the step runs (optional)
+}
+
+// Value returns the plan as a Plan struct value.
+func (p Plan) Value() *glyph.GValue {
+	steps := make([]*glyph.GValue, len(p.Steps))
+	for i, s := range p.Steps {
+		steps[i] = s.Value()
+	}
+	var b builder
+	b.str("goal", p.Goal, true)
+	b.value("steps", glyph.List(steps...))
+	return b.build("Plan")
+}
+
+// Value returns the step as a Step struct value.
+func (s Step) Value() *glyph.GValue {
+	var b builder
+	b.str("id", s.ID, true)
+	b.str("title", s.Title, true)
+	b.str("status", s.Status, true)
+	if len(s.DependsOn) > 0 {
+		deps := make([]*glyph.GValue, len(s.DependsOn))
+		for i, d := range s.DependsOn {
+			deps[i] = glyph.Str(d)
+		}
+		b.value("depends_on", glyph.List(deps...))
+	}
+	b.str("tool", s.Tool, false)
+	return b.build("Step")
+}
+
+// PlanFromValue validates v and reads it as a Plan.
+func PlanFromValue(v *glyph.GValue) (Plan, error) {
+	if err := check(v, "Plan"); err != nil {
+		return Plan{}, err
+	}
+	p := Plan{Goal: str(v, "goal")}
+	for _, item := range list(v, "steps") {
+		s := Step{ID: str(item, "id"), Title: str(item, "title"), Status: str(item, "status"), Tool: str(item, "tool")}
+		for _, dep := range list(item, "depends_on") {
+			d, _ := dep.AsStr()
+			s.DependsOn = append(s.DependsOn, d)
+		}
+		p.Steps = append(p.Steps, s)
+	}
+	return p, nil
+}
+
+// builder collects the fields of a struct value, leaving out unset ones.
+type builder struct {
+	fields []glyph.MapEntry
+}
+
+func (b *builder) value(key string, v *glyph.GValue) {
+	if v != nil {
+		b.fields = append(b.fields, glyph.MapEntry{Key: key, Value: v})
+	}
+}
+
+func (b *builder) str(key, s string, required bool) {
+	if required || s != "" {
+		b.value(key, glyph.Str(s))
+	}
+}
+
+func (b *builder) artifacts(as []Artifact) {
+	if len(as) == 0 {
+		return
+	}
+	items := make([]*glyph.GValue, len(as))
+	for i, a := range as {
+		items[i] = a.Value()
+	}
+	b.value("artifacts", glyph.List(items...))
+}
+
+func (b *builder) build(typeName string) *glyph.GValue {
+	return glyph.Struct(typeName, b.fields...)
+}
+
+// field returns the value of key in v, or nil if it is absent or null.
+func field(v *glyph.GValue, key string) *glyph.GValue {
+	if v == nil {
+		return nil
+	}
+	f := v.Get(key)
+	if f == nil || f.IsNull() {
+		return nil
+	}
+	return f
+}
+
+func str(v *glyph.GValue, key string) string {
+	s, _ := field(v, key).AsStr()
+	return s
+}
+
+func timeField(v *glyph.GValue, key string) time.Time {
+	t, _ := field(v, key).AsTime()
+	return t
+}
+
+func list(v *glyph.GValue, key string) []*glyph.GValue {
+	items, _ := field(v, key).AsList()
+	return items
+}
+
+func artifacts(v *glyph.GValue) ([]Artifact, error) {
+	var out []Artifact
+	for _, item := range list(v, "artifacts") {
+		a, err := glyph.ArtifactFromValue(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
@@ -429,6 +429,7 @@ func (s *StreamSession) LearnKeys(v *GValue) {
 }
 
 func (s *StreamSession) extractKeys(v *GValue) {
+	v.force()
 	if v == nil {
 		return
 	}
@@ -529,6 +530,7 @@ func EncodeDictFrame(v *GValue, session *StreamSession) []byte {
 }
 
 func encodeDictValue(buf []byte, v *GValue, session *StreamSession) []byte {
+	v.force()
 	if v == nil || v.IsNull() {
 		buf = append(buf, 0x00) // null tag
 		return buf
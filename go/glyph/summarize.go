@@ -0,0 +1,236 @@
+package glyph
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ============================================================
+// Budget-Aware Summarization
+// ============================================================
+//
+// Long-lived state documents grow past what fits in a model's context. With
+// EmitOptions.Summarizer set, every subtree whose estimated size exceeds
+// EmitOptions.SummaryThreshold tokens is offered to the summarizer; if it
+// returns a value, the subtree is emitted as
+//
+//     Summary{of=^#9f86d081884c7d65 value="3 200 log lines, 2 errors"}
+//
+// where ^#hash is a content-hash ref to the full subtree. When
+// EmitOptions.Subtrees is set the full subtree is stored there under that
+// hash, and ExpandSummaries restores it after parsing. Subtrees are visited
+// top-down, so a summarized subtree's children are never visited; the root
+// itself is never summarized.
+
+// Summarizer returns a short stand-in for the subtree v at path, or nil to
+// keep v (its children may still be summarized). Paths use the validator's
+// form: "a.b[2].c".
+type Summarizer func(path string, v *GValue) *GValue
+
+// SummaryTypeName is the struct type that wraps a summarized subtree.
+const SummaryTypeName = "Summary"
+
+// SubtreeStore holds full subtrees replaced by summaries, keyed by content
+// hash.
+type SubtreeStore interface {
+	// Put stores v under hash.
+	Put(hash string, v *GValue) error
+	// Get returns the subtree stored under hash.
+	Get(hash string) (*GValue, error)
+}
+
+// MemorySubtreeStore is an in-memory SubtreeStore. Safe for concurrent use.
+type MemorySubtreeStore struct {
+	mu    sync.RWMutex
+	trees map[string]*GValue
+}
+
+// NewMemorySubtreeStore creates an empty in-memory subtree store.
+func NewMemorySubtreeStore() *MemorySubtreeStore {
+	return &MemorySubtreeStore{trees: make(map[string]*GValue)}
+}
+
+// Put stores a copy of v under hash.
+func (s *MemorySubtreeStore) Put(hash string, v *GValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.trees[hash]; !ok {
+		s.trees[hash] = deepCopy(v)
+	}
+	return nil
+}
+
+// Get returns the subtree stored under hash.
+func (s *MemorySubtreeStore) Get(hash string) (*GValue, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.trees[hash]
+	if !ok {
+		return nil, fmt.Errorf("subtree not found: %s", hash)
+	}
+	return v, nil
+}
+
+// ContentRef returns the ^#hash ref for v: the first 16 hex digits of
+// FingerprintLoose(v).
+func ContentRef(v *GValue) RefID {
+	return RefID{Value: "#" + FingerprintLoose(v)[:16]}
+}
+
+// SummarizeSubtrees returns v with every subtree larger than threshold
+// estimated tokens replaced by a Summary struct, for those subtrees fn
+// returns a summary for. If store is non-nil the replaced subtrees are put
+// there; a subtree whose Put fails is kept in full. v is not modified.
+func SummarizeSubtrees(v *GValue, threshold int, fn Summarizer, store SubtreeStore) *GValue {
+	if fn == nil || v == nil {
+		return v
+	}
+	s := &summarizer{threshold: threshold, fn: fn, store: store}
+	return s.children(v, "")
+}
+
+type summarizer struct {
+	threshold int
+	fn        Summarizer
+	store     SubtreeStore
+}
+
+func (s *summarizer) visit(v *GValue, path string) *GValue {
+	if v == nil {
+		return v
+	}
+	if EstimateTokens(CanonicalizeLooseNoTabular(v)) > s.threshold {
+		if summary := s.fn(path, v); summary != nil {
+			ref := ContentRef(v)
+			if s.store == nil || s.store.Put(ref.Value[1:], v) == nil {
+				return Struct(SummaryTypeName,
+					MapEntry{Key: "of", Value: IDFromRef(ref)},
+					MapEntry{Key: "value", Value: summary},
+				)
+			}
+		}
+	}
+	return s.children(v, path)
+}
+
+// children returns v with its children visited, copying v only if a child
+// changed.
+func (s *summarizer) children(v *GValue, path string) *GValue {
+	v.force()
+	switch v.typ {
+	case TypeList:
+		var out []*GValue
+		for i, elem := range v.listVal {
+			next := s.visit(elem, path+"["+strconv.Itoa(i)+"]")
+			if next != elem && out == nil {
+				out = append([]*GValue(nil), v.listVal...)
+			}
+			if out != nil {
+				out[i] = next
+			}
+		}
+		if out != nil {
+			return List(out...)
+		}
+	case TypeMap:
+		if entries, ok := s.entries(v.mapVal, path); ok {
+			return Map(entries...)
+		}
+	case TypeStruct:
+		if fields, ok := s.entries(v.structVal.Fields, path); ok {
+			return Struct(v.structVal.TypeName, fields...)
+		}
+	case TypeSum:
+		if next := s.visit(v.sumVal.Value, path); next != v.sumVal.Value {
+			return Sum(v.sumVal.Tag, next)
+		}
+	}
+	return v
+}
+
+func (s *summarizer) entries(entries []MapEntry, path string) ([]MapEntry, bool) {
+	var out []MapEntry
+	for i, e := range entries {
+		next := s.visit(e.Value, joinPath(path, e.Key))
+		if next != e.Value && out == nil {
+			out = append([]MapEntry(nil), entries...)
+		}
+		if out != nil {
+			out[i].Value = next
+		}
+	}
+	return out, out != nil
+}
+
+// IsSummary reports whether v is a Summary struct produced by
+// SummarizeSubtrees.
+func IsSummary(v *GValue) bool {
+	if v == nil || v.typ != TypeStruct || v.structVal.TypeName != SummaryTypeName {
+		return false
+	}
+	of := v.Get("of")
+	return of != nil && of.typ == TypeID && of.idVal().Prefix == "" &&
+		len(of.idVal().Value) > 1 && of.idVal().Value[0] == '#'
+}
+
+// ExpandSummaries returns v with every Summary struct replaced by the full
+// subtree stored under its hash. v is not modified.
+func ExpandSummaries(v *GValue, store SubtreeStore) (*GValue, error) {
+	v.force()
+	if v == nil {
+		return v, nil
+	}
+	if IsSummary(v) {
+		hash := v.Get("of").idVal().Value[1:]
+		full, err := store.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		return deepCopy(full), nil
+	}
+
+	expandEntries := func(entries []MapEntry) ([]MapEntry, error) {
+		out := make([]MapEntry, len(entries))
+		for i, e := range entries {
+			next, err := ExpandSummaries(e.Value, store)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = MapEntry{Key: e.Key, Value: next}
+		}
+		return out, nil
+	}
+
+	switch v.typ {
+	case TypeList:
+		out := make([]*GValue, len(v.listVal))
+		for i, elem := range v.listVal {
+			next, err := ExpandSummaries(elem, store)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = next
+		}
+		return List(out...), nil
+	case TypeMap:
+		entries, err := expandEntries(v.mapVal)
+		if err != nil {
+			return nil, err
+		}
+		return Map(entries...), nil
+	case TypeStruct:
+		fields, err := expandEntries(v.structVal.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return Struct(v.structVal.TypeName, fields...), nil
+	case TypeSum:
+		inner, err := ExpandSummaries(v.sumVal.Value, store)
+		if err != nil {
+			return nil, err
+		}
+		return Sum(v.sumVal.Tag, inner), nil
+	}
+	return v, nil
+}
@@ -0,0 +1,100 @@
+package glyph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func summaryState() *GValue {
+	var lines []*GValue
+	for i := 0; i < 40; i++ {
+		lines = append(lines, Str(fmt.Sprintf("step %d finished without errors", i)))
+	}
+	return Struct("Task",
+		MapEntry{Key: "name", Value: Str("migrate")},
+		MapEntry{Key: "log", Value: List(lines...)},
+		MapEntry{Key: "meta", Value: Map(MapEntry{Key: "owner", Value: Str("ops")})},
+	)
+}
+
+func TestSummarize_EmitAndExpand(t *testing.T) {
+	state := summaryState()
+	store := NewMemorySubtreeStore()
+	var paths []string
+	opts := DefaultEmitOptions()
+	opts.Summarizer = func(path string, v *GValue) *GValue {
+		paths = append(paths, path)
+		return Str(fmt.Sprintf("%d log lines", len(v.listVal)))
+	}
+	opts.SummaryThreshold = 50
+	opts.Subtrees = store
+
+	out := EmitWithOptions(state, opts)
+	ref := canonRef(ContentRef(state.Get("log")))
+	want := `Task{log=Summary{of=` + ref + ` value="40 log lines"} meta={owner:ops} name=migrate}`
+	if out != want {
+		t.Fatalf("got  %s\nwant %s", out, want)
+	}
+	if !strings.HasPrefix(ref, "^#") || len(ref) != 18 {
+		t.Errorf("content ref should be bare ^#<16 hex>: %s", ref)
+	}
+	if len(paths) != 1 || paths[0] != "log" {
+		t.Errorf("only the log subtree is over budget: %v", paths)
+	}
+
+	parsed, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSummary(parsed.Value.Get("log")) {
+		t.Fatalf("parsed log should be a summary: %s", Emit(parsed.Value.Get("log")))
+	}
+	full, err := ExpandSummaries(parsed.Value, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(full, state) {
+		t.Errorf("expanded state differs:\n%s", Emit(full))
+	}
+	if len(state.Get("log").listVal) != 40 {
+		t.Error("input must not be modified")
+	}
+}
+
+func TestSummarize_DeclineAndDescend(t *testing.T) {
+	state := summaryState()
+	// Declining the whole log lets individual entries be offered instead.
+	out := SummarizeSubtrees(state, 5, func(path string, v *GValue) *GValue {
+		if path == "log[3]" {
+			return Str("…")
+		}
+		return nil
+	}, nil)
+	if !IsSummary(out.Get("log").listVal[3]) || IsSummary(out.Get("log").listVal[2]) {
+		t.Errorf("only log[3] should be summarized: %s", Emit(out))
+	}
+	if SummarizeSubtrees(state, 1<<20, func(string, *GValue) *GValue { return Str("x") }, nil) != state {
+		t.Error("nothing over budget: value should be returned unchanged")
+	}
+
+	if _, err := ExpandSummaries(out, NewMemorySubtreeStore()); err == nil {
+		t.Error("expanding without the stored subtree should fail")
+	}
+}
+
+func TestContentRef_Lexing(t *testing.T) {
+	v := ID("", "#0123abcd")
+	for _, text := range []string{Emit(v), CanonicalizeLoose(v)} {
+		if text != "^#0123abcd" {
+			t.Errorf("got %s", text)
+		}
+		r, err := Parse(text)
+		if err != nil || r.Value.typ != TypeID || r.Value.idVal().Value != "#0123abcd" {
+			t.Errorf("parse %s: %v", text, err)
+		}
+	}
+	if got := canonRef(RefID{Value: "#"}); got != `^"#"` {
+		t.Errorf("a lone # must stay quoted: %s", got)
+	}
+}
@@ -0,0 +1,207 @@
+package glyph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ============================================================
+// Table Statistics
+// ============================================================
+//
+// A model asked "is latency getting worse?" needs the distribution of a
+// column, not ten thousand rows of it. SummarizeTable reduces a list of
+// objects to the statistics of its numeric columns:
+//
+//	TableStats{rows=1200 columns={
+//	    latency_ms=ColumnStats{count=1187 min=3 max=980 mean=45.21 p50=31.0 p95=210.0}
+//	}}
+//
+// count is the number of numeric cells (null, missing, NaN and infinite
+// cells are skipped);
+// min and max keep the column's type, mean and percentiles are floats
+// rounded to Digits significant digits. With Ref set the stats are wrapped
+// in a Summary struct whose ^#hash names the full list, so ExpandSummaries
+// can bring the rows back. TableSummarizer plugs the same reduction into
+// EmitOptions.Summarizer.
+
+// TableStatsTypeName and ColumnStatsTypeName are the struct types produced by
+// SummarizeTable.
+const (
+	TableStatsTypeName  = "TableStats"
+	ColumnStatsTypeName = "ColumnStats"
+)
+
+// TableSummaryOpts configures SummarizeTable.
+type TableSummaryOpts struct {
+	Columns     []string     // Columns to summarize; nil means every numeric column
+	Percentiles []float64    // Percentiles to report (default 50, 95)
+	Digits      int          // Significant digits of mean and percentiles (default 4)
+	Ref         bool         // Wrap the stats in a Summary with a ^#hash ref to the list
+	Store       SubtreeStore // With Ref, where the full list is put (optional)
+}
+
+// SummarizeTable returns a TableStats struct describing the numeric columns
+// of list, a list of maps or structs. A column is numeric if every non-null
+// cell holds an int or float. Requested Columns that are not numeric are an
+// error.
+func SummarizeTable(list *GValue, opts TableSummaryOpts) (*GValue, error) {
+	list.force()
+	if list == nil || list.typ != TypeList {
+		return nil, fmt.Errorf("glyph: summarize table: expected list, got %s", list.Type())
+	}
+	if opts.Digits <= 0 {
+		opts.Digits = 4
+	}
+	if opts.Percentiles == nil {
+		opts.Percentiles = []float64{50, 95}
+	}
+	for _, p := range opts.Percentiles {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, fmt.Errorf("glyph: summarize table: percentile %v out of range", p)
+		}
+	}
+
+	cols, err := tableColumns(list.listVal)
+	if err != nil {
+		return nil, err
+	}
+	names := opts.Columns
+	if names == nil {
+		for _, c := range cols.order {
+			if cols.numeric(c) {
+				names = append(names, c)
+			}
+		}
+	}
+
+	entries := make([]MapEntry, 0, len(names))
+	for _, name := range names {
+		if !cols.numeric(name) {
+			return nil, fmt.Errorf("glyph: summarize table: column %q is not numeric", name)
+		}
+		entries = append(entries, MapEntry{Key: name, Value: columnStats(cols.cells[name], opts)})
+	}
+	stats := Struct(TableStatsTypeName,
+		MapEntry{Key: "rows", Value: Int(int64(len(list.listVal)))},
+		MapEntry{Key: "columns", Value: Map(entries...)},
+	)
+	if !opts.Ref {
+		return stats, nil
+	}
+
+	ref := ContentRef(list)
+	if opts.Store != nil {
+		if err := opts.Store.Put(ref.Value[1:], list); err != nil {
+			return nil, fmt.Errorf("glyph: summarize table: %w", err)
+		}
+	}
+	return Struct(SummaryTypeName,
+		MapEntry{Key: "of", Value: IDFromRef(ref)},
+		MapEntry{Key: "value", Value: stats},
+	), nil
+}
+
+// TableSummarizer returns a Summarizer that replaces lists of objects with
+// at least one numeric column by their TableStats. Ref and Store in opts are
+// ignored; the summarizer's caller adds the ref.
+func TableSummarizer(opts TableSummaryOpts) Summarizer {
+	opts.Ref, opts.Store = false, nil
+	return func(path string, v *GValue) *GValue {
+		stats, err := SummarizeTable(v, opts)
+		if err != nil || stats.Get("columns").Len() == 0 {
+			return nil
+		}
+		return stats
+	}
+}
+
+// tableCells holds the cells of each column, in order of first appearance.
+type tableCells struct {
+	order   []string
+	cells   map[string][]*GValue // Non-null cells
+	invalid map[string]bool      // Columns with a non-numeric cell
+}
+
+func (t *tableCells) numeric(col string) bool {
+	return len(t.cells[col]) > 0 && !t.invalid[col]
+}
+
+func tableColumns(rows []*GValue) (*tableCells, error) {
+	t := &tableCells{cells: make(map[string][]*GValue), invalid: make(map[string]bool)}
+	for i, row := range rows {
+		row.force()
+		if row == nil || row.typ != TypeMap && row.typ != TypeStruct {
+			return nil, fmt.Errorf("glyph: summarize table: row %d is a %s, not an object", i, row.Type())
+		}
+		row.Range(func(key string, cell *GValue) bool {
+			if _, ok := t.cells[key]; !ok {
+				t.order = append(t.order, key)
+				t.cells[key] = nil
+			}
+			cell.force()
+			switch {
+			case cell.IsNull():
+			case cell.typ == TypeFloat && (math.IsNaN(cell.floatVal) || math.IsInf(cell.floatVal, 0)):
+				// Skipped like null: no statistic can include it.
+			case cell.typ == TypeInt, cell.typ == TypeFloat:
+				t.cells[key] = append(t.cells[key], cell)
+			default:
+				t.invalid[key] = true
+			}
+			return true
+		})
+	}
+	return t, nil
+}
+
+// columnStats returns the ColumnStats struct of numeric cells.
+func columnStats(cells []*GValue, opts TableSummaryOpts) *GValue {
+	xs := make([]float64, len(cells))
+	minCell, maxCell := cells[0], cells[0]
+	sum := 0.0
+	for i, c := range cells {
+		xs[i], _ = c.Number()
+		sum += xs[i]
+		if lo, _ := minCell.Number(); xs[i] < lo {
+			minCell = c
+		}
+		if hi, _ := maxCell.Number(); xs[i] > hi {
+			maxCell = c
+		}
+	}
+	sort.Float64s(xs)
+
+	round := func(f float64) *GValue {
+		r, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', opts.Digits, 64), 64)
+		return Float(r)
+	}
+	fields := []MapEntry{
+		{Key: "count", Value: Int(int64(len(xs)))},
+		{Key: "min", Value: minCell},
+		{Key: "max", Value: maxCell},
+		{Key: "mean", Value: round(sum / float64(len(xs)))},
+	}
+	for _, p := range opts.Percentiles {
+		fields = append(fields, MapEntry{Key: percentileKey(p), Value: round(percentile(xs, p))})
+	}
+	return Struct(ColumnStatsTypeName, fields...)
+}
+
+// percentile returns the p-th percentile of sorted xs, interpolating
+// linearly between the closest ranks.
+func percentile(xs []float64, p float64) float64 {
+	pos := p / 100 * float64(len(xs)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(xs) {
+		return xs[len(xs)-1]
+	}
+	return xs[lo] + (pos-float64(lo))*(xs[lo+1]-xs[lo])
+}
+
+// percentileKey names the field of percentile p: p50, p99.9.
+func percentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
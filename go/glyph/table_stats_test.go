@@ -0,0 +1,108 @@
+package glyph
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func statsRows() *GValue {
+	var rows []*GValue
+	for i := 1; i <= 20; i++ {
+		latency := Int(int64(i * 10))
+		if i == 7 {
+			latency = Null()
+		}
+		rows = append(rows, Map(
+			MapEntry{Key: "id", Value: Int(int64(i))},
+			MapEntry{Key: "status", Value: Str("ok")},
+			MapEntry{Key: "latency", Value: latency},
+			MapEntry{Key: "score", Value: Float(float64(i) / 3)},
+		))
+	}
+	return List(rows...)
+}
+
+func TestSummarizeTable(t *testing.T) {
+	stats, err := SummarizeTable(statsRows(), TableSummaryOpts{Columns: []string{"latency", "score"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// latency skips its null cell: 19 of 10..200, without 70.
+	want := "TableStats{columns={" +
+		"latency:ColumnStats{count=19 max=200 mean=106.8 min=10 p50=110.0 p95=191.0} " +
+		"score:ColumnStats{count=20 max=6.666666666666667 mean=3.5 min=0.3333333333333333 p50=3.5 p95=6.35}" +
+		"} rows=20}"
+	if got := Emit(stats); got != want {
+		t.Errorf("stats =\n%s\nwant\n%s", got, want)
+	}
+
+	// Every numeric column by default, in order of first appearance.
+	stats, err = SummarizeTable(statsRows(), TableSummaryOpts{Percentiles: []float64{99.9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, _ := stats.Get("columns").AsMap()
+	if len(cols) != 3 || cols[0].Key != "id" || cols[1].Key != "latency" || cols[0].Value.Get("p99.9") == nil {
+		t.Errorf("columns = %s", Emit(stats))
+	}
+
+	for _, tc := range []struct {
+		list *GValue
+		opts TableSummaryOpts
+		want string
+	}{
+		{Str("x"), TableSummaryOpts{}, "expected list"},
+		{List(Int(1)), TableSummaryOpts{}, "row 0"},
+		{statsRows(), TableSummaryOpts{Columns: []string{"status"}}, "not numeric"},
+		{statsRows(), TableSummaryOpts{Columns: []string{"missing"}}, "not numeric"},
+		{statsRows(), TableSummaryOpts{Percentiles: []float64{101}}, "out of range"},
+	} {
+		if _, err := SummarizeTable(tc.list, tc.opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("err = %v, want %q", err, tc.want)
+		}
+	}
+}
+
+func TestSummarizeTable_Ref(t *testing.T) {
+	rows := statsRows()
+	store := NewMemorySubtreeStore()
+	summary, err := SummarizeTable(rows, TableSummaryOpts{Ref: true, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSummary(summary) || summary.Get("value").Get("rows") == nil {
+		t.Fatalf("summary = %s", Emit(summary))
+	}
+	full, err := ExpandSummaries(Map(MapEntry{Key: "calls", Value: summary}), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualLoose(full.Get("calls"), rows) {
+		t.Error("expanded list differs from the original")
+	}
+}
+
+func TestTableSummarizer(t *testing.T) {
+	doc := Map(
+		MapEntry{Key: "calls", Value: statsRows()},
+		MapEntry{Key: "tags", Value: List(Str("a"), Str("b"), Str("c"), Str("d"))},
+	)
+	store := NewMemorySubtreeStore()
+	out := SummarizeSubtrees(doc, 5, TableSummarizer(TableSummaryOpts{Columns: []string{"latency"}}), store)
+	if !IsSummary(out.Get("calls")) || out.Get("tags").Type() != TypeList {
+		t.Fatalf("out = %s", Emit(out))
+	}
+	if got := Emit(out.Get("calls").Get("value")); !strings.Contains(got, "latency:ColumnStats{count=19") {
+		t.Errorf("stats = %s", got)
+	}
+
+	// Non-finite cells are skipped.
+	rows := List(
+		Map(MapEntry{Key: "x", Value: Float(math.NaN())}),
+		Map(MapEntry{Key: "x", Value: Float(2)}),
+	)
+	if stats, err := SummarizeTable(rows, TableSummaryOpts{}); err != nil || Emit(stats.Get("columns").Get("x").Get("count")) != "1" {
+		t.Errorf("NaN stats = %v, %v", stats, err)
+	}
+}
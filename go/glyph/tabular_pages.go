@@ -0,0 +1,164 @@
+package glyph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Paginated Tables
+// ============================================================
+//
+// A large table can be sent as a run of standalone @tab blocks, one per
+// page, each carrying its position and a continuation token:
+//
+//   @tab _ rows=100 cols=3 page=1/7 cont=3f9a0c41d2e8.2 [id name score]
+//   |1|Alice|0.95|
+//   ...
+//   @end
+//
+// The token is "<table id>.<next page>", where the table id is the first
+// 12 hex digits of FingerprintLoose of the whole list. The last page has
+// no cont=. A server that is handed a token back re-paginates its data,
+// checks the id, and resumes at the page the token names.
+
+// ErrNotTablePage is returned by TableAssembler.Add for input that is not a
+// page of a paginated table.
+var ErrNotTablePage = errors.New("not a paginated @tab block")
+
+// PaginateTabular splits the list v into pages of at most pageRows rows.
+// Every page uses the columns and type auto-tabular chooses for the whole
+// list, so the pages reassemble to v; each page's layout follows
+// opts.TableLayout. It returns an error if v is not a list auto-tabular
+// would write as a table under opts.
+func PaginateTabular(v *GValue, pageRows int, opts LooseCanonOpts) ([]string, error) {
+	if pageRows < 1 {
+		return nil, fmt.Errorf("page size must be positive, got %d", pageRows)
+	}
+	v.force()
+	if v == nil || v.typ != TypeList {
+		return nil, fmt.Errorf("paginate: expected list")
+	}
+	items := v.listVal
+	table := planLooseTable(items, opts)
+	if !opts.AutoTabular || table == nil {
+		return nil, fmt.Errorf("paginate: list is not tabular")
+	}
+
+	id := FingerprintLoose(v)[:12]
+	npages := (len(items) + pageRows - 1) / pageRows
+	pages := make([]string, 0, npages)
+	var b strings.Builder
+	for p := 1; p <= npages; p++ {
+		attrs := "page=" + strconv.Itoa(p) + "/" + strconv.Itoa(npages)
+		if p < npages {
+			attrs += " cont=" + id + "." + strconv.Itoa(p+1)
+		}
+		b.Reset()
+		writeTablePageLoose(&b, items[(p-1)*pageRows:min(p*pageRows, len(items))], table, attrs, opts)
+		pages = append(pages, b.String())
+	}
+	return pages, nil
+}
+
+// ParseContToken splits a cont= token written by PaginateTabular into the
+// table id and the page it continues at.
+func ParseContToken(token string) (id string, page int, err error) {
+	dot := strings.LastIndexByte(token, '.')
+	if dot <= 0 {
+		return "", 0, fmt.Errorf("invalid cont token: %q", token)
+	}
+	page, err = strconv.Atoi(token[dot+1:])
+	if err != nil || page < 2 {
+		return "", 0, fmt.Errorf("invalid cont token: %q", token)
+	}
+	return token[:dot], page, nil
+}
+
+// TableAssembler reassembles the pages of one paginated table. Pages must
+// be added in order; the zero value is ready to use.
+type TableAssembler struct {
+	KeyDict []string // Resolves #N keys in cell values, as for ParseLoose
+
+	first *TabularMetadata
+	last  *TabularMetadata
+	rows  []*GValue
+}
+
+// Add parses one page and appends its rows. It returns ErrNotTablePage if
+// page is not a paginated @tab block, and an error if the page is out of
+// order, truncated, or does not belong to the table assembled so far.
+func (a *TableAssembler) Add(page string) error {
+	if !isTabHeader(strings.TrimSpace(page)) {
+		return ErrNotTablePage
+	}
+	rows, meta, err := parseTabularLoose(page, a.KeyDict)
+	if err != nil {
+		return err
+	}
+	if meta.Pages == 0 {
+		return ErrNotTablePage
+	}
+	if a.Done() {
+		return fmt.Errorf("page %d/%d: table already complete", meta.Page, meta.Pages)
+	}
+
+	want := 1
+	if a.last != nil {
+		want = a.last.Page + 1
+	}
+	if meta.Page != want {
+		return fmt.Errorf("page %d/%d: expected page %d", meta.Page, meta.Pages, want)
+	}
+	if !meta.Ended || meta.Truncated() {
+		return fmt.Errorf("page %d/%d: truncated (%d of %d rows)", meta.Page, meta.Pages, meta.ActualRows, meta.Rows)
+	}
+	if (meta.Cont == "") != (meta.Page == meta.Pages) {
+		return fmt.Errorf("page %d/%d: cont= must be present on every page but the last", meta.Page, meta.Pages)
+	}
+	if a.first != nil {
+		if meta.Pages != a.first.Pages || meta.Type != a.first.Type || !slices.Equal(meta.Keys, a.first.Keys) {
+			return fmt.Errorf("page %d/%d: columns or page count differ from page 1", meta.Page, meta.Pages)
+		}
+		if id, next, err := ParseContToken(a.last.Cont); err == nil {
+			if next != meta.Page || (meta.Cont != "" && !strings.HasPrefix(meta.Cont, id+".")) {
+				return fmt.Errorf("page %d/%d: does not continue %s", meta.Page, meta.Pages, a.last.Cont)
+			}
+		}
+	} else {
+		a.first = meta
+	}
+
+	a.last = meta
+	a.rows = append(a.rows, rows.listVal...)
+	return nil
+}
+
+// Done reports whether the last page has been added.
+func (a *TableAssembler) Done() bool {
+	return a.last != nil && a.last.Page == a.last.Pages
+}
+
+// Cont returns the token for the next page to request, or "" before the
+// first page and once the table is complete.
+func (a *TableAssembler) Cont() string {
+	if a.last == nil {
+		return ""
+	}
+	return a.last.Cont
+}
+
+// Value returns the reassembled list. It returns an error until Done.
+func (a *TableAssembler) Value() (*GValue, error) {
+	if !a.Done() {
+		received, total := 0, 0
+		if a.last != nil {
+			received, total = a.last.Page, a.last.Pages
+		}
+		return nil, fmt.Errorf("table incomplete: %d of %d pages", received, total)
+	}
+	return List(a.rows...), nil
+}
@@ -0,0 +1,129 @@
+package glyph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func makePagedRows(n int) *GValue {
+	rows := make([]*GValue, n)
+	for i := range rows {
+		rows[i] = Map(
+			MapEntry{Key: "id", Value: Int(int64(i + 1))},
+			MapEntry{Key: "name", Value: Str("user" + strings.Repeat("x", i%3))},
+		)
+	}
+	return List(rows...)
+}
+
+func TestPaginateTabular(t *testing.T) {
+	v := makePagedRows(7)
+	pages, err := PaginateTabular(v, 3, DefaultLooseCanonOpts())
+	if err != nil {
+		t.Fatalf("PaginateTabular: %v", err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages, want 3", len(pages))
+	}
+
+	id := FingerprintLoose(v)[:12]
+	wantHeaders := []string{
+		"@tab _ rows=3 cols=2 page=1/3 cont=" + id + ".2 [id name]",
+		"@tab _ rows=3 cols=2 page=2/3 cont=" + id + ".3 [id name]",
+		"@tab _ rows=1 cols=2 page=3/3 [id name]",
+	}
+	for i, page := range pages {
+		if header, _, _ := strings.Cut(page, "\n"); header != wantHeaders[i] {
+			t.Errorf("page %d header = %q, want %q", i+1, header, wantHeaders[i])
+		}
+	}
+
+	// Each page is a standalone block.
+	_, meta, err := ParseTabularLooseWithMeta(pages[1])
+	if err != nil {
+		t.Fatalf("ParseTabularLooseWithMeta: %v", err)
+	}
+	if meta.Page != 2 || meta.Pages != 3 || meta.Cont != id+".3" || meta.ActualRows != 3 {
+		t.Errorf("meta = %+v", meta)
+	}
+	tokID, next, err := ParseContToken(meta.Cont)
+	if err != nil || tokID != id || next != 3 {
+		t.Errorf("ParseContToken(%q) = %q, %d, %v", meta.Cont, tokID, next, err)
+	}
+
+	var a TableAssembler
+	for i, page := range pages {
+		if a.Done() {
+			t.Fatalf("done before page %d", i+1)
+		}
+		if err := a.Add(page); err != nil {
+			t.Fatalf("Add page %d: %v", i+1, err)
+		}
+	}
+	got, err := a.Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	if !EqualLoose(got, v) || a.Cont() != "" {
+		t.Errorf("reassembled %s, cont %q", CanonicalizeLooseNoTabular(got), a.Cont())
+	}
+}
+
+func TestPaginateTabular_Errors(t *testing.T) {
+	opts := DefaultLooseCanonOpts()
+	if _, err := PaginateTabular(makePagedRows(5), 0, opts); err == nil {
+		t.Error("expected error for page size 0")
+	}
+	if _, err := PaginateTabular(List(Int(1), Int(2), Int(3)), 2, opts); err == nil {
+		t.Error("expected error for non-tabular list")
+	}
+	if _, err := PaginateTabular(makePagedRows(5), 2, NoTabularLooseCanonOpts()); err == nil {
+		t.Error("expected error with auto-tabular off")
+	}
+}
+
+func TestTableAssembler_Rejects(t *testing.T) {
+	pages, err := PaginateTabular(makePagedRows(9), 3, DefaultLooseCanonOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := PaginateTabular(makePagedRows(8), 3, DefaultLooseCanonOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		pages []string
+		want  string
+	}{
+		{"skipped page", []string{pages[0], pages[2]}, "expected page 2"},
+		{"other table", []string{pages[0], other[1]}, "does not continue"},
+		{"truncated", []string{strings.TrimSuffix(pages[0], "@end")}, "truncated"},
+		{"after last", []string{pages[0], pages[1], pages[2], pages[2]}, "already complete"},
+	}
+	for _, tt := range tests {
+		var a TableAssembler
+		var err error
+		for _, p := range tt.pages {
+			if err = a.Add(p); err != nil {
+				break
+			}
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	var a TableAssembler
+	for _, notPage := range []string{"{a=1}", CanonicalizeLoose(makePagedRows(3))} {
+		if err := a.Add(notPage); !errors.Is(err, ErrNotTablePage) {
+			t.Errorf("Add(%q) = %v, want ErrNotTablePage", notPage, err)
+		}
+	}
+	a.Add(pages[0])
+	if _, err := a.Value(); err == nil || a.Cont() == "" {
+		t.Errorf("partial table: Value err %v, Cont %q", err, a.Cont())
+	}
+}
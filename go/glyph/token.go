@@ -23,7 +23,10 @@ const (
 	TokenBareStr // bare_identifier
 	TokenRef     // ^prefix:value
 	TokenTime    // 2025-12-19T20:00Z
-	TokenBytes   // b64"base64..."
+	TokenBytes   // b64"base64...", b64u"...", b85"...", hex"..."
+	TokenGeo     // geo5"x y;dx dy" (see geo.go)
+	TokenVec     // vec16"base64..." (see vec.go)
+	TokenCustom  // money"12.34 USD" (see scalar_ext.go)
 
 	// Structural
 	TokenLBrace   // {
@@ -32,16 +35,19 @@ const (
 	TokenRBracket // ]
 	TokenLParen   // (
 	TokenRParen   // )
-	TokenEq       // = or :
+	TokenEq       // = or : (: only outside schema text)
 	TokenComma    // , (optional)
 	TokenPipe     // |
 	TokenDotDot   // .. (range operator in schema constraints, e.g. [0..10])
+	TokenEllipsis // … or ... (truncation marker, see truncate.go)
 
 	// Schema-related
-	TokenAt   // @
-	TokenHash // #
-	TokenLT   // <
-	TokenGT   // >
+	TokenAt    // @
+	TokenHash  // #
+	TokenLT    // <
+	TokenGT    // >
+	TokenNotEq // != (schema @assert comparisons)
+	TokenColon // : (schema text only; elsewhere : lexes as TokenEq)
 
 	// Identifiers (for type names, field names)
 	TokenIdent // Match, Team, fieldName
@@ -74,6 +80,12 @@ func (t TokenType) String() string {
 		return "TIME"
 	case TokenBytes:
 		return "BYTES"
+	case TokenGeo:
+		return "GEO"
+	case TokenVec:
+		return "VEC"
+	case TokenCustom:
+		return "CUSTOM"
 	case TokenLBrace:
 		return "{"
 	case TokenRBrace:
@@ -94,6 +106,8 @@ func (t TokenType) String() string {
 		return "|"
 	case TokenDotDot:
 		return ".."
+	case TokenEllipsis:
+		return "…"
 	case TokenAt:
 		return "@"
 	case TokenHash:
@@ -102,6 +116,10 @@ func (t TokenType) String() string {
 		return "<"
 	case TokenGT:
 		return ">"
+	case TokenNotEq:
+		return "!="
+	case TokenColon:
+		return ":"
 	case TokenIdent:
 		return "IDENT"
 	default:
@@ -133,6 +151,17 @@ type Lexer struct {
 	start  int // Start position of current token
 	tokens []Token
 	err    error
+
+	// In schema text ':' separates names from types and versions and lexes
+	// as TokenColon; in values it is a key separator like '='.
+	schema bool
+
+	// /// doc comments on lines of their own, keyed by the offset of the
+	// token on the line right after them (see ParseSchema).
+	docs     map[int]string
+	docLines []string
+	docLine  int // Line of the last doc comment
+	tokLine  int // Line of the last token
 }
 
 // NewLexer creates a new lexer for the given input.
@@ -145,6 +174,13 @@ func NewLexer(input string) *Lexer {
 	}
 }
 
+// newSchemaLexer creates a lexer for schema text (see ParseSchema).
+func newSchemaLexer(input string) *Lexer {
+	l := NewLexer(input)
+	l.schema = true
+	return l
+}
+
 // Tokenize returns all tokens from the input.
 func (l *Lexer) Tokenize() ([]Token, error) {
 	for {
@@ -163,6 +199,16 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 // nextToken returns the next token.
 func (l *Lexer) nextToken() Token {
 	l.skipWhitespaceAndComments()
+	if len(l.docLines) > 0 {
+		if l.line == l.docLine+1 && l.pos < len(l.input) {
+			if l.docs == nil {
+				l.docs = make(map[int]string)
+			}
+			l.docs[l.pos] = strings.Join(l.docLines, "\n")
+		}
+		l.docLines = l.docLines[:0]
+	}
+	l.tokLine = l.line
 
 	if l.pos >= len(l.input) {
 		return l.makeToken(TokenEOF, "")
@@ -193,7 +239,13 @@ func (l *Lexer) nextToken() Token {
 	case ')':
 		l.advance()
 		return Token{Type: TokenRParen, Value: ")", Pos: startPos}
-	case '=', ':':
+	case ':':
+		l.advance()
+		if l.schema {
+			return Token{Type: TokenColon, Value: ":", Pos: startPos}
+		}
+		return Token{Type: TokenEq, Value: ":", Pos: startPos}
+	case '=':
 		l.advance()
 		return Token{Type: TokenEq, Value: string(ch), Pos: startPos}
 	case ',':
@@ -214,17 +266,36 @@ func (l *Lexer) nextToken() Token {
 	case '>':
 		l.advance()
 		return Token{Type: TokenGT, Value: ">", Pos: startPos}
+	case '!':
+		// "!=" only appears in schema @assert comparisons; a lone '!' is
+		// not GLYPH syntax.
+		if l.pos+1 < len(l.input) && l.input[l.pos+1] == '=' {
+			l.advance()
+			l.advance()
+			return Token{Type: TokenNotEq, Value: "!=", Pos: startPos}
+		}
 	case '.':
-		// ".." is the schema range operator (e.g. [0..10]). A lone "." is not a
-		// valid standalone token (numbers/times consume their own '.' inside
-		// scanNumber before reaching here).
+		// ".." is the schema range operator (e.g. [0..10]) and "..." an
+		// ellipsis. A lone "." is not a valid standalone token (numbers/times
+		// consume their own '.' inside scanNumber before reaching here).
+		if strings.HasPrefix(l.input[l.pos:], "...") {
+			l.advance()
+			l.advance()
+			l.advance()
+			return Token{Type: TokenEllipsis, Value: "...", Pos: startPos}
+		}
 		if l.pos+1 < len(l.input) && l.input[l.pos+1] == '.' {
 			l.advance()
 			l.advance()
 			return Token{Type: TokenDotDot, Value: "..", Pos: startPos}
 		}
-	case '"':
-		return l.scanString()
+	case '"', '\'':
+		if strings.HasPrefix(l.input[l.pos:], `"""`) || strings.HasPrefix(l.input[l.pos:], "'''") {
+			return l.scanRawString()
+		}
+		if ch == '"' {
+			return l.scanString()
+		}
 	case '^':
 		return l.scanRef()
 	}
@@ -236,8 +307,17 @@ func (l *Lexer) nextToken() Token {
 		return Token{Type: TokenNull, Value: "∅", Pos: startPos}
 	}
 
-	// Numbers (including negative)
-	if ch == '-' || (ch >= '0' && ch <= '9') {
+	// Ellipsis (… is multi-byte UTF-8)
+	if strings.HasPrefix(l.input[l.pos:], "…") {
+		l.pos += len("…")
+		l.col += 1
+		return Token{Type: TokenEllipsis, Value: "…", Pos: startPos}
+	}
+
+	// Numbers (including negative). A leading '+' is not GLYPH syntax but is
+	// lexed as part of the number so the parser can report or repair it.
+	if ch == '-' || (ch >= '0' && ch <= '9') ||
+		(ch == '+' && l.pos+1 < len(l.input) && isDigit(l.input[l.pos+1])) {
 		return l.scanNumber()
 	}
 
@@ -248,7 +328,7 @@ func (l *Lexer) nextToken() Token {
 
 	// Unknown character
 	l.advance()
-	l.err = fmt.Errorf("unexpected character %q at %s", ch, startPos)
+	l.err = &ParseError{Message: fmt.Sprintf("unexpected character %q", ch), Pos: startPos}
 	return Token{Type: TokenError, Value: string(ch), Pos: startPos}
 }
 
@@ -260,7 +340,7 @@ func (l *Lexer) scanString() Token {
 	var sb strings.Builder
 	for {
 		if l.pos >= len(l.input) {
-			l.err = fmt.Errorf("unterminated string at %s", startPos)
+			l.err = &ParseError{Message: "unterminated string", Pos: startPos}
 			return Token{Type: TokenError, Value: sb.String(), Pos: startPos}
 		}
 
@@ -273,7 +353,7 @@ func (l *Lexer) scanString() Token {
 		if ch == '\\' {
 			l.advance()
 			if l.pos >= len(l.input) {
-				l.err = fmt.Errorf("unterminated escape at %s", l.currentPos())
+				l.err = &ParseError{Message: "unterminated escape", Pos: l.currentPos()}
 				return Token{Type: TokenError, Value: sb.String(), Pos: startPos}
 			}
 			escaped := l.peek()
@@ -294,7 +374,7 @@ func (l *Lexer) scanString() Token {
 				// for control characters, so it must decode back identically.
 				r, ok := l.scanUnicodeEscape()
 				if !ok {
-					l.err = fmt.Errorf("invalid \\u escape at %s", startPos)
+					l.err = &ParseError{Message: "invalid \\u escape", Pos: startPos}
 					return Token{Type: TokenError, Value: sb.String(), Pos: startPos}
 				}
 				sb.WriteRune(r)
@@ -317,6 +397,43 @@ func (l *Lexer) scanString() Token {
 	return Token{Type: TokenString, Value: str, Pos: startPos}
 }
 
+// scanRawString scans a raw string delimited by three double or three single
+// quotes.
+// The body is taken literally up to the first matching closing delimiter: no
+// escapes are processed. A newline directly after the opening delimiter is
+// dropped so multi-line bodies can start on their own line.
+func (l *Lexer) scanRawString() Token {
+	startPos := l.currentPos()
+	delim := l.input[l.pos : l.pos+3]
+	for i := 0; i < 3; i++ {
+		l.advance()
+	}
+	if strings.HasPrefix(l.input[l.pos:], "\r\n") {
+		l.advance()
+	}
+	if l.peek() == '\n' {
+		l.advance()
+	}
+
+	end := strings.Index(l.input[l.pos:], delim)
+	if end < 0 {
+		l.err = &ParseError{Message: "unterminated raw string", Pos: startPos}
+		for l.pos < len(l.input) {
+			l.advance()
+		}
+		return Token{Type: TokenError, Value: delim, Pos: startPos}
+	}
+	str := l.input[l.pos : l.pos+end]
+	for target := l.pos + end + len(delim); l.pos < target; {
+		l.advance()
+	}
+
+	if !utf8.ValidString(str) {
+		str = strings.ToValidUTF8(str, "\uFFFD")
+	}
+	return Token{Type: TokenString, Value: str, Pos: startPos}
+}
+
 // scanUnicodeEscape reads exactly four hex digits (the body of a \uXXXX escape,
 // with the leading "\u" already consumed) and returns the decoded rune.
 func (l *Lexer) scanUnicodeEscape() (rune, bool) {
@@ -343,16 +460,18 @@ func (l *Lexer) scanUnicodeEscape() (rune, bool) {
 	return code, true
 }
 
-// scanBytesLiteral scans the quoted base64 body of a b64"..." literal. The
-// "b64" prefix has already been consumed; the cursor is on the opening quote.
-// The raw base64 text is returned as the token value; decoding (and base64
-// validation) happens in the parser so errors carry a source position.
-func (l *Lexer) scanBytesLiteral(startPos Position) Token {
+// scanBytesLiteral scans the quoted body of a bytes literal such as b64"...".
+// The encoding prefix has already been consumed; the cursor is on the opening
+// quote. The token value is "<prefix>:<body>"; decoding (and validation)
+// happens in the parser so errors carry a source position. Geo literals
+// (geo5"...") and vector literals (vec16"...") share the same shape and are
+// scanned here too.
+func (l *Lexer) scanBytesLiteral(startPos Position, prefix string) Token {
 	l.advance() // consume opening "
 	var sb strings.Builder
 	for {
 		if l.pos >= len(l.input) {
-			l.err = fmt.Errorf("unterminated bytes literal at %s", startPos)
+			l.err = &ParseError{Message: "unterminated bytes literal", Pos: startPos}
 			return Token{Type: TokenError, Value: sb.String(), Pos: startPos}
 		}
 		ch := l.peek()
@@ -363,7 +482,13 @@ func (l *Lexer) scanBytesLiteral(startPos Position) Token {
 		sb.WriteByte(ch)
 		l.advance()
 	}
-	return Token{Type: TokenBytes, Value: sb.String(), Pos: startPos}
+	typ := TokenBytes
+	if geoLiteralPrefix(prefix+`"`) != "" {
+		typ = TokenGeo
+	} else if vecLiteralPrefix(prefix+`"`) != "" {
+		typ = TokenVec
+	}
+	return Token{Type: typ, Value: prefix + ":" + sb.String(), Pos: startPos}
 }
 
 // scanRef scans a reference: bare ^prefix:value or quoted ^"prefix:value".
@@ -380,8 +505,12 @@ func (l *Lexer) scanRef() Token {
 		return Token{Type: TokenRef, Value: inner.Value, Pos: startPos}
 	}
 
-	// Bare form: ^prefix:value
+	// Bare form: ^prefix:value, or ^#hash for a content-hash ref
 	var sb strings.Builder
+	if l.pos < len(l.input) && l.peek() == '#' {
+		sb.WriteByte('#')
+		l.advance()
+	}
 	for l.pos < len(l.input) {
 		ch := l.peek()
 		if isRefChar(ch) {
@@ -400,8 +529,10 @@ func (l *Lexer) scanNumber() Token {
 	startPos := l.currentPos()
 	start := l.pos
 
-	// Optional negative sign
-	if l.peek() == '-' {
+	// Optional sign
+	if l.peek() == '+' {
+		l.advance()
+	} else if l.peek() == '-' {
 		l.advance()
 
 		// Check for -Inf (with word boundary: next char must not be ident-continue)
@@ -444,6 +575,11 @@ func (l *Lexer) scanNumber() Token {
 		}
 	}
 
+	// A trailing '%' is kept on the token for the parser to report or repair.
+	if l.pos < len(l.input) && l.peek() == '%' {
+		l.advance()
+	}
+
 	value := l.input[start:l.pos]
 
 	// Check if this might be a time value (starts with 4 digits followed by -)
@@ -478,15 +614,41 @@ func (l *Lexer) scanIdentOrKeyword() Token {
 	startPos := l.currentPos()
 	start := l.pos
 
-	for l.pos < len(l.input) && isIdentContinue(l.peek()) {
-		l.advance()
+	for l.pos < len(l.input) {
+		if isIdentContinue(l.peek()) {
+			l.advance()
+			continue
+		}
+		// A dot followed by a letter continues a qualified type name
+		// (billing.Invoice); ".." and a trailing dot do not.
+		if l.peek() == '.' && l.pos+1 < len(l.input) && isIdentStart(l.input[l.pos+1]) {
+			l.advance()
+			continue
+		}
+		break
 	}
 
 	value := l.input[start:l.pos]
 
-	// Bytes literal: b64"..." (the prefix lexes as an identifier first).
-	if value == "b64" && l.peek() == '"' {
-		return l.scanBytesLiteral(startPos)
+	// Bytes literal: b64"...", b64u"...", b85"...", or hex"..." (the prefix
+	// lexes as an identifier first).
+	if l.peek() == '"' && bytesLiteralPrefix(value+`"`) == value {
+		return l.scanBytesLiteral(startPos, value)
+	}
+	if l.peek() == '"' && geoLiteralPrefix(value+`"`) == value {
+		return l.scanBytesLiteral(startPos, value)
+	}
+	if l.peek() == '"' && vecLiteralPrefix(value+`"`) == value {
+		return l.scanBytesLiteral(startPos, value)
+	}
+	// Custom scalar literal: money"12.34 USD". The text is a full string, so
+	// escapes are decoded here; the token value is "<tag>:<text>".
+	if l.peek() == '"' && isScalarTag(value) {
+		text := l.scanString()
+		if text.Type == TokenError {
+			return text
+		}
+		return Token{Type: TokenCustom, Value: value + ":" + text.Value, Pos: startPos}
 	}
 
 	// Check for keywords
@@ -516,18 +678,24 @@ func (l *Lexer) skipWhitespaceAndComments() {
 
 		if ch == '\n' {
 			l.advance()
-			l.line++
-			l.col = 1
 			continue
 		}
 
-		// Skip // comments
+		// Skip // comments, keeping /// doc comments on lines of their own
 		if ch == '/' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '/' {
+			start := l.pos
 			l.advance()
 			l.advance()
 			for l.pos < len(l.input) && l.peek() != '\n' {
 				l.advance()
 			}
+			if text, ok := strings.CutPrefix(l.input[start:l.pos], "///"); ok && l.line != l.tokLine {
+				if len(l.docLines) > 0 && l.line != l.docLine+1 {
+					l.docLines = l.docLines[:0] // a blank line detaches earlier docs
+				}
+				l.docLines = append(l.docLines, strings.TrimRight(strings.TrimPrefix(text, " "), " \t\r"))
+				l.docLine = l.line
+			}
 			continue
 		}
 
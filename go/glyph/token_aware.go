@@ -342,6 +342,7 @@ type tokenEmitter struct {
 }
 
 func (e *tokenEmitter) emit(v *GValue, depth int) {
+	v.force()
 	if v == nil || v.IsNull() {
 		e.sb.WriteString("∅")
 		return
@@ -370,19 +371,22 @@ func (e *tokenEmitter) emit(v *GValue, depth int) {
 	case TypeBytes:
 		// Same as regular emit
 		e.sb.WriteString("b64\"")
-		e.sb.WriteString(encodeBase64(v.bytesVal))
+		e.sb.WriteString(encodeBase64(v.bytesVal()))
 		e.sb.WriteString("\"")
 
 	case TypeTime:
-		e.sb.WriteString(v.timeVal.Format("2006-01-02T15:04:05Z07:00"))
+		e.sb.WriteString(v.timeVal().Format("2006-01-02T15:04:05Z07:00"))
 
 	case TypeID:
 		e.sb.WriteString("^")
-		if v.idVal.Prefix != "" {
-			e.sb.WriteString(v.idVal.Prefix)
+		if v.idVal().Prefix != "" {
+			e.sb.WriteString(v.idVal().Prefix)
 			e.sb.WriteString(":")
 		}
-		e.sb.WriteString(v.idVal.Value)
+		e.sb.WriteString(v.idVal().Value)
+
+	case TypeCustom:
+		e.sb.WriteString(canonCustom(v.customVal()))
 
 	case TypeList:
 		e.emitList(v, depth)
@@ -574,6 +578,7 @@ func (e *tokenEmitter) emitSum(v *GValue, depth int) {
 
 // isDefaultValue checks if a value is a "default" (false, 0, "", null).
 func isDefaultValue(v *GValue) bool {
+	v.force()
 	if v == nil {
 		return true
 	}
@@ -616,6 +621,7 @@ func ExpandAbbreviations(v *GValue, dict *KeyDict) {
 }
 
 func expandAbbrevHelper(v *GValue, dict *KeyDict) {
+	v.force()
 	switch v.typ {
 	case TypeList:
 		for _, elem := range v.listVal {
@@ -651,11 +657,10 @@ func expandAbbrevHelper(v *GValue, dict *KeyDict) {
 // Token Counting (Estimation)
 // ============================================================
 
-// EstimateTokens estimates the number of LLM tokens in a string.
-// Uses a simple heuristic: ~4 chars per token for English.
+// EstimateTokens estimates the number of LLM tokens in a string with
+// DefaultTokenEstimator.
 func EstimateTokens(s string) int {
-	// Simple heuristic for GPT-style tokenizers
-	return (len(s) + 3) / 4
+	return DefaultTokenEstimator.Count(s)
 }
 
 // TokenSavings calculates token savings from abbreviation.
@@ -0,0 +1,321 @@
+package glyph
+
+import (
+	"errors"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ============================================================
+// BPE Token Estimation
+// ============================================================
+//
+// Budget decisions (Optimize, ChooseKeyEncoding, context windows) need the
+// token cost of a string, and the exact answer needs the model's own BPE
+// vocabulary. TokenEstimator approximates it without one, in two steps:
+//
+//  1. Split the text into pieces exactly as the cl100k_base pre-tokenizer
+//     does: contractions, letter runs with an optional leading space or
+//     symbol, digit groups of one to three, punctuation runs, whitespace.
+//     BPE never merges across these pieces, so their boundaries are exact.
+//  2. Price each piece with a per-class rate. Digit groups are always one
+//     token. A word costs one token per camelCase part, plus a share of a
+//     token per letter past the sixth, since common short words are single
+//     tokens and rare long ones split. Punctuation, whitespace runs and
+//     non-ASCII letters each have a rate of their own.
+//
+// The default rates follow cl100k_base merges for English text and JSON;
+// Calibrate refits them from strings counted by an exact tokenizer. When an
+// exact tokenizer is available, pass it as the Tokenizer instead: the
+// estimator's Count method is itself a Tokenizer, so the two swap freely.
+
+// TokenEstimator estimates BPE token counts from per-class rates. The zero
+// value counts nothing; start from DefaultTokenEstimator or Calibrate.
+type TokenEstimator struct {
+	Word       float64 // Per word, or per camelCase part of one
+	LongLetter float64 // Per letter of a word part past longWordLetters
+	Punct      float64 // Per punctuation or symbol character
+	Space      float64 // Per whitespace run not attached to a word
+	Rune       float64 // Per non-ASCII letter
+}
+
+// DefaultTokenEstimator holds rates for cl100k_base-style vocabularies.
+var DefaultTokenEstimator = TokenEstimator{
+	Word:       1,
+	LongLetter: 0.25,
+	Punct:      0.5,
+	Space:      1,
+	Rune:       1,
+}
+
+// longWordLetters is the length up to which a word part counts as a single
+// token; most English words and identifiers of this length are one merge.
+const longWordLetters = 6
+
+// Count returns the estimated number of tokens in s: 0 for an empty string
+// and at least 1 otherwise.
+func (e TokenEstimator) Count(s string) int {
+	if s == "" {
+		return 0
+	}
+	f := tokenFeatures(s)
+	n := int(math.Round(f.digits + e.dot(f)))
+	return max(1, n)
+}
+
+// TokenSample is a string and its exact token count, for Calibrate.
+type TokenSample struct {
+	Text   string
+	Tokens int
+}
+
+// Calibrate fits the rates of an estimator to samples counted by an exact
+// tokenizer, by least squares. Rates the samples say little about (say,
+// Rune when none contains non-ASCII text) stay close to base. The samples
+// should resemble the text being budgeted; a few dozen suffice.
+func Calibrate(base TokenEstimator, samples []TokenSample) (TokenEstimator, error) {
+	if len(samples) == 0 {
+		return base, errors.New("calibrate: no samples")
+	}
+
+	// Solve (XᵀX + λI)w = Xᵀy + λ·base, a ridge regression pulled toward
+	// base, so a feature absent from the samples keeps its rate.
+	const n = 5
+	var a [n][n + 1]float64
+	prior := base.rates()
+	lambda := 1e-3 * float64(len(samples))
+	for i := range n {
+		a[i][i] = lambda
+		a[i][n] = lambda * prior[i]
+	}
+	for _, s := range samples {
+		f := tokenFeatures(s.Text)
+		x := f.vector()
+		y := float64(s.Tokens) - f.digits
+		for i := range n {
+			for j := range n {
+				a[i][j] += x[i] * x[j]
+			}
+			a[i][n] += x[i] * y
+		}
+	}
+
+	// Gaussian elimination with partial pivoting; the system is positive
+	// definite, so it always has a solution.
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for row := range n {
+			if row == col {
+				continue
+			}
+			k := a[row][col] / a[col][col]
+			for j := col; j <= n; j++ {
+				a[row][j] -= k * a[col][j]
+			}
+		}
+	}
+	var w [n]float64
+	for i := range n {
+		w[i] = math.Max(0, a[i][n]/a[i][i])
+	}
+	return TokenEstimator{Word: w[0], LongLetter: w[1], Punct: w[2], Space: w[3], Rune: w[4]}, nil
+}
+
+func (e TokenEstimator) rates() [5]float64 {
+	return [5]float64{e.Word, e.LongLetter, e.Punct, e.Space, e.Rune}
+}
+
+func (e TokenEstimator) dot(f pieceFeatures) float64 {
+	var sum float64
+	r := e.rates()
+	for i, x := range f.vector() {
+		sum += r[i] * x
+	}
+	return sum
+}
+
+// pieceFeatures counts the priced classes of a string's pre-token pieces.
+type pieceFeatures struct {
+	words, longLetters, punct, spaces, runes float64
+	digits                                   float64 // Digit groups, one token each
+}
+
+func (f pieceFeatures) vector() [5]float64 {
+	return [5]float64{f.words, f.longLetters, f.punct, f.spaces, f.runes}
+}
+
+// tokenFeatures splits s as the cl100k_base pattern does,
+//
+//	'(?i:[sdmt]|ll|ve|re) | [^\r\n\p{L}\p{N}]?\p{L}+ | \p{N}{1,3} |
+//	 ?[^\s\p{L}\p{N}]+[\r\n]* | \s*[\r\n]+ | \s+(?!\S) | \s+
+//
+// trying the alternatives in order at each position, and counts the
+// features of each piece.
+func tokenFeatures(s string) pieceFeatures {
+	var f pieceFeatures
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		if n := contraction(s[i:]); n > 0 {
+			f.words++
+			i += n
+			continue
+		}
+
+		// Letter run, with one leading symbol or space folded in.
+		j := i
+		if !isLetter(r) && !isNumber(r) && r != '\r' && r != '\n' {
+			j += size
+		}
+		if k := scanLetters(s, j); k > j {
+			if j > i && s[i] != ' ' {
+				f.punct++
+			}
+			f.addWord(s[j:k])
+			i = k
+			continue
+		}
+
+		if isNumber(r) {
+			for n := 0; n < 3 && i < len(s); n++ {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if !isNumber(r) {
+					break
+				}
+				i += size
+			}
+			f.digits++
+			continue
+		}
+
+		// Punctuation run, with an optional leading space and trailing
+		// line breaks.
+		j = i
+		if s[j] == ' ' {
+			j++
+		}
+		k := j
+		for k < len(s) {
+			r, size := utf8.DecodeRuneInString(s[k:])
+			if unicode.IsSpace(r) || isLetter(r) || isNumber(r) {
+				break
+			}
+			k += size
+		}
+		if k > j {
+			f.punct += float64(utf8.RuneCountInString(s[j:k]))
+			for k < len(s) && (s[k] == '\r' || s[k] == '\n') {
+				k++
+			}
+			i = k
+			continue
+		}
+
+		// Whitespace. A run followed by a non-space leaves its last
+		// character to start the next piece, unless it ends in a line break.
+		k = i
+		lastBreak := -1
+		for k < len(s) {
+			r, size := utf8.DecodeRuneInString(s[k:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			if r == '\r' || r == '\n' {
+				lastBreak = k + size
+			}
+			k += size
+		}
+		switch {
+		case lastBreak > 0:
+			k = lastBreak
+		case k < len(s):
+			if _, size := utf8.DecodeLastRuneInString(s[i:k]); k-size > i {
+				k -= size
+			}
+		}
+		f.spaces++
+		i = k
+	}
+	return f
+}
+
+// addWord counts a letter run: ASCII letters by camelCase part, others as
+// runes.
+func (f *pieceFeatures) addWord(w string) {
+	part := 0
+	prevLower := false
+	flush := func() {
+		if part > 0 {
+			f.words++
+			f.longLetters += float64(max(0, part-longWordLetters))
+		}
+		part = 0
+	}
+	for _, r := range w {
+		if r >= utf8.RuneSelf {
+			flush()
+			f.runes++
+			prevLower = false
+			continue
+		}
+		upper := r >= 'A' && r <= 'Z'
+		if upper && prevLower {
+			flush()
+		}
+		part++
+		prevLower = !upper
+	}
+	flush()
+}
+
+// contraction returns the length of an English contraction suffix at the
+// start of s, or 0.
+func contraction(s string) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+	lower := func(c byte) byte { return c | 0x20 }
+	if len(s) >= 3 {
+		switch string([]byte{lower(s[1]), lower(s[2])}) {
+		case "ll", "ve", "re":
+			return 3
+		}
+	}
+	switch lower(s[1]) {
+	case 's', 'd', 'm', 't':
+		return 2
+	}
+	return 0
+}
+
+func scanLetters(s string, i int) int {
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isLetter(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isLetter(r rune) bool {
+	if r < utf8.RuneSelf {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}
+	return unicode.IsLetter(r)
+}
+
+func isNumber(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= '0' && r <= '9'
+	}
+	return unicode.IsNumber(r)
+}
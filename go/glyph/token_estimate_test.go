@@ -0,0 +1,89 @@
+package glyph
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTokenFeatures_Pieces(t *testing.T) {
+	tests := []struct {
+		in   string
+		want pieceFeatures
+	}{
+		{"hello world", pieceFeatures{words: 2}},
+		{"userName", pieceFeatures{words: 2}},
+		{"internationalization", pieceFeatures{words: 1, longLetters: 14}},
+		{"1234567", pieceFeatures{digits: 3}},
+		{"I'll", pieceFeatures{words: 2}},
+		{`{"a":1}`, pieceFeatures{words: 1, punct: 5, digits: 1}},
+		{"a  b", pieceFeatures{words: 2, spaces: 1}},
+		{"x\n\n  y", pieceFeatures{words: 2, spaces: 2}},
+		{"日本", pieceFeatures{runes: 2}},
+	}
+	for _, tt := range tests {
+		if got := tokenFeatures(tt.in); got != tt.want {
+			t.Errorf("tokenFeatures(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokenEstimator_Count(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{" ", 1},
+		{"hello world", 2},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"{name=Alice age=30 tags=[a b]}", 11},
+	}
+	for _, tt := range tests {
+		if got := DefaultTokenEstimator.Count(tt.in); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	// Loose output is cheaper than the JSON it came from.
+	json := `{"name":"Alice","age":30,"tags":["a","b"]}`
+	if EstimateTokens(json) <= EstimateTokens("{name=Alice age=30 tags=[a b]}") {
+		t.Error("JSON estimated no more expensive than loose")
+	}
+
+	var tok Tokenizer = DefaultTokenEstimator.Count
+	if tok(json) != EstimateTokens(json) {
+		t.Error("Count is not interchangeable with EstimateTokens")
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	truth := TokenEstimator{Word: 1.2, LongLetter: 0.4, Punct: 0.8, Space: 1, Rune: 1}
+	texts := []string{
+		"hello world", "internationalization matters", `{"a":[1,2,3]}`,
+		"x = y + z;", "first\n\nsecond", "camelCaseIdentifier", "a, b, c",
+		"{name=Alice age=30}", "  indented\n  code();\n", "extraordinarily long words",
+	}
+	var samples []TokenSample
+	for _, text := range texts {
+		f := tokenFeatures(text)
+		samples = append(samples, TokenSample{Text: text, Tokens: int(math.Round(f.digits + truth.dot(f)))})
+	}
+
+	got, err := Calibrate(DefaultTokenEstimator, samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range samples {
+		if n := got.Count(s.Text); n != s.Tokens {
+			t.Errorf("calibrated Count(%q) = %d, want %d", s.Text, n, s.Tokens)
+		}
+	}
+	// No sample has non-ASCII letters, so Rune keeps its base rate.
+	if math.Abs(got.Rune-DefaultTokenEstimator.Rune) > 1e-6 {
+		t.Errorf("Rune = %v, want base rate", got.Rune)
+	}
+
+	if _, err := Calibrate(DefaultTokenEstimator, nil); err == nil {
+		t.Error("expected error for no samples")
+	}
+}
@@ -0,0 +1,214 @@
+package glyph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Truncation Markers
+// ============================================================
+//
+// Models asked to repeat state often shorten it: [a b c …]. Read literally,
+// the elided items simply vanish, and the consumer cannot tell a list that
+// was cut from one that was short. Both parsers recognize two markers in
+// place of list items, map or struct entries, or a whole value:
+//
+//	…  (or ...)      something was left out
+//	@omitted n=K     K items were left out
+//
+// A marker is dropped from the container and recorded on it instead, where
+// IsTruncated and OmittedCount report it; a marker standing for a whole value
+// yields a truncated null. Several markers in one container add up, and an
+// uncounted one makes the total unknown. The emitters write the marker back
+// after the last item, so truncation survives a round trip and a truncated
+// value never fingerprints like a complete one. Where the marker stood is
+// not kept.
+
+const (
+	ellipsisMarker = "…"
+	omittedMarker  = "@omitted"
+)
+
+// Truncated returns a copy of v marked as truncated, with omitted items left
+// out of it; omitted <= 0 means the count is unknown. A nil v is a truncated
+// null. Only containers and null carry the marker when emitted.
+func Truncated(v *GValue, omitted int) *GValue {
+	if v == nil {
+		v = Null()
+	}
+	if omitted <= 0 {
+		omitted = -1
+	}
+	return withOmitted(v, omitted)
+}
+
+// IsTruncated reports whether v was elided in part (a container) or in whole
+// (a null) by a truncation marker, so "empty" can be told from "elided".
+func (v *GValue) IsTruncated() bool {
+	return v != nil && v.ext != nil && v.ext.omitted != 0
+}
+
+// OmittedCount returns the number of items a truncation marker said were left
+// out of v. ok is false if v is not truncated or the marker gave no count.
+func (v *GValue) OmittedCount() (n int, ok bool) {
+	if v == nil || v.ext == nil || v.ext.omitted <= 0 {
+		return 0, false
+	}
+	return v.ext.omitted, true
+}
+
+// withOmitted returns a shallow copy of v recording omitted (-1: unknown),
+// or v itself when omitted is 0.
+func withOmitted(v *GValue, omitted int) *GValue {
+	if omitted == 0 {
+		return v
+	}
+	v.force()
+	cp := *v
+	x := valueExt{}
+	if v.ext != nil {
+		x = *v.ext
+	}
+	x.omitted = omitted
+	cp.ext = &x
+	return &cp
+}
+
+// addOmitted combines the counts of two markers; an unknown count (-1) wins.
+func addOmitted(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	case a < 0 || b < 0:
+		return -1
+	}
+	return a + b
+}
+
+// omissionMarker returns the marker text for omitted.
+func omissionMarker(omitted int) string {
+	if omitted > 0 {
+		return omittedMarker + " n=" + strconv.Itoa(omitted)
+	}
+	return ellipsisMarker
+}
+
+// carriesMarker reports whether v is emitted with a truncation marker.
+func carriesMarker(v *GValue) bool {
+	if !v.IsTruncated() {
+		return false
+	}
+	switch v.typ {
+	case TypeNull, TypeList, TypeMap, TypeStruct:
+		return true
+	}
+	return false
+}
+
+// parseOmission consumes a truncation marker at the current token, returning
+// the count it gives (-1: unknown). ok is false if there is no marker.
+func (p *Parser) parseOmission() (omitted int, ok bool) {
+	tok := p.stream.Peek()
+	switch {
+	case tok.Type == TokenEllipsis:
+		p.stream.Advance()
+		return -1, true
+	case tok.Type == TokenAt:
+		if name := p.stream.PeekN(1); name.Type != TokenIdent || name.Value != "omitted" {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	p.stream.Advance() // consume @
+	p.stream.Advance() // consume omitted
+
+	if key := p.stream.Peek(); key.Type != TokenIdent || key.Value != "n" || p.stream.PeekN(1).Type != TokenEq {
+		return -1, true
+	}
+	p.stream.Advance() // consume n
+	p.stream.Advance() // consume =
+	countTok := p.stream.Peek()
+	n, err := strconv.Atoi(countTok.Value)
+	if countTok.Type != TokenInt || err != nil || n < 0 {
+		p.addError(countTok.Pos, "invalid @omitted count %q", countTok.Value)
+		return -1, true
+	}
+	p.stream.Advance()
+	return n, true
+}
+
+// cutOmission is parseOmission for the loose parser: if s starts with a
+// truncation marker it returns the marker's count (-1: unknown) and the rest
+// of s.
+func cutOmission(s string) (omitted int, rest string, ok bool, err error) {
+	var word string
+	switch {
+	case strings.HasPrefix(s, ellipsisMarker):
+		word = ellipsisMarker
+	case strings.HasPrefix(s, "..."):
+		word = "..."
+	case strings.HasPrefix(s, omittedMarker):
+		word = omittedMarker
+	default:
+		return 0, s, false, nil
+	}
+	rest = s[len(word):]
+	if rest != "" && !isMarkerEnd(rest[0]) {
+		return 0, s, false, nil
+	}
+	if word != omittedMarker {
+		return -1, rest, true, nil
+	}
+
+	trimmed := strings.TrimLeft(rest, " \t\n")
+	if !strings.HasPrefix(trimmed, "n=") {
+		return -1, rest, true, nil
+	}
+	count := trimmed[len("n="):]
+	end := 0
+	for end < len(count) && !isMarkerEnd(count[end]) {
+		end++
+	}
+	n, convErr := strconv.Atoi(count[:end])
+	if convErr != nil || n < 0 {
+		return 0, s, false, fmt.Errorf("invalid @omitted count %q", count[:end])
+	}
+	return n, count[end:], true, nil
+}
+
+// isMarkerEnd reports whether ch ends a marker in loose text.
+func isMarkerEnd(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',' || ch == ']' || ch == '}'
+}
+
+// writeTruncatedLoose writes a container or null carrying a truncation
+// marker in loose canonical form: the marker follows the last item. Such
+// lists are never written as @tab blocks.
+func writeTruncatedLoose(b *strings.Builder, v *GValue, opts LooseCanonOpts) {
+	marker := omissionMarker(v.ext.omitted)
+	var inner strings.Builder
+	opts.AutoTabular = false
+	switch v.typ {
+	case TypeList:
+		writeListLoose(&inner, v.listVal, opts)
+	case TypeMap:
+		writeMapLoose(&inner, v.mapVal, opts)
+	case TypeStruct:
+		writeStructLoose(&inner, v.structVal, opts)
+	default:
+		b.WriteString(marker)
+		return
+	}
+	s := inner.String()
+	b.WriteString(s[:len(s)-1])
+	if len(s) > 2 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(marker)
+	b.WriteByte(s[len(s)-1])
+}
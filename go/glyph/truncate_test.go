@@ -0,0 +1,115 @@
+package glyph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncation_Parse(t *testing.T) {
+	r, err := Parse(`State{log=[a b …] steps=[1 2 @omitted n=40 3] meta={owner:ops ...} notes=… empty=[] task=Task{id=1 @omitted}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := r.Value
+
+	for _, tc := range []struct {
+		field string
+		len   int
+		count int // -1: truncated, count unknown; 0: not truncated
+	}{
+		{"log", 2, -1},
+		{"steps", 3, 40},
+		{"meta", 1, -1},
+		{"notes", 0, -1},
+		{"empty", 0, 0},
+		{"task", 1, -1},
+	} {
+		got := v.Get(tc.field)
+		if got.Len() != tc.len || got.IsTruncated() != (tc.count != 0) {
+			t.Errorf("%s: len %d truncated %v", tc.field, got.Len(), got.IsTruncated())
+		}
+		n, ok := got.OmittedCount()
+		if ok != (tc.count > 0) || tc.count > 0 && n != tc.count {
+			t.Errorf("%s: OmittedCount = %d, %v", tc.field, n, ok)
+		}
+	}
+	if !v.Get("notes").IsNull() {
+		t.Error("an elided value should read as null")
+	}
+	if v.IsTruncated() {
+		t.Error("the root is complete")
+	}
+
+	// Markers add up; an uncounted one makes the count unknown.
+	r, _ = Parse(`[a @omitted n=2 b @omitted n=3]`)
+	if n, ok := r.Value.OmittedCount(); !ok || n != 5 {
+		t.Errorf("summed count = %d, %v", n, ok)
+	}
+	r, _ = Parse(`[a @omitted n=2 …]`)
+	if _, ok := r.Value.OmittedCount(); ok || !r.Value.IsTruncated() {
+		t.Error("an uncounted marker should make the count unknown")
+	}
+
+	// A quoted ellipsis is a string.
+	r, _ = Parse(`["…" "..."]`)
+	if r.Value.IsTruncated() || r.Value.Len() != 2 {
+		t.Errorf("quoted ellipses: %s", Emit(r.Value))
+	}
+
+	if r, _ := Parse(`[a @omitted n=x]`); !r.HasErrors() {
+		t.Error("a non-integer count should be an error")
+	}
+}
+
+func TestTruncation_RoundTrip(t *testing.T) {
+	v := Map(
+		MapEntry{Key: "log", Value: Truncated(List(Str("a"), Str("b")), 0)},
+		MapEntry{Key: "steps", Value: Truncated(List(Int(1)), 40)},
+		MapEntry{Key: "notes", Value: Truncated(nil, 0)},
+		MapEntry{Key: "task", Value: Truncated(Struct("Task", MapEntry{Key: "id", Value: Int(1)}), 2)},
+		MapEntry{Key: "rows", Value: Truncated(List(
+			Map(MapEntry{Key: "a", Value: Int(1)}),
+			Map(MapEntry{Key: "a", Value: Int(2)}),
+			Map(MapEntry{Key: "a", Value: Int(3)}),
+		), 0)},
+	)
+
+	typed := Emit(v)
+	for _, want := range []string{"log:[a b …]", "steps:[1 @omitted n=40]", "notes:…", "task:Task{id=1 @omitted n=2}"} {
+		if !strings.Contains(typed, want) {
+			t.Errorf("typed %s: missing %s", typed, want)
+		}
+	}
+	r, err := Parse(typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := Emit(r.Value); again != typed {
+		t.Errorf("typed round trip:\n%s\n%s", typed, again)
+	}
+
+	loose := CanonicalizeLoose(v)
+	if !strings.Contains(loose, "rows=[{a=1} {a=2} {a=3} …]") {
+		t.Errorf("a truncated list must not become a table: %s", loose)
+	}
+	for _, parse := range []func(string) (*GValue, error){
+		func(s string) (*GValue, error) { return ParseLoose(s, nil) },
+		func(s string) (*GValue, error) { return ParseLooseLazy(s, nil, 8) },
+	} {
+		got, err := parse(loose)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again := CanonicalizeLoose(got); again != loose {
+			t.Errorf("loose round trip:\n%s\n%s", loose, again)
+		}
+		if n, ok := got.Get("steps").OmittedCount(); !ok || n != 40 {
+			t.Errorf("loose steps count = %d, %v", n, ok)
+		}
+	}
+
+	// Elided is not empty.
+	if EqualLoose(v.Get("log"), List(Str("a"), Str("b"))) || EqualLoose(v.Get("notes"), Null()) {
+		t.Error("a truncated value should not equal the complete one")
+	}
+}
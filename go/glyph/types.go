@@ -2,6 +2,7 @@ package glyph
 
 import (
 	"fmt"
+	"iter"
 	"time"
 )
 
@@ -21,6 +22,7 @@ const (
 	TypeMap
 	TypeStruct // Typed struct: Type{...}
 	TypeSum    // Tagged union: Tag(value) or Tag{...}
+	TypeCustom // Application-defined scalar: tag"text" (see scalar_ext.go)
 )
 
 // String returns the type name.
@@ -50,12 +52,18 @@ func (t GType) String() string {
 		return "struct"
 	case TypeSum:
 		return "sum"
+	case TypeCustom:
+		return "custom"
 	default:
 		return "unknown"
 	}
 }
 
 // GValue represents a GLYPH value.
+//
+// Every node carries the fields of the common kinds; bytes, time, and ID
+// payloads and source positions live behind ext, so they do not widen the
+// maps, lists, and strings that make up most documents.
 type GValue struct {
 	typ GType
 
@@ -64,9 +72,6 @@ type GValue struct {
 	intVal   int64
 	floatVal float64
 	strVal   string
-	bytesVal []byte
-	timeVal  time.Time
-	idVal    RefID
 
 	// Container values
 	listVal   []*GValue
@@ -76,8 +81,61 @@ type GValue struct {
 	// Sum type
 	sumVal *SumValue
 
-	// Source location for error reporting
-	pos Position
+	// Rare payloads; nil for most values. Never mutated once set, so copies
+	// of a GValue may share it.
+	ext *valueExt
+}
+
+// valueExt holds the payloads few values need.
+type valueExt struct {
+	bytesVal []byte
+	timeVal  time.Time
+	idVal    RefID
+	custom   *CustomValue
+	pos      Position  // Source location for error reporting
+	lazy     *lazySpan // Unparsed source of a deferred container
+	omitted  int       // Items elided by a truncation marker (-1: unknown; see truncate.go)
+}
+
+// extValue allocates a GValue together with its valueExt.
+type extValue struct {
+	v GValue
+	x valueExt
+}
+
+// newExtValue returns a value of type t whose ext is x, in one allocation.
+func newExtValue(t GType, x valueExt) *GValue {
+	ev := &extValue{v: GValue{typ: t}, x: x}
+	ev.v.ext = &ev.x
+	return &ev.v
+}
+
+func (v *GValue) bytesVal() []byte {
+	if v.ext == nil {
+		return nil
+	}
+	return v.ext.bytesVal
+}
+
+func (v *GValue) timeVal() time.Time {
+	if v.ext == nil {
+		return time.Time{}
+	}
+	return v.ext.timeVal
+}
+
+func (v *GValue) idVal() RefID {
+	if v.ext == nil {
+		return RefID{}
+	}
+	return v.ext.idVal
+}
+
+func (v *GValue) customVal() *CustomValue {
+	if v.ext == nil {
+		return nil
+	}
+	return v.ext.custom
 }
 
 // RefID represents a reference identifier (^prefix:value).
@@ -155,22 +213,22 @@ func Str(v string) *GValue {
 
 // Bytes creates a bytes value.
 func Bytes(v []byte) *GValue {
-	return &GValue{typ: TypeBytes, bytesVal: v}
+	return newExtValue(TypeBytes, valueExt{bytesVal: v})
 }
 
 // Time creates a time value.
 func Time(v time.Time) *GValue {
-	return &GValue{typ: TypeTime, timeVal: v}
+	return newExtValue(TypeTime, valueExt{timeVal: v})
 }
 
 // ID creates a reference ID value.
 func ID(prefix, value string) *GValue {
-	return &GValue{typ: TypeID, idVal: RefID{Prefix: prefix, Value: value}}
+	return newExtValue(TypeID, valueExt{idVal: RefID{Prefix: prefix, Value: value}})
 }
 
 // IDFromRef creates a reference ID from a RefID.
 func IDFromRef(ref RefID) *GValue {
-	return &GValue{typ: TypeID, idVal: ref}
+	return newExtValue(TypeID, valueExt{idVal: ref})
 }
 
 // List creates a list value.
@@ -274,7 +332,7 @@ func (v *GValue) AsBytes() ([]byte, error) {
 	if v.typ != TypeBytes {
 		return nil, fmt.Errorf("glyph: expected bytes, got %s", v.typ)
 	}
-	return v.bytesVal, nil
+	return v.bytesVal(), nil
 }
 
 // AsTime returns the time value.
@@ -285,7 +343,7 @@ func (v *GValue) AsTime() (time.Time, error) {
 	if v.typ != TypeTime {
 		return time.Time{}, fmt.Errorf("glyph: expected time, got %s", v.typ)
 	}
-	return v.timeVal, nil
+	return v.timeVal(), nil
 }
 
 // AsID returns the reference ID.
@@ -296,11 +354,14 @@ func (v *GValue) AsID() (RefID, error) {
 	if v.typ != TypeID {
 		return RefID{}, fmt.Errorf("glyph: expected id, got %s", v.typ)
 	}
-	return v.idVal, nil
+	return v.idVal(), nil
 }
 
 // AsList returns the list elements.
 func (v *GValue) AsList() ([]*GValue, error) {
+	if err := v.force(); err != nil {
+		return nil, err
+	}
 	if v == nil {
 		return nil, fmt.Errorf("glyph: nil value")
 	}
@@ -312,6 +373,9 @@ func (v *GValue) AsList() ([]*GValue, error) {
 
 // AsMap returns the map entries.
 func (v *GValue) AsMap() ([]MapEntry, error) {
+	if err := v.force(); err != nil {
+		return nil, err
+	}
 	if v == nil {
 		return nil, fmt.Errorf("glyph: nil value")
 	}
@@ -345,6 +409,7 @@ func (v *GValue) AsSum() (*SumValue, error) {
 
 // Len returns the length of a list, map, or struct.
 func (v *GValue) Len() int {
+	v.force()
 	switch v.typ {
 	case TypeList:
 		return len(v.listVal)
@@ -359,6 +424,7 @@ func (v *GValue) Len() int {
 
 // Get returns a field value by key from a map or struct.
 func (v *GValue) Get(key string) *GValue {
+	v.force()
 	switch v.typ {
 	case TypeMap:
 		for _, e := range v.mapVal {
@@ -376,8 +442,61 @@ func (v *GValue) Get(key string) *GValue {
 	return nil
 }
 
+// Range calls fn for each entry of a map or each field of a struct, in
+// order, until fn returns false. It does nothing for other values and nil.
+// fn must not add or remove entries.
+func (v *GValue) Range(fn func(key string, v *GValue) bool) {
+	v.force()
+	if v == nil {
+		return
+	}
+	var entries []MapEntry
+	switch v.typ {
+	case TypeMap:
+		entries = v.mapVal
+	case TypeStruct:
+		if v.structVal != nil {
+			entries = v.structVal.Fields
+		}
+	}
+	for _, e := range entries {
+		if !fn(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// Fields returns an iterator over the entries of a map or the fields of a
+// struct, in order:
+//
+//	for key, val := range v.Fields() {
+//		...
+//	}
+func (v *GValue) Fields() iter.Seq2[string, *GValue] {
+	return v.Range
+}
+
+// Items returns an iterator over the elements of a list, with their indexes.
+// It yields nothing for other values and nil.
+func (v *GValue) Items() iter.Seq2[int, *GValue] {
+	return func(yield func(int, *GValue) bool) {
+		v.force()
+		if v == nil || v.typ != TypeList {
+			return
+		}
+		for i, item := range v.listVal {
+			if !yield(i, item) {
+				return
+			}
+		}
+	}
+}
+
 // Index returns the i-th element of a list.
 func (v *GValue) Index(i int) (*GValue, error) {
+	if err := v.force(); err != nil {
+		return nil, err
+	}
 	if v == nil || v.typ != TypeList {
 		return nil, fmt.Errorf("glyph: not a list")
 	}
@@ -389,15 +508,20 @@ func (v *GValue) Index(i int) (*GValue, error) {
 
 // Pos returns the source position of this value.
 func (v *GValue) Pos() Position {
-	if v == nil {
+	if v == nil || v.ext == nil {
 		return Position{}
 	}
-	return v.pos
+	return v.ext.pos
 }
 
 // SetPos sets the source position.
 func (v *GValue) SetPos(pos Position) {
-	v.pos = pos
+	x := valueExt{pos: pos}
+	if v.ext != nil {
+		x = *v.ext
+		x.pos = pos
+	}
+	v.ext = &x
 }
 
 // ============================================================
@@ -406,6 +530,7 @@ func (v *GValue) SetPos(pos Position) {
 
 // Set sets a field value on a map or struct.
 func (v *GValue) Set(key string, val *GValue) {
+	v.force()
 	switch v.typ {
 	case TypeMap:
 		for i := range v.mapVal {
@@ -430,6 +555,7 @@ func (v *GValue) Set(key string, val *GValue) {
 
 // Append adds a value to a list.
 func (v *GValue) Append(val *GValue) {
+	v.force()
 	if v.typ != TypeList {
 		panic("glyph: cannot append to non-list")
 	}
@@ -0,0 +1,69 @@
+package glyph
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// loadLooseJSONCorpus returns the JSON inputs under testdata/loose_json/cases.
+func loadLooseJSONCorpus(tb testing.TB) [][]byte {
+	dir := filepath.Join("testdata", "loose_json", "cases")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var docs [][]byte
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		docs = append(docs, data)
+	}
+	return docs
+}
+
+func BenchmarkCorpus_Footprint(b *testing.B) {
+	docs := loadLooseJSONCorpus(b)
+
+	// Live heap held by one decoded copy of the corpus.
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	held := make([]*GValue, 0, len(docs))
+	for _, d := range docs {
+		v, err := FromJSONLoose(d)
+		if err != nil {
+			b.Fatal(err)
+		}
+		held = append(held, v)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(held)
+	live := float64(after.HeapAlloc - before.HeapAlloc)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, d := range docs {
+			if _, err := FromJSONLoose(d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(live, "live-B")
+}
+
+func TestGValueSize(t *testing.T) {
+	if n := unsafe.Sizeof(GValue{}); n > 112 {
+		t.Errorf("GValue is %d bytes; keep rare payloads in valueExt", n)
+	}
+}
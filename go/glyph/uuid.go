@@ -0,0 +1,188 @@
+package glyph
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// UUID and ULID Identifiers
+// ============================================================
+//
+// Identifiers are a large share of payload bytes. A hyphenated UUID costs 36
+// characters; the same 128 bits in Crockford base32 (the ULID text form) cost
+// 26 and tokenize no worse. UUID and ULID therefore both return id values in
+// the 26-character base32 form, and CompactUUID converts hyphenated UUIDs to
+// it. ParseUUID accepts either form.
+//
+// Schema id fields can require these forms with the [uuid] and [ulid]
+// constraints: `id: id [uuid]` accepts either text form of a 128-bit id,
+// `id: id [ulid]` only the base32 form.
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var crockfordDecode = func() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = 0xFF
+	}
+	for i := 0; i < len(crockfordAlphabet); i++ {
+		c := crockfordAlphabet[i]
+		t[c] = byte(i)
+		t[c|0x20] = byte(i) // lowercase
+	}
+	return t
+}()
+
+// UUID returns a new random (version 4) UUID as an id value in compact
+// base32 form.
+func UUID() *GValue {
+	return defaultIDGen.UUID()
+}
+
+// ULID returns a new ULID (48-bit millisecond timestamp followed by 80 random
+// bits) as an id value. ULIDs sort by creation time.
+func ULID() *GValue {
+	return defaultIDGen.ULID()
+}
+
+// IDGen generates UUIDs and ULIDs from its own clock and random source.
+// Recorded streams and golden files that contain ids stay reproducible when
+// Now is a fixed clock and Rand a seeded source. Nil fields fall back to
+// time.Now and crypto/rand, as UUID and ULID use.
+type IDGen struct {
+	Now  func() time.Time
+	Rand io.Reader
+}
+
+var defaultIDGen IDGen
+
+// UUID returns a new random (version 4) UUID, as the package UUID does.
+func (g *IDGen) UUID() *GValue {
+	var u [16]byte
+	g.read(u[:])
+	u[6] = u[6]&0x0F | 0x40 // version 4
+	u[8] = u[8]&0x3F | 0x80 // RFC 4122 variant
+	return ID("", encodeCrockford128(u))
+}
+
+// ULID returns a new ULID stamped with g's clock, as the package ULID does.
+func (g *IDGen) ULID() *GValue {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	return ID("", g.newULID(now()))
+}
+
+func (g *IDGen) newULID(t time.Time) string {
+	var u [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(t.UnixMilli()))
+	copy(u[:6], ts[2:])
+	g.read(u[6:])
+	return encodeCrockford128(u)
+}
+
+// read fills b from g's random source.
+func (g *IDGen) read(b []byte) {
+	var err error
+	if g.Rand != nil {
+		_, err = io.ReadFull(g.Rand, b)
+	} else {
+		_, err = rand.Read(b)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("glyph: reading random bytes: %v", err))
+	}
+}
+
+// ParseUUID decodes a 128-bit id from either hyphenated UUID form
+// (8-4-4-4-12 hex digits) or 26-character Crockford base32 form.
+func ParseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	switch len(s) {
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return u, fmt.Errorf("glyph: invalid UUID %q", s)
+		}
+		h := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+		if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+			return u, fmt.Errorf("glyph: invalid UUID %q", s)
+		}
+		return u, nil
+	case 26:
+		return decodeCrockford128(s)
+	}
+	return u, fmt.Errorf("glyph: invalid UUID %q: want 36 or 26 characters, got %d", s, len(s))
+}
+
+// CompactUUID converts a UUID in either text form to the 26-character base32
+// form.
+func CompactUUID(s string) (string, error) {
+	u, err := ParseUUID(s)
+	if err != nil {
+		return "", err
+	}
+	return encodeCrockford128(u), nil
+}
+
+// FormatUUID returns the hyphenated form of a 128-bit id.
+func FormatUUID(u [16]byte) string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// encodeCrockford128 encodes 128 bits as 26 base32 characters. The value is
+// treated as a 130-bit number with two leading zero bits, so the first
+// character is always 0-7.
+func encodeCrockford128(u [16]byte) string {
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func decodeCrockford128(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) != 26 {
+		return u, fmt.Errorf("glyph: invalid ULID %q: want 26 characters", s)
+	}
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		d := crockfordDecode[s[i]]
+		if d == 0xFF {
+			return u, fmt.Errorf("glyph: invalid ULID character %q in %q", s[i], s)
+		}
+		if i == 0 && d > 7 {
+			return u, fmt.Errorf("glyph: ULID %q overflows 128 bits", s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(d)
+	}
+	binary.BigEndian.PutUint64(u[:8], hi)
+	binary.BigEndian.PutUint64(u[8:], lo)
+	return u, nil
+}
+
+// checkIDFormat reports whether value is a valid id in the given format
+// ("uuid" or "ulid").
+func checkIDFormat(format, value string) error {
+	if format == "ulid" && len(value) != 26 {
+		return fmt.Errorf("%q is not a ULID", value)
+	}
+	if _, err := ParseUUID(value); err != nil {
+		return fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "glyph: "))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package glyph
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUUID_CompactForm(t *testing.T) {
+	ref, _ := UUID().AsID()
+	if len(ref.Value) != 26 || ref.Prefix != "" {
+		t.Fatalf("UUID: got %q", ref.Value)
+	}
+	u, err := ParseUUID(ref.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u[6]>>4 != 4 || u[8]>>6 != 2 {
+		t.Errorf("UUID: wrong version/variant bits in %s", FormatUUID(u))
+	}
+	if got := CanonicalizeLoose(UUID()); len(got) != 27 || got[0] != '^' {
+		t.Errorf("UUID should emit as a bare ref, got %s", got)
+	}
+
+	const hyphenated = "01890a5d-ac96-774b-bcce-b302099a8057"
+	compact, err := CompactUUID(hyphenated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compact != "01H455VB4PEX5VSKNK084SN02Q" {
+		t.Errorf("CompactUUID: got %s", compact)
+	}
+	back, err := ParseUUID(strings.ToLower(compact))
+	if err != nil || FormatUUID(back) != hyphenated {
+		t.Errorf("round trip: got %s %v", FormatUUID(back), err)
+	}
+
+	for _, bad := range []string{"", "01890a5d-ac96-774b-bcce-b302099a805", "01890a5dxac96-774b-bcce-b302099a8057", "81H455VB4PEX5VSKNK084SN02Q", "01H455VB4PEX5VSKNK084SN0UQ"} {
+		if _, err := ParseUUID(bad); err == nil {
+			t.Errorf("ParseUUID(%q): expected error", bad)
+		}
+	}
+}
+
+func TestULID_TimeOrdered(t *testing.T) {
+	t0 := time.UnixMilli(1700000000000)
+	a, b := defaultIDGen.newULID(t0), defaultIDGen.newULID(t0.Add(time.Millisecond))
+	if a >= b {
+		t.Errorf("ULIDs should sort by time: %s >= %s", a, b)
+	}
+	if a[:10] != "01HF7YAT00" {
+		t.Errorf("timestamp prefix: got %s", a[:10])
+	}
+	if ref, _ := ULID().AsID(); len(ref.Value) != 26 {
+		t.Errorf("ULID: got %q", ref.Value)
+	}
+}
+
+func TestIDGen_Reproducible(t *testing.T) {
+	gen := func() *IDGen {
+		return &IDGen{
+			Now:  func() time.Time { return time.UnixMilli(1700000000000) },
+			Rand: rand.NewChaCha8([32]byte{1}),
+		}
+	}
+	a, b := gen(), gen()
+	for i := 0; i < 3; i++ {
+		if x, y := a.ULID(), b.ULID(); !EqualLoose(x, y) {
+			t.Fatalf("ULID %d: %s != %s", i, Emit(x), Emit(y))
+		}
+		if x, y := a.UUID(), b.UUID(); !EqualLoose(x, y) {
+			t.Fatalf("UUID %d: %s != %s", i, Emit(x), Emit(y))
+		}
+	}
+	if ref, _ := a.ULID().AsID(); ref.Value[:10] != "01HF7YAT00" {
+		t.Errorf("ULID clock: got %s", ref.Value)
+	}
+	if x, y := a.UUID(), a.UUID(); EqualLoose(x, y) {
+		t.Error("successive UUIDs are equal")
+	}
+}
+
+func TestValidate_UUIDConstraints(t *testing.T) {
+	schema, err := ParseSchema(`@schema{
+		Event:v1 struct{
+			id: id[ulid]
+			trace: id[uuid] [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.GetField("Event", "id").Constraints[0].String(); got != "ulid" {
+		t.Errorf("String: got %s", got)
+	}
+	if errs := schema.Check(); len(errs) != 0 {
+		t.Errorf("Check: %v", errs)
+	}
+
+	event := func(id, trace *GValue) *GValue {
+		fields := []MapEntry{{Key: "id", Value: id}}
+		if trace != nil {
+			fields = append(fields, MapEntry{Key: "trace", Value: trace})
+		}
+		return Struct("Event", fields...)
+	}
+
+	valid := []*GValue{
+		event(ULID(), nil),
+		event(ULID(), UUID()),
+		event(ID("ev", "01H455VB4PEX5VSKNK084SN02Q"), ID("", "01890a5d-ac96-774b-bcce-b302099a8057")),
+		event(Str("^01H455VB4PEX5VSKNK084SN02Q"), nil),
+	}
+	for _, v := range valid {
+		if r := ValidateAs(v, schema, "Event"); !r.Valid {
+			t.Errorf("%s: %v", CanonicalizeLoose(v), r.Errors)
+		}
+	}
+
+	invalid := map[string]*GValue{
+		"constraint_ulid": event(ID("", "01890a5d-ac96-774b-bcce-b302099a8057"), nil),
+		"constraint_uuid": event(ULID(), ID("", "not-a-uuid")),
+	}
+	for code, v := range invalid {
+		r := ValidateAs(v, schema, "Event")
+		if r.Valid || r.Errors[0].Code != code {
+			t.Errorf("%s: expected %s, got %v", CanonicalizeLoose(v), code, r.Errors)
+		}
+	}
+}
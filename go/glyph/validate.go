@@ -26,6 +26,27 @@ type ValidationResult struct {
 	Valid    bool
 	Errors   []ValidationError
 	Warnings []ValidationError
+	Unknown  []*UnknownFieldError // Rejected unknown fields, also listed in Errors
+}
+
+// UnknownFieldError reports a field that the schema does not declare and that
+// the validator rejected (closed type, or any type under strict validation).
+type UnknownFieldError struct {
+	Path       string // Path to the rejected field
+	Key        string // The unknown key
+	Type       string // Struct type being validated
+	Suggestion string // Nearest declared field name, "" if none is close
+}
+
+func (e *UnknownFieldError) Error() string {
+	msg := fmt.Sprintf("unknown field %s in %s", e.Key, e.Type)
+	if e.Path != "" && e.Path != e.Key {
+		msg = fmt.Sprintf("%s: %s", e.Path, msg)
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %s?)", e.Suggestion)
+	}
+	return msg
 }
 
 // Validator validates GValues against a Schema.
@@ -33,6 +54,7 @@ type Validator struct {
 	schema           *Schema
 	errors           []ValidationError
 	warnings         []ValidationError
+	unknown          []*UnknownFieldError
 	compiledPatterns map[string]*regexp.Regexp
 	strict           bool // If true, treat unknown fields as errors even for @open
 }
@@ -58,6 +80,7 @@ func NewStrictValidator(schema *Schema) *Validator {
 func (v *Validator) Validate(value *GValue) *ValidationResult {
 	v.errors = nil
 	v.warnings = nil
+	v.unknown = nil
 
 	// If value is a struct, validate against its type
 	if value.typ == TypeStruct {
@@ -71,6 +94,7 @@ func (v *Validator) Validate(value *GValue) *ValidationResult {
 		Valid:    len(v.errors) == 0,
 		Errors:   v.errors,
 		Warnings: v.warnings,
+		Unknown:  v.unknown,
 	}
 }
 
@@ -78,6 +102,7 @@ func (v *Validator) Validate(value *GValue) *ValidationResult {
 func (v *Validator) ValidateAs(value *GValue, typeName string) *ValidationResult {
 	v.errors = nil
 	v.warnings = nil
+	v.unknown = nil
 
 	td := v.schema.GetType(typeName)
 	if td == nil {
@@ -104,10 +129,12 @@ func (v *Validator) ValidateAs(value *GValue, typeName string) *ValidationResult
 		Valid:    len(v.errors) == 0,
 		Errors:   v.errors,
 		Warnings: v.warnings,
+		Unknown:  v.unknown,
 	}
 }
 
 func (v *Validator) validateStruct(value *GValue, path, typeName string) {
+	value.force()
 	td := v.schema.GetType(typeName)
 	if td == nil {
 		v.addWarning(path, "unknown_type", "unknown type: %s", typeName)
@@ -118,6 +145,9 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 		v.addError(path, "type_mismatch", "%s is not a struct type", typeName)
 		return
 	}
+	if td.Deprecated != nil {
+		v.addWarning(path, "deprecated_type", "%s", td.Deprecated.describe("type "+typeName))
+	}
 
 	// Get fields from value
 	var fields []MapEntry
@@ -146,6 +176,13 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 			}
 		}
 
+		if fieldDef.Optional && fieldVal.IsNull() {
+			if cond, ok := requiredIfMet(td, fieldDef, fieldValues); ok {
+				v.addError(fieldPath, "required_if", "%s is required when %s", fieldDef.Name, cond)
+				continue
+			}
+		}
+
 		if !exists {
 			if fieldDef.Optional {
 				continue
@@ -161,6 +198,10 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 			continue
 		}
 
+		if fieldDef.Deprecated != nil && !fieldVal.IsNull() {
+			v.addWarning(fieldPath, "deprecated_field", "%s", fieldDef.Deprecated.describe("field "+typeName+"."+fieldDef.Name))
+		}
+
 		// Validate field value against type spec
 		v.validateValue(fieldVal, fieldPath, fieldDef.Type)
 
@@ -168,6 +209,10 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 		v.validateConstraints(fieldVal, fieldPath, fieldDef.Constraints)
 	}
 
+	// Cross-field assertions
+	v.validateAsserts(td, fieldValues, path)
+	v.validateOneOf(td, fieldValues, path)
+
 	// Check for unknown fields
 	knownFields := make(map[string]bool)
 	for _, fd := range td.Struct.Fields {
@@ -180,8 +225,9 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 	// Collect unknown fields; for @open structs capture them into the @unknown bucket.
 	var unknownEntries []MapEntry
 	for _, f := range fields {
-		if f.Key == "@unknown" {
+		if f.Key == "@unknown" && !v.strict {
 			// Already a bucket from a previous pass — skip to avoid double-capture.
+			// Strict mode rejects the bucket itself: it is extra data like any other.
 			continue
 		}
 		if !knownFields[f.Key] {
@@ -191,7 +237,7 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 				v.addWarning(joinPath(path, f.Key), "unknown_field_captured", "unknown field captured: %s", f.Key)
 			} else {
 				// Strict mode or non-open structs reject unknown fields.
-				v.addError(joinPath(path, f.Key), "unknown_field", "unknown field: %s (type %s is not @open)", f.Key, typeName)
+				v.rejectUnknown(joinPath(path, f.Key), f.Key, td)
 			}
 		}
 	}
@@ -209,12 +255,73 @@ func (v *Validator) validateStruct(value *GValue, path, typeName string) {
 	}
 }
 
+// rejectUnknown records an unknown_field error for key, suggesting the
+// nearest declared field when one is close enough to be a likely typo.
+func (v *Validator) rejectUnknown(path, key string, td *TypeDef) {
+	uf := &UnknownFieldError{Path: path, Key: key, Type: td.Name, Suggestion: nearestField(td, key)}
+	v.unknown = append(v.unknown, uf)
+
+	msg := fmt.Sprintf("unknown field: %s (type %s is not @open)", key, td.Name)
+	if td.Open {
+		msg = fmt.Sprintf("unknown field: %s (strict validation of %s)", key, td.Name)
+	}
+	if uf.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %s?", uf.Suggestion)
+	}
+	v.errors = append(v.errors, ValidationError{Path: path, Code: "unknown_field", Message: msg})
+}
+
+// nearestField returns the declared field of td (by name or wire key) closest
+// to key in edit distance, or "" if nothing is within a third of key's length.
+// A case-insensitive exact match always wins.
+func nearestField(td *TypeDef, key string) string {
+	best, bestDist := "", len(key)/3+1
+	for _, fd := range td.Struct.Fields {
+		for _, cand := range []string{fd.Name, fd.WireKey} {
+			if cand == "" {
+				continue
+			}
+			if strings.EqualFold(cand, key) {
+				return fd.Name
+			}
+			if d := editDistance(strings.ToLower(key), strings.ToLower(cand)); d < bestDist {
+				best, bestDist = fd.Name, d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b (bytewise).
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
 func (v *Validator) validateSum(value *GValue, path, typeName string) {
 	td := v.schema.GetType(typeName)
 	if td == nil || td.Kind != TypeDefSum || td.Sum == nil {
 		v.addError(path, "type_mismatch", "%s is not a sum type", typeName)
 		return
 	}
+	if td.Deprecated != nil {
+		v.addWarning(path, "deprecated_type", "%s", td.Deprecated.describe("type "+typeName))
+	}
 
 	sv := value.sumVal
 	if sv == nil {
@@ -246,6 +353,7 @@ func (v *Validator) validateSum(value *GValue, path, typeName string) {
 }
 
 func (v *Validator) validateValue(value *GValue, path string, spec TypeSpec) {
+	value.force()
 	if value == nil || value.IsNull() {
 		// Null is generally valid unless spec says otherwise
 		return
@@ -319,7 +427,11 @@ func (v *Validator) validateValue(value *GValue, path string, spec TypeSpec) {
 		// Reference to named type
 		td := v.schema.GetType(spec.Name)
 		if td == nil {
-			v.addWarning(path, "unknown_type", "unknown type reference: %s", spec.Name)
+			if st, ok := DefaultScalarRegistry.Lookup(spec.Name); ok {
+				v.validateCustom(value, path, st)
+			} else {
+				v.addWarning(path, "unknown_type", "unknown type reference: %s", spec.Name)
+			}
 		} else if td.Kind == TypeDefStruct {
 			if value.typ == TypeStruct || value.typ == TypeMap {
 				v.validateStruct(value, path, spec.Name)
@@ -341,7 +453,23 @@ func (v *Validator) validateValue(value *GValue, path string, spec TypeSpec) {
 	}
 }
 
+// validateCustom checks a value against a registered custom scalar type.
+func (v *Validator) validateCustom(value *GValue, path string, st *ScalarType) {
+	cv := value.customVal()
+	if value.typ != TypeCustom || cv.Tag != st.Tag {
+		v.addError(path, "type_mismatch", "expected %s, got %s", st.Tag, value.typ)
+		return
+	}
+	if st.Validate == nil {
+		return
+	}
+	if err := st.Validate(cv.Value); err != nil {
+		v.addError(path, "invalid_value", "invalid %s: %v", st.Tag, err)
+	}
+}
+
 func (v *Validator) validateConstraints(value *GValue, path string, constraints []Constraint) {
+	value.force()
 	for _, c := range constraints {
 		switch c.Kind {
 		case ConstraintMin:
@@ -415,6 +543,33 @@ func (v *Validator) validateConstraints(value *GValue, path string, constraints
 				}
 			}
 
+		case ConstraintPrefix:
+			prefixes := c.Value.([]string)
+			if ref, ok := refFromValue(value); ok {
+				allowed := false
+				for _, prefix := range prefixes {
+					if ref.Prefix == prefix {
+						allowed = true
+						break
+					}
+				}
+				if !allowed {
+					v.addError(path, "constraint_prefix", "id prefix %q is not in allowed prefixes: %v", ref.Prefix, prefixes)
+				} else if ns, ok := DefaultRefRegistry.Lookup(ref.Prefix); ok {
+					if err := ns.check(ref.Value); err != nil {
+						v.addError(path, "constraint_prefix", "%s", strings.TrimPrefix(err.Error(), "glyph: "))
+					}
+				}
+			}
+
+		case ConstraintIDFormat:
+			format := c.Value.(string)
+			if ref, ok := refFromValue(value); ok {
+				if err := checkIDFormat(format, ref.Value); err != nil {
+					v.addError(path, "constraint_"+format, "%v", err)
+				}
+			}
+
 		case ConstraintUnique:
 			if value.typ == TypeList {
 				seen := make(map[string]bool)
@@ -469,11 +624,12 @@ func joinPath(base, field string) string {
 }
 
 func valueLength(v *GValue) int {
+	v.force()
 	switch v.typ {
 	case TypeStr:
 		return len(v.strVal)
 	case TypeBytes:
-		return len(v.bytesVal)
+		return len(v.bytesVal())
 	case TypeList:
 		return len(v.listVal)
 	case TypeMap:
@@ -505,6 +661,7 @@ func isInteger(f float64) bool {
 // Call Normalize (or ApplyDefaults directly) before Validate when you want
 // defaults applied; it is opt-in and never called inside Validate automatically.
 func ApplyDefaults(schema *Schema, typeName string, value *GValue) *GValue {
+	value.force()
 	td := schema.GetType(typeName)
 	if td == nil || td.Kind != TypeDefStruct || td.Struct == nil {
 		return value
@@ -594,3 +751,15 @@ func IsValid(value *GValue, schema *Schema) bool {
 func ValidateStrict(value *GValue, schema *Schema) *ValidationResult {
 	return NewStrictValidator(schema).Validate(value)
 }
+
+// RejectUnknownFields validates value as typeName in strict mode and returns
+// the first rejected field as an *UnknownFieldError, or nil if every field is
+// declared. Other validation failures are ignored; use ValidateAs for those.
+// Intended for consumers that must not silently accept extra data.
+func RejectUnknownFields(value *GValue, schema *Schema, typeName string) error {
+	result := NewStrictValidator(schema).ValidateAs(value, typeName)
+	if len(result.Unknown) > 0 {
+		return result.Unknown[0]
+	}
+	return nil
+}
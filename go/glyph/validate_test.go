@@ -1,6 +1,9 @@
 package glyph
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 // ============================================================
 // @open Struct Tests
@@ -484,3 +487,73 @@ func TestStrictValidator_OpenStruct_NoCapture(t *testing.T) {
 		t.Errorf("Expected unknown_field error from strict validator, got: %v", result.Errors)
 	}
 }
+
+func TestRejectUnknownFields_TypedErrorWithSuggestion(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddOpenStruct("Config", "v1",
+			Field("name", PrimitiveType("str")),
+			Field("timeout_ms", PrimitiveType("int"), WithWireKey("t")),
+		).
+		Build()
+
+	value := Struct("Config",
+		MapEntry{Key: "name", Value: Str("myapp")},
+		MapEntry{Key: "timeout_sm", Value: Int(100)},
+	)
+
+	err := RejectUnknownFields(value, schema, "Config")
+	var uf *UnknownFieldError
+	if !errors.As(err, &uf) {
+		t.Fatalf("expected *UnknownFieldError, got %v", err)
+	}
+	if uf.Key != "timeout_sm" || uf.Type != "Config" || uf.Suggestion != "timeout_ms" {
+		t.Errorf("unexpected error fields: %+v", uf)
+	}
+	if got, want := err.Error(), "unknown field timeout_sm in Config (did you mean timeout_ms?)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	// Far-off keys get no suggestion; case-only differences always do.
+	for key, want := range map[string]string{"zzzzzz": "", "NAME": "name"} {
+		v := Struct("Config", MapEntry{Key: "name", Value: Str("x")}, MapEntry{Key: key, Value: Int(1)})
+		if !errors.As(RejectUnknownFields(v, schema, "Config"), &uf) || uf.Suggestion != want {
+			t.Errorf("%s: suggestion = %q, want %q", key, uf.Suggestion, want)
+		}
+	}
+
+	clean := Struct("Config", MapEntry{Key: "name", Value: Str("x")}, MapEntry{Key: "t", Value: Int(1)})
+	if err := RejectUnknownFields(clean, schema, "Config"); err != nil {
+		t.Errorf("declared fields only: unexpected error %v", err)
+	}
+}
+
+func TestStrictValidator_RejectsNestedAndBucket(t *testing.T) {
+	schema := NewSchemaBuilder().
+		AddStruct("Inner", "v1", Field("id", PrimitiveType("int"))).
+		AddOpenStruct("Outer", "v1", Field("inner", RefType("Inner"))).
+		Build()
+
+	value := Struct("Outer",
+		MapEntry{Key: "inner", Value: Struct("Inner",
+			MapEntry{Key: "id", Value: Int(1)},
+			MapEntry{Key: "ids", Value: Int(2)},
+		)},
+		MapEntry{Key: "@unknown", Value: Map(MapEntry{Key: "x", Value: Int(1)})},
+	)
+
+	result := NewStrictValidator(schema).ValidateAs(value, "Outer")
+	if len(result.Unknown) != 2 {
+		t.Fatalf("expected 2 unknown fields, got %v", result.Unknown)
+	}
+	if u := result.Unknown[0]; u.Path != "inner.ids" || u.Suggestion != "id" {
+		t.Errorf("nested unknown: %+v", u)
+	}
+	if u := result.Unknown[1]; u.Key != "@unknown" {
+		t.Errorf("strict mode should reject the @unknown bucket, got %+v", u)
+	}
+
+	// Non-strict validation still tolerates a bucket on an @open type.
+	if r := NewValidator(schema).ValidateAs(value, "Outer"); len(r.Unknown) != 1 {
+		t.Errorf("non-strict: expected only the nested unknown, got %v", r.Unknown)
+	}
+}
@@ -0,0 +1,247 @@
+package glyph
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ============================================================
+// Embedding Vector Codec
+// ============================================================
+//
+// Embeddings are long lists of floats, and as decimal text each element
+// costs several tokens. A field declared with a vector codec holding a list
+// of numbers is emitted as a vector literal instead: the elements packed as
+// little-endian binary and base64 encoded.
+//
+//	[0.125 -0.5 0.75]  →  vec16"ADAAuAA6"
+//
+// The codec name chooses the element format:
+//
+//	@codec(vec32)  float32, exact for float32 embeddings
+//	@codec(vec16)  IEEE half precision, about 3 significant digits
+//	@codec(vec8)   int8 quantized: a float32 scale, then one byte per
+//	               element; element i decodes as q[i] * scale
+//
+// vec16 and vec8 are lossy, which is why the codec is opt-in. Lists that do
+// not fit the format (non-numbers, non-finite values, magnitudes beyond
+// half precision for vec16) are emitted normally. Vector literals are
+// self-describing: every parser decodes them without a schema, back to a
+// list of floats. Vector and AsVector convert between []float32 and values.
+
+// vecCodecs lists the vector codec names, which are also the literal
+// prefixes.
+var vecCodecs = []string{"vec32", "vec16", "vec8"}
+
+// isVecCodec reports whether codec names a vector codec.
+func isVecCodec(codec string) bool {
+	for _, c := range vecCodecs {
+		if codec == c {
+			return true
+		}
+	}
+	return false
+}
+
+// codecFieldLiteral returns the literal for val if fd declares a geo or
+// vector codec that val fits.
+func codecFieldLiteral(val *GValue, fd *FieldDef) (string, bool) {
+	if fd == nil || fd.Codec == "" {
+		return "", false
+	}
+	if isVecCodec(fd.Codec) {
+		return encodeVecLiteral(val, fd.Codec)
+	}
+	return geoFieldLiteral(val, fd)
+}
+
+// Vector returns a list of floats holding xs.
+func Vector(xs []float32) *GValue {
+	items := make([]*GValue, len(xs))
+	for i, x := range xs {
+		items[i] = Float(float64(x))
+	}
+	return List(items...)
+}
+
+// AsVector returns a list of numbers as float32s.
+func (v *GValue) AsVector() ([]float32, error) {
+	items, err := v.AsList()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float32, len(items))
+	for i, item := range items {
+		n, ok := item.Number()
+		if !ok {
+			return nil, fmt.Errorf("glyph: vector element %d: expected number, got %s", i, item.Type())
+		}
+		out[i] = float32(n)
+	}
+	return out, nil
+}
+
+// encodeVecLiteral encodes a list of numbers as a vector literal in the
+// given codec. It returns false if v is not a non-empty list of finite
+// numbers the codec can represent.
+func encodeVecLiteral(v *GValue, codec string) (string, bool) {
+	v.force()
+	if v == nil || v.typ != TypeList || len(v.listVal) == 0 {
+		return "", false
+	}
+	xs := make([]float32, len(v.listVal))
+	maxAbs := 0.0
+	for i, item := range v.listVal {
+		item.force()
+		if item == nil || (item.typ != TypeInt && item.typ != TypeFloat) {
+			return "", false
+		}
+		n, _ := item.Number()
+		if math.IsNaN(n) || math.Abs(n) > math.MaxFloat32 {
+			return "", false
+		}
+		xs[i] = float32(n)
+		maxAbs = math.Max(maxAbs, math.Abs(n))
+	}
+
+	var buf []byte
+	switch codec {
+	case "vec32":
+		buf = make([]byte, 4*len(xs))
+		for i, x := range xs {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+		}
+	case "vec16":
+		buf = make([]byte, 2*len(xs))
+		for i, x := range xs {
+			h, ok := float32ToHalf(x)
+			if !ok {
+				return "", false
+			}
+			binary.LittleEndian.PutUint16(buf[2*i:], h)
+		}
+	case "vec8":
+		scale := float32(maxAbs / 127)
+		buf = make([]byte, 4+len(xs))
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(scale))
+		for i, x := range xs {
+			var q float64
+			if scale != 0 {
+				q = math.Max(-127, math.Min(127, math.Round(float64(x/scale))))
+			}
+			buf[4+i] = byte(int8(q))
+		}
+	default:
+		return "", false
+	}
+	return codec + `"` + base64.StdEncoding.EncodeToString(buf) + `"`, true
+}
+
+// vecLiteralPrefix returns the vector literal prefix (vec8, vec16, vec32)
+// if s starts with one immediately followed by '"', or "".
+func vecLiteralPrefix(s string) string {
+	for _, prefix := range vecCodecs {
+		if len(s) > len(prefix) && s[len(prefix)] == '"' && strings.HasPrefix(s, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// decodeVecLiteral decodes the unquoted body of a vector literal with the
+// given prefix into a list of floats.
+func decodeVecLiteral(prefix, body string) (*GValue, error) {
+	buf, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s literal: %v", prefix, err)
+	}
+	var xs []float32
+	switch prefix {
+	case "vec32":
+		if len(buf)%4 != 0 {
+			return nil, fmt.Errorf("invalid vec32 literal: %d bytes is not a multiple of 4", len(buf))
+		}
+		xs = make([]float32, len(buf)/4)
+		for i := range xs {
+			xs[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+		}
+	case "vec16":
+		if len(buf)%2 != 0 {
+			return nil, fmt.Errorf("invalid vec16 literal: %d bytes is not a multiple of 2", len(buf))
+		}
+		xs = make([]float32, len(buf)/2)
+		for i := range xs {
+			xs[i] = halfToFloat32(binary.LittleEndian.Uint16(buf[2*i:]))
+		}
+	case "vec8":
+		if len(buf) < 4 {
+			return nil, fmt.Errorf("invalid vec8 literal: missing scale")
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(buf))
+		xs = make([]float32, len(buf)-4)
+		for i := range xs {
+			xs[i] = float32(int8(buf[4+i])) * scale
+		}
+	default:
+		return nil, fmt.Errorf("unknown vector literal prefix %q", prefix)
+	}
+	return Vector(xs), nil
+}
+
+// float32ToHalf converts f to IEEE 754 half precision, rounding to nearest
+// even. It returns false if f is not finite or too large for a half.
+func float32ToHalf(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp >= 31:
+		return 0, false // Inf, NaN, or beyond the half range
+	case exp <= 0:
+		// Subnormal half, or zero.
+		if exp < -10 {
+			return sign, true
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := uint32(sign) | mant>>shift
+		rem, half := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > half || rem == half && h&1 == 1 {
+			h++
+		}
+		return uint16(h), true
+	}
+
+	h := uint32(sign) | uint32(exp)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || rem == 0x1000 && h&1 == 1 {
+		h++ // May carry into the exponent, which rounds correctly
+	}
+	if h&0x7c00 == 0x7c00 {
+		return 0, false // Rounded up to Inf
+	}
+	return uint16(h), true
+}
+
+// halfToFloat32 converts an IEEE 754 half precision value to float32.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
@@ -0,0 +1,195 @@
+package glyph
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func vecTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := ParseSchema(`@schema{
+		Doc:v1 @pack struct{
+			id: int @fid(1)
+			e32: list<float> @fid(2) @codec(vec32)
+			e16: list<float> @fid(3) @codec(vec16)
+			e8: list<float> @fid(4) @codec(vec8)
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func vecDoc(xs []float32) *GValue {
+	return Struct("Doc",
+		MapEntry{Key: "id", Value: Int(7)},
+		MapEntry{Key: "e32", Value: Vector(xs)},
+		MapEntry{Key: "e16", Value: Vector(xs)},
+		MapEntry{Key: "e8", Value: Vector(xs)},
+	)
+}
+
+func TestVecCodec_Literals(t *testing.T) {
+	v := List(Float(0.125), Float(-0.5), Int(1))
+	for codec, want := range map[string]string{
+		"vec32": `vec32"AAAAPgAAAL8AAIA/"`,
+		"vec16": `vec16"ADAAuAA8"`,
+		"vec8":  `vec8"BAIBPBDAfw=="`, // scale 1/127, then 16 -64 127
+	} {
+		got, ok := encodeVecLiteral(v, codec)
+		if !ok || got != want {
+			t.Errorf("%s: got %s, want %s", codec, got, want)
+		}
+	}
+
+	for _, bad := range []*GValue{
+		List(),
+		List(Str("x")),
+		List(Float(math.NaN())),
+		List(Float(1e300)),
+	} {
+		if lit, ok := encodeVecLiteral(bad, "vec32"); ok {
+			t.Errorf("encoded %s as %s", CanonicalizeLoose(bad), lit)
+		}
+	}
+	if lit, ok := encodeVecLiteral(List(Float(70000)), "vec16"); ok {
+		t.Errorf("70000 encoded as half: %s", lit)
+	}
+
+	for _, bad := range []string{`vec32"AAA="`, `vec16"AAAA"`, `vec8"AA=="`, `vec16"!!"`} {
+		prefix := vecLiteralPrefix(bad)
+		if _, err := decodeVecLiteral(prefix, bad[len(prefix)+1:len(bad)-1]); err == nil {
+			t.Errorf("decoded %s", bad)
+		}
+	}
+}
+
+func TestVecCodec_Packed(t *testing.T) {
+	schema := vecTestSchema(t)
+	xs := []float32{0.1, -0.25, 0.5, 1, 0}
+	out, err := EmitPacked(vecDoc(xs), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `vec32"`) || !strings.Contains(out, `vec16"`) || !strings.Contains(out, `vec8"`) {
+		t.Fatalf("packed = %s", out)
+	}
+
+	back, err := ParsePacked(out, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tolerance := map[string]float64{"e32": 0, "e16": 1e-3, "e8": 1.0 / 254}
+	for key, tol := range tolerance {
+		got, err := back.Get(key).AsVector()
+		if err != nil || len(got) != len(xs) {
+			t.Fatalf("%s: %v, %v", key, got, err)
+		}
+		for i := range xs {
+			if d := math.Abs(float64(got[i] - xs[i])); d > tol {
+				t.Errorf("%s[%d] = %v, want %v ± %v", key, i, got[i], xs[i], tol)
+			}
+		}
+	}
+}
+
+func TestVecCodec_TextAndTabular(t *testing.T) {
+	schema := vecTestSchema(t)
+	xs := []float32{0.5, -2, 3.25}
+
+	opts := DefaultEmitOptions()
+	opts.Schema = schema
+	text := EmitWithOptions(vecDoc(xs), opts)
+	if !strings.Contains(text, `e32=vec32"`) {
+		t.Fatalf("text = %s", text)
+	}
+	r, err := Parse(text)
+	if err != nil || r.HasErrors() {
+		t.Fatalf("parse: %v %v", err, r.Errors)
+	}
+	if got := CanonicalizeLoose(r.Value.Get("e16")); got != "[0.5 -2.0 3.25]" {
+		t.Errorf("e16 = %s", got)
+	}
+
+	rows := List(vecDoc(xs), vecDoc(xs), vecDoc(xs))
+	tab, err := EmitTabular(rows, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewTabularReaderFromString(tab, schema).ReadAll()
+	if err != nil || len(got) != 3 {
+		t.Fatalf("tabular: %v, %v", got, err)
+	}
+	if v, _ := got[2].Get("e32").AsVector(); len(v) != 3 || v[2] != 3.25 {
+		t.Errorf("row 2 e32 = %v", v)
+	}
+
+	// Loose documents decode vector literals without a schema.
+	v, err := ParseLoose(`{e=vec16"ADgAwIBC"}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalizeLoose(v); got != "{e=[0.5 -2.0 3.25]}" {
+		t.Errorf("loose = %s", got)
+	}
+}
+
+func TestVecCodec_Half(t *testing.T) {
+	for _, tc := range []struct {
+		f float32
+		h uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{6.1035156e-05, 0x0400}, // Smallest normal
+		{5.9604645e-08, 0x0001}, // Smallest subnormal
+		{2.9802322e-08, 0x0000}, // Half the smallest subnormal: ties to even
+		{1.0009766, 0x3c01},     // 1 + 2^-10
+		{1.00048828125, 0x3c00}, // Halfway between 1 and 1+2^-10: ties to even
+		{1.00146484375, 0x3c02}, // Halfway above an odd mantissa: rounds up
+		{0.33325195, 0x3555},
+	} {
+		h, ok := float32ToHalf(tc.f)
+		if !ok || h != tc.h {
+			t.Errorf("float32ToHalf(%v) = %#04x, want %#04x", tc.f, h, tc.h)
+		}
+	}
+	for _, f := range []float32{65520, float32(math.Inf(1)), float32(math.NaN())} {
+		if h, ok := float32ToHalf(f); ok {
+			t.Errorf("float32ToHalf(%v) = %#04x, want overflow", f, h)
+		}
+	}
+
+	// Every finite half converts to float32 and back unchanged.
+	for h := 0; h < 0x10000; h++ {
+		if h&0x7c00 == 0x7c00 {
+			continue
+		}
+		back, ok := float32ToHalf(halfToFloat32(uint16(h)))
+		if !ok || back != uint16(h) {
+			t.Fatalf("half %#04x round trips to %#04x", h, back)
+		}
+	}
+}
+
+func TestVector_Helpers(t *testing.T) {
+	xs := []float32{1.5, -0.25}
+	got, err := Vector(xs).AsVector()
+	if err != nil || len(got) != 2 || got[0] != 1.5 || got[1] != -0.25 {
+		t.Errorf("AsVector = %v, %v", got, err)
+	}
+	if got, _ := List(Int(3)).AsVector(); got[0] != 3 {
+		t.Errorf("int element = %v", got)
+	}
+	if _, err := List(Str("x")).AsVector(); err == nil {
+		t.Error("expected error for a non-number element")
+	}
+	if _, err := Str("x").AsVector(); err == nil {
+		t.Error("expected error for a non-list")
+	}
+}
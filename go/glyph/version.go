@@ -0,0 +1,117 @@
+package glyph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// Format Version Header
+// ============================================================
+//
+// A document may begin with a line naming the GLYPH format version it was
+// written for, ahead of any @doc header:
+//
+//	@glyph 2.6
+//	{...}
+//
+// LooseCanonOpts.VersionHeader emits it. Parsers skip it, and
+// ParseDocumentWithMeta reports it in DocMeta.Version. A document that
+// declares a version newer than FormatVersion still parses if this library
+// can read it, with a warning in DocMeta.Warnings. If it cannot, the parse
+// error is a *VersionError naming both versions: the likely cause is syntax
+// this library predates, not a malformed document.
+
+// FormatVersion is the newest GLYPH format version this library reads and
+// writes.
+const FormatVersion = "2.6"
+
+// VersionError is returned when a document declaring a format version newer
+// than FormatVersion fails to parse.
+type VersionError struct {
+	Version   string // Version the document declares
+	Supported string // FormatVersion
+	Err       error  // The parse error
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("document declares GLYPH %s; this library supports up to %s: %v", e.Version, e.Supported, e.Err)
+}
+
+func (e *VersionError) Unwrap() error {
+	return e.Err
+}
+
+// EmitVersionHeader returns the version header line for FormatVersion
+// (without a newline).
+func EmitVersionHeader() string {
+	return "@glyph " + FormatVersion
+}
+
+// SplitVersionHeader separates a leading @glyph (or legacy @lyph) header
+// line from the document body. version is as written, e.g. "2.6" or "v2",
+// and "" if there is no header; a header without a version reads as "v2".
+func SplitVersionHeader(input string) (version, body string, err error) {
+	trimmed := strings.TrimLeft(input, " \t\r\n")
+	if !isVersionHeader(trimmed) {
+		return "", input, nil
+	}
+	line, body, _ := strings.Cut(trimmed, "\n")
+	h, err := ParseHeader(line)
+	if err != nil {
+		return "", input, err
+	}
+	return h.Version, body, nil
+}
+
+// isVersionHeader reports whether s starts with an @glyph or @lyph token,
+// as opposed to another directive sharing the prefix.
+func isVersionHeader(s string) bool {
+	for _, tok := range []string{"@glyph", "@lyph"} {
+		if rest, ok := strings.CutPrefix(s, tok); ok {
+			return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n'
+		}
+	}
+	return false
+}
+
+// splitDocHeaders strips a leading version header and then a leading @doc
+// header from input.
+func splitDocHeaders(input string) (version string, meta *DocMeta, body string, err error) {
+	version, body, err = SplitVersionHeader(input)
+	if err != nil {
+		return "", nil, input, err
+	}
+	meta, body, err = SplitDocHeader(body)
+	if err != nil {
+		return "", nil, input, err
+	}
+	return version, meta, body, nil
+}
+
+// versionWarning returns the warning for a declared version this library
+// may not fully read, or "" if it is supported.
+func versionWarning(version string) string {
+	if version == "" {
+		return ""
+	}
+	switch {
+	case !isFormatVersion(version):
+		return fmt.Sprintf("unrecognized GLYPH version %q; supported version is %s", version, FormatVersion)
+	case compareVersions(strings.TrimPrefix(version, "v"), FormatVersion) > 0:
+		return fmt.Sprintf("document declares GLYPH %s, newer than supported %s; newer features may be misread", version, FormatVersion)
+	}
+	return ""
+}
+
+// isFormatVersion reports whether v is a dotted version such as "2.6",
+// "v2" or "2.6.0".
+func isFormatVersion(v string) bool {
+	for _, s := range strings.Split(strings.TrimPrefix(v, "v"), ".") {
+		if _, err := strconv.ParseUint(s, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
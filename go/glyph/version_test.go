@@ -0,0 +1,99 @@
+package glyph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVersionHeader_Emit(t *testing.T) {
+	v := Map(MapEntry{Key: "a", Value: Int(1)})
+	opts := DefaultLooseCanonOpts()
+	opts.VersionHeader = true
+	opts.Verify = true
+
+	out := CanonicalizeLooseWithOpts(v, opts)
+	if out != "@glyph "+FormatVersion+"\n{a=1}" {
+		t.Fatalf("output = %q", out)
+	}
+	back, err := ParseLoose(out, nil)
+	if err != nil || !EqualLoose(back, v) {
+		t.Fatalf("round trip: %v, %v", back, err)
+	}
+
+	ctx := NewSchemaContext([]string{"a"})
+	opts.Schema = ctx
+	out = CanonicalizeLooseWithSchema(v, opts)
+	if !strings.HasPrefix(out, EmitVersionHeader()+"\n@schema#") {
+		t.Errorf("schema output = %q", out)
+	}
+	if FingerprintLoose(v) != FingerprintLoose(back) {
+		t.Error("version header changed the fingerprint")
+	}
+}
+
+func TestVersionHeader_Parse(t *testing.T) {
+	tests := []struct {
+		in      string
+		version string
+		warn    bool
+	}{
+		{"{a=1}", "", false},
+		{"@glyph 2.6\n{a=1}", "2.6", false},
+		{"@glyph 2.4\n@doc id=^d1\n{a=1}", "2.4", false},
+		{"@lyph v2\n{a=1}", "v2", false},
+		{"@glyph\n{a=1}", "v2", false},
+		{"@glyph 2.7\n{a=1}", "2.7", true},
+		{"@glyph 3\n{a=1}", "3", true},
+		{"@glyph next\n{a=1}", "next", true},
+	}
+	for _, tt := range tests {
+		v, meta, err := ParseDocumentWithMeta(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if got, _ := v.Get("a").AsInt(); got != 1 {
+			t.Errorf("%q: a = %d", tt.in, got)
+		}
+		if tt.version == "" {
+			if meta != nil {
+				t.Errorf("%q: meta = %+v", tt.in, meta)
+			}
+			continue
+		}
+		if meta == nil || meta.Version != tt.version || (len(meta.Warnings) > 0) != tt.warn {
+			t.Errorf("%q: meta = %+v", tt.in, meta)
+		}
+	}
+
+	_, meta, _ := ParseDocumentWithMeta("@glyph 2.4\n@doc id=^d1\n{a=1}")
+	if meta.ID.Value != "d1" {
+		t.Errorf("@doc header after version header lost: %+v", meta)
+	}
+}
+
+func TestVersionHeader_NewerSyntax(t *testing.T) {
+	// Syntax from a future version fails with the versions named.
+	_, err := ParseLoose("@glyph 3.0\n{a=1 %%b}", nil)
+	var ve *VersionError
+	if !errors.As(err, &ve) || ve.Version != "3.0" || ve.Supported != FormatVersion {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(err.Error(), "supports up to "+FormatVersion) {
+		t.Errorf("message = %q", err)
+	}
+
+	// The same error under a supported version stays a plain syntax error.
+	_, err = ParseLoose("@glyph 2.6\n{a=1 %%b}", nil)
+	if err == nil || errors.As(err, &ve) {
+		t.Errorf("supported version: err = %v", err)
+	}
+
+	if _, err := ParseLoose("@glyph 2.6 @mode=bogus\n{a=1}", nil); err == nil {
+		t.Error("expected error for malformed header")
+	}
+	if _, _, err := SplitVersionHeader("@glyphs\n{}"); err != nil {
+		t.Errorf("@glyphs treated as a header: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// ============================================================
+// Producer Clocks
+// ============================================================
+//
+// A frame may carry its producer's clock as ts=<unix ms> (Frame.Time; see
+// Writer.SetClock), and the Reader stamps each frame with the local time
+// it was read (Frame.Received). Producer clocks are not the local clock, so
+// the Reader keeps an estimate of each SID's clock skew, the producer clock
+// minus the local clock:
+//
+//   - Every timestamped frame bounds it from below: a frame cannot arrive
+//     before it was sent, so skew >= Time - Received. With only this bound
+//     the fastest frame seen counts as having taken no time.
+//   - A ping answered by a timestamped pong measures it, as in NTP:
+//     skew = pong.Time - (pingSent + rtt/2). SyncClock records this.
+//
+// LocalTime puts a frame's Time on the local clock, for ordering events
+// from several producers, and Latency is how long the frame took to arrive.
+
+// clockEstimate is the skew estimate of one SID.
+type clockEstimate struct {
+	lower    time.Duration // Largest Time - Received seen
+	measured time.Duration // From the last SyncClock
+	synced   bool
+}
+
+// bound raises the lower bound to at least d.
+func (c *clockEstimate) bound(d time.Duration) {
+	if d > c.lower {
+		c.lower = d
+	}
+}
+
+// skew is the measurement, or the lower bound if that is higher.
+func (c *clockEstimate) skew() time.Duration {
+	if c.synced && c.measured > c.lower {
+		return c.measured
+	}
+	return c.lower
+}
+
+// clock returns sid's estimate, creating it with lower as its bound.
+func (r *Reader) clock(sid uint64, lower time.Duration) *clockEstimate {
+	c := r.clocks[sid]
+	if c == nil {
+		c = &clockEstimate{lower: lower}
+		r.clocks[sid] = c
+	}
+	c.bound(lower)
+	return c
+}
+
+// SyncClock measures the skew of sid's producer from a pong it sent in
+// reply to a ping the caller sent at pingSent, local time. The measurement
+// replaces any earlier one; the estimate never drops below the bound set
+// by the frames read.
+func (r *Reader) SyncClock(sid uint64, pingSent time.Time, pong *Frame) {
+	if pong.Time.IsZero() || pong.Received.IsZero() {
+		return
+	}
+	rtt := pong.Received.Sub(pingSent)
+	c := r.clock(sid, pong.Time.Sub(pong.Received))
+	c.measured, c.synced = pong.Time.Sub(pingSent.Add(rtt/2)), true
+}
+
+// ClockSkew returns the estimated skew of sid's producer clock, its clock
+// minus the local clock. It is false until a timestamped frame on sid has
+// been read.
+func (r *Reader) ClockSkew(sid uint64) (time.Duration, bool) {
+	c := r.clocks[sid]
+	if c == nil {
+		return 0, false
+	}
+	return c.skew(), true
+}
+
+// LocalTime returns when f was sent, on the local clock. It is zero if f
+// has no Time.
+func (r *Reader) LocalTime(f *Frame) time.Time {
+	if f.Time.IsZero() {
+		return time.Time{}
+	}
+	skew, _ := r.ClockSkew(f.SID)
+	return f.Time.Add(-skew)
+}
+
+// Latency returns how long f took from its producer to this reader, or 0
+// if f has no Time.
+func (r *Reader) Latency(f *Frame) time.Duration {
+	sent := r.LocalTime(f)
+	if sent.IsZero() {
+		return 0
+	}
+	return f.Received.Sub(sent)
+}
+
+// ============================================================
+// Replay Clocks
+// ============================================================
+
+// StepClock is a clock for reproducible streams. It starts at a fixed time
+// and moves only when read, by a fixed step, or when slept on, so a demo or
+// recording driven by it writes the same timestamps on every run and never
+// waits. Pass its Now to Writer.SetClock, WithClock or LogAt and its Sleep
+// where the producer would sleep. Safe for concurrent use.
+type StepClock struct {
+	mu   sync.Mutex
+	t    time.Time
+	step time.Duration
+}
+
+// NewStepClock creates a clock reading start, then start+step, and so on.
+func NewStepClock(start time.Time, step time.Duration) *StepClock {
+	return &StepClock{t: start, step: step}
+}
+
+// Now returns the clock's time and advances it by its step.
+func (c *StepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.t
+	c.t = c.t.Add(c.step)
+	return t
+}
+
+// Sleep advances the clock by d without waiting.
+func (c *StepClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader_ClockSkew(t *testing.T) {
+	local := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	skew := 5 * time.Second
+	ms := time.Millisecond
+
+	// The producer's clock runs 5s ahead of ours.
+	var sent []time.Time
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetClock(func() time.Time { return sent[len(sent)-1] })
+	for _, at := range []time.Duration{0, time.Second, 2*time.Second + 30*ms} {
+		sent = append(sent, local.Add(at+skew))
+		w.WriteDoc(1, uint64(len(sent)-1), []byte("{}"))
+	}
+	w.SetClock(nil)
+	w.WriteDoc(2, 0, []byte("{}"))
+	if strings.Count(buf.String(), "ts=") != 3 {
+		t.Fatalf("timestamps:\n%s", buf.String())
+	}
+
+	arrivals := []time.Time{local.Add(100 * ms), local.Add(time.Second + 40*ms), local.Add(2*time.Second + 60*ms), local}
+	r := NewReader(&buf, WithClock(func() time.Time {
+		at := arrivals[0]
+		arrivals = arrivals[1:]
+		return at
+	}))
+	frames, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !frames[0].Time.Equal(local.Add(skew)) || !frames[0].Received.Equal(local.Add(100*ms)) {
+		t.Errorf("frame 0: Time %v, Received %v", frames[0].Time, frames[0].Received)
+	}
+
+	// From one-way frames alone, the fastest one counts as instant.
+	if got, ok := r.ClockSkew(1); !ok || got != skew-30*ms {
+		t.Errorf("ClockSkew = %v, %v", got, ok)
+	}
+	if got := r.Latency(frames[0]); got != 70*ms {
+		t.Errorf("Latency before sync = %v", got)
+	}
+
+	// A pong answering a ping sent at 2s measures the skew exactly.
+	r.SyncClock(1, local.Add(2*time.Second), frames[2])
+	if got, _ := r.ClockSkew(1); got != skew {
+		t.Errorf("ClockSkew after sync = %v", got)
+	}
+	if got := r.Latency(frames[0]); got != 100*ms {
+		t.Errorf("Latency after sync = %v", got)
+	}
+	if got := r.LocalTime(frames[1]); !got.Equal(local.Add(time.Second)) {
+		t.Errorf("LocalTime = %v", got)
+	}
+
+	if _, ok := r.ClockSkew(2); ok || r.Latency(frames[3]) != 0 || !r.LocalTime(frames[3]).IsZero() {
+		t.Error("untimed SID has a clock estimate")
+	}
+	r.Forget(1)
+	if _, ok := r.ClockSkew(1); ok {
+		t.Error("Forget kept the clock estimate")
+	}
+
+	if _, err := NewReader(strings.NewReader("@frame{v=1 sid=1 seq=0 kind=doc len=0 ts=soon}\n")).Next(); err == nil {
+		t.Error("expected error for malformed ts")
+	}
+}
+
+func TestStepClock_Replay(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func() string {
+		clock := NewStepClock(start, time.Millisecond)
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetClock(clock.Now)
+		w.WriteUI(1, 1, EmitLogAt("info", "starting", clock.Now()))
+		clock.Sleep(time.Second)
+		w.WriteUI(1, 2, EmitLogAt("info", "done", clock.Now()))
+		return buf.String()
+	}
+
+	first := record()
+	if second := record(); second != first {
+		t.Fatalf("replay differs:\n%s\n---\n%s", first, second)
+	}
+	frames, err := NewReader(strings.NewReader(first)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frames[1].Time.Sub(frames[0].Time); got != time.Second+2*time.Millisecond {
+		t.Errorf("frame times %v apart", got)
+	}
+	if !strings.Contains(string(frames[1].Payload), "2025-01-01T00:00:01.002Z") {
+		t.Errorf("log payload: %s", frames[1].Payload)
+	}
+}
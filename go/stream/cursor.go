@@ -26,6 +26,7 @@ type SIDState struct {
 	HasState  bool          // Whether StateHash is valid
 	State     *glyph.GValue // Current state document (optional)
 	Final     bool          // Whether stream has ended
+	FinalSeq  uint64        // Seq of the final frame, if Final
 }
 
 // NewStreamCursor creates a new stream cursor.
@@ -76,6 +77,7 @@ func (sc *StreamCursor) AllSIDs() []uint64 {
 
 // ProcessFrame processes a frame and updates cursor state.
 // Returns an error if:
+//   - The SID has already ended (*StreamClosedError)
 //   - Sequence number is not monotonic (gap or duplicate)
 //   - Base hash mismatch for patch frames
 //
@@ -83,6 +85,10 @@ func (sc *StreamCursor) AllSIDs() []uint64 {
 func (sc *StreamCursor) ProcessFrame(frame *Frame) error {
 	state := sc.Get(frame.SID)
 
+	if state.Final {
+		return &StreamClosedError{SID: frame.SID, FinalSeq: state.FinalSeq, Seq: frame.Seq}
+	}
+
 	// Check sequence monotonicity
 	if frame.Seq != 0 && frame.Seq <= state.LastSeq {
 		return fmt.Errorf("sequence not monotonic: got %d, last was %d", frame.Seq, state.LastSeq)
@@ -109,6 +115,7 @@ func (sc *StreamCursor) ProcessFrame(frame *Frame) error {
 	// Update final flag
 	if frame.IsFinal() {
 		state.Final = true
+		state.FinalSeq = frame.Seq
 	}
 
 	return nil
@@ -183,6 +190,10 @@ type FrameHandler struct {
 	OnErr   func(sid uint64, seq uint64, payload []byte, state *SIDState) error
 	OnFinal func(sid uint64, state *SIDState) error
 
+	// OnRepeat is called for a repeat frame (Frame.Repeat) instead of the
+	// callback for its kind, which already saw the payload.
+	OnRepeat func(sid uint64, seq uint64, kind FrameKind, state *SIDState) error
+
 	// Error handling
 	OnSeqGap       func(sid uint64, expected, got uint64) error // Called on sequence gap
 	OnBaseMismatch func(sid uint64, frame *Frame) error         // Called on base hash mismatch
@@ -196,9 +207,18 @@ func NewFrameHandler() *FrameHandler {
 }
 
 // Handle processes a frame and calls the appropriate callback.
+// A frame on a SID that has ended returns a *StreamClosedError, except a
+// resent final frame, which is discarded like any duplicate.
 func (h *FrameHandler) Handle(frame *Frame) error {
 	state := h.Cursor.Get(frame.SID)
 
+	if state.Final {
+		if frame.Seq == state.FinalSeq {
+			return nil
+		}
+		return &StreamClosedError{SID: frame.SID, FinalSeq: state.FinalSeq, Seq: frame.Seq}
+	}
+
 	// Check sequence
 	if frame.Seq != 0 && state.LastSeq > 0 {
 		if frame.Seq <= state.LastSeq {
@@ -230,28 +250,32 @@ func (h *FrameHandler) Handle(frame *Frame) error {
 
 	// Dispatch to callback
 	var err error
-	switch frame.Kind {
-	case KindDoc:
+	switch {
+	case frame.Repeat:
+		if h.OnRepeat != nil {
+			err = h.OnRepeat(frame.SID, frame.Seq, frame.Kind, state)
+		}
+	case frame.Kind == KindDoc:
 		if h.OnDoc != nil {
 			err = h.OnDoc(frame.SID, frame.Seq, frame.Payload, state)
 		}
-	case KindPatch:
+	case frame.Kind == KindPatch:
 		if h.OnPatch != nil {
 			err = h.OnPatch(frame.SID, frame.Seq, frame.Payload, state)
 		}
-	case KindRow:
+	case frame.Kind == KindRow:
 		if h.OnRow != nil {
 			err = h.OnRow(frame.SID, frame.Seq, frame.Payload, state)
 		}
-	case KindUI:
+	case frame.Kind == KindUI:
 		if h.OnUI != nil {
 			err = h.OnUI(frame.SID, frame.Seq, frame.Payload, state)
 		}
-	case KindAck:
+	case frame.Kind == KindAck:
 		if h.OnAck != nil {
 			err = h.OnAck(frame.SID, frame.Seq, state)
 		}
-	case KindErr:
+	case frame.Kind == KindErr:
 		if h.OnErr != nil {
 			err = h.OnErr(frame.SID, frame.Seq, frame.Payload, state)
 		}
@@ -264,6 +288,7 @@ func (h *FrameHandler) Handle(frame *Frame) error {
 	// Handle final
 	if frame.IsFinal() {
 		state.Final = true
+		state.FinalSeq = frame.Seq
 		if h.OnFinal != nil {
 			return h.OnFinal(frame.SID, state)
 		}
@@ -1,6 +1,7 @@
 package stream
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/Neumenon/glyph/glyph"
@@ -132,6 +133,70 @@ func TestStreamCursor_PatchVerification(t *testing.T) {
 	}
 }
 
+func TestStreamCursor_DocumentStream(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteUI(1, 1, EmitLog("info", "starting")); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &glyph.Document{Body: glyph.Struct("AgentState",
+		glyph.MapEntry{Key: "step", Value: glyph.Int(0)},
+		glyph.MapEntry{Key: "items", Value: glyph.List()},
+	)}
+	ds := doc.AttachStream(w, 1)
+	if err := ds.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	for step := int64(1); step <= 3; step++ {
+		err := ds.Batch(func() error {
+			doc.Set("step", glyph.Int(step))
+			return doc.Append("items", glyph.Int(step))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A consumer verifies every base and ends in the producer's state.
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := NewStreamCursor()
+	var state *glyph.GValue
+	for _, f := range frames {
+		if err := cursor.ProcessFrame(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+		switch f.Kind {
+		case KindDoc:
+			r, err := glyph.Parse(string(f.Payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			state = r.Value
+		case KindPatch:
+			p, err := glyph.ParsePatch(string(f.Payload), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state, err = glyph.ApplyPatch(state, p); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			continue
+		}
+		cursor.SetState(1, state)
+	}
+	if len(frames) != 5 || frames[4].Seq != 5 || frames[4].Base == nil {
+		t.Fatalf("frames = %d", len(frames))
+	}
+	if !glyph.EqualLoose(state, doc.Body) {
+		t.Errorf("consumer state %s, want %s", glyph.CanonicalizeLoose(state), glyph.CanonicalizeLoose(doc.Body))
+	}
+}
+
 func TestStreamCursor_Ack(t *testing.T) {
 	cursor := NewStreamCursor()
 
@@ -179,6 +244,46 @@ func TestStreamCursor_Final(t *testing.T) {
 	}
 }
 
+func TestStreamCursor_FramesAfterFinal(t *testing.T) {
+	cursor := NewStreamCursor()
+	cursor.ProcessFrame(&Frame{SID: 1, Seq: 1, Kind: KindDoc, Payload: []byte("a")})
+	cursor.ProcessFrame(&Frame{SID: 1, Seq: 2, Kind: KindAck, Final: true})
+
+	for _, seq := range []uint64{1, 3, 0} {
+		err := cursor.ProcessFrame(&Frame{SID: 1, Seq: seq, Kind: KindDoc})
+		closed, ok := err.(*StreamClosedError)
+		if !ok || closed.FinalSeq != 2 || closed.Seq != seq {
+			t.Errorf("seq %d after final: %v", seq, err)
+		}
+	}
+	if err := cursor.ProcessFrame(&Frame{SID: 2, Seq: 1, Kind: KindDoc}); err != nil {
+		t.Errorf("other SID: %v", err)
+	}
+}
+
+func TestFrameHandler_FramesAfterFinal(t *testing.T) {
+	handler := NewFrameHandler()
+	docs := 0
+	handler.OnDoc = func(sid, seq uint64, payload []byte, state *SIDState) error {
+		docs++
+		return nil
+	}
+
+	final := &Frame{SID: 1, Seq: 2, Kind: KindDoc, Payload: []byte("b"), Final: true}
+	handler.Handle(&Frame{SID: 1, Seq: 1, Kind: KindDoc, Payload: []byte("a")})
+	handler.Handle(final)
+	if err := handler.Handle(final); err != nil {
+		t.Errorf("resent final frame: %v", err)
+	}
+	err := handler.Handle(&Frame{SID: 1, Seq: 3, Kind: KindDoc, Payload: []byte("c")})
+	if _, ok := err.(*StreamClosedError); !ok {
+		t.Errorf("frame after final: %v", err)
+	}
+	if docs != 2 {
+		t.Errorf("OnDoc called %d times, want 2", docs)
+	}
+}
+
 func TestFrameHandler_Basic(t *testing.T) {
 	handler := NewFrameHandler()
 
@@ -293,6 +398,10 @@ func TestErrorCodeRegistry(t *testing.T) {
 		{ErrCodePayloadTooLarge, "PAYLOAD_TOO_LARGE"},
 		{ErrCodeHeaderTooLarge, "HEADER_TOO_LARGE"},
 		{ErrCodeFrameInvalid, "FRAME_INVALID"},
+		{ErrCodeUnknownSchema, "UNKNOWN_SCHEMA"},
+		{ErrCodePayloadInvalid, "PAYLOAD_INVALID"},
+		{ErrCodeToolFailed, "TOOL_FAILED"},
+		{ErrCodeStreamClosed, "STREAM_CLOSED"},
 	}
 	for _, c := range cases {
 		if c.constant != c.expected {
@@ -300,3 +409,32 @@ func TestErrorCodeRegistry(t *testing.T) {
 		}
 	}
 }
+
+func TestFrameHandler_Repeat(t *testing.T) {
+	h := NewFrameHandler()
+	var docs []string
+	var repeats []FrameKind
+	h.OnDoc = func(sid, seq uint64, payload []byte, state *SIDState) error {
+		docs = append(docs, string(payload))
+		return nil
+	}
+	h.OnRepeat = func(sid, seq uint64, kind FrameKind, state *SIDState) error {
+		repeats = append(repeats, kind)
+		return nil
+	}
+	for _, f := range []*Frame{
+		{SID: 1, Seq: 1, Kind: KindDoc, Payload: []byte("{}")},
+		{SID: 1, Seq: 2, Kind: KindDoc, Repeat: true},
+		{SID: 1, Seq: 3, Kind: KindUI, Repeat: true},
+	} {
+		if err := h.Handle(f); err != nil {
+			t.Fatalf("seq %d: %v", f.Seq, err)
+		}
+	}
+	if len(docs) != 1 || len(repeats) != 2 || repeats[0] != KindDoc || repeats[1] != KindUI {
+		t.Errorf("docs %q, repeats %v", docs, repeats)
+	}
+	if h.Cursor.Get(1).LastSeq != 3 {
+		t.Errorf("LastSeq = %d", h.Cursor.Get(1).LastSeq)
+	}
+}
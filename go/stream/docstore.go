@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// ============================================================
+// Typed Views of Document State
+// ============================================================
+//
+// A consumer of agent state wants a Plan with a Steps slice, not a GValue to
+// walk. DecodeInto projects a doc or row frame's payload into a Go value,
+// and a DocStore keeps each SID's document current as doc and patch frames
+// arrive, so View can project the latest state:
+//
+//	store := stream.NewDocStore(schemas) // schemas may be nil
+//	for {
+//	    frame, err := r.Next()
+//	    ...
+//	    if err := store.Apply(frame); err != nil { ... }
+//	    plan, err := stream.View[Plan](store, frame.SID)
+//	}
+//
+// Values are projected through the loose JSON bridge (glyph.ToJSONLoose), so
+// the Go type is matched the way encoding/json matches it: by json tags, or
+// field names compared case-insensitively. Struct type names are dropped,
+// times arrive as RFC 3339 strings and bytes as base64, which time.Time and
+// []byte fields decode. View is a function rather than a DocStore method
+// since Go methods cannot take type parameters.
+
+// ErrNoDocument is returned by View for a SID with no document state.
+var ErrNoDocument = errors.New("gs1: no document state")
+
+// DecodeInto parses the payload of a doc or row frame and projects it into a
+// T. With a schema the payload is parsed as GLYPH-T under it and validated
+// first, as TypedReader does; a payload that fails is a *PayloadError.
+func DecodeInto[T any](frame *Frame, schema *glyph.Schema) (T, error) {
+	var out T
+	if frame.Kind != KindDoc && frame.Kind != KindRow {
+		return out, fmt.Errorf("gs1: sid=%d seq=%d: cannot decode a %s frame", frame.SID, frame.Seq, frame.Kind)
+	}
+	v, _, err := decodePayload(frame, schema)
+	if err != nil {
+		return out, err
+	}
+	return project[T](v)
+}
+
+// DocStore holds the current document of each SID, built from the doc and
+// patch frames passed to Apply. Safe for concurrent use.
+type DocStore struct {
+	mu       sync.RWMutex
+	cursor   *StreamCursor
+	resolver SchemaResolver
+}
+
+// NewDocStore creates an empty store. resolver finds the schemas frames
+// name by hash; with a nil resolver, such frames are an error.
+func NewDocStore(resolver SchemaResolver) *DocStore {
+	return &DocStore{cursor: NewStreamCursor(), resolver: resolver}
+}
+
+// Apply checks frame's seq, and base for a patch, against its SID (see
+// StreamCursor.ProcessFrame), then updates the SID's document: a doc frame
+// replaces it, a patch frame is applied to it. Frames of other kinds, and
+// repeat frames (Frame.Repeat), only advance the seq. A frame that fails the checks or does not parse changes
+// nothing. A patch that does not apply leaves the SID without a document,
+// until the next doc frame.
+func (s *DocStore) Apply(frame *Frame) error {
+	if frame.Kind != KindDoc && frame.Kind != KindPatch || frame.Repeat {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.cursor.ProcessFrame(frame)
+	}
+
+	var schema *glyph.Schema
+	if frame.Schema != "" {
+		if s.resolver == nil {
+			return fmt.Errorf("gs1: sid=%d seq=%d: %w %s", frame.SID, frame.Seq, ErrUnknownSchema, frame.Schema)
+		}
+		var err error
+		if schema, err = s.resolver.ResolveSchema(frame.Schema); err != nil {
+			return fmt.Errorf("gs1: sid=%d seq=%d: %w", frame.SID, frame.Seq, err)
+		}
+	}
+
+	var doc *glyph.GValue
+	var patch *glyph.Patch
+	if frame.Kind == KindDoc {
+		var err error
+		if doc, _, err = decodePayload(frame, schema); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if patch, err = glyph.ParsePatch(string(frame.Payload), schema); err != nil {
+			return payloadError(frame, schema, []string{err.Error()})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.cursor.ProcessFrame(frame); err != nil {
+		return err
+	}
+	if patch != nil {
+		state := s.cursor.Get(frame.SID)
+		if state.State == nil {
+			state.HasState = false
+			return fmt.Errorf("gs1: sid=%d seq=%d: patch before any doc frame", frame.SID, frame.Seq)
+		}
+		var err error
+		if schema != nil {
+			doc, err = glyph.ApplyPatchWithSchema(state.State, patch, schema)
+		} else {
+			doc, err = glyph.ApplyPatch(state.State, patch)
+		}
+		if err != nil {
+			state.State, state.HasState = nil, false
+			return fmt.Errorf("gs1: sid=%d seq=%d: %w", frame.SID, frame.Seq, err)
+		}
+	}
+	s.cursor.SetState(frame.SID, doc)
+	return nil
+}
+
+// Get returns the current document of sid.
+func (s *DocStore) Get(sid uint64) (*glyph.GValue, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state := s.cursor.GetReadOnly(sid)
+	if state == nil || state.State == nil {
+		return nil, false
+	}
+	return state.State, true
+}
+
+// View projects the current document of sid into a T. It returns
+// ErrNoDocument if the SID has none.
+func View[T any](s *DocStore, sid uint64) (T, error) {
+	v, ok := s.Get(sid)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%w for sid %d", ErrNoDocument, sid)
+	}
+	return project[T](v)
+}
+
+// project converts v into a T through its loose JSON form.
+func project[T any](v *glyph.GValue) (T, error) {
+	var out T
+	data, err := glyph.ToJSONLoose(v)
+	if err != nil {
+		return out, fmt.Errorf("gs1: project into %T: %w", out, err)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("gs1: project into %T: %w", out, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,166 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+type agentState struct {
+	Step  int      `json:"step"`
+	Items []string `json:"items"`
+	Done  bool     `json:"done"`
+}
+
+func TestDocStore_View(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	doc := &glyph.Document{Body: glyph.Struct("AgentState",
+		glyph.MapEntry{Key: "step", Value: glyph.Int(0)},
+		glyph.MapEntry{Key: "items", Value: glyph.List()},
+	)}
+	ds := doc.AttachStream(w, 1)
+	if err := ds.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteUI(1, w.NextSeq(1), EmitLog("info", "working"))
+	ds.Batch(func() error {
+		doc.Set("step", glyph.Int(1))
+		return doc.Append("items", glyph.Str("fetch"))
+	})
+	doc.Set("done", glyph.Bool(true))
+
+	store := NewDocStore(nil)
+	if _, err := View[agentState](store, 1); !errors.Is(err, ErrNoDocument) {
+		t.Fatalf("empty store: %v", err)
+	}
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range frames {
+		if err := store.Apply(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+	}
+
+	got, err := View[agentState](store, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := agentState{Step: 1, Items: []string{"fetch"}, Done: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("View = %+v, want %+v", got, want)
+	}
+	if v, ok := store.Get(1); !ok || !glyph.EqualLoose(v, doc.Body) {
+		t.Errorf("Get = %v, %v", v, ok)
+	}
+}
+
+func TestDocStore_RejectsBadFrames(t *testing.T) {
+	store := NewDocStore(nil)
+	if err := store.Apply(&Frame{SID: 1, Seq: 1, Kind: KindDoc, Payload: []byte("{step=1}")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale base changes nothing.
+	stale := StateHashLoose(glyph.Map())
+	err := store.Apply(&Frame{SID: 1, Seq: 2, Kind: KindPatch, Base: &stale, Payload: []byte("@patch\n= step 2\n@end")})
+	var mismatch *BaseMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("stale base: %v", err)
+	}
+	if got, _ := View[agentState](store, 1); got.Step != 1 {
+		t.Errorf("step = %d after rejected patch", got.Step)
+	}
+
+	// A schema the store cannot resolve is an error.
+	err = store.Apply(&Frame{SID: 2, Seq: 1, Kind: KindDoc, Payload: []byte("{}"), Schema: "00ff"})
+	if !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("unknown schema: %v", err)
+	}
+
+	// A patch with no doc before it leaves nothing to view.
+	if err := store.Apply(&Frame{SID: 3, Seq: 1, Kind: KindPatch, Payload: []byte("@patch\n= step 2\n@end")}); err == nil {
+		t.Error("patch without doc applied")
+	}
+	if _, err := View[agentState](store, 3); !errors.Is(err, ErrNoDocument) {
+		t.Errorf("View after failed patch: %v", err)
+	}
+}
+
+// TestDocStore_Repeat applies a deduplicated stream: repeat doc frames keep
+// the document, and a patch in between is followed by the doc in full.
+func TestDocStore_Repeat(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetDedup(KindDoc)
+	w.WriteDoc(1, 1, []byte("{step=1}"))
+	w.WriteDoc(1, 2, []byte("{step=1}"))
+	w.WritePatch(1, 3, []byte("@patch\n= step 2\n@end"), nil)
+	w.WriteDoc(1, 4, []byte("{step=1}"))
+	w.WriteDoc(1, 5, []byte("{step=1}"))
+
+	store := NewDocStore(nil)
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range frames {
+		if err := store.Apply(f); err != nil {
+			t.Fatalf("frame %d: %v", f.Seq, err)
+		}
+		got, _ := View[agentState](store, 1)
+		if want := map[bool]int{true: 2, false: 1}[f.Seq == 3]; got.Step != want {
+			t.Errorf("after frame %d (repeat=%v): step %d, want %d", f.Seq, f.Repeat, got.Step, want)
+		}
+	}
+	if !frames[1].Repeat || frames[3].Repeat || !frames[4].Repeat {
+		t.Errorf("repeats: %v %v %v", frames[1].Repeat, frames[3].Repeat, frames[4].Repeat)
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	schema := linkSchema(t)
+	type link struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	}
+
+	frame := &Frame{SID: 1, Kind: KindDoc, Payload: []byte(`Link{url="https://x" title="X"}`)}
+	got, err := DecodeInto[link](frame, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (link{URL: "https://x", Title: "X"}) {
+		t.Errorf("DecodeInto = %+v", got)
+	}
+
+	frame.Payload = []byte(`Link{url=""}`)
+	var perr *PayloadError
+	if _, err := DecodeInto[link](frame, schema); !errors.As(err, &perr) || perr.Schema != schema.Hash {
+		t.Errorf("invalid payload: %v", err)
+	}
+
+	// Without a schema, times and bytes land in their Go types.
+	type event struct {
+		At   time.Time `json:"at"`
+		Blob []byte    `json:"blob"`
+	}
+	row := &Frame{SID: 1, Kind: KindRow, Payload: []byte(`{at=2025-01-02T03:04:05Z blob=b64"aGk="}`)}
+	ev, err := DecodeInto[event](row, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ev.At.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)) || string(ev.Blob) != "hi" {
+		t.Errorf("event = %+v", ev)
+	}
+
+	if _, err := DecodeInto[link](&Frame{Kind: KindPatch}, nil); err == nil {
+		t.Error("patch frame decoded")
+	}
+}
@@ -3,10 +3,13 @@ package stream
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // MaxHeaderSize is the maximum number of bytes read for a single header line.
@@ -15,12 +18,29 @@ import (
 const MaxHeaderSize = 64 * 1024 // 64 KiB
 
 // Reader reads GS1-T (text) frames from an io.Reader.
+//
+// It remembers which SIDs have ended with a final frame (see Done) and
+// rejects any later frame on them with a *StreamClosedError, except a
+// resent final frame, which it skips as FrameHandler does.
 type Reader struct {
 	r          *bufio.Reader
 	maxPayload int
 	verifyCRC  bool
+
+	final  map[uint64]uint64 // Seq of the final frame, per ended SID
+	now    func() time.Time  // Stamps Frame.Received
+	clocks map[uint64]*clockEstimate
+	mw     Middleware // Applied to each frame read (nil: none)
+
+	maxFrames int   // ReadAll limit (0: none)
+	maxBytes  int64 // ReadAll limit (0: none)
+	consumed  int64 // Bytes read off the wire so far
 }
 
+// ErrReadLimit is wrapped by the error ReadAll returns once the input goes
+// past the limits set with WithMaxFrames or WithMaxBytes.
+var ErrReadLimit = errors.New("gs1: read limit exceeded")
+
 // ReaderOption configures a Reader.
 type ReaderOption func(*Reader)
 
@@ -38,12 +58,40 @@ func WithCRCVerification() ReaderOption {
 	}
 }
 
+// WithClock sets the clock that stamps Frame.Received (default: time.Now).
+func WithClock(now func() time.Time) ReaderOption {
+	return func(r *Reader) {
+		r.now = now
+	}
+}
+
+// WithMaxFrames makes ReadAll fail once it has read more than max frames
+// (default: no limit).
+func WithMaxFrames(max int) ReaderOption {
+	return func(r *Reader) {
+		r.maxFrames = max
+	}
+}
+
+// WithMaxBytes makes ReadAll fail once it has read more than max bytes of
+// input, headers included (default: no limit). A frame is read in full
+// before it counts, so ReadAll may go past max by up to one frame, itself
+// bounded by WithMaxPayload.
+func WithMaxBytes(max int64) ReaderOption {
+	return func(r *Reader) {
+		r.maxBytes = max
+	}
+}
+
 // NewReader creates a new GS1-T frame reader.
 func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
 	reader := &Reader{
 		r:          bufio.NewReaderSize(r, MaxHeaderSize),
 		maxPayload: MaxPayloadSize,
 		verifyCRC:  true, // verify by default
+		final:      make(map[uint64]uint64),
+		now:        time.Now,
+		clocks:     make(map[uint64]*clockEstimate),
 	}
 	for _, opt := range opts {
 		opt(reader)
@@ -52,8 +100,29 @@ func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
 }
 
 // Next reads and returns the next frame.
-// Returns io.EOF when no more frames are available.
+// Returns io.EOF when no more frames are available. A frame on a SID that
+// has ended is consumed and rejected with a *StreamClosedError; the next
+// call reads the following frame. A frame with the seq of the SID's final
+// frame is a resend of it, and is skipped. Frames pass through the reader's
+// middleware last, after these checks; a frame it drops is skipped.
 func (r *Reader) Next() (*Frame, error) {
+	for {
+		frame, err := r.read()
+		if frame == nil && err == nil {
+			continue // A resent final frame
+		}
+		if err != nil || r.mw == nil {
+			return frame, err
+		}
+		if frame, err = r.mw(frame); frame != nil || err != nil {
+			return frame, err
+		}
+	}
+}
+
+// read reads the next frame off the wire and checks it. It returns neither
+// a frame nor an error for a resent final frame.
+func (r *Reader) read() (*Frame, error) {
 	// Read header line, bounded to MaxHeaderSize to prevent DoS via a line
 	// with no newline (bufio.ReadString would otherwise grow unboundedly).
 	line, isPrefix, err := r.r.ReadLine()
@@ -71,6 +140,8 @@ func (r *Reader) Next() (*Frame, error) {
 		return nil, &ParseError{Reason: fmt.Sprintf("header line exceeds maximum size (%d bytes)", MaxHeaderSize), Offset: -1}
 	}
 	headerLine := string(line) + "\n"
+	r.consumed += int64(len(headerLine))
+	received := r.now()
 
 	// Parse header
 	frame, err := r.parseHeader(headerLine)
@@ -89,6 +160,7 @@ func (r *Reader) Next() (*Frame, error) {
 		if _, err := io.ReadFull(r.r, frame.Payload); err != nil {
 			return nil, fmt.Errorf("read payload: %w", err)
 		}
+		r.consumed += int64(payloadLen)
 	} else {
 		frame.Payload = nil
 	}
@@ -98,6 +170,8 @@ func (r *Reader) Next() (*Frame, error) {
 		if b != '\n' {
 			// Put it back - it's part of the next frame
 			r.r.UnreadByte()
+		} else {
+			r.consumed++
 		}
 	}
 
@@ -109,9 +183,37 @@ func (r *Reader) Next() (*Frame, error) {
 		}
 	}
 
+	if finalSeq, ok := r.final[frame.SID]; ok {
+		if frame.Seq == finalSeq {
+			return nil, nil
+		}
+		return nil, &StreamClosedError{SID: frame.SID, FinalSeq: finalSeq, Seq: frame.Seq}
+	}
+	frame.Received = received
+	if !frame.Time.IsZero() {
+		r.clock(frame.SID, frame.Time.Sub(received))
+	}
+	if frame.IsFinal() {
+		r.final[frame.SID] = frame.Seq
+	}
+
 	return frame, nil
 }
 
+// Done reports whether sid has ended with a final frame.
+func (r *Reader) Done(sid uint64) bool {
+	_, ok := r.final[sid]
+	return ok
+}
+
+// Forget drops what the reader knows about sid, so that a new stream may
+// reuse it. Long-lived readers call it for finished SIDs they will not see
+// again, to bound memory.
+func (r *Reader) Forget(sid uint64) {
+	delete(r.final, sid)
+	delete(r.clocks, sid)
+}
+
 // parseHeader parses the @frame{...} header line.
 func (r *Reader) parseHeader(line string) (*Frame, error) {
 	line = strings.TrimSpace(line)
@@ -195,9 +297,25 @@ func (r *Reader) parseHeader(line string) (*Frame, error) {
 			}
 			frame.Base = &base
 
+		case "schema":
+			if !isSchemaHash(val) {
+				return nil, &ParseError{Reason: "invalid schema: " + val, Offset: -1}
+			}
+			frame.Schema = val
+
+		case "ts":
+			ms, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return nil, &ParseError{Reason: "invalid ts: " + val, Offset: -1}
+			}
+			frame.Time = time.UnixMilli(ms).UTC()
+
 		case "final":
 			frame.Final = val == "true" || val == "1"
 
+		case "repeat":
+			frame.Repeat = val == "true" || val == "1"
+
 		case "flags":
 			flags, err := strconv.ParseUint(val, 16, 8)
 			if err == nil {
@@ -206,6 +324,10 @@ func (r *Reader) parseHeader(line string) (*Frame, error) {
 		}
 	}
 
+	if frame.Repeat && payloadLen > 0 {
+		return nil, &ParseError{Reason: "repeat frame with a payload", Offset: -1}
+	}
+
 	// Use payloadLen as temporary storage (we need it for reading)
 	frame.Payload = make([]byte, payloadLen)
 
@@ -262,10 +384,37 @@ func parseBase(val string) ([32]byte, bool) {
 	return HexToHash(val)
 }
 
-// ReadAll reads all frames until EOF.
+// isSchemaHash reports whether val is a non-empty run of hex digits, the
+// form of glyph.Schema.Hash.
+func isSchemaHash(val string) bool {
+	if val == "" {
+		return false
+	}
+	for i := 0; i < len(val); i++ {
+		if hexDigit(val[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadAll reads all frames until EOF. On an error it returns the frames
+// read before it.
 func (r *Reader) ReadAll() ([]*Frame, error) {
+	return r.ReadAllContext(context.Background())
+}
+
+// ReadAllContext is ReadAll that gives up with ctx.Err() once ctx is done.
+// ctx is checked between frames; a read blocked on the underlying
+// io.Reader ends only when that reader returns. It enforces the limits set
+// with WithMaxFrames and WithMaxBytes, counting from the call.
+func (r *Reader) ReadAllContext(ctx context.Context) ([]*Frame, error) {
 	var frames []*Frame
+	start := r.consumed
 	for {
+		if err := ctx.Err(); err != nil {
+			return frames, err
+		}
 		frame, err := r.Next()
 		if err == io.EOF {
 			return frames, nil
@@ -273,6 +422,12 @@ func (r *Reader) ReadAll() ([]*Frame, error) {
 		if err != nil {
 			return frames, err
 		}
+		if r.maxFrames > 0 && len(frames) == r.maxFrames {
+			return frames, fmt.Errorf("%w: more than %d frames", ErrReadLimit, r.maxFrames)
+		}
+		if r.maxBytes > 0 && r.consumed-start > r.maxBytes {
+			return frames, fmt.Errorf("%w: input over %d bytes", ErrReadLimit, r.maxBytes)
+		}
 		frames = append(frames, frame)
 	}
 }
@@ -2,6 +2,8 @@ package stream
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -330,6 +332,36 @@ func TestReader_PayloadTooLarge(t *testing.T) {
 	}
 }
 
+func TestReader_ReadAllLimits(t *testing.T) {
+	input := `@frame{v=1 sid=1 seq=0 kind=doc len=5}
+hello
+@frame{v=1 sid=1 seq=1 kind=patch len=6}
+update
+@frame{v=1 sid=1 seq=2 kind=ack len=0}
+
+`
+	frames, err := NewReader(strings.NewReader(input), WithMaxFrames(2)).ReadAll()
+	if !errors.Is(err, ErrReadLimit) || len(frames) != 2 {
+		t.Errorf("frame limit: %d frames, %v", len(frames), err)
+	}
+
+	// The first two frames take 93 bytes.
+	frames, err = NewReader(strings.NewReader(input), WithMaxBytes(93)).ReadAll()
+	if !errors.Is(err, ErrReadLimit) || len(frames) != 2 {
+		t.Errorf("byte limit: %d frames, %v", len(frames), err)
+	}
+	frames, err = NewReader(strings.NewReader(input), WithMaxFrames(3), WithMaxBytes(int64(len(input)))).ReadAll()
+	if err != nil || len(frames) != 3 {
+		t.Errorf("within limits: %d frames, %v", len(frames), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewReader(strings.NewReader(input)).ReadAllContext(ctx); err != context.Canceled {
+		t.Errorf("canceled: %v", err)
+	}
+}
+
 // TestReader_VersionEnforcement verifies that GS1-T frames with v != 1
 // are rejected per §3.1 of docs/GS1_SPEC.md.
 func TestReader_VersionEnforcement(t *testing.T) {
@@ -375,6 +407,147 @@ func TestReader_HeaderTooLong(t *testing.T) {
 	}
 }
 
+func TestReader_FramesAfterFinal(t *testing.T) {
+	input := "@frame{v=1 sid=1 seq=0 kind=doc len=2}\n{}\n" +
+		"@frame{v=1 sid=1 seq=1 kind=ack len=0 final=true}\n\n" +
+		"@frame{v=1 sid=1 seq=2 kind=doc len=2}\n{}\n" +
+		"@frame{v=1 sid=2 seq=0 kind=doc len=2}\n{}\n"
+	r := NewReader(strings.NewReader(input))
+
+	if _, err := r.Next(); err != nil || r.Done(1) {
+		t.Fatalf("first frame: %v, done=%v", err, r.Done(1))
+	}
+	if f, err := r.Next(); err != nil || !f.IsFinal() || !r.Done(1) {
+		t.Fatalf("final frame: %v, done=%v", err, r.Done(1))
+	}
+	_, err := r.Next()
+	closed, ok := err.(*StreamClosedError)
+	if !ok || closed.SID != 1 || closed.FinalSeq != 1 || closed.Seq != 2 {
+		t.Errorf("frame after final: %v", err)
+	}
+	if f, err := r.Next(); err != nil || f.SID != 2 || r.Done(2) {
+		t.Errorf("next SID: %+v, %v", f, err)
+	}
+
+	r.Forget(1)
+	if r.Done(1) {
+		t.Error("Forget did not reset sid 1")
+	}
+}
+
+func TestResentFinalFrame(t *testing.T) {
+	final := "@frame{v=1 sid=1 seq=1 kind=ack len=0 final=true}\n\n"
+	input := "@frame{v=1 sid=1 seq=0 kind=doc len=2}\n{}\n" + final + final +
+		"@frame{v=1 sid=2 seq=0 kind=doc len=2}\n{}\n"
+
+	// The reader skips the resend.
+	frames, err := NewReader(strings.NewReader(input)).ReadAll()
+	if err != nil || len(frames) != 3 || frames[2].SID != 2 {
+		t.Fatalf("ReadAll = %d frames, %v", len(frames), err)
+	}
+
+	// The handler discards it.
+	resent, err := NewReader(strings.NewReader(final)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewFrameHandler()
+	for i, f := range append(frames[:2:2], resent) {
+		if err := h.Handle(f); err != nil {
+			t.Errorf("frame %d: %v", i, err)
+		}
+	}
+}
+
+func TestWriter_CloseSID(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteDoc(4, 0, []byte("{}"))
+	w.WriteUI(4, 1, EmitProgress(1, "done"))
+	if err := w.CloseSID(4); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Closed(4) || w.Closed(5) {
+		t.Error("Closed")
+	}
+	err := w.WriteDoc(4, 3, []byte("{}"))
+	if _, ok := err.(*StreamClosedError); !ok {
+		t.Errorf("write after close: %v", err)
+	}
+	if err := w.CloseSID(5); err != nil {
+		t.Errorf("close unused SID: %v", err)
+	}
+	w.Forget(5)
+	if w.Closed(5) || w.NextSeq(5) != 1 {
+		t.Error("Forget did not reset sid 5")
+	}
+
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil || len(frames) != 4 {
+		t.Fatalf("ReadAll = %d frames, %v", len(frames), err)
+	}
+	if f := frames[2]; f.SID != 4 || f.Seq != 2 || f.Kind != KindAck || !f.IsFinal() || len(f.Payload) != 0 {
+		t.Errorf("close frame = %+v", f)
+	}
+	if f := frames[3]; f.SID != 5 || f.Seq != 0 || !f.IsFinal() {
+		t.Errorf("close frame of unused SID = %+v", f)
+	}
+}
+
+func TestWriter_Dedup(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetDedup(KindDoc, KindUI, KindPatch)
+	state := &Frame{SID: 1, Seq: 0, Kind: KindDoc, Payload: []byte("{n=1}")}
+	w.WriteFrame(state)
+	state.Seq = 1
+	w.WriteFrame(state)
+	w.WriteUI(1, 2, []byte("tick"))
+	w.WriteUI(1, 3, []byte("tick"))
+	w.WriteDoc(1, 4, []byte("{n=1}"))
+	w.WritePatch(1, 5, []byte("p"), nil)
+	w.WritePatch(1, 6, []byte("p"), nil)
+	w.WriteDoc(1, 7, []byte("{n=1}"))
+	w.WriteDoc(2, 0, []byte("{n=1}"))
+	w.WriteFrame(&Frame{SID: 1, Seq: 8, Kind: KindDoc, Payload: []byte("{n=1}"), Final: true})
+	if len(state.Payload) == 0 || state.Repeat {
+		t.Errorf("WriteFrame changed its frame: %+v", state)
+	}
+	if !strings.Contains(buf.String(), "@frame{v=1 sid=1 seq=1 kind=doc len=0 repeat=true}\n\n") {
+		t.Errorf("no repeat header in:\n%s", buf.String())
+	}
+
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil || len(frames) != 10 {
+		t.Fatalf("ReadAll = %d frames, %v", len(frames), err)
+	}
+	want := []bool{false, true, false, true, true, false, false, false, false, false}
+	for i, f := range frames {
+		if f.Repeat != want[i] || f.Repeat != (len(f.Payload) == 0) {
+			t.Errorf("frame %d (sid %d seq %d): repeat=%v, payload %q", i, f.SID, f.Seq, f.Repeat, f.Payload)
+		}
+	}
+
+	w.SetDedup()
+	buf.Reset()
+	w.WriteDoc(3, 0, []byte("{}"))
+	w.WriteDoc(3, 1, []byte("{}"))
+	if strings.Contains(buf.String(), "repeat") {
+		t.Errorf("dedup not turned off:\n%s", buf.String())
+	}
+}
+
+func TestReader_Repeat(t *testing.T) {
+	r := NewReader(strings.NewReader("@frame{v=1 sid=1 seq=3 kind=doc len=0 repeat=true}\n\n"))
+	if f, err := r.Next(); err != nil || !f.Repeat || f.Payload != nil {
+		t.Errorf("repeat frame = %+v, %v", f, err)
+	}
+	r = NewReader(strings.NewReader("@frame{v=1 sid=1 seq=3 kind=doc len=2 repeat=true}\n{}\n"))
+	if _, err := r.Next(); err == nil {
+		t.Error("expected an error for a repeat frame with a payload")
+	}
+}
+
 // ============================================================
 // Round-trip Tests
 // ============================================================
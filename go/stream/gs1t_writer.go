@@ -1,16 +1,35 @@
 package stream
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Writer writes GS1-T (text) frames to an io.Writer.
+//
+// It remembers the next seq of each SID, for CloseSID, and refuses frames
+// on a SID it has written a final frame for.
 type Writer struct {
 	w       io.Writer
-	withCRC bool // Whether to compute and include CRC
+	withCRC bool             // Whether to compute and include CRC
+	now     func() time.Time // Stamps frames without a Time (nil: don't)
+	mw      Middleware       // Applied to each frame before writing (nil: none)
+
+	next   map[uint64]uint64 // Seq after the last frame written, per SID
+	closed map[uint64]uint64 // Seq of the final frame, per closed SID
+
+	dedup map[FrameKind]bool    // Kinds whose unchanged payloads are not resent
+	last  map[dedupKey][32]byte // Payload hash of the last frame sent, per SID and kind
+}
+
+// dedupKey identifies the frames a payload repeats: same SID, same kind.
+type dedupKey struct {
+	sid  uint64
+	kind FrameKind
 }
 
 // NewWriter creates a new GS1-T frame writer.
@@ -27,9 +46,24 @@ func NewWriterWithCRC(w io.Writer) *Writer {
 //
 // Format:
 //
-//	@frame{v=1 sid=N seq=N kind=K len=N [crc=X] [base=sha256:X] [final=true]}\n
+//	@frame{v=1 sid=N seq=N kind=K len=N [crc=X] [base=sha256:X] [schema=X] [ts=ms] [final=true] [repeat=true]}\n
 //	<payload bytes>\n
+//
+// With SetDedup, a frame that repeats the payload of the last frame of its
+// kind on the SID is written as a repeat frame: the header alone, with
+// repeat=true and len=0. f itself is not changed.
 func (w *Writer) WriteFrame(f *Frame) error {
+	if w.mw != nil {
+		var err error
+		if f, err = w.mw(f); err != nil || f == nil {
+			return err
+		}
+	}
+	if finalSeq, ok := w.closed[f.SID]; ok {
+		return &StreamClosedError{SID: f.SID, FinalSeq: finalSeq, Seq: f.Seq}
+	}
+	f, sum, dedup := w.dedupFrame(f)
+
 	var header strings.Builder
 	header.WriteString("@frame{")
 
@@ -70,11 +104,32 @@ func (w *Writer) WriteFrame(f *Frame) error {
 		header.WriteString(HashToHex(*f.Base))
 	}
 
+	// Optional schema hash
+	if f.Schema != "" {
+		header.WriteString(" schema=")
+		header.WriteString(f.Schema)
+	}
+
+	// Optional producer timestamp
+	ts := f.Time
+	if ts.IsZero() && w.now != nil {
+		ts = w.now()
+	}
+	if !ts.IsZero() {
+		header.WriteString(" ts=")
+		header.WriteString(strconv.FormatInt(ts.UnixMilli(), 10))
+	}
+
 	// Optional final flag
 	if f.Final || f.Flags&FlagFinal != 0 {
 		header.WriteString(" final=true")
 	}
 
+	// Optional repeat marker
+	if f.Repeat {
+		header.WriteString(" repeat=true")
+	}
+
 	header.WriteString("}\n")
 
 	// Write header
@@ -94,9 +149,70 @@ func (w *Writer) WriteFrame(f *Frame) error {
 		return fmt.Errorf("write trailing newline: %w", err)
 	}
 
+	if w.next == nil {
+		w.next = make(map[uint64]uint64)
+		w.closed = make(map[uint64]uint64)
+	}
+	w.next[f.SID] = f.Seq + 1
+	if f.IsFinal() {
+		w.closed[f.SID] = f.Seq
+		for kind := range w.dedup {
+			delete(w.last, dedupKey{f.SID, kind})
+		}
+	} else if dedup {
+		w.last[dedupKey{f.SID, f.Kind}] = sum
+	}
+	if f.Kind == KindPatch {
+		// The patch changes the state, so resending the last doc is news.
+		delete(w.last, dedupKey{f.SID, KindDoc})
+	}
 	return nil
 }
 
+// SetDedup makes the writer drop the payload of any frame of the given kinds
+// whose payload is byte for byte that of the last frame of the same kind on
+// its SID, as agents that re-emit unchanged state send over and over. Such a
+// frame still goes out, with its seq, base and timestamp, as a repeat frame
+// (Frame.Repeat), so the receiver keeps its sequence and knows the sender is
+// alive. Final frames are always sent whole, and so are patches: applying a
+// patch twice is not a no-op. Call with no kinds to stop.
+func (w *Writer) SetDedup(kinds ...FrameKind) {
+	w.dedup, w.last = nil, nil
+	for _, kind := range kinds {
+		if kind == KindPatch {
+			continue
+		}
+		if w.dedup == nil {
+			w.dedup = make(map[FrameKind]bool)
+			w.last = make(map[dedupKey][32]byte)
+		}
+		w.dedup[kind] = true
+	}
+}
+
+// dedupFrame returns the frame to write for f: f, or a repeat frame in its
+// place. It also returns the payload hash of f, and whether the hash should
+// be recorded once f is written.
+func (w *Writer) dedupFrame(f *Frame) (*Frame, [32]byte, bool) {
+	if !w.dedup[f.Kind] || f.Repeat || f.IsFinal() || len(f.Payload) == 0 {
+		return f, [32]byte{}, false
+	}
+	sum := sha256.Sum256(f.Payload)
+	if prev, ok := w.last[dedupKey{f.SID, f.Kind}]; !ok || prev != sum {
+		return f, sum, true
+	}
+	repeat := *f
+	repeat.Payload, repeat.CRC, repeat.Repeat = nil, nil, true
+	return &repeat, sum, false
+}
+
+// SetClock makes the writer send now() as the timestamp of every frame
+// whose Time is zero, so receivers can estimate latency and order events
+// from several producers. Pass time.Now, or nil to stop.
+func (w *Writer) SetClock(now func() time.Time) {
+	w.now = now
+}
+
 // WriteDoc writes a doc frame with the given payload.
 func (w *Writer) WriteDoc(sid, seq uint64, payload []byte) error {
 	return w.WriteFrame(&Frame{
@@ -164,6 +280,11 @@ func (w *Writer) WriteErr(sid, seq uint64, payload []byte) error {
 	})
 }
 
+// WriteErrorEvent writes an error frame carrying e.
+func (w *Writer) WriteErrorEvent(sid, seq uint64, e *ErrorEvent) error {
+	return w.WriteErr(sid, seq, EmitErrorEvent(e))
+}
+
 // WritePing writes a ping frame.
 func (w *Writer) WritePing(sid, seq uint64) error {
 	return w.WriteFrame(&Frame{
@@ -186,6 +307,44 @@ func (w *Writer) WritePong(sid, seq uint64) error {
 	})
 }
 
+// CloseSID ends a stream with a final ack frame without payload, numbered
+// after the last frame written on sid. Later frames on sid are refused.
+func (w *Writer) CloseSID(sid uint64) error {
+	return w.WriteFrame(&Frame{
+		Version: Version,
+		SID:     sid,
+		Seq:     w.next[sid],
+		Kind:    KindAck,
+		Final:   true,
+	})
+}
+
+// NextSeq returns the seq after the last frame written on sid, or 1 if none
+// has been.
+func (w *Writer) NextSeq(sid uint64) uint64 {
+	if next, ok := w.next[sid]; ok {
+		return next
+	}
+	return 1
+}
+
+// Closed reports whether a final frame has been written on sid.
+func (w *Writer) Closed(sid uint64) bool {
+	_, ok := w.closed[sid]
+	return ok
+}
+
+// Forget drops what the writer knows about sid, so that a new stream may
+// reuse it. Long-lived writers call it for SIDs they have closed, to bound
+// memory.
+func (w *Writer) Forget(sid uint64) {
+	delete(w.next, sid)
+	delete(w.closed, sid)
+	for kind := range w.dedup {
+		delete(w.last, dedupKey{sid, kind})
+	}
+}
+
 // WriteFinal writes a final frame for a stream.
 func (w *Writer) WriteFinal(sid, seq uint64, kind FrameKind, payload []byte) error {
 	return w.WriteFrame(&Frame{
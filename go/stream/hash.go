@@ -18,6 +18,20 @@ func StateHashLoose(value *glyph.GValue) [32]byte {
 	return sha256.Sum256([]byte(canonical))
 }
 
+// StateHashTyped computes the state hash over the schema-normalized form.
+// This is: sha256(CanonicalizeTyped(decoded value, schema, rootType))
+//
+// Use this when producers share a schema but may differ in field order,
+// name vs wire key spelling, omitted defaults, null optionals, or unknown
+// fields; none of those change the hash.
+func StateHashTyped(value *glyph.GValue, schema *glyph.Schema, rootType string) ([32]byte, error) {
+	canonical, err := glyph.CanonicalizeTyped(value, schema, rootType)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256([]byte(canonical)), nil
+}
+
 // StateHashEmit computes the state hash using Emit (default emit).
 // This is: sha256(Emit(decoded value))
 //
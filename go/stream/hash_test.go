@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+func TestStateHashTyped(t *testing.T) {
+	schema := glyph.NewSchemaBuilder().
+		AddStruct("Score", "v1",
+			glyph.Field("home", glyph.PrimitiveType("int"), glyph.WithWireKey("h")),
+			glyph.Field("away", glyph.PrimitiveType("int"), glyph.WithDefault(glyph.Int(0))),
+			glyph.Field("note", glyph.PrimitiveType("str"), glyph.WithOptional()),
+		).
+		Build()
+
+	a := glyph.Struct("Score", glyph.FieldVal("home", glyph.Int(2)), glyph.FieldVal("away", glyph.Int(0)))
+	b := glyph.Struct("Score",
+		glyph.FieldVal("note", glyph.Null()),
+		glyph.FieldVal("h", glyph.Int(2)),
+		glyph.FieldVal("trace", glyph.Str("producer-b")),
+	)
+
+	ha, err := StateHashTyped(a, schema, "Score")
+	if err != nil {
+		t.Fatalf("StateHashTyped(a) error: %v", err)
+	}
+	hb, err := StateHashTyped(b, schema, "Score")
+	if err != nil {
+		t.Fatalf("StateHashTyped(b) error: %v", err)
+	}
+	if ha != hb {
+		t.Error("typed hashes should match across producer noise")
+	}
+	if StateHashLoose(a) == StateHashLoose(b) {
+		t.Error("loose hashes should differ for this fixture")
+	}
+
+	c := glyph.Struct("Score", glyph.FieldVal("home", glyph.Int(3)))
+	if hc, _ := StateHashTyped(c, schema, "Score"); hc == ha {
+		t.Error("different content must hash differently")
+	}
+	if _, err := StateHashTyped(a, nil, "Score"); err == nil {
+		t.Error("expected error without schema")
+	}
+}
@@ -0,0 +1,135 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ============================================================
+// Merging Producers
+// ============================================================
+//
+// An orchestrator running several agents reads one GS1 stream from each
+// and forwards a single feed. Merge interleaves the frames of several
+// Readers into one stream. The SIDs of different sources may collide, so
+// each (source, SID) pair gets a SID of its own in the merged stream,
+// numbered from 1 in the order first emitted; 0 stays free for the
+// orchestrator's own frames. Origin maps a merged SID back.
+//
+// Interleaving is deterministic:
+//
+//  1. Frames of one source keep their order.
+//  2. Of the next frames of all sources, the one sent earliest goes first:
+//     its Time on the local clock (Reader.LocalTime, using the source's
+//     skew estimate). A frame without Time counts as sent with the frame
+//     before it from the same source, or at the zero time if it is first.
+//  3. Ties go to the source listed first.
+//
+// Merge waits for a frame, or EOF, from every source before choosing, so
+// one silent source holds up the feed.
+
+// SourceError is returned by Merger.Next when a source fails.
+type SourceError struct {
+	Source int // Index of the source in the Merge arguments
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("gs1: merge source %d: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Merger reads the merged stream of several sources. Not safe for
+// concurrent use.
+type Merger struct {
+	sources []*mergeSource
+	sids    map[sourceSID]uint64
+	origins map[uint64]sourceSID
+}
+
+type mergeSource struct {
+	r    *Reader
+	head *Frame    // Next frame, read but not yet returned
+	key  time.Time // When head was sent, for ordering
+	done bool
+}
+
+type sourceSID struct {
+	source int
+	sid    uint64
+}
+
+// Merge returns a Merger reading the frames of readers in one stream.
+func Merge(readers ...*Reader) *Merger {
+	m := &Merger{
+		sids:    make(map[sourceSID]uint64),
+		origins: make(map[uint64]sourceSID),
+	}
+	for _, r := range readers {
+		m.sources = append(m.sources, &mergeSource{r: r})
+	}
+	return m
+}
+
+// Next returns the next frame of the merged stream, with its SID remapped.
+// It returns io.EOF once every source has. An error from a source other
+// than io.EOF is returned as a *SourceError, and that source's following
+// frame is read on the next call; a caller that cannot recover from the
+// error should stop.
+func (m *Merger) Next() (*Frame, error) {
+	for i, s := range m.sources {
+		if s.head != nil || s.done {
+			continue
+		}
+		f, err := s.r.Next()
+		if err == io.EOF {
+			s.done = true
+			continue
+		}
+		if err != nil {
+			return nil, &SourceError{Source: i, Err: err}
+		}
+		s.head = f
+		if !f.Time.IsZero() {
+			s.key = s.r.LocalTime(f)
+		}
+	}
+
+	var next *mergeSource
+	source := -1
+	for i, s := range m.sources {
+		if s.head != nil && (next == nil || s.key.Before(next.key)) {
+			next, source = s, i
+		}
+	}
+	if next == nil {
+		return nil, io.EOF
+	}
+
+	f := next.head
+	next.head = nil
+	f.SID = m.remap(source, f.SID)
+	return f, nil
+}
+
+// remap returns the merged SID of sid from source, assigning one if new.
+func (m *Merger) remap(source int, sid uint64) uint64 {
+	key := sourceSID{source, sid}
+	merged, ok := m.sids[key]
+	if !ok {
+		merged = uint64(len(m.sids) + 1)
+		m.sids[key] = merged
+		m.origins[merged] = key
+	}
+	return merged
+}
+
+// Origin returns the source index and original SID of a merged SID.
+func (m *Merger) Origin(sid uint64) (source int, originalSID uint64, ok bool) {
+	key, ok := m.origins[sid]
+	return key.source, key.sid, ok
+}
@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mergeSourceFor writes frames {sid, ms, payload} sent at base+ms (no ts if
+// ms < 0) and returns a Reader that receives each transit after it was sent.
+func mergeSourceFor(t *testing.T, base time.Time, transit time.Duration, frames []struct {
+	sid     uint64
+	ms      int
+	payload string
+}) *Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	var arrivals []time.Time
+	last := base
+	for seq, f := range frames {
+		frame := &Frame{SID: f.sid, Seq: uint64(seq), Kind: KindUI, Payload: []byte(f.payload)}
+		if f.ms >= 0 {
+			last = base.Add(time.Duration(f.ms) * time.Millisecond)
+			frame.Time = last
+		}
+		if err := w.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+		arrivals = append(arrivals, last.Add(transit))
+	}
+	return NewReader(&buf, WithClock(func() time.Time {
+		at := arrivals[0]
+		arrivals = arrivals[1:]
+		return at
+	}))
+}
+
+func TestMerge_Interleaving(t *testing.T) {
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	type f = struct {
+		sid     uint64
+		ms      int
+		payload string
+	}
+	a := mergeSourceFor(t, base, 5*time.Millisecond, []f{{1, 0, "a0"}, {1, 20, "a20"}, {1, -1, "a-untimed"}, {1, 40, "a40"}})
+	b := mergeSourceFor(t, base, 7*time.Millisecond, []f{{1, 10, "b10"}, {1, 18, "b18"}, {2, 30, "b30"}})
+
+	m := Merge(a, b)
+	var got []string
+	var sids []uint64
+	for {
+		frame, err := m.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(frame.Payload))
+		sids = append(sids, frame.SID)
+	}
+
+	// Keys on the local clock: a at ms+5, b at ms+7; ties go to a.
+	want := "a0 b10 a20 a-untimed b18 b30 a40"
+	if strings.Join(got, " ") != want {
+		t.Errorf("order = %v, want %s", got, want)
+	}
+	wantSIDs := []uint64{1, 2, 1, 1, 2, 3, 1}
+	for i := range wantSIDs {
+		if sids[i] != wantSIDs[i] {
+			t.Fatalf("merged SIDs = %v, want %v", sids, wantSIDs)
+		}
+	}
+	if src, sid, ok := m.Origin(3); !ok || src != 1 || sid != 2 {
+		t.Errorf("Origin(3) = %d, %d, %v", src, sid, ok)
+	}
+	if _, _, ok := m.Origin(4); ok {
+		t.Error("Origin of unassigned SID")
+	}
+}
+
+func TestMerge_SourceError(t *testing.T) {
+	bad := NewReader(strings.NewReader(
+		"@frame{v=1 sid=1 seq=0 kind=doc len=0 final=true}\n\n" +
+			"@frame{v=1 sid=1 seq=1 kind=doc len=0}\n\n" +
+			"@frame{v=1 sid=2 seq=0 kind=doc len=0}\n\n"))
+	m := Merge(NewReader(strings.NewReader("")), bad)
+
+	if f, err := m.Next(); err != nil || f.SID != 1 {
+		t.Fatalf("first frame: %+v, %v", f, err)
+	}
+	_, err := m.Next()
+	var se *SourceError
+	var closed *StreamClosedError
+	if !errors.As(err, &se) || se.Source != 1 || !errors.As(err, &closed) {
+		t.Fatalf("frame after final: %v", err)
+	}
+	if f, err := m.Next(); err != nil || f.SID != 2 {
+		t.Errorf("frame after error: %+v, %v", f, err)
+	}
+	if _, err := m.Next(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
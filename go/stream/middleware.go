@@ -0,0 +1,67 @@
+package stream
+
+// ============================================================
+// Middleware
+// ============================================================
+//
+// Redaction, compression, signing, and metrics apply to every frame of a
+// stream whatever produces it. A Middleware is one such step; Writer.Use
+// and WithMiddleware install a chain of them on a Writer or Reader:
+//
+//	w.Use(redactSecrets, countFrames)
+//	r := stream.NewReader(conn, stream.WithMiddleware(countFrames))
+//
+// A Writer runs its chain before writing a frame, so the CRC it computes
+// covers the transformed payload. A Reader runs its chain on each frame
+// after reading and checking it (CRC, end of stream), so a chain there
+// sees what was on the wire.
+
+// Middleware transforms a frame on its way through a Writer or Reader. It
+// returns the frame to pass on, nil to drop the frame, or an error to fail
+// the write or read. It must not modify the frame it is given, which may
+// belong to the caller; it returns a modified copy instead. A middleware
+// that changes the payload of a frame carrying a CRC must recompute or
+// clear it.
+type Middleware func(*Frame) (*Frame, error)
+
+// Chain composes middlewares into one that applies them in order. It stops
+// at the first that drops the frame or fails.
+func Chain(mws ...Middleware) Middleware {
+	return func(f *Frame) (*Frame, error) {
+		for _, mw := range mws {
+			var err error
+			if f, err = mw(f); err != nil || f == nil {
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+}
+
+// Observe returns a middleware that calls fn with each frame and passes it
+// on unchanged, for metrics and logging.
+func Observe(fn func(*Frame)) Middleware {
+	return func(f *Frame) (*Frame, error) {
+		fn(f)
+		return f, nil
+	}
+}
+
+// Use appends mws to the writer's middleware chain.
+func (w *Writer) Use(mws ...Middleware) {
+	w.mw = appendMiddleware(w.mw, mws)
+}
+
+// WithMiddleware appends mws to the reader's middleware chain.
+func WithMiddleware(mws ...Middleware) ReaderOption {
+	return func(r *Reader) {
+		r.mw = appendMiddleware(r.mw, mws)
+	}
+}
+
+func appendMiddleware(chain Middleware, mws []Middleware) Middleware {
+	if chain != nil {
+		mws = append([]Middleware{chain}, mws...)
+	}
+	return Chain(mws...)
+}
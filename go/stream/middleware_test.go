@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func redact(f *Frame) (*Frame, error) {
+	if !bytes.Contains(f.Payload, []byte("hunter2")) {
+		return f, nil
+	}
+	c := *f
+	c.Payload = bytes.ReplaceAll(f.Payload, []byte("hunter2"), []byte("*******"))
+	return &c, nil
+}
+
+func dropKind(kind FrameKind) Middleware {
+	return func(f *Frame) (*Frame, error) {
+		if f.Kind == kind {
+			return nil, nil
+		}
+		return f, nil
+	}
+}
+
+func TestMiddleware_Writer(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterWithCRC(&buf)
+	written := 0
+	w.Use(redact, dropKind(KindPing))
+	w.Use(Observe(func(*Frame) { written++ }))
+
+	doc := &Frame{SID: 1, Seq: 0, Kind: KindDoc, Payload: []byte(`{password=hunter2}`)}
+	if err := w.WriteFrame(doc); err != nil {
+		t.Fatal(err)
+	}
+	w.WritePing(1, 1)
+	w.WriteUI(1, 2, EmitLog("info", "ok"))
+	if string(doc.Payload) != `{password=hunter2}` {
+		t.Error("middleware modified the caller's frame")
+	}
+	if written != 2 {
+		t.Errorf("observed %d frames, want 2", written)
+	}
+
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("ReadAll = %d frames, %v", len(frames), err)
+	}
+	if string(frames[0].Payload) != `{password=*******}` {
+		t.Errorf("payload = %s", frames[0].Payload)
+	}
+}
+
+func TestMiddleware_Reader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteDoc(1, 0, []byte("{a=1}"))
+	w.WriteUI(1, 1, EmitProgress(0.5, "half"))
+	w.WriteDoc(1, 2, []byte("{a=hunter2}"))
+	w.WriteErr(1, 3, EmitError(ErrCodeToolFailed, "boom", 1, 2))
+
+	errBoom := errors.New("boom")
+	failOnErr := func(f *Frame) (*Frame, error) {
+		if f.Kind == KindErr {
+			return nil, errBoom
+		}
+		return f, nil
+	}
+	r := NewReader(&buf, WithMiddleware(dropKind(KindUI)), WithMiddleware(redact, failOnErr))
+
+	var docs []string
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, errBoom) {
+				t.Fatal(err)
+			}
+			continue
+		}
+		docs = append(docs, string(f.Payload))
+	}
+	if len(docs) != 2 || docs[1] != "{a=*******}" {
+		t.Errorf("docs = %q", docs)
+	}
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"errors"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// ============================================================
+// Paginated Tables
+// ============================================================
+//
+// A tool server returns a large table in bounded chunks by sending the
+// pages from glyph.PaginateTabular as consecutive doc frames on one SID.
+// The receiver feeds frames to a TableCollector, which hands back the
+// whole table once its last page arrives.
+
+// WriteTablePages writes each page as a doc frame on sid, starting at seq.
+// It returns the seq after the last frame written.
+func (w *Writer) WriteTablePages(sid, seq uint64, pages []string) (uint64, error) {
+	for _, page := range pages {
+		if err := w.WriteDoc(sid, seq, []byte(page)); err != nil {
+			return seq, err
+		}
+		seq++
+	}
+	return seq, nil
+}
+
+// TableCollector reassembles paginated tables from doc frames, one table
+// in flight per SID.
+type TableCollector struct {
+	KeyDict []string // Passed to each glyph.TableAssembler
+
+	tables map[uint64]*glyph.TableAssembler
+}
+
+// NewTableCollector creates an empty collector.
+func NewTableCollector() *TableCollector {
+	return &TableCollector{tables: make(map[uint64]*glyph.TableAssembler)}
+}
+
+// Add feeds a frame to the collector. It returns the reassembled table when
+// frame carries the last page of one, and nil otherwise. Frames that are
+// not doc frames holding a table page, repeat frames among them, are
+// ignored. If a page is out of order or does not match the table in
+// progress, Add returns the error and drops that SID's partial table.
+func (c *TableCollector) Add(frame *Frame) (*glyph.GValue, error) {
+	if frame.Kind != KindDoc || frame.Repeat {
+		return nil, nil
+	}
+	a := c.tables[frame.SID]
+	if a == nil {
+		a = &glyph.TableAssembler{KeyDict: c.KeyDict}
+	}
+	if err := a.Add(string(frame.Payload)); err != nil {
+		if errors.Is(err, glyph.ErrNotTablePage) {
+			return nil, nil
+		}
+		delete(c.tables, frame.SID)
+		return nil, err
+	}
+	if !a.Done() {
+		c.tables[frame.SID] = a
+		return nil, nil
+	}
+	delete(c.tables, frame.SID)
+	return a.Value()
+}
+
+// Cont returns the cont= token of the last page received on sid, for
+// requesting the rest of a table whose stream ended early. It is "" when
+// no table is in progress on sid.
+func (c *TableCollector) Cont(sid uint64) string {
+	if a := c.tables[sid]; a != nil {
+		return a.Cont()
+	}
+	return ""
+}
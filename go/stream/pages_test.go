@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+func TestTablePages_RoundTrip(t *testing.T) {
+	var rows []*glyph.GValue
+	for i := 0; i < 10; i++ {
+		rows = append(rows, glyph.Map(
+			glyph.MapEntry{Key: "id", Value: glyph.Int(int64(i))},
+			glyph.MapEntry{Key: "ok", Value: glyph.Bool(i%2 == 0)},
+		))
+	}
+	table := glyph.List(rows...)
+	pages, err := glyph.PaginateTabular(table, 4, glyph.DefaultLooseCanonOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteUI(7, 1, EmitProgress(0.5, "querying"))
+	next, err := w.WriteTablePages(7, 2, pages)
+	if err != nil || next != 5 {
+		t.Fatalf("WriteTablePages = %d, %v", next, err)
+	}
+	w.WriteDoc(7, next, []byte("{done=t}"))
+
+	frames, err := NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewTableCollector()
+	var got []*glyph.GValue
+	for _, f := range frames {
+		v, err := c.Add(f)
+		if err != nil {
+			t.Fatalf("seq %d: %v", f.Seq, err)
+		}
+		if v == nil && f.Seq == 2 && c.Cont(7) == "" {
+			t.Error("expected a cont token after the first page")
+		}
+		if v != nil {
+			got = append(got, v)
+		}
+	}
+	if len(got) != 1 || !glyph.EqualLoose(got[0], table) {
+		t.Fatalf("collected %d tables", len(got))
+	}
+	if c.Cont(7) != "" {
+		t.Errorf("Cont after completion = %q", c.Cont(7))
+	}
+}
+
+func TestTableCollector_OutOfOrder(t *testing.T) {
+	var rows []*glyph.GValue
+	for i := 0; i < 6; i++ {
+		rows = append(rows, glyph.Map(glyph.MapEntry{Key: "id", Value: glyph.Int(int64(i))}))
+	}
+	pages, err := glyph.PaginateTabular(glyph.List(rows...), 2, glyph.DefaultLooseCanonOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewTableCollector()
+	c.Add(&Frame{SID: 1, Seq: 1, Kind: KindDoc, Payload: []byte(pages[0])})
+	if _, err := c.Add(&Frame{SID: 1, Seq: 2, Kind: KindDoc, Payload: []byte(pages[2])}); err == nil {
+		t.Fatal("expected error for skipped page")
+	}
+	if c.Cont(1) != "" {
+		t.Error("partial table should be dropped after an error")
+	}
+}
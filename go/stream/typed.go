@@ -0,0 +1,210 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+// ============================================================
+// Schema-Bound Frames
+// ============================================================
+//
+// A frame may name the schema its payload is typed by with schema=<hash>
+// in its header, where hash is the glyph.Schema's Hash. A TypedReader
+// resolves the hash and hands back doc and row payloads already parsed as
+// GLYPH-T under that schema and validated, instead of payload bytes:
+//
+//	@frame{v=1 sid=1 seq=0 kind=doc len=21 schema=5d41402abc4b2a76b9719d911017c592}
+//	Link{url="https://x"}
+
+// ErrUnknownSchema is wrapped by the error a SchemaResolver returns for a
+// hash it does not know.
+var ErrUnknownSchema = errors.New("gs1: unknown schema")
+
+// SchemaResolver finds the schema a frame's schema hash names.
+type SchemaResolver interface {
+	ResolveSchema(hash string) (*glyph.Schema, error)
+}
+
+// SchemaSet is a SchemaResolver over a fixed set of schemas, keyed by hash.
+// Safe for concurrent use.
+type SchemaSet struct {
+	mu      sync.RWMutex
+	schemas map[string]*glyph.Schema
+}
+
+// NewSchemaSet creates a set holding schemas.
+func NewSchemaSet(schemas ...*glyph.Schema) *SchemaSet {
+	s := &SchemaSet{schemas: make(map[string]*glyph.Schema)}
+	for _, schema := range schemas {
+		s.Add(schema)
+	}
+	return s
+}
+
+// Add adds schema to the set and returns its hash, computing it if unset.
+func (s *SchemaSet) Add(schema *glyph.Schema) string {
+	hash := schema.Hash
+	if hash == "" {
+		hash = schema.ComputeHash()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemas[hash] = schema
+	return hash
+}
+
+// ResolveSchema returns the schema with the given hash. The error wraps
+// ErrUnknownSchema if there is none.
+func (s *SchemaSet) ResolveSchema(hash string) (*glyph.Schema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if schema, ok := s.schemas[hash]; ok {
+		return schema, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnknownSchema, hash)
+}
+
+// WriteTyped writes v as a frame of the given kind, emitted as GLYPH-T and
+// bound to schema by its hash.
+func (w *Writer) WriteTyped(sid, seq uint64, kind FrameKind, v *glyph.GValue, schema *glyph.Schema) error {
+	hash := schema.Hash
+	if hash == "" {
+		hash = schema.ComputeHash()
+	}
+	return w.WriteFrame(&Frame{
+		Version: Version,
+		SID:     sid,
+		Seq:     seq,
+		Kind:    kind,
+		Payload: []byte(glyph.Emit(v)),
+		Schema:  hash,
+	})
+}
+
+// TypedFrame is a frame read by a TypedReader.
+type TypedFrame struct {
+	Frame    *Frame                  // The frame as read, payload bytes included
+	Schema   *glyph.Schema           // Schema named by the header; nil if none
+	Value    *glyph.GValue           // Parsed payload of a doc or row frame with a schema
+	Warnings []glyph.ValidationError // Validation warnings, e.g. deprecated fields
+}
+
+// PayloadError reports a frame whose payload does not parse or validate
+// under the schema its header names, or does not parse at all.
+type PayloadError struct {
+	SID    uint64
+	Seq    uint64
+	Schema string   // Hash from the frame header
+	Errors []string // Parse or validation errors
+}
+
+func (e *PayloadError) Error() string {
+	if e.Schema == "" {
+		return fmt.Sprintf("gs1: sid=%d seq=%d: payload does not parse: %s",
+			e.SID, e.Seq, strings.Join(e.Errors, "; "))
+	}
+	return fmt.Sprintf("gs1: sid=%d seq=%d: payload does not match schema %s: %s",
+		e.SID, e.Seq, e.Schema, strings.Join(e.Errors, "; "))
+}
+
+// TypedReader reads frames and decodes the payloads of those bound to a
+// schema.
+//
+// For a doc or row frame with a schema hash, Next resolves the schema,
+// parses the payload with glyph.ParseWithSchema, and validates the result;
+// Value holds it. Frames of other kinds are returned with Schema set and
+// Value nil (a patch payload can be read with glyph.ParsePatch and Schema),
+// and frames without a schema hash are returned as read, as are repeat
+// frames (Frame.Repeat), whose value is that of the last frame of their kind
+// on the SID. An unknown schema
+// or a payload that does not match it is an error for that frame only:
+// the next call reads the following frame.
+type TypedReader struct {
+	r        *Reader
+	resolver SchemaResolver
+}
+
+// NewTypedReader creates a reader that resolves schema hashes with
+// resolver.
+func NewTypedReader(r *Reader, resolver SchemaResolver) *TypedReader {
+	return &TypedReader{r: r, resolver: resolver}
+}
+
+// Next reads the next frame and decodes its payload.
+// Returns io.EOF when no more frames are available.
+func (t *TypedReader) Next() (*TypedFrame, error) {
+	frame, err := t.r.Next()
+	if err != nil {
+		return nil, err
+	}
+	tf := &TypedFrame{Frame: frame}
+	if frame.Schema == "" {
+		return tf, nil
+	}
+
+	tf.Schema, err = t.resolver.ResolveSchema(frame.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("gs1: sid=%d seq=%d: %w", frame.SID, frame.Seq, err)
+	}
+	if frame.Kind != KindDoc && frame.Kind != KindRow || frame.Repeat {
+		return tf, nil
+	}
+
+	tf.Value, tf.Warnings, err = decodePayload(frame, tf.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+// decodePayload parses the payload of a doc or row frame: under schema, and
+// validated, if it is not nil, as plain GLYPH otherwise.
+func decodePayload(frame *Frame, schema *glyph.Schema) (*glyph.GValue, []glyph.ValidationError, error) {
+	var result *glyph.ParseResult
+	var err error
+	if schema != nil {
+		result, err = glyph.ParseWithSchema(string(frame.Payload), schema)
+	} else {
+		result, err = glyph.Parse(string(frame.Payload))
+	}
+	if err != nil {
+		return nil, nil, payloadError(frame, schema, []string{err.Error()})
+	}
+	if result.HasErrors() {
+		msgs := make([]string, len(result.Errors))
+		for i := range result.Errors {
+			msgs[i] = result.Errors[i].Error()
+		}
+		return nil, nil, payloadError(frame, schema, msgs)
+	}
+	if schema == nil {
+		return result.Value, nil, nil
+	}
+	check := glyph.ValidateWithSchema(result.Value, schema)
+	if !check.Valid {
+		msgs := make([]string, len(check.Errors))
+		for i := range check.Errors {
+			msgs[i] = check.Errors[i].Error()
+		}
+		return nil, nil, payloadError(frame, schema, msgs)
+	}
+	return result.Value, check.Warnings, nil
+}
+
+// payloadError reports the payload of frame as invalid under schema, named
+// by the frame's hash or, if the header names none, by its own.
+func payloadError(frame *Frame, schema *glyph.Schema, msgs []string) error {
+	hash := frame.Schema
+	if hash == "" && schema != nil {
+		hash = schema.Hash
+		if hash == "" {
+			hash = schema.ComputeHash()
+		}
+	}
+	return &PayloadError{SID: frame.SID, Seq: frame.Seq, Schema: hash, Errors: msgs}
+}
@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Neumenon/glyph/glyph"
+)
+
+func linkSchema(t *testing.T) *glyph.Schema {
+	t.Helper()
+	schema, err := glyph.ParseSchema(`@schema{
+		Link struct{
+			url: str [nonempty]
+			title: str [optional]
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestTypedReader_RoundTrip(t *testing.T) {
+	schema := linkSchema(t)
+	link := glyph.Struct("Link",
+		glyph.MapEntry{Key: "url", Value: glyph.Str("https://x")},
+		glyph.MapEntry{Key: "title", Value: glyph.Str("X")},
+	)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteTyped(1, 0, KindDoc, link, schema); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteUI(1, 1, EmitProgress(0.5, "half"))
+	w.WriteFrame(&Frame{SID: 1, Seq: 2, Kind: KindPatch, Payload: []byte("@patch\n@end"), Schema: schema.Hash})
+	if !strings.Contains(buf.String(), "schema="+schema.Hash+"}") {
+		t.Fatalf("header missing schema hash:\n%s", buf.String())
+	}
+
+	r := NewTypedReader(NewReader(&buf), NewSchemaSet(schema))
+	doc, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema != schema || doc.Frame.Schema != schema.Hash {
+		t.Errorf("schema = %v, %q", doc.Schema, doc.Frame.Schema)
+	}
+	if doc.Value == nil || glyph.Emit(doc.Value) != glyph.Emit(link) {
+		t.Errorf("value = %v", doc.Value)
+	}
+
+	ui, err := r.Next()
+	if err != nil || ui.Schema != nil || ui.Value != nil || len(ui.Frame.Payload) == 0 {
+		t.Errorf("untyped frame: %+v %v", ui, err)
+	}
+	patch, err := r.Next()
+	if err != nil || patch.Schema != schema || patch.Value != nil {
+		t.Errorf("patch frame: %+v %v", patch, err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestTypedReader_Errors(t *testing.T) {
+	schema := linkSchema(t)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteFrame(&Frame{SID: 2, Seq: 0, Kind: KindDoc, Payload: []byte(`Link{url=""}`), Schema: schema.Hash})
+	w.WriteFrame(&Frame{SID: 2, Seq: 1, Kind: KindDoc, Payload: []byte(`Link{url=a}`), Schema: "00ff"})
+	w.WriteTyped(2, 2, KindRow, glyph.Struct("Link", glyph.MapEntry{Key: "url", Value: glyph.Str("b")}), schema)
+
+	r := NewTypedReader(NewReader(&buf), NewSchemaSet(schema))
+	_, err := r.Next()
+	var pe *PayloadError
+	if !errors.As(err, &pe) || pe.Seq != 0 || pe.Schema != schema.Hash || len(pe.Errors) == 0 {
+		t.Errorf("invalid payload: %v", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("unknown schema: %v", err)
+	}
+	row, err := r.Next()
+	if err != nil || glyph.Emit(row.Value) != `Link{url=b}` {
+		t.Errorf("frame after errors: %+v %v", row, err)
+	}
+
+	if _, err := NewReader(strings.NewReader("@frame{v=1 sid=1 seq=0 kind=doc len=0 schema=xyz}\n")).Next(); err == nil {
+		t.Error("expected error for malformed schema hash")
+	}
+}
@@ -13,6 +13,7 @@ package stream
 
 import (
 	"fmt"
+	"time"
 )
 
 // Version is the GS1 protocol version.
@@ -105,10 +106,16 @@ type Frame struct {
 	Payload []byte    // GLYPH payload bytes (UTF-8)
 
 	// Optional fields
-	CRC   *uint32   // CRC-32 of payload (nil if not present)
-	Base  *[32]byte // SHA-256 state hash (nil if not present)
-	Flags Flags     // Flag bits
-	Final bool      // End-of-stream marker
+	CRC    *uint32   // CRC-32 of payload (nil if not present)
+	Base   *[32]byte // SHA-256 state hash (nil if not present)
+	Schema string    // Hash of the glyph.Schema typing the payload ("" if none)
+	Time   time.Time // Producer clock when sent, to the millisecond (zero if not present)
+	Flags  Flags     // Flag bits
+	Final  bool      // End-of-stream marker
+	Repeat bool      // Payload is that of the last frame of this Kind on SID, and was not sent
+
+	// Set by Reader, not sent
+	Received time.Time // Local clock when read
 }
 
 // HasCRC returns true if CRC is present.
@@ -162,6 +169,18 @@ func (e *BaseMismatchError) Error() string {
 	return fmt.Sprintf("gs1: base hash mismatch")
 }
 
+// StreamClosedError is returned for a frame on a SID that has already
+// ended with a final frame, whether it is a late, reordered, or new frame.
+type StreamClosedError struct {
+	SID      uint64
+	FinalSeq uint64 // Seq of the final frame
+	Seq      uint64 // Seq of the rejected frame
+}
+
+func (e *StreamClosedError) Error() string {
+	return fmt.Sprintf("gs1: frame seq=%d on sid=%d after final frame seq=%d", e.Seq, e.SID, e.FinalSeq)
+}
+
 // ============================================================
 // Error Code Registry
 // ============================================================
@@ -202,4 +221,20 @@ const (
 	// ErrCodeFrameInvalid is emitted for structural parse failures
 	// (missing @frame{, missing closing brace, invalid field values).
 	ErrCodeFrameInvalid ErrorCode = "FRAME_INVALID"
+
+	// ErrCodeUnknownSchema is emitted when a frame's schema hash names no
+	// schema the receiver knows.
+	ErrCodeUnknownSchema ErrorCode = "UNKNOWN_SCHEMA"
+
+	// ErrCodePayloadInvalid is emitted when a payload does not parse or
+	// validate under the schema its frame names.
+	ErrCodePayloadInvalid ErrorCode = "PAYLOAD_INVALID"
+
+	// ErrCodeToolFailed is emitted when the tool or handler producing a
+	// stream fails; the message carries its error.
+	ErrCodeToolFailed ErrorCode = "TOOL_FAILED"
+
+	// ErrCodeStreamClosed is emitted for a frame received on a SID after
+	// its final frame.
+	ErrCodeStreamClosed ErrorCode = "STREAM_CLOSED"
 )
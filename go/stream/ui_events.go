@@ -1,6 +1,7 @@
 package stream
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -38,10 +39,16 @@ func Progress(pct float64, msg string) *glyph.GValue {
 // Log represents a log message.
 // Payload: Log@(level "info" msg "decoded 1000 rows" ts "2025-06-20T10:30:00Z")
 func Log(level, msg string) *glyph.GValue {
+	return LogAt(level, msg, time.Now())
+}
+
+// LogAt is Log stamped with ts instead of the current time, for streams
+// that must be reproducible (see StepClock).
+func LogAt(level, msg string, ts time.Time) *glyph.GValue {
 	return glyph.Struct("Log",
 		glyph.MapEntry{Key: "level", Value: glyph.Str(level)},
 		glyph.MapEntry{Key: "msg", Value: glyph.Str(msg)},
-		glyph.MapEntry{Key: "ts", Value: glyph.Time(time.Now().UTC())},
+		glyph.MapEntry{Key: "ts", Value: glyph.Time(ts.UTC())},
 	)
 }
 
@@ -131,6 +138,11 @@ func EmitLog(level, msg string) []byte {
 	return EmitUI(Log(level, msg))
 }
 
+// EmitLogAt emits a log event stamped with ts as GLYPH bytes.
+func EmitLogAt(level, msg string, ts time.Time) []byte {
+	return EmitUI(LogAt(level, msg, ts))
+}
+
 // EmitMetric emits a metric event as GLYPH bytes.
 func EmitMetric(name string, value float64, unit string) []byte {
 	return EmitUI(Metric(name, value, unit))
@@ -148,12 +160,7 @@ func EmitArtifact(mime, ref, name string) []byte {
 // Error represents an error event for kind=err frames.
 // Payload: Error@(code "BASE_MISMATCH" msg "state hash mismatch" sid 1 seq 42)
 func Error(code, msg string, sid, seq uint64) *glyph.GValue {
-	return glyph.Struct("Error",
-		glyph.MapEntry{Key: "code", Value: glyph.Str(code)},
-		glyph.MapEntry{Key: "msg", Value: glyph.Str(msg)},
-		glyphUintField("sid", sid),
-		glyphUintField("seq", seq),
-	)
+	return (&ErrorEvent{Code: code, Message: msg, SID: sid, Seq: seq}).Value()
 }
 
 // EmitError emits an error event as GLYPH bytes.
@@ -161,6 +168,116 @@ func EmitError(code, msg string, sid, seq uint64) []byte {
 	return []byte(glyph.Emit(Error(code, msg, sid, seq)))
 }
 
+// ErrorEvent is the structured payload of a kind=err frame. It implements
+// error, so a tool can return one and have it reach the peer unchanged.
+// Payload: Error@(code "CRC_MISMATCH" msg "..." sid 1 seq 42 retriable t)
+type ErrorEvent struct {
+	Code      ErrorCode // One of the ErrCode constants, or an application code
+	Message   string    // Human-readable description
+	SID       uint64    // Stream the error concerns
+	Seq       uint64    // Frame the error concerns
+	Retriable bool      // Resending the frame may succeed
+}
+
+func (e *ErrorEvent) Error() string {
+	return fmt.Sprintf("gs1: %s: %s (sid=%d seq=%d)", e.Code, e.Message, e.SID, e.Seq)
+}
+
+// Value returns the event as an Error struct. retriable is present only
+// when true.
+func (e *ErrorEvent) Value() *glyph.GValue {
+	entries := []glyph.MapEntry{
+		{Key: "code", Value: glyph.Str(e.Code)},
+		{Key: "msg", Value: glyph.Str(e.Message)},
+		glyphUintField("sid", e.SID),
+		glyphUintField("seq", e.Seq),
+	}
+	if e.Retriable {
+		entries = append(entries, glyph.MapEntry{Key: "retriable", Value: glyph.Bool(true)})
+	}
+	return glyph.Struct("Error", entries...)
+}
+
+// EmitErrorEvent emits an error event as GLYPH bytes.
+func EmitErrorEvent(e *ErrorEvent) []byte {
+	return []byte(glyph.Emit(e.Value()))
+}
+
+// ErrorEventFor describes err, raised while handling frame seq of sid, as
+// an ErrorEvent. An ErrorEvent in err's chain is returned as is; errors of
+// this package get their error code, and any other error is TOOL_FAILED.
+func ErrorEventFor(err error, sid, seq uint64) *ErrorEvent {
+	var ev *ErrorEvent
+	if errors.As(err, &ev) {
+		return ev
+	}
+	ev = &ErrorEvent{Code: ErrCodeToolFailed, Message: err.Error(), SID: sid, Seq: seq}
+	var (
+		crcErr     *CRCMismatchError
+		baseErr    *BaseMismatchError
+		parseErr   *ParseError
+		payloadErr *PayloadError
+		closedErr  *StreamClosedError
+	)
+	switch {
+	case errors.As(err, &crcErr):
+		ev.Code, ev.Retriable = ErrCodeCRCMismatch, true
+	case errors.As(err, &baseErr):
+		ev.Code, ev.Retriable = ErrCodeBaseMismatch, true
+	case errors.As(err, &closedErr):
+		ev.Code = ErrCodeStreamClosed
+	case errors.As(err, &payloadErr):
+		ev.Code = ErrCodePayloadInvalid
+	case errors.Is(err, ErrUnknownSchema):
+		ev.Code = ErrCodeUnknownSchema
+	case errors.As(err, &parseErr):
+		ev.Code = ErrCodeFrameInvalid
+	}
+	return ev
+}
+
+// ParseErrorEvent parses the payload of a kind=err frame.
+func ParseErrorEvent(payload []byte) (*ErrorEvent, error) {
+	typeName, fields, err := ParseUIEvent(payload)
+	if err != nil {
+		return nil, fmt.Errorf("parse error event: %w", err)
+	}
+	if typeName != "Error" {
+		return nil, fmt.Errorf("parse error event: got %s, want Error", typeName)
+	}
+	ev := &ErrorEvent{}
+	var ok bool
+	if ev.Code, ok = fields["code"].(string); !ok {
+		return nil, fmt.Errorf("parse error event: missing code")
+	}
+	ev.Message, _ = fields["msg"].(string)
+	ev.Retriable, _ = fields["retriable"].(bool)
+	if ev.SID, err = eventUint(fields, "sid"); err != nil {
+		return nil, err
+	}
+	if ev.Seq, err = eventUint(fields, "seq"); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// eventUint reads a field written by glyphUintField; a missing field is 0.
+func eventUint(fields map[string]interface{}, key string) (uint64, error) {
+	switch v := fields[key].(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case string:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("parse error event: invalid %s %v", key, fields[key])
+}
+
 // ============================================================
 // Parse helpers
 // ============================================================
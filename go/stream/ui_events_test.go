@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorEvent_RoundTrip(t *testing.T) {
+	ev := &ErrorEvent{Code: ErrCodeCRCMismatch, Message: "bad crc", SID: 3, Seq: 1 << 63, Retriable: true}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).WriteErrorEvent(3, 9, ev); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := NewReader(&buf).Next()
+	if err != nil || frame.Kind != KindErr {
+		t.Fatalf("frame = %+v, %v", frame, err)
+	}
+	got, err := ParseErrorEvent(frame.Payload)
+	if err != nil || *got != *ev {
+		t.Errorf("ParseErrorEvent = %+v, %v", got, err)
+	}
+
+	// The plain Error payload is unchanged and reads back as not retriable.
+	plain := EmitError(ErrCodeSeqGap, "gap", 1, 42)
+	if strings.Contains(string(plain), "retriable") {
+		t.Errorf("EmitError = %s", plain)
+	}
+	got, err = ParseErrorEvent(plain)
+	if err != nil || got.Retriable || got.Seq != 42 || got.Code != ErrCodeSeqGap {
+		t.Errorf("plain error = %+v, %v", got, err)
+	}
+
+	for _, bad := range []string{`Progress{pct=0.5 msg=x}`, `Error{msg=x}`, `Error{code=X seq=-1}`, `[1 2]`} {
+		if _, err := ParseErrorEvent([]byte(bad)); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestErrorEventFor(t *testing.T) {
+	tool := &ErrorEvent{Code: "RATE_LIMITED", Message: "slow down", SID: 1, Seq: 2, Retriable: true}
+	for _, tc := range []struct {
+		err       error
+		code      ErrorCode
+		retriable bool
+	}{
+		{&CRCMismatchError{}, ErrCodeCRCMismatch, true},
+		{fmt.Errorf("apply: %w", &BaseMismatchError{}), ErrCodeBaseMismatch, true},
+		{&ParseError{Reason: "x", Offset: -1}, ErrCodeFrameInvalid, false},
+		{&PayloadError{}, ErrCodePayloadInvalid, false},
+		{fmt.Errorf("gs1: %w abc", ErrUnknownSchema), ErrCodeUnknownSchema, false},
+		{errors.New("disk full"), ErrCodeToolFailed, false},
+		{fmt.Errorf("call: %w", tool), "RATE_LIMITED", true},
+	} {
+		ev := ErrorEventFor(tc.err, 5, 6)
+		if ev.Code != tc.code || ev.Retriable != tc.retriable {
+			t.Errorf("%v: %+v", tc.err, ev)
+		}
+	}
+	if ev := ErrorEventFor(errors.New("disk full"), 5, 6); ev.Message != "disk full" || ev.SID != 5 || ev.Seq != 6 {
+		t.Errorf("tool failure = %+v", ev)
+	}
+}